@@ -20,6 +20,7 @@ import (
 	"io"
 	"os"
 
+	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
 
@@ -30,6 +31,7 @@ var (
 func init() {
 	applyCmd.PersistentFlags().StringVar(&to, "to", "", "Template to apply")
 	applyCmd.PersistentFlags().StringVar(&out, "out", "", "Output path")
+	applyCmd.PersistentFlags().BoolVar(&dryRun, "dry-run", false, "Print the output without writing it to the output path")
 	applyCmd.AddCommand(applyBranchCmd)
 	applyCmd.AddCommand(applyCommitCmd)
 	applyCmd.AddCommand(applyHeadCmd)
@@ -97,6 +99,13 @@ func applyCore(f applyFunc) error {
 		return errors.New("requires the path to template, specify --to argument")
 	}
 
+	if dryRun {
+		if out != "" {
+			logrus.Infof("Dry run: output would be written to %v", out)
+		}
+		return f(to, os.Stdout)
+	}
+
 	output, err := getOutput(out)
 	if err != nil {
 		return err
@@ -0,0 +1,72 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	approvalsCommand.Flags().BoolVar(&toJSON, "json", false, "Format output as json")
+
+	approvalsCommand.Flags().StringVar(&src, "src", "", "Source branch")
+	approvalsCommand.Flags().StringVar(&dst, "dst", "", "Destination branch")
+	approvalsCommand.Flags().StringVar(&from, "from", "", "From commit")
+	approvalsCommand.Flags().StringVar(&to, "to", "", "To commit")
+	approvalsCommand.Flags().BoolVarP(&content, "content", "c", false, "List the approvals of the modules impacted by the content of the commit")
+
+	RootCmd.AddCommand(approvalsCommand)
+}
+
+var approvalsCommand = &cobra.Command{
+	Use:   "approvals <commit|diff|local|pr> [args]",
+	Short: docText("approvals-summary"),
+	Long:  docText("approvals"),
+	RunE: buildHandler(func(cmd *cobra.Command, args []string) error {
+		if len(args) < 1 {
+			return errors.New("requires the changes to list approvals for")
+		}
+
+		m, err := manifestByMode(args[0], args[1:])
+		if err != nil {
+			return err
+		}
+
+		approvals := m.RequiredApprovals()
+		if toJSON {
+			buff, err := json.MarshalIndent(approvals, "", "  ")
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(buff))
+			return nil
+		}
+
+		tw := tabwriter.NewWriter(os.Stdout, 0, 4, 4, ' ', 0)
+		fmt.Fprintf(tw, "GROUP\tMODULES\n")
+		for _, a := range approvals {
+			fmt.Fprintf(tw, "%s\t%s\n", a.Group, strings.Join(a.Modules, ","))
+		}
+		return tw.Flush()
+	}),
+}
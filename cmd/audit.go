@@ -0,0 +1,69 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/mbtproject/mbt/lib"
+	"github.com/spf13/cobra"
+)
+
+var (
+	knownProperties []string
+)
+
+func init() {
+	auditCommand.Flags().BoolVar(&toJSON, "json", false, "Format output as json")
+	auditCommand.Flags().StringSliceVar(&knownProperties, "known-property", nil, "Properties consumed outside of modules which are not reported as stale")
+	RootCmd.AddCommand(auditCommand)
+}
+
+var auditCommand = &cobra.Command{
+	Use:   "audit",
+	Short: docText("audit-summary"),
+	Long:  docText("audit"),
+	RunE: buildHandler(func(cmd *cobra.Command, args []string) error {
+		m, err := system.ManifestByWorkspace()
+		if err != nil {
+			return err
+		}
+
+		findings, err := lib.Audit(m, &lib.AuditOptions{KnownProperties: knownProperties})
+		if err != nil {
+			return err
+		}
+
+		if toJSON {
+			buff, err := json.MarshalIndent(findings, "", "  ")
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(buff))
+			return nil
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 4, ' ', 0)
+		fmt.Fprintf(w, "Name\tKIND\tMESSAGE\n")
+		for _, f := range findings {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", f.Module, f.Kind, f.Message)
+		}
+		return w.Flush()
+	}),
+}
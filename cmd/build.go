@@ -17,6 +17,10 @@ package cmd
 
 import (
 	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/sirupsen/logrus"
 
@@ -25,6 +29,25 @@ import (
 )
 
 func init() {
+	buildCommand.PersistentFlags().BoolVar(&keepGoing, "keep-going", false, "Continue building the remaining modules when a build fails")
+	buildCommand.PersistentFlags().BoolVar(&dryRun, "dry-run", false, "Print the build plan without executing it")
+	buildCommand.PersistentFlags().StringVar(&logDir, "log-dir", "", "Directory to store the build output of each module")
+	buildCommand.PersistentFlags().IntVar(&parallelism, "parallelism", 1, "Capacity available to build modules concurrently")
+	buildCommand.PersistentFlags().StringVar(&cacheDir, "cache-dir", "", "Directory to cache the outputs of module builds")
+	buildCommand.PersistentFlags().StringVar(&remoteCache, "remote-cache", "", "URL of the HTTP server to cache the outputs of module builds")
+	buildCommand.PersistentFlags().StringVar(&durationsFile, "durations-file", "", "File to persist build durations (default .git/mbt/durations.json)")
+	buildCommand.PersistentFlags().StringVar(&buildNumbersFile, "build-numbers-file", "", "File to persist the last build number of each module")
+	buildCommand.PersistentFlags().StringVar(&snapshotPattern, "snapshot-pattern", "", "Pattern of the snapshot version issued for each module built (e.g. {semver}-{date}.{buildNumber}+{sha})")
+	buildCommand.PersistentFlags().StringVar(&sbomDir, "sbom-dir", "", "Directory to write the software bill of materials of each module built")
+	buildCommand.PersistentFlags().StringVar(&sbomFormat, "sbom-format", lib.SBOMFormatCycloneDX, "Format of the software bill of materials (cyclonedx or spdx)")
+	buildCommand.PersistentFlags().StringVar(&sbomScanner, "sbom-scanner", "syft", "Scanner used to generate the software bill of materials of modules without an sbom command")
+	buildCommand.PersistentFlags().StringVar(&artifactsFile, "artifacts-file", "", "File to write the artifact manifest of the build")
+	buildCommand.PersistentFlags().StringVar(&provenanceDir, "provenance-dir", "", "Directory to write the SLSA provenance of each module built")
+	buildCommand.PersistentFlags().StringVar(&builderID, "builder-id", "https://github.com/mbtproject/mbt", "Identifier of the builder recorded in the provenance")
+	buildCommand.PersistentFlags().StringVar(&repoURI, "repo-uri", "", "URI of the repository recorded in the provenance (default path to the repository)")
+	buildCommand.PersistentFlags().BoolVar(&sign, "sign", false, "Sign the provenance and artifact manifest with cosign keyless signing")
+	buildCommand.PersistentFlags().StringVar(&cosign, "cosign", "cosign", "Path to cosign binary")
+
 	buildPr.Flags().StringVar(&src, "src", "", "Source branch")
 	buildPr.Flags().StringVar(&dst, "dst", "", "Destination branch")
 
@@ -34,16 +57,28 @@ func init() {
 	buildLocal.Flags().BoolVarP(&all, "all", "a", false, "All modules")
 	buildLocal.Flags().StringVarP(&name, "name", "n", "", "Build modules with a name that matches this value. Multiple names can be specified as a comma separated string.")
 	buildLocal.Flags().BoolVarP(&fuzzy, "fuzzy", "f", false, "Use fuzzy match when filtering")
+	buildLocal.Flags().StringVar(&filterExpr, "expr", "", "Filter modules with an expression")
+	buildLocal.Flags().StringVar(&owner, "owner", "", "Filter modules owned by this owner according to CODEOWNERS")
+	buildLocal.Flags().StringVar(&group, "group", "", "Filter modules in this group")
 
 	buildCommit.Flags().BoolVarP(&content, "content", "c", false, "Build the modules impacted by the content of the commit")
 	buildCommit.Flags().StringVarP(&name, "name", "n", "", "Build modules with a name that matches this value. Multiple names can be specified as a comma separated string.")
 	buildCommit.Flags().BoolVarP(&fuzzy, "fuzzy", "f", false, "Use fuzzy match when filtering")
+	buildCommit.Flags().StringVar(&filterExpr, "expr", "", "Filter modules with an expression")
+	buildCommit.Flags().StringVar(&owner, "owner", "", "Filter modules owned by this owner according to CODEOWNERS")
+	buildCommit.Flags().StringVar(&group, "group", "", "Filter modules in this group")
 
 	buildBranch.Flags().StringVarP(&name, "name", "n", "", "Build modules with a name that matches this value. Multiple names can be specified as a comma separated string.")
 	buildBranch.Flags().BoolVarP(&fuzzy, "fuzzy", "f", false, "Use fuzzy match when filtering")
+	buildBranch.Flags().StringVar(&filterExpr, "expr", "", "Filter modules with an expression")
+	buildBranch.Flags().StringVar(&owner, "owner", "", "Filter modules owned by this owner according to CODEOWNERS")
+	buildBranch.Flags().StringVar(&group, "group", "", "Filter modules in this group")
 
 	buildHead.Flags().StringVarP(&name, "name", "n", "", "Build modules with a name that matches this value. Multiple names can be specified as a comma separated string.")
 	buildHead.Flags().BoolVarP(&fuzzy, "fuzzy", "f", false, "Use fuzzy match when filtering")
+	buildHead.Flags().StringVar(&filterExpr, "expr", "", "Filter modules with an expression")
+	buildHead.Flags().StringVar(&owner, "owner", "", "Filter modules owned by this owner according to CODEOWNERS")
+	buildHead.Flags().StringVar(&group, "group", "", "Filter modules in this group")
 
 	buildCommand.AddCommand(buildBranch)
 	buildCommand.AddCommand(buildPr)
@@ -57,7 +92,7 @@ func init() {
 var buildHead = &cobra.Command{
 	Use: "head",
 	RunE: buildHandler(func(cmd *cobra.Command, args []string) error {
-		return summarise(system.BuildCurrentBranch(&lib.FilterOptions{Name: name, Fuzzy: fuzzy}, lib.CmdOptionsWithStdIO(buildStageCB)))
+		return summarise(system.BuildCurrentBranch(&lib.FilterOptions{Name: name, Fuzzy: fuzzy, Expr: filterExpr, Owner: owner, Group: group}, buildCmdOptions()))
 	}),
 }
 
@@ -69,7 +104,7 @@ var buildBranch = &cobra.Command{
 			branch = args[0]
 		}
 
-		return summarise(system.BuildBranch(branch, &lib.FilterOptions{Name: name, Fuzzy: fuzzy}, lib.CmdOptionsWithStdIO(buildStageCB)))
+		return summarise(system.BuildBranch(branch, &lib.FilterOptions{Name: name, Fuzzy: fuzzy, Expr: filterExpr, Owner: owner, Group: group}, buildCmdOptions()))
 	}),
 }
 
@@ -84,7 +119,7 @@ var buildPr = &cobra.Command{
 			return errors.New("requires dest")
 		}
 
-		return summarise(system.BuildPr(src, dst, lib.CmdOptionsWithStdIO(buildStageCB)))
+		return summarise(system.BuildPr(src, dst, buildCmdOptions()))
 	}),
 }
 
@@ -99,7 +134,7 @@ var buildDiff = &cobra.Command{
 			return errors.New("requires to commit")
 		}
 
-		return summarise(system.BuildDiff(from, to, lib.CmdOptionsWithStdIO(buildStageCB)))
+		return summarise(system.BuildDiff(from, to, buildCmdOptions()))
 	}),
 }
 
@@ -113,9 +148,9 @@ var buildCommit = &cobra.Command{
 		commit := args[0]
 
 		if content {
-			return summarise(system.BuildCommitContent(commit, lib.CmdOptionsWithStdIO(buildStageCB)))
+			return summarise(system.BuildCommitContent(commit, buildCmdOptions()))
 		}
-		return summarise(system.BuildCommit(commit, &lib.FilterOptions{Name: name, Fuzzy: fuzzy}, lib.CmdOptionsWithStdIO(buildStageCB)))
+		return summarise(system.BuildCommit(commit, &lib.FilterOptions{Name: name, Fuzzy: fuzzy, Expr: filterExpr, Owner: owner, Group: group}, buildCmdOptions()))
 	}),
 }
 
@@ -123,10 +158,10 @@ var buildLocal = &cobra.Command{
 	Use: "local [--all]",
 	RunE: buildHandler(func(cmd *cobra.Command, args []string) error {
 		if all || name != "" {
-			return summarise(system.BuildWorkspace(&lib.FilterOptions{Name: name, Fuzzy: fuzzy}, lib.CmdOptionsWithStdIO(buildStageCB)))
+			return summarise(system.BuildWorkspace(&lib.FilterOptions{Name: name, Fuzzy: fuzzy, Expr: filterExpr, Owner: owner, Group: group}, buildCmdOptions()))
 		}
 
-		return summarise(system.BuildWorkspaceChanges(lib.CmdOptionsWithStdIO(buildStageCB)))
+		return summarise(system.BuildWorkspaceChanges(buildCmdOptions()))
 	}),
 }
 
@@ -136,21 +171,195 @@ func buildStageCB(a *lib.Module, s lib.CmdStage, err error) {
 		logrus.Infof("BUILD %s in %s for %s", a.Name(), a.Path(), a.Version())
 	case lib.CmdStageSkipBuild:
 		logrus.Infof("SKIP %s in %s for %s", a.Name(), a.Path(), a.Version())
+	case lib.CmdStageFailedBuild:
+		logrus.Errorf("FAILED %s in %s for %s: %v", a.Name(), a.Path(), a.Version(), err)
+	case lib.CmdStageCachedBuild:
+		logrus.Infof("RESTORED %s in %s for %s", a.Name(), a.Path(), a.Version())
+	}
+}
+
+// targetText contains the words used to report the progress
+// of a target (e.g. build, test).
+type targetText struct {
+	// verb is the lower case form of the target action
+	verb string
+	// done is the past tense of verb
+	done string
+}
+
+var buildText = &targetText{verb: "build", done: "built"}
+
+func capitalize(s string) string {
+	if s == "" {
+		return s
 	}
+	return strings.ToUpper(s[:1]) + s[1:]
 }
 
 func summarise(summary *lib.BuildSummary, err error) error {
+	notify(summary)
+	publishCommitStatuses(summary)
+	if writeErr := writeArtifactManifest(summary); writeErr != nil {
+		logrus.Errorf("Failed to write artifact manifest: %v", writeErr)
+	}
+	if writeErr := writeProvenance(summary); writeErr != nil {
+		logrus.Errorf("Failed to write provenance: %v", writeErr)
+	}
+	if writeErr := recordBuildMetadata(summary); writeErr != nil {
+		logrus.Errorf("Failed to record build results: %v", writeErr)
+	}
+	err = summariseTarget(buildText, summary, err)
 	if err == nil {
-		logrus.Infof("Modules: %v Built: %v Skipped: %v",
+		err = reportReproducibility(summary)
+	}
+	return err
+}
+
+func summariseTarget(text *targetText, summary *lib.BuildSummary, err error) error {
+	if summary != nil && dryRun {
+		logrus.Infof("Modules: %v To %s: %v Skipped: %v",
 			len(summary.Manifest.Modules),
+			text.verb,
 			len(summary.Completed),
 			len(summary.Skipped))
+	} else if summary != nil {
+		logrus.Infof("Modules: %v %s: %v Skipped: %v Failed: %v",
+			len(summary.Manifest.Modules),
+			capitalize(text.done),
+			len(summary.Completed),
+			len(summary.Skipped),
+			len(summary.Failures))
+
+		for _, r := range summary.Completed {
+			if r.Cached {
+				logrus.Infof("RESTORED %s from cache", r.Module.Name())
+			} else if r.LogFile != "" {
+				logrus.Infof("%s %s in %v log: %s", strings.ToUpper(text.done), r.Module.Name(), r.Duration, r.LogFile)
+			} else {
+				logrus.Infof("%s %s in %v", strings.ToUpper(text.done), r.Module.Name(), r.Duration)
+			}
+		}
+
+		for _, f := range summary.Failures {
+			if f.LogFile != "" {
+				logrus.Errorf("FAILED %s: %v log: %s", f.Module.Name(), f.Err, f.LogFile)
+			} else {
+				logrus.Errorf("FAILED %s: %v", f.Module.Name(), f.Err)
+			}
+		}
+
+		logrus.Infof("%s finished for commit %v", capitalize(text.verb), summary.Manifest.Sha)
+	}
 
-		logrus.Infof("Build finished for commit %v", summary.Manifest.Sha)
+	if err == nil && summary != nil {
+		return checkSelection(summary.Manifest.Modules)
 	}
 	return err
 }
 
+func buildCmdOptions() *lib.CmdOptions {
+	buildStarted = time.Now()
+	callback := buildStageCB
+	if notifySlack != "" || notifyWebhook != "" || len(notifyEmail) > 0 {
+		recorder = &buildRecorder{}
+		callback = recorder.callback(callback)
+	}
+
+	options := targetCmdOptions(callback)
+	options.Cache = buildCache()
+	if buildNumbersFile != "" {
+		options.BuildNumbers = lib.NewFileBuildNumberStore(buildNumbersFile)
+	}
+	options.SnapshotPattern = snapshotPattern
+	if verifyReproducible {
+		options.Reproducible = reproducible
+	}
+	if sbomDir != "" {
+		options.SBOM = &lib.SBOMOptions{Dir: sbomDir, Format: sbomFormat, Scanner: sbomScanner}
+	}
+	return options
+}
+
+func writeArtifactManifest(summary *lib.BuildSummary) error {
+	if artifactsFile == "" || summary == nil || dryRun {
+		return nil
+	}
+
+	a, err := lib.NewArtifactManifest(summary)
+	if err != nil {
+		return err
+	}
+
+	if err := a.Write(artifactsFile); err != nil {
+		return err
+	}
+
+	if sign {
+		return lib.SignFile(cosign, artifactsFile)
+	}
+	return nil
+}
+
+func writeProvenance(summary *lib.BuildSummary) error {
+	if provenanceDir == "" || summary == nil || dryRun {
+		return nil
+	}
+
+	paths, err := lib.GenerateProvenance(summary, &lib.ProvenanceOptions{
+		Dir:       provenanceDir,
+		BuilderID: builderID,
+		RepoURI:   repoURI,
+		Sign:      sign,
+		Cosign:    cosign,
+		StartedOn: buildStarted,
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, p := range paths {
+		logrus.Infof("PROVENANCE %s", p)
+	}
+	return nil
+}
+
+// targetCmdOptions creates the options shared by the commands
+// executing a target for modules (e.g. build, test).
+func targetCmdOptions(callback lib.CmdStageCallback) *lib.CmdOptions {
+	options := lib.CmdOptionsWithStdIO(callback)
+	options.KeepGoing = keepGoing
+	options.DryRun = dryRun
+	options.LogDir = logDir
+	options.Parallelism = parallelism
+	options.DurationsFile = durationsFile
+	options.AllowEnv = allowEnv
+	if options.DurationsFile == "" {
+		if fi, err := os.Stat(filepath.Join(in, ".git")); err == nil && fi.IsDir() {
+			options.DurationsFile = filepath.Join(in, ".git", "mbt", "durations.json")
+		}
+	}
+	return withOutputOptions(options)
+}
+
+func buildCache() lib.Cache {
+	caches := make([]lib.Cache, 0)
+	if cacheDir != "" {
+		caches = append(caches, lib.NewDirCache(cacheDir))
+	}
+	if remoteCache != "" {
+		caches = append(caches, lib.NewHTTPCache(remoteCache))
+	}
+
+	switch len(caches) {
+	case 0:
+		return nil
+	case 1:
+		return caches[0]
+	default:
+		return lib.NewTieredCache(caches...)
+	}
+}
+
 var buildCommand = &cobra.Command{
 	Use:   "build",
 	Short: docText("build-summary"),
@@ -0,0 +1,107 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/mbtproject/mbt/e"
+	"github.com/mbtproject/mbt/lib"
+	"github.com/spf13/cobra"
+)
+
+var (
+	recordNotes      bool
+	notesRef         string
+	buildNumbersFile string
+	snapshotPattern  string
+)
+
+func init() {
+	buildCommand.PersistentFlags().BoolVar(&recordNotes, "record-notes", false, "Record the build results as git notes on the built commit")
+	buildCommand.PersistentFlags().StringVar(&notesRef, "notes-ref", lib.DefaultNotesRef, "Notes reference used to record the build results")
+
+	buildsCommand.Flags().BoolVar(&toJSON, "json", false, "Format output as json")
+	buildsCommand.Flags().StringVar(&notesRef, "notes-ref", lib.DefaultNotesRef, "Notes reference used to record the build results")
+	RootCmd.AddCommand(buildsCommand)
+}
+
+var buildsCommand = &cobra.Command{
+	Use:   "builds [commit]",
+	Short: docText("builds-summary"),
+	Long:  docText("builds"),
+	RunE: buildHandler(func(cmd *cobra.Command, args []string) error {
+		repo, err := lib.NewLibgitRepo(in, lib.NewStdLog(lib.LogLevelNormal))
+		if err != nil {
+			return err
+		}
+
+		var sha string
+		if len(args) > 0 {
+			sha = args[0]
+		} else {
+			head, err := repo.CurrentBranchCommit()
+			if err != nil {
+				return err
+			}
+			sha = head.ID()
+		}
+
+		m, err := lib.NewGitNotesStore(repo, notesRef).BuildMetadata(sha)
+		if err != nil {
+			return err
+		}
+		if m == nil {
+			return e.NewErrorf(lib.ErrClassUser, "no build results recorded for commit %s", sha)
+		}
+
+		if toJSON {
+			buff, err := json.MarshalIndent(m, "", "  ")
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(buff))
+			return nil
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 4, ' ', 0)
+		fmt.Fprintf(w, "Name\tVERSION\tSTATUS\tDURATION\tARTIFACTS\n")
+		for _, mod := range m.Modules {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%v\t%v\n", mod.Name, mod.Version, mod.Status, mod.Duration, len(mod.Artifacts))
+		}
+		return w.Flush()
+	}),
+}
+
+func recordBuildMetadata(summary *lib.BuildSummary) error {
+	if !recordNotes || summary == nil || dryRun {
+		return nil
+	}
+
+	m, err := lib.NewBuildMetadata(summary)
+	if err != nil {
+		return err
+	}
+
+	repo, err := lib.NewLibgitRepo(in, lib.NewStdLog(lib.LogLevelNormal))
+	if err != nil {
+		return err
+	}
+	return lib.NewGitNotesStore(repo, notesRef).Record(m)
+}
@@ -0,0 +1,95 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/mbtproject/mbt/e"
+	"github.com/mbtproject/mbt/lib"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	maxAge    time.Duration
+	maxSizeMB int64
+	keepLast  int
+)
+
+func init() {
+	cacheGC.Flags().StringVar(&cacheDir, "cache-dir", "", "Directory caching the outputs of module builds")
+	cacheGC.Flags().DurationVar(&maxAge, "max-age", 0, "Remove the entries not used within this duration (e.g. 720h)")
+	cacheGC.Flags().Int64Var(&maxSizeMB, "max-size-mb", 0, "Remove the least recently used entries until the cache is at most this many megabytes")
+	cacheGC.Flags().IntVar(&keepLast, "keep-last", 0, "Keep only this many most recently used entries of each module")
+	cacheGC.Flags().BoolVar(&dryRun, "dry-run", false, "List the entries to remove without removing them")
+	cacheGC.Flags().BoolVar(&toJSON, "json", false, "Format output as json")
+
+	cacheCommand.AddCommand(cacheGC)
+	RootCmd.AddCommand(cacheCommand)
+}
+
+var cacheCommand = &cobra.Command{
+	Use:   "cache",
+	Short: docText("cache-summary"),
+	Long:  docText("cache"),
+}
+
+var cacheGC = &cobra.Command{
+	Use:   "gc --cache-dir <dir>",
+	Short: docText("cache-gc-summary"),
+	Long:  docText("cache-gc"),
+	RunE: buildHandler(func(cmd *cobra.Command, args []string) error {
+		if cacheDir == "" {
+			return e.NewError(lib.ErrClassUser, "--cache-dir is required")
+		}
+
+		options := &lib.CacheGCOptions{MaxAge: maxAge, MaxSize: maxSizeMB * 1024 * 1024, KeepLast: keepLast, DryRun: dryRun}
+		if keepLast > 0 {
+			m, err := system.ManifestByWorkspace()
+			if err != nil {
+				return err
+			}
+			options.Modules = m.Modules.Names()
+		}
+
+		result, err := lib.CollectCache(lib.NewDirCache(cacheDir), options)
+		if err != nil {
+			return err
+		}
+
+		if toJSON {
+			buff, err := json.MarshalIndent(result, "", "  ")
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(buff))
+			return nil
+		}
+
+		verb := "REMOVED"
+		if dryRun {
+			verb = "REMOVE"
+		}
+		for _, entry := range result.Removed {
+			logrus.Infof("%s %s (%v bytes)", verb, entry.Key, entry.Size)
+		}
+		logrus.Infof("Freed %v bytes, kept %v entries", result.Freed, result.Kept)
+		return nil
+	}),
+}
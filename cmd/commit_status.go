@@ -0,0 +1,58 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"os"
+
+	"github.com/mbtproject/mbt/lib"
+	"github.com/sirupsen/logrus"
+)
+
+var statusOptions = &lib.CommitStatusOptions{}
+
+func init() {
+	buildCommand.PersistentFlags().StringVar(&statusOptions.Provider, "status-provider", "", "Publish commit statuses on github, gitlab or bitbucket")
+	buildCommand.PersistentFlags().StringVar(&statusOptions.API, "status-api", "", "Base URL of the provider API (default public API of the provider)")
+	buildCommand.PersistentFlags().StringVar(&statusOptions.Repo, "status-repo", "", "Repository in the provider API (e.g. owner/name)")
+	buildCommand.PersistentFlags().StringVar(&statusOptions.Context, "status-context", "mbt", "Prefix of commit status contexts")
+	buildCommand.PersistentFlags().StringVar(&statusOptions.TargetURL, "status-url", "", "URL linked from commit statuses (e.g. CI job URL)")
+	buildCommand.PersistentFlags().BoolVar(&statusOptions.Aggregate, "status-aggregate", false, "Publish a single commit status for all modules")
+}
+
+// publishCommitStatuses publishes the build results as commit
+// statuses. Failures to publish are logged without failing the build.
+func publishCommitStatuses(summary *lib.BuildSummary) {
+	if statusOptions.Provider == "" || dryRun {
+		return
+	}
+
+	if summary == nil {
+		logrus.Warn("Commit statuses are not published for aborted builds, use --keep-going to publish the statuses of failed builds")
+		return
+	}
+
+	statusOptions.Token = os.Getenv("MBT_STATUS_TOKEN")
+	p, err := lib.NewStatusPublisher(statusOptions)
+	if err != nil {
+		logrus.Warn(err)
+		return
+	}
+
+	if err := lib.PublishCommitStatuses(summary, p, statusOptions); err != nil {
+		logrus.Warnf("Failed to publish commit statuses: %v", err)
+	}
+}
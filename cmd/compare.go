@@ -0,0 +1,86 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/mbtproject/mbt/lib"
+	"github.com/spf13/cobra"
+)
+
+var (
+	differsOnly bool
+)
+
+func init() {
+	compareCommand.Flags().BoolVar(&differsOnly, "differs", false, "List only the modules with different versions")
+	compareCommand.Flags().BoolVar(&toJSON, "json", false, "Format output as json")
+	RootCmd.AddCommand(compareCommand)
+}
+
+var compareCommand = &cobra.Command{
+	Use:   "compare <branch> <branch>",
+	Short: docText("compare-summary"),
+	Long:  docText("compare"),
+	RunE: buildHandler(func(cmd *cobra.Command, args []string) error {
+		if len(args) != 2 {
+			return errors.New("requires the branches to compare")
+		}
+
+		comparison, err := system.CompareBranches(args[0], args[1])
+		if err != nil {
+			return err
+		}
+
+		if differsOnly {
+			differs := make([]*lib.ModuleComparison, 0)
+			for _, c := range comparison {
+				if c.Differs {
+					differs = append(differs, c)
+				}
+			}
+			comparison = differs
+		}
+
+		if toJSON {
+			buff, err := json.MarshalIndent(comparison, "", "  ")
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(buff))
+			return nil
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 4, ' ', 0)
+		fmt.Fprintf(w, "Name\t%s\t%s\tDIFFERS\n", args[0], args[1])
+		for _, c := range comparison {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%v\n", c.Name, orDash(c.A), orDash(c.B), c.Differs)
+		}
+		return w.Flush()
+	}),
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,145 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"os"
+	"strings"
+
+	"github.com/mbtproject/mbt/e"
+	"github.com/mbtproject/mbt/lib"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	// Completions are registered once all commands and their flags
+	// are initialised.
+	cobra.OnInitialize(registerCompletions)
+	RootCmd.AddCommand(completionCommand)
+}
+
+var completionCommand = &cobra.Command{
+	Use:       "completion <bash|zsh|fish|powershell>",
+	Short:     docText("completion-summary"),
+	Long:      docText("completion"),
+	ValidArgs: []string{"bash", "zsh", "fish", "powershell"},
+	RunE: buildHandler(func(cmd *cobra.Command, args []string) error {
+		if len(args) != 1 {
+			return e.NewError(lib.ErrClassUser, "requires the shell")
+		}
+
+		switch args[0] {
+		case "bash":
+			return cmd.Root().GenBashCompletion(os.Stdout)
+		case "zsh":
+			return cmd.Root().GenZshCompletion(os.Stdout)
+		case "fish":
+			return cmd.Root().GenFishCompletion(os.Stdout, true)
+		case "powershell":
+			return cmd.Root().GenPowerShellCompletion(os.Stdout)
+		default:
+			return e.NewErrorf(lib.ErrClassUser, "invalid shell '%v' (available options are 'bash', 'zsh', 'fish' and 'powershell')", args[0])
+		}
+	}),
+}
+
+var completionsRegistered bool
+
+// registerCompletions completes module names in --name flag of all
+// commands and in the arguments of commands expecting a module name.
+func registerCompletions() {
+	if completionsRegistered {
+		return
+	}
+	completionsRegistered = true
+
+	describeVersionCmd.ValidArgsFunction = completeModuleArg
+	exportCommand.ValidArgsFunction = completeModuleArg
+	registerNameCompletion(RootCmd)
+}
+
+func registerNameCompletion(c *cobra.Command) {
+	if c.Flag("name") != nil {
+		c.RegisterFlagCompletionFunc("name", completeModuleNames)
+	}
+
+	for _, sub := range c.Commands() {
+		registerNameCompletion(sub)
+	}
+}
+
+// isCompletionCommand returns true for the commands used by shell
+// completion. They don't require a system.
+func isCompletionCommand(c *cobra.Command) bool {
+	return c == completionCommand || c.Name() == cobra.ShellCompRequestCmd
+}
+
+func completeModuleArg(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	return completeModuleNames(cmd, args, toComplete)
+}
+
+// completeModuleNames completes the names of modules in the workspace.
+// Last name in a comma separated list is completed.
+func completeModuleNames(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	names, err := workspaceModuleNames()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+
+	prefix, partial := "", toComplete
+	if i := strings.LastIndex(toComplete, ","); i >= 0 {
+		prefix, partial = toComplete[:i+1], toComplete[i+1:]
+	}
+
+	completions := make([]string, 0)
+	for _, n := range names {
+		if strings.HasPrefix(n, partial) {
+			completions = append(completions, prefix+n)
+		}
+	}
+
+	return completions, cobra.ShellCompDirectiveNoFileComp
+}
+
+func workspaceModuleNames() ([]string, error) {
+	dir := in
+	if dir == "" {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return nil, err
+		}
+		dir, err = lib.GitRepoRoot(cwd)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	s, err := lib.NewSystem(dir, lib.LogLevelNormal)
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := s.ManifestByWorkspace()
+	if err != nil {
+		return nil, err
+	}
+
+	return m.Modules.Names(), nil
+}
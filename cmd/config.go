@@ -0,0 +1,124 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/mbtproject/mbt/e"
+	"github.com/mbtproject/mbt/lib"
+	"github.com/spf13/cobra"
+)
+
+// configurableOptions are the options whose default values can be set
+// in the configuration file or environment variables.
+var configurableOptions = map[string]bool{
+	"parallelism":        true,
+	"keep-going":         true,
+	"fail-fast":          true,
+	"fail-on-empty":      true,
+	"log-dir":            true,
+	"durations-file":     true,
+	"build-numbers-file": true,
+	"snapshot-pattern":   true,
+	"cache-dir":          true,
+	"remote-cache":       true,
+	"exclude-dir":        true,
+	"include-dir":        true,
+	"no-exclude-dirs":    true,
+	"similarity":         true,
+	"exclude-untracked":  true,
+	"skip-binary":        true,
+	"force-include":      true,
+	"force-exclude":      true,
+	"include-dependents": true,
+	"budget":             true,
+	"budget-policy":      true,
+	"trailers":           true,
+	"spec-file":          true,
+	"version-format":     true,
+	"terraform":          true,
+	"manifest-script":    true,
+	"odb-cache-size":     true,
+	"odb-mapped-limit":   true,
+	"preload-packs":      true,
+	"allow-env":          true,
+	"output":             true,
+	"no-color":           true,
+	"executor":           true,
+	"docker-image":       true,
+	"k8s-image":          true,
+	"k8s-namespace":      true,
+	"sbom-format":        true,
+	"sbom-scanner":       true,
+	"notify-slack":       true,
+	"notify-webhook":     true,
+	"notify-email":       true,
+	"smtp-server":        true,
+	"smtp-from":          true,
+	"offline":            true,
+}
+
+// configEnv returns the name of the environment variable setting the
+// default value of an option (e.g. MBT_CACHE_DIR for cache-dir).
+func configEnv(option string) string {
+	return "MBT_" + strings.ToUpper(strings.Replace(option, "-", "_", -1))
+}
+
+// applyConfig sets the options of cmd not specified in the command
+// line from the environment variables or the configuration file of
+// the repository, in that order of precedence.
+func applyConfig(cmd *cobra.Command) error {
+	config, err := lib.LoadConfig(in)
+	if err != nil {
+		return err
+	}
+
+	for k := range config {
+		if !configurableOptions[k] {
+			return e.NewErrorf(lib.ErrClassUser, "unknown option '%v' in %v", k, lib.ConfigFile)
+		}
+	}
+
+	options := make([]string, 0, len(configurableOptions))
+	for o := range configurableOptions {
+		options = append(options, o)
+	}
+	sort.Strings(options)
+
+	for _, o := range options {
+		f := cmd.Flags().Lookup(o)
+		if f == nil || f.Changed {
+			continue
+		}
+
+		v, ok := os.LookupEnv(configEnv(o))
+		if !ok {
+			v, ok = config[o]
+		}
+		if !ok {
+			continue
+		}
+
+		if err := cmd.Flags().Set(o, v); err != nil {
+			return e.NewErrorf(lib.ErrClassUser, "invalid value '%v' of %v: %v", v, o, err)
+		}
+	}
+
+	return nil
+}
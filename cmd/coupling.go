@@ -0,0 +1,71 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/mbtproject/mbt/lib"
+	"github.com/spf13/cobra"
+)
+
+var (
+	top int
+)
+
+func init() {
+	couplingCommand.Flags().StringVar(&from, "from", "", "Exclude the commits reachable from this commit (default entire history)")
+	couplingCommand.Flags().StringVar(&to, "to", "", "Include the commits reachable from this commit (default head of current branch)")
+	couplingCommand.Flags().IntVar(&top, "top", 10, "Number of module pairs and files to report")
+	couplingCommand.Flags().BoolVar(&toJSON, "json", false, "Format output as json")
+	RootCmd.AddCommand(couplingCommand)
+}
+
+var couplingCommand = &cobra.Command{
+	Use:   "coupling",
+	Short: docText("coupling-summary"),
+	Long:  docText("coupling"),
+	RunE: buildHandler(func(cmd *cobra.Command, args []string) error {
+		report, err := system.Coupling(from, to, &lib.CouplingOptions{Top: top})
+		if err != nil {
+			return err
+		}
+
+		if toJSON {
+			buff, err := json.MarshalIndent(report, "", "  ")
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(buff))
+			return nil
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 4, ' ', 0)
+		fmt.Fprintf(w, "Name\tCOUPLED WITH\tTOGETHER\tCOUPLING\n")
+		for _, p := range report.Pairs {
+			fmt.Fprintf(w, "%s\t%s\t%v\t%.2f\n", p.A, p.B, p.Together, p.Coupling)
+		}
+
+		fmt.Fprintf(w, "\nPath\tMODULES\tCHANGES\n")
+		for _, f := range report.Files {
+			fmt.Fprintf(w, "%s\t%v\t%v\n", f.Path, f.Modules, f.Changes)
+		}
+		return w.Flush()
+	}),
+}
@@ -0,0 +1,114 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"runtime/pprof"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mbtproject/mbt/e"
+	"github.com/mbtproject/mbt/lib"
+	"github.com/spf13/cobra"
+)
+
+var (
+	profiler    *lib.Profiler
+	pprofOutput string
+)
+
+func init() {
+	debugProfileCommand.Flags().StringVar(&pprofOutput, "pprof", "", "Write a CPU profile in pprof format to this file")
+	debugProfileCommand.Flags().BoolVar(&toJSON, "json", false, "Format output as json")
+
+	debugProfileCommand.Flags().StringVar(&src, "src", "", "Source branch")
+	debugProfileCommand.Flags().StringVar(&dst, "dst", "", "Destination branch")
+	debugProfileCommand.Flags().StringVar(&from, "from", "", "From commit")
+	debugProfileCommand.Flags().StringVar(&to, "to", "", "To commit")
+	debugProfileCommand.Flags().BoolVarP(&content, "content", "c", false, "Profile the manifest of the content of the commit")
+
+	debugCommand.AddCommand(debugProfileCommand)
+	RootCmd.AddCommand(debugCommand)
+}
+
+var debugCommand = &cobra.Command{
+	Use:   "debug",
+	Short: docText("debug-summary"),
+	Long:  docText("debug"),
+}
+
+type profileReport struct {
+	Mode    string
+	Modules int
+	Total   time.Duration
+	Phases  []*lib.PhaseStats
+}
+
+var debugProfileCommand = &cobra.Command{
+	Use:   "profile <branch|commit|diff|head|local|pr> [args]",
+	Short: docText("debug-profile-summary"),
+	Long:  docText("debug-profile"),
+	RunE: buildHandler(func(cmd *cobra.Command, args []string) error {
+		if len(args) < 1 {
+			return errors.New("requires the manifest to profile")
+		}
+
+		if pprofOutput != "" {
+			f, err := os.Create(pprofOutput)
+			if err != nil {
+				return e.Wrapf(lib.ErrClassUser, err, "failed to create the profile %v", pprofOutput)
+			}
+			defer f.Close()
+
+			if err := pprof.StartCPUProfile(f); err != nil {
+				return e.Wrap(lib.ErrClassInternal, err)
+			}
+			defer pprof.StopCPUProfile()
+		}
+
+		start := time.Now()
+		m, err := manifestByMode(args[0], args[1:])
+		if err != nil {
+			return err
+		}
+
+		report := &profileReport{Mode: args[0], Modules: len(m.Modules), Total: time.Since(start), Phases: profiler.Stats()}
+		if toJSON {
+			buff, err := json.MarshalIndent(report, "", "  ")
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(buff))
+			return nil
+		}
+
+		tw := tabwriter.NewWriter(os.Stdout, 0, 4, 4, ' ', 0)
+		fmt.Fprintf(tw, "Phase\tCALLS\tTOTAL\tMEAN\tMIN\tMAX\n")
+		for _, s := range report.Phases {
+			fmt.Fprintf(tw, "%s\t%v\t%v\t%v\t%v\t%v\n", s.Phase, s.Calls, s.Total, s.Mean(), s.Min, s.Max)
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+
+		fmt.Printf("\nCreated the manifest of %v modules in %v\n", report.Modules, report.Total)
+		return nil
+	}),
+}
@@ -0,0 +1,143 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+
+	"github.com/mbtproject/mbt/lib"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	deploymentsFile string
+)
+
+func init() {
+	deploymentsCommand.PersistentFlags().StringVar(&deploymentsFile, "deployments-file", "", "File to persist deployments (default .git/mbt/deployments.json)")
+	driftCommand.Flags().StringVar(&deploymentsFile, "deployments-file", "", "File to persist deployments (default .git/mbt/deployments.json)")
+
+	for _, c := range []*cobra.Command{deploymentsRecordCommand, driftCommand} {
+		c.Flags().StringVar(&src, "src", "", "Source branch")
+		c.Flags().StringVar(&dst, "dst", "", "Destination branch")
+		c.Flags().StringVar(&from, "from", "", "From commit")
+		c.Flags().StringVar(&to, "to", "", "To commit")
+		c.Flags().BoolVarP(&all, "all", "a", false, "All modules")
+		c.Flags().BoolVarP(&content, "content", "c", false, "Select the modules impacted by the content of the commit")
+		c.Flags().StringVarP(&name, "name", "n", "", "Select modules with a name that matches this value. Multiple names can be specified as a comma separated string.")
+		c.Flags().BoolVarP(&fuzzy, "fuzzy", "f", false, "Use fuzzy match when filtering")
+		c.Flags().StringVar(&filterExpr, "expr", "", "Filter modules with an expression")
+		c.Flags().StringVar(&owner, "owner", "", "Filter modules owned by this owner according to CODEOWNERS")
+		c.Flags().StringVar(&group, "group", "", "Filter modules in this group")
+	}
+
+	deploymentsCommand.AddCommand(deploymentsRecordCommand)
+	deploymentsCommand.AddCommand(deploymentsListCommand)
+	RootCmd.AddCommand(deploymentsCommand)
+	RootCmd.AddCommand(driftCommand)
+}
+
+var deploymentsCommand = &cobra.Command{
+	Use:   "deployments",
+	Short: docText("deployments-summary"),
+	Long:  docText("deployments"),
+}
+
+var deploymentsRecordCommand = &cobra.Command{
+	Use: "record <env> <branch|commit|diff|head|local|pr> [args]",
+	RunE: buildHandler(func(cmd *cobra.Command, args []string) error {
+		if len(args) < 2 {
+			return errors.New("requires the environment and the deployed modules")
+		}
+
+		m, err := manifestByMode(args[1], args[2:])
+		if err != nil {
+			return err
+		}
+
+		deployments, err := lib.RecordDeployments(deploymentStore(), args[0], m)
+		if err != nil {
+			return err
+		}
+
+		for _, d := range deployments {
+			logrus.Infof("DEPLOYED %s %s to %s", d.Module, d.Version, d.Environment)
+		}
+		return nil
+	}),
+}
+
+var deploymentsListCommand = &cobra.Command{
+	Use: "list <env>",
+	RunE: buildHandler(func(cmd *cobra.Command, args []string) error {
+		if len(args) == 0 {
+			return errors.New("requires the environment")
+		}
+
+		deployments, err := deploymentStore().Deployments(args[0])
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 4, ' ', 0)
+		fmt.Fprintf(w, "Name\tVERSION\tCOMMIT\tTIME\n")
+		for _, d := range deployments {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", d.Module, d.Version, d.Commit, d.Time.Format("2006-01-02T15:04:05Z07:00"))
+		}
+		return w.Flush()
+	}),
+}
+
+var driftCommand = &cobra.Command{
+	Use:   "drift <env> <branch|commit|diff|head|local|pr> [args]",
+	Short: docText("drift-summary"),
+	Long:  docText("drift"),
+	RunE: buildHandler(func(cmd *cobra.Command, args []string) error {
+		if len(args) < 2 {
+			return errors.New("requires the environment and the modules to compare with")
+		}
+
+		m, err := manifestByMode(args[1], args[2:])
+		if err != nil {
+			return err
+		}
+
+		drifts, err := lib.DetectDrift(deploymentStore(), args[0], m)
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 4, ' ', 0)
+		fmt.Fprintf(w, "Name\tDEPLOYED\tEXPECTED\tSTATUS\n")
+		for _, d := range drifts {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", d.Module, d.Deployed, d.Expected, d.Status)
+		}
+		return w.Flush()
+	}),
+}
+
+func deploymentStore() lib.DeploymentStore {
+	path := deploymentsFile
+	if path == "" {
+		path = filepath.Join(in, ".git", "mbt", "deployments.json")
+	}
+	return lib.NewFileDeploymentStore(path)
+}
@@ -16,10 +16,12 @@ limitations under the License.
 package cmd
 
 import (
+	"bufio"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
+	"strings"
 	"text/tabwriter"
 
 	"github.com/mbtproject/mbt/lib"
@@ -28,8 +30,15 @@ import (
 
 var (
 	toJSON     bool
+	toYAML     bool
+	toBinary   bool
+	namesOnly  bool
+	nullDelim  bool
 	toGraph    bool
+	toMermaid  bool
 	dependents bool
+	byGroup    bool
+	why        bool
 )
 
 func init() {
@@ -48,11 +57,21 @@ func init() {
 	describeCommitCmd.Flags().BoolVarP(&content, "content", "c", false, "Describe the modules impacted by the changes in commit")
 
 	describeCmd.PersistentFlags().BoolVarP(&fuzzy, "fuzzy", "f", false, "Use fuzzy match when filtering")
+	describeCmd.PersistentFlags().StringVar(&filterExpr, "expr", "", "Filter modules with an expression")
+	describeCmd.PersistentFlags().StringVar(&owner, "owner", "", "Filter modules owned by this owner according to CODEOWNERS")
+	describeCmd.PersistentFlags().StringVar(&group, "group", "", "Filter modules in this group")
 	describeCmd.PersistentFlags().StringVarP(&name, "name", "n", "", "Describe modules with a name that matches this value. Multiple names can be specified as a comma separated string.")
 
 	describeCmd.PersistentFlags().BoolVar(&toJSON, "json", false, "Format output as json")
+	describeCmd.PersistentFlags().BoolVar(&toYAML, "yaml", false, "Format output as yaml")
+	describeCmd.PersistentFlags().BoolVar(&toBinary, "binary", false, "Format output in binary manifest format")
+	describeCmd.PersistentFlags().BoolVarP(&namesOnly, "quiet", "q", false, "Output only the names of modules, one per line")
+	describeCmd.PersistentFlags().BoolVarP(&nullDelim, "null", "z", false, "Output only the names of modules, each terminated by a NUL character")
 	describeCmd.PersistentFlags().BoolVar(&toGraph, "graph", false, "Format output as dot graph")
+	describeCmd.PersistentFlags().BoolVar(&toMermaid, "mermaid", false, "Format output as mermaid graph")
 	describeCmd.PersistentFlags().BoolVar(&dependents, "dependents", false, "Output dependents on potential change")
+	describeCmd.PersistentFlags().BoolVar(&byGroup, "by-group", false, "Output modules grouped by their group")
+	describeCmd.PersistentFlags().BoolVar(&why, "why", false, "Output the reasons of selecting each module in a diff")
 
 	describeCmd.AddCommand(describeCommitCmd)
 	describeCmd.AddCommand(describeBranchCmd)
@@ -61,6 +80,7 @@ func init() {
 	describeCmd.AddCommand(describePrCmd)
 	describeCmd.AddCommand(describeIntersectionCmd)
 	describeCmd.AddCommand(describeDiffCmd)
+	describeCmd.AddCommand(describeVersionCmd)
 
 	RootCmd.AddCommand(describeCmd)
 }
@@ -83,7 +103,7 @@ var describeBranchCmd = &cobra.Command{
 			return err
 		}
 
-		m, err = m.ApplyFilters(&lib.FilterOptions{Name: name, Fuzzy: fuzzy, Dependents: dependents})
+		m, err = m.ApplyFilters(&lib.FilterOptions{Name: name, Fuzzy: fuzzy, Expr: filterExpr, Owner: owner, Group: group, Dependents: dependents})
 
 		if err != nil {
 			return err
@@ -101,7 +121,7 @@ var describeHeadCmd = &cobra.Command{
 			return err
 		}
 
-		m, err = m.ApplyFilters(&lib.FilterOptions{Name: name, Fuzzy: fuzzy, Dependents: dependents})
+		m, err = m.ApplyFilters(&lib.FilterOptions{Name: name, Fuzzy: fuzzy, Expr: filterExpr, Owner: owner, Group: group, Dependents: dependents})
 
 		if err != nil {
 			return err
@@ -126,7 +146,7 @@ var describeLocalCmd = &cobra.Command{
 				return err
 			}
 
-			m, err = m.ApplyFilters(&lib.FilterOptions{Name: name, Fuzzy: fuzzy, Dependents: dependents})
+			m, err = m.ApplyFilters(&lib.FilterOptions{Name: name, Fuzzy: fuzzy, Expr: filterExpr, Owner: owner, Group: group, Dependents: dependents})
 		} else {
 			m, err = system.ManifestByWorkspaceChanges()
 		}
@@ -155,7 +175,7 @@ var describePrCmd = &cobra.Command{
 			return err
 		}
 
-		m, err = m.ApplyFilters(&lib.FilterOptions{Name: name, Fuzzy: fuzzy, Dependents: dependents})
+		m, err = m.ApplyFilters(&lib.FilterOptions{Name: name, Fuzzy: fuzzy, Expr: filterExpr, Owner: owner, Group: group, Dependents: dependents})
 
 		if err != nil {
 			return err
@@ -189,7 +209,7 @@ var describeCommitCmd = &cobra.Command{
 			return err
 		}
 
-		m, err = m.ApplyFilters(&lib.FilterOptions{Name: name, Fuzzy: fuzzy, Dependents: dependents})
+		m, err = m.ApplyFilters(&lib.FilterOptions{Name: name, Fuzzy: fuzzy, Expr: filterExpr, Owner: owner, Group: group, Dependents: dependents})
 
 		if err != nil {
 			return err
@@ -250,7 +270,7 @@ var describeDiffCmd = &cobra.Command{
 			return err
 		}
 
-		m, err = m.ApplyFilters(&lib.FilterOptions{Name: name, Fuzzy: fuzzy, Dependents: dependents})
+		m, err = m.ApplyFilters(&lib.FilterOptions{Name: name, Fuzzy: fuzzy, Expr: filterExpr, Owner: owner, Group: group, Dependents: dependents})
 
 		if err != nil {
 			return err
@@ -263,31 +283,62 @@ var describeDiffCmd = &cobra.Command{
 const columnWidth = 30
 
 func output(mods lib.Modules) error {
+	if err := checkSelection(mods); err != nil {
+		return err
+	}
+
+	if namesOnly || nullDelim {
+		return outputNames(mods)
+	}
+
+	if byGroup {
+		return outputGroups(mods.Groups())
+	}
+
 	if toJSON {
-		m := make(map[string]map[string]interface{})
-		for _, a := range mods {
-			v := make(map[string]interface{})
-			v["Name"] = a.Name()
-			v["Path"] = a.Path()
-			v["Version"] = a.Version()
-			v["Properties"] = a.Properties()
-			m[a.Name()] = v
-		}
-		buff, err := json.MarshalIndent(m, "", "  ")
+		buff, err := json.MarshalIndent(mods.Document().Modules, "", "  ")
 		if err != nil {
 			return err
 		}
 		fmt.Println(string(buff))
+	} else if toYAML {
+		s, err := mods.SerializeAsYAML()
+		if err != nil {
+			return err
+		}
+		fmt.Print(s)
+	} else if toBinary {
+		b, err := mods.Document().MarshalBinary()
+		if err != nil {
+			return err
+		}
+		if _, err := os.Stdout.Write(b); err != nil {
+			return err
+		}
 	} else if toGraph {
 		if dependents {
 			fmt.Println(mods.GroupedSerializeAsDot())
 		} else {
 			fmt.Println(mods.SerializeAsDot())
 		}
+	} else if toMermaid {
+		if dependents {
+			fmt.Println(mods.GroupedSerializeAsMermaid())
+		} else {
+			fmt.Println(mods.SerializeAsMermaid())
+		}
 	} else {
 		w := tabwriter.NewWriter(os.Stdout, 0, 4, 4, ' ', 0)
-		fmt.Fprintf(w, "Name\tPATH\tVERSION\n")
+		if why {
+			fmt.Fprintf(w, "Name\tPATH\tVERSION\tSELECTED BECAUSE\n")
+		} else {
+			fmt.Fprintf(w, "Name\tPATH\tVERSION\n")
+		}
 		for _, a := range mods {
+			if why {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", a.Name(), a.Path(), a.Version(), orDash(selectionReasons(a)))
+				continue
+			}
 			fmt.Fprintf(w, "%s\t%s\t%s\n", a.Name(), a.Path(), a.Version())
 		}
 
@@ -298,3 +349,76 @@ func output(mods lib.Modules) error {
 
 	return nil
 }
+
+// selectionReasons returns the reasons of selecting a module
+// separated by commas.
+func selectionReasons(mod *lib.Module) string {
+	reasons := make([]string, 0)
+	for _, r := range mod.SelectionReasons() {
+		reasons = append(reasons, r.String())
+	}
+	return strings.Join(reasons, ", ")
+}
+
+// outputNames writes the names of modules each terminated by a new line
+// or a NUL character so that they can be piped to tools like xargs.
+func outputNames(mods lib.Modules) error {
+	terminator := "\n"
+	if nullDelim {
+		terminator = "\x00"
+	}
+
+	w := bufio.NewWriter(os.Stdout)
+	for _, n := range mods.Names() {
+		if _, err := w.WriteString(n + terminator); err != nil {
+			return err
+		}
+	}
+
+	return w.Flush()
+}
+
+func outputGroups(groups []*lib.ModuleGroup) error {
+	if toJSON {
+		m := make(map[string][]string)
+		for _, g := range groups {
+			m[g.Name] = g.Modules.Names()
+		}
+		buff, err := json.MarshalIndent(m, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(buff))
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 4, ' ', 0)
+	fmt.Fprintf(w, "Group\tMODULES\n")
+	for _, g := range groups {
+		fmt.Fprintf(w, "%s\t%s\n", orDash(g.Name), strings.Join(g.Modules.Names(), ", "))
+	}
+
+	return w.Flush()
+}
+
+var describeVersionCmd = &cobra.Command{
+	Use: "version <name> [ref]",
+	RunE: buildHandler(func(cmd *cobra.Command, args []string) error {
+		if len(args) == 0 {
+			return errors.New("requires the module name")
+		}
+
+		ref := "HEAD"
+		if len(args) > 1 {
+			ref = args[1]
+		}
+
+		v, err := system.ModuleVersion(args[0], ref)
+		if err != nil {
+			return err
+		}
+
+		fmt.Println(v)
+		return nil
+	}),
+}
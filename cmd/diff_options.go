@@ -0,0 +1,55 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import "github.com/mbtproject/mbt/lib"
+
+var (
+	diffOptions       = &lib.DiffOptions{}
+	noExcludeDirs     bool
+	forceInclude      []string
+	forceExclude      []string
+	includeDependents string
+	budget            int
+	budgetPolicy      string
+	trailers          []string
+)
+
+func init() {
+	RootCmd.PersistentFlags().IntVar(&diffOptions.SimilarityThreshold, "similarity", 0, "Detect renamed files at least this percent similar (0 disables rename detection)")
+	RootCmd.PersistentFlags().BoolVar(&diffOptions.ExcludeUntracked, "exclude-untracked", false, "Ignore untracked files in local workspace changes")
+	RootCmd.PersistentFlags().BoolVar(&diffOptions.SkipBinary, "skip-binary", false, "Ignore changes to binary files")
+	RootCmd.PersistentFlags().StringSliceVar(&diffOptions.ExcludeDirs, "exclude-dir", lib.DefaultExcludedDirs, "Names of the directories skipped when discovering modules and matching changes")
+	RootCmd.PersistentFlags().StringSliceVar(&diffOptions.IncludeDirs, "include-dir", nil, "Names or paths of the excluded directories to consider nevertheless")
+	RootCmd.PersistentFlags().BoolVar(&noExcludeDirs, "no-exclude-dirs", false, "Walk all directories ignoring --exclude-dir (including vendor and node_modules)")
+	RootCmd.PersistentFlags().StringSliceVar(&forceInclude, "force-include", nil, "Modules always included in the modules selected by changes")
+	RootCmd.PersistentFlags().StringSliceVar(&forceExclude, "force-exclude", nil, "Modules never included in the modules selected by changes")
+	RootCmd.PersistentFlags().StringVar(&includeDependents, "include-dependents", lib.DependentsTransitive, "Dependents of changed modules to include (none, direct, transitive or the maximum depth)")
+	RootCmd.PersistentFlags().IntVar(&budget, "budget", 0, "Maximum cost of the modules selected by changes (0 disables the budget)")
+	RootCmd.PersistentFlags().StringVar(&budgetPolicy, "budget-policy", lib.BudgetWarn, "Action taken when the budget is exceeded (warn or fail)")
+	RootCmd.PersistentFlags().StringSliceVar(&trailers, "trailers", nil, "Keys of the commit trailers collected from the commits changing each module (e.g. Ticket)")
+}
+
+// systemDiffOptions returns the diff options specified in the command line.
+func systemDiffOptions() *lib.DiffOptions {
+	if !noExcludeDirs {
+		return diffOptions
+	}
+
+	o := *diffOptions
+	o.ExcludeDirs = []string{}
+	return &o
+}
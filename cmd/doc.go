@@ -33,21 +33,73 @@ You can turn any directory into a module by placing a spec file called {{c ".mbt
 Spec file is written in {{c "yaml" }} following the schema specified below.
 
 {{c "" }}
+version: Version of the spec format, current version is 1 (optional)
 name: Unique module name (required)
 build: Dictionary of build commands specific to a platform (optional)
   default: (optional)
     cmd: Default command to run when os specific command is not found (required)
     args: Array of arguments to default build command (optional)
+    timeout: Maximum duration of the command e.g. 10m (optional)
+    retries: Number of times to retry the command on failure (optional)
+    when: Expression that must be true for the command to run (optional)
+    workDir: Directory the command runs in, relative to the module or to the repository root if it starts with / (optional)
   linux|darwin|windows:
     cmd: Operating system specific command name (required)
     args: Array of arguments (optional)
+    timeout: Maximum duration of the command e.g. 10m (optional)
+    retries: Number of times to retry the command on failure (optional)
+    when: Expression that must be true for the command to run (optional)
+    workDir: Directory the command runs in (optional)
+test: Dictionary of test commands specific to a platform in the same format as build (optional)
+hooks: Commands executed around the build command (optional)
+  pre: Command executed before the build command (optional)
+  post: Command executed after a successful build (optional)
+  onFailure: Command executed when the build fails (optional)
+matrix: Dictionary of arrays of values to build the module with (optional)
+resources: Resources required to build the module (optional)
+  weight: Share of the build capacity consumed by the build, default 1 (optional)
+  cpu: CPU requested by the build e.g. 500m (optional)
+  memory: Memory requested by the build e.g. 1Gi (optional)
+  label: Label of the agent the build should be routed to (optional)
+outputs: An array of files produced by the build, relative to the module directory (optional)
 dependencies: An array of modules that this module's build depend on (optional)
 fileDependencies: An array of file names that this module's build depend on (optional)
+lfs: Include the object ids of Git LFS files in the version, default false (optional)
+group: Group of the module e.g. business domain, default top level directory (optional)
+externalDependencies: An array of paths in other repositories that this module's build depend on (optional)
+  repo: URL of the repository (required)
+  path: Path in the repository, default entire repository (optional)
+  ref: Commit sha or tag the dependency is pinned at (required)
+  track: Ref checked for newer commits, default HEAD (optional)
+migrations: An array of patterns of migration files relative to the module directory, default [migrations/, db/migrate/] (optional)
+lifecycle: State of the module, one of active, deprecated or frozen, default active (optional)
+approvals: An array of groups required to approve the changes impacting the module (optional)
+cost: Weight of building the module compared to other modules, checked against --budget, default 1 (optional)
+semver: Semantic version of the module used in snapshot versions e.g. 1.2.3, default 0.0.0 (optional)
+tasks: Dictionary of named tasks e.g. lint, deploy (optional)
+  name:
+    cmd: Command name (required)
+    args: Array of arguments (optional)
+    os: Array of os identifiers where this task should run (optional)
+    timeout: Maximum duration of the command e.g. 10m (optional)
+    retries: Number of times to retry the command on failure (optional)
+    when: Expression that must be true for the command to run (optional)
+    dependsOn: Array of tasks executed before this task, ^ prefix refers to upstream modules (optional)
+    outputs: An array of files produced by the task (optional)
+    cache: Skip the task when its outputs are in the cache (optional)
+    workDir: Directory the task runs in (optional)
+sbom: Command writing the software bill of materials of the module to stdout (optional)
+  cmd: Command name (required)
+  args: Array of arguments (optional)
+  timeout: Maximum duration of the command e.g. 10m (optional)
 commands: Optional dictionary of custom commands (optional)
   name:
     cmd: Command name (required)
     args: Array of arguments (optional)
     os: Array of os identifiers where this command should run (optional)
+    timeout: Maximum duration of the command e.g. 10m (optional)
+    retries: Number of times to retry the command on failure (optional)
+    when: Expression that must be true for the command to run (optional)
 properties: Custom dictionary to hold any module specific information (optional)
 {{c ""}}
 
@@ -61,6 +113,167 @@ found {{link "here" "https://golang.org/doc/install/source#environment"}}
 When the command is applicable for multiple operating systems, you could list it as
 the default command. Operating system specific commands take precedence.
 
+Modules without a build command for the operating system but with a
+{{c "Dockerfile"}} in the module directory are built with
+{{c "docker build -t <name>:<version> ."}}, where the name is the lower case
+module name and the version is the module version. Specify a build command
+to override this behaviour.
+
+{{h2 "Work Directory"}}
+Commands run in the module directory by default. Use {{c "workDir"}} to run a
+command in another directory, relative to the module directory or to the
+repository root when it starts with {{c "/"}}. Relative command paths
+(e.g. {{c "./build.sh"}}) are always resolved against the module directory.
+For example, following command builds an image with the repository root as
+the build context.
+
+{{c ""}}
+build:
+  default:
+    cmd: docker
+    args: [build, -f, app-a/Dockerfile, .]
+    workDir: /
+{{c ""}}
+
+{{h2 "Timeouts and Retries"}}
+Commands can specify a {{c "timeout"}} using the duration format (e.g. {{c "90s"}}, {{c "10m"}}).
+When a command does not complete within that time, it is terminated along with
+all the processes started by it and the command is considered failed.
+Failed commands are retried as many times as specified in {{c "retries"}}.
+
+{{h2 "Conditional Commands"}}
+Commands can specify a {{c "when"}} expression (see Expressions).
+Command is executed only if the expression evaluates to {{c "true"}}.
+Modules are skipped when the expression of their build command evaluates
+to {{c "false"}}.
+Following variables are available to expressions.
+
+- {{c "app.name"}}, {{c "app.path"}}, {{c "app.version"}} and {{c "app.properties"}} of the module
+- {{c "app.dependencies"}} Names of the modules this module depends on
+- {{c "app.owners"}} Owners of the module according to CODEOWNERS
+- {{c "app.changedFiles"}} Files changed in the module
+- {{c "app.requiresMigration"}} True when the files changed in the module include migration files
+- {{c "app.changeClasses"}} Classes of the files changed in the module (see Change Classes)
+- {{c "changedFiles"}} Files changed in the repository
+- {{c "branch"}} Name of the branch being built
+- {{c "sha"}} Commit being built
+- {{c "env"}} Environment variables
+
+Changed files are available only when building a diff
+(e.g. {{c "mbt build diff"}} or {{c "mbt build pr"}}).
+For example, following expression runs a command only when a file in {{c "db"}}
+directory of the module is changed.
+
+{{c "when: app.changedFiles.exists(f, f.startsWith(app.path + '/db/'))"}}
+
+Files matching the {{c "migrations"}} patterns of the module are migration files.
+Patterns follow the syntax of CODEOWNERS patterns. Modules with changed migration
+files have {{c "RequiresMigration"}} set in the output of {{c "mbt describe"}}
+for a diff so that deployments can apply schema changes along with them.
+
+{{h2 "Expressions"}}
+Expressions used in {{c "when"}}, {{c "--expr"}} and expression policies are
+written in mbt's own expression language. Its syntax resembles
+{{link "CEL" "https://github.com/google/cel-spec"}} but it is not an implementation
+of CEL. Values are dynamically typed, there are no timestamps, durations or
+protocol buffer messages and int and double values are compared numerically
+(e.g. {{c "1 == 1.0"}} is {{c "true"}}).
+
+- Literals: {{c "null"}}, {{c "true"}}, {{c "false"}}, ints, doubles, quoted strings, lists ({{c "[1, 2]"}}) and maps ({{c "{'a': 1}"}})
+- Operators in the order of precedence: {{c "!"}} and unary {{c "-"}}, {{c "* / %"}}, {{c "+ -"}}, {{c "== != < <= > >= in"}}, {{c "&&"}}, {{c "||"}} and {{c "?:"}}
+- Field selection ({{c "a.b"}}), indexing ({{c "a[0]"}}, {{c "a['b']"}}) and {{c "has(a.b)"}}
+- Functions: {{c "size"}}, {{c "string"}}, {{c "startsWith"}}, {{c "endsWith"}}, {{c "contains"}} and {{c "matches"}} (Go regular expressions)
+- Macros: {{c "all"}}, {{c "exists"}}, {{c "exists_one"}}, {{c "filter"}} and {{c "map"}}
+
+{{h2 "Change Classes"}}
+Files changed in a module are classified as {{c "code"}}, {{c "docs"}}, {{c "config"}},
+{{c "tests"}} or {{c "migrations"}} and the classes present in the changes of each
+module are listed in {{c "ChangeClasses"}} of the output of {{c "mbt describe"}} for a diff.
+Rules mapping patterns to classes can be specified in {{c ".mbt/classes.yml"}}
+at the root of the repository. Patterns follow the syntax of CODEOWNERS patterns
+and are relative to the module directory. First matching rule takes precedence,
+migration files of the module are always classified as {{c "migrations"}} and
+files without a matching rule are classified as {{c "code"}}.
+
+{{c ""}}
+- class: docs
+  patterns: ["*.md", docs/]
+- class: tests
+  patterns: ["*_test.go", testdata/]
+{{c ""}}
+
+Change classes are empty unless building a diff. For example, following task does
+not deploy modules with documentation only changes.
+
+{{c ""}}
+tasks:
+  deploy:
+    cmd: ./deploy.sh
+    when: "size(app.changeClasses) == 0 || app.changeClasses.exists(c, c != 'docs')"
+{{c ""}}
+
+{{h2 "Environment Variables in Commands"}}
+Commands and their arguments can refer to environment variables as {{c "${NAME}"}}
+or {{c "${NAME:-default}"}}. Default value is used when the variable is unset or
+empty. References are expanded by mbt before executing the command, only for the
+variables allowed with {{c "--allow-env"}} option (e.g. {{c "--allow-env JAVA_HOME,GO_*"}}).
+References to other variables are passed to the command as they are.
+For example:
+
+{{c ""}}
+build:
+  default:
+    cmd: ${GRADLE_HOME:-/opt/gradle}/bin/gradle
+    args: [build, "-Dtoolchain=${JAVA_HOME}"]
+{{c ""}}
+
+{{h2 "Build Matrix"}}
+Modules can be built multiple times with different parameters by specifying a
+{{c "matrix"}}. Each combination of the values in the matrix is called a variant
+and the build command is executed once for every variant.
+For example, following matrix builds the module four times.
+
+{{c ""}}
+matrix:
+  goos: [linux, darwin]
+  goarch: [amd64, arm64]
+{{c ""}}
+
+Name of a variant is formed by joining its values with {{c "-"}} in the alphabetical
+order of the keys (e.g. {{c "amd64-linux"}}). Variant name is appended to the
+module version and is available in {{c "MBT_VARIANT"}} environment variable.
+Values of the variant are available in {{c "MBT_VARIANT_XXX"}} where {{c "XXX"}}
+denotes the key.
+
+{{h2 "Hooks"}}
+Build command of a module can be surrounded by {{c "hooks"}}. Each hook is specified
+in the same format as a build command ({{c "cmd"}}, {{c "args"}}, {{c "timeout"}} and
+{{c "retries"}}) and executed in the module directory with the same environment.
+Module is not built if {{c "pre"}} hook fails. {{c "post"}} hook is executed only
+after a successful build. {{c "onFailure"}} hook is executed when the build
+command or any of the other hooks fail.
+
+{{h2 "Tasks"}}
+In addition to build and test commands, modules can define named {{c "tasks"}}
+(e.g. {{c "lint"}}, {{c "deploy"}}) executed with {{c "mbt run <task>"}}.
+A task is applicable to the operating systems listed in {{c "os"}} or all of
+them if not specified. Tasks listed in {{c "dependsOn"}} are executed before the
+task, in the module directory. Build and test commands are available to
+{{c "dependsOn"}} as {{c "build"}} and {{c "test"}} tasks. Defining a task named
+{{c "build"}} or {{c "test"}} replaces the corresponding section of the spec.
+
+Prefix a task in {{c "dependsOn"}} with {{c "^"}} to refer to the task of the
+modules listed in {{c "dependencies"}} (e.g. {{c "dependsOn: [^build]"}} runs the
+{{c "build"}} task of the dependencies first). Tasks of upstream modules are
+executed in the order of module dependencies before the requested task, even
+if those modules are not selected (e.g. unchanged in a pull request).
+Upstream modules that do not define the task are ignored. Enable {{c "cache"}}
+in those tasks to restore their outputs instead of executing them again.
+
+When {{c "cache"}} is {{c "true"}}, outputs of the task are stored in the cache
+(see {{c "mbt build --help"}}) and the task is not executed again for the same
+module version. A task without outputs is cached to record its success.
+
 {{h2 "Dependencies"}}
 {{ c "mbt"}} comes with a set of primitives to manage build dependencies. Current build
 tools do a good job in managing dependencies between source files/projects.
@@ -105,6 +318,43 @@ As a result, version of a module is changed only when any of those attributes
 are changed making it a safe attribute to use for tagging the 
 build artifacts (i.e. tar balls, container images).
 
+Modules storing large files in Git LFS (e.g. models, assets) can set
+{{c "lfs: true"}} to include the object ids of the LFS files explicitly in
+the version. These ids are also available in templates via {{c ".LFSObjects"}}.
+Files checked out by Git LFS are considered modified in local workspace only
+when their content differs from the object referred by the pointer.
+
+Versions are 40 character hex strings by default. Use {{c "--version-format"}}
+to render them in a format accepted by registries with tag length constraints:
+{{c "full"}} (default), {{c "base36"}} (up to 31 characters) or the number of
+leading characters to keep (e.g. {{c "12"}} or {{c "7"}}). Rendered versions are
+used consistently in outputs, templates, build environment and caches. Shorter
+versions are more likely to collide, therefore prefer longer ones in large
+repositories.
+
+{{h2 "Manifest Scripts"}}
+Selection of modules and their properties can be customised with a script
+specified with {{c "--manifest-script"}} (or {{c "manifest-script"}} in the
+configuration file). Script is an executable written in any language (e.g.
+Python or Starlark via its interpreter), its path is relative to the repository
+root. It runs in the repository root and receives the manifest on stdin in the
+same format as {{c "mbt plugin"}}. Script writes the modules to keep to stdout.
+Properties returned for a module are merged into its properties.
+
+{{c ""}}
+{"Modules": {"app-a": {}, "app-b": {"Properties": {"replicas": 3}}}}
+{{c ""}}
+
+Modules not in the output are removed from the manifest.
+
+{{h2 "Diagnostics"}}
+Problems which do not prevent {{c "mbt"}} from creating the manifest are
+reported as warnings. These include specs without a name, modules nested in the
+directory of another module (changes to them also change the outer module),
+dependencies listed more than once and violations of policies with level
+{{c "warning"}}. Diagnostics of a module are included in the {{c "Diagnostics"}}
+field of its json and yaml output.
+
 {{h2 "Document Generation"}}
 {{ c "mbt" }} has a powerful feature that exposes the module state inferred from
 the repository to a template engine. This could be quite useful for generating
@@ -115,6 +365,72 @@ sha.
 
 See {{c "apply"}} command for more details.
 
+{{h2 "Exit Codes"}}
+{{c "0"}} Command succeeded.{{br}}
+{{c "1"}} Invalid usage or failed to create the manifest (e.g. invalid spec or unknown commit).{{br}}
+{{c "2"}} A command executed for a module (e.g. build or test) failed.{{br}}
+{{c "3"}} No modules are selected and {{c "--fail-on-empty"}} is specified.{{br}}
+
+Commands selecting modules (e.g. {{c "build"}}, {{c "test"}}, {{c "run"}},
+{{c "run-in"}}, {{c "describe"}} and {{c "generate"}}) succeed when no modules
+are selected unless {{c "--fail-on-empty"}} is specified.
+
+{{h2 "Configuration"}}
+Default values of frequently used options can be stored in {{c ".mbt/config.yml"}}
+at the root of the repository. Keys are the names of the options and lists are
+specified as yaml arrays. For example:
+
+{{c ""}}
+parallelism: 4
+cache-dir: /var/cache/mbt
+exclude-dir: [vendor, node_modules, third_party]
+output: prefix
+notify-slack: https://hooks.slack.com/services/xxx
+{{c ""}}
+
+Each option can also be set with an environment variable named after the option
+(e.g. {{c "MBT_CACHE_DIR"}} for {{c "cache-dir"}}). Options specified in the command
+line take precedence over environment variables, which take precedence over the
+configuration file.
+
+Following options can be configured: {{c "parallelism"}}, {{c "keep-going"}},
+{{c "fail-fast"}}, {{c "fail-on-empty"}}, {{c "log-dir"}}, {{c "durations-file"}}, {{c "build-numbers-file"}}, {{c "snapshot-pattern"}},
+{{c "cache-dir"}}, {{c "remote-cache"}}, {{c "exclude-dir"}}, {{c "include-dir"}}, {{c "no-exclude-dirs"}},
+{{c "similarity"}}, {{c "exclude-untracked"}}, {{c "skip-binary"}}, {{c "spec-file"}}, {{c "version-format"}}, {{c "allow-env"}},
+{{c "force-include"}}, {{c "force-exclude"}}, {{c "include-dependents"}}, {{c "budget"}}, {{c "budget-policy"}},
+{{c "trailers"}},
+{{c "terraform"}}, {{c "manifest-script"}}, {{c "odb-cache-size"}}, {{c "odb-mapped-limit"}}, {{c "preload-packs"}},
+{{c "output"}}, {{c "no-color"}}, {{c "executor"}}, {{c "docker-image"}},
+{{c "k8s-image"}}, {{c "k8s-namespace"}}, {{c "sbom-format"}}, {{c "sbom-scanner"}},
+{{c "notify-slack"}}, {{c "notify-webhook"}}, {{c "notify-email"}}, {{c "smtp-server"}},
+{{c "smtp-from"}} and {{c "offline"}}.
+
+Use {{c "--spec-file"}} to discover modules by a spec file name other than {{c ".mbt.yml"}}.
+
+{{h2 "Offline Mode"}}
+Use {{c "--offline"}} (or {{c "MBT_OFFLINE=true"}}) in air-gapped environments or to
+make sure a build does not depend on the network. Commands fail immediately with
+an error naming the option requiring network access when any of {{c "--pr"}},
+{{c "--remote-cache"}}, {{c "--notify-slack"}}, {{c "--notify-webhook"}},
+{{c "--notify-email"}}, {{c "--status-provider"}}, {{c "--sign"}} or the
+{{c "kubernetes"}} and {{c "ssh"}} executors is specified.
+
+Operations discovered to require network access later also fail instead of
+reaching the network. For example, resolving external dependencies pinned to a
+branch or a tag (pin them to a commit sha instead) and fetching objects missing
+in partial clones. Docker images are not pulled and signatures are verified
+without contacting the transparency log.
+
+{{h2 "Large Repositories"}}
+Git objects read while creating manifests are cached in memory. In repositories
+with millions of objects, raise {{c "--odb-cache-size"}} (in MB, 256 by default)
+so that trees are not read repeatedly and {{c "--odb-mapped-limit"}} to map more
+of the pack files into memory. {{c "--preload-packs"}} loads the indexes of all
+pack files upfront instead of on the first lookups. Use {{c "mbt debug profile"}}
+to measure the effect of these options. Commit-graph files are not used by the
+version of libgit2 mbt is built with, run {{c "git gc"}} to keep the number of
+pack files low instead.
+
 `,
 	"apply-summary": `Apply repository manifest over a go template`,
 	"apply": `{{cli "Apply repository manifest over a go template\n" }}
@@ -138,6 +454,24 @@ Apply the manifest of local workspace to a template.
 Template path should be relative to the repository root and must be available
 in the workspace.
 
+{{h2 "Dry Run"}}
+Use {{c "--dry-run"}} to print the output without writing it to the path
+specified by {{c "--out"}}.
+
+{{h2 "Commit Metadata"}}
+Templates can access the commit of the manifest via {{c ".Commit"}} with
+{{c "Sha"}}, {{c "Author"}}, {{c "AuthorEmail"}}, {{c "Time"}} (committer timestamp)
+and {{c "Subject"}} fields (e.g. {{c "{{ .Commit.Subject }}"}}). Similarly, the last
+commit changing the directory of a module is available via {{c ".LastCommit"}}
+of the module. Both are empty when applying the manifest of local workspace.
+
+Values of the commit trailers specified with {{c "--trailers"}} (e.g.
+{{c "--trailers Ticket,Deploy-Env"}}) in the commits changing a module since the
+base of a diff are available via {{c ".Trailers"}} of the module, a map from
+the trailer key to its values (e.g. {{c "{{ index .Trailers \"Ticket\" }}"}}).
+They are also included in {{c "Trailers"}} of json, yaml and binary outputs of
+{{c "mbt describe"}}.
+
 {{h2 "Template Helpers"}}
 Following helper functions are available when writing templates.
 
@@ -150,6 +484,21 @@ Find the specified property in the given module. Standard dot notation can be us
 {{ c "propertyOr <module> <name> <default>" }}{{br}}
 Find specified property in the given module or return the designated default value.
 
+Properties can also be converted to a type with the {{ c "GetString" }}, {{ c "GetInt" }},
+{{ c "GetBool" }} and {{ c "GetStringSlice" }} methods of a module, which take a dotted path
+and a default value (e.g. {{ c "{{ (module \"app-a\").GetInt \"kubernetes.replicas\" 1 }}" }}).
+Numbers and booleans specified as strings are converted.
+
+{{ c "imageTag <module> <registry> <pattern>" }}{{br}}
+Return the image reference of the given module. Pattern can use {{ c "{registry}" }}, {{ c "{name}" }},
+{{ c "{version}" }}, {{ c "{group}" }} and {{ c "{property:<name>}" }} placeholders and defaults to
+{{ c "{registry}/{name}:{version}" }} when empty. Names are converted to lower case and characters
+not allowed in image references are replaced with dashes.
+
+{{ c "snapshotVersion <module> <pattern>" }}{{br}}
+Return the snapshot version of the given module (see {{ c "mbt build --help" }}). Pattern defaults to
+{{ c "{semver}-{date}.{buildNumber}+{sha}" }} when empty. Build number is 0 when applying templates.
+
 {{ c "contains <array> <item>" }}{{br}}
 Return true if the given item is present in the array.
 
@@ -185,6 +534,23 @@ Return true if the specified value is the tail of the array/slice.
 
 These functions can be pipelined to simplify complex template expressions. Below is an example of emitting the word "foo"
 when module "app-a" has the value "a" in it's tags property.
+`,
+	"audit-summary": `Report problems in module specs`,
+	"audit": `{{cli "Report problems in module specs \n"}}
+{{c "mbt audit [--json] [--known-property <name>]"}}{{br}}
+Report the following problems in the modules of the current workspace.
+
+{{c "no-build-command"}} Module does not have a build command{{br}}
+{{c "no-owners"}} Module does not have an owner in {{c "CODEOWNERS"}}{{br}}
+{{c "undeclared-dependency"}} Module refers to the files of another module without declaring it as a dependency{{br}}
+{{c "stale-property"}} Module property is not referenced by the module
+
+References to other modules are detected by looking for their paths in the
+string literals of module files (e.g. import paths), hence the report may
+include false positives. A property is considered referenced if a module file
+(including {{c ".mbt.yml"}}) refers to it as {{c "MBT_MODULE_PROPERTY_XXX"}} or
+{{c "properties.xxx"}}. Use {{c "--known-property"}} to exclude the properties
+consumed elsewhere (e.g. in templates).
 `,
 	"build-summary": `Run build command`,
 	"build": `{{cli "Run build command \n"}}
@@ -223,6 +589,86 @@ built if {{c "--all"}} option is specified.
 Build just the modules matching the {{c "--name"}} filter if specified.
 Default {{c "--name"}} filter is a prefix match. You can change this to a subsequence
 match by using {{c "--fuzzy"}} option.
+Changes in this mode include staged and untracked files. Untracked files matching
+the rules in {{c ".gitignore"}} or {{c ".mbtignore"}} (a file in the root of the
+repository using the same format) are not considered as changes. Use
+{{c ".mbtignore"}} to exclude the build outputs mbt should not see without
+affecting git.
+
+{{h2 "Expression Filter"}}
+Use {{c "--expr <expression>"}} along with the commands supporting {{c "--name"}}
+filter to select the modules satisfying an expression. Expressions have access
+to the same variables as {{c "when"}} expressions (see {{c "mbt --help"}}).
+For example, {{c "--expr \"app.properties.team == 'payments'\""}}
+
+{{h2 "Owner Filter"}}
+Use {{c "--owner <owner>"}} along with the commands supporting {{c "--name"}}
+filter to select the modules owned by an owner according to the {{c "CODEOWNERS"}}
+file in the repository (e.g. {{c "--owner @org/payments"}}). Owners of a module are
+determined by the last rule matching the module directory.
+
+{{h2 "Group Filter"}}
+Use {{c "--group <group>"}} along with the commands supporting {{c "--name"}}
+filter to select the modules in a group (e.g. {{c "--group payments"}}). Group of a
+module is specified with {{c "group"}} in the spec and defaults to the top level
+directory of the module.
+
+{{h2 "Pull Requests"}}
+Use {{c "--pr <id>"}} instead of {{c "--src"}} and {{c "--dst"}} to build a pull request
+without fetching it manually. mbt looks up the pull request with the API of
+{{c "--pr-provider"}} and fetches its head and destination branch from {{c "--pr-remote"}}.
+
+For Bitbucket Server ({{c "--pr-provider bitbucket"}}), the head is fetched from
+{{c "refs/pull-requests/<id>/from"}}. Specify {{c "--pr-api <url>"}} and
+{{c "--pr-repo <project>/<slug>"}} to look up the destination branch or
+{{c "--dst"}} to skip the lookup. For Bitbucket Cloud ({{c "--pr-provider bitbucket-cloud"}}),
+specify {{c "--pr-repo <workspace>/<slug>"}} and the source branch is fetched instead.
+
+For Gerrit ({{c "--pr-provider gerrit"}}), specify the change number optionally
+followed by the patch set (e.g. {{c "--pr 1234/5"}}). The patch set is fetched from
+{{c "refs/changes/.."}} and the target branch of the change is used as {{c "--dst"}}.
+Specify {{c "--pr-api <url>"}} to look up the current patch set and the target branch.
+
+Access token is read from {{c "MBT_PR_TOKEN"}} environment variable and used as
+a bearer token for both API and git requests. Specify {{c "--pr-user"}} to use basic
+authentication instead (e.g. Bitbucket Cloud app passwords or Gerrit HTTP passwords).
+
+{{h2 "Change Detection"}}
+Following options change how modified files are detected by the commands
+comparing trees (i.e. {{c "branch"}}, {{c "commit"}}, {{c "diff"}}, {{c "pr"}} and {{c "local"}}).
+
+- {{c "--similarity <percent>"}} Detect renamed files at least this similar. Renamed
+files are reported once and change both the source and the destination module.
+- {{c "--skip-binary"}} Ignore changes to binary files (e.g. generated or vendored assets).
+- {{c "--exclude-untracked"}} Ignore untracked files in {{c "local"}} mode.
+- {{c "--exclude-dir <name>"}} Skip the directories with this name when discovering
+modules and matching changes (default {{c "vendor"}} and {{c "node_modules"}}).
+- {{c "--include-dir <name|path>"}} Consider an excluded directory nevertheless
+(e.g. {{c "--include-dir app-a/vendor"}}).
+- {{c "--no-exclude-dirs"}} Walk all directories, which was the behaviour before
+directories were excluded by default.
+
+Changes in excluded directories do not select the modules containing them.
+However, the version of a module is computed from the entire module directory,
+therefore such changes still change the version of the module. Use
+{{c "--include-dir"}} or {{c "--no-exclude-dirs"}} when the content of these
+directories is committed and must trigger builds (e.g. vendored Go dependencies).
+
+Following options override the modules selected by the changes in
+{{c "commit --content"}}, {{c "diff"}}, {{c "pr"}} and {{c "local"}} modes.
+
+- {{c "--force-include <name>"}} Always include the module (e.g. smoke test suites).
+- {{c "--force-exclude <name>"}} Never include the module (e.g. deprecated modules),
+even if it depends on a changed module. Exclusion takes precedence over inclusion.
+- {{c "--include-dependents <policy>"}} Modules depending on the changed modules
+to include: {{c "none"}}, {{c "direct"}}, {{c "transitive"}} (default) or the
+maximum depth of the dependency chain (e.g. {{c "2"}} includes the dependents of
+direct dependents). Use {{c "none"}} or {{c "direct"}} for pre-merge checks when
+building all transitive dependents is too expensive.
+- {{c "--budget <cost>"}} Maximum sum of the {{c "cost"}} of the selected modules.
+Use it to discourage repository wide changes landing in a single pull request.
+- {{c "--budget-policy <warn|fail>"}} Warn (default) or fail when the budget is
+exceeded.
 
 {{h2 "Build Environment"}}
 
@@ -231,12 +677,219 @@ used by the command being executed.
 
 - {{c "MBT_MODULE_NAME"}} Name of the module
 - {{c "MBT_MODULE_PATH"}} Relative path to the module directory
+- {{c "MBT_MODULE_GROUP"}} Group of the module
 - {{c "MBT_MODULE_VERSION"}} Module version
 - {{c "MBT_BUILD_COMMIT"}} Git commit SHA of the commit being built
 - {{c "MBT_REPO_PATH"}} Absolute path to the repository directory
+- {{c "MBT_BUILD_NUMBER"}} Build number of the module (see Build Numbers)
+- {{c "MBT_SNAPSHOT_VERSION"}} Snapshot version of the module (see Snapshot Versions)
 
 In addition to the variables listed above, module properties are also populated 
 in the form of {{c "MBT_MODULE_PROPERTY_XXX"}} where {{c "XXX"}} denotes the key.
+
+{{h2 "Build Numbers"}}
+
+Use {{c "--build-numbers-file <path>"}} to issue a monotonically increasing
+build number for each module built, starting from 1. Last build number of each
+module is persisted in the specified json file, therefore the file should be
+kept across builds (e.g. in a shared volume of the CI agents). Builds sharing
+the file take turns to update it via a lock on {{c "<path>.lock"}}, hence the file
+system must support file locks. Build numbers are useful for artifact naming
+schemes requiring integers and are recorded with {{c "--record-notes"}}.
+
+{{h2 "Snapshot Versions"}}
+
+Use {{c "--snapshot-pattern <pattern>"}} to issue a snapshot version for each
+module built, in the format expected by ecosystems such as Maven and NuGet.
+Following placeholders are replaced in the pattern.
+
+{{c "{semver}"}} {{c "semver"}} in the module spec (default {{c "0.0.0"}}){{br}}
+{{c "{date}"}} Date the build started in UTC ({{c "yyyyMMdd"}}){{br}}
+{{c "{time}"}} Time the build started in UTC ({{c "HHmmss"}}){{br}}
+{{c "{buildNumber}"}} Build number of the module (see Build Numbers){{br}}
+{{c "{sha}"}} Abbreviated sha of the commit being built{{br}}
+{{c "{version}"}} Module version{{br}}
+{{c "{name}"}} Module name
+
+For example, {{c "--snapshot-pattern {semver}-{date}.{buildNumber}+{sha}"}}
+issues versions like {{c "1.2.3-20240601.1+4b9a0d4"}}.
+
+{{h2 "Parallel Builds"}}
+
+Use {{c "--parallelism <n>"}} to build modules concurrently. A module is built
+only after the modules it depends on. Each module consumes a share of the
+capacity equal to its {{c "resources.weight"}} (default 1), therefore heavy
+builds do not oversubscribe the host. A module heavier than the capacity is
+built when no other module is being built.
+
+Duration of each module build is recorded in {{c ".git/mbt/durations.json"}}
+(see {{c "--durations-file"}}). In parallel builds, modules on the longest
+estimated chain of dependent builds are started first.
+
+{{h2 "Caching"}}
+
+Outputs of a module build can be cached by declaring them in {{c "outputs"}}
+of the spec. Entries are paths relative to the module directory and may contain
+glob patterns. Directories are cached along with their content.
+
+Use {{c "--cache-dir <path>"}} to cache outputs in a local directory and
+{{c "--remote-cache <url>"}} to share them via an HTTP server. Entries are read
+with {{c "GET"}} and written with {{c "PUT"}} requests to {{c "<url>/<key>"}}.
+When both are specified, outputs found in the remote cache are copied to the
+local cache.
+
+Outputs are cached for each module version and platform. When the outputs of
+a module version are found in the cache, they are restored into the module
+directory instead of building the module. Hooks are not executed for
+restored modules. Failures to read or write the cache are reported and
+the module is built as usual.
+
+{{h2 "Reproducible Builds"}}
+
+Use {{c "--verify-reproducible"}} to find the modules with nondeterministic builds.
+Each module is built twice and the SHA-256 digests of its {{c "outputs"}} are
+compared. Outputs of the first build are removed before the second one so
+that they cannot be reused. Outputs are not restored from the cache in this mode.
+
+The second build is executed with the executor specified by {{c "--reproducible-executor"}}
+(e.g. {{c "--executor host --reproducible-executor docker"}}) or {{c "--executor"}}.
+The result is reported for each module along with the outputs which differ
+between the builds and the command fails when any of the builds is not
+reproducible. Modules without outputs are reported as unverified.
+
+{{h2 "Software Bill of Materials"}}
+
+Use {{c "--sbom-dir <path>"}} to generate a software bill of materials (SBOM) for
+each module built. SBOMs are written to {{c "<path>/<module>-<version>.<format>.json"}}
+in the format specified by {{c "--sbom-format"}} ({{c "cyclonedx"}} or {{c "spdx"}}).
+
+SBOM of a module is read from the stdout of the {{c "sbom"}} command in its spec.
+Modules without an {{c "sbom"}} command are scanned with the scanner specified by
+{{c "--sbom-scanner"}} (default {{c "syft"}}), which is executed in the module
+directory as {{c "<scanner> dir:. -o <format>-json"}}. Build of a module fails
+if its SBOM cannot be generated.
+
+{{h2 "Artifact Manifest"}}
+
+Use {{c "--artifacts-file <path>"}} to write a json document listing the files
+matching the {{c "outputs"}} of each module built along with their SHA-256 digests
+and the path to its SBOM. When {{c "--sign"}} is specified, the artifact manifest
+is signed with cosign keyless signing. Use {{c "mbt verify"}} to validate artifacts
+against the manifest.
+
+{{h2 "Provenance"}}
+
+Use {{c "--provenance-dir <dir>"}} to write an in-toto statement with SLSA provenance
+for each module built with artifacts. The subjects of the statement are the
+artifacts of the module with their SHA-256 digests. Its dependencies are the
+commit of the repository and the versions of the modules it depends on.
+Use {{c "--builder-id"}} to identify the platform running the build and
+{{c "--repo-uri"}} to record the URI of the repository.
+
+Specify {{c "--sign"}} to sign each statement (and the artifact manifest) with cosign keyless signing.
+The signature bundle is written next to the statement with {{c ".bundle"}} extension.
+
+{{h2 "Build Results in Git Notes"}}
+
+Use {{c "--record-notes"}} to record the status, duration and artifact digests of
+each module as a git note attached to the built commit. Notes are stored in
+{{c "refs/notes/mbt"}} unless {{c "--notes-ref"}} is specified. Results of subsequent
+builds of the same commit are merged with the recorded results. Push the notes
+reference (e.g. {{c "git push origin refs/notes/mbt"}}) to share them and use
+{{c "mbt builds"}} to display them.
+
+{{h2 "Commit Statuses"}}
+
+Use {{c "--status-provider <github|gitlab|bitbucket>"}} to publish the build result
+of each module as a commit status on the built commit. Statuses are named
+{{c "mbt/<module>"}}; use {{c "--status-context"}} to change the prefix or
+{{c "--status-aggregate"}} to publish a single status for all modules.
+Specify the repository with {{c "--status-repo"}} (e.g. {{c "owner/name"}} for GitHub,
+project path for GitLab and {{c "workspace/slug"}} for Bitbucket) and the access
+token with {{c "MBT_STATUS_TOKEN"}} environment variable. Use {{c "--status-api"}}
+for self-hosted instances and {{c "--status-url"}} to link the statuses to the CI job.
+
+Statuses are published only when the build produces a summary, hence use
+{{c "--keep-going"}} to publish the statuses of failed builds. Failures to
+publish statuses do not fail the build.
+
+{{h2 "Notifications"}}
+
+Build results can be sent to the owners of modules according to the
+{{c "CODEOWNERS"}} file in the repository. A notification listing the modules
+built, failed and skipped is sent for each owner. Modules without an owner
+are reported in a separate notification.
+
+Use {{c "--notify-slack <url>"}} to post notifications to a Slack incoming webhook
+and {{c "--notify-webhook <url>"}} to post them as json to any other service.
+Use {{c "--notify-email <address>"}} along with {{c "--smtp-server"}} and
+{{c "--smtp-from"}} to send them by email. Owners specified as email addresses
+in {{c "CODEOWNERS"}} receive their notifications in addition to the
+specified addresses. Failures to send notifications do not fail the build.
+
+{{h2 "Output"}}
+
+Use {{c "--output prefix"}} to prefix each line written by build commands with the name of
+the module. Prefixes are highlighted unless {{c "--no-color"}} is specified.
+Use {{c "--output group"}} to hold the output of each module until its build is complete
+and write it at once.
+
+{{h2 "Log Files"}}
+
+Use {{c "--log-dir <path>"}} to write the build output of each module to
+{{c "<path>/<module name>/<module version>.<task>.log"}} in addition to the console.
+Each task (e.g. {{c "build"}}, {{c "test"}} and the tasks they depend on) is
+written to its own log file.
+Paths to log files are listed once the build is complete.
+
+{{h2 "Dry Run"}}
+
+Use {{c "--dry-run"}} to print the modules that would be built, in the order they
+would be built, along with the build command and environment of each module.
+Nothing is executed and the workspace is not modified in a dry run.
+
+{{h2 "Failures"}}
+
+By default, build is aborted on the first module build failure.
+Use {{c "--keep-going"}} to continue building the remaining modules instead.
+Modules depending on a failed module are skipped. Once the build is
+complete, {{c "mbt"}} reports the status of each module and exits with an error
+listing the modules failed to build.
+
+{{h2 "Executors"}}
+
+By default, build commands are executed in the host running {{c "mbt"}}.
+Executors apply to {{c "mbt build"}}, {{c "mbt test"}}, {{c "mbt run"}} and
+{{c "mbt run-in"}} alike.
+
+Use {{c "--executor docker"}} to execute module builds in containers created from
+the image specified by {{c "--docker-image"}}. Repository is mounted into the
+container at {{c "--docker-workdir"}} (default {{c "/workspace"}}).
+
+Use {{c "--executor kubernetes"}} to schedule each module build as a Kubernetes Job
+instead. Jobs are created with {{c "kubectl"}} in the namespace specified by
+{{c "--k8s-namespace"}} using the image specified by {{c "--k8s-image"}}.
+Logs of each job are streamed back as the build progresses.
+Module {{c "resources.cpu"}} and {{c "resources.memory"}} take precedence over
+{{c "--k8s-cpu"}} and {{c "--k8s-memory"}}.
+
+Image is expected to contain the repository at {{c "--k8s-workdir"}} (default {{c "/workspace"}}).
+Alternatively, specify {{c "--k8s-repo-url"}} to clone the repository into the job
+workspace at the commit being built.
+
+Use {{c "--executor ssh"}} to execute module builds in remote hosts over ssh.
+Each remote host must have a clone of the repository at {{c "--ssh-repo-dir"}}.
+Before running the first command in a remote host, {{c "mbt"}} fetches the remote
+specified by {{c "--ssh-remote"}} and checks out the commit being built into a new
+worktree under {{c ".git/mbt-worktrees"}} of that clone. Commands of the build
+run in that worktree, so concurrent builds sharing a remote host do not interfere
+with each other. Worktrees older than a day are removed when a new one is created.
+Changes in the workspace cannot be built with this executor since they are not
+available in remote hosts.
+Remote host of a module is selected by matching the {{c "resources.label"}} or
+the {{c "executorLabel"}} property in its spec against the labels specified
+in {{c "--ssh-hosts"}}.
+Modules without that property are built in {{c "--ssh-default-host"}}.
 `,
 	"describe-summary": `Describe repository manifest`,
 	"describe": `{{cli "Describe repository manifest \n"}}
@@ -276,12 +929,601 @@ Describe just the modules matching the {{c "--name"}} filter if specified.
 Default {{c "--name"}} filter is a prefix match. You can change this to a subsequence
 match by using {{c "--fuzzy"}} option.
 
+{{c "mbt describe version <name> [ref]"}}{{br}}
+Print the version of a module at a branch, tag or commit (default {{c "HEAD"}}).
+This is cheaper than describing all modules since the manifest is not built.
+
+{{h2 "Expression Filter"}}
+Use {{c "--expr <expression>"}} along with the commands supporting {{c "--name"}}
+filter to select the modules satisfying an expression. Expressions have access
+to the same variables as {{c "when"}} expressions (see {{c "mbt --help"}}).
+For example, {{c "--expr \"app.properties.team == 'payments'\""}}
+
+{{h2 "Owner Filter"}}
+Use {{c "--owner <owner>"}} along with the commands supporting {{c "--name"}}
+filter to select the modules owned by an owner according to the {{c "CODEOWNERS"}}
+file in the repository (e.g. {{c "--owner @org/payments"}}). Owners of a module are
+determined by the last rule matching the module directory.
+
+{{h2 "Group Filter"}}
+Use {{c "--group <group>"}} along with the commands supporting {{c "--name"}}
+filter to select the modules in a group (e.g. {{c "--group payments"}}). Group of a
+module is specified with {{c "group"}} in the spec and defaults to the top level
+directory of the module.
+
+{{h2 "Selection Reasons"}}
+Modules in a diff (e.g. {{c "mbt describe pr"}}) are listed with the reasons
+of selecting them in {{c "SelectedBecause"}} of json, yaml and binary outputs.
+Use {{c "--why"}} option to list the reasons in the default output. A module is
+selected because:
+
+{{c "own files changed"}} Files in the module directory changed{{br}}
+{{c "file dependency <path> changed"}} A file matching one of its {{c "fileDependencies"}} changed{{br}}
+{{c "dependency <name> changed"}} A module it depends on is selected{{br}}
+{{c "forced"}} It is included with {{c "--force-include"}}
+
+{{h2 "Lifecycle"}}
+Specify {{c "lifecycle"}} in a module spec to track its state. A module is
+{{c "active"}} by default. When a {{c "deprecated"}} module is selected by the
+changes in a diff, a warning is reported in its diagnostics. A diff fails when
+the files of a {{c "frozen"}} module (or its {{c "fileDependencies"}}) change.
+Frozen modules can still be selected as dependents.
+
 {{h2 "Output Formats"}}
 Use {{c "--graph"}} option to output the manifest in graphviz dot format. This can
 be useful to visualise build dependencies.
 
+Use {{c "--mermaid"}} option to output the same graph in mermaid format. Mermaid
+graphs are rendered in GitHub and GitLab markdown (e.g. in pull request comments)
+without installing graphviz. Both formats highlight the impacted modules along with
+{{c "--dependents"}} option.
+
 Use {{c "--json"}} option to output the manifest in json format.
 
+Use {{c "--yaml"}} option to output the manifest in yaml format. Modules are
+listed under {{c "Modules"}} with the same schema as json output and
+{{c "SchemaVersion"}} identifies the version of the schema. The version is
+incremented only when the schema changes in a backward incompatible way.
+
+Use {{c "--binary"}} option to output the manifest in a compact binary format
+with the same schema as yaml output. The format uses protocol buffers wire
+encoding so that it can be decoded without mbt (see {{c "lib/manifest_binary.go"}}
+for the message definitions).
+
+Use {{c "--by-group"}} option to list the modules of each group (e.g.
+{{c "mbt describe diff --from <commit> --to <commit> --by-group"}} to find the
+groups impacted by a change).
+
+Use {{c "--quiet"}} ({{c "-q"}}) option to output only the names of modules, one per
+line, and {{c "--null"}} ({{c "-z"}}) option to terminate each name with a NUL character
+instead. Nothing is written when there are no modules. For example:
+
+{{c "mbt describe pr --src feature --dst master -z | xargs -0 -n1 echo"}}{{br}}
+
+`,
+	"explain-summary": `Explain why a module is selected by changes`,
+	"explain": `{{cli "Explain why a module is selected by changes \n"}}
+{{c "mbt explain pr --src <branch> --dst <branch> --app <name> [--format <format>]"}}{{br}}
+{{c "mbt explain diff --from <commit> --to <commit> --app <name> [--format <format>]"}}{{br}}
+{{c "mbt explain commit <commit> --content --app <name> [--format <format>]"}}{{br}}
+{{c "mbt explain local --app <name> [--format <format>]"}}{{br}}
+Output the graph of the paths from the changed files to a module through its
+dependencies. Modules are selected in the same way as {{c "mbt describe"}} and
+each path follows the reasons of selecting a module (see {{c "--why"}} option of
+{{c "mbt describe"}}). Graph includes only the modules impacting {{c "--app"}}.
+
+Use {{c "--format"}} to specify the format of the graph: {{c "dot"}} (default),
+{{c "mermaid"}} to render it in GitHub or GitLab markdown or {{c "json"}}.
+`,
+	"test-summary": `Run test command`,
+	"test": `{{cli "Run test command \n"}}
+Test commands are specified in {{c "test"}} section of the spec in the same
+format as build commands (see {{c "mbt --help"}}). Modules without a test
+command for the host platform are skipped.
+
+{{c "mbt test branch [name] [--name <name>] [--fuzzy]"}}{{br}}
+Test modules in a branch. Assume master if branch name is not specified.
+
+{{c "mbt test commit <commit> [--content] [--name <name>] [--fuzzy]"}}{{br}}
+Test modules in a commit. Full commit sha is required.
+Test just the modules impacted by the commit when {{c "--content"}} flag is used.
+
+{{c "mbt test diff --from <commit> --to <commit>"}}{{br}}
+Test modules changed between {{c "from"}} and {{c "to"}} commits and the
+modules depending on them.
+
+{{c "mbt test head [--name <name>] [--fuzzy]"}}{{br}}
+Test modules in current head.
+
+{{c "mbt test pr --src <name> --dst <name>"}}{{br}}
+Test modules changed between {{c "--src"}} and {{c "--dst"}} branches and the
+modules depending on them.
+
+{{c "mbt test local [--all] [--name <name>] [--fuzzy]"}}{{br}}
+Test modules modified in current workspace. All modules in the workspace are
+tested if {{c "--all"}} option is specified.
+
+Filters, execution environment and options such as {{c "--parallelism"}},
+{{c "--keep-going"}}, {{c "--log-dir"}} and {{c "--dry-run"}} work the same way as
+they do in {{c "mbt build"}}. Tests are executed in the order of module dependencies.
+Build hooks and output caching are not applicable to tests.
+`,
+	"run-summary": `Run a task`,
+	"run": `{{cli "Run a task \n"}}
+{{c "mbt run <task> branch [name] [--name <name>] [--fuzzy]"}}{{br}}
+{{c "mbt run <task> commit <commit> [--content] [--name <name>] [--fuzzy]"}}{{br}}
+{{c "mbt run <task> diff --from <commit> --to <commit>"}}{{br}}
+{{c "mbt run <task> head [--name <name>] [--fuzzy]"}}{{br}}
+{{c "mbt run <task> local [--all] [--name <name>] [--fuzzy]"}}{{br}}
+{{c "mbt run <task> pr --src <name> --dst <name>"}}{{br}}
+Run the named task of the modules selected the same way as {{c "mbt build"}}.
+Modules that do not define the task are skipped. Build and test commands
+can be run as {{c "build"}} and {{c "test"}} tasks.
+
+Tasks are executed in the order of module dependencies. Options such as
+{{c "--parallelism"}}, {{c "--keep-going"}}, {{c "--log-dir"}}, {{c "--cache-dir"}}
+and {{c "--dry-run"}} work the same way as they do in {{c "mbt build"}}.
+See {{c "mbt --help"}} for defining tasks.
+`,
+	"generate-summary": `Generate deployment manifests`,
+	"generate": `{{cli "Generate deployment manifests \n"}}
+{{c "mbt generate kubernetes <branch|commit|diff|head|local|pr> [args] [--registry <registry>] [--namespace <namespace>] [--out <path>]"}}{{br}}
+{{c "mbt generate kustomize <branch|commit|diff|head|local|pr> [args] [--registry <registry>] [--namespace <namespace>] [--out <path>]"}}{{br}}
+{{c "mbt generate helm-values <branch|commit|diff|head|local|pr> [args] [--property <name>] [--split] [--out <path>]"}}{{br}}
+Generate deployment manifests for the modules selected the same way as {{c "mbt build"}}.
+Modules without a {{c "kubernetes"}} property are skipped by {{c "kubernetes"}} and
+{{c "kustomize"}} formats. The image of each module
+is tagged with its version, so the generated manifests deploy exactly the
+versions in the manifest.
+
+{{c "kubernetes"}} format generates a Deployment for each module and a Service
+for the modules exposing a port. {{c "kustomize"}} format generates a kustomization
+which overrides the image tags of existing resources.
+
+Configure the deployment of a module with following keys in {{c "kubernetes"}} property.
+
+{{c "image"}} Image name (default {{c "--registry"}} followed by the module name){{br}}
+{{c "replicas"}} Number of replicas (default 1){{br}}
+{{c "port"}} Container port{{br}}
+{{c "servicePort"}} Service port (default {{c "port"}}){{br}}
+{{c "serviceType"}} Service type{{br}}
+{{c "env"}} Environment variables of the container
+
+{{c "helm-values"}} format generates a {{c "values.yaml"}} mapping the name of each
+module to its version and the properties specified with {{c "--property"}}.
+For example, {{c "mbt generate helm-values head --property team"}} generates
+
+{{c "app-a:"}}{{br}}
+{{c "  version: 0a4b9c3..."}}{{br}}
+{{c "  team: payments"}}
+
+Use {{c "--split"}} to write the values of each module to {{c "<module name>.yaml"}}
+in the directory specified by {{c "--out"}} instead.
+`,
+	"deployments-summary": `Record and list deployments`,
+	"deployments": `{{cli "Record and list deployments \n"}}
+{{c "mbt deployments record <env> <branch|commit|diff|head|local|pr> [args]"}}{{br}}
+Record that the modules selected the same way as {{c "mbt build"}} are deployed
+to environment {{c "env"}}. The version of each module replaces its previous
+deployment in the environment. Run this command after each successful
+deployment, for example {{c "mbt deployments record prod head --name app-a"}}.
+
+{{c "mbt deployments list <env>"}}{{br}}
+List the versions of modules deployed to environment {{c "env"}}.
+
+Deployments are stored in {{c ".git/mbt/deployments.json"}} unless a different
+file is specified with {{c "--deployments-file"}}. Use a file in a shared
+location to track the deployments made from different machines.
+`,
+	"drift-summary": `Compare deployed versions with a manifest`,
+	"drift": `{{cli "Compare deployed versions with a manifest \n"}}
+{{c "mbt drift <env> <branch|commit|diff|head|local|pr> [args]"}}{{br}}
+Compare the versions of modules deployed to environment {{c "env"}} (see
+{{c "mbt deployments"}}) with the versions of the modules selected the same way
+as {{c "mbt build"}}. For example, {{c "mbt drift prod branch master"}} shows
+what is running in prod compared to master.
+
+Status of each module is one of
+
+{{c "in-sync"}} Deployed version is the version in the manifest{{br}}
+{{c "outdated"}} A different version is deployed{{br}}
+{{c "not-deployed"}} Module is not deployed to the environment{{br}}
+{{c "unknown"}} Deployed module is not in the manifest
+`,
+	"promote-summary": `Promote deployments between environments`,
+	"promote": `{{cli "Promote deployments between environments \n"}}
+{{c "mbt promote --from <env> --to <env> [--apps <name>] [--render <format>] [--out <path>]"}}{{br}}
+Record that the versions of modules deployed to {{c "--from"}} environment are
+deployed to {{c "--to"}} environment (see {{c "mbt deployments"}}). All modules
+deployed to {{c "--from"}} environment are promoted unless a comma separated list
+of modules is specified with {{c "--apps"}}.
+
+Use {{c "--render"}} to generate the manifests of the promoted versions in one of
+the formats supported by {{c "mbt generate"}}. For example,
+{{c "mbt promote --from stage --to prod --apps app-a --render kustomize"}}
+generates a kustomization deploying the version of {{c "app-a"}} running in stage.
+`,
+	"release-summary": `Release modules`,
+	"release": `{{cli "Release modules \n"}}
+{{c "mbt release branch <branch> --name <release> [--since <commit>] [--out <path>]"}}{{br}}
+{{c "mbt release diff <release-file> <release-file>"}}{{br}}
+{{c "mbt release validate <release-file> [--sha <commit>]"}}{{br}}
+Build, tag and publish the modules in a branch and write a release manifest.
+Compare and validate release manifests. See the help of each subcommand.
+`,
+	"release-branch-summary": `Release the modules in a branch`,
+	"release-branch": `{{cli "Release the modules in a branch \n"}}
+{{c "mbt release branch <branch> --name <release> [--since <commit>] [--out <path>] [--json]"}}{{br}}
+Release the modules in a branch in the following steps:
+
+1. Build the modules in the same way as {{c "mbt build branch"}}. When
+{{c "--since"}} is specified with the commit of the previous release,
+only the modules impacted by the changes since then are released
+(same as {{c "mbt build diff --from <since> --to <branch>"}}).
+2. Generate the changelog of each module from the subjects of the commits
+changing it since the previous release.
+3. Tag the commit of each module as {{c "<module>/<release>"}}.
+4. Run the {{c "publish"}} task of each module (see {{c "mbt run"}}). Modules
+without a {{c "publish"}} task are released without being published.
+Modules with a build matrix are tagged once and their {{c "publish"}} task
+is run for each variant.
+5. Write the release manifest to {{c "--out"}} (default release.json). It lists
+the name, version, tag, changes and artifact digests (see
+{{c "--artifacts-file"}} of {{c "mbt build"}}) of each released module.
+Release manifests can be compared with {{c "mbt release diff"}} and validated
+with {{c "mbt release validate"}}.
+
+Release stops at the first failure and deletes the tags it has created.
+Artifacts already published by the {{c "publish"}} task are not retracted.
+Use {{c "--dry-run"}} to list the modules to release without building,
+tagging or publishing them.
+`,
+	"release-diff-summary": `Compare two release manifests`,
+	"release-diff": `{{cli "Compare two release manifests \n"}}
+{{c "mbt release diff <release-file> <release-file> [--json]"}}{{br}}
+List the modules added, removed, updated (released with a different version)
+or rebuilt (released with the same version but different artifacts) in the
+second release manifest compared to the first one.
+`,
+	"release-validate-summary": `Validate a release manifest against a commit`,
+	"release-validate": `{{cli "Validate a release manifest against a commit \n"}}
+{{c "mbt release validate <release-file> [--sha <commit>] [--artifacts] [--dir <path>] [--json]"}}{{br}}
+Compare the version of each module in a release manifest with its version
+in {{c "--sha"}} (default the commit of the release). Status of a module is
+one of:
+
+{{c "ok"}} Module has the released version{{br}}
+{{c "mismatch"}} Module has a different version{{br}}
+{{c "missing"}} Module does not exist in the commit
+
+Use {{c "--artifacts"}} to verify the digests of the released artifacts in
+the same way as {{c "mbt verify"}}. Fails if any module or artifact does not
+match the release manifest.
+`,
+	"policy-summary": `Check modules against policies`,
+	"policy": `{{cli "Check modules against policies \n"}}
+{{c "mbt policy <branch|commit|diff|head|local|pr> [args] --policy <path> [--json]"}}{{br}}
+Evaluate the modules selected the same way as {{c "mbt build"}} against the
+policies specified with {{c "--policy"}} and list the violations. Command fails
+if there are violations at {{c "error"}} level.
+
+When {{c "--policy"}} is specified with other commands, policies are enforced
+while creating the manifest. That is, commands fail if a module violates a
+policy at {{c "error"}} level. Violations at {{c "warning"}} level are logged.
+
+{{h2 "Expression Policies"}}
+Policies can be written as expressions with access to the same variables as
+{{c "when"}} expressions (see {{c "mbt --help"}}). Each module must satisfy the
+{{c "rule"}} of each policy.
+
+{{c "policies:"}}{{br}}
+{{c "  - name: team"}}{{br}}
+{{c "    description: Modules must specify the owning team"}}{{br}}
+{{c "    rule: has(app.properties.team)"}}{{br}}
+{{c "  - name: naming"}}{{br}}
+{{c "    rule: app.name.matches('^[a-z-]+$')"}}{{br}}
+{{c "    level: warning"}}
+
+{{h2 "Rego Policies"}}
+Policy files with {{c ".rego"}} extension are evaluated with Open Policy Agent
+({{c "opa"}} must be available in {{c "PATH"}} or specified with {{c "--opa"}}).
+Policies must be in package {{c "mbt"}}. Messages in {{c "deny"}} and {{c "warn"}}
+sets are reported as violations at {{c "error"}} and {{c "warning"}} levels.
+Input of the policy is the variables available to expressions.
+
+{{c "package mbt"}}{{br}}
+{{c "deny[msg] { not input.app.properties.team; msg := \"team is required\" }"}}
+`,
+	"builds-summary": `Display the build results recorded for a commit`,
+	"builds": `{{cli "Display the build results recorded for a commit \n"}}
+{{c "mbt builds [commit] [--notes-ref <ref>] [--json]"}}{{br}}
+Display the build results recorded as git notes (see {{c "--record-notes"}} option
+of build command) for a commit. Assume the head commit if commit is not specified.
+`,
+	"stats-summary": `Report statistics of module changes`,
+	"stats": `{{cli "Report statistics of module changes \n"}}
+{{c "mbt stats [--from <commit>] [--to <commit>] [--hot-paths <n>] [--json]"}}{{br}}
+Report the following statistics over the commits reachable from {{c "--to"}}
+(default head of the current branch) but not from {{c "--from"}} (default entire history).
+
+- Number of modules
+- Number of commits and modules changed in each week
+- Average number of modules impacted by a commit and by a merge commit
+  (e.g. a merged pull request compared to the destination branch)
+- Most frequently changed paths
+- Fan-in (number of dependents) and fan-out (number of dependencies) of each module
+
+Commits must be specified as full shas. Statistics require building
+the manifest of each commit in the range, hence specify {{c "--from"}} in large repositories.
+`,
+	"coupling-summary": `Report modules changing together and files with the widest impact`,
+	"coupling": `{{cli "Report modules changing together and files with the widest impact \n"}}
+{{c "mbt coupling [--from <commit>] [--to <commit>] [--top <n>] [--json]"}}{{br}}
+Analyse the commits reachable from {{c "--to"}} (default head of the current branch)
+but not from {{c "--from"}} (default entire history) and report
+
+- Pairs of modules most frequently changed in the same commit. Coupling is the
+  ratio of the commits changing both modules to the commits changing either of them.
+- Changed files impacting the most modules, including the dependents of the
+  module containing the file.
+
+Merge commits are not analysed since they repeat the changes of the merged commits.
+Frequently coupled modules and files with a wide impact are candidates for
+revisiting module boundaries.
+`,
+	"compare-summary": `Compare module versions in two branches`,
+	"compare": `{{cli "Compare module versions in two branches \n"}}
+{{c "mbt compare <branch> <branch> [--differs] [--json]"}}{{br}}
+List the version of each module in both branches and whether they differ.
+Modules existing in only one branch are listed with {{c "-"}} as the version in the
+other branch. Use {{c "--differs"}} to list just the modules with different versions
+(e.g. the modules pending promotion to a release branch).
+`,
+	"workspace-summary": `Describe modules across multiple repositories`,
+	"workspace": `{{cli "Describe modules across multiple repositories \n"}}
+{{c "mbt workspace <branch|head|local> [--file <path>] [--all] [--name <name>] [--fuzzy] [--json]"}}{{br}}
+Describe the modules of the repositories listed in a workspace file
+(default {{c "mbt-workspace.yml"}}). Module names are prefixed with the name
+of their repository (e.g. {{c "core/app-a"}}) so that modules with the same name
+in different repositories can be told apart.
+
+{{h2 "Workspace File"}}
+{{c "repositories"}} lists the repositories in the workspace.
+
+{{c "name"}} Name of the repository used as the prefix of module names (required){{br}}
+{{c "path"}} Path to the repository relative to the workspace file (required){{br}}
+{{c "branch"}} Branch described in {{c "branch"}} mode, default master (optional){{br}}
+
+For example:
+
+{{c "repositories:"}}{{br}}
+{{c "  - name: core"}}{{br}}
+{{c "    path: ../core"}}{{br}}
+{{c "  - name: web"}}{{br}}
+{{c "    path: ../web"}}{{br}}
+{{c "    branch: main"}}{{br}}
+
+{{h2 "Modes"}}
+{{c "branch"}} Describe modules in the branch of each repository.{{br}}
+{{c "head"}} Describe modules in current head of each repository.{{br}}
+{{c "local"}} Describe modules changed in the workspace of each repository.
+All modules are described if {{c "--all"}} option is specified.{{br}}
+`,
+	"completion-summary": `Generate shell completion script`,
+	"completion": `{{cli "Generate shell completion script \n"}}
+{{c "mbt completion <bash|zsh|fish|powershell>"}}{{br}}
+Write the completion script of the specified shell to stdout. Besides
+commands and flags, the names of modules in the current repository are
+completed for {{c "--name"}} option and commands expecting a module name
+(e.g. {{c "mbt export"}}).
+
+For example, to load completions in current bash session:
+
+{{c "source <(mbt completion bash)"}}{{br}}
+
+To load completions for every zsh session:
+
+{{c "mbt completion zsh > \"${fpath[1]}/_mbt\""}}{{br}}
+
+To load completions for every fish session:
+
+{{c "mbt completion fish > ~/.config/fish/completions/mbt.fish"}}{{br}}
+`,
+	"hook-summary": `Manage the pre-push git hook`,
+	"hook": `{{cli "Manage the pre-push git hook \n"}}
+{{c "mbt hook install [--test] [--base <branch>] [--force]"}}{{br}}
+{{c "mbt hook uninstall"}}{{br}}
+Install a pre-push hook listing the modules impacted by the commits being
+pushed (i.e. {{c "mbt describe diff"}} between the remote and the local
+commit of each pushed ref). Commits of a new branch are compared with
+the merge base of {{c "--base"}} branch (default master).
+
+Use {{c "--test"}} to run the tests of impacted modules with {{c "mbt test diff"}}
+and reject the push when they fail. Use {{c "git push --no-verify"}} to skip the hook.
+
+An existing pre-push hook is only replaced with {{c "--force"}}. Uninstall
+removes the hook only if it is generated by mbt.
+`,
+	"debug-summary": `Troubleshoot mbt`,
+	"debug": `{{cli "Troubleshoot mbt \n"}}
+Commands collecting data to troubleshoot mbt.
+`,
+	"debug-profile-summary": `Profile the creation of a manifest`,
+	"debug-profile": `{{cli "Profile the creation of a manifest \n"}}
+{{c "mbt debug profile <branch|commit|diff|head|local|pr> [args] [--pprof <file>] [--json]"}}{{br}}
+Create the manifest selected in the same way as {{c "mbt build"}} and report the
+number of calls and the time spent in each phase. Include the output when
+reporting slowness.
+
+- {{c "discover"}}: finding modules (includes {{c "tree walk"}} and {{c "spec parse"}})
+- {{c "tree walk"}}: walking the git tree or the workspace for spec files
+- {{c "spec parse"}}: parsing spec files
+- {{c "merge base"}}: finding the merge base of commits
+- {{c "diff"}}: finding changed files
+- {{c "reduce"}}: selecting the modules impacted by changed files
+
+Use {{c "--pprof"}} to write a CPU profile which can be inspected with
+{{c "go tool pprof"}}.
+`,
+	"export-summary": `Export the history of a module to a new repository`,
+	"export": `{{cli "Export the history of a module to a new repository \n"}}
+{{c "mbt export <module> --to <path> [--ref <ref>] [--json]"}}{{br}}
+Extract the history of a module into a standalone repository at {{c "--to"}}
+(e.g. to move the module out of the monorepo). Module directory becomes the
+root of the new repository and only the commits changing it are retained with
+their original authors, dates and messages. History is read from {{c "--ref"}},
+default HEAD.
+
+History of the new repository is linear. Commits made before the module
+was moved to its current directory are not included.
+Destination must not exist or must be an empty directory.
+`,
+	"external-summary": `Check external dependencies of modules for staleness`,
+	"external": `{{cli "Check external dependencies of modules for staleness \n"}}
+{{c "mbt external <branch|commit|diff|head|local|pr> [args] [--fail-on-stale] [--json]"}}{{br}}
+List the external dependencies of modules selected in the same way as
+{{c "mbt build"}} and compare the commit of each pinned {{c "ref"}} with
+the commit of the ref it tracks ({{c "track"}}, default HEAD) in the remote
+repository. A dependency is stale when the tracked ref has advanced.
+
+Pinned refs are included in the version of a module. Pin dependencies to a
+commit sha or a tag because moving a branch does not change the version.
+
+Use {{c "--fail-on-stale"}} to fail when any dependency is stale.
+`,
+	"migrate-specs-summary": `Rewrite module specs to the current version`,
+	"migrate-specs": `{{cli "Rewrite module specs to the current version \n"}}
+{{c "mbt migrate-specs [--dry-run]"}}{{br}}
+Rewrite the spec files in the workspace to the current version of the spec
+format and list the rewritten files. Comments and formatting of the specs are
+preserved. Use {{c "--dry-run"}} to list the specs without modifying them.
+
+Specs declare the version of their format in {{c "version"}}. Specs without a
+version are compatible with version 1. mbt fails to read specs with a version
+newer than it supports.
+`,
+	"plugin-summary": `Run a plugin with a set of modules`,
+	"plugin": `{{cli "Run a plugin with a set of modules \n"}}
+{{c "mbt plugin <name> <branch|commit|diff|head|local|pr> [args] [-- plugin-args]"}}{{br}}
+Run plugin {{c "<name>"}}, an executable named {{c "mbt-<name>"}} in PATH, with
+the modules selected in the same way as {{c "mbt build"}}. Plugins extend mbt
+with custom emitters, notifiers and selectors without modifying it.
+
+Plugins receive the manifest on stdin in the same json format as
+{{c "mbt describe --json"}}, wrapped in a document with {{c "SchemaVersion"}},
+{{c "Modules"}} keyed by name, {{c "Sha"}}, {{c "Branch"}}, {{c "Base"}} and
+{{c "ChangedFiles"}}. Arguments after {{c "--"}} are passed to the plugin.
+Plugins run in the repository directory with {{c "MBT_REPO_PATH"}},
+{{c "MBT_BUILD_COMMIT"}} and {{c "MBT_BRANCH"}} environment variables.
+
+{{c ""}}
+mbt plugin changelog diff --from origin/master --to HEAD -- --format markdown
+{{c ""}}
+
+{{c "mbt plugin --list"}}{{br}}
+List the plugins found in PATH.
+`,
+	"risk-summary": `Assess the risk of module changes`,
+	"risk": `{{cli "Assess the risk of module changes \n"}}
+{{c "mbt risk <commit|diff|local|pr> [args] [--fail-above <score>] [--json]"}}{{br}}
+Compute a risk score between 0 and 100 for each module impacted by the changes
+selected in the same way as {{c "mbt build"}} (e.g. {{c "mbt risk diff --from <commit> --to <commit>"}}).
+Use it to route risky changes to extra review or canary deployments.
+Following factors contribute up to 25 points each, growing linearly until the
+value in brackets.
+
+- Number of files changed in the module (20)
+- Number of lines added or deleted in the module (500)
+- Number of modules depending on the module directly or transitively (10)
+- Number of days since the module was changed before these changes (180)
+
+Scores below 34 are {{c "low"}}, below 67 are {{c "medium"}} and others are {{c "high"}}.
+Use {{c "--fail-above"}} to fail when the score of any module is above the specified value.
+`,
+	"approvals-summary": `List the approvals required for module changes`,
+	"approvals": `{{cli "List the approvals required for module changes \n"}}
+{{c "mbt approvals <commit|diff|local|pr> [args] [--json]"}}{{br}}
+List the union of {{c "approvals"}} groups in the specs of the modules impacted
+by the changes selected in the same way as {{c "mbt build"}}, along with the
+modules requiring each group (e.g. {{c "mbt approvals pr --src feature --dst master"}}).
+Review bots can use it to enforce module level review policies complementing
+CODEOWNERS.
+`,
+	"cache-summary": `Manage the cache of module outputs`,
+	"cache": `{{cli "Manage the cache of module outputs \n"}}
+{{c "mbt cache gc --cache-dir <dir> [--max-age <duration>] [--max-size-mb <n>] [--keep-last <n>]"}}{{br}}
+Remove the entries of the local cache of module outputs according to the
+retention policies (see {{c "mbt cache gc --help"}}).
+`,
+	"cache-gc-summary": `Remove old entries from the cache of module outputs`,
+	"cache-gc": `{{cli "Remove old entries from the cache of module outputs \n"}}
+{{c "mbt cache gc --cache-dir <dir> [--max-age <duration>] [--max-size-mb <n>] [--keep-last <n>] [--dry-run] [--json]"}}{{br}}
+Remove the entries of the cache specified with {{c "--cache-dir"}} (see
+{{c "mbt build --help"}}) that are not retained by the following policies.
+Entries are considered used when they are written or restored.
+
+{{c "--max-age <duration>"}} Remove the entries not used within the duration (e.g. {{c "720h"}}){{br}}
+{{c "--max-size-mb <n>"}} Remove the least recently used entries until the cache fits in the size{{br}}
+{{c "--keep-last <n>"}} Keep only the most recently used entries of each module
+
+Use {{c "--dry-run"}} to list the entries to remove without removing them.
+Remote caches specified with {{c "--remote-cache"}} are plain HTTP servers
+without an API to list their entries, therefore their retention should be
+configured in the server (e.g. lifecycle rules of the bucket).
+`,
+	"doctor-summary": `Validate the prerequisites of mbt`,
+	"doctor": `{{cli "Validate the prerequisites of mbt \n"}}
+{{c "mbt doctor [--cache-dir <dir>] [--remote-cache <url>] [--json]"}}{{br}}
+Validate the environment and the repository, and report the problems which
+would make other commands fail or behave unexpectedly. Following checks are
+performed.
+
+{{c "libgit2"}} Version and features of libgit2 mbt is built with{{br}}
+{{c "git"}} Availability of git, used for pull requests, partial clones, commit trailers and external dependencies{{br}}
+{{c "repository"}} Shallow clones, detached HEAD, sparse checkout and partial clones{{br}}
+{{c "specs"}} Discovery of the modules in HEAD, which fails on invalid specs{{br}}
+{{c "cache"}} Connectivity of the caches specified with {{c "--cache-dir"}} and {{c "--remote-cache"}}{{br}}
+{{c "MBT_PR_TOKEN"}}, {{c "MBT_STATUS_TOKEN"}} Availability of the credentials of pull requests and commit statuses
+
+Each check is reported as {{c "ok"}}, {{c "warning"}} (some commands are limited)
+or {{c "error"}}. The command fails when any of the checks is an error. Missing
+credentials are reported as warnings since they are only required by some of
+the options.
+`,
+	"terraform-summary": `List impacted Terraform root modules`,
+	"terraform": `{{cli "List impacted Terraform root modules \n"}}
+{{c "mbt terraform <branch|commit|diff|head|local|pr> [args] [--chdir] [--json]"}}{{br}}
+List the directories of Terraform root modules selected in the same way as
+{{c "mbt build"}}, one per line. Use {{c "--chdir"}} to format them as
+{{c "-chdir"}} arguments of terraform. For example, following command plans
+the stacks changed in a pull request.
+
+{{c ""}}
+mbt terraform diff --from origin/master --to HEAD --chdir | xargs -I{} terraform {} plan
+{{c ""}}
+
+Directories with a {{c "backend"}} block in a {{c ".tf"}} file or a
+{{c ".terraform.lock.hcl"}} file are discovered as modules in {{c "terraform"}}
+group when {{c "--terraform"}} option is set, which is implied for this command.
+Modules are named after their directory (e.g. {{c "infra-prod"}}) unless the
+directory has a spec file. Local modules used via relative {{c "source"}}
+paths are file dependencies of the root modules using them, therefore changes
+to shared modules impact all the stacks using them.
+`,
+	"verify-summary": `Verify artifacts against an artifact manifest`,
+	"verify": `{{cli "Verify artifacts against an artifact manifest \n"}}
+{{c "mbt verify <artifacts-file> [--dir <dir>] [--json]"}}{{br}}
+Compute the SHA-256 digests of the artifacts listed in an artifact manifest
+(see {{c "--artifacts-file"}} option of build command) and compare them with
+the recorded digests. Use it to validate artifacts downloaded from a cache or
+before promoting them. Artifact paths are resolved relative to {{c "--dir"}}
+which defaults to the path to the repository. Exits with an error if an artifact
+is missing or does not match its digest.
+
+{{c "mbt verify <artifacts-file> --signature [--certificate-identity <identity>] [--certificate-oidc-issuer <issuer>]"}}{{br}}
+Verify the signature of the artifact manifest created with {{c "--sign"}}
+option of build command before verifying the artifacts. Signature bundle is
+read from {{c "<artifacts-file>.bundle"}} and verified with cosign.
 `,
 	"run-in-summary": `Run user defined command`,
 	"run-in": `{{cli "Run user defined command \n"}}
@@ -321,6 +1563,24 @@ Consider just the modules matching the {{c "--name"}} filter if specified.
 Default {{c "--name"}} filter is a prefix match. You can change this to a subsequence
 match by using {{c "--fuzzy"}} option.
 
+{{h2 "Expression Filter"}}
+Use {{c "--expr <expression>"}} along with the commands supporting {{c "--name"}}
+filter to select the modules satisfying an expression. Expressions have access
+to the same variables as {{c "when"}} expressions (see {{c "mbt --help"}}).
+For example, {{c "--expr \"app.properties.team == 'payments'\""}}
+
+{{h2 "Owner Filter"}}
+Use {{c "--owner <owner>"}} along with the commands supporting {{c "--name"}}
+filter to select the modules owned by an owner according to the {{c "CODEOWNERS"}}
+file in the repository (e.g. {{c "--owner @org/payments"}}). Owners of a module are
+determined by the last rule matching the module directory.
+
+{{h2 "Group Filter"}}
+Use {{c "--group <group>"}} along with the commands supporting {{c "--name"}}
+filter to select the modules in a group (e.g. {{c "--group payments"}}). Group of a
+module is specified with {{c "group"}} in the spec and defaults to the top level
+directory of the module.
+
 {{h2 "Execution Environment"}}
 
 When executing a command, following environment variables are initialised and can be
@@ -332,6 +1592,13 @@ used by the command being executed.
 
 In addition to the variables listed above, module properties are also populated 
 in the form of {{c "MBT_MODULE_PROPERTY_XXX"}} where {{c "XXX"}} denotes the key.
+
+{{h2 "Output"}}
+
+Use {{c "--output prefix"}} to prefix each line written by commands with the name of
+the module. Prefixes are highlighted unless {{c "--no-color"}} is specified.
+Use {{c "--output group"}} to hold the output of each module until the command is complete
+and write it at once.
 `,
 }
 
@@ -0,0 +1,77 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/mbtproject/mbt/e"
+	"github.com/mbtproject/mbt/lib"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	doctorCommand.Flags().StringVar(&cacheDir, "cache-dir", "", "Directory caching the outputs of module builds")
+	doctorCommand.Flags().StringVar(&remoteCache, "remote-cache", "", "URL of the HTTP server caching the outputs of module builds")
+	doctorCommand.Flags().BoolVar(&toJSON, "json", false, "Format output as json")
+
+	RootCmd.AddCommand(doctorCommand)
+}
+
+var doctorCommand = &cobra.Command{
+	Use:   "doctor",
+	Short: docText("doctor-summary"),
+	Long:  docText("doctor"),
+	RunE: buildHandler(func(cmd *cobra.Command, args []string) error {
+		checks, err := system.Doctor(&lib.DoctorOptions{
+			Cache: buildCache(),
+			Credentials: []*lib.Credential{
+				{Env: "MBT_PR_TOKEN", UsedBy: "--pr"},
+				{Env: "MBT_STATUS_TOKEN", UsedBy: "--status-provider"},
+			},
+		})
+		if err != nil {
+			return err
+		}
+
+		if toJSON {
+			buff, err := json.MarshalIndent(checks, "", "  ")
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(buff))
+		} else {
+			tw := tabwriter.NewWriter(os.Stdout, 0, 4, 4, ' ', 0)
+			fmt.Fprintf(tw, "CHECK\tSTATUS\tMESSAGE\n")
+			for _, c := range checks {
+				fmt.Fprintf(tw, "%s\t%s\t%s\n", c.Name, c.Status, c.Message)
+			}
+			if err := tw.Flush(); err != nil {
+				return err
+			}
+		}
+
+		for _, c := range checks {
+			if c.Status == lib.DoctorStatusError {
+				return e.NewError(lib.ErrClassUser, "Prerequisites of mbt are not satisfied")
+			}
+		}
+		return nil
+	}),
+}
@@ -0,0 +1,122 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"github.com/mbtproject/mbt/e"
+	"github.com/mbtproject/mbt/lib"
+	"github.com/spf13/cobra"
+)
+
+// Flags used to select and configure the executor of
+// build and user defined commands.
+var (
+	executor   string
+	kubernetes = &lib.KubernetesOptions{}
+	ssh        = &lib.SSHOptions{}
+	docker     = &lib.DockerOptions{}
+)
+
+func init() {
+	for _, c := range []*cobra.Command{buildCommand, testCommand, runCommand, runIn} {
+		c.PersistentFlags().StringVar(&executor, "executor", "host", "Where commands are executed (available options are 'host', 'docker', 'kubernetes' and 'ssh')")
+		c.PersistentFlags().StringVar(&docker.Docker, "docker-binary", "", "Path to docker")
+		c.PersistentFlags().StringVar(&docker.Image, "docker-image", "", "Container image used to execute commands")
+		c.PersistentFlags().StringVar(&docker.WorkDir, "docker-workdir", "", "Path where the repository is mounted inside the container")
+		c.PersistentFlags().StringArrayVar(&docker.Args, "docker-arg", nil, "Additional argument to docker run (can be specified multiple times)")
+		c.PersistentFlags().StringVar(&kubernetes.Kubectl, "k8s-kubectl", "", "Path to kubectl")
+		c.PersistentFlags().StringVar(&kubernetes.Context, "k8s-context", "", "Kubeconfig context")
+		c.PersistentFlags().StringVar(&kubernetes.Namespace, "k8s-namespace", "", "Namespace of the jobs")
+		c.PersistentFlags().StringVar(&kubernetes.Image, "k8s-image", "", "Container image used to execute commands")
+		c.PersistentFlags().StringVar(&kubernetes.CPU, "k8s-cpu", "", "CPU allocated for each job (e.g. 500m)")
+		c.PersistentFlags().StringVar(&kubernetes.Memory, "k8s-memory", "", "Memory allocated for each job (e.g. 1Gi)")
+		c.PersistentFlags().StringVar(&kubernetes.ServiceAccount, "k8s-service-account", "", "Service account of the job pods")
+		c.PersistentFlags().StringVar(&kubernetes.RepoURL, "k8s-repo-url", "", "URL of the repository cloned into the job workspace")
+		c.PersistentFlags().StringVar(&kubernetes.WorkDir, "k8s-workdir", "", "Path to the repository inside the job container")
+		c.PersistentFlags().DurationVar(&kubernetes.Timeout, "k8s-timeout", 0, "Maximum time to wait for a job")
+		c.PersistentFlags().BoolVar(&kubernetes.KeepJobs, "k8s-keep-jobs", false, "Do not delete the jobs after completion")
+		c.PersistentFlags().StringVar(&ssh.SSH, "ssh-binary", "", "Path to ssh")
+		c.PersistentFlags().StringToStringVar(&ssh.Hosts, "ssh-hosts", nil, "Remote hosts by label (e.g. arm64=ci@arm-host,darwin=ci@mac-host)")
+		c.PersistentFlags().StringVar(&ssh.DefaultHost, "ssh-default-host", "", "Remote host for modules without an executorLabel property")
+		c.PersistentFlags().IntVar(&ssh.Port, "ssh-port", 0, "Port of the ssh server")
+		c.PersistentFlags().StringVar(&ssh.Identity, "ssh-identity", "", "Path to the private key used for authentication")
+		c.PersistentFlags().StringVar(&ssh.RepoDir, "ssh-repo-dir", "", "Path to the repository clone in remote hosts")
+		c.PersistentFlags().StringVar(&ssh.Remote, "ssh-remote", "", "Git remote fetched in remote hosts (default origin)")
+	}
+}
+
+func systemOptions(level int) (*lib.SystemOptions, error) {
+	options := &lib.SystemOptions{
+		LogLevel:       level,
+		Diff:           systemDiffOptions(),
+		SpecFile:       specFile,
+		Terraform:      terraform,
+		ManifestScript: manifestScript,
+		Profiler:       profiler,
+		ODB:            odbOptions,
+		ForceInclude:   forceInclude,
+		ForceExclude:   forceExclude,
+		Dependents:     includeDependents,
+		Budget:         budget,
+		BudgetPolicy:   budgetPolicy,
+		Trailers:       trailers,
+		VersionFormat:  versionFormat,
+	}
+
+	log := lib.NewStdLog(level)
+
+	var err error
+	if options.Executor, err = newExecutor(log, executor); err != nil {
+		return nil, err
+	}
+
+	if verifyReproducible && reproducibleExecutor != "" {
+		if reproducible.Executor, err = newExecutor(log, reproducibleExecutor); err != nil {
+			return nil, err
+		}
+		if reproducible.Executor == nil {
+			reproducible.Executor = lib.NewHostExecutor()
+		}
+	}
+
+	return options, nil
+}
+
+// newExecutor creates the executor specified by name. Returns nil for
+// the host executor, which is the default of the system.
+func newExecutor(log lib.Log, name string) (lib.Executor, error) {
+	switch name {
+	case "", "host":
+		return nil, nil
+	case "docker":
+		if docker.Image == "" {
+			return nil, e.NewError(lib.ErrClassUser, "--docker-image is required for docker executor")
+		}
+		return lib.NewDockerExecutor(log, docker), nil
+	case "kubernetes":
+		if kubernetes.Image == "" {
+			return nil, e.NewError(lib.ErrClassUser, "--k8s-image is required for kubernetes executor")
+		}
+		return lib.NewKubernetesExecutor(log, kubernetes), nil
+	case "ssh":
+		if ssh.RepoDir == "" {
+			return nil, e.NewError(lib.ErrClassUser, "--ssh-repo-dir is required for ssh executor")
+		}
+		return lib.NewSSHExecutor(log, ssh), nil
+	default:
+		return nil, e.NewErrorf(lib.ErrClassUser, "not a valid executor '%s' - available options are 'host', 'docker', 'kubernetes' and 'ssh'", name)
+	}
+}
@@ -0,0 +1,73 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"errors"
+
+	"github.com/mbtproject/mbt/e"
+	"github.com/mbtproject/mbt/lib"
+)
+
+// Exit codes of mbt.
+const (
+	// ExitCodeSuccess indicates that the command succeeded.
+	ExitCodeSuccess = 0
+	// ExitCodeError indicates an invalid usage or a failure to create
+	// the manifest (e.g. invalid module spec or unknown commit).
+	ExitCodeError = 1
+	// ExitCodeBuildFailure indicates that a command executed for a
+	// module (e.g. build or test) failed.
+	ExitCodeBuildFailure = 2
+	// ExitCodeEmptySelection indicates that no modules are selected
+	// and --fail-on-empty is specified.
+	ExitCodeEmptySelection = 3
+)
+
+var (
+	failOnEmpty       bool
+	errEmptySelection = errors.New("No modules are selected")
+)
+
+func init() {
+	RootCmd.PersistentFlags().BoolVar(&failOnEmpty, "fail-on-empty", false, "Fail when no modules are selected")
+}
+
+// checkSelection returns an error if no modules are selected and
+// --fail-on-empty is specified.
+func checkSelection(mods lib.Modules) error {
+	if failOnEmpty && len(mods) == 0 {
+		return errEmptySelection
+	}
+	return nil
+}
+
+// ExitCode returns the exit code describing err.
+func ExitCode(err error) int {
+	if err == nil {
+		return ExitCodeSuccess
+	}
+
+	if err == errEmptySelection {
+		return ExitCodeEmptySelection
+	}
+
+	if ee, ok := err.(*e.E); ok && ee.Class() == lib.ErrClassBuild {
+		return ExitCodeBuildFailure
+	}
+
+	return ExitCodeError
+}
@@ -0,0 +1,83 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	explainApp    string
+	explainFormat string
+)
+
+func init() {
+	explainCommand.Flags().StringVar(&explainApp, "app", "", "Module to explain the selection of")
+	explainCommand.Flags().StringVar(&explainFormat, "format", "dot", "Format of the graph (dot, mermaid or json)")
+
+	explainCommand.Flags().StringVar(&src, "src", "", "Source branch")
+	explainCommand.Flags().StringVar(&dst, "dst", "", "Destination branch")
+	explainCommand.Flags().StringVar(&from, "from", "", "From commit")
+	explainCommand.Flags().StringVar(&to, "to", "", "To commit")
+	explainCommand.Flags().BoolVarP(&content, "content", "c", false, "Explain the selection by the content of the commit")
+
+	RootCmd.AddCommand(explainCommand)
+}
+
+var explainCommand = &cobra.Command{
+	Use:   "explain <pr|diff|commit|local> [args] --app <name>",
+	Short: docText("explain-summary"),
+	Long:  docText("explain"),
+	RunE: buildHandler(func(cmd *cobra.Command, args []string) error {
+		if len(args) < 1 {
+			return errors.New("requires the changes to explain")
+		}
+
+		if explainApp == "" {
+			return errors.New("requires the module to explain, specify --app argument")
+		}
+
+		m, err := manifestByMode(args[0], args[1:])
+		if err != nil {
+			return err
+		}
+
+		g, err := m.ImpactGraph(explainApp)
+		if err != nil {
+			return err
+		}
+
+		switch explainFormat {
+		case "dot":
+			fmt.Println(g.SerializeAsDot())
+		case "mermaid":
+			fmt.Println(g.SerializeAsMermaid())
+		case "json":
+			buff, err := json.MarshalIndent(g, "", "  ")
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(buff))
+		default:
+			return fmt.Errorf("unknown format '%s' (expected dot, mermaid or json)", explainFormat)
+		}
+		return nil
+	}),
+}
@@ -0,0 +1,68 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	exportTo  string
+	exportRef string
+)
+
+func init() {
+	exportCommand.Flags().StringVar(&exportTo, "to", "", "Path of the new repository")
+	exportCommand.Flags().StringVar(&exportRef, "ref", "HEAD", "Branch, tag or commit to export the history from")
+	exportCommand.Flags().BoolVar(&toJSON, "json", false, "Format output as json")
+	RootCmd.AddCommand(exportCommand)
+}
+
+var exportCommand = &cobra.Command{
+	Use:   "export <module> --to <path>",
+	Short: docText("export-summary"),
+	Long:  docText("export"),
+	RunE: buildHandler(func(cmd *cobra.Command, args []string) error {
+		if len(args) == 0 {
+			return errors.New("requires the module name")
+		}
+
+		if exportTo == "" {
+			return errors.New("requires the path of the new repository")
+		}
+
+		r, err := system.Export(args[0], exportRef, exportTo)
+		if err != nil {
+			return err
+		}
+
+		if toJSON {
+			buff, err := json.MarshalIndent(r, "", "  ")
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(buff))
+			return nil
+		}
+
+		fmt.Printf("Exported %v commits of %s to %s (head %s)\n", r.Commits, r.Module, r.Path, r.Head)
+		return nil
+	}),
+}
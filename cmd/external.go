@@ -0,0 +1,103 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/mbtproject/mbt/e"
+	"github.com/mbtproject/mbt/lib"
+	"github.com/spf13/cobra"
+)
+
+var (
+	failOnStale bool
+)
+
+func init() {
+	externalCommand.Flags().BoolVar(&failOnStale, "fail-on-stale", false, "Fail when an external dependency is stale")
+	externalCommand.Flags().BoolVar(&toJSON, "json", false, "Format output as json")
+
+	externalCommand.Flags().StringVar(&src, "src", "", "Source branch")
+	externalCommand.Flags().StringVar(&dst, "dst", "", "Destination branch")
+	externalCommand.Flags().StringVar(&from, "from", "", "From commit")
+	externalCommand.Flags().StringVar(&to, "to", "", "To commit")
+	externalCommand.Flags().BoolVarP(&all, "all", "a", false, "All modules")
+	externalCommand.Flags().BoolVarP(&content, "content", "c", false, "Check the modules impacted by the content of the commit")
+	externalCommand.Flags().StringVarP(&name, "name", "n", "", "Check modules with a name that matches this value. Multiple names can be specified as a comma separated string.")
+	externalCommand.Flags().BoolVarP(&fuzzy, "fuzzy", "f", false, "Use fuzzy match when filtering")
+	externalCommand.Flags().StringVar(&filterExpr, "expr", "", "Filter modules with an expression")
+	externalCommand.Flags().StringVar(&owner, "owner", "", "Filter modules owned by this owner according to CODEOWNERS")
+	externalCommand.Flags().StringVar(&group, "group", "", "Filter modules in this group")
+
+	RootCmd.AddCommand(externalCommand)
+}
+
+var externalCommand = &cobra.Command{
+	Use:   "external <branch|commit|diff|head|local|pr> [args]",
+	Short: docText("external-summary"),
+	Long:  docText("external"),
+	RunE: buildHandler(func(cmd *cobra.Command, args []string) error {
+		if len(args) < 1 {
+			return errors.New("requires the modules to check")
+		}
+
+		m, err := manifestByMode(args[0], args[1:])
+		if err != nil {
+			return err
+		}
+
+		statuses, err := lib.CheckExternalDependencies(m.Modules, lib.LsRemote)
+		if err != nil {
+			return err
+		}
+
+		if toJSON {
+			buff, err := json.MarshalIndent(statuses, "", "  ")
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(buff))
+		} else {
+			tw := tabwriter.NewWriter(os.Stdout, 0, 4, 4, ' ', 0)
+			fmt.Fprintf(tw, "Name\tREPOSITORY\tPATH\tREF\tLATEST\tSTALE\n")
+			for _, s := range statuses {
+				fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%v\n", s.Module, s.Repo, orDash(s.Path), s.Ref, s.Latest, s.Stale)
+			}
+			if err := tw.Flush(); err != nil {
+				return err
+			}
+		}
+
+		if failOnStale {
+			stale := 0
+			for _, s := range statuses {
+				if s.Stale {
+					stale++
+				}
+			}
+			if stale > 0 {
+				return e.NewErrorf(lib.ErrClassUser, "%v external dependencies are stale", stale)
+			}
+		}
+
+		return nil
+	}),
+}
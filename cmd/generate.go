@@ -0,0 +1,134 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/mbtproject/mbt/lib"
+	"github.com/spf13/cobra"
+)
+
+var (
+	registry   string
+	namespace  string
+	properties []string
+	split      bool
+)
+
+func init() {
+	generateCommand.Flags().StringVar(&registry, "registry", "", "Registry prefixed to the image of each module")
+	generateCommand.Flags().StringVar(&namespace, "namespace", "", "Namespace of the generated resources")
+	generateCommand.Flags().StringSliceVar(&properties, "property", nil, "Module properties included in Helm values")
+	generateCommand.Flags().BoolVar(&split, "split", false, "Write Helm values of each module to a separate file in the output directory")
+	generateCommand.Flags().StringVar(&out, "out", "", "Output path")
+
+	generateCommand.Flags().StringVar(&src, "src", "", "Source branch")
+	generateCommand.Flags().StringVar(&dst, "dst", "", "Destination branch")
+	generateCommand.Flags().StringVar(&from, "from", "", "From commit")
+	generateCommand.Flags().StringVar(&to, "to", "", "To commit")
+	generateCommand.Flags().BoolVarP(&all, "all", "a", false, "All modules")
+	generateCommand.Flags().BoolVarP(&content, "content", "c", false, "Generate for the modules impacted by the content of the commit")
+	generateCommand.Flags().StringVarP(&name, "name", "n", "", "Generate for modules with a name that matches this value. Multiple names can be specified as a comma separated string.")
+	generateCommand.Flags().BoolVarP(&fuzzy, "fuzzy", "f", false, "Use fuzzy match when filtering")
+	generateCommand.Flags().StringVar(&filterExpr, "expr", "", "Filter modules with an expression")
+	generateCommand.Flags().StringVar(&owner, "owner", "", "Filter modules owned by this owner according to CODEOWNERS")
+	generateCommand.Flags().StringVar(&group, "group", "", "Filter modules in this group")
+
+	RootCmd.AddCommand(generateCommand)
+}
+
+var generateCommand = &cobra.Command{
+	Use:   "generate <kubernetes|kustomize|helm-values> <branch|commit|diff|head|local|pr> [args]",
+	Short: docText("generate-summary"),
+	Long:  docText("generate"),
+	RunE: buildHandler(func(cmd *cobra.Command, args []string) error {
+		if len(args) < 2 {
+			return errors.New("requires the format and the modules to generate it for")
+		}
+
+		m, err := manifestByMode(args[1], args[2:])
+		if err != nil {
+			return err
+		}
+
+		if err := checkSelection(m.Modules); err != nil {
+			return err
+		}
+
+		if split {
+			if args[0] != "helm-values" {
+				return errors.New("--split is only supported for helm-values")
+			}
+			return generateHelmValuesByModule(m.Modules)
+		}
+
+		s, err := generate(args[0], m.Modules)
+		if err != nil {
+			return err
+		}
+
+		output, err := getOutput(out)
+		if err != nil {
+			return err
+		}
+
+		_, err = fmt.Fprint(output, s)
+		return err
+	}),
+}
+
+func generate(format string, mods lib.Modules) (string, error) {
+	options := &lib.KubernetesManifestOptions{Registry: registry, Namespace: namespace}
+
+	switch format {
+	case "kubernetes":
+		return mods.SerializeAsKubernetes(options)
+	case "kustomize":
+		return mods.SerializeAsKustomization(options)
+	case "helm-values":
+		return mods.SerializeAsHelmValues(&lib.HelmValuesOptions{Properties: properties})
+	}
+
+	return "", fmt.Errorf("unknown format '%s', expected one of kubernetes, kustomize or helm-values", format)
+}
+
+func generateHelmValuesByModule(mods lib.Modules) error {
+	if out == "" {
+		return errors.New("requires the output directory, specify --out argument")
+	}
+
+	fragments, err := mods.SerializeAsHelmValuesByModule(&lib.HelmValuesOptions{Properties: properties})
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(out, 0755); err != nil {
+		return err
+	}
+
+	for name, f := range fragments {
+		if err := ioutil.WriteFile(filepath.Join(out, name+".yaml"), []byte(f), 0644); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,71 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/mbtproject/mbt/lib"
+	"github.com/spf13/cobra"
+)
+
+var (
+	hookBase  string
+	hookTest  bool
+	hookForce bool
+)
+
+func init() {
+	hookInstallCommand.Flags().StringVar(&hookBase, "base", "master", "Branch new branches are compared with")
+	hookInstallCommand.Flags().BoolVar(&hookTest, "test", false, "Run the tests of impacted modules before pushing")
+	hookInstallCommand.Flags().BoolVar(&hookForce, "force", false, "Replace an existing pre-push hook")
+
+	hookCommand.AddCommand(hookInstallCommand)
+	hookCommand.AddCommand(hookUninstallCommand)
+	RootCmd.AddCommand(hookCommand)
+}
+
+var hookCommand = &cobra.Command{
+	Use:   "hook",
+	Short: docText("hook-summary"),
+	Long:  docText("hook"),
+}
+
+var hookInstallCommand = &cobra.Command{
+	Use: "install [--test] [--base <branch>] [--force]",
+	RunE: buildHandler(func(cmd *cobra.Command, args []string) error {
+		path, err := lib.InstallHook(in, &lib.HookOptions{Base: hookBase, Test: hookTest}, hookForce)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Installed %s\n", path)
+		return nil
+	}),
+}
+
+var hookUninstallCommand = &cobra.Command{
+	Use: "uninstall",
+	RunE: buildHandler(func(cmd *cobra.Command, args []string) error {
+		path, err := lib.UninstallHook(in)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Removed %s\n", path)
+		return nil
+	}),
+}
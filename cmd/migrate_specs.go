@@ -0,0 +1,49 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/mbtproject/mbt/lib"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	migrateSpecsCommand.Flags().BoolVar(&dryRun, "dry-run", false, "List the specs to migrate without modifying them")
+	RootCmd.AddCommand(migrateSpecsCommand)
+}
+
+var migrateSpecsCommand = &cobra.Command{
+	Use:   "migrate-specs",
+	Short: docText("migrate-specs-summary"),
+	Long:  docText("migrate-specs"),
+	RunE: buildHandler(func(cmd *cobra.Command, args []string) error {
+		migrations, err := lib.MigrateSpecs(in, specFile, dryRun)
+		if err != nil {
+			return err
+		}
+
+		tw := tabwriter.NewWriter(os.Stdout, 0, 4, 4, ' ', 0)
+		fmt.Fprintf(tw, "PATH\tFROM\tTO\n")
+		for _, m := range migrations {
+			fmt.Fprintf(tw, "%s\t%v\t%v\n", m.Path, m.From, m.To)
+		}
+		return tw.Flush()
+	}),
+}
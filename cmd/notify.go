@@ -0,0 +1,109 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/mbtproject/mbt/lib"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	notifySlack   string
+	notifyWebhook string
+	notifyEmail   []string
+	smtpServer    string
+	smtpFrom      string
+	recorder      *buildRecorder
+)
+
+func init() {
+	buildCommand.PersistentFlags().StringVar(&notifySlack, "notify-slack", "", "Slack webhook URL to notify the build results of each owner")
+	buildCommand.PersistentFlags().StringVar(&notifyWebhook, "notify-webhook", "", "URL to post the build results of each owner as json")
+	buildCommand.PersistentFlags().StringSliceVar(&notifyEmail, "notify-email", nil, "Email addresses to notify the build results of each owner")
+	buildCommand.PersistentFlags().StringVar(&smtpServer, "smtp-server", "", "Address of the SMTP server used to send email notifications (e.g. smtp.example.com:25)")
+	buildCommand.PersistentFlags().StringVar(&smtpFrom, "smtp-from", "", "Sender of email notifications")
+}
+
+func notifiers() ([]lib.Notifier, error) {
+	n := make([]lib.Notifier, 0)
+	if notifySlack != "" {
+		n = append(n, lib.NewSlackNotifier(notifySlack))
+	}
+
+	if notifyWebhook != "" {
+		n = append(n, lib.NewWebhookNotifier(notifyWebhook))
+	}
+
+	if len(notifyEmail) > 0 {
+		if smtpServer == "" || smtpFrom == "" {
+			return nil, errors.New("email notifications require --smtp-server and --smtp-from")
+		}
+		n = append(n, lib.NewEmailNotifier(&lib.EmailOptions{Server: smtpServer, From: smtpFrom, To: notifyEmail}))
+	}
+
+	return n, nil
+}
+
+// buildRecorder keeps track of the build stages of modules so that
+// notifications can be sent even when the build is aborted without
+// a summary.
+type buildRecorder struct {
+	mutex   sync.Mutex
+	summary *lib.BuildSummary
+}
+
+func (r *buildRecorder) callback(next lib.CmdStageCallback) lib.CmdStageCallback {
+	r.summary = &lib.BuildSummary{}
+	return func(a *lib.Module, s lib.CmdStage, err error) {
+		r.mutex.Lock()
+		switch s {
+		case lib.CmdStageAfterBuild, lib.CmdStageCachedBuild:
+			r.summary.Completed = append(r.summary.Completed, &lib.BuildResult{Module: a})
+		case lib.CmdStageSkipBuild:
+			r.summary.Skipped = append(r.summary.Skipped, a)
+		case lib.CmdStageFailedBuild:
+			r.summary.Failures = append(r.summary.Failures, &lib.CmdFailure{Module: a, Err: err})
+		}
+		r.mutex.Unlock()
+
+		next(a, s, err)
+	}
+}
+
+// notify sends the build results to the configured notifiers.
+// Failures to notify are logged without failing the build.
+func notify(summary *lib.BuildSummary) {
+	if recorder == nil || dryRun {
+		return
+	}
+
+	n, err := notifiers()
+	if err != nil {
+		logrus.Warn(err)
+		return
+	}
+
+	if summary == nil {
+		summary = recorder.summary
+	}
+
+	if err := lib.SendNotifications(summary, n...); err != nil {
+		logrus.Warnf("Failed to send build notifications: %v", err)
+	}
+}
@@ -0,0 +1,26 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import "github.com/mbtproject/mbt/lib"
+
+var odbOptions = &lib.ODBOptions{}
+
+func init() {
+	RootCmd.PersistentFlags().IntVar(&odbOptions.CacheSize, "odb-cache-size", 0, "Maximum size of git objects cached in memory in MB (0 uses the default of 256)")
+	RootCmd.PersistentFlags().IntVar(&odbOptions.MappedLimit, "odb-mapped-limit", 0, "Maximum size of git pack files mapped into memory in MB (0 uses the default)")
+	RootCmd.PersistentFlags().BoolVar(&odbOptions.PreloadPacks, "preload-packs", false, "Load the indexes of all git pack files when the repository is opened")
+}
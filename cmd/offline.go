@@ -0,0 +1,68 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"github.com/mbtproject/mbt/lib"
+	"github.com/spf13/cobra"
+)
+
+var offline bool
+
+// networkOptions are the options of commands which require network
+// access when specified.
+var networkOptions = []string{
+	"pr",
+	"remote-cache",
+	"notify-slack",
+	"notify-webhook",
+	"notify-email",
+	"status-provider",
+	"sign",
+}
+
+func init() {
+	RootCmd.PersistentFlags().BoolVar(&offline, "offline", false, "Forbid network access and fail operations requiring it")
+}
+
+// checkOffline enables offline mode when specified and fails fast if
+// cmd is configured to perform an operation requiring network access.
+func checkOffline(cmd *cobra.Command) error {
+	lib.SetOffline(offline)
+	if !offline {
+		return nil
+	}
+
+	for _, o := range networkOptions {
+		f := cmd.Flags().Lookup(o)
+		if f == nil || f.Value.String() == f.DefValue {
+			continue
+		}
+
+		if err := lib.RequireNetwork("--" + o); err != nil {
+			return err
+		}
+	}
+
+	executors := map[string]string{"executor": executor, "reproducible-executor": reproducibleExecutor}
+	for _, o := range []string{"executor", "reproducible-executor"} {
+		if x := executors[o]; x == "kubernetes" || x == "ssh" {
+			return lib.RequireNetwork("--" + o + " " + x)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,47 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"github.com/mbtproject/mbt/lib"
+	"github.com/spf13/cobra"
+)
+
+// Flags used to control how the output of each module is
+// written to the console.
+var (
+	outputMode string
+	noColor    bool
+)
+
+var outputModes = map[string]lib.OutputMode{
+	"raw":    lib.OutputModeRaw,
+	"prefix": lib.OutputModePrefixed,
+	"group":  lib.OutputModeGrouped,
+}
+
+func init() {
+	for _, c := range []*cobra.Command{buildCommand, runIn} {
+		c.PersistentFlags().StringVar(&outputMode, "output", "raw", "How the output of modules is written (available options are 'raw', 'prefix' and 'group')")
+		c.PersistentFlags().BoolVar(&noColor, "no-color", false, "Do not highlight module name prefixes")
+	}
+}
+
+func withOutputOptions(options *lib.CmdOptions) *lib.CmdOptions {
+	options.OutputMode = outputModes[outputMode]
+	options.Color = !noColor
+	return options
+}
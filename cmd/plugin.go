@@ -0,0 +1,81 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/mbtproject/mbt/lib"
+	"github.com/spf13/cobra"
+)
+
+var (
+	listPlugins bool
+)
+
+func init() {
+	pluginCommand.Flags().BoolVar(&listPlugins, "list", false, "List the plugins found in PATH")
+
+	pluginCommand.Flags().StringVar(&src, "src", "", "Source branch")
+	pluginCommand.Flags().StringVar(&dst, "dst", "", "Destination branch")
+	pluginCommand.Flags().StringVar(&from, "from", "", "From commit")
+	pluginCommand.Flags().StringVar(&to, "to", "", "To commit")
+	pluginCommand.Flags().BoolVarP(&all, "all", "a", false, "All modules")
+	pluginCommand.Flags().BoolVarP(&content, "content", "c", false, "Pass the modules impacted by the content of the commit")
+	pluginCommand.Flags().StringVarP(&name, "name", "n", "", "Pass modules with a name that matches this value. Multiple names can be specified as a comma separated string.")
+	pluginCommand.Flags().BoolVarP(&fuzzy, "fuzzy", "f", false, "Use fuzzy match when filtering")
+	pluginCommand.Flags().StringVar(&filterExpr, "expr", "", "Filter modules with an expression")
+	pluginCommand.Flags().StringVar(&owner, "owner", "", "Filter modules owned by this owner according to CODEOWNERS")
+	pluginCommand.Flags().StringVar(&group, "group", "", "Filter modules in this group")
+
+	RootCmd.AddCommand(pluginCommand)
+}
+
+var pluginCommand = &cobra.Command{
+	Use:   "plugin <name> <branch|commit|diff|head|local|pr> [args] [-- plugin-args]",
+	Short: docText("plugin-summary"),
+	Long:  docText("plugin"),
+	RunE: buildHandler(func(cmd *cobra.Command, args []string) error {
+		if listPlugins {
+			for _, p := range lib.Plugins() {
+				fmt.Println(p)
+			}
+			return nil
+		}
+
+		var pluginArgs []string
+		if dash := cmd.ArgsLenAtDash(); dash >= 0 {
+			pluginArgs = args[dash:]
+			args = args[:dash]
+		}
+
+		if len(args) < 2 {
+			return errors.New("requires the plugin and the modules to pass to it")
+		}
+
+		m, err := manifestByMode(args[1], args[2:])
+		if err != nil {
+			return err
+		}
+
+		if err := checkSelection(m.Modules); err != nil {
+			return err
+		}
+
+		return lib.RunPlugin(args[0], m, &lib.PluginOptions{Args: pluginArgs})
+	}),
+}
@@ -0,0 +1,120 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/mbtproject/mbt/e"
+	"github.com/mbtproject/mbt/lib"
+	"github.com/spf13/cobra"
+)
+
+var (
+	policies []string
+	opa      string
+)
+
+func init() {
+	RootCmd.PersistentFlags().StringSliceVar(&policies, "policy", nil, "Policy enforced on modules (.rego or expression policy file)")
+	RootCmd.PersistentFlags().StringVar(&opa, "opa", "", "Path to opa used to evaluate Rego policies")
+
+	policyCommand.Flags().BoolVar(&toJSON, "json", false, "Format output as json")
+	policyCommand.Flags().StringVar(&src, "src", "", "Source branch")
+	policyCommand.Flags().StringVar(&dst, "dst", "", "Destination branch")
+	policyCommand.Flags().StringVar(&from, "from", "", "From commit")
+	policyCommand.Flags().StringVar(&to, "to", "", "To commit")
+	policyCommand.Flags().BoolVarP(&all, "all", "a", false, "All modules")
+	policyCommand.Flags().BoolVarP(&content, "content", "c", false, "Check the modules impacted by the content of the commit")
+	policyCommand.Flags().StringVarP(&name, "name", "n", "", "Check modules with a name that matches this value. Multiple names can be specified as a comma separated string.")
+	policyCommand.Flags().BoolVarP(&fuzzy, "fuzzy", "f", false, "Use fuzzy match when filtering")
+	policyCommand.Flags().StringVar(&filterExpr, "expr", "", "Filter modules with an expression")
+	policyCommand.Flags().StringVar(&owner, "owner", "", "Filter modules owned by this owner according to CODEOWNERS")
+	policyCommand.Flags().StringVar(&group, "group", "", "Filter modules in this group")
+
+	RootCmd.AddCommand(policyCommand)
+}
+
+var policyCommand = &cobra.Command{
+	Use:   "policy <branch|commit|diff|head|local|pr> [args]",
+	Short: docText("policy-summary"),
+	Long:  docText("policy"),
+	RunE: buildHandler(func(cmd *cobra.Command, args []string) error {
+		if len(args) == 0 {
+			return errors.New("requires the modules to check")
+		}
+
+		if len(policies) == 0 {
+			return errors.New("requires the policies to check, specify --policy argument")
+		}
+
+		p, err := loadPolicies()
+		if err != nil {
+			return err
+		}
+
+		m, err := manifestByMode(args[0], args[1:])
+		if err != nil {
+			return err
+		}
+
+		violations, err := lib.EvaluatePolicies(m, p...)
+		if err != nil {
+			return err
+		}
+
+		if toJSON {
+			buff, err := json.MarshalIndent(violations, "", "  ")
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(buff))
+		} else {
+			w := tabwriter.NewWriter(os.Stdout, 0, 4, 4, ' ', 0)
+			fmt.Fprintf(w, "Name\tPOLICY\tLEVEL\tMESSAGE\n")
+			for _, v := range violations {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", v.Module, v.Policy, v.Level, v.Message)
+			}
+			if err := w.Flush(); err != nil {
+				return err
+			}
+		}
+
+		for _, v := range violations {
+			if v.Level == lib.PolicyLevelError {
+				return e.NewError(lib.ErrClassUser, "modules violate policies")
+			}
+		}
+		return nil
+	}),
+}
+
+func loadPolicies() ([]lib.Policy, error) {
+	loaded := make([]lib.Policy, 0, len(policies))
+	for _, path := range policies {
+		p, err := lib.LoadPolicy(path, opa)
+		if err != nil {
+			return nil, err
+		}
+		loaded = append(loaded, p)
+	}
+
+	return loaded, nil
+}
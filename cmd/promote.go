@@ -0,0 +1,125 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/mbtproject/mbt/lib"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	fromEnv string
+	toEnv   string
+	apps    []string
+	render  string
+)
+
+func init() {
+	promoteCommand.Flags().StringVar(&fromEnv, "from", "", "Environment to promote from")
+	promoteCommand.Flags().StringVar(&toEnv, "to", "", "Environment to promote to")
+	promoteCommand.Flags().StringSliceVar(&apps, "apps", nil, "Modules to promote (default all modules deployed to the source environment)")
+	promoteCommand.Flags().StringVar(&deploymentsFile, "deployments-file", "", "File to persist deployments (default .git/mbt/deployments.json)")
+	promoteCommand.Flags().StringVar(&render, "render", "", "Format of the manifests generated for the promoted versions (kubernetes, kustomize or helm-values)")
+	promoteCommand.Flags().StringVar(&registry, "registry", "", "Registry prefixed to the image of each module")
+	promoteCommand.Flags().StringVar(&namespace, "namespace", "", "Namespace of the generated resources")
+	promoteCommand.Flags().StringSliceVar(&properties, "property", nil, "Module properties included in Helm values")
+	promoteCommand.Flags().StringVar(&out, "out", "", "Output path of the generated manifests")
+
+	RootCmd.AddCommand(promoteCommand)
+}
+
+var promoteCommand = &cobra.Command{
+	Use:   "promote --from <env> --to <env> [--apps <name>]",
+	Short: docText("promote-summary"),
+	Long:  docText("promote"),
+	RunE: buildHandler(func(cmd *cobra.Command, args []string) error {
+		if fromEnv == "" {
+			return errors.New("requires the environment to promote from, specify --from argument")
+		}
+
+		if toEnv == "" {
+			return errors.New("requires the environment to promote to, specify --to argument")
+		}
+
+		promoted, err := lib.PromoteDeployments(deploymentStore(), fromEnv, toEnv, apps)
+		if err != nil {
+			return err
+		}
+
+		for _, d := range promoted {
+			logrus.Infof("PROMOTED %s %s from %s to %s", d.Module, d.Version, fromEnv, toEnv)
+		}
+
+		if render == "" {
+			return nil
+		}
+
+		mods, err := deployedModules(promoted)
+		if err != nil {
+			return err
+		}
+
+		s, err := generate(render, mods)
+		if err != nil {
+			return err
+		}
+
+		output, err := getOutput(out)
+		if err != nil {
+			return err
+		}
+
+		_, err = fmt.Fprint(output, s)
+		return err
+	}),
+}
+
+// deployedModules resolves the modules of deployments from the
+// manifests of the commits they were deployed from.
+func deployedModules(deployments []*lib.Deployment) (lib.Modules, error) {
+	manifests := make(map[string]*lib.Manifest)
+	mods := make(lib.Modules, 0, len(deployments))
+	for _, d := range deployments {
+		m, ok := manifests[d.Commit]
+		if !ok {
+			var err error
+			m, err = system.ManifestByCommit(d.Commit)
+			if err != nil {
+				return nil, err
+			}
+			manifests[d.Commit] = m
+		}
+
+		found := false
+		for _, mod := range m.Modules {
+			if mod.Name() == d.Module {
+				mods = append(mods, mod)
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return nil, fmt.Errorf("module '%s' is not found in commit %s", d.Module, d.Commit)
+		}
+	}
+
+	return mods, nil
+}
@@ -0,0 +1,57 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"os"
+
+	"github.com/mbtproject/mbt/lib"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	prID      string
+	prOptions = &lib.PullRequestOptions{}
+)
+
+func init() {
+	RootCmd.PersistentFlags().StringVar(&prID, "pr", "", "Pull request or Gerrit change resolved into --src and --dst")
+	RootCmd.PersistentFlags().StringVar(&prOptions.Provider, "pr-provider", lib.PullRequestProviderBitbucketServer, "Hosting service of the pull request (bitbucket, bitbucket-cloud or gerrit)")
+	RootCmd.PersistentFlags().StringVar(&prOptions.API, "pr-api", "", "Base URL of the provider API")
+	RootCmd.PersistentFlags().StringVar(&prOptions.Repo, "pr-repo", "", "Repository in the provider API (e.g. project/slug)")
+	RootCmd.PersistentFlags().StringVar(&prOptions.User, "pr-user", "", "User name for basic authentication with the provider (e.g. Bitbucket Cloud app passwords)")
+	RootCmd.PersistentFlags().StringVar(&prOptions.Remote, "pr-remote", "origin", "Git remote the pull request is fetched from")
+}
+
+// resolvePullRequest fetches the pull request specified with --pr and
+// points --src and --dst to its head and destination branch.
+func resolvePullRequest() error {
+	if prID == "" {
+		return nil
+	}
+
+	prOptions.Token = os.Getenv("MBT_PR_TOKEN")
+	prOptions.Dst = dst
+	pr, err := lib.ResolvePullRequest(in, prID, prOptions)
+	if err != nil {
+		return err
+	}
+
+	logrus.Debugf("Resolved pull request %s from %s into %s", pr.ID, pr.SrcBranch, pr.DstBranch)
+	src = pr.Src
+	dst = pr.Dst
+	return nil
+}
@@ -0,0 +1,231 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/mbtproject/mbt/e"
+	"github.com/mbtproject/mbt/lib"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	releaseName  string
+	releaseSince string
+	releaseOut   string
+	releaseSha   string
+	verifyFiles  bool
+)
+
+func init() {
+	releaseBranchCommand.Flags().StringVar(&releaseName, "name", "", "Name of the release used to tag the released modules")
+	releaseBranchCommand.Flags().StringVar(&releaseSince, "since", "", "Commit of the previous release (default release all modules)")
+	releaseBranchCommand.Flags().StringVar(&releaseOut, "out", "release.json", "File to write the release manifest")
+	releaseBranchCommand.Flags().BoolVar(&keepGoing, "keep-going", false, "Continue building the remaining modules when a build fails")
+	releaseBranchCommand.Flags().BoolVar(&dryRun, "dry-run", false, "Print the modules to release without building, tagging or publishing them")
+	releaseBranchCommand.Flags().StringVar(&logDir, "log-dir", "", "Directory to store the output of each module")
+	releaseBranchCommand.Flags().IntVar(&parallelism, "parallelism", 1, "Capacity available to build modules concurrently")
+	releaseBranchCommand.Flags().BoolVar(&toJSON, "json", false, "Format output as json")
+
+	releaseDiffCommand.Flags().BoolVar(&toJSON, "json", false, "Format output as json")
+
+	releaseValidateCommand.Flags().StringVar(&releaseSha, "sha", "", "Commit to validate the release against (default commit of the release)")
+	releaseValidateCommand.Flags().BoolVar(&verifyFiles, "artifacts", false, "Verify the digests of the released artifacts as well")
+	releaseValidateCommand.Flags().StringVar(&artifactsDir, "dir", "", "Directory containing the artifacts (default path to repo)")
+	releaseValidateCommand.Flags().BoolVar(&toJSON, "json", false, "Format output as json")
+
+	releaseCommand.AddCommand(releaseBranchCommand)
+	releaseCommand.AddCommand(releaseDiffCommand)
+	releaseCommand.AddCommand(releaseValidateCommand)
+	RootCmd.AddCommand(releaseCommand)
+}
+
+var releaseCommand = &cobra.Command{
+	Use:   "release",
+	Short: docText("release-summary"),
+	Long:  docText("release"),
+}
+
+var releaseBranchCommand = &cobra.Command{
+	Use:   "branch <branch> --name <release>",
+	Short: docText("release-branch-summary"),
+	Long:  docText("release-branch"),
+	RunE: buildHandler(func(cmd *cobra.Command, args []string) error {
+		if len(args) == 0 {
+			return errors.New("requires the branch to release")
+		}
+
+		if releaseName == "" {
+			return errors.New("requires the name of the release, specify --name argument")
+		}
+
+		r, err := system.Release(args[0], &lib.ReleaseOptions{
+			Name:  releaseName,
+			Since: releaseSince,
+			Path:  releaseOut,
+			Cmd:   targetCmdOptions(buildStageCB),
+		})
+		if err != nil {
+			return err
+		}
+
+		if toJSON {
+			buff, err := json.MarshalIndent(r, "", "  ")
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(buff))
+			return nil
+		}
+
+		for _, m := range r.Modules {
+			if dryRun {
+				logrus.Infof("RELEASE %s %s as %s", m.Name, m.Version, m.Tag)
+				continue
+			}
+			logrus.Infof("RELEASED %s %s as %s (%v changes, %v artifacts, published: %v)", m.Name, m.Version, m.Tag, len(m.Changes), len(m.Artifacts), m.Published)
+		}
+
+		if !dryRun {
+			logrus.Infof("Release %s of commit %s written to %s", r.Name, r.Sha, releaseOut)
+		}
+		return nil
+	}),
+}
+
+var releaseDiffCommand = &cobra.Command{
+	Use:   "diff <release-file> <release-file>",
+	Short: docText("release-diff-summary"),
+	Long:  docText("release-diff"),
+	RunE: buildHandler(func(cmd *cobra.Command, args []string) error {
+		if len(args) != 2 {
+			return errors.New("requires the release manifests to compare")
+		}
+
+		a, err := lib.ReadReleaseManifest(args[0])
+		if err != nil {
+			return err
+		}
+
+		b, err := lib.ReadReleaseManifest(args[1])
+		if err != nil {
+			return err
+		}
+
+		changes := lib.DiffReleases(a, b)
+		if toJSON {
+			buff, err := json.MarshalIndent(changes, "", "  ")
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(buff))
+			return nil
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 4, ' ', 0)
+		fmt.Fprintf(w, "Name\t%s\t%s\tSTATUS\n", orDash(a.Name), orDash(b.Name))
+		for _, c := range changes {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", c.Module, orDash(c.From), orDash(c.To), c.Status)
+		}
+		return w.Flush()
+	}),
+}
+
+type releaseValidationReport struct {
+	Modules   []*lib.ReleaseValidation    `json:"modules"`
+	Artifacts []*lib.ArtifactVerification `json:"artifacts,omitempty"`
+}
+
+var releaseValidateCommand = &cobra.Command{
+	Use:   "validate <release-file>",
+	Short: docText("release-validate-summary"),
+	Long:  docText("release-validate"),
+	RunE: buildHandler(func(cmd *cobra.Command, args []string) error {
+		if len(args) != 1 {
+			return errors.New("requires the path to the release manifest")
+		}
+
+		r, err := lib.ReadReleaseManifest(args[0])
+		if err != nil {
+			return err
+		}
+
+		report := &releaseValidationReport{}
+		report.Modules, err = system.ValidateRelease(r, releaseSha)
+		if err != nil {
+			return err
+		}
+
+		if verifyFiles {
+			dir := artifactsDir
+			if dir == "" {
+				dir = in
+			}
+
+			report.Artifacts, err = lib.VerifyArtifacts(r.ArtifactManifest(), dir)
+			if err != nil {
+				return err
+			}
+		}
+
+		if toJSON {
+			buff, err := json.MarshalIndent(report, "", "  ")
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(buff))
+		} else {
+			w := tabwriter.NewWriter(os.Stdout, 0, 4, 4, ' ', 0)
+			fmt.Fprintf(w, "Name\tEXPECTED\tACTUAL\tSTATUS\n")
+			for _, v := range report.Modules {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", v.Module, v.Expected, orDash(v.Actual), v.Status)
+			}
+			if err := w.Flush(); err != nil {
+				return err
+			}
+
+			if verifyFiles {
+				fmt.Println()
+				w = tabwriter.NewWriter(os.Stdout, 0, 4, 4, ' ', 0)
+				fmt.Fprintf(w, "Name\tPATH\tSTATUS\n")
+				for _, a := range report.Artifacts {
+					fmt.Fprintf(w, "%s\t%s\t%s\n", a.Module, a.Path, a.Status)
+				}
+				if err := w.Flush(); err != nil {
+					return err
+				}
+			}
+		}
+
+		for _, v := range report.Modules {
+			if v.Status != lib.ReleaseValidationOK {
+				return e.NewError(lib.ErrClassUser, "modules do not match the release manifest")
+			}
+		}
+		for _, a := range report.Artifacts {
+			if a.Status != lib.ArtifactStatusOK {
+				return e.NewError(lib.ErrClassUser, "artifacts do not match the release manifest")
+			}
+		}
+		return nil
+	}),
+}
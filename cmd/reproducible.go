@@ -0,0 +1,65 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"github.com/mbtproject/mbt/lib"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	verifyReproducible   bool
+	reproducibleExecutor string
+	reproducible         = &lib.ReproducibleOptions{}
+)
+
+func init() {
+	buildCommand.PersistentFlags().BoolVar(&verifyReproducible, "verify-reproducible", false, "Build each module twice and report the modules with outputs differing between the builds")
+	buildCommand.PersistentFlags().StringVar(&reproducibleExecutor, "reproducible-executor", "", "Executor of the second build of each module (default --executor)")
+}
+
+// reportReproducibility logs the result of verifying the builds in
+// summary and returns an error if any of them is nondeterministic.
+func reportReproducibility(summary *lib.BuildSummary) error {
+	if !verifyReproducible || summary == nil || dryRun {
+		return nil
+	}
+
+	for _, r := range summary.Completed {
+		rep := r.Reproducibility
+		switch {
+		case rep == nil:
+		case rep.Outputs == 0:
+			logrus.Warnf("UNVERIFIED %s has no outputs to compare", r.Module.Name())
+		case rep.Reproducible():
+			logrus.Infof("REPRODUCIBLE %s (%v outputs)", r.Module.Name(), rep.Outputs)
+		default:
+			logrus.Errorf("NONDETERMINISTIC %s", r.Module.Name())
+			for _, d := range rep.Differences {
+				logrus.Errorf("  %s first: %s second: %s", d.Path, digestText(d.First), digestText(d.Second))
+			}
+		}
+	}
+
+	return lib.CheckReproducible(summary)
+}
+
+func digestText(digest string) string {
+	if digest == "" {
+		return "(missing)"
+	}
+	return digest
+}
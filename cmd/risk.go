@@ -0,0 +1,106 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/mbtproject/mbt/e"
+	"github.com/mbtproject/mbt/lib"
+	"github.com/spf13/cobra"
+)
+
+var (
+	failAbove int
+)
+
+func init() {
+	riskCommand.Flags().IntVar(&failAbove, "fail-above", -1, "Fail when the risk score of a module is above this value")
+	riskCommand.Flags().BoolVar(&toJSON, "json", false, "Format output as json")
+
+	riskCommand.Flags().StringVar(&src, "src", "", "Source branch")
+	riskCommand.Flags().StringVar(&dst, "dst", "", "Destination branch")
+	riskCommand.Flags().StringVar(&from, "from", "", "From commit")
+	riskCommand.Flags().StringVar(&to, "to", "", "To commit")
+	riskCommand.Flags().BoolVarP(&content, "content", "c", false, "Assess the modules impacted by the content of the commit")
+	riskCommand.Flags().StringVarP(&name, "name", "n", "", "Assess modules with a name that matches this value. Multiple names can be specified as a comma separated string.")
+	riskCommand.Flags().BoolVarP(&fuzzy, "fuzzy", "f", false, "Use fuzzy match when filtering")
+	riskCommand.Flags().StringVar(&filterExpr, "expr", "", "Filter modules with an expression")
+	riskCommand.Flags().StringVar(&owner, "owner", "", "Filter modules owned by this owner according to CODEOWNERS")
+	riskCommand.Flags().StringVar(&group, "group", "", "Filter modules in this group")
+
+	RootCmd.AddCommand(riskCommand)
+}
+
+var riskCommand = &cobra.Command{
+	Use:   "risk <commit|diff|local|pr> [args]",
+	Short: docText("risk-summary"),
+	Long:  docText("risk"),
+	RunE: buildHandler(func(cmd *cobra.Command, args []string) error {
+		if len(args) < 1 {
+			return errors.New("requires the changes to assess")
+		}
+
+		m, err := manifestByMode(args[0], args[1:])
+		if err != nil {
+			return err
+		}
+
+		if err := checkSelection(m.Modules); err != nil {
+			return err
+		}
+
+		scores, err := lib.AssessRisk(m)
+		if err != nil {
+			return err
+		}
+
+		if toJSON {
+			buff, err := json.MarshalIndent(scores, "", "  ")
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(buff))
+		} else {
+			tw := tabwriter.NewWriter(os.Stdout, 0, 4, 4, ' ', 0)
+			fmt.Fprintf(tw, "Name\tSCORE\tLEVEL\tFILES\tLINES\tDEPENDENTS\tDAYS SINCE LAST CHANGE\n")
+			for _, s := range scores {
+				days := "-"
+				if s.Factors.DaysSinceLastChange >= 0 {
+					days = fmt.Sprint(s.Factors.DaysSinceLastChange)
+				}
+				fmt.Fprintf(tw, "%s\t%v\t%s\t%v\t%v\t%v\t%s\n", s.Module, s.Score, s.Level, s.Factors.Files, s.Factors.Lines, s.Factors.Dependents, days)
+			}
+			if err := tw.Flush(); err != nil {
+				return err
+			}
+		}
+
+		if failAbove >= 0 {
+			for _, s := range scores {
+				if s.Score > failAbove {
+					return e.NewErrorf(lib.ErrClassUser, "risk score of module %v is %v (above %v)", s.Module, s.Score, failAbove)
+				}
+			}
+		}
+
+		return nil
+	}),
+}
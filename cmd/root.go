@@ -17,6 +17,7 @@ package cmd
 
 import (
 	"os"
+	"time"
 
 	"github.com/mbtproject/mbt/e"
 	"github.com/mbtproject/mbt/lib"
@@ -26,27 +27,57 @@ import (
 
 // Flags available to all commands.
 var (
-	in       string
-	src      string
-	dst      string
-	from     string
-	to       string
-	first    string
-	second   string
-	kind     string
-	name     string
-	command  string
-	all      bool
-	debug    bool
-	content  bool
-	fuzzy    bool
-	failFast bool
-	system   lib.System
+	in             string
+	src            string
+	dst            string
+	from           string
+	to             string
+	first          string
+	second         string
+	kind           string
+	name           string
+	command        string
+	all            bool
+	debug          bool
+	content        bool
+	fuzzy          bool
+	failFast       bool
+	keepGoing      bool
+	dryRun         bool
+	logDir         string
+	filterExpr     string
+	owner          string
+	group          string
+	parallelism    int
+	durationsFile  string
+	cacheDir       string
+	remoteCache    string
+	sbomDir        string
+	sbomFormat     string
+	sbomScanner    string
+	artifactsFile  string
+	provenanceDir  string
+	builderID      string
+	repoURI        string
+	sign           bool
+	cosign         string
+	specFile       string
+	terraform      bool
+	manifestScript string
+	versionFormat  string
+	allowEnv       []string
+	buildStarted   time.Time
+	system         lib.System
 )
 
 func init() {
 	RootCmd.PersistentFlags().StringVar(&in, "in", "", "Path to repo")
 	RootCmd.PersistentFlags().BoolVar(&debug, "debug", false, "Enable debug output")
+	RootCmd.PersistentFlags().StringVar(&specFile, "spec-file", lib.DefaultSpecFile, "Name of module spec files")
+	RootCmd.PersistentFlags().StringVar(&versionFormat, "version-format", lib.VersionFormatFull, "Format of module versions (full, base36 or the number of characters e.g. 7 or 12)")
+	RootCmd.PersistentFlags().StringVar(&manifestScript, "manifest-script", "", "Script post-processing the manifests, relative to the repository root")
+	RootCmd.PersistentFlags().BoolVar(&terraform, "terraform", false, "Discover Terraform root modules as modules")
+	RootCmd.PersistentFlags().StringSliceVar(&allowEnv, "allow-env", nil, "Environment variables expanded in commands of modules (e.g. JAVA_HOME,GO_*)")
 }
 
 // RootCmd is the main command.
@@ -56,7 +87,7 @@ var RootCmd = &cobra.Command{
 	Long:         docText("main"),
 	SilenceUsage: true,
 	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
-		if cmd.Use == "version" {
+		if cmd.Use == "version" || cmd == workspaceCommand || isCompletionCommand(cmd) {
 			return nil
 		}
 
@@ -71,6 +102,26 @@ var RootCmd = &cobra.Command{
 			}
 		}
 
+		if err := applyConfig(cmd); err != nil {
+			return err
+		}
+
+		if err := checkOffline(cmd); err != nil {
+			return err
+		}
+
+		if cmd == terraformCommand {
+			terraform = true
+		}
+
+		if cmd == debugProfileCommand {
+			profiler = lib.NewProfiler()
+		}
+
+		if err := resolvePullRequest(); err != nil {
+			return err
+		}
+
 		parent := cmd.Parent()
 		if parent != nil && parent.Name() == "run-in" && command == "" {
 			return e.NewError(lib.ErrClassUser, "--command (-m) is not specified")
@@ -79,14 +130,31 @@ var RootCmd = &cobra.Command{
 			return e.NewError(lib.ErrClassUser, "--dependents flag can only be specified with the --name (-n) flag")
 		}
 
+		if _, ok := outputModes[outputMode]; !ok {
+			return e.NewErrorf(lib.ErrClassUser, "invalid --output '%v' (available options are 'raw', 'prefix' and 'group')", outputMode)
+		}
+
 		level := lib.LogLevelNormal
 		if debug {
 			logrus.SetLevel(logrus.DebugLevel)
 			level = lib.LogLevelDebug
 		}
 
-		var err error
-		system, err = lib.NewSystem(in, level)
+		options, err := systemOptions(level)
+		if err != nil {
+			return err
+		}
+
+		// Policy command reports the violations instead of failing
+		// on them.
+		if cmd != policyCommand {
+			options.Policies, err = loadPolicies()
+			if err != nil {
+				return err
+			}
+		}
+
+		system, err = lib.NewSystemWithOptions(in, options)
 		return err
 	},
 }
@@ -0,0 +1,159 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/mbtproject/mbt/lib"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	runCommand.Flags().BoolVar(&keepGoing, "keep-going", false, "Continue running the task for the remaining modules when it fails")
+	runCommand.Flags().BoolVar(&dryRun, "dry-run", false, "Print the execution plan without executing it")
+	runCommand.Flags().StringVar(&logDir, "log-dir", "", "Directory to store the output of each module")
+	runCommand.Flags().IntVar(&parallelism, "parallelism", 1, "Capacity available to run the task concurrently")
+	runCommand.Flags().StringVar(&cacheDir, "cache-dir", "", "Directory to cache the outputs of tasks")
+	runCommand.Flags().StringVar(&remoteCache, "remote-cache", "", "URL of the HTTP server to cache the outputs of tasks")
+	runCommand.Flags().StringVar(&durationsFile, "durations-file", "", "File to persist task durations (default .git/mbt/durations.json)")
+
+	runCommand.Flags().StringVar(&src, "src", "", "Source branch")
+	runCommand.Flags().StringVar(&dst, "dst", "", "Destination branch")
+	runCommand.Flags().StringVar(&from, "from", "", "From commit")
+	runCommand.Flags().StringVar(&to, "to", "", "To commit")
+	runCommand.Flags().BoolVarP(&all, "all", "a", false, "All modules")
+	runCommand.Flags().BoolVarP(&content, "content", "c", false, "Run the task for the modules impacted by the content of the commit")
+	runCommand.Flags().StringVarP(&name, "name", "n", "", "Run the task for modules with a name that matches this value. Multiple names can be specified as a comma separated string.")
+	runCommand.Flags().BoolVarP(&fuzzy, "fuzzy", "f", false, "Use fuzzy match when filtering")
+	runCommand.Flags().StringVar(&filterExpr, "expr", "", "Filter modules with an expression")
+	runCommand.Flags().StringVar(&owner, "owner", "", "Filter modules owned by this owner according to CODEOWNERS")
+	runCommand.Flags().StringVar(&group, "group", "", "Filter modules in this group")
+
+	RootCmd.AddCommand(runCommand)
+}
+
+var runCommand = &cobra.Command{
+	Use:   "run <task> <branch|commit|diff|head|local|pr> [args]",
+	Short: docText("run-summary"),
+	Long:  docText("run"),
+	RunE: buildHandler(func(cmd *cobra.Command, args []string) error {
+		if len(args) < 2 {
+			return errors.New("requires the task and the modules to run it for")
+		}
+
+		task := args[0]
+		m, err := manifestByMode(args[1], args[2:])
+		if err != nil {
+			return err
+		}
+
+		options := targetCmdOptions(taskStageCB(task))
+		options.Cache = buildCache()
+		summary, err := system.RunTask(task, m, options)
+		return summariseTarget(&targetText{verb: "run " + task, done: "completed"}, summary, err)
+	}),
+}
+
+// manifestByMode creates the manifest of the modules selected by
+// mode (e.g. pr, branch) in the same way as build command.
+func manifestByMode(mode string, args []string) (*lib.Manifest, error) {
+	filter := &lib.FilterOptions{Name: name, Fuzzy: fuzzy, Expr: filterExpr, Owner: owner, Group: group}
+
+	switch mode {
+	case "branch":
+		branch := "master"
+		if len(args) > 0 {
+			branch = args[0]
+		}
+
+		m, err := system.ManifestByBranch(branch)
+		if err != nil {
+			return nil, err
+		}
+		return m.ApplyFilters(filter)
+	case "commit":
+		if len(args) == 0 {
+			return nil, errors.New("requires the commit sha")
+		}
+
+		if content {
+			return system.ManifestByCommitContent(args[0])
+		}
+
+		m, err := system.ManifestByCommit(args[0])
+		if err != nil {
+			return nil, err
+		}
+		return m.ApplyFilters(filter)
+	case "diff":
+		if from == "" {
+			return nil, errors.New("requires from commit")
+		}
+
+		if to == "" {
+			return nil, errors.New("requires to commit")
+		}
+
+		return system.ManifestByDiff(from, to)
+	case "head":
+		m, err := system.ManifestByCurrentBranch()
+		if err != nil {
+			return nil, err
+		}
+		return m.ApplyFilters(filter)
+	case "local":
+		if !all && name == "" {
+			return system.ManifestByWorkspaceChanges()
+		}
+
+		m, err := system.ManifestByWorkspace()
+		if err != nil {
+			return nil, err
+		}
+		return m.ApplyFilters(filter)
+	case "pr":
+		if src == "" {
+			return nil, errors.New("requires source")
+		}
+
+		if dst == "" {
+			return nil, errors.New("requires dest")
+		}
+
+		return system.ManifestByPr(src, dst)
+	}
+
+	return nil, fmt.Errorf("unknown mode '%s', expected one of branch, commit, diff, head, local or pr", mode)
+}
+
+func taskStageCB(task string) lib.CmdStageCallback {
+	return func(a *lib.Module, s lib.CmdStage, err error) {
+		switch s {
+		case lib.CmdStageBeforeBuild:
+			logrus.Infof("RUN %s %s in %s for %s", task, a.Name(), a.Path(), a.Version())
+		case lib.CmdStageSkipBuild:
+			logrus.Infof("SKIP %s in %s for %s", a.Name(), a.Path(), a.Version())
+		case lib.CmdStageFailedBuild:
+			logrus.Errorf("FAILED %s in %s for %s: %v", a.Name(), a.Path(), a.Version(), err)
+		case lib.CmdStageCachedBuild:
+			logrus.Infof("RESTORED %s in %s for %s", a.Name(), a.Path(), a.Version())
+		}
+	}
+}
@@ -38,16 +38,28 @@ func init() {
 	runInLocal.Flags().BoolVarP(&all, "all", "a", false, "All modules")
 	runInLocal.Flags().StringVarP(&name, "name", "n", "", "Build modules with a name that matches this value. Multiple names can be specified as a comma separated string.")
 	runInLocal.Flags().BoolVarP(&fuzzy, "fuzzy", "f", false, "Use fuzzy match when filtering")
+	runInLocal.Flags().StringVar(&filterExpr, "expr", "", "Filter modules with an expression")
+	runInLocal.Flags().StringVar(&owner, "owner", "", "Filter modules owned by this owner according to CODEOWNERS")
+	runInLocal.Flags().StringVar(&group, "group", "", "Filter modules in this group")
 
 	runInCommit.Flags().BoolVarP(&content, "content", "c", false, "Build the modules impacted by the content of the commit")
 	runInCommit.Flags().StringVarP(&name, "name", "n", "", "Build modules with a name that matches this value. Multiple names can be specified as a comma separated string.")
 	runInCommit.Flags().BoolVarP(&fuzzy, "fuzzy", "f", false, "Use fuzzy match when filtering")
+	runInCommit.Flags().StringVar(&filterExpr, "expr", "", "Filter modules with an expression")
+	runInCommit.Flags().StringVar(&owner, "owner", "", "Filter modules owned by this owner according to CODEOWNERS")
+	runInCommit.Flags().StringVar(&group, "group", "", "Filter modules in this group")
 
 	runInBranch.Flags().StringVarP(&name, "name", "n", "", "Build modules with a name that matches this value. Multiple names can be specified as a comma separated string.")
 	runInBranch.Flags().BoolVarP(&fuzzy, "fuzzy", "f", false, "Use fuzzy match when filtering")
+	runInBranch.Flags().StringVar(&filterExpr, "expr", "", "Filter modules with an expression")
+	runInBranch.Flags().StringVar(&owner, "owner", "", "Filter modules owned by this owner according to CODEOWNERS")
+	runInBranch.Flags().StringVar(&group, "group", "", "Filter modules in this group")
 
 	runInHead.Flags().StringVarP(&name, "name", "n", "", "Build modules with a name that matches this value. Multiple names can be specified as a comma separated string.")
 	runInHead.Flags().BoolVarP(&fuzzy, "fuzzy", "f", false, "Use fuzzy match when filtering")
+	runInHead.Flags().StringVar(&filterExpr, "expr", "", "Filter modules with an expression")
+	runInHead.Flags().StringVar(&owner, "owner", "", "Filter modules owned by this owner according to CODEOWNERS")
+	runInHead.Flags().StringVar(&group, "group", "", "Filter modules in this group")
 
 	runIn.AddCommand(runInBranch)
 	runIn.AddCommand(runInPr)
@@ -61,7 +73,7 @@ func init() {
 var runInHead = &cobra.Command{
 	Use: "head",
 	RunE: buildHandler(func(cmd *cobra.Command, args []string) error {
-		return summariseRun(system.RunInCurrentBranch(command, &lib.FilterOptions{Name: name, Fuzzy: fuzzy}, runInCmdOptions()))
+		return summariseRun(system.RunInCurrentBranch(command, &lib.FilterOptions{Name: name, Fuzzy: fuzzy, Expr: filterExpr, Owner: owner, Group: group}, runInCmdOptions()))
 	}),
 }
 
@@ -73,7 +85,7 @@ var runInBranch = &cobra.Command{
 			branch = args[0]
 		}
 
-		return summariseRun(system.RunInBranch(command, branch, &lib.FilterOptions{Name: name, Fuzzy: fuzzy}, runInCmdOptions()))
+		return summariseRun(system.RunInBranch(command, branch, &lib.FilterOptions{Name: name, Fuzzy: fuzzy, Expr: filterExpr, Owner: owner, Group: group}, runInCmdOptions()))
 	}),
 }
 
@@ -119,7 +131,7 @@ var runInCommit = &cobra.Command{
 		if content {
 			return summariseRun(system.RunInCommitContent(command, commit, runInCmdOptions()))
 		}
-		return summariseRun(system.RunInCommit(command, commit, &lib.FilterOptions{Name: name, Fuzzy: fuzzy}, runInCmdOptions()))
+		return summariseRun(system.RunInCommit(command, commit, &lib.FilterOptions{Name: name, Fuzzy: fuzzy, Expr: filterExpr, Owner: owner, Group: group}, runInCmdOptions()))
 	}),
 }
 
@@ -127,7 +139,7 @@ var runInLocal = &cobra.Command{
 	Use: "local [--all]",
 	RunE: buildHandler(func(cmd *cobra.Command, args []string) error {
 		if all || name != "" {
-			return summariseRun(system.RunInWorkspace(command, &lib.FilterOptions{Name: name, Fuzzy: fuzzy}, runInCmdOptions()))
+			return summariseRun(system.RunInWorkspace(command, &lib.FilterOptions{Name: name, Fuzzy: fuzzy, Expr: filterExpr, Owner: owner, Group: group}, runInCmdOptions()))
 		}
 
 		return summariseRun(system.RunInWorkspaceChanges(command, runInCmdOptions()))
@@ -156,8 +168,10 @@ func summariseRun(summary *lib.RunResult, err error) error {
 		logrus.Infof("Build finished for commit %v", summary.Manifest.Sha)
 
 		if len(summary.Failures) > 0 && failFast {
-			return e.NewError(lib.ErrClassUser, "One or more commands failed to run")
+			return e.NewError(lib.ErrClassBuild, "One or more commands failed to run")
 		}
+
+		return checkSelection(summary.Manifest.Modules)
 	}
 	return err
 }
@@ -165,7 +179,8 @@ func summariseRun(summary *lib.RunResult, err error) error {
 func runInCmdOptions() *lib.CmdOptions {
 	options := lib.CmdOptionsWithStdIO(runCmdStageCB)
 	options.FailFast = failFast
-	return options
+	options.AllowEnv = allowEnv
+	return withOutputOptions(options)
 }
 
 var runIn = &cobra.Command{
@@ -0,0 +1,81 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/mbtproject/mbt/lib"
+	"github.com/spf13/cobra"
+)
+
+var (
+	hotPaths int
+)
+
+func init() {
+	statsCommand.Flags().StringVar(&from, "from", "", "Exclude the commits reachable from this commit (default entire history)")
+	statsCommand.Flags().StringVar(&to, "to", "", "Include the commits reachable from this commit (default head of current branch)")
+	statsCommand.Flags().IntVar(&hotPaths, "hot-paths", 10, "Number of most frequently changed paths to report")
+	statsCommand.Flags().BoolVar(&toJSON, "json", false, "Format output as json")
+	RootCmd.AddCommand(statsCommand)
+}
+
+var statsCommand = &cobra.Command{
+	Use:   "stats",
+	Short: docText("stats-summary"),
+	Long:  docText("stats"),
+	RunE: buildHandler(func(cmd *cobra.Command, args []string) error {
+		stats, err := system.Stats(from, to, &lib.StatsOptions{HotPaths: hotPaths})
+		if err != nil {
+			return err
+		}
+
+		if toJSON {
+			buff, err := json.MarshalIndent(stats, "", "  ")
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(buff))
+			return nil
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 4, ' ', 0)
+		fmt.Fprintf(w, "Modules\t%v\n", stats.Modules)
+		fmt.Fprintf(w, "Commits\t%v\n", stats.Commits)
+		fmt.Fprintf(w, "Average commit manifest size\t%.2f\n", stats.AverageCommitManifestSize)
+		fmt.Fprintf(w, "Average merge manifest size\t%.2f\n", stats.AverageMergeManifestSize)
+
+		fmt.Fprintf(w, "\nWeek\tCOMMITS\tMODULES CHANGED\n")
+		for _, s := range stats.Weeks {
+			fmt.Fprintf(w, "%s\t%v\t%v\n", s.Week, s.Commits, s.Modules)
+		}
+
+		fmt.Fprintf(w, "\nPath\tCHANGES\n")
+		for _, p := range stats.HotPaths {
+			fmt.Fprintf(w, "%s\t%v\n", p.Path, p.Changes)
+		}
+
+		fmt.Fprintf(w, "\nName\tFAN-IN\tFAN-OUT\n")
+		for _, d := range stats.Dependencies {
+			fmt.Fprintf(w, "%s\t%v\t%v\n", d.Module, d.FanIn, d.FanOut)
+		}
+		return w.Flush()
+	}),
+}
@@ -0,0 +1,87 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/mbtproject/mbt/lib"
+	"github.com/spf13/cobra"
+)
+
+var (
+	chdir bool
+)
+
+func init() {
+	terraformCommand.Flags().BoolVar(&chdir, "chdir", false, "Format directories as -chdir arguments of terraform")
+	terraformCommand.Flags().BoolVar(&toJSON, "json", false, "Format output as json")
+
+	terraformCommand.Flags().StringVar(&src, "src", "", "Source branch")
+	terraformCommand.Flags().StringVar(&dst, "dst", "", "Destination branch")
+	terraformCommand.Flags().StringVar(&from, "from", "", "From commit")
+	terraformCommand.Flags().StringVar(&to, "to", "", "To commit")
+	terraformCommand.Flags().BoolVarP(&all, "all", "a", false, "All modules")
+	terraformCommand.Flags().BoolVarP(&content, "content", "c", false, "List the stacks impacted by the content of the commit")
+	terraformCommand.Flags().StringVarP(&name, "name", "n", "", "List stacks with a name that matches this value. Multiple names can be specified as a comma separated string.")
+	terraformCommand.Flags().BoolVarP(&fuzzy, "fuzzy", "f", false, "Use fuzzy match when filtering")
+	terraformCommand.Flags().StringVar(&filterExpr, "expr", "", "Filter modules with an expression")
+	terraformCommand.Flags().StringVar(&owner, "owner", "", "Filter modules owned by this owner according to CODEOWNERS")
+
+	RootCmd.AddCommand(terraformCommand)
+}
+
+var terraformCommand = &cobra.Command{
+	Use:   "terraform <branch|commit|diff|head|local|pr> [args]",
+	Short: docText("terraform-summary"),
+	Long:  docText("terraform"),
+	RunE: buildHandler(func(cmd *cobra.Command, args []string) error {
+		if len(args) < 1 {
+			return errors.New("requires the stacks to list")
+		}
+
+		m, err := manifestByMode(args[0], args[1:])
+		if err != nil {
+			return err
+		}
+
+		stacks := lib.TerraformStacks(m.Modules)
+
+		if toJSON {
+			buff, err := json.MarshalIndent(stacks, "", "  ")
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(buff))
+		} else {
+			for _, s := range stacks {
+				if chdir {
+					fmt.Printf("-chdir=%s\n", s.Dir)
+				} else {
+					fmt.Println(s.Dir)
+				}
+			}
+		}
+
+		if failOnEmpty && len(stacks) == 0 {
+			return errEmptySelection
+		}
+
+		return nil
+	}),
+}
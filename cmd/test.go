@@ -0,0 +1,176 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"errors"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/mbtproject/mbt/lib"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	testCommand.PersistentFlags().BoolVar(&keepGoing, "keep-going", false, "Continue testing the remaining modules when tests fail")
+	testCommand.PersistentFlags().BoolVar(&dryRun, "dry-run", false, "Print the test plan without executing it")
+	testCommand.PersistentFlags().StringVar(&logDir, "log-dir", "", "Directory to store the test output of each module")
+	testCommand.PersistentFlags().IntVar(&parallelism, "parallelism", 1, "Capacity available to test modules concurrently")
+	testCommand.PersistentFlags().StringVar(&durationsFile, "durations-file", "", "File to persist test durations (default .git/mbt/durations.json)")
+
+	testPr.Flags().StringVar(&src, "src", "", "Source branch")
+	testPr.Flags().StringVar(&dst, "dst", "", "Destination branch")
+
+	testDiff.Flags().StringVar(&from, "from", "", "From commit")
+	testDiff.Flags().StringVar(&to, "to", "", "To commit")
+
+	testLocal.Flags().BoolVarP(&all, "all", "a", false, "All modules")
+	testLocal.Flags().StringVarP(&name, "name", "n", "", "Test modules with a name that matches this value. Multiple names can be specified as a comma separated string.")
+	testLocal.Flags().BoolVarP(&fuzzy, "fuzzy", "f", false, "Use fuzzy match when filtering")
+	testLocal.Flags().StringVar(&filterExpr, "expr", "", "Filter modules with an expression")
+	testLocal.Flags().StringVar(&owner, "owner", "", "Filter modules owned by this owner according to CODEOWNERS")
+	testLocal.Flags().StringVar(&group, "group", "", "Filter modules in this group")
+
+	testCommit.Flags().BoolVarP(&content, "content", "c", false, "Test the modules impacted by the content of the commit")
+	testCommit.Flags().StringVarP(&name, "name", "n", "", "Test modules with a name that matches this value. Multiple names can be specified as a comma separated string.")
+	testCommit.Flags().BoolVarP(&fuzzy, "fuzzy", "f", false, "Use fuzzy match when filtering")
+	testCommit.Flags().StringVar(&filterExpr, "expr", "", "Filter modules with an expression")
+	testCommit.Flags().StringVar(&owner, "owner", "", "Filter modules owned by this owner according to CODEOWNERS")
+	testCommit.Flags().StringVar(&group, "group", "", "Filter modules in this group")
+
+	testBranch.Flags().StringVarP(&name, "name", "n", "", "Test modules with a name that matches this value. Multiple names can be specified as a comma separated string.")
+	testBranch.Flags().BoolVarP(&fuzzy, "fuzzy", "f", false, "Use fuzzy match when filtering")
+	testBranch.Flags().StringVar(&filterExpr, "expr", "", "Filter modules with an expression")
+	testBranch.Flags().StringVar(&owner, "owner", "", "Filter modules owned by this owner according to CODEOWNERS")
+	testBranch.Flags().StringVar(&group, "group", "", "Filter modules in this group")
+
+	testHead.Flags().StringVarP(&name, "name", "n", "", "Test modules with a name that matches this value. Multiple names can be specified as a comma separated string.")
+	testHead.Flags().BoolVarP(&fuzzy, "fuzzy", "f", false, "Use fuzzy match when filtering")
+	testHead.Flags().StringVar(&filterExpr, "expr", "", "Filter modules with an expression")
+	testHead.Flags().StringVar(&owner, "owner", "", "Filter modules owned by this owner according to CODEOWNERS")
+	testHead.Flags().StringVar(&group, "group", "", "Filter modules in this group")
+
+	testCommand.AddCommand(testBranch)
+	testCommand.AddCommand(testPr)
+	testCommand.AddCommand(testDiff)
+	testCommand.AddCommand(testHead)
+	testCommand.AddCommand(testCommit)
+	testCommand.AddCommand(testLocal)
+	RootCmd.AddCommand(testCommand)
+}
+
+var testText = &targetText{verb: "test", done: "tested"}
+
+var testHead = &cobra.Command{
+	Use: "head",
+	RunE: buildHandler(func(cmd *cobra.Command, args []string) error {
+		return summariseTests(system.TestCurrentBranch(&lib.FilterOptions{Name: name, Fuzzy: fuzzy, Expr: filterExpr, Owner: owner, Group: group}, testCmdOptions()))
+	}),
+}
+
+var testBranch = &cobra.Command{
+	Use: "branch <branch>",
+	RunE: buildHandler(func(cmd *cobra.Command, args []string) error {
+		branch := "master"
+		if len(args) > 0 {
+			branch = args[0]
+		}
+
+		return summariseTests(system.TestBranch(branch, &lib.FilterOptions{Name: name, Fuzzy: fuzzy, Expr: filterExpr, Owner: owner, Group: group}, testCmdOptions()))
+	}),
+}
+
+var testPr = &cobra.Command{
+	Use: "pr --src <branch> --dst <branch>",
+	RunE: buildHandler(func(cmd *cobra.Command, args []string) error {
+		if src == "" {
+			return errors.New("requires source")
+		}
+
+		if dst == "" {
+			return errors.New("requires dest")
+		}
+
+		return summariseTests(system.TestPr(src, dst, testCmdOptions()))
+	}),
+}
+
+var testDiff = &cobra.Command{
+	Use: "diff --from <sha> --to <sha>",
+	RunE: buildHandler(func(cmd *cobra.Command, args []string) error {
+		if from == "" {
+			return errors.New("requires from commit")
+		}
+
+		if to == "" {
+			return errors.New("requires to commit")
+		}
+
+		return summariseTests(system.TestDiff(from, to, testCmdOptions()))
+	}),
+}
+
+var testCommit = &cobra.Command{
+	Use: "commit <sha>",
+	RunE: buildHandler(func(cmd *cobra.Command, args []string) error {
+		if len(args) == 0 {
+			return errors.New("requires the commit sha")
+		}
+
+		commit := args[0]
+
+		if content {
+			return summariseTests(system.TestCommitContent(commit, testCmdOptions()))
+		}
+		return summariseTests(system.TestCommit(commit, &lib.FilterOptions{Name: name, Fuzzy: fuzzy, Expr: filterExpr, Owner: owner, Group: group}, testCmdOptions()))
+	}),
+}
+
+var testLocal = &cobra.Command{
+	Use: "local [--all]",
+	RunE: buildHandler(func(cmd *cobra.Command, args []string) error {
+		if all || name != "" {
+			return summariseTests(system.TestWorkspace(&lib.FilterOptions{Name: name, Fuzzy: fuzzy, Expr: filterExpr, Owner: owner, Group: group}, testCmdOptions()))
+		}
+
+		return summariseTests(system.TestWorkspaceChanges(testCmdOptions()))
+	}),
+}
+
+func testStageCB(a *lib.Module, s lib.CmdStage, err error) {
+	switch s {
+	case lib.CmdStageBeforeBuild:
+		logrus.Infof("TEST %s in %s for %s", a.Name(), a.Path(), a.Version())
+	case lib.CmdStageSkipBuild:
+		logrus.Infof("SKIP %s in %s for %s", a.Name(), a.Path(), a.Version())
+	case lib.CmdStageFailedBuild:
+		logrus.Errorf("FAILED %s in %s for %s: %v", a.Name(), a.Path(), a.Version(), err)
+	}
+}
+
+func summariseTests(summary *lib.BuildSummary, err error) error {
+	return summariseTarget(testText, summary, err)
+}
+
+func testCmdOptions() *lib.CmdOptions {
+	return targetCmdOptions(testStageCB)
+}
+
+var testCommand = &cobra.Command{
+	Use:   "test",
+	Short: docText("test-summary"),
+	Long:  docText("test"),
+}
@@ -0,0 +1,106 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/mbtproject/mbt/e"
+	"github.com/mbtproject/mbt/lib"
+	"github.com/spf13/cobra"
+)
+
+var (
+	artifactsDir          string
+	verifySignature       bool
+	certificateIdentity   string
+	certificateOIDCIssuer string
+)
+
+func init() {
+	verifyCommand.Flags().BoolVar(&toJSON, "json", false, "Format output as json")
+	verifyCommand.Flags().StringVar(&artifactsDir, "dir", "", "Directory containing the artifacts (default path to repo)")
+	verifyCommand.Flags().BoolVar(&verifySignature, "signature", false, "Verify the signature of the artifact manifest")
+	verifyCommand.Flags().StringVar(&certificateIdentity, "certificate-identity", "", "Identity expected in the signing certificate")
+	verifyCommand.Flags().StringVar(&certificateOIDCIssuer, "certificate-oidc-issuer", "", "OIDC issuer expected in the signing certificate")
+	verifyCommand.Flags().StringVar(&cosign, "cosign", "cosign", "Path to cosign binary")
+	RootCmd.AddCommand(verifyCommand)
+}
+
+var verifyCommand = &cobra.Command{
+	Use:   "verify <artifacts-file>",
+	Short: docText("verify-summary"),
+	Long:  docText("verify"),
+	RunE: buildHandler(func(cmd *cobra.Command, args []string) error {
+		if len(args) != 1 {
+			return errors.New("requires the path to the artifact manifest")
+		}
+
+		if verifySignature {
+			err := lib.VerifySignature(args[0], &lib.SignatureOptions{
+				Cosign:                cosign,
+				CertificateIdentity:   certificateIdentity,
+				CertificateOIDCIssuer: certificateOIDCIssuer,
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		a, err := lib.ReadArtifactManifest(args[0])
+		if err != nil {
+			return err
+		}
+
+		dir := artifactsDir
+		if dir == "" {
+			dir = in
+		}
+
+		results, err := lib.VerifyArtifacts(a, dir)
+		if err != nil {
+			return err
+		}
+
+		if toJSON {
+			buff, err := json.MarshalIndent(results, "", "  ")
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(buff))
+		} else {
+			w := tabwriter.NewWriter(os.Stdout, 0, 4, 4, ' ', 0)
+			fmt.Fprintf(w, "Name\tPATH\tSTATUS\n")
+			for _, r := range results {
+				fmt.Fprintf(w, "%s\t%s\t%s\n", r.Module, r.Path, r.Status)
+			}
+			if err := w.Flush(); err != nil {
+				return err
+			}
+		}
+
+		for _, r := range results {
+			if r.Status != lib.ArtifactStatusOK {
+				return e.NewError(lib.ErrClassUser, "artifacts do not match the artifact manifest")
+			}
+		}
+		return nil
+	}),
+}
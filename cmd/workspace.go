@@ -0,0 +1,120 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/mbtproject/mbt/e"
+	"github.com/mbtproject/mbt/lib"
+	"github.com/spf13/cobra"
+)
+
+var (
+	workspaceFile string
+)
+
+func init() {
+	workspaceCommand.Flags().StringVar(&workspaceFile, "file", lib.DefaultWorkspaceFile, "Path to the workspace file")
+	workspaceCommand.Flags().BoolVarP(&all, "all", "a", false, "Describe all modules in local mode")
+	workspaceCommand.Flags().StringVarP(&name, "name", "n", "", "Describe modules with a name that matches this value. Multiple names can be specified as a comma separated string.")
+	workspaceCommand.Flags().BoolVarP(&fuzzy, "fuzzy", "f", false, "Use fuzzy match when filtering")
+	workspaceCommand.Flags().BoolVar(&toJSON, "json", false, "Format output as json")
+	RootCmd.AddCommand(workspaceCommand)
+}
+
+var workspaceCommand = &cobra.Command{
+	Use:   "workspace <branch|head|local>",
+	Short: docText("workspace-summary"),
+	Long:  docText("workspace"),
+	RunE: buildHandler(func(cmd *cobra.Command, args []string) error {
+		if len(args) != 1 {
+			return errors.New("requires the mode")
+		}
+
+		w, err := lib.LoadWorkspaceFile(workspaceFile)
+		if err != nil {
+			return err
+		}
+
+		wm, err := lib.NewWorkspaceManifest(w, repositoryManifest(args[0]))
+		if err != nil {
+			return err
+		}
+
+		mods := wm.Modules()
+		if toJSON {
+			m := make(map[string]map[string]interface{})
+			for _, a := range mods {
+				m[a.Name] = map[string]interface{}{
+					"Name":       a.Module.Name(),
+					"Repository": a.Repository.Name,
+					"Path":       a.Module.Path(),
+					"Version":    a.Module.Version(),
+				}
+			}
+			buff, err := json.MarshalIndent(m, "", "  ")
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(buff))
+			return nil
+		}
+
+		tw := tabwriter.NewWriter(os.Stdout, 0, 4, 4, ' ', 0)
+		fmt.Fprintf(tw, "Name\tREPOSITORY\tPATH\tVERSION\n")
+		for _, a := range mods {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", a.Name, a.Repository.Name, a.Module.Path(), a.Module.Version())
+		}
+		return tw.Flush()
+	}),
+}
+
+// repositoryManifest creates the manifest of a repository in the
+// workspace according to mode.
+func repositoryManifest(mode string) func(*lib.WorkspaceRepository) (*lib.Manifest, error) {
+	return func(r *lib.WorkspaceRepository) (*lib.Manifest, error) {
+		s, err := lib.NewSystem(r.Path, lib.LogLevelNormal)
+		if err != nil {
+			return nil, err
+		}
+
+		var m *lib.Manifest
+		switch mode {
+		case "branch":
+			m, err = s.ManifestByBranch(r.Branch)
+		case "head":
+			m, err = s.ManifestByCurrentBranch()
+		case "local":
+			if !all && name == "" {
+				return s.ManifestByWorkspaceChanges()
+			}
+			m, err = s.ManifestByWorkspace()
+		default:
+			return nil, e.NewErrorf(lib.ErrClassUser, "invalid mode '%v' (available options are 'branch', 'head' and 'local')", mode)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		return m.ApplyFilters(&lib.FilterOptions{Name: name, Fuzzy: fuzzy})
+	}
+}
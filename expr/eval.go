@@ -0,0 +1,588 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package expr
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+type scope struct {
+	vars   map[string]interface{}
+	parent *scope
+}
+
+func (s *scope) lookup(name string) (interface{}, bool) {
+	for c := s; c != nil; c = c.parent {
+		if v, ok := c.vars[name]; ok {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+type node interface {
+	eval(s *scope) (interface{}, error)
+}
+
+type literalNode struct {
+	value interface{}
+}
+
+func (n *literalNode) eval(s *scope) (interface{}, error) {
+	return n.value, nil
+}
+
+type identNode struct {
+	name string
+}
+
+func (n *identNode) eval(s *scope) (interface{}, error) {
+	v, ok := s.lookup(n.name)
+	if !ok {
+		return nil, fmt.Errorf("undeclared reference to '%s'", n.name)
+	}
+	return v, nil
+}
+
+type memberNode struct {
+	target node
+	name   string
+}
+
+func (n *memberNode) eval(s *scope) (interface{}, error) {
+	t, err := n.target.eval(s)
+	if err != nil {
+		return nil, err
+	}
+
+	m, ok := t.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("cannot select field '%s' from %s", n.name, typeName(t))
+	}
+
+	v, ok := m[n.name]
+	if !ok {
+		return nil, fmt.Errorf("no such key: %s", n.name)
+	}
+	return v, nil
+}
+
+type hasNode struct {
+	member *memberNode
+}
+
+func (n *hasNode) eval(s *scope) (interface{}, error) {
+	t, err := n.member.target.eval(s)
+	if err != nil {
+		return nil, err
+	}
+
+	m, ok := t.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("cannot select field '%s' from %s", n.member.name, typeName(t))
+	}
+
+	_, ok = m[n.member.name]
+	return ok, nil
+}
+
+type indexNode struct {
+	target node
+	index  node
+}
+
+func (n *indexNode) eval(s *scope) (interface{}, error) {
+	t, err := n.target.eval(s)
+	if err != nil {
+		return nil, err
+	}
+
+	i, err := n.index.eval(s)
+	if err != nil {
+		return nil, err
+	}
+
+	switch c := t.(type) {
+	case []interface{}:
+		idx, ok := i.(int64)
+		if !ok {
+			return nil, fmt.Errorf("list index must be an int, found %s", typeName(i))
+		}
+		if idx < 0 || idx >= int64(len(c)) {
+			return nil, fmt.Errorf("index out of range: %v", idx)
+		}
+		return c[idx], nil
+	case map[string]interface{}:
+		k, ok := i.(string)
+		if !ok {
+			return nil, fmt.Errorf("map key must be a string, found %s", typeName(i))
+		}
+		v, ok := c[k]
+		if !ok {
+			return nil, fmt.Errorf("no such key: %s", k)
+		}
+		return v, nil
+	}
+
+	return nil, fmt.Errorf("cannot index %s", typeName(t))
+}
+
+type listNode struct {
+	elems []node
+}
+
+func (n *listNode) eval(s *scope) (interface{}, error) {
+	l := make([]interface{}, 0, len(n.elems))
+	for _, e := range n.elems {
+		v, err := e.eval(s)
+		if err != nil {
+			return nil, err
+		}
+		l = append(l, v)
+	}
+	return l, nil
+}
+
+type mapNode struct {
+	keys   []node
+	values []node
+}
+
+func (n *mapNode) eval(s *scope) (interface{}, error) {
+	m := make(map[string]interface{}, len(n.keys))
+	for i, kn := range n.keys {
+		k, err := kn.eval(s)
+		if err != nil {
+			return nil, err
+		}
+		ks, ok := k.(string)
+		if !ok {
+			return nil, fmt.Errorf("map key must be a string, found %s", typeName(k))
+		}
+		v, err := n.values[i].eval(s)
+		if err != nil {
+			return nil, err
+		}
+		m[ks] = v
+	}
+	return m, nil
+}
+
+type condNode struct {
+	c, t, f node
+}
+
+func (n *condNode) eval(s *scope) (interface{}, error) {
+	c, err := evalBool(n.c, s)
+	if err != nil {
+		return nil, err
+	}
+
+	if c {
+		return n.t.eval(s)
+	}
+	return n.f.eval(s)
+}
+
+type unaryNode struct {
+	op string
+	x  node
+}
+
+func (n *unaryNode) eval(s *scope) (interface{}, error) {
+	if n.op == "!" {
+		b, err := evalBool(n.x, s)
+		if err != nil {
+			return nil, err
+		}
+		return !b, nil
+	}
+
+	v, err := n.x.eval(s)
+	if err != nil {
+		return nil, err
+	}
+
+	switch x := v.(type) {
+	case int64:
+		return -x, nil
+	case float64:
+		return -x, nil
+	}
+
+	return nil, fmt.Errorf("cannot negate %s", typeName(v))
+}
+
+type binaryNode struct {
+	op   string
+	l, r node
+}
+
+func (n *binaryNode) eval(s *scope) (interface{}, error) {
+	switch n.op {
+	case "&&":
+		l, err := evalBool(n.l, s)
+		if err != nil || !l {
+			return false, err
+		}
+		return evalBool(n.r, s)
+	case "||":
+		l, err := evalBool(n.l, s)
+		if err != nil || l {
+			return l, err
+		}
+		return evalBool(n.r, s)
+	}
+
+	l, err := n.l.eval(s)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := n.r.eval(s)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.op {
+	case "==":
+		return equal(l, r), nil
+	case "!=":
+		return !equal(l, r), nil
+	case "<", "<=", ">", ">=":
+		return compare(n.op, l, r)
+	case "in":
+		return contains(r, l)
+	case "+":
+		if ls, ok := l.(string); ok {
+			if rs, ok := r.(string); ok {
+				return ls + rs, nil
+			}
+		}
+		if ll, ok := l.([]interface{}); ok {
+			if rl, ok := r.([]interface{}); ok {
+				return append(append([]interface{}{}, ll...), rl...), nil
+			}
+		}
+	}
+
+	return arithmetic(n.op, l, r)
+}
+
+type callNode struct {
+	target node
+	name   string
+	args   []node
+}
+
+func (n *callNode) eval(s *scope) (interface{}, error) {
+	args := make([]interface{}, 0, len(n.args)+1)
+	if n.target != nil {
+		t, err := n.target.eval(s)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, t)
+	}
+
+	for _, a := range n.args {
+		v, err := a.eval(s)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, v)
+	}
+
+	f, ok := functions[n.name]
+	if !ok {
+		return nil, fmt.Errorf("undeclared reference to function '%s'", n.name)
+	}
+
+	return f(args)
+}
+
+type macroNode struct {
+	target   node
+	name     string
+	variable string
+	body     node
+}
+
+func (n *macroNode) eval(s *scope) (interface{}, error) {
+	t, err := n.target.eval(s)
+	if err != nil {
+		return nil, err
+	}
+
+	var items []interface{}
+	switch c := t.(type) {
+	case []interface{}:
+		items = c
+	case map[string]interface{}:
+		for k := range c {
+			items = append(items, k)
+		}
+	default:
+		return nil, fmt.Errorf("%s() is not supported on %s", n.name, typeName(t))
+	}
+
+	results := make([]interface{}, 0, len(items))
+	matches := 0
+	for _, item := range items {
+		v, err := n.body.eval(&scope{vars: map[string]interface{}{n.variable: item}, parent: s})
+		if err != nil {
+			return nil, err
+		}
+
+		if n.name == "map" {
+			results = append(results, v)
+			continue
+		}
+
+		b, ok := v.(bool)
+		if !ok {
+			return nil, fmt.Errorf("%s() predicate must be a bool, found %s", n.name, typeName(v))
+		}
+
+		switch {
+		case n.name == "exists" && b:
+			return true, nil
+		case n.name == "all" && !b:
+			return false, nil
+		case b:
+			matches++
+			results = append(results, item)
+		}
+	}
+
+	switch n.name {
+	case "exists":
+		return false, nil
+	case "all":
+		return true, nil
+	case "exists_one":
+		return matches == 1, nil
+	}
+	return results, nil
+}
+
+func evalBool(n node, s *scope) (bool, error) {
+	v, err := n.eval(s)
+	if err != nil {
+		return false, err
+	}
+
+	b, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("expected a bool, found %s", typeName(v))
+	}
+	return b, nil
+}
+
+var functions = map[string]func(args []interface{}) (interface{}, error){
+	"size": func(args []interface{}) (interface{}, error) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("size() takes one argument")
+		}
+		switch v := args[0].(type) {
+		case string:
+			return int64(len([]rune(v))), nil
+		case []interface{}:
+			return int64(len(v)), nil
+		case map[string]interface{}:
+			return int64(len(v)), nil
+		}
+		return nil, fmt.Errorf("size() is not supported on %s", typeName(args[0]))
+	},
+	"string": func(args []interface{}) (interface{}, error) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("string() takes one argument")
+		}
+		return fmt.Sprintf("%v", args[0]), nil
+	},
+	"startsWith": stringFunction("startsWith", strings.HasPrefix),
+	"endsWith":   stringFunction("endsWith", strings.HasSuffix),
+	"contains":   stringFunction("contains", strings.Contains),
+	"matches": func(args []interface{}) (interface{}, error) {
+		if len(args) != 2 {
+			return nil, fmt.Errorf("matches() takes one argument")
+		}
+		a, ok1 := args[0].(string)
+		b, ok2 := args[1].(string)
+		if !ok1 || !ok2 {
+			return nil, fmt.Errorf("matches() is not supported on %s", typeName(args[0]))
+		}
+		r, err := regexp.Compile(b)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern '%s': %v", b, err)
+		}
+		return r.MatchString(a), nil
+	},
+}
+
+func stringFunction(name string, f func(a, b string) bool) func(args []interface{}) (interface{}, error) {
+	return func(args []interface{}) (interface{}, error) {
+		if len(args) != 2 {
+			return nil, fmt.Errorf("%s() takes one argument", name)
+		}
+		a, ok1 := args[0].(string)
+		b, ok2 := args[1].(string)
+		if !ok1 || !ok2 {
+			return nil, fmt.Errorf("%s() is not supported on %s", name, typeName(args[0]))
+		}
+		return f(a, b), nil
+	}
+}
+
+func equal(l, r interface{}) bool {
+	if lf, ok := toFloat(l); ok {
+		if rf, ok := toFloat(r); ok {
+			return lf == rf
+		}
+	}
+	return reflect.DeepEqual(l, r)
+}
+
+func compare(op string, l, r interface{}) (interface{}, error) {
+	var c int
+	lf, lok := toFloat(l)
+	rf, rok := toFloat(r)
+	ls, lsok := l.(string)
+	rs, rsok := r.(string)
+
+	switch {
+	case lok && rok:
+		c = cmpFloat(lf, rf)
+	case lsok && rsok:
+		c = strings.Compare(ls, rs)
+	default:
+		return nil, fmt.Errorf("cannot compare %s and %s", typeName(l), typeName(r))
+	}
+
+	switch op {
+	case "<":
+		return c < 0, nil
+	case "<=":
+		return c <= 0, nil
+	case ">":
+		return c > 0, nil
+	}
+	return c >= 0, nil
+}
+
+func cmpFloat(a, b float64) int {
+	if a < b {
+		return -1
+	}
+	if a > b {
+		return 1
+	}
+	return 0
+}
+
+func contains(container, item interface{}) (interface{}, error) {
+	switch c := container.(type) {
+	case []interface{}:
+		for _, i := range c {
+			if equal(i, item) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case map[string]interface{}:
+		k, ok := item.(string)
+		if !ok {
+			return false, nil
+		}
+		_, ok = c[k]
+		return ok, nil
+	}
+
+	return nil, fmt.Errorf("'in' is not supported on %s", typeName(container))
+}
+
+func arithmetic(op string, l, r interface{}) (interface{}, error) {
+	li, lint := l.(int64)
+	ri, rint := r.(int64)
+	if lint && rint {
+		switch op {
+		case "+":
+			return li + ri, nil
+		case "-":
+			return li - ri, nil
+		case "*":
+			return li * ri, nil
+		case "/", "%":
+			if ri == 0 {
+				return nil, fmt.Errorf("division by zero")
+			}
+			if op == "/" {
+				return li / ri, nil
+			}
+			return li % ri, nil
+		}
+	}
+
+	lf, lok := toFloat(l)
+	rf, rok := toFloat(r)
+	if lok && rok {
+		switch op {
+		case "+":
+			return lf + rf, nil
+		case "-":
+			return lf - rf, nil
+		case "*":
+			return lf * rf, nil
+		case "/":
+			return lf / rf, nil
+		}
+	}
+
+	return nil, fmt.Errorf("operator '%s' is not supported on %s and %s", op, typeName(l), typeName(r))
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int64:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
+
+func typeName(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "bool"
+	case int64:
+		return "int"
+	case float64:
+		return "double"
+	case string:
+		return "string"
+	case []interface{}:
+		return "list"
+	case map[string]interface{}:
+		return "map"
+	}
+	return fmt.Sprintf("%T", v)
+}
@@ -0,0 +1,187 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package expr implements the expression language of mbt used in
+// command conditions, filters and policies.
+//
+// The syntax borrows from Common Expression Language (CEL) but the
+// language is mbt's own. It is dynamically typed, has no type
+// declarations, timestamps, durations or protocol buffer messages, and
+// int and double values are compared and combined numerically
+// (1 == 1.0 is true, 1 + 0.5 is 1.5).
+//
+// # Grammar
+//
+//	Expr     = Or [ "?" Expr ":" Expr ] .
+//	Or       = And { "||" And } .
+//	And      = Relation { "&&" Relation } .
+//	Relation = Addition { ( "==" | "!=" | "<" | "<=" | ">" | ">=" | "in" ) Addition } .
+//	Addition = Product { ( "+" | "-" ) Product } .
+//	Product  = Unary { ( "*" | "/" | "%" ) Unary } .
+//	Unary    = ( "!" | "-" ) Unary | Member .
+//	Member   = Primary { "." IDENT [ "(" [ Args ] ")" ] | "[" Expr "]" } .
+//	Primary  = "true" | "false" | "null" | INT | DOUBLE | STRING
+//	         | IDENT [ "(" [ Args ] ")" ]
+//	         | "(" Expr ")"
+//	         | "[" [ Args ] "]"
+//	         | "{" [ Expr ":" Expr { "," Expr ":" Expr } ] "}" .
+//	Args     = Expr { "," Expr } .
+//
+// STRING is quoted with ' or ". \n, \t and \r are escapes for newline,
+// tab and carriage return, a backslash before any other character
+// stands for that character. Map keys must be strings.
+//
+// # Built-ins
+//
+//   - has(a.b) tests whether map a has key b
+//   - size(x) returns the length of a string, list or map
+//   - string(x) converts x to a string
+//   - s.startsWith(t), s.endsWith(t), s.contains(t) and s.matches(re)
+//     operate on strings, matches takes a Go regular expression
+//   - + concatenates strings and lists, in tests list membership and map keys
+//
+// Macros take an iteration variable and an expression evaluated for each
+// element of a list (or key of a map): l.all(x, p), l.exists(x, p),
+// l.exists_one(x, p), l.filter(x, p) and l.map(x, e).
+package expr
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+var errUnterminatedString = errors.New("unterminated string")
+
+// SyntaxError is returned when an expression cannot be parsed.
+type SyntaxError struct {
+	// Pos is the position of the offending character
+	Pos int
+	Msg string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("syntax error at position %v: %s", e.Pos, e.Msg)
+}
+
+// Expr is a parsed expression which can be evaluated many times.
+type Expr struct {
+	src  string
+	root node
+}
+
+// Parse parses the specified expression.
+func Parse(src string) (*Expr, error) {
+	tokens, err := tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+
+	root, err := (&parser{tokens: tokens}).parse()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Expr{src: src, root: root}, nil
+}
+
+// String returns the source of the expression.
+func (x *Expr) String() string {
+	return x.src
+}
+
+// Eval evaluates the expression with the specified variables.
+// Variables can be of any type composed of Go bool, numeric types,
+// string, slices and maps with string keys.
+func (x *Expr) Eval(vars map[string]interface{}) (interface{}, error) {
+	s := &scope{vars: make(map[string]interface{}, len(vars))}
+	for k, v := range vars {
+		s.vars[k] = Normalize(v)
+	}
+
+	return x.root.eval(s)
+}
+
+// EvalBool evaluates the expression and returns an error unless
+// the result is a bool.
+func (x *Expr) EvalBool(vars map[string]interface{}) (bool, error) {
+	v, err := x.Eval(vars)
+	if err != nil {
+		return false, err
+	}
+
+	b, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("expression must evaluate to a bool, found %s", typeName(v))
+	}
+
+	return b, nil
+}
+
+// Normalize converts a Go value to the representation used in
+// expressions. That is, all integers are converted to int64,
+// floats to float64, slices to []interface{} and maps to
+// map[string]interface{}.
+func Normalize(v interface{}) interface{} {
+	switch t := v.(type) {
+	case nil, bool, string, int64, float64:
+		return t
+	case []interface{}:
+		l := make([]interface{}, 0, len(t))
+		for _, i := range t {
+			l = append(l, Normalize(i))
+		}
+		return l
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(t))
+		for k, i := range t {
+			m[k] = Normalize(i)
+		}
+		return m
+	}
+
+	r := reflect.ValueOf(v)
+	switch r.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return r.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int64(r.Uint())
+	case reflect.Float32, reflect.Float64:
+		return r.Float()
+	case reflect.String:
+		return r.String()
+	case reflect.Bool:
+		return r.Bool()
+	case reflect.Slice, reflect.Array:
+		l := make([]interface{}, 0, r.Len())
+		for i := 0; i < r.Len(); i++ {
+			l = append(l, Normalize(r.Index(i).Interface()))
+		}
+		return l
+	case reflect.Map:
+		m := make(map[string]interface{}, r.Len())
+		for _, k := range r.MapKeys() {
+			m[fmt.Sprintf("%v", k.Interface())] = Normalize(r.MapIndex(k).Interface())
+		}
+		return m
+	case reflect.Ptr, reflect.Interface:
+		if r.IsNil() {
+			return nil
+		}
+		return Normalize(r.Elem().Interface())
+	}
+
+	return v
+}
@@ -0,0 +1,155 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package expr
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func eval(t *testing.T, src string, vars map[string]interface{}) interface{} {
+	x, err := Parse(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v, err := x.Eval(vars)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return v
+}
+
+func TestLiterals(t *testing.T) {
+	assert.Equal(t, int64(42), eval(t, "42", nil))
+	assert.Equal(t, 1.5, eval(t, "1.5", nil))
+	assert.Equal(t, "a'b", eval(t, `'a\'b'`, nil))
+	assert.Equal(t, "ab", eval(t, `"ab"`, nil))
+	assert.Equal(t, true, eval(t, "true", nil))
+	assert.Nil(t, eval(t, "null", nil))
+	assert.Equal(t, []interface{}{int64(1), "a"}, eval(t, "[1, 'a']", nil))
+	assert.Equal(t, map[string]interface{}{"a": int64(1)}, eval(t, "{'a': 1}", nil))
+}
+
+func TestOperators(t *testing.T) {
+	assert.Equal(t, int64(7), eval(t, "1 + 2 * 3", nil))
+	assert.Equal(t, int64(9), eval(t, "(1 + 2) * 3", nil))
+	assert.Equal(t, int64(1), eval(t, "7 % 3", nil))
+	assert.Equal(t, 2.5, eval(t, "5 / 2.0", nil))
+	assert.Equal(t, int64(-2), eval(t, "-2", nil))
+	assert.Equal(t, "ab", eval(t, "'a' + 'b'", nil))
+	assert.Equal(t, true, eval(t, "1 == 1.0", nil))
+	assert.Equal(t, 1.5, eval(t, "1 + 0.5", nil))
+	assert.Equal(t, []interface{}{int64(1), int64(2)}, eval(t, "[1] + [2]", nil))
+	assert.Equal(t, true, eval(t, "'a' < 'b' && 2 >= 2", nil))
+	assert.Equal(t, true, eval(t, "false || !false", nil))
+	assert.Equal(t, true, eval(t, "2 in [1, 2]", nil))
+	assert.Equal(t, false, eval(t, "'c' in {'a': 1}", nil))
+	assert.Equal(t, "y", eval(t, "1 > 2 ? 'x' : 'y'", nil))
+}
+
+func TestShortCircuit(t *testing.T) {
+	assert.Equal(t, false, eval(t, "false && a.b", nil))
+	assert.Equal(t, true, eval(t, "true || a.b", nil))
+}
+
+func TestVariables(t *testing.T) {
+	vars := map[string]interface{}{
+		"app": map[string]interface{}{
+			"name":       "app-a",
+			"properties": map[string]interface{}{"team": "payments", "replicas": 3},
+			"files":      []string{"db/a.sql", "main.go"},
+		},
+	}
+
+	assert.Equal(t, true, eval(t, "app.properties.team == 'payments'", vars))
+	assert.Equal(t, true, eval(t, "app.properties.replicas > 2", vars))
+	assert.Equal(t, "main.go", eval(t, "app.files[1]", vars))
+	assert.Equal(t, "payments", eval(t, "app['properties']['team']", vars))
+	assert.Equal(t, true, eval(t, "has(app.name)", vars))
+	assert.Equal(t, false, eval(t, "has(app.properties.owner)", vars))
+	assert.Equal(t, int64(2), eval(t, "size(app.files)", vars))
+	assert.Equal(t, int64(5), eval(t, "app.name.size()", vars))
+}
+
+func TestFunctions(t *testing.T) {
+	assert.Equal(t, true, eval(t, "'db/a.sql'.startsWith('db/')", nil))
+	assert.Equal(t, true, eval(t, "'db/a.sql'.endsWith('.sql')", nil))
+	assert.Equal(t, true, eval(t, "'db/a.sql'.contains('a.s')", nil))
+	assert.Equal(t, true, eval(t, "'db/a.sql'.matches('^db/.*')", nil))
+	assert.Equal(t, "1", eval(t, "string(1)", nil))
+}
+
+func TestMacros(t *testing.T) {
+	vars := map[string]interface{}{"files": []interface{}{"db/a.sql", "main.go"}}
+
+	assert.Equal(t, true, eval(t, "files.exists(f, f.endsWith('.sql'))", vars))
+	assert.Equal(t, false, eval(t, "files.all(f, f.endsWith('.sql'))", vars))
+	assert.Equal(t, true, eval(t, "files.exists_one(f, f.endsWith('.go'))", vars))
+	assert.Equal(t, []interface{}{"main.go"}, eval(t, "files.filter(f, f.endsWith('.go'))", vars))
+	assert.Equal(t, []interface{}{int64(8), int64(7)}, eval(t, "files.map(f, size(f))", vars))
+	assert.Equal(t, true, eval(t, "[].all(f, false)", vars))
+}
+
+func TestEvalBool(t *testing.T) {
+	x, err := Parse("1 + 1")
+	assert.NoError(t, err)
+
+	_, err = x.EvalBool(nil)
+	assert.EqualError(t, err, "expression must evaluate to a bool, found int")
+}
+
+func TestSyntaxErrors(t *testing.T) {
+	_, err := Parse("a ==")
+	assert.EqualError(t, err, "syntax error at position 4: expected an operand but found end of expression")
+
+	_, err = Parse("a b")
+	assert.EqualError(t, err, "syntax error at position 2: expected end of expression but found 'b'")
+
+	_, err = Parse("'abc")
+	assert.EqualError(t, err, "syntax error at position 0: unterminated string")
+
+	_, err = Parse("a # b")
+	assert.EqualError(t, err, "syntax error at position 2: unexpected character #")
+
+	_, err = Parse("has(a)")
+	assert.EqualError(t, err, "syntax error at position 0: has() requires a field selection")
+}
+
+func TestEvalErrors(t *testing.T) {
+	x, err := Parse("a.b")
+	assert.NoError(t, err)
+
+	_, err = x.Eval(nil)
+	assert.EqualError(t, err, "undeclared reference to 'a'")
+
+	_, err = x.Eval(map[string]interface{}{"a": map[string]interface{}{}})
+	assert.EqualError(t, err, "no such key: b")
+
+	x, err = Parse("'a' < 1")
+	assert.NoError(t, err)
+
+	_, err = x.Eval(nil)
+	assert.EqualError(t, err, "cannot compare string and int")
+
+	x, err = Parse("foo(1)")
+	assert.NoError(t, err)
+
+	_, err = x.Eval(nil)
+	assert.EqualError(t, err, "undeclared reference to function 'foo'")
+}
@@ -0,0 +1,150 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package expr
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokInt
+	tokFloat
+	tokString
+	tokPunct
+)
+
+type token struct {
+	kind  tokenKind
+	text  string
+	value interface{}
+	pos   int
+}
+
+// Punctuation tokens ordered so that longer tokens are matched first.
+var punctuations = []string{
+	"&&", "||", "==", "!=", "<=", ">=",
+	"(", ")", "[", "]", "{", "}", ".", ",", ":", "?",
+	"!", "<", ">", "+", "-", "*", "/", "%",
+}
+
+func tokenize(src string) ([]*token, error) {
+	tokens := make([]*token, 0)
+	runes := []rune(src)
+	i := 0
+
+	for i < len(runes) {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '_' || unicode.IsLetter(r):
+			start := i
+			for i < len(runes) && (runes[i] == '_' || unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i])) {
+				i++
+			}
+			tokens = append(tokens, &token{kind: tokIdent, text: string(runes[start:i]), pos: start})
+		case unicode.IsDigit(r):
+			start := i
+			kind := tokInt
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.') {
+				if runes[i] == '.' {
+					// Member access on a number is not supported, therefore
+					// a dot is always the decimal separator.
+					kind = tokFloat
+				}
+				i++
+			}
+			text := string(runes[start:i])
+			t := &token{kind: kind, text: text, pos: start}
+			if kind == tokInt {
+				v, err := strconv.ParseInt(text, 10, 64)
+				if err != nil {
+					return nil, &SyntaxError{Pos: start, Msg: "invalid number " + text}
+				}
+				t.value = v
+			} else {
+				v, err := strconv.ParseFloat(text, 64)
+				if err != nil {
+					return nil, &SyntaxError{Pos: start, Msg: "invalid number " + text}
+				}
+				t.value = v
+			}
+			tokens = append(tokens, t)
+		case r == '\'' || r == '"':
+			start := i
+			s, n, err := readString(runes[i:])
+			if err != nil {
+				return nil, &SyntaxError{Pos: start, Msg: err.Error()}
+			}
+			i += n
+			tokens = append(tokens, &token{kind: tokString, text: string(runes[start:i]), value: s, pos: start})
+		default:
+			matched := false
+			rest := string(runes[i:])
+			for _, p := range punctuations {
+				if strings.HasPrefix(rest, p) {
+					tokens = append(tokens, &token{kind: tokPunct, text: p, pos: i})
+					i += len([]rune(p))
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, &SyntaxError{Pos: i, Msg: "unexpected character " + string(r)}
+			}
+		}
+	}
+
+	return append(tokens, &token{kind: tokEOF, pos: len(runes)}), nil
+}
+
+// readString reads a quoted string literal from the beginning of
+// runes. Returns the unquoted value and the number of runes consumed.
+func readString(runes []rune) (string, int, error) {
+	quote := runes[0]
+	var b strings.Builder
+	i := 1
+	for i < len(runes) {
+		r := runes[i]
+		switch {
+		case r == quote:
+			return b.String(), i + 1, nil
+		case r == '\\' && i+1 < len(runes):
+			i++
+			switch runes[i] {
+			case 'n':
+				b.WriteRune('\n')
+			case 't':
+				b.WriteRune('\t')
+			case 'r':
+				b.WriteRune('\r')
+			default:
+				b.WriteRune(runes[i])
+			}
+		default:
+			b.WriteRune(r)
+		}
+		i++
+	}
+
+	return "", i, errUnterminatedString
+}
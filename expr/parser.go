@@ -0,0 +1,383 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package expr
+
+import "fmt"
+
+// Macros take an iteration variable and an expression evaluated
+// for each element of the target.
+var macros = map[string]bool{
+	"all":        true,
+	"exists":     true,
+	"exists_one": true,
+	"filter":     true,
+	"map":        true,
+}
+
+type parser struct {
+	tokens []*token
+	pos    int
+}
+
+func (p *parser) peek() *token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() *token {
+	t := p.tokens[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) isPunct(text string) bool {
+	t := p.peek()
+	return t.kind == tokPunct && t.text == text
+}
+
+func (p *parser) accept(text string) bool {
+	if p.isPunct(text) {
+		p.next()
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(text string) error {
+	if !p.accept(text) {
+		return p.unexpected(fmt.Sprintf("expected '%s'", text))
+	}
+	return nil
+}
+
+func (p *parser) unexpected(msg string) error {
+	t := p.peek()
+	if t.kind == tokEOF {
+		return &SyntaxError{Pos: t.pos, Msg: msg + " but found end of expression"}
+	}
+	return &SyntaxError{Pos: t.pos, Msg: fmt.Sprintf("%s but found '%s'", msg, t.text)}
+}
+
+func (p *parser) parse() (node, error) {
+	n, err := p.conditional()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.peek().kind != tokEOF {
+		return nil, p.unexpected("expected end of expression")
+	}
+
+	return n, nil
+}
+
+func (p *parser) conditional() (node, error) {
+	c, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+
+	if !p.accept("?") {
+		return c, nil
+	}
+
+	t, err := p.conditional()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := p.expect(":"); err != nil {
+		return nil, err
+	}
+
+	f, err := p.conditional()
+	if err != nil {
+		return nil, err
+	}
+
+	return &condNode{c: c, t: t, f: f}, nil
+}
+
+func (p *parser) or() (node, error) {
+	return p.binary(p.and, "||")
+}
+
+func (p *parser) and() (node, error) {
+	return p.binary(p.relation, "&&")
+}
+
+func (p *parser) relation() (node, error) {
+	l, err := p.addition()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		t := p.peek()
+		op := ""
+		if t.kind == tokPunct && (t.text == "==" || t.text == "!=" || t.text == "<" || t.text == "<=" || t.text == ">" || t.text == ">=") {
+			op = t.text
+		} else if t.kind == tokIdent && t.text == "in" {
+			op = "in"
+		}
+
+		if op == "" {
+			return l, nil
+		}
+
+		p.next()
+		r, err := p.addition()
+		if err != nil {
+			return nil, err
+		}
+		l = &binaryNode{op: op, l: l, r: r}
+	}
+}
+
+func (p *parser) addition() (node, error) {
+	return p.binary(p.multiplication, "+", "-")
+}
+
+func (p *parser) multiplication() (node, error) {
+	return p.binary(p.unary, "*", "/", "%")
+}
+
+func (p *parser) binary(operand func() (node, error), ops ...string) (node, error) {
+	l, err := operand()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		op := ""
+		for _, o := range ops {
+			if p.isPunct(o) {
+				op = o
+				break
+			}
+		}
+
+		if op == "" {
+			return l, nil
+		}
+
+		p.next()
+		r, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		l = &binaryNode{op: op, l: l, r: r}
+	}
+}
+
+func (p *parser) unary() (node, error) {
+	if p.isPunct("!") || p.isPunct("-") {
+		op := p.next().text
+		x, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return &unaryNode{op: op, x: x}, nil
+	}
+
+	return p.member()
+}
+
+func (p *parser) member() (node, error) {
+	n, err := p.primary()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		switch {
+		case p.accept("."):
+			if p.peek().kind != tokIdent {
+				return nil, p.unexpected("expected field or method name")
+			}
+
+			t := p.next()
+			if !p.isPunct("(") {
+				n = &memberNode{target: n, name: t.text}
+				continue
+			}
+
+			p.next()
+			if macros[t.text] {
+				n, err = p.macro(n, t.text)
+			} else {
+				var args []node
+				args, err = p.args(")")
+				n = &callNode{target: n, name: t.text, args: args}
+			}
+			if err != nil {
+				return nil, err
+			}
+		case p.accept("["):
+			idx, err := p.conditional()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect("]"); err != nil {
+				return nil, err
+			}
+			n = &indexNode{target: n, index: idx}
+		default:
+			return n, nil
+		}
+	}
+}
+
+func (p *parser) macro(target node, name string) (node, error) {
+	if p.peek().kind != tokIdent {
+		return nil, p.unexpected(fmt.Sprintf("expected variable name in %s", name))
+	}
+	v := p.next()
+
+	if err := p.expect(","); err != nil {
+		return nil, err
+	}
+
+	body, err := p.conditional()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := p.expect(")"); err != nil {
+		return nil, err
+	}
+
+	return &macroNode{target: target, name: name, variable: v.text, body: body}, nil
+}
+
+func (p *parser) args(end string) ([]node, error) {
+	args := make([]node, 0)
+	if p.accept(end) {
+		return args, nil
+	}
+
+	for {
+		a, err := p.conditional()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, a)
+
+		if p.accept(end) {
+			return args, nil
+		}
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (p *parser) primary() (node, error) {
+	start := p.pos
+	t := p.next()
+	switch t.kind {
+	case tokInt, tokFloat, tokString:
+		return &literalNode{value: t.value}, nil
+	case tokIdent:
+		switch t.text {
+		case "true":
+			return &literalNode{value: true}, nil
+		case "false":
+			return &literalNode{value: false}, nil
+		case "null":
+			return &literalNode{value: nil}, nil
+		}
+
+		if !p.accept("(") {
+			return &identNode{name: t.text}, nil
+		}
+
+		if t.text == "has" {
+			m, err := p.conditional()
+			if err != nil {
+				return nil, err
+			}
+			member, ok := m.(*memberNode)
+			if !ok {
+				return nil, &SyntaxError{Pos: t.pos, Msg: "has() requires a field selection"}
+			}
+			if err := p.expect(")"); err != nil {
+				return nil, err
+			}
+			return &hasNode{member: member}, nil
+		}
+
+		args, err := p.args(")")
+		if err != nil {
+			return nil, err
+		}
+		return &callNode{name: t.text, args: args}, nil
+	case tokPunct:
+		switch t.text {
+		case "(":
+			n, err := p.conditional()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(")"); err != nil {
+				return nil, err
+			}
+			return n, nil
+		case "[":
+			elems, err := p.args("]")
+			if err != nil {
+				return nil, err
+			}
+			return &listNode{elems: elems}, nil
+		case "{":
+			return p.mapLiteral()
+		}
+	}
+
+	p.pos = start
+	return nil, p.unexpected("expected an operand")
+}
+
+func (p *parser) mapLiteral() (node, error) {
+	n := &mapNode{}
+	if p.accept("}") {
+		return n, nil
+	}
+
+	for {
+		k, err := p.conditional()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(":"); err != nil {
+			return nil, err
+		}
+		v, err := p.conditional()
+		if err != nil {
+			return nil, err
+		}
+		n.keys = append(n.keys, k)
+		n.values = append(n.values, v)
+
+		if p.accept("}") {
+			return n, nil
+		}
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+	}
+}
@@ -0,0 +1,143 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"path"
+	"strings"
+
+	"github.com/mbtproject/mbt/e"
+)
+
+// AdvanceModules returns the modules in commit to given the modules
+// discovered in commit from. Instead of walking the tree of to, hashes
+// of the known module directories and file dependencies are looked up.
+// Tree is walked as in ModulesInCommit when the changes between the
+// commits touch the files used to discover modules (e.g. spec files or
+// CODEOWNERS).
+func (d *stdDiscover) AdvanceModules(prev Modules, from, to Commit) (Modules, error) {
+	deltas, err := d.Repo.Diff(from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	if d.requiresDiscovery(prev, deltas) {
+		d.Log.Debug("Changes between %v and %v require discovering modules", from, to)
+		return d.ModulesInCommit(to)
+	}
+
+	set := make(moduleMetadataSet, 0, len(prev))
+	for _, mod := range prev {
+		m := *mod.metadata
+		m.diagnostics = nil
+		m.lastCommit = nil
+
+		// Hashes are looked up rather than derived from the changes
+		// since changes can be filtered (e.g. with DiffOptions.SkipBinary).
+		if m.dir == "" {
+			m.hash = to.ID()
+		} else if m.hash, err = d.Repo.EntryID(to, m.dir); err != nil {
+			return nil, err
+		}
+
+		if len(m.dependentFileHashes) > 0 {
+			hashes := make(map[string]string, len(m.dependentFileHashes))
+			for f := range m.dependentFileHashes {
+				if hashes[f], err = d.Repo.EntryID(to, f); err != nil {
+					return nil, e.Wrapf(ErrClassUser, err, msgFileDependencyNotFound, f, m.spec.Name, m.dir)
+				}
+			}
+			m.dependentFileHashes = hashes
+		}
+
+		set = append(set, &m)
+	}
+
+	return toModules(set)
+}
+
+// requiresDiscovery returns true if the changes affect the files
+// modules are discovered from.
+func (d *stdDiscover) requiresDiscovery(prev Modules, deltas []*DiffDelta) bool {
+	for _, delta := range deltas {
+		for _, p := range []string{delta.NewFile, delta.OldFile} {
+			name := path.Base(p)
+			if name == d.specFile || name == dockerfileName || p == ChangeClassesFile ||
+				(d.terraform && isTerraformFile(name)) {
+				return true
+			}
+
+			for _, l := range codeOwnersLocations {
+				if p == l {
+					return true
+				}
+			}
+		}
+	}
+
+	// Git LFS objects of a module are found by walking its tree.
+	for _, mod := range prev {
+		if mod.metadata.spec.LFS && isChangedPath(mod.Path(), deltas) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isChangedPath returns true if the file or directory at p is changed.
+// Root directory is represented by an empty p.
+func isChangedPath(p string, deltas []*DiffDelta) bool {
+	for _, delta := range deltas {
+		for _, f := range []string{delta.NewFile, delta.OldFile} {
+			if f == "" {
+				continue
+			}
+			if p == "" || f == p || strings.HasPrefix(f, p+"/") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (b *stdManifestBuilder) Advance(prev *Manifest, to Commit) (*Manifest, error) {
+	if prev.discovered == nil {
+		return nil, e.NewErrorf(ErrClassUser, msgCannotAdvanceManifest, prev.Sha)
+	}
+
+	from, err := b.Repo.GetCommit(prev.Sha)
+	if err != nil {
+		return nil, err
+	}
+
+	mods, err := b.Discover.AdvanceModules(prev.discovered, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := b.buildManifest(mods, to.ID())
+	if err != nil {
+		return nil, err
+	}
+
+	if err = b.describeCommit(m, to); err != nil {
+		return nil, err
+	}
+
+	m.discovered = mods
+	return m, nil
+}
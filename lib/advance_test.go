@@ -0,0 +1,135 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdvanceManifest(t *testing.T) {
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+
+	check(t, repo.InitModule("app-a"))
+	check(t, repo.InitModuleWithOptions("app-b", &Spec{
+		Name:         "app-b",
+		Dependencies: []string{"app-a"},
+	}))
+	check(t, repo.InitModule("app-c"))
+	check(t, repo.Commit("first"))
+
+	world := NewWorld(t, ".tmp/repo")
+	m1, err := world.System.ManifestByCommit(repo.LastCommit.String())
+	check(t, err)
+
+	check(t, repo.WriteContent("app-a/foo", "hello"))
+	check(t, repo.Commit("second"))
+
+	// Tree must not be walked when specs are not changed.
+	world.Discover.Interceptor.Config("ModulesInCommit").Return(nil, errors.New("tree walked"))
+	m2, err := world.System.AdvanceManifest(m1, repo.LastCommit.String())
+	check(t, err)
+	expected, err := NewWorld(t, ".tmp/repo").System.ManifestByCommit(repo.LastCommit.String())
+	check(t, err)
+
+	assert.Equal(t, repo.LastCommit.String(), m2.Sha)
+	assert.Equal(t, expected.Modules.Names(), m2.Modules.Names())
+	for i, mod := range expected.Modules {
+		assert.Equal(t, mod.Version(), m2.Modules[i].Version())
+	}
+}
+
+func TestAdvanceManifestWithNewModule(t *testing.T) {
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+
+	check(t, repo.InitModule("app-a"))
+	check(t, repo.Commit("first"))
+
+	world := NewWorld(t, ".tmp/repo")
+	m1, err := world.System.ManifestByCommit(repo.LastCommit.String())
+	check(t, err)
+
+	check(t, repo.InitModule("app-b"))
+	check(t, repo.Commit("second"))
+
+	m2, err := world.System.AdvanceManifest(m1, repo.LastCommit.String())
+	check(t, err)
+
+	assert.Equal(t, []string{"app-a", "app-b"}, m2.Modules.Names())
+}
+
+func TestAdvanceFilteredManifest(t *testing.T) {
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+
+	check(t, repo.InitModule("app-a"))
+	check(t, repo.InitModule("app-b"))
+	check(t, repo.Commit("first"))
+
+	world := NewWorld(t, ".tmp/repo")
+	m1, err := world.System.ManifestByCommit(repo.LastCommit.String())
+	check(t, err)
+	m1, err = m1.ApplyFilters(ExactMatchFilter("app-a"))
+	check(t, err)
+
+	check(t, repo.WriteContent("app-b/foo", "hello"))
+	check(t, repo.Commit("second"))
+
+	m2, err := world.System.AdvanceManifest(m1, repo.LastCommit.String())
+	check(t, err)
+
+	assert.Equal(t, []string{"app-a", "app-b"}, m2.Modules.Names())
+}
+
+func TestAdvanceDiffManifest(t *testing.T) {
+	m := &Manifest{Sha: "abc", Modules: Modules{}}
+
+	_, err := (&stdManifestBuilder{}).Advance(m, nil)
+
+	assert.EqualError(t, err, "Manifest of 'abc' cannot be advanced (only manifests of commits and branches can be advanced)")
+}
+
+func TestRequiresDiscovery(t *testing.T) {
+	d := &stdDiscover{specFile: DefaultSpecFile}
+	lfs := newModule(newModuleMetadata("app-lfs", "a", &Spec{Name: "app-lfs", LFS: true}, nil), nil)
+	prev := Modules{newTestModule("app-a", "app-a", "a"), lfs}
+
+	assert.False(t, d.requiresDiscovery(prev, []*DiffDelta{{NewFile: "app-a/main.go", OldFile: "app-a/main.go"}}))
+	assert.True(t, d.requiresDiscovery(prev, []*DiffDelta{{NewFile: "app-b/.mbt.yml"}}))
+	assert.True(t, d.requiresDiscovery(prev, []*DiffDelta{{NewFile: "app-a/.mbt.yml", OldFile: "app-a/.mbt.yml"}}))
+	assert.True(t, d.requiresDiscovery(prev, []*DiffDelta{{NewFile: "CODEOWNERS"}}))
+	assert.True(t, d.requiresDiscovery(prev, []*DiffDelta{{NewFile: "app-a/Dockerfile"}}))
+	assert.True(t, d.requiresDiscovery(prev, []*DiffDelta{{NewFile: "app-lfs/image.png"}}))
+	assert.False(t, d.requiresDiscovery(prev, []*DiffDelta{{NewFile: "infra/main.tf"}}))
+
+	d.terraform = true
+	assert.True(t, d.requiresDiscovery(prev, []*DiffDelta{{NewFile: "infra/main.tf"}}))
+}
+
+func TestIsChangedPath(t *testing.T) {
+	deltas := []*DiffDelta{{NewFile: "app-a/main.go", OldFile: "app-a/main.go"}, {NewFile: "lib/b.go", OldFile: "old/b.go"}}
+
+	assert.True(t, isChangedPath("", deltas))
+	assert.True(t, isChangedPath("app-a", deltas))
+	assert.True(t, isChangedPath("old", deltas))
+	assert.True(t, isChangedPath("lib/b.go", deltas))
+	assert.False(t, isChangedPath("app", deltas))
+	assert.False(t, isChangedPath("", nil))
+}
@@ -25,6 +25,7 @@ import (
 	"sort"
 	"strings"
 	"text/template"
+	"time"
 
 	"github.com/mbtproject/mbt/e"
 )
@@ -33,6 +34,7 @@ import (
 type TemplateData struct {
 	Args           map[string]interface{}
 	Sha            string
+	Commit         *CommitInfo
 	Env            map[string]string
 	Modules        map[string]*Module
 	ModulesList    []*Module
@@ -135,6 +137,7 @@ func (s *stdSystem) applyCore(commit Commit, templatePath string, output io.Writ
 
 func processTemplate(buffer []byte, m *Manifest, output io.Writer) error {
 	modulesIndex := m.Modules.indexByName()
+	now := time.Now()
 	sortedModules := make(modulesByNameSorter, len(m.Modules))
 	copy(sortedModules, m.Modules)
 	sort.Sort(sortedModules)
@@ -157,6 +160,20 @@ func processTemplate(buffer []byte, m *Manifest, output io.Writer) error {
 
 			return resolveProperty(m.Properties(), strings.Split(n, "."), def)
 		},
+		"imageTag": func(m *Module, registry, pattern string) (string, error) {
+			if m == nil {
+				return "", nil
+			}
+
+			return m.ImageTag(registry, pattern)
+		},
+		"snapshotVersion": func(mod *Module, pattern string) (string, error) {
+			if mod == nil {
+				return "", nil
+			}
+
+			return m.SnapshotVersion(mod, pattern, now)
+		},
 		"contains": func(container interface{}, item interface{}) bool {
 			if container == nil {
 				return false
@@ -290,6 +307,7 @@ func processTemplate(buffer []byte, m *Manifest, output io.Writer) error {
 
 	data := &TemplateData{
 		Sha:            m.Sha,
+		Commit:         m.Commit,
 		Env:            getEnvMap(),
 		Modules:        m.Modules.indexByName(),
 		ModulesList:    sortedModules,
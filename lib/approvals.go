@@ -0,0 +1,65 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import "sort"
+
+// Approval is a group whose approval is required for the changes in
+// a manifest.
+type Approval struct {
+	Group string
+	// Modules are the names of the modules requiring the approval.
+	Modules []string
+}
+
+// Approvals returns the approval groups required by the module spec
+// when the module is impacted by changes.
+func (a *Module) Approvals() []string {
+	if a.metadata.spec.Approvals == nil {
+		return []string{}
+	}
+	return append([]string{}, a.metadata.spec.Approvals...)
+}
+
+// RequiredApprovals returns the union of the approval groups required
+// by the modules in manifest m sorted by group name. Use it with a
+// manifest created for a pull request to find out whose reviews are
+// required to merge it.
+func (m *Manifest) RequiredApprovals() []*Approval {
+	groups := make(map[string]*Approval)
+	for _, mod := range m.Modules {
+		for _, g := range mod.Approvals() {
+			a, ok := groups[g]
+			if !ok {
+				a = &Approval{Group: g, Modules: []string{}}
+				groups[g] = a
+			}
+			if len(a.Modules) == 0 || a.Modules[len(a.Modules)-1] != mod.Name() {
+				a.Modules = append(a.Modules, mod.Name())
+			}
+		}
+	}
+
+	approvals := make([]*Approval, 0, len(groups))
+	for _, a := range groups {
+		approvals = append(approvals, a)
+	}
+	sort.Slice(approvals, func(i, j int) bool {
+		return approvals[i].Group < approvals[j].Group
+	})
+
+	return approvals
+}
@@ -0,0 +1,58 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequiredApprovals(t *testing.T) {
+	a := newModule(newModuleMetadata("app-a", "a1", &Spec{Name: "app-a", Approvals: []string{"security", "payments", "security"}}, nil), nil)
+	b := newModule(newModuleMetadata("app-b", "b1", &Spec{Name: "app-b", Approvals: []string{"payments"}}, nil), nil)
+	c := newTestModule("app-c", "app-c", "c1")
+
+	m := &Manifest{Modules: Modules{a, b, c}}
+
+	assert.Equal(t, []*Approval{
+		{Group: "payments", Modules: []string{"app-a", "app-b"}},
+		{Group: "security", Modules: []string{"app-a"}},
+	}, m.RequiredApprovals())
+	assert.Equal(t, []string{}, c.Approvals())
+}
+
+func TestRequiredApprovalsOfEmptyManifest(t *testing.T) {
+	assert.Equal(t, []*Approval{}, (&Manifest{Modules: Modules{}}).RequiredApprovals())
+}
+
+func TestRequiredApprovalsInPr(t *testing.T) {
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+
+	check(t, repo.InitModuleWithOptions("app-a", &Spec{Name: "app-a", Approvals: []string{"team-a"}}))
+	check(t, repo.InitModuleWithOptions("app-b", &Spec{Name: "app-b", Approvals: []string{"team-b"}}))
+	check(t, repo.Commit("first"))
+
+	check(t, repo.SwitchToBranch("feature"))
+	check(t, repo.WriteContent("app-b/foo", "bar"))
+	check(t, repo.Commit("second"))
+
+	m, err := NewWorld(t, ".tmp/repo").System.ManifestByPr("feature", "master")
+	check(t, err)
+
+	assert.Equal(t, []*Approval{{Group: "team-b", Modules: []string{"app-b"}}}, m.RequiredApprovals())
+}
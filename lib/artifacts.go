@@ -0,0 +1,178 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/mbtproject/mbt/e"
+)
+
+// ArtifactManifest lists the artifacts produced by a build.
+type ArtifactManifest struct {
+	Sha     string             `json:"sha"`
+	Branch  string             `json:"branch"`
+	Modules []*ModuleArtifacts `json:"modules"`
+}
+
+// ModuleArtifacts lists the artifacts of a module.
+type ModuleArtifacts struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	// Artifacts are the files matching the outputs of the module.
+	Artifacts []*Artifact `json:"artifacts"`
+	// SBOM is the path to the software bill of materials of the
+	// module, if generated.
+	SBOM string `json:"sbom,omitempty"`
+}
+
+// Artifact is a file produced by a module build.
+type Artifact struct {
+	// Path of the artifact relative to the repository.
+	Path string `json:"path"`
+	// Digest is the hex encoded SHA-256 digest of the artifact.
+	Digest string `json:"sha256"`
+}
+
+// Artifact verification statuses.
+const (
+	// ArtifactStatusOK is the status of an artifact matching its digest.
+	ArtifactStatusOK = "ok"
+	// ArtifactStatusMismatch is the status of an artifact not matching its digest.
+	ArtifactStatusMismatch = "mismatch"
+	// ArtifactStatusMissing is the status of an artifact that does not exist.
+	ArtifactStatusMissing = "missing"
+)
+
+// ArtifactVerification is the result of verifying an artifact
+// against the digest recorded in the artifact manifest.
+type ArtifactVerification struct {
+	Module   string `json:"module"`
+	Path     string `json:"path"`
+	Expected string `json:"expected"`
+	Actual   string `json:"actual"`
+	Status   string `json:"status"`
+}
+
+// NewArtifactManifest creates the artifact manifest of the modules
+// completed in summary. Artifacts are the files matching the outputs
+// declared in the spec of each module.
+func NewArtifactManifest(summary *BuildSummary) (*ArtifactManifest, error) {
+	a := &ArtifactManifest{
+		Sha:     summary.Manifest.Sha,
+		Branch:  summary.Manifest.Branch,
+		Modules: make([]*ModuleArtifacts, 0, len(summary.Completed)),
+	}
+
+	for _, r := range summary.Completed {
+		dir := filepath.Join(summary.Manifest.Dir, filepath.FromSlash(r.Module.Path()))
+		files, err := outputFiles(dir, r.Module.Outputs())
+		if err != nil {
+			return nil, err
+		}
+
+		artifacts := make([]*Artifact, 0, len(files))
+		for _, f := range files {
+			rel, err := filepath.Rel(summary.Manifest.Dir, f)
+			if err != nil {
+				return nil, e.Wrap(ErrClassInternal, err)
+			}
+			d, err := fileDigest(f)
+			if err != nil {
+				return nil, err
+			}
+			artifacts = append(artifacts, &Artifact{Path: filepath.ToSlash(rel), Digest: d})
+		}
+
+		a.Modules = append(a.Modules, &ModuleArtifacts{
+			Name:      moduleDisplayName(r.Module),
+			Version:   r.Module.Version(),
+			Artifacts: artifacts,
+			SBOM:      r.SBOM,
+		})
+	}
+
+	return a, nil
+}
+
+// ReadArtifactManifest reads the artifact manifest stored in path.
+func ReadArtifactManifest(path string) (*ArtifactManifest, error) {
+	c, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, e.Wrapf(ErrClassUser, err, msgFailedReadFile, path)
+	}
+
+	a := &ArtifactManifest{}
+	if err := json.Unmarshal(c, a); err != nil {
+		return nil, e.Wrapf(ErrClassUser, err, msgFailedReadFile, path)
+	}
+
+	return a, nil
+}
+
+// Write stores the artifact manifest in path.
+func (a *ArtifactManifest) Write(path string) error {
+	c, err := json.MarshalIndent(a, "", "  ")
+	if err != nil {
+		return e.Wrap(ErrClassInternal, err)
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), 0755)
+	if err == nil {
+		err = ioutil.WriteFile(path, c, 0644)
+	}
+	if err != nil {
+		return e.Wrapf(ErrClassUser, err, msgFailedWriteFile, path)
+	}
+
+	return nil
+}
+
+// VerifyArtifacts computes the digests of the artifacts listed in
+// the artifact manifest and compares them with the recorded ones.
+// Paths of the artifacts are resolved relative to dir.
+func VerifyArtifacts(a *ArtifactManifest, dir string) ([]*ArtifactVerification, error) {
+	results := make([]*ArtifactVerification, 0)
+	for _, m := range a.Modules {
+		for _, f := range m.Artifacts {
+			r := &ArtifactVerification{Module: m.Name, Path: f.Path, Expected: f.Digest}
+			path := filepath.Join(dir, filepath.FromSlash(f.Path))
+			if _, err := os.Stat(path); os.IsNotExist(err) {
+				r.Status = ArtifactStatusMissing
+				results = append(results, r)
+				continue
+			}
+
+			d, err := fileDigest(path)
+			if err != nil {
+				return nil, err
+			}
+
+			r.Actual = d
+			if d == f.Digest {
+				r.Status = ArtifactStatusOK
+			} else {
+				r.Status = ArtifactStatusMismatch
+			}
+			results = append(results, r)
+		}
+	}
+
+	return results, nil
+}
@@ -0,0 +1,87 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestArtifactManifest(t *testing.T) {
+	clean()
+	writeAuditFile(t, ".tmp/repo/app-a/dist/a.tar.gz", "a")
+	writeAuditFile(t, ".tmp/repo/app-a/dist/docs/index.html", "docs")
+	writeAuditFile(t, ".tmp/repo/app-a/src/main.go", "package main")
+
+	dir, err := filepath.Abs(".tmp/repo")
+	check(t, err)
+
+	a := newModule(newModuleMetadata("app-a", "a", &Spec{Name: "app-a", Outputs: []string{"dist"}}, nil), nil)
+	a.version = "a1"
+	b := newTestModule("app-b", "app-b", "b1")
+
+	manifest, err := NewArtifactManifest(&BuildSummary{
+		Manifest:  &Manifest{Dir: dir, Sha: "abc", Branch: "master"},
+		Completed: []*BuildResult{{Module: a, SBOM: "sbom/app-a.json"}, {Module: b}},
+	})
+	check(t, err)
+
+	assert.Equal(t, &ArtifactManifest{
+		Sha:    "abc",
+		Branch: "master",
+		Modules: []*ModuleArtifacts{
+			{Name: "app-a", Version: "a1", SBOM: "sbom/app-a.json", Artifacts: []*Artifact{
+				{Path: "app-a/dist/a.tar.gz", Digest: "ca978112ca1bbdcafac231b39a23dc4da786eff8147c4e72b9807785afee48bb"},
+				{Path: "app-a/dist/docs/index.html", Digest: "46b42b4229cd7a39c564e780bb665a8bde4fdf722007e8473f167fe53ed4b995"},
+			}},
+			{Name: "app-b", Version: "b1", Artifacts: []*Artifact{}},
+		},
+	}, manifest)
+
+	check(t, manifest.Write(".tmp/artifacts/manifest.json"))
+	read, err := ReadArtifactManifest(".tmp/artifacts/manifest.json")
+	check(t, err)
+	assert.Equal(t, manifest, read)
+}
+
+func TestVerifyArtifacts(t *testing.T) {
+	clean()
+	writeAuditFile(t, ".tmp/repo/app-a/dist/a.tar.gz", "a")
+	writeAuditFile(t, ".tmp/repo/app-a/dist/b.tar.gz", "tampered")
+
+	a := &ArtifactManifest{
+		Modules: []*ModuleArtifacts{
+			{Name: "app-a", Artifacts: []*Artifact{
+				{Path: "app-a/dist/a.tar.gz", Digest: "ca978112ca1bbdcafac231b39a23dc4da786eff8147c4e72b9807785afee48bb"},
+				{Path: "app-a/dist/b.tar.gz", Digest: "3e23e8160039594a33894f6564e1b1348bbd7a0088d42c4acb73eeaed59c009d"},
+				{Path: "app-a/dist/c.tar.gz", Digest: "2e7d2c03a9507ae265ecf5b5356885a53393a2029d241394997265a1a25aefc6"},
+			}},
+		},
+	}
+
+	results, err := VerifyArtifacts(a, ".tmp/repo")
+	check(t, err)
+
+	assert.Len(t, results, 3)
+	assert.Equal(t, ArtifactStatusOK, results[0].Status)
+	assert.Equal(t, ArtifactStatusMismatch, results[1].Status)
+	assert.Equal(t, "3e23e8160039594a33894f6564e1b1348bbd7a0088d42c4acb73eeaed59c009d", results[1].Expected)
+	assert.NotEqual(t, results[1].Expected, results[1].Actual)
+	assert.Equal(t, ArtifactStatusMissing, results[2].Status)
+	assert.Equal(t, "app-a/dist/c.tar.gz", results[2].Path)
+}
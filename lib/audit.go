@@ -0,0 +1,264 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/mbtproject/mbt/e"
+)
+
+const (
+	// AuditNoBuildCommand is reported for modules without a build command.
+	AuditNoBuildCommand = "no-build-command"
+	// AuditNoOwners is reported for modules without an owner in CODEOWNERS.
+	AuditNoOwners = "no-owners"
+	// AuditUndeclaredDependency is reported for modules referring to
+	// files of another module without declaring it as a dependency.
+	AuditUndeclaredDependency = "undeclared-dependency"
+	// AuditStaleProperty is reported for module properties which are
+	// not referenced by the module.
+	AuditStaleProperty = "stale-property"
+)
+
+// maxAuditFileSize limits the size of the files scanned by audit.
+const maxAuditFileSize = 1 << 20
+
+// builtinProperties are the properties consumed by mbt.
+var builtinProperties = []string{"kubernetes", sshHostLabelProperty}
+
+// quotedString matches the string literals in source files which
+// may refer to other modules (e.g. import paths).
+var quotedString = regexp.MustCompile("[\"'`]([^\"'`\\s]{1,256})[\"'`]")
+
+// AuditOptions describes how modules are audited.
+type AuditOptions struct {
+	// KnownProperties are the properties consumed outside of modules
+	// (e.g. in templates) and must not be reported as stale.
+	KnownProperties []string
+}
+
+// AuditFinding describes a problem found in a module.
+type AuditFinding struct {
+	Module  string `json:"module"`
+	Kind    string `json:"kind"`
+	Message string `json:"message"`
+}
+
+// Audit reports the modules in m without a build command, without
+// owners, referring to other modules without declaring them as
+// dependencies or having properties which are not referenced.
+// Module files are read from the working directory of m.
+// References are detected by looking for the paths of other modules
+// in the string literals (e.g. import paths) of module files, hence
+// findings may include false positives.
+// Findings are sorted by module and kind.
+func Audit(m *Manifest, options *AuditOptions) ([]*AuditFinding, error) {
+	findings := make([]*AuditFinding, 0)
+	known := make(map[string]bool)
+	for _, p := range append(builtinProperties, options.KnownProperties...) {
+		known[p] = true
+	}
+
+	for _, mod := range m.Modules {
+		if len(mod.Build()) == 0 && mod.Tasks()["build"] == nil && !mod.HasDockerfile() {
+			findings = append(findings, &AuditFinding{Module: mod.Name(), Kind: AuditNoBuildCommand, Message: "module does not have a build command"})
+		}
+
+		if len(mod.Owners()) == 0 {
+			findings = append(findings, &AuditFinding{Module: mod.Name(), Kind: AuditNoOwners, Message: "module does not have an owner in CODEOWNERS"})
+		}
+
+		files, err := moduleFiles(m, mod)
+		if err != nil {
+			return nil, err
+		}
+
+		findings = append(findings, undeclaredDependencies(m, mod, files)...)
+		findings = append(findings, staleProperties(mod, files, known)...)
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].Module != findings[j].Module {
+			return findings[i].Module < findings[j].Module
+		}
+		return findings[i].Kind < findings[j].Kind
+	})
+
+	return findings, nil
+}
+
+// moduleFiles reads the text files of mod excluding the ones in the
+// directories of nested modules. Returned map is keyed by the path of
+// each file relative to the repository.
+func moduleFiles(m *Manifest, mod *Module) (map[string][]byte, error) {
+	nested := make(map[string]bool)
+	for _, o := range m.Modules {
+		if o != mod {
+			nested[o.Path()] = true
+		}
+	}
+
+	files := make(map[string][]byte)
+	root := filepath.Join(m.Dir, filepath.FromSlash(mod.Path()))
+	if _, err := os.Stat(root); os.IsNotExist(err) {
+		// Module is not in the working directory.
+		return files, nil
+	}
+
+	err := filepath.Walk(root, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(m.Dir, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		if info.IsDir() {
+			if info.Name() == ".git" || (p != root && nested[rel]) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if !info.Mode().IsRegular() || info.Size() > maxAuditFileSize {
+			return nil
+		}
+
+		c, err := ioutil.ReadFile(p)
+		if err != nil {
+			return err
+		}
+
+		head := c
+		if len(head) > 512 {
+			head = head[:512]
+		}
+		if bytes.IndexByte(head, 0) < 0 {
+			files[rel] = c
+		}
+		return nil
+	})
+
+	if err != nil {
+		return nil, e.Wrapf(ErrClassUser, err, msgFailedAudit, mod.Name())
+	}
+
+	return files, nil
+}
+
+// refersTo returns true if the string literal s in file refers to the
+// directory of a module at modulePath.
+func refersTo(file, s, modulePath string) bool {
+	if strings.HasPrefix(s, "./") || strings.HasPrefix(s, "../") {
+		s = path.Clean(path.Join(path.Dir(file), s))
+		return s == modulePath || strings.HasPrefix(s, modulePath+"/")
+	}
+
+	return strings.HasSuffix(s, "/"+modulePath) || strings.Contains(s, "/"+modulePath+"/")
+}
+
+func undeclaredDependencies(m *Manifest, mod *Module, files map[string][]byte) []*AuditFinding {
+	declared := make(map[string]bool)
+	for _, r := range mod.Requires() {
+		declared[r.Name()] = true
+	}
+
+	names := make([]string, 0, len(files))
+	for f := range files {
+		names = append(names, f)
+	}
+	sort.Strings(names)
+
+	findings := make([]*AuditFinding, 0)
+	for _, o := range m.Modules {
+		if o == mod || o.Path() == "" || declared[o.Name()] {
+			continue
+		}
+
+	search:
+		for _, f := range names {
+			for _, match := range quotedString.FindAllSubmatch(files[f], -1) {
+				if refersTo(f, string(match[1]), o.Path()) {
+					findings = append(findings, &AuditFinding{
+						Module:  mod.Name(),
+						Kind:    AuditUndeclaredDependency,
+						Message: fmt.Sprintf("%s refers to module %s without declaring it as a dependency", f, o.Name()),
+					})
+					break search
+				}
+			}
+		}
+	}
+
+	return findings
+}
+
+func staleProperties(mod *Module, files map[string][]byte, known map[string]bool) []*AuditFinding {
+	keys := make([]string, 0, len(mod.Properties()))
+	for k := range mod.Properties() {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	findings := make([]*AuditFinding, 0)
+	for _, k := range keys {
+		if known[k] {
+			continue
+		}
+
+		references := [][]byte{
+			[]byte("MBT_MODULE_PROPERTY_" + strings.ToUpper(k)),
+			[]byte("properties." + k),
+			[]byte("properties['" + k + "']"),
+			[]byte(`properties["` + k + `"]`),
+		}
+
+		referenced := false
+		for _, c := range files {
+			for _, r := range references {
+				if bytes.Contains(c, r) {
+					referenced = true
+					break
+				}
+			}
+			if referenced {
+				break
+			}
+		}
+
+		if !referenced {
+			findings = append(findings, &AuditFinding{
+				Module:  mod.Name(),
+				Kind:    AuditStaleProperty,
+				Message: fmt.Sprintf("property %s is not referenced by the module", k),
+			})
+		}
+	}
+
+	return findings
+}
@@ -0,0 +1,72 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeAuditFile(t *testing.T, path, content string) {
+	check(t, os.MkdirAll(filepath.Dir(path), 0755))
+	check(t, ioutil.WriteFile(path, []byte(content), 0644))
+}
+
+func TestAudit(t *testing.T) {
+	clean()
+	writeAuditFile(t, ".tmp/audit/lib-c/c.go", "package c\n")
+	writeAuditFile(t, ".tmp/audit/app-a/main.go", "package main\n\nimport \"github.com/org/repo/lib-c\"\n")
+	writeAuditFile(t, ".tmp/audit/app-a/build.sh", "echo $MBT_MODULE_PROPERTY_TEAM\n")
+	writeAuditFile(t, ".tmp/audit/app-b/index.js", "const c = require('../lib-c/index.js');\nconst d = require('./lib-c');\n")
+
+	dir, err := filepath.Abs(".tmp/audit")
+	check(t, err)
+
+	c := newModule(newModuleMetadata("lib-c", "c", &Spec{Name: "lib-c", Build: map[string]*Cmd{"default": {Cmd: "make"}}}, nil), nil)
+	c.metadata.owners = []string{"@org/core"}
+	a := newModule(newModuleMetadata("app-a", "a", &Spec{
+		Name:       "app-a",
+		Build:      map[string]*Cmd{"default": {Cmd: "./build.sh"}},
+		Properties: map[string]interface{}{"team": "payments", "tier": 1, "chart": "a"},
+	}, nil), nil)
+	a.metadata.owners = []string{"@org/payments"}
+	b := newModule(newModuleMetadata("app-b", "b", &Spec{Name: "app-b"}, nil), Modules{c})
+	b.metadata.owners = []string{"@org/orders"}
+	d := newModule(newModuleMetadata("lib-d", "d", &Spec{Name: "lib-d", Tasks: map[string]*Task{"build": {Cmd: "make"}}}, nil), nil)
+
+	findings, err := Audit(&Manifest{Dir: dir, Modules: Modules{c, a, b, d}}, &AuditOptions{KnownProperties: []string{"chart"}})
+	check(t, err)
+
+	assert.Equal(t, []*AuditFinding{
+		{Module: "app-a", Kind: AuditStaleProperty, Message: "property tier is not referenced by the module"},
+		{Module: "app-a", Kind: AuditUndeclaredDependency, Message: "app-a/main.go refers to module lib-c without declaring it as a dependency"},
+		{Module: "app-b", Kind: AuditNoBuildCommand, Message: "module does not have a build command"},
+		{Module: "lib-d", Kind: AuditNoOwners, Message: "module does not have an owner in CODEOWNERS"},
+	}, findings)
+}
+
+func TestRefersTo(t *testing.T) {
+	assert.True(t, refersTo("app-b/index.js", "../lib-c", "lib-c"))
+	assert.True(t, refersTo("app-b/src/index.js", "../../lib-c/x", "lib-c"))
+	assert.False(t, refersTo("app-b/index.js", "./lib-c", "lib-c"))
+	assert.True(t, refersTo("app-a/main.go", "github.com/org/repo/libs/c", "libs/c"))
+	assert.False(t, refersTo("app-a/main.go", "github.com/org/repo/libs/cc", "libs/c"))
+	assert.False(t, refersTo("app-a/main.go", "lib-c", "lib-c"))
+}
@@ -0,0 +1,75 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import "sync"
+
+// specCache holds the specs parsed by a discover keyed by the id of
+// their blobs. Specs are not modified once parsed so they are shared
+// by the modules of all commits containing them.
+type specCache struct {
+	mu    sync.Mutex
+	specs map[string]*Spec
+}
+
+func newSpecCache() *specCache {
+	return &specCache{specs: make(map[string]*Spec)}
+}
+
+func (c *specCache) get(id string) (*Spec, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s, ok := c.specs[id]
+	return s, ok
+}
+
+func (c *specCache) put(id string, spec *Spec) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.specs[id] = spec
+}
+
+// ManifestsByBranches creates the manifests of the specified branches.
+// Instead of discovering the modules of each branch from scratch, each
+// manifest is advanced from the manifest of the previous branch (see
+// AdvanceManifest), sharing the repository, its object cache and the
+// parsed specs.
+func (s *stdSystem) ManifestsByBranches(names []string) ([]*Manifest, error) {
+	manifests := make([]*Manifest, 0, len(names))
+	var prev *Manifest
+	for _, n := range names {
+		c, err := s.Repo.BranchCommit(n)
+		if err != nil {
+			return nil, err
+		}
+
+		var m *Manifest
+		if prev == nil {
+			m, err = s.MB.ByCommit(c)
+		} else {
+			m, err = s.MB.Advance(prev, c)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		m.Branch = n
+		manifests = append(manifests, m)
+		prev = m
+	}
+
+	return manifests, nil
+}
@@ -0,0 +1,68 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSpecCache(t *testing.T) {
+	c := newSpecCache()
+	spec := &Spec{Name: "app-a"}
+
+	_, ok := c.get("a")
+	assert.False(t, ok)
+
+	c.put("a", spec)
+	cached, ok := c.get("a")
+	assert.True(t, ok)
+	assert.Equal(t, spec, cached)
+}
+
+func TestManifestsByBranches(t *testing.T) {
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+
+	check(t, repo.InitModule("app-a"))
+	check(t, repo.Commit("first"))
+
+	check(t, repo.SwitchToBranch("release-1"))
+	check(t, repo.InitModule("app-b"))
+	check(t, repo.Commit("second"))
+
+	check(t, repo.SwitchToBranch("release-2"))
+	check(t, repo.WriteContent("app-a/foo", "hello"))
+	check(t, repo.Commit("third"))
+
+	world := NewWorld(t, ".tmp/repo")
+	manifests, err := world.System.ManifestsByBranches([]string{"master", "release-1", "release-2"})
+	check(t, err)
+
+	assert.Len(t, manifests, 3)
+	for i, b := range []string{"master", "release-1", "release-2"} {
+		expected, err := NewWorld(t, ".tmp/repo").System.ManifestByBranch(b)
+		check(t, err)
+
+		assert.Equal(t, b, manifests[i].Branch)
+		assert.Equal(t, expected.Sha, manifests[i].Sha)
+		assert.Equal(t, expected.Modules.Names(), manifests[i].Modules.Names())
+		for j, mod := range expected.Modules {
+			assert.Equal(t, mod.Version(), manifests[i].Modules[j].Version())
+		}
+	}
+}
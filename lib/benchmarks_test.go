@@ -78,3 +78,86 @@ func BenchmarkReduceToDiff1000(b *testing.B) {
 func BenchmarkReduceToDiff10000(b *testing.B) {
 	benchmarkReduceToDiff(10000, 10000, b)
 }
+
+// syntheticMetadata creates the metadata of modules in a large repository
+// without a git repository. Each module depends on the next two modules.
+func syntheticMetadata(modulesCount int) moduleMetadataSet {
+	set := make(moduleMetadataSet, 0, modulesCount)
+	for i := 0; i < modulesCount; i++ {
+		spec := &Spec{Name: fmt.Sprintf("app-%v", i)}
+		for j := i + 1; j < modulesCount && j <= i+2; j++ {
+			spec.Dependencies = append(spec.Dependencies, fmt.Sprintf("app-%v", j))
+		}
+		set = append(set, newModuleMetadata(fmt.Sprintf("apps/app-%v", i), fmt.Sprintf("%040d", i), spec, nil))
+	}
+	return set
+}
+
+func benchmarkToModules(modulesCount int, b *testing.B) {
+	set := syntheticMetadata(modulesCount)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := toModules(set); err != nil {
+			b.Fatalf("%v", err)
+		}
+	}
+}
+
+func BenchmarkToModules1000(b *testing.B) {
+	benchmarkToModules(1000, b)
+}
+
+func BenchmarkToModules10000(b *testing.B) {
+	benchmarkToModules(10000, b)
+}
+
+func benchmarkReduce(modulesCount, deltaCount int, b *testing.B) {
+	mods, err := toModules(syntheticMetadata(modulesCount))
+	if err != nil {
+		b.Fatalf("%v", err)
+	}
+
+	deltas := make([]*DiffDelta, 0, deltaCount)
+	for i := 0; i < deltaCount; i++ {
+		deltas = append(deltas, &DiffDelta{NewFile: fmt.Sprintf("apps/app-%v/file-%v", i%modulesCount, i)})
+	}
+
+	reducer := NewReducer(NewStdLog(LogLevelNormal))
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := reducer.Reduce(mods, deltas); err != nil {
+			b.Fatalf("%v", err)
+		}
+	}
+}
+
+func BenchmarkReduce1000(b *testing.B) {
+	benchmarkReduce(1000, 1000, b)
+}
+
+func BenchmarkReduce10000(b *testing.B) {
+	benchmarkReduce(10000, 10000, b)
+}
+
+func BenchmarkNewSpec(b *testing.B) {
+	content := []byte(`name: app-a
+build:
+  default:
+    cmd: make
+    args: [build]
+dependencies: [lib-a, lib-b]
+properties:
+  kubernetes:
+    replicas: 3
+    ports:
+      80: http
+`)
+
+	for i := 0; i < b.N; i++ {
+		if _, err := newSpec(content); err != nil {
+			b.Fatalf("%v", err)
+		}
+	}
+}
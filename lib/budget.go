@@ -0,0 +1,80 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import "github.com/mbtproject/mbt/e"
+
+// Build budget policies.
+const (
+	// BudgetWarn reports a warning when the cost of the modules
+	// selected by changes exceeds the budget. This is the default.
+	BudgetWarn = "warn"
+	// BudgetFail fails the construction of manifests when the cost of
+	// the modules selected by changes exceeds the budget.
+	BudgetFail = "fail"
+)
+
+// Cost returns the weight of building the module relative to other
+// modules. Defaults to 1.
+func (a *Module) Cost() int {
+	if c := a.metadata.spec.Cost; c > 0 {
+		return c
+	}
+	return 1
+}
+
+// Cost returns the sum of the costs of the modules.
+func (l Modules) Cost() int {
+	cost := 0
+	for _, m := range l {
+		cost += m.Cost()
+	}
+	return cost
+}
+
+func validateCost(spec *Spec) error {
+	if spec.Cost < 0 {
+		return e.NewErrorf(ErrClassUser, msgInvalidCost, spec.Cost, spec.Name)
+	}
+	return nil
+}
+
+func validateBudgetPolicy(policy string) error {
+	switch policy {
+	case "", BudgetWarn, BudgetFail:
+		return nil
+	}
+	return e.NewErrorf(ErrClassUser, msgInvalidBudgetPolicy, policy)
+}
+
+// checkBudget compares the cost of the modules selected by a diff
+// with the budget and warns or fails according to the budget policy.
+func (b *stdManifestBuilder) checkBudget(mods Modules) error {
+	if b.options == nil || b.options.Budget <= 0 {
+		return nil
+	}
+
+	cost := mods.Cost()
+	if cost <= b.options.Budget {
+		return nil
+	}
+
+	if b.options.BudgetPolicy == BudgetFail {
+		return e.NewErrorf(ErrClassUser, msgBuildBudgetExceeded, cost, b.options.Budget)
+	}
+	b.Log.Warnf(msgBuildBudgetExceeded, cost, b.options.Budget)
+	return nil
+}
@@ -0,0 +1,91 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/mbtproject/mbt/e"
+	"github.com/stretchr/testify/assert"
+)
+
+func testBudgetModules() Modules {
+	a := newModule(newModuleMetadata("app-a", "a1", &Spec{Name: "app-a", Cost: 5}, nil), nil)
+	b := newTestModule("app-b", "app-b", "b1")
+	return Modules{a, b}
+}
+
+func TestCost(t *testing.T) {
+	mods := testBudgetModules()
+
+	assert.Equal(t, 5, mods[0].Cost())
+	assert.Equal(t, 1, mods[1].Cost())
+	assert.Equal(t, 6, mods.Cost())
+	assert.Equal(t, 0, Modules{}.Cost())
+}
+
+func TestInvalidCost(t *testing.T) {
+	_, err := newSpec([]byte("name: app-a\ncost: -1"))
+
+	assert.EqualError(t, err, fmt.Sprintf(msgInvalidCost, -1, "app-a"))
+	assert.Equal(t, ErrClassUser, (err.(*e.E)).Class())
+}
+
+func TestBudgetWithinLimit(t *testing.T) {
+	for _, options := range []*ManifestBuilderOptions{nil, {}, {Budget: 6, BudgetPolicy: BudgetFail}} {
+		builder := &stdManifestBuilder{Log: NewStdLog(LogLevelNormal), options: options}
+		check(t, builder.checkBudget(testBudgetModules()))
+	}
+}
+
+func TestBudgetExceeded(t *testing.T) {
+	builder := &stdManifestBuilder{Log: NewStdLog(LogLevelNormal), options: &ManifestBuilderOptions{Budget: 5}}
+	check(t, builder.checkBudget(testBudgetModules()))
+
+	builder.options.BudgetPolicy = BudgetFail
+	err := builder.checkBudget(testBudgetModules())
+
+	assert.EqualError(t, err, fmt.Sprintf(msgBuildBudgetExceeded, 6, 5))
+	assert.Equal(t, ErrClassUser, (err.(*e.E)).Class())
+}
+
+func TestInvalidBudgetPolicy(t *testing.T) {
+	_, err := NewSystemWithOptions(".tmp/repo", &SystemOptions{BudgetPolicy: "block"})
+
+	assert.EqualError(t, err, fmt.Sprintf(msgInvalidBudgetPolicy, "block"))
+	assert.Equal(t, ErrClassUser, (err.(*e.E)).Class())
+}
+
+func TestBudgetInDiff(t *testing.T) {
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+
+	check(t, repo.InitModuleWithOptions("app-a", &Spec{Name: "app-a", Cost: 3}))
+	check(t, repo.InitModuleWithOptions("app-b", &Spec{Name: "app-b"}))
+	check(t, repo.Commit("first"))
+	c1 := repo.LastCommit
+
+	check(t, repo.WriteContent("app-a/foo", "bar"))
+	check(t, repo.WriteContent("app-b/foo", "bar"))
+	check(t, repo.Commit("second"))
+
+	system, err := NewSystemWithOptions(".tmp/repo", &SystemOptions{LogLevel: LogLevelNormal, Budget: 3, BudgetPolicy: BudgetFail})
+	check(t, err)
+
+	_, err = system.ManifestByDiff(c1.String(), repo.LastCommit.String())
+	assert.EqualError(t, err, fmt.Sprintf(msgBuildBudgetExceeded, 4, 3))
+}
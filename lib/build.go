@@ -16,10 +16,17 @@ limitations under the License.
 package lib
 
 import (
+	"fmt"
+	"io"
 	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 
 	git "github.com/libgit2/git2go/v28"
 	"github.com/mbtproject/mbt/e"
+	"github.com/mbtproject/mbt/utils"
 )
 
 var defaultCheckoutOptions = &git.CheckoutOpts{
@@ -37,7 +44,7 @@ func (s *stdSystem) BuildBranch(name string, filterOptions *FilterOptions, optio
 		return nil, err
 	}
 
-	return s.checkoutAndBuildManifest(m, options)
+	return s.checkoutAndRunTarget(m, buildTarget, options)
 }
 
 func (s *stdSystem) BuildPr(src, dst string, options *CmdOptions) (*BuildSummary, error) {
@@ -46,7 +53,7 @@ func (s *stdSystem) BuildPr(src, dst string, options *CmdOptions) (*BuildSummary
 		return nil, err
 	}
 
-	return s.checkoutAndBuildManifest(m, options)
+	return s.checkoutAndRunTarget(m, buildTarget, options)
 }
 
 func (s *stdSystem) BuildDiff(from, to string, options *CmdOptions) (*BuildSummary, error) {
@@ -55,7 +62,7 @@ func (s *stdSystem) BuildDiff(from, to string, options *CmdOptions) (*BuildSumma
 		return nil, err
 	}
 
-	return s.checkoutAndBuildManifest(m, options)
+	return s.checkoutAndRunTarget(m, buildTarget, options)
 }
 
 func (s *stdSystem) BuildCurrentBranch(filterOptions *FilterOptions, options *CmdOptions) (*BuildSummary, error) {
@@ -69,7 +76,7 @@ func (s *stdSystem) BuildCurrentBranch(filterOptions *FilterOptions, options *Cm
 		return nil, err
 	}
 
-	return s.checkoutAndBuildManifest(m, options)
+	return s.checkoutAndRunTarget(m, buildTarget, options)
 }
 
 func (s *stdSystem) BuildCommit(commit string, filterOptions *FilterOptions, options *CmdOptions) (*BuildSummary, error) {
@@ -83,7 +90,7 @@ func (s *stdSystem) BuildCommit(commit string, filterOptions *FilterOptions, opt
 		return nil, err
 	}
 
-	return s.checkoutAndBuildManifest(m, options)
+	return s.checkoutAndRunTarget(m, buildTarget, options)
 }
 
 func (s *stdSystem) BuildCommitContent(commit string, options *CmdOptions) (*BuildSummary, error) {
@@ -92,7 +99,7 @@ func (s *stdSystem) BuildCommitContent(commit string, options *CmdOptions) (*Bui
 		return nil, err
 	}
 
-	return s.checkoutAndBuildManifest(m, options)
+	return s.checkoutAndRunTarget(m, buildTarget, options)
 }
 
 func (s *stdSystem) BuildWorkspace(filterOptions *FilterOptions, options *CmdOptions) (*BuildSummary, error) {
@@ -106,7 +113,7 @@ func (s *stdSystem) BuildWorkspace(filterOptions *FilterOptions, options *CmdOpt
 		return nil, err
 	}
 
-	return s.buildManifest(m, options)
+	return s.runTarget(m, buildTarget, options)
 }
 
 func (s *stdSystem) BuildWorkspaceChanges(options *CmdOptions) (*BuildSummary, error) {
@@ -115,58 +122,425 @@ func (s *stdSystem) BuildWorkspaceChanges(options *CmdOptions) (*BuildSummary, e
 		return nil, err
 	}
 
-	return s.buildManifest(m, options)
+	return s.runTarget(m, buildTarget, options)
 }
 
-func (s *stdSystem) checkoutAndBuildManifest(m *Manifest, options *CmdOptions) (*BuildSummary, error) {
+func (s *stdSystem) checkoutAndRunTarget(m *Manifest, t *target, options *CmdOptions) (*BuildSummary, error) {
+	if options.DryRun {
+		// Nothing is executed in a dry run, therefore we can
+		// leave the workspace untouched.
+		return s.runTarget(m, t, options)
+	}
+
 	r, err := s.WorkspaceManager.CheckoutAndRun(m.Sha, func() (interface{}, error) {
-		return s.buildManifest(m, options)
+		return s.runTarget(m, t, options)
 	})
 
+	summary, _ := r.(*BuildSummary)
+	return summary, err
+}
+
+func (s *stdSystem) runTarget(m *Manifest, t *target, options *CmdOptions) (*BuildSummary, error) {
+	completed := make([]*BuildResult, 0)
+	skipped := make([]*Module, 0)
+	failures := make([]*CmdFailure, 0)
+	broken := make(map[string]bool)
+	var mutex sync.Mutex
+
+	skip := func(a *Module) {
+		mutex.Lock()
+		skipped = append(skipped, a)
+		mutex.Unlock()
+		options.Callback(a, CmdStageSkipBuild, nil)
+	}
+
+	fail := func(a *Module, err error, logFile string) error {
+		if !options.KeepGoing {
+			return err
+		}
+
+		mutex.Lock()
+		broken[a.Name()] = true
+		failures = append(failures, &CmdFailure{Module: a, Err: err, LogFile: logFile})
+		mutex.Unlock()
+		options.Callback(a, CmdStageFailedBuild, err)
+		return nil
+	}
+
+	capacity := options.Parallelism
+	if options.DryRun {
+		// Build plan is written in the order modules would be
+		// built sequentially.
+		capacity = 1
+	}
+
+	var durations *buildDurations
+	var priorities map[string]time.Duration
+	if options.DurationsFile != "" {
+		var err error
+		durations, err = loadBuildDurations(options.DurationsFile)
+		if err != nil {
+			s.Log.Error(err)
+		}
+
+		if capacity > 1 {
+			priorities = criticalPaths(m.Modules, func(mod *Module) time.Duration {
+				return durations.estimate(mod, t.durationKey)
+			})
+		}
+	}
+
+	started := time.Now()
+	step := 0
+	err := s.runPrerequisites(m, t, options, durations, &step)
+	if err == nil {
+		err = schedule(m.Modules, capacity, priorities, func(a *Module) error {
+			cmd, ok := t.cmd(a)
+			mutex.Lock()
+			brokenDependency := requiresAny(a, broken)
+			if ok && brokenDependency {
+				// Module is not built because one of its dependencies
+				// failed. Modules depending on this should be skipped
+				// as well.
+				broken[a.Name()] = true
+			}
+			mutex.Unlock()
+
+			if !ok || brokenDependency {
+				skip(a)
+				return nil
+			}
+
+			run, err := evalWhen(cmd.Cmd, cmd.When, m, a)
+			if err != nil {
+				return fail(a, err, "")
+			}
+
+			if !run {
+				skip(a)
+				return nil
+			}
+
+			// Module is built as a copy carrying the values issued for
+			// this build, leaving the module of the manifest untouched.
+			built := a
+			if t == buildTarget && options.BuildNumbers != nil && !options.DryRun {
+				n, err := options.BuildNumbers.Next(a.Name())
+				if err != nil {
+					return fail(a, err, "")
+				}
+				built = built.withBuildNumber(n)
+			}
+
+			if t == buildTarget && options.SnapshotPattern != "" {
+				v, err := m.SnapshotVersion(built, options.SnapshotPattern, started)
+				if err != nil {
+					return fail(a, err, "")
+				}
+				built = built.withSnapshotVersion(v)
+			}
+
+			steps := t.plan(built)
+			for _, v := range built.Variants() {
+				if options.DryRun {
+					step++
+					writeBuildPlan(options.Stdout, step, steps, m, v)
+					completed = append(completed, &BuildResult{Module: v})
+					continue
+				}
+
+				var result *BuildResult
+				for _, st := range steps {
+					result, err = s.execStep(st, m, v, options, durations)
+					if err != nil {
+						break
+					}
+				}
+
+				if err == nil && t == buildTarget && options.Reproducible != nil {
+					result.Reproducibility, err = s.verifyReproducible(steps[len(steps)-1], m, v, options)
+				}
+
+				if err == nil && t == buildTarget && options.SBOM != nil {
+					result.SBOM, err = s.generateSBOM(m, v, options)
+				}
+
+				if err != nil {
+					if err = fail(v, err, result.LogFile); err != nil {
+						return err
+					}
+					continue
+				}
+
+				mutex.Lock()
+				completed = append(completed, result)
+				mutex.Unlock()
+			}
+
+			return nil
+		})
+	}
+
+	if durations != nil && !options.DryRun {
+		if saveErr := durations.save(); saveErr != nil {
+			s.Log.Error(saveErr)
+		}
+	}
+
 	if err != nil {
 		return nil, err
 	}
 
-	return r.(*BuildSummary), nil
+	summary := &BuildSummary{Manifest: m, Completed: completed, Skipped: skipped, Failures: failures}
+	if len(failures) > 0 {
+		names := make([]string, 0, len(failures))
+		for _, f := range failures {
+			names = append(names, moduleDisplayName(f.Module))
+		}
+		return summary, t.failures(names)
+	}
+
+	return summary, nil
 }
 
-func (s *stdSystem) buildManifest(m *Manifest, options *CmdOptions) (*BuildSummary, error) {
-	completed := make([]*BuildResult, 0)
-	skipped := make([]*Module, 0)
+// runPrerequisites executes the tasks of upstream modules the target
+// depends on. Unlike the target itself, a failure in these tasks
+// aborts the execution regardless of KeepGoing option.
+func (s *stdSystem) runPrerequisites(m *Manifest, t *target, options *CmdOptions, durations *buildDurations, step *int) error {
+	for _, n := range t.prerequisites(m.Modules) {
+		steps := n.target.plan(n.mod)
+		// Progress of the prerequisites is not reported via callbacks
+		// since they are not part of the summary.
+		steps[len(steps)-1].main = false
+
+		for _, v := range n.mod.Variants() {
+			if options.DryRun {
+				*step++
+				writeBuildPlan(options.Stdout, *step, steps, m, v)
+				continue
+			}
+
+			s.Log.Infof(msgRunningUpstreamTask, n.target.name, moduleDisplayName(v))
+			for _, st := range steps {
+				if _, err := s.execStep(st, m, v, options, durations); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// execStep executes a step of a target for a module.
+// Steps preceding the last one in a plan are the tasks the
+// target depends on. Outputs of the step are restored from the
+// cache instead of executing it, if available.
+func (s *stdSystem) execStep(step *taskStep, m *Manifest, mod *Module, options *CmdOptions, durations *buildDurations) (*BuildResult, error) {
+	t := step.target
+	if !step.main {
+		run, err := evalWhen(step.cmd.Cmd, step.cmd.When, m, mod)
+		if err != nil || !run {
+			return &BuildResult{Module: mod}, err
+		}
+	}
+
+	if s.restoreCachedOutputs(t, m, mod, options) {
+		if step.main {
+			options.Callback(mod, CmdStageCachedBuild, nil)
+		}
+		return &BuildResult{Module: mod, Cached: true}, nil
+	}
+
+	if step.main {
+		options.Callback(mod, CmdStageBeforeBuild, nil)
+	} else {
+		s.Log.Debug("Running task %v of module %v", t.name, moduleDisplayName(mod))
+	}
+
+	start := time.Now()
+	logFile, err := s.execTarget(t, step.cmd, m, mod, options)
+	duration := time.Since(start)
+	if err != nil {
+		return &BuildResult{Module: mod, LogFile: logFile, Duration: duration}, err
+	}
+
+	if step.main {
+		options.Callback(mod, CmdStageAfterBuild, nil)
+	}
+	if durations != nil {
+		durations.record(t.durationKey(mod), duration)
+	}
+	s.cacheOutputs(t, m, mod, options)
+
+	return &BuildResult{Module: mod, LogFile: logFile, Duration: duration}, nil
+}
+
+// restoreCachedOutputs restores the outputs of a module from the
+// cache. Returns false if the target has to be executed.
+// Cache errors are not fatal, target is executed instead.
+// Outputs are not restored when verifying reproducible builds since
+// the builds have to be executed to be compared.
+func (s *stdSystem) restoreCachedOutputs(t *target, m *Manifest, mod *Module, options *CmdOptions) bool {
+	outputs, ok := t.cache(mod)
+	if options.Cache == nil || !ok || options.Reproducible != nil {
+		return false
+	}
 
-	for _, a := range m.Modules {
-		cmd, ok := s.canBuildHere(a)
-		if !ok {
-			skipped = append(skipped, a)
-			options.Callback(a, CmdStageSkipBuild, nil)
-			continue
+	restored, err := restoreOutputs(options.Cache, t.cacheKey(mod), m, mod)
+	if err != nil {
+		s.Log.Error(e.Wrapf(ErrClassUser, err, msgFailedCacheRestore, moduleDisplayName(mod)))
+		return false
+	}
+
+	if restored {
+		s.Log.Debug("Restored outputs %v of module %v", outputs, moduleDisplayName(mod))
+	}
+
+	return restored
+}
+
+func (s *stdSystem) cacheOutputs(t *target, m *Manifest, mod *Module, options *CmdOptions) {
+	outputs, ok := t.cache(mod)
+	if options.Cache == nil || !ok {
+		return
+	}
+
+	if err := saveOutputs(options.Cache, t.cacheKey(mod), m, mod, outputs); err != nil {
+		s.Log.Error(e.Wrapf(ErrClassUser, err, msgFailedCacheSave, moduleDisplayName(mod)))
+	}
+}
+
+// moduleDisplayName returns the name of a module along with
+// its variant, if any.
+func moduleDisplayName(mod *Module) string {
+	if v := mod.Variant(); v != nil {
+		return fmt.Sprintf("%s[%s]", mod.Name(), v.Name)
+	}
+	return mod.Name()
+}
+
+func requiresAny(mod *Module, names map[string]bool) bool {
+	for _, r := range mod.Requires() {
+		if names[r.Name()] {
+			return true
 		}
+	}
+	return false
+}
+
+// execTarget executes the command of a module for a target and returns
+// the path to its log file if LogDir option is specified.
+func (s *stdSystem) execTarget(t *target, buildCmd *Cmd, manifest *Manifest, module *Module, options *CmdOptions) (string, error) {
+	options, flush := moduleOutput(options, module)
+	defer flush()
 
-		options.Callback(a, CmdStageBeforeBuild, nil)
-		err := s.execBuild(cmd, m, a, options)
+	logFile := ""
+	if options.LogDir != "" {
+		f, err := createModuleLog(options.LogDir, module, t.name)
 		if err != nil {
-			return nil, err
+			return "", err
 		}
-		options.Callback(a, CmdStageAfterBuild, nil)
-		completed = append(completed, &BuildResult{Module: a})
+		defer f.Close()
+
+		logFile = f.Name()
+		o := *options
+		o.Stdout = teeWriter(options.Stdout, f)
+		o.Stderr = teeWriter(options.Stderr, f)
+		options = &o
+	}
+
+	hooks := t.hooks(module)
+	err := s.execHook("pre", hooks.Pre, manifest, module, options)
+	if err == nil {
+		err = s.execWithRetries(manifest, module, options, buildCmd.Timeout, buildCmd.Retries, buildCmd.WorkDir, buildCmd.Cmd, buildCmd.Args...)
+	}
+	if err == nil {
+		err = s.execHook("post", hooks.Post, manifest, module, options)
 	}
 
-	return &BuildSummary{Manifest: m, Completed: completed, Skipped: skipped}, nil
+	if err != nil {
+		if hookErr := s.execHook("onFailure", hooks.OnFailure, manifest, module, options); hookErr != nil {
+			s.Log.Error(hookErr)
+		}
+		return logFile, t.failed(err, module)
+	}
+	return logFile, nil
 }
 
-func (s *stdSystem) execBuild(buildCmd *Cmd, manifest *Manifest, module *Module, options *CmdOptions) error {
-	err := s.ProcessManager.Exec(manifest, module, options, buildCmd.Cmd, buildCmd.Args...)
+func (s *stdSystem) execHook(name string, hook *Cmd, manifest *Manifest, module *Module, options *CmdOptions) error {
+	if hook == nil {
+		return nil
+	}
+
+	run, err := evalWhen(hook.Cmd, hook.When, manifest, module)
+	if err != nil || !run {
+		return err
+	}
+
+	err = s.execWithRetries(manifest, module, options, hook.Timeout, hook.Retries, hook.WorkDir, hook.Cmd, hook.Args...)
 	if err != nil {
-		return e.Wrapf(ErrClassUser, err, msgFailedBuild, module.Name())
+		return e.Wrapf(ErrClassUser, err, msgFailedHook, name, moduleDisplayName(module))
 	}
 	return nil
 }
 
-func (s *stdSystem) canBuildHere(mod *Module) (*Cmd, bool) {
-	c, ok := mod.Build()[runtime.GOOS]
+// writeBuildPlan describes how a module would be built without
+// executing its build command.
+func writeBuildPlan(w io.Writer, step int, steps []*taskStep, manifest *Manifest, module *Module) {
+	fmt.Fprintf(w, "%v. %s (path: %s version: %s)\n", step, moduleDisplayName(module), module.Path(), module.Version())
+
+	main := steps[len(steps)-1]
+	for _, s := range steps[:len(steps)-1] {
+		writeCmdPlan(w, s.target.name, s.cmd)
+	}
+
+	hooks := main.target.hooks(module)
+	writeCmdPlan(w, "pre", hooks.Pre)
+	writeCmdPlan(w, "cmd", main.cmd)
+	writeCmdPlan(w, "post", hooks.Post)
+	writeCmdPlan(w, "onFailure", hooks.OnFailure)
+
+	if outputs, ok := main.target.cache(module); ok && len(outputs) > 0 {
+		fmt.Fprintf(w, "  outputs: %s\n", strings.Join(outputs, ", "))
+	}
+
+	env := setupModBuildEnvironment(manifest, module)
+	sort.Strings(env)
+	for _, v := range env {
+		fmt.Fprintf(w, "  env: %s\n", v)
+	}
+}
+
+func writeCmdPlan(w io.Writer, name string, c *Cmd) {
+	if c == nil {
+		return
+	}
+
+	words := []string{utils.ShellQuote(c.Cmd)}
+	for _, a := range c.Args {
+		words = append(words, utils.ShellQuote(a))
+	}
+
+	fmt.Fprintf(w, "  %s: %s\n", name, strings.Join(words, " "))
+	if c.When != "" {
+		fmt.Fprintf(w, "  when: %s\n", c.When)
+	}
+	if c.Timeout != "" {
+		fmt.Fprintf(w, "  timeout: %s\n", c.Timeout)
+	}
+	if c.Retries > 0 {
+		fmt.Fprintf(w, "  retries: %v\n", c.Retries)
+	}
+}
+
+// platformCmd returns the command applicable to the host platform.
+// Platform specific commands take precedence over the default command.
+func platformCmd(cmds map[string]*Cmd) (*Cmd, bool) {
+	c, ok := cmds[runtime.GOOS]
 
 	if !ok {
-		c, ok = mod.Build()["default"]
+		c, ok = cmds["default"]
 	}
 
 	return c, ok
@@ -0,0 +1,188 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/mbtproject/mbt/e"
+)
+
+// DefaultNotesRef is the notes reference used to store build metadata.
+const DefaultNotesRef = "refs/notes/mbt"
+
+// Build statuses of modules recorded in build metadata.
+const (
+	// BuildStatusBuilt is the status of a module built successfully.
+	BuildStatusBuilt = "built"
+	// BuildStatusCached is the status of a module restored from the cache.
+	BuildStatusCached = "cached"
+	// BuildStatusFailed is the status of a module failed to build.
+	BuildStatusFailed = "failed"
+	// BuildStatusSkipped is the status of a module skipped in the build.
+	BuildStatusSkipped = "skipped"
+)
+
+// BuildMetadata records the results of building a commit.
+type BuildMetadata struct {
+	Sha     string                 `json:"sha"`
+	Branch  string                 `json:"branch"`
+	Time    time.Time              `json:"time"`
+	Modules []*ModuleBuildMetadata `json:"modules"`
+}
+
+// ModuleBuildMetadata records the result of building a module.
+type ModuleBuildMetadata struct {
+	Name      string        `json:"name"`
+	Version   string        `json:"version"`
+	Status    string        `json:"status"`
+	Duration  time.Duration `json:"duration"`
+	Artifacts []*Artifact   `json:"artifacts,omitempty"`
+	// BuildNumber is the build number issued for the module.
+	// See CmdOptions.BuildNumbers.
+	BuildNumber int `json:"buildNumber,omitempty"`
+}
+
+// NewBuildMetadata creates the metadata of the build in summary
+// including the digests of the artifacts of completed modules.
+func NewBuildMetadata(summary *BuildSummary) (*BuildMetadata, error) {
+	artifacts, err := NewArtifactManifest(summary)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &BuildMetadata{
+		Sha:     summary.Manifest.Sha,
+		Branch:  summary.Manifest.Branch,
+		Time:    time.Now().UTC(),
+		Modules: make([]*ModuleBuildMetadata, 0),
+	}
+
+	for i, r := range summary.Completed {
+		status := BuildStatusBuilt
+		if r.Cached {
+			status = BuildStatusCached
+		}
+		m.Modules = append(m.Modules, &ModuleBuildMetadata{
+			Name:        artifacts.Modules[i].Name,
+			Version:     artifacts.Modules[i].Version,
+			Status:      status,
+			Duration:    r.Duration,
+			Artifacts:   artifacts.Modules[i].Artifacts,
+			BuildNumber: r.Module.BuildNumber(),
+		})
+	}
+
+	for _, f := range summary.Failures {
+		m.Modules = append(m.Modules, &ModuleBuildMetadata{
+			Name:    moduleDisplayName(f.Module),
+			Version: f.Module.Version(),
+			Status:  BuildStatusFailed,
+		})
+	}
+
+	for _, s := range summary.Skipped {
+		m.Modules = append(m.Modules, &ModuleBuildMetadata{
+			Name:    moduleDisplayName(s),
+			Version: s.Version(),
+			Status:  BuildStatusSkipped,
+		})
+	}
+
+	return m, nil
+}
+
+type gitNotesStore struct {
+	repo Repo
+	ref  string
+}
+
+// NewGitNotesStore creates a BuildMetadataStore storing the metadata
+// as git notes attached to the built commits. Notes are stored in
+// DefaultNotesRef unless ref is specified.
+func NewGitNotesStore(repo Repo, ref string) BuildMetadataStore {
+	if ref == "" {
+		ref = DefaultNotesRef
+	}
+	return &gitNotesStore{repo: repo, ref: ref}
+}
+
+func (s *gitNotesStore) BuildMetadata(sha string) (*BuildMetadata, error) {
+	commit, err := s.repo.GetCommit(sha)
+	if err != nil {
+		return nil, err
+	}
+
+	note, err := s.repo.Note(commit, s.ref)
+	if err != nil || note == "" {
+		return nil, err
+	}
+
+	m := &BuildMetadata{}
+	if err := json.Unmarshal([]byte(note), m); err != nil {
+		return nil, e.Wrapf(ErrClassUser, err, msgFailedReadNote, sha)
+	}
+
+	return m, nil
+}
+
+func (s *gitNotesStore) Record(metadata *BuildMetadata) error {
+	existing, err := s.BuildMetadata(metadata.Sha)
+	if err != nil {
+		return err
+	}
+
+	merged := *metadata
+	if existing != nil {
+		merged.Modules = mergeModuleBuildMetadata(existing.Modules, metadata.Modules)
+	}
+
+	c, err := json.MarshalIndent(&merged, "", "  ")
+	if err != nil {
+		return e.Wrap(ErrClassInternal, err)
+	}
+
+	commit, err := s.repo.GetCommit(metadata.Sha)
+	if err != nil {
+		return err
+	}
+
+	return s.repo.SetNote(commit, s.ref, string(c))
+}
+
+// mergeModuleBuildMetadata combines the results of two builds of the
+// same commit. Results in b replace the results of the same module in a.
+func mergeModuleBuildMetadata(a, b []*ModuleBuildMetadata) []*ModuleBuildMetadata {
+	byName := make(map[string]*ModuleBuildMetadata)
+	for _, m := range a {
+		byName[m.Name] = m
+	}
+	for _, m := range b {
+		byName[m.Name] = m
+	}
+
+	merged := make([]*ModuleBuildMetadata, 0, len(byName))
+	for _, m := range byName {
+		merged = append(merged, m)
+	}
+	sort.Slice(merged, func(i, j int) bool {
+		return merged[i].Name < merged[j].Name
+	})
+
+	return merged
+}
@@ -0,0 +1,85 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewBuildMetadata(t *testing.T) {
+	clean()
+	writeAuditFile(t, ".tmp/repo/app-a/dist/a.tar.gz", "a")
+
+	dir, err := filepath.Abs(".tmp/repo")
+	check(t, err)
+
+	a := newModule(newModuleMetadata("app-a", "a", &Spec{Name: "app-a", Outputs: []string{"dist"}}, nil), nil)
+	a.version = "a1"
+
+	m, err := NewBuildMetadata(&BuildSummary{
+		Manifest:  &Manifest{Dir: dir, Sha: "abc", Branch: "master"},
+		Completed: []*BuildResult{{Module: a, Duration: time.Second}, {Module: newTestModule("app-b", "app-b", "b1"), Cached: true}},
+		Failures:  []*CmdFailure{{Module: newTestModule("app-c", "app-c", "c1")}},
+		Skipped:   []*Module{newTestModule("app-d", "app-d", "d1")},
+	})
+	check(t, err)
+
+	assert.Equal(t, "abc", m.Sha)
+	assert.Equal(t, "master", m.Branch)
+	assert.Equal(t, []*ModuleBuildMetadata{
+		{Name: "app-a", Version: "a1", Status: BuildStatusBuilt, Duration: time.Second, Artifacts: []*Artifact{
+			{Path: "app-a/dist/a.tar.gz", Digest: "ca978112ca1bbdcafac231b39a23dc4da786eff8147c4e72b9807785afee48bb"},
+		}},
+		{Name: "app-b", Version: "b1", Status: BuildStatusCached, Artifacts: []*Artifact{}},
+		{Name: "app-c", Version: "c1", Status: BuildStatusFailed},
+		{Name: "app-d", Version: "d1", Status: BuildStatusSkipped},
+	}, m.Modules)
+}
+
+func TestGitNotesStore(t *testing.T) {
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+
+	check(t, repo.InitModule("app-a"))
+	check(t, repo.Commit("first"))
+	sha := repo.LastCommit.String()
+
+	store := NewGitNotesStore(NewWorld(t, ".tmp/repo").Repo, "")
+
+	m, err := store.BuildMetadata(sha)
+	check(t, err)
+	assert.Nil(t, m)
+
+	check(t, store.Record(&BuildMetadata{Sha: sha, Branch: "master", Modules: []*ModuleBuildMetadata{
+		{Name: "app-b", Version: "b1", Status: BuildStatusFailed},
+		{Name: "app-a", Version: "a1", Status: BuildStatusBuilt},
+	}}))
+	check(t, store.Record(&BuildMetadata{Sha: sha, Branch: "master", Modules: []*ModuleBuildMetadata{
+		{Name: "app-b", Version: "b1", Status: BuildStatusBuilt},
+	}}))
+
+	m, err = store.BuildMetadata(sha)
+	check(t, err)
+	assert.Equal(t, sha, m.Sha)
+	assert.Equal(t, []*ModuleBuildMetadata{
+		{Name: "app-a", Version: "a1", Status: BuildStatusBuilt},
+		{Name: "app-b", Version: "b1", Status: BuildStatusBuilt},
+	}, m.Modules)
+}
@@ -0,0 +1,89 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"sync"
+)
+
+type fileBuildNumberStore struct {
+	path  string
+	mutex sync.Mutex
+}
+
+type buildNumbersFile struct {
+	// Modules maps the name of each module to its last build number.
+	Modules map[string]int `json:"modules"`
+}
+
+// NewFileBuildNumberStore creates a BuildNumberStore persisting the
+// last build number of each module in a json file.
+// Numbers are issued while holding a lock on <path>.lock, therefore
+// processes sharing the file (e.g. CI jobs) never receive the same
+// number for a module.
+func NewFileBuildNumberStore(path string) BuildNumberStore {
+	return &fileBuildNumberStore{path: path}
+}
+
+func (f *buildNumbersFile) init() {
+	if f.Modules == nil {
+		f.Modules = make(map[string]int)
+	}
+}
+
+func (s *fileBuildNumberStore) Current(module string) (int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	f := &buildNumbersFile{}
+	if err := readJSONFile(s.path, f); err != nil {
+		return 0, err
+	}
+
+	return f.Modules[module], nil
+}
+
+func (s *fileBuildNumberStore) Next(module string) (int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	f := &buildNumbersFile{}
+	err := updateJSONFile(s.path, f, func() error {
+		f.init()
+		f.Modules[module]++
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return f.Modules[module], nil
+}
+
+// BuildNumber returns the build number issued to the module when it
+// is built with CmdOptions.BuildNumbers. Zero otherwise.
+func (a *Module) BuildNumber() int {
+	return a.buildNumber
+}
+
+// withBuildNumber returns a copy of the module with build number n.
+// Modules of a manifest are shared between builds, therefore numbers
+// are issued on copies.
+func (a *Module) withBuildNumber(n int) *Module {
+	c := *a
+	c.buildNumber = n
+	return &c
+}
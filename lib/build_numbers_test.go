@@ -0,0 +1,180 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileBuildNumberStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mbt-build-numbers")
+	check(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "state", "build-numbers.json")
+	store := NewFileBuildNumberStore(path)
+
+	n, err := store.Current("app-a")
+	check(t, err)
+	assert.Equal(t, 0, n)
+
+	for i := 1; i <= 3; i++ {
+		n, err = store.Next("app-a")
+		check(t, err)
+		assert.Equal(t, i, n)
+	}
+
+	n, err = store.Next("app-b")
+	check(t, err)
+	assert.Equal(t, 1, n)
+
+	n, err = NewFileBuildNumberStore(path).Current("app-a")
+	check(t, err)
+	assert.Equal(t, 3, n)
+}
+
+func TestFileBuildNumberStoresSharingFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mbt-build-numbers")
+	check(t, err)
+	defer os.RemoveAll(dir)
+
+	// Each store has its own in-process lock, as in separate
+	// processes sharing the file.
+	path := filepath.Join(dir, "build-numbers.json")
+	numbers := make([]int, 20)
+	var wg sync.WaitGroup
+	for i := range numbers {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			n, err := NewFileBuildNumberStore(path).Next("app-a")
+			check(t, err)
+			numbers[i] = n
+		}(i)
+	}
+	wg.Wait()
+
+	issued := make(map[int]bool)
+	for _, n := range numbers {
+		issued[n] = true
+	}
+	assert.Len(t, issued, 20)
+
+	n, err := NewFileBuildNumberStore(path).Current("app-a")
+	check(t, err)
+	assert.Equal(t, 20, n)
+}
+
+func TestFileBuildNumberStoreWithInvalidFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mbt-build-numbers")
+	check(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "build-numbers.json")
+	check(t, ioutil.WriteFile(path, []byte("{"), 0644))
+
+	_, err = NewFileBuildNumberStore(path).Next("app-a")
+	assert.Error(t, err)
+}
+
+func TestBuildNumberEnvironment(t *testing.T) {
+	mod := newTestModule("app-a", "app-a", "a1")
+	assert.NotContains(t, strings.Join(setupModBuildEnvironment(&Manifest{}, mod), "\n"), "MBT_BUILD_NUMBER")
+
+	mod.buildNumber = 7
+	assert.Contains(t, setupModBuildEnvironment(&Manifest{}, mod), "MBT_BUILD_NUMBER=7")
+}
+
+func TestBuildNumbersInBuild(t *testing.T) {
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+
+	check(t, repo.InitModule("app-a"))
+	check(t, repo.WriteShellScript("app-a/build.sh", "echo $MBT_BUILD_NUMBER"))
+	check(t, repo.WritePowershellScript("app-a/build.ps1", "write-host $Env:MBT_BUILD_NUMBER"))
+	check(t, repo.Commit("first"))
+
+	store := NewFileBuildNumberStore(".tmp/build-numbers.json")
+	for i := 1; i <= 2; i++ {
+		buff := new(bytes.Buffer)
+		options := stdTestCmdOptions(buff)
+		options.BuildNumbers = store
+
+		summary, err := NewWorld(t, ".tmp/repo").System.BuildBranch("master", NoFilter, options)
+		check(t, err)
+
+		assert.Equal(t, fmt.Sprintf("%v\n", i), buff.String())
+		assert.Equal(t, i, summary.Completed[0].Module.BuildNumber())
+	}
+}
+
+// buildNumberProcessManager records the build number of each module
+// executed.
+type buildNumberProcessManager struct {
+	mutex   sync.Mutex
+	numbers map[string]int
+}
+
+func (p *buildNumberProcessManager) Exec(manifest *Manifest, module *Module, options *CmdOptions, command string, args ...string) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.numbers[module.Name()] = module.BuildNumber()
+	return nil
+}
+
+func TestBuildNumbersInParallelBuild(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mbt-build-numbers")
+	check(t, err)
+	defer os.RemoveAll(dir)
+
+	mods := Modules{}
+	for _, name := range []string{"app-a", "app-b", "app-c", "app-d"} {
+		spec := &Spec{Name: name, Build: map[string]*Cmd{"default": {Cmd: "./build.sh"}}}
+		mods = append(mods, newModule(newModuleMetadata(name, name, spec, nil), nil))
+	}
+	m := &Manifest{Dir: dir, Sha: "abc", Modules: mods}
+
+	pm := &buildNumberProcessManager{numbers: make(map[string]int)}
+	s := &stdSystem{Log: NewStdLog(LogLevelNormal), ProcessManager: pm}
+	options := stdTestCmdOptions(new(bytes.Buffer))
+	options.Parallelism = 4
+	options.BuildNumbers = NewFileBuildNumberStore(filepath.Join(dir, "build-numbers.json"))
+
+	for i := 1; i <= 2; i++ {
+		summary, err := s.runTarget(m, buildTarget, options)
+		check(t, err)
+
+		assert.Len(t, summary.Completed, 4)
+		for _, r := range summary.Completed {
+			assert.Equal(t, i, r.Module.BuildNumber())
+			assert.Equal(t, i, pm.numbers[r.Module.Name()])
+		}
+	}
+
+	// Numbers are issued on copies of the modules in the manifest.
+	for _, mod := range m.Modules {
+		assert.Equal(t, 0, mod.BuildNumber())
+	}
+}
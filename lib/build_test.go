@@ -20,6 +20,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"runtime"
@@ -107,13 +108,13 @@ func TestBuildSkip(t *testing.T) {
 	case "linux", "darwin":
 		check(t, repo.InitModuleWithOptions("app-a", &Spec{
 			Name:  "app-a",
-			Build: map[string]*Cmd{"windows": {"powershell", []string{"-ExecutionPolicy", "Bypass", "-File", ".\\build.ps1"}}},
+			Build: map[string]*Cmd{"windows": {Cmd: "powershell", Args: []string{"-ExecutionPolicy", "Bypass", "-File", ".\\build.ps1"}}},
 		}))
 		check(t, repo.WritePowershellScript("app-a/build.ps1", "write-host built app-a"))
 	case "windows":
 		check(t, repo.InitModuleWithOptions("app-a", &Spec{
 			Name:  "app-a",
-			Build: map[string]*Cmd{"darwin": {"./build.sh", []string{}}},
+			Build: map[string]*Cmd{"darwin": {Cmd: "./build.sh", Args: []string{}}},
 		}))
 		check(t, repo.WriteShellScript("app-a/build.sh", "echo built app-a"))
 	}
@@ -729,6 +730,7 @@ func TestRestorationOnBuildFailure(t *testing.T) {
 	buff := new(bytes.Buffer)
 	_, err := w.System.BuildBranch("feature", NoFilter, stdTestCmdOptions(buff))
 	assert.EqualError(t, err, fmt.Sprintf(msgFailedBuild, "app-a"))
+	assert.Equal(t, ErrClassBuild, (err.(*e.E)).Class())
 
 	idx, err := repo.Repo.Index()
 	check(t, err)
@@ -742,3 +744,503 @@ func TestRestorationOnBuildFailure(t *testing.T) {
 	check(t, err)
 	assert.Equal(t, 0, numDeltas)
 }
+
+func TestBuildRetries(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.SkipNow()
+	}
+
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+	check(t, repo.InitModuleWithOptions("app-a", &Spec{
+		Name:  "app-a",
+		Build: map[string]*Cmd{"default": {Cmd: "./build.sh", Retries: 2}},
+	}))
+	check(t, repo.WriteShellScript("app-a/build.sh", "if [ -f attempt ]; then echo built; else touch attempt; echo failed; exit 1; fi"))
+	check(t, repo.Commit("first"))
+
+	buff := new(bytes.Buffer)
+	summary, err := NewWorld(t, ".tmp/repo").System.BuildWorkspace(NoFilter, stdTestCmdOptions(buff))
+	check(t, err)
+
+	assert.Equal(t, "failed\nbuilt\n", buff.String())
+	assert.Len(t, summary.Completed, 1)
+}
+
+func TestBuildTimeout(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.SkipNow()
+	}
+
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+	check(t, repo.InitModuleWithOptions("app-a", &Spec{
+		Name:  "app-a",
+		Build: map[string]*Cmd{"default": {Cmd: "sleep", Args: []string{"10"}, Timeout: "100ms"}},
+	}))
+	check(t, repo.Commit("first"))
+
+	_, err := NewWorld(t, ".tmp/repo").System.BuildWorkspace(NoFilter, stdTestCmdOptions(nil))
+
+	assert.EqualError(t, err, fmt.Sprintf(msgFailedBuild, "app-a"))
+	assert.EqualError(t, err.(*e.E).InnerError(), fmt.Sprintf(msgCommandTimeout, "100ms"))
+}
+
+func TestBuildWithInvalidTimeout(t *testing.T) {
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+	check(t, repo.InitModuleWithOptions("app-a", &Spec{
+		Name:  "app-a",
+		Build: map[string]*Cmd{"default": {Cmd: "echo", Timeout: "forever"}},
+	}))
+	check(t, repo.Commit("first"))
+
+	_, err := NewWorld(t, ".tmp/repo").System.BuildCurrentBranch(NoFilter, stdTestCmdOptions(nil))
+
+	assert.Error(t, err)
+	assert.EqualError(t, err.(*e.E).InnerError(), fmt.Sprintf(msgInvalidTimeout, "forever", "echo"))
+}
+
+func TestBuildKeepGoing(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.SkipNow()
+	}
+
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+	check(t, repo.InitModuleWithOptions("app-a", &Spec{
+		Name:  "app-a",
+		Build: map[string]*Cmd{"default": {Cmd: "false"}},
+	}))
+	check(t, repo.InitModuleWithOptions("app-b", &Spec{
+		Name:         "app-b",
+		Dependencies: []string{"app-a"},
+		Build:        map[string]*Cmd{"default": {Cmd: "echo", Args: []string{"app-b"}}},
+	}))
+	check(t, repo.InitModuleWithOptions("app-c", &Spec{
+		Name:  "app-c",
+		Build: map[string]*Cmd{"default": {Cmd: "echo", Args: []string{"app-c"}}},
+	}))
+	check(t, repo.Commit("first"))
+
+	buff := new(bytes.Buffer)
+	options := stdTestCmdOptions(buff)
+	options.KeepGoing = true
+	summary, err := NewWorld(t, ".tmp/repo").System.BuildCurrentBranch(NoFilter, options)
+
+	assert.EqualError(t, err, fmt.Sprintf(msgFailedBuilds, 1, "app-a"))
+	assert.Equal(t, ErrClassBuild, (err.(*e.E)).Class())
+	assert.Equal(t, "app-c\n", buff.String())
+	assert.Len(t, summary.Completed, 1)
+	assert.Equal(t, "app-c", summary.Completed[0].Module.Name())
+	assert.Len(t, summary.Skipped, 1)
+	assert.Equal(t, "app-b", summary.Skipped[0].Name())
+	assert.Len(t, summary.Failures, 1)
+	assert.Equal(t, "app-a", summary.Failures[0].Module.Name())
+	assert.EqualError(t, summary.Failures[0].Err, fmt.Sprintf(msgFailedBuild, "app-a"))
+}
+
+func TestBuildFailFastByDefault(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.SkipNow()
+	}
+
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+	check(t, repo.InitModuleWithOptions("app-a", &Spec{
+		Name:  "app-a",
+		Build: map[string]*Cmd{"default": {Cmd: "false"}},
+	}))
+	check(t, repo.InitModuleWithOptions("app-b", &Spec{
+		Name:  "app-b",
+		Build: map[string]*Cmd{"default": {Cmd: "echo", Args: []string{"app-b"}}},
+	}))
+	check(t, repo.Commit("first"))
+
+	buff := new(bytes.Buffer)
+	summary, err := NewWorld(t, ".tmp/repo").System.BuildCurrentBranch(NoFilter, stdTestCmdOptions(buff))
+
+	assert.EqualError(t, err, fmt.Sprintf(msgFailedBuild, "app-a"))
+	assert.Nil(t, summary)
+	assert.Equal(t, "", buff.String())
+}
+
+func TestBuildDryRun(t *testing.T) {
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+	check(t, repo.InitModuleWithOptions("app-a", &Spec{
+		Name:  "app-a",
+		Build: map[string]*Cmd{"default": {Cmd: "./build.sh", Args: []string{"a b"}, Retries: 1}},
+	}))
+	check(t, repo.InitModuleWithOptions("app-b", &Spec{
+		Name:         "app-b",
+		Dependencies: []string{"app-a"},
+		Build:        map[string]*Cmd{"default": {Cmd: "./build.sh"}},
+	}))
+	check(t, repo.Commit("first"))
+
+	buff := new(bytes.Buffer)
+	options := stdTestCmdOptions(buff)
+	options.DryRun = true
+	summary, err := NewWorld(t, ".tmp/repo").System.BuildCurrentBranch(NoFilter, options)
+	check(t, err)
+
+	assert.Len(t, summary.Completed, 2)
+	a := summary.Manifest.Modules.indexByName()["app-a"]
+	b := summary.Manifest.Modules.indexByName()["app-b"]
+	out := buff.String()
+	assert.Contains(t, out, fmt.Sprintf("1. app-a (path: app-a version: %s)\n  cmd: ./build.sh 'a b'\n  retries: 1\n", a.Version()))
+	assert.Contains(t, out, fmt.Sprintf("2. app-b (path: app-b version: %s)\n  cmd: ./build.sh\n", b.Version()))
+	assert.Contains(t, out, "  env: MBT_MODULE_NAME=app-a\n")
+	assert.Contains(t, out, fmt.Sprintf("  env: MBT_BUILD_COMMIT=%s\n", summary.Manifest.Sha))
+}
+
+func TestBuildLogFiles(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.SkipNow()
+	}
+
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+	check(t, repo.InitModuleWithOptions("app-a", &Spec{
+		Name:  "app-a",
+		Build: map[string]*Cmd{"default": {Cmd: "echo", Args: []string{"app-a"}}},
+	}))
+	check(t, repo.Commit("first"))
+
+	buff := new(bytes.Buffer)
+	options := stdTestCmdOptions(buff)
+	options.LogDir = ".tmp/logs"
+	summary, err := NewWorld(t, ".tmp/repo").System.BuildCurrentBranch(NoFilter, options)
+	check(t, err)
+
+	r := summary.Completed[0]
+	assert.Equal(t, filepath.Join(".tmp/logs", "app-a", r.Module.Version()+".build.log"), r.LogFile)
+	c, err := ioutil.ReadFile(r.LogFile)
+	check(t, err)
+	assert.Equal(t, "app-a\n", string(c))
+	assert.Equal(t, "app-a\n", buff.String())
+}
+
+func TestBuildHooks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.SkipNow()
+	}
+
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+	check(t, repo.InitModuleWithOptions("app-a", &Spec{
+		Name:  "app-a",
+		Build: map[string]*Cmd{"default": {Cmd: "echo", Args: []string{"build"}}},
+		Hooks: &Hooks{
+			Pre:       &Cmd{Cmd: "echo", Args: []string{"pre"}},
+			Post:      &Cmd{Cmd: "echo", Args: []string{"post"}},
+			OnFailure: &Cmd{Cmd: "echo", Args: []string{"failed"}},
+		},
+	}))
+	check(t, repo.Commit("first"))
+
+	buff := new(bytes.Buffer)
+	_, err := NewWorld(t, ".tmp/repo").System.BuildCurrentBranch(NoFilter, stdTestCmdOptions(buff))
+	check(t, err)
+
+	assert.Equal(t, "pre\nbuild\npost\n", buff.String())
+}
+
+func TestBuildFailureHook(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.SkipNow()
+	}
+
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+	check(t, repo.InitModuleWithOptions("app-a", &Spec{
+		Name:  "app-a",
+		Build: map[string]*Cmd{"default": {Cmd: "echo", Args: []string{"build"}}},
+		Hooks: &Hooks{
+			Pre:       &Cmd{Cmd: "false"},
+			Post:      &Cmd{Cmd: "echo", Args: []string{"post"}},
+			OnFailure: &Cmd{Cmd: "sh", Args: []string{"-c", "echo failed $MBT_MODULE_NAME"}},
+		},
+	}))
+	check(t, repo.Commit("first"))
+
+	buff := new(bytes.Buffer)
+	_, err := NewWorld(t, ".tmp/repo").System.BuildCurrentBranch(NoFilter, stdTestCmdOptions(buff))
+
+	assert.EqualError(t, err, fmt.Sprintf(msgFailedBuild, "app-a"))
+	assert.EqualError(t, err.(*e.E).InnerError(), fmt.Sprintf(msgFailedHook, "pre", "app-a"))
+	assert.Equal(t, "failed app-a\n", buff.String())
+}
+
+func TestBuildWhen(t *testing.T) {
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+	check(t, repo.InitModuleWithOptions("app-a", &Spec{
+		Name:  "app-a",
+		Build: map[string]*Cmd{"default": {Cmd: "echo", Args: []string{"app-a"}, When: "app.changedFiles.exists(f, f.startsWith('app-a/db/'))"}},
+	}))
+	check(t, repo.Commit("first"))
+	c1 := repo.LastCommit
+
+	check(t, repo.WriteContent("app-a/db/a.sql", "select 1"))
+	check(t, repo.Commit("second"))
+	c2 := repo.LastCommit
+
+	check(t, repo.WriteContent("app-a/main.go", "package main"))
+	check(t, repo.Commit("third"))
+	c3 := repo.LastCommit
+
+	buff := new(bytes.Buffer)
+	summary, err := NewWorld(t, ".tmp/repo").System.BuildDiff(c1.String(), c2.String(), stdTestCmdOptions(buff))
+	check(t, err)
+	assert.Len(t, summary.Completed, 1)
+	assert.Equal(t, "app-a\n", buff.String())
+
+	buff = new(bytes.Buffer)
+	summary, err = NewWorld(t, ".tmp/repo").System.BuildDiff(c2.String(), c3.String(), stdTestCmdOptions(buff))
+	check(t, err)
+	assert.Len(t, summary.Completed, 0)
+	assert.Len(t, summary.Skipped, 1)
+	assert.Equal(t, "", buff.String())
+}
+
+func TestBuildMatrix(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.SkipNow()
+	}
+
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+	check(t, repo.InitModuleWithOptions("app-a", &Spec{
+		Name:   "app-a",
+		Build:  map[string]*Cmd{"default": {Cmd: "sh", Args: []string{"-c", "echo $MBT_VARIANT_GOOS $MBT_MODULE_VERSION"}}},
+		Matrix: map[string][]string{"goos": {"linux", "darwin"}},
+	}))
+	check(t, repo.Commit("first"))
+
+	buff := new(bytes.Buffer)
+	summary, err := NewWorld(t, ".tmp/repo").System.BuildCurrentBranch(NoFilter, stdTestCmdOptions(buff))
+	check(t, err)
+
+	v := summary.Manifest.Modules[0].Version()
+	assert.Len(t, summary.Completed, 2)
+	assert.Equal(t, "linux", summary.Completed[0].Module.Variant().Name)
+	assert.Equal(t, "darwin", summary.Completed[1].Module.Variant().Name)
+	assert.Equal(t, fmt.Sprintf("linux %s-linux\ndarwin %s-darwin\n", v, v), buff.String())
+}
+
+func TestBuildCachedOutputs(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.SkipNow()
+	}
+
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+	check(t, repo.InitModuleWithOptions("app-a", &Spec{
+		Name:    "app-a",
+		Build:   map[string]*Cmd{"default": {Cmd: "sh", Args: []string{"-c", "echo built; echo out > out.txt"}}},
+		Outputs: []string{"out.txt"},
+	}))
+	check(t, repo.WriteContent(".gitignore", "out.txt\n"))
+	check(t, repo.Commit("first"))
+
+	buff := new(bytes.Buffer)
+	options := stdTestCmdOptions(buff)
+	options.Cache = NewDirCache(".tmp/cache")
+	world := NewWorld(t, ".tmp/repo")
+	summary, err := world.System.BuildCurrentBranch(NoFilter, options)
+	check(t, err)
+	assert.False(t, summary.Completed[0].Cached)
+	assert.Equal(t, "built\n", buff.String())
+
+	check(t, os.Remove(".tmp/repo/app-a/out.txt"))
+	buff.Reset()
+	summary, err = world.System.BuildCurrentBranch(NoFilter, options)
+	check(t, err)
+	assert.True(t, summary.Completed[0].Cached)
+	assert.Equal(t, "", buff.String())
+
+	c, err := ioutil.ReadFile(".tmp/repo/app-a/out.txt")
+	check(t, err)
+	assert.Equal(t, "out\n", string(c))
+}
+
+func TestTestPr(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.SkipNow()
+	}
+
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+	check(t, repo.InitModuleWithOptions("app-a", &Spec{
+		Name:  "app-a",
+		Build: map[string]*Cmd{"default": {Cmd: "echo", Args: []string{"build app-a"}}},
+		Test:  map[string]*Cmd{"default": {Cmd: "echo", Args: []string{"test app-a"}}},
+	}))
+	check(t, repo.InitModuleWithOptions("app-b", &Spec{
+		Name:         "app-b",
+		Test:         map[string]*Cmd{"default": {Cmd: "echo", Args: []string{"test app-b"}}},
+		Dependencies: []string{"app-a"},
+	}))
+	check(t, repo.InitModuleWithOptions("app-c", &Spec{
+		Name: "app-c",
+		Test: map[string]*Cmd{"default": {Cmd: "echo", Args: []string{"test app-c"}}},
+	}))
+	check(t, repo.Commit("first"))
+
+	check(t, repo.SwitchToBranch("feature"))
+	check(t, repo.WriteContent("app-a/foo", "hello"))
+	check(t, repo.Commit("second"))
+
+	buff := new(bytes.Buffer)
+	summary, err := NewWorld(t, ".tmp/repo").System.TestPr("feature", "master", stdTestCmdOptions(buff))
+	check(t, err)
+
+	assert.Len(t, summary.Completed, 2)
+	assert.Equal(t, "test app-a\ntest app-b\n", buff.String())
+}
+
+func TestTestFailure(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.SkipNow()
+	}
+
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+	check(t, repo.InitModuleWithOptions("app-a", &Spec{
+		Name: "app-a",
+		Test: map[string]*Cmd{"default": {Cmd: "false"}},
+	}))
+	check(t, repo.Commit("first"))
+
+	buff := new(bytes.Buffer)
+	_, err := NewWorld(t, ".tmp/repo").System.TestWorkspace(NoFilter, stdTestCmdOptions(buff))
+	assert.EqualError(t, err, "Tests failed in module 'app-a'")
+}
+
+func TestBuildWorkspaceIsNotCached(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.SkipNow()
+	}
+
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+	check(t, repo.InitModuleWithOptions("app-a", &Spec{
+		Name:    "app-a",
+		Build:   map[string]*Cmd{"default": {Cmd: "sh", Args: []string{"-c", "echo built; echo out > out.txt"}}},
+		Outputs: []string{"out.txt"},
+	}))
+	check(t, repo.Commit("first"))
+
+	buff := new(bytes.Buffer)
+	options := stdTestCmdOptions(buff)
+	options.Cache = NewDirCache(".tmp/cache")
+	world := NewWorld(t, ".tmp/repo")
+	_, err := world.System.BuildWorkspace(NoFilter, options)
+	check(t, err)
+
+	summary, err := world.System.BuildWorkspace(NoFilter, options)
+	check(t, err)
+	assert.False(t, summary.Completed[0].Cached)
+	assert.Equal(t, "built\nbuilt\n", buff.String())
+}
+
+func TestRunTask(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.SkipNow()
+	}
+
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+	check(t, repo.InitModuleWithOptions("app-a", &Spec{
+		Name:  "app-a",
+		Build: map[string]*Cmd{"default": {Cmd: "echo", Args: []string{"build app-a"}}},
+		Tasks: map[string]*Task{
+			"lint":   {Cmd: "echo", Args: []string{"lint app-a"}},
+			"deploy": {Cmd: "echo", Args: []string{"deploy app-a"}, DependsOn: []string{"build", "lint"}},
+		},
+	}))
+	check(t, repo.InitModuleWithOptions("app-b", &Spec{
+		Name:  "app-b",
+		Build: map[string]*Cmd{"default": {Cmd: "echo", Args: []string{"build app-b"}}},
+	}))
+	check(t, repo.Commit("first"))
+
+	world := NewWorld(t, ".tmp/repo")
+	m, err := world.System.ManifestByCurrentBranch()
+	check(t, err)
+
+	buff := new(bytes.Buffer)
+	summary, err := world.System.RunTask("deploy", m, stdTestCmdOptions(buff))
+	check(t, err)
+
+	assert.Len(t, summary.Completed, 1)
+	assert.Len(t, summary.Skipped, 1)
+	assert.Equal(t, "build app-a\nlint app-a\ndeploy app-a\n", buff.String())
+}
+
+func TestRunTaskWithUpstreamDependency(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.SkipNow()
+	}
+
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+	check(t, repo.InitModuleWithOptions("app-a", &Spec{
+		Name:  "app-a",
+		Build: map[string]*Cmd{"default": {Cmd: "echo", Args: []string{"build app-a"}}},
+	}))
+	check(t, repo.InitModuleWithOptions("app-b", &Spec{
+		Name:         "app-b",
+		Dependencies: []string{"app-a"},
+		Tasks: map[string]*Task{
+			"test": {Cmd: "echo", Args: []string{"test app-b"}, DependsOn: []string{"^build"}},
+		},
+	}))
+	check(t, repo.Commit("first"))
+
+	world := NewWorld(t, ".tmp/repo")
+	m, err := world.System.ManifestByCurrentBranch()
+	check(t, err)
+
+	m, err = m.ApplyFilters(ExactMatchFilter("app-b"))
+	check(t, err)
+
+	buff := new(bytes.Buffer)
+	summary, err := world.System.RunTask("test", m, stdTestCmdOptions(buff))
+	check(t, err)
+
+	assert.Len(t, summary.Completed, 1)
+	assert.Equal(t, "build app-a\ntest app-b\n", buff.String())
+}
+
+func TestBuildSBOM(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.SkipNow()
+	}
+
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+	check(t, repo.InitModuleWithOptions("app-a", &Spec{
+		Name:  "app-a",
+		Build: map[string]*Cmd{"default": {Cmd: "echo", Args: []string{"built"}}},
+		SBOM:  &Cmd{Cmd: "sh", Args: []string{"-c", "echo \"{\\\"name\\\": \\\"$MBT_MODULE_NAME\\\"}\""}},
+	}))
+	check(t, repo.Commit("first"))
+
+	buff := new(bytes.Buffer)
+	options := stdTestCmdOptions(buff)
+	options.SBOM = &SBOMOptions{Dir: ".tmp/sbom", Format: SBOMFormatSPDX}
+	world := NewWorld(t, ".tmp/repo")
+	summary, err := world.System.BuildCurrentBranch(NoFilter, options)
+	check(t, err)
+
+	m := summary.Manifest.Modules[0]
+	assert.Equal(t, filepath.Join(".tmp/sbom", "app-a-"+m.Version()+".spdx.json"), summary.Completed[0].SBOM)
+	assert.Equal(t, "built\n", buff.String())
+
+	c, err := ioutil.ReadFile(summary.Completed[0].SBOM)
+	check(t, err)
+	assert.Equal(t, "{\"name\": \"app-a\"}\n", string(c))
+}
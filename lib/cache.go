@@ -0,0 +1,391 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mbtproject/mbt/e"
+)
+
+type dirCache struct {
+	dir string
+}
+
+const dirCacheEntrySuffix = ".tar.gz"
+
+// NewDirCache creates a Cache storing entries in a local directory.
+func NewDirCache(dir string) Cache {
+	return &dirCache{dir: dir}
+}
+
+func (c *dirCache) path(key string) string {
+	return filepath.Join(c.dir, key+dirCacheEntrySuffix)
+}
+
+func (c *dirCache) Get(key string) (io.ReadCloser, error) {
+	f, err := os.Open(c.path(key))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, e.Wrapf(ErrClassUser, err, msgFailedReadFile, c.path(key))
+	}
+
+	// Modification time records when the entry was last used so that
+	// it is retained by CollectCache. Failing to update it is harmless.
+	now := time.Now()
+	_ = os.Chtimes(c.path(key), now, now)
+	return f, nil
+}
+
+func (c *dirCache) Put(key string, r io.Reader) error {
+	p := c.path(key)
+	err := os.MkdirAll(filepath.Dir(p), 0755)
+	if err != nil {
+		return e.Wrapf(ErrClassUser, err, msgFailedWriteFile, p)
+	}
+
+	// Write to a temporary file first so that concurrent readers
+	// never observe a partially written entry.
+	f, err := ioutil.TempFile(filepath.Dir(p), ".tmp-")
+	if err != nil {
+		return e.Wrapf(ErrClassUser, err, msgFailedWriteFile, p)
+	}
+
+	_, err = io.Copy(f, r)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), p)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return e.Wrapf(ErrClassUser, err, msgFailedWriteFile, p)
+	}
+
+	return nil
+}
+
+type httpCache struct {
+	url    string
+	client *http.Client
+}
+
+// NewHTTPCache creates a Cache backed by an HTTP server.
+// Entries are read with GET and written with PUT requests
+// to <baseURL>/<key>.
+func NewHTTPCache(baseURL string) Cache {
+	return &httpCache{url: strings.TrimSuffix(baseURL, "/"), client: http.DefaultClient}
+}
+
+func (c *httpCache) entryURL(key string) string {
+	return fmt.Sprintf("%s/%s", c.url, url.PathEscape(key))
+}
+
+func (c *httpCache) Get(key string) (io.ReadCloser, error) {
+	if err := RequireNetwork("Remote cache " + c.url); err != nil {
+		return nil, err
+	}
+
+	res, err := c.client.Get(c.entryURL(key))
+	if err != nil {
+		return nil, e.Wrap(ErrClassUser, err)
+	}
+
+	switch res.StatusCode {
+	case http.StatusOK:
+		return res.Body, nil
+	case http.StatusNotFound:
+		res.Body.Close()
+		return nil, nil
+	default:
+		res.Body.Close()
+		return nil, e.NewErrorf(ErrClassUser, msgUnexpectedCacheResponse, res.Status, key)
+	}
+}
+
+func (c *httpCache) Put(key string, r io.Reader) error {
+	if err := RequireNetwork("Remote cache " + c.url); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPut, c.entryURL(key), r)
+	if err != nil {
+		return e.Wrap(ErrClassInternal, err)
+	}
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return e.Wrap(ErrClassUser, err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return e.NewErrorf(ErrClassUser, msgUnexpectedCacheResponse, res.Status, key)
+	}
+
+	return nil
+}
+
+type tieredCache struct {
+	caches []Cache
+}
+
+// NewTieredCache creates a Cache that looks up entries in the
+// specified caches in order. When an entry is found, it is copied
+// to the caches preceding the one it was found in.
+// Entries are written to all caches.
+// This is typically used to combine a local cache with a remote one.
+func NewTieredCache(caches ...Cache) Cache {
+	return &tieredCache{caches: caches}
+}
+
+func (c *tieredCache) Get(key string) (io.ReadCloser, error) {
+	for i, cache := range c.caches {
+		r, err := cache.Get(key)
+		if err != nil {
+			return nil, err
+		}
+		if r == nil {
+			continue
+		}
+
+		if i == 0 {
+			return r, nil
+		}
+
+		content, err := ioutil.ReadAll(r)
+		r.Close()
+		if err != nil {
+			return nil, e.Wrap(ErrClassUser, err)
+		}
+
+		for _, prev := range c.caches[:i] {
+			if err := prev.Put(key, bytes.NewReader(content)); err != nil {
+				return nil, err
+			}
+		}
+
+		return ioutil.NopCloser(bytes.NewReader(content)), nil
+	}
+
+	return nil, nil
+}
+
+func (c *tieredCache) Put(key string, r io.Reader) error {
+	content, err := ioutil.ReadAll(r)
+	if err != nil {
+		return e.Wrap(ErrClassUser, err)
+	}
+
+	for _, cache := range c.caches {
+		if err := cache.Put(key, bytes.NewReader(content)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// restoreOutputs extracts the cached outputs stored for key into the
+// module directory. Returns false if outputs are not in the cache.
+func restoreOutputs(cache Cache, key string, manifest *Manifest, mod *Module) (bool, error) {
+	r, err := cache.Get(key)
+	if err != nil || r == nil {
+		return false, err
+	}
+	defer r.Close()
+
+	err = extractOutputs(r, filepath.Join(manifest.Dir, mod.Path()))
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// saveOutputs stores the files matching outputs in module directory
+// in the cache.
+func saveOutputs(cache Cache, key string, manifest *Manifest, mod *Module, outputs []string) error {
+	buff := new(bytes.Buffer)
+	err := archiveOutputs(buff, filepath.Join(manifest.Dir, mod.Path()), outputs)
+	if err != nil {
+		return err
+	}
+
+	return cache.Put(key, buff)
+}
+
+// archiveOutputs writes a gzipped tarball of the files matching
+// patterns in dir to w. Directories are included recursively.
+func archiveOutputs(w io.Writer, dir string, patterns []string) error {
+	paths, err := outputFiles(dir, patterns)
+	if err != nil {
+		return err
+	}
+
+	gw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gw)
+	for _, p := range paths {
+		if err := addToArchive(tw, dir, p); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return e.Wrap(ErrClassInternal, err)
+	}
+	if err := gw.Close(); err != nil {
+		return e.Wrap(ErrClassInternal, err)
+	}
+
+	return nil
+}
+
+// outputFiles returns the files matching the output patterns in dir.
+// Directories are expanded to the files within.
+func outputFiles(dir string, patterns []string) ([]string, error) {
+	files := make(map[string]bool)
+	for _, p := range patterns {
+		matches, err := filepath.Glob(filepath.Join(dir, filepath.FromSlash(p)))
+		if err != nil {
+			return nil, e.Wrap(ErrClassUser, err)
+		}
+
+		for _, m := range matches {
+			err = filepath.Walk(m, func(path string, info os.FileInfo, err error) error {
+				if err != nil {
+					return err
+				}
+				if info.Mode().IsRegular() {
+					files[path] = true
+				}
+				return nil
+			})
+			if err != nil {
+				return nil, e.Wrapf(ErrClassUser, err, msgFailedLocalPath, m)
+			}
+		}
+	}
+
+	paths := make([]string, 0, len(files))
+	for f := range files {
+		paths = append(paths, f)
+	}
+	sort.Strings(paths)
+
+	return paths, nil
+}
+
+func addToArchive(tw *tar.Writer, dir, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return e.Wrapf(ErrClassUser, err, msgFailedReadFile, path)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return e.Wrapf(ErrClassUser, err, msgFailedReadFile, path)
+	}
+
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return e.Wrap(ErrClassInternal, err)
+	}
+
+	err = tw.WriteHeader(&tar.Header{
+		Name:     filepath.ToSlash(rel),
+		Mode:     int64(info.Mode().Perm()),
+		Size:     info.Size(),
+		ModTime:  info.ModTime(),
+		Typeflag: tar.TypeReg,
+	})
+	if err == nil {
+		_, err = io.Copy(tw, f)
+	}
+	if err != nil {
+		return e.Wrap(ErrClassInternal, err)
+	}
+
+	return nil
+}
+
+// extractOutputs extracts a tarball created by archiveOutputs into dir.
+func extractOutputs(r io.Reader, dir string) error {
+	gr, err := gzip.NewReader(r)
+	if err != nil {
+		return e.Wrap(ErrClassUser, err)
+	}
+	defer gr.Close()
+
+	tr := tar.NewReader(gr)
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return e.Wrap(ErrClassUser, err)
+		}
+
+		name := filepath.Clean(filepath.FromSlash(h.Name))
+		if h.Typeflag != tar.TypeReg || filepath.IsAbs(name) || name == ".." || strings.HasPrefix(name, ".."+string(filepath.Separator)) {
+			// Entries are always regular files within the module
+			// directory unless the archive is tampered with.
+			continue
+		}
+
+		err = extractFile(tr, filepath.Join(dir, name), os.FileMode(h.Mode).Perm())
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func extractFile(r io.Reader, path string, mode os.FileMode) error {
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return e.Wrapf(ErrClassUser, err, msgFailedWriteFile, path)
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, mode)
+	if err != nil {
+		return e.Wrapf(ErrClassUser, err, msgFailedWriteFile, path)
+	}
+	defer f.Close()
+
+	_, err = io.Copy(f, r)
+	if err != nil {
+		return e.Wrapf(ErrClassUser, err, msgFailedWriteFile, path)
+	}
+
+	return nil
+}
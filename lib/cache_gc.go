@@ -0,0 +1,167 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mbtproject/mbt/e"
+)
+
+// CacheEntry describes an entry stored in a cache.
+type CacheEntry struct {
+	Key  string
+	Size int64
+	// Time is when the entry was last written or read.
+	Time time.Time
+}
+
+// CacheGCOptions describes the retention policies applied when
+// collecting the garbage in a cache. Zero values disable a policy.
+type CacheGCOptions struct {
+	// MaxAge removes the entries not used within this duration.
+	MaxAge time.Duration
+	// MaxSize removes the least recently used entries until the total
+	// size of the cache is at most this many bytes.
+	MaxSize int64
+	// KeepLast keeps only the most recently used entries of each
+	// module. Modules are identified by Modules.
+	KeepLast int
+	// Modules are the names of the modules entries are stored for.
+	// Entries of other modules are grouped by their key.
+	Modules []string
+	// DryRun reports the entries that would be removed without
+	// removing them.
+	DryRun bool
+}
+
+// CacheGCResult is the outcome of collecting the garbage in a cache.
+type CacheGCResult struct {
+	// Removed are the entries removed from the cache.
+	Removed []*CacheEntry
+	// Kept is the number of entries left in the cache.
+	Kept int
+	// Freed is the total size of the removed entries in bytes.
+	Freed int64
+}
+
+// CollectCache removes the entries of cache according to the
+// retention policies in options. Only the caches implementing
+// CollectableCache (e.g. NewDirCache) are supported since other
+// caches cannot list their entries.
+func CollectCache(cache Cache, options *CacheGCOptions) (*CacheGCResult, error) {
+	return collectCache(cache, options, time.Now())
+}
+
+func collectCache(cache Cache, options *CacheGCOptions, now time.Time) (*CacheGCResult, error) {
+	c, ok := cache.(CollectableCache)
+	if !ok {
+		return nil, e.NewError(ErrClassUser, msgCacheGCNotSupported)
+	}
+
+	entries, err := c.Entries()
+	if err != nil {
+		return nil, err
+	}
+
+	// Most recently used entries are retained first.
+	sort.SliceStable(entries, func(i, j int) bool {
+		if !entries[i].Time.Equal(entries[j].Time) {
+			return entries[i].Time.After(entries[j].Time)
+		}
+		return entries[i].Key < entries[j].Key
+	})
+
+	kept := make(map[string]int)
+	var size int64
+	result := &CacheGCResult{Removed: make([]*CacheEntry, 0)}
+	for _, entry := range entries {
+		group := cacheEntryModule(entry.Key, options.Modules)
+		remove := (options.MaxAge > 0 && now.Sub(entry.Time) > options.MaxAge) ||
+			(options.KeepLast > 0 && kept[group] >= options.KeepLast) ||
+			(options.MaxSize > 0 && size+entry.Size > options.MaxSize)
+
+		if !remove {
+			kept[group]++
+			size += entry.Size
+			result.Kept++
+			continue
+		}
+
+		if !options.DryRun {
+			if err := c.Delete(entry.Key); err != nil {
+				return nil, err
+			}
+		}
+		result.Removed = append(result.Removed, entry)
+		result.Freed += entry.Size
+	}
+
+	return result, nil
+}
+
+// cacheEntryModule returns the name of the module the entry with key
+// is stored for. Keys start with the module name followed by a dash
+// (see target.cacheKey), therefore the longest matching name is used.
+// Key itself is returned if it does not belong to any module.
+func cacheEntryModule(key string, modules []string) string {
+	match := ""
+	for _, m := range modules {
+		if strings.HasPrefix(key, m+"-") && len(m) > len(match) {
+			match = m
+		}
+	}
+	if match == "" {
+		return key
+	}
+	return match
+}
+
+func (c *dirCache) Entries() ([]*CacheEntry, error) {
+	files, err := ioutil.ReadDir(c.dir)
+	if os.IsNotExist(err) {
+		return []*CacheEntry{}, nil
+	}
+	if err != nil {
+		return nil, e.Wrapf(ErrClassUser, err, msgFailedReadFile, c.dir)
+	}
+
+	entries := make([]*CacheEntry, 0, len(files))
+	for _, f := range files {
+		// Temporary files of entries being written are skipped.
+		if !f.Mode().IsRegular() || !strings.HasSuffix(f.Name(), dirCacheEntrySuffix) || strings.HasPrefix(f.Name(), ".tmp-") {
+			continue
+		}
+		entries = append(entries, &CacheEntry{
+			Key:  strings.TrimSuffix(f.Name(), dirCacheEntrySuffix),
+			Size: f.Size(),
+			Time: f.ModTime(),
+		})
+	}
+
+	return entries, nil
+}
+
+func (c *dirCache) Delete(key string) error {
+	if err := os.Remove(c.path(key)); err != nil && !os.IsNotExist(err) {
+		return e.Wrapf(ErrClassUser, err, msgFailedWriteFile, c.path(key))
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var gcNow = time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+
+// putCacheEntry stores an entry of size bytes last used age ago.
+func putCacheEntry(t *testing.T, c Cache, key string, size int, age time.Duration) {
+	check(t, c.Put(key, strings.NewReader(strings.Repeat("x", size))))
+	used := gcNow.Add(-age)
+	check(t, os.Chtimes(c.(*dirCache).path(key), used, used))
+}
+
+func testGCCache(t *testing.T) Cache {
+	clean()
+	c := NewDirCache(".tmp/cache")
+	putCacheEntry(t, c, "app-a-a3-linux", 10, time.Hour)
+	putCacheEntry(t, c, "app-a-a2-linux", 10, 2*time.Hour)
+	putCacheEntry(t, c, "app-a-a1-linux", 10, 48*time.Hour)
+	putCacheEntry(t, c, "app-a-b-b1-linux", 10, 3*time.Hour)
+	putCacheEntry(t, c, "app-c-c1-linux", 10, 4*time.Hour)
+	return c
+}
+
+func removedKeys(r *CacheGCResult) []string {
+	keys := make([]string, 0)
+	for _, e := range r.Removed {
+		keys = append(keys, e.Key)
+	}
+	return keys
+}
+
+func TestCacheGCMaxAge(t *testing.T) {
+	c := testGCCache(t)
+
+	r, err := collectCache(c, &CacheGCOptions{MaxAge: 24 * time.Hour}, gcNow)
+	check(t, err)
+
+	assert.Equal(t, []string{"app-a-a1-linux"}, removedKeys(r))
+	assert.Equal(t, 4, r.Kept)
+	assert.Equal(t, int64(10), r.Freed)
+	assert.Equal(t, "", readCacheEntry(t, c, "app-a-a1-linux"))
+}
+
+func TestCacheGCMaxSize(t *testing.T) {
+	c := testGCCache(t)
+
+	r, err := collectCache(c, &CacheGCOptions{MaxSize: 25}, gcNow)
+	check(t, err)
+
+	assert.Equal(t, []string{"app-a-b-b1-linux", "app-c-c1-linux", "app-a-a1-linux"}, removedKeys(r))
+	assert.Equal(t, 2, r.Kept)
+}
+
+func TestCacheGCKeepLast(t *testing.T) {
+	c := testGCCache(t)
+
+	r, err := collectCache(c, &CacheGCOptions{KeepLast: 1, Modules: []string{"app-a", "app-a-b", "app-c"}}, gcNow)
+	check(t, err)
+
+	assert.Equal(t, []string{"app-a-a2-linux", "app-a-a1-linux"}, removedKeys(r))
+	assert.Equal(t, 3, r.Kept)
+}
+
+func TestCacheGCDryRun(t *testing.T) {
+	c := testGCCache(t)
+
+	r, err := collectCache(c, &CacheGCOptions{MaxAge: time.Minute, DryRun: true}, gcNow)
+	check(t, err)
+
+	assert.Len(t, r.Removed, 5)
+	assert.Equal(t, strings.Repeat("x", 10), readCacheEntry(t, c, "app-a-a1-linux"))
+}
+
+func TestCacheGCOfEmptyCache(t *testing.T) {
+	clean()
+
+	r, err := CollectCache(NewDirCache(".tmp/cache"), &CacheGCOptions{MaxAge: time.Minute})
+	check(t, err)
+
+	assert.Empty(t, r.Removed)
+	assert.Equal(t, 0, r.Kept)
+}
+
+func TestCacheGCOfRemoteCache(t *testing.T) {
+	_, err := CollectCache(NewHTTPCache("http://localhost"), &CacheGCOptions{})
+
+	assert.EqualError(t, err, msgCacheGCNotSupported)
+}
+
+func TestCacheEntryModule(t *testing.T) {
+	modules := []string{"app-a", "app-a-b"}
+
+	assert.Equal(t, "app-a", cacheEntryModule("app-a-a1-linux", modules))
+	assert.Equal(t, "app-a-b", cacheEntryModule("app-a-b-b1-test-linux", modules))
+	assert.Equal(t, "app-x-x1-linux", cacheEntryModule("app-x-x1-linux", modules))
+}
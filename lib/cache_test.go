@@ -0,0 +1,149 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func readCacheEntry(t *testing.T, c Cache, key string) string {
+	r, err := c.Get(key)
+	check(t, err)
+	if r == nil {
+		return ""
+	}
+	defer r.Close()
+
+	b, err := ioutil.ReadAll(r)
+	check(t, err)
+	return string(b)
+}
+
+func TestDirCache(t *testing.T) {
+	clean()
+	c := NewDirCache(".tmp/cache")
+
+	r, err := c.Get("a")
+	check(t, err)
+	assert.Nil(t, r)
+
+	check(t, c.Put("a", strings.NewReader("content")))
+	assert.Equal(t, "content", readCacheEntry(t, c, "a"))
+}
+
+func TestHTTPCache(t *testing.T) {
+	entries := make(map[string][]byte)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			c, ok := entries[r.URL.Path]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Write(c)
+		case http.MethodPut:
+			c, _ := ioutil.ReadAll(r.Body)
+			entries[r.URL.Path] = c
+			w.WriteHeader(http.StatusCreated)
+		}
+	}))
+	defer server.Close()
+
+	c := NewHTTPCache(server.URL + "/cache/")
+	assert.Equal(t, "", readCacheEntry(t, c, "a"))
+
+	check(t, c.Put("a", strings.NewReader("content")))
+	assert.Equal(t, []byte("content"), entries["/cache/a"])
+	assert.Equal(t, "content", readCacheEntry(t, c, "a"))
+}
+
+func TestHTTPCacheError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	c := NewHTTPCache(server.URL)
+	_, err := c.Get("a")
+	assert.EqualError(t, err, "Unexpected response 500 Internal Server Error from cache for key 'a'")
+
+	err = c.Put("a", strings.NewReader("content"))
+	assert.EqualError(t, err, "Unexpected response 500 Internal Server Error from cache for key 'a'")
+}
+
+func TestTieredCache(t *testing.T) {
+	clean()
+	local := NewDirCache(".tmp/local")
+	remote := NewDirCache(".tmp/remote")
+	c := NewTieredCache(local, remote)
+
+	check(t, remote.Put("a", strings.NewReader("remote")))
+	assert.Equal(t, "remote", readCacheEntry(t, c, "a"))
+	assert.Equal(t, "remote", readCacheEntry(t, local, "a"))
+
+	check(t, c.Put("b", strings.NewReader("both")))
+	assert.Equal(t, "both", readCacheEntry(t, local, "b"))
+	assert.Equal(t, "both", readCacheEntry(t, remote, "b"))
+
+	assert.Equal(t, "", readCacheEntry(t, c, "c"))
+}
+
+func TestArchiveOutputs(t *testing.T) {
+	clean()
+	check(t, os.MkdirAll(".tmp/src/bin/sub", 0755))
+	check(t, ioutil.WriteFile(".tmp/src/bin/a", []byte("a"), 0755))
+	check(t, ioutil.WriteFile(".tmp/src/bin/sub/b", []byte("b"), 0644))
+	check(t, ioutil.WriteFile(".tmp/src/c.txt", []byte("c"), 0644))
+	check(t, ioutil.WriteFile(".tmp/src/d.go", []byte("d"), 0644))
+
+	buff := new(bytes.Buffer)
+	check(t, archiveOutputs(buff, ".tmp/src", []string{"bin", "*.txt"}))
+	check(t, extractOutputs(buff, ".tmp/dst"))
+
+	files := make([]string, 0)
+	check(t, filepath.Walk(".tmp/dst", func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			files = append(files, filepath.ToSlash(path))
+		}
+		return err
+	}))
+	assert.Equal(t, []string{".tmp/dst/bin/a", ".tmp/dst/bin/sub/b", ".tmp/dst/c.txt"}, files)
+
+	c, err := ioutil.ReadFile(".tmp/dst/bin/sub/b")
+	check(t, err)
+	assert.Equal(t, "b", string(c))
+}
+
+func TestOutputOutsideModule(t *testing.T) {
+	_, err := newSpec([]byte("name: app-a\noutputs: [../bin]"))
+	assert.EqualError(t, err, "Output '../bin' of module 'app-a' must be a relative path within the module")
+
+	_, err = newSpec([]byte("name: app-a\noutputs: [bin/../../bin]"))
+	assert.Error(t, err)
+
+	_, err = newSpec([]byte("name: app-a\noutputs: [bin, '*.jar']"))
+	assert.NoError(t, err)
+}
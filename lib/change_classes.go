@@ -0,0 +1,159 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"regexp"
+	"sort"
+
+	yaml "github.com/go-yaml/yaml"
+	"github.com/mbtproject/mbt/e"
+)
+
+// ChangeClassesFile is the path of the file describing the classes
+// of changes relative to the root of the repository.
+const ChangeClassesFile = ".mbt/classes.yml"
+
+// Built-in classes of changes.
+const (
+	ChangeClassCode       = "code"
+	ChangeClassDocs       = "docs"
+	ChangeClassConfig     = "config"
+	ChangeClassTests      = "tests"
+	ChangeClassMigrations = "migrations"
+)
+
+// ChangeClassRule maps the files matching any of the patterns
+// to a class of changes. Patterns follow the rules of CODEOWNERS
+// patterns and are relative to the module directory.
+type ChangeClassRule struct {
+	Class    string   `yaml:"class"`
+	Patterns []string `yaml:"patterns"`
+}
+
+// DefaultChangeClassRules are used when the repository does not
+// have a ChangeClassesFile.
+var DefaultChangeClassRules = []*ChangeClassRule{
+	{Class: ChangeClassTests, Patterns: []string{"*_test.go", "*.test.*", "*.spec.*", "test/", "tests/", "__tests__/"}},
+	{Class: ChangeClassDocs, Patterns: []string{"*.md", "*.rst", "*.adoc", "docs/", "LICENSE"}},
+	{Class: ChangeClassConfig, Patterns: []string{"*.yml", "*.yaml", "*.json", "*.toml", "*.ini", "*.properties", ".*"}},
+}
+
+var defaultChangeClassRules = newChangeClassRules(DefaultChangeClassRules)
+
+type changeClassRule struct {
+	class    string
+	patterns []*regexp.Regexp
+}
+
+// changeClassRules classifies changed files. Migration files of
+// modules are classified as migrations, otherwise the first
+// matching rule takes precedence. Files without a matching rule
+// are classified as code.
+type changeClassRules []*changeClassRule
+
+func newChangeClassRules(rules []*ChangeClassRule) changeClassRules {
+	compiled := make(changeClassRules, 0, len(rules))
+	for _, r := range rules {
+		c := &changeClassRule{class: r.Class}
+		for _, p := range r.Patterns {
+			if re, err := codeOwnersPattern(p); err == nil {
+				c.patterns = append(c.patterns, re)
+			}
+		}
+		compiled = append(compiled, c)
+	}
+	return compiled
+}
+
+// parseChangeClasses parses the contents of ChangeClassesFile.
+func parseChangeClasses(content []byte) (changeClassRules, error) {
+	var rules []*ChangeClassRule
+	if err := yaml.Unmarshal(content, &rules); err != nil {
+		return nil, e.Wrapf(ErrClassUser, err, msgInvalidChangeClasses, ChangeClassesFile)
+	}
+
+	for _, r := range rules {
+		if r == nil || r.Class == "" {
+			return nil, e.NewErrorf(ErrClassUser, msgInvalidChangeClasses, ChangeClassesFile)
+		}
+	}
+
+	return newChangeClassRules(rules), nil
+}
+
+// class returns the class of file f in module mod.
+func (rules changeClassRules) class(mod *Module, f string) string {
+	if isMigration(mod, []string{f}) {
+		return ChangeClassMigrations
+	}
+
+	p := moduleRelativePath(mod, f)
+	for _, r := range rules {
+		for _, re := range r.patterns {
+			if re.MatchString(p) {
+				return r.class
+			}
+		}
+	}
+
+	return ChangeClassCode
+}
+
+// classify returns the sorted classes of the files in module mod.
+func (rules changeClassRules) classify(mod *Module, files []string) []string {
+	set := make(map[string]bool)
+	for _, f := range files {
+		set[rules.class(mod, f)] = true
+	}
+
+	classes := make([]string, 0, len(set))
+	for c := range set {
+		classes = append(classes, c)
+	}
+	sort.Strings(classes)
+	return classes
+}
+
+// setChangedFiles sets the files changed in the diff used to create
+// the manifest and classifies the changes of its modules.
+func (m *Manifest) setChangedFiles(files []string) {
+	m.ChangedFiles = files
+	for _, mod := range m.Modules {
+		rules := mod.metadata.classRules
+		if rules == nil {
+			rules = defaultChangeClassRules
+		}
+		mod.changeClasses = rules.classify(mod, mod.ChangedFiles(m))
+	}
+}
+
+// assignChangeClasses sets the rules used to classify the changes
+// of the modules.
+func (set moduleMetadataSet) assignChangeClasses(rules changeClassRules) {
+	for _, m := range set {
+		m.classRules = rules
+	}
+}
+
+// ChangeClasses returns the sorted classes of the changes of the
+// module in a diff based manifest.
+func (a *Module) ChangeClasses() []string {
+	if a.changeClasses == nil {
+		return []string{}
+	}
+	return append([]string{}, a.changeClasses...)
+}
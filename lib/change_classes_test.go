@@ -0,0 +1,108 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefaultChangeClasses(t *testing.T) {
+	a := newTestModule("app-a", "app-a", "a")
+	b := newTestModule("app-b", "app-b", "b")
+	c := newTestModule("app-c", "app-c", "c")
+	m := &Manifest{Modules: Modules{a, b, c}}
+
+	m.setChangedFiles([]string{
+		"app-a/README.md",
+		"app-a/docs/guide.txt",
+		"app-b/main.go",
+		"app-b/main_test.go",
+		"app-b/config.yml",
+		"app-b/migrations/001.sql",
+	})
+
+	assert.Equal(t, []string{"docs"}, a.ChangeClasses())
+	assert.Equal(t, []string{"code", "config", "migrations", "tests"}, b.ChangeClasses())
+	assert.Equal(t, []string{}, c.ChangeClasses())
+	assert.True(t, b.RequiresMigration())
+	assert.Equal(t, []string{"docs"}, a.Document().ChangeClasses)
+}
+
+func TestChangeClassRules(t *testing.T) {
+	rules, err := parseChangeClasses([]byte(`
+- class: generated
+  patterns: ["*.pb.go"]
+- class: docs
+  patterns: ["*.md"]
+`))
+	check(t, err)
+
+	mod := newTestModule("app-a", "app-a", "a")
+	assert.Equal(t, "generated", rules.class(mod, "app-a/api/api.pb.go"))
+	assert.Equal(t, "docs", rules.class(mod, "app-a/README.md"))
+	assert.Equal(t, "code", rules.class(mod, "app-a/main_test.go"))
+	assert.Equal(t, "migrations", rules.class(mod, "app-a/migrations/001.sql"))
+}
+
+func TestInvalidChangeClasses(t *testing.T) {
+	_, err := parseChangeClasses([]byte(`docs: ["*.md"]`))
+	assert.Error(t, err)
+
+	_, err = parseChangeClasses([]byte(`- patterns: ["*.md"]`))
+	assert.EqualError(t, err, "Invalid change classes file '.mbt/classes.yml' (expected a list of class and patterns)")
+}
+
+func TestChangeClassesInExpressions(t *testing.T) {
+	when := "size(app.changeClasses) == 0 || app.changeClasses.exists(c, c != 'docs')"
+	a := newTestModule("app-a", "app-a", "a")
+	m := &Manifest{Modules: Modules{a}}
+
+	r, err := evalWhen("deploy", when, m, a)
+	check(t, err)
+	assert.True(t, r)
+
+	m.setChangedFiles([]string{"app-a/README.md"})
+	r, err = evalWhen("deploy", when, m, a)
+	check(t, err)
+	assert.False(t, r)
+
+	m.setChangedFiles([]string{"app-a/README.md", "app-a/main.go"})
+	r, err = evalWhen("deploy", when, m, a)
+	check(t, err)
+	assert.True(t, r)
+}
+
+func TestChangeClassesOfVariants(t *testing.T) {
+	when := "app.changeClasses.exists(c, c != 'docs')"
+	a := newModule(newModuleMetadata("app-a", "a", &Spec{
+		Name:   "app-a",
+		Matrix: map[string][]string{"goos": {"linux", "darwin"}},
+	}, nil), nil)
+	m := &Manifest{Modules: Modules{a}}
+	m.setChangedFiles([]string{"app-a/README.md", "app-a/main.go"})
+
+	variants := a.Variants()
+	assert.Len(t, variants, 2)
+	for _, v := range variants {
+		assert.Equal(t, []string{"code", "docs"}, v.ChangeClasses())
+
+		r, err := evalWhen("deploy", when, m, v)
+		check(t, err)
+		assert.True(t, r)
+	}
+}
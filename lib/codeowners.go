@@ -0,0 +1,119 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"regexp"
+	"strings"
+)
+
+// codeOwnersLocations are the paths of CODEOWNERS file in the order
+// of precedence.
+var codeOwnersLocations = []string{".github/CODEOWNERS", "CODEOWNERS", "docs/CODEOWNERS"}
+
+type codeOwnersRule struct {
+	pattern *regexp.Regexp
+	owners  []string
+}
+
+// codeOwners is a parsed CODEOWNERS file.
+type codeOwners []*codeOwnersRule
+
+// parseCodeOwners parses the content of a CODEOWNERS file.
+// Invalid patterns are ignored.
+func parseCodeOwners(content []byte) codeOwners {
+	rules := make(codeOwners, 0)
+	for _, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		owners := make([]string, 0, len(fields)-1)
+		for _, f := range fields[1:] {
+			if strings.HasPrefix(f, "#") {
+				break
+			}
+			owners = append(owners, f)
+		}
+
+		p, err := codeOwnersPattern(fields[0])
+		if err != nil {
+			continue
+		}
+
+		rules = append(rules, &codeOwnersRule{pattern: p, owners: owners})
+	}
+
+	return rules
+}
+
+// codeOwnersPattern converts a CODEOWNERS pattern to a regular expression
+// matching the paths it applies to.
+// Patterns follow the rules of .gitignore. That is, a pattern is relative to
+// the repository root if it contains a slash other than a trailing one.
+// Otherwise, it matches at any level.
+func codeOwnersPattern(pattern string) (*regexp.Regexp, error) {
+	anchored := strings.Contains(strings.TrimSuffix(pattern, "/"), "/")
+	pattern = strings.Trim(pattern, "/")
+
+	var b strings.Builder
+	b.WriteString("^")
+	if !anchored {
+		b.WriteString("(.*/)?")
+	}
+
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; c {
+		case '*':
+			if i+1 < len(pattern) && pattern[i+1] == '*' {
+				b.WriteString(".*")
+				i++
+			} else {
+				b.WriteString("[^/]*")
+			}
+		case '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+
+	// A pattern matching a directory applies to everything within.
+	b.WriteString("(/.*)?$")
+	return regexp.Compile(b.String())
+}
+
+// owners returns the owners of the directory at path.
+// Last matching rule takes precedence as it does in GitHub.
+func (c codeOwners) owners(path string) []string {
+	for i := len(c) - 1; i >= 0; i-- {
+		if c[i].pattern.MatchString(path) {
+			return c[i].owners
+		}
+	}
+
+	return []string{}
+}
+
+// assignOwners sets the owners of each module in set according to
+// the specified CODEOWNERS.
+func (set moduleMetadataSet) assignOwners(c codeOwners) {
+	for _, m := range set {
+		m.owners = c.owners(m.dir)
+	}
+}
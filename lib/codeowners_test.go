@@ -0,0 +1,67 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCodeOwners(t *testing.T) {
+	c := parseCodeOwners([]byte(`# Default owners
+* @org/platform
+
+apps/ @org/apps
+/apps/payments @org/payments @alice # Payments team
+services/*/api @org/api
+docs @org/docs
+`))
+
+	assert.Equal(t, []string{"@org/platform"}, c.owners(""))
+	assert.Equal(t, []string{"@org/platform"}, c.owners("lib/a"))
+	assert.Equal(t, []string{"@org/apps"}, c.owners("apps/orders"))
+	assert.Equal(t, []string{"@org/apps"}, c.owners("src/apps/orders"))
+	assert.Equal(t, []string{"@org/payments", "@alice"}, c.owners("apps/payments"))
+	assert.Equal(t, []string{"@org/payments", "@alice"}, c.owners("apps/payments/worker"))
+	assert.Equal(t, []string{"@org/apps"}, c.owners("apps/payments-v2"))
+	assert.Equal(t, []string{"@org/api"}, c.owners("services/orders/api"))
+	assert.Equal(t, []string{"@org/platform"}, c.owners("x/services/orders/api"))
+	assert.Equal(t, []string{"@org/docs"}, c.owners("a/docs"))
+}
+
+func TestCodeOwnersWithoutMatch(t *testing.T) {
+	c := parseCodeOwners([]byte("/apps/** @org/apps\n"))
+
+	assert.Equal(t, []string{"@org/apps"}, c.owners("apps/a/b"))
+	assert.Equal(t, []string{}, c.owners("lib"))
+}
+
+func TestFilterByOwner(t *testing.T) {
+	a := newModuleMetadata("app-a", "a", &Spec{Name: "app-a"}, nil)
+	b := newModuleMetadata("app-b", "b", &Spec{Name: "app-b"}, nil)
+	set := moduleMetadataSet{a, b}
+	set.assignOwners(parseCodeOwners([]byte("app-a @Org/Payments\napp-b @org/orders\n")))
+
+	mods, err := toModules(set)
+	check(t, err)
+
+	m, err := (&Manifest{Modules: mods}).ApplyFilters(&FilterOptions{Owner: "@org/payments"})
+	check(t, err)
+	assert.Len(t, m.Modules, 1)
+	assert.Equal(t, "app-a", m.Modules[0].Name())
+	assert.Equal(t, []string{"@Org/Payments"}, m.Modules[0].Owners())
+}
@@ -0,0 +1,259 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/mbtproject/mbt/e"
+)
+
+// Commit status states.
+const (
+	// CommitStateSuccess is the state of a successful build.
+	CommitStateSuccess = "success"
+	// CommitStateFailure is the state of a failed build.
+	CommitStateFailure = "failure"
+	// CommitStateSkipped is the state of a module that was not built.
+	CommitStateSkipped = "skipped"
+)
+
+// Commit status providers.
+const (
+	CommitStatusProviderGitHub    = "github"
+	CommitStatusProviderGitLab    = "gitlab"
+	CommitStatusProviderBitbucket = "bitbucket"
+)
+
+// maxStatusDescription is the maximum length of a status description
+// accepted by GitHub.
+const maxStatusDescription = 140
+
+// CommitStatus is the status of a build published on a commit.
+type CommitStatus struct {
+	// Context distinguishes the status from the statuses of
+	// other modules and services (e.g. mbt/app-a).
+	Context     string
+	State       string
+	Description string
+	// TargetURL links the status to the build (e.g. CI job URL).
+	TargetURL string
+}
+
+// CommitStatusOptions describes how commit statuses are published.
+type CommitStatusOptions struct {
+	// Provider is the hosting service of the repository
+	// (github, gitlab or bitbucket).
+	Provider string
+	// API is the base URL of the provider API. Defaults to the
+	// public API of the provider.
+	API string
+	// Repo identifies the repository in the provider API
+	// (e.g. owner/name for GitHub, project path for GitLab and
+	// workspace/slug for Bitbucket).
+	Repo  string
+	Token string
+	// Context is the prefix of status contexts. Defaults to mbt.
+	Context string
+	// TargetURL links the statuses to the build.
+	TargetURL string
+	// Aggregate publishes a single status for all modules instead
+	// of a status per module.
+	Aggregate bool
+}
+
+// CommitStatuses creates the commit statuses of the build in summary.
+func CommitStatuses(summary *BuildSummary, options *CommitStatusOptions) []*CommitStatus {
+	context := options.Context
+	if context == "" {
+		context = "mbt"
+	}
+
+	if options.Aggregate {
+		state := CommitStateSuccess
+		if len(summary.Failures) > 0 {
+			state = CommitStateFailure
+		}
+		return []*CommitStatus{{
+			Context:     context,
+			State:       state,
+			Description: fmt.Sprintf("%v built, %v failed, %v skipped", len(summary.Completed), len(summary.Failures), len(summary.Skipped)),
+			TargetURL:   options.TargetURL,
+		}}
+	}
+
+	statuses := make([]*CommitStatus, 0)
+	add := func(m *Module, state, description string) {
+		statuses = append(statuses, &CommitStatus{
+			Context:     fmt.Sprintf("%s/%s", context, moduleDisplayName(m)),
+			State:       state,
+			Description: truncate(description, maxStatusDescription),
+			TargetURL:   options.TargetURL,
+		})
+	}
+
+	for _, r := range summary.Completed {
+		if r.Cached {
+			add(r.Module, CommitStateSuccess, fmt.Sprintf("Restored %s from cache", r.Module.Version()))
+		} else {
+			add(r.Module, CommitStateSuccess, fmt.Sprintf("Built %s in %v", r.Module.Version(), r.Duration))
+		}
+	}
+
+	for _, f := range summary.Failures {
+		add(f.Module, CommitStateFailure, fmt.Sprint(f.Err))
+	}
+
+	for _, m := range summary.Skipped {
+		add(m, CommitStateSkipped, "Skipped")
+	}
+
+	return statuses
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n-3] + "..."
+}
+
+// PublishCommitStatuses publishes the commit statuses of the build
+// in summary on the built commit. All statuses are attempted and the
+// first error is returned.
+func PublishCommitStatuses(summary *BuildSummary, publisher StatusPublisher, options *CommitStatusOptions) error {
+	var first error
+	for _, s := range CommitStatuses(summary, options) {
+		if err := publisher.Publish(summary.Manifest.Sha, s); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+type httpStatusPublisher struct {
+	options *CommitStatusOptions
+	client  *http.Client
+	// request returns the url and the payload of the request
+	// publishing a status.
+	request func(sha string, s *CommitStatus) (string, interface{})
+	// authorize sets the authentication headers of the request.
+	authorize func(r *http.Request)
+}
+
+// NewStatusPublisher creates a StatusPublisher publishing commit
+// statuses via the API of the provider specified in options.
+func NewStatusPublisher(options *CommitStatusOptions) (StatusPublisher, error) {
+	p := &httpStatusPublisher{options: options, client: http.DefaultClient}
+	api := strings.TrimSuffix(options.API, "/")
+
+	switch options.Provider {
+	case CommitStatusProviderGitHub:
+		if api == "" {
+			api = "https://api.github.com"
+		}
+		p.request = func(sha string, s *CommitStatus) (string, interface{}) {
+			state := s.State
+			if state == CommitStateSkipped {
+				// GitHub does not have a state for skipped checks.
+				state = CommitStateSuccess
+			}
+			return fmt.Sprintf("%s/repos/%s/statuses/%s", api, options.Repo, sha), map[string]string{
+				"state":       state,
+				"context":     s.Context,
+				"description": s.Description,
+				"target_url":  s.TargetURL,
+			}
+		}
+		p.authorize = func(r *http.Request) {
+			r.Header.Set("Authorization", "token "+options.Token)
+			r.Header.Set("Accept", "application/vnd.github.v3+json")
+		}
+	case CommitStatusProviderGitLab:
+		if api == "" {
+			api = "https://gitlab.com/api/v4"
+		}
+		states := map[string]string{CommitStateSuccess: "success", CommitStateFailure: "failed", CommitStateSkipped: "skipped"}
+		p.request = func(sha string, s *CommitStatus) (string, interface{}) {
+			return fmt.Sprintf("%s/projects/%s/statuses/%s", api, url.PathEscape(options.Repo), sha), map[string]string{
+				"state":       states[s.State],
+				"name":        s.Context,
+				"description": s.Description,
+				"target_url":  s.TargetURL,
+			}
+		}
+		p.authorize = func(r *http.Request) {
+			r.Header.Set("PRIVATE-TOKEN", options.Token)
+		}
+	case CommitStatusProviderBitbucket:
+		if api == "" {
+			api = "https://api.bitbucket.org/2.0"
+		}
+		states := map[string]string{CommitStateSuccess: "SUCCESSFUL", CommitStateFailure: "FAILED", CommitStateSkipped: "STOPPED"}
+		p.request = func(sha string, s *CommitStatus) (string, interface{}) {
+			return fmt.Sprintf("%s/repositories/%s/commit/%s/statuses/build", api, options.Repo, sha), map[string]string{
+				"key":         s.Context,
+				"name":        s.Context,
+				"state":       states[s.State],
+				"description": s.Description,
+				"url":         s.TargetURL,
+			}
+		}
+		p.authorize = func(r *http.Request) {
+			r.Header.Set("Authorization", "Bearer "+options.Token)
+		}
+	default:
+		return nil, e.NewErrorf(ErrClassUser, msgInvalidStatusProvider, options.Provider)
+	}
+
+	return p, nil
+}
+
+func (p *httpStatusPublisher) Publish(sha string, s *CommitStatus) error {
+	u, payload := p.request(sha, s)
+	if err := RequireNetwork("Commit status " + u); err != nil {
+		return err
+	}
+
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return e.Wrap(ErrClassInternal, err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, u, bytes.NewReader(b))
+	if err != nil {
+		return e.Wrapf(ErrClassUser, err, msgFailedCommitStatus, u)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	p.authorize(req)
+
+	res, err := p.client.Do(req)
+	if err != nil {
+		return e.Wrapf(ErrClassUser, err, msgFailedCommitStatus, u)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return e.NewErrorf(ErrClassUser, msgUnexpectedNotificationResponse, res.Status, u)
+	}
+
+	return nil
+}
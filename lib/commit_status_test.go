@@ -0,0 +1,119 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCommitStatuses(t *testing.T) {
+	statuses := CommitStatuses(testNotificationSummary(), &CommitStatusOptions{TargetURL: "https://ci.example.com/1"})
+
+	assert.Equal(t, []*CommitStatus{
+		{Context: "mbt/app-a", State: CommitStateSuccess, Description: "Built app-a1 in 0s", TargetURL: "https://ci.example.com/1"},
+		{Context: "mbt/app-b", State: CommitStateSuccess, Description: "Built app-b1 in 0s", TargetURL: "https://ci.example.com/1"},
+		{Context: "mbt/app-c", State: CommitStateFailure, Description: "exit status 1", TargetURL: "https://ci.example.com/1"},
+		{Context: "mbt/app-d", State: CommitStateSkipped, Description: "Skipped", TargetURL: "https://ci.example.com/1"},
+	}, statuses)
+}
+
+func TestAggregateCommitStatus(t *testing.T) {
+	statuses := CommitStatuses(testNotificationSummary(), &CommitStatusOptions{Aggregate: true, Context: "ci"})
+
+	assert.Equal(t, []*CommitStatus{
+		{Context: "ci", State: CommitStateFailure, Description: "2 built, 1 failed, 1 skipped"},
+	}, statuses)
+}
+
+func statusTestServer(t *testing.T, requests *[]*http.Request, payloads *[]map[string]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, err := ioutil.ReadAll(r.Body)
+		check(t, err)
+		p := make(map[string]string)
+		check(t, json.Unmarshal(b, &p))
+		*requests = append(*requests, r)
+		*payloads = append(*payloads, p)
+		w.WriteHeader(http.StatusCreated)
+	}))
+}
+
+func TestGitHubStatusPublisher(t *testing.T) {
+	var requests []*http.Request
+	var payloads []map[string]string
+	server := statusTestServer(t, &requests, &payloads)
+	defer server.Close()
+
+	options := &CommitStatusOptions{Provider: CommitStatusProviderGitHub, API: server.URL, Repo: "org/repo", Token: "secret"}
+	p, err := NewStatusPublisher(options)
+	check(t, err)
+	check(t, PublishCommitStatuses(testNotificationSummary(), p, options))
+
+	assert.Len(t, requests, 4)
+	assert.Equal(t, "/repos/org/repo/statuses/abc", requests[0].URL.Path)
+	assert.Equal(t, "token secret", requests[0].Header.Get("Authorization"))
+	assert.Equal(t, "mbt/app-a", payloads[0]["context"])
+	assert.Equal(t, "failure", payloads[2]["state"])
+	assert.Equal(t, "success", payloads[3]["state"])
+}
+
+func TestGitLabStatusPublisher(t *testing.T) {
+	var requests []*http.Request
+	var payloads []map[string]string
+	server := statusTestServer(t, &requests, &payloads)
+	defer server.Close()
+
+	options := &CommitStatusOptions{Provider: CommitStatusProviderGitLab, API: server.URL, Repo: "group/repo", Token: "secret", Aggregate: true}
+	p, err := NewStatusPublisher(options)
+	check(t, err)
+	check(t, PublishCommitStatuses(testNotificationSummary(), p, options))
+
+	assert.Len(t, requests, 1)
+	assert.Equal(t, "/projects/group%2Frepo/statuses/abc", requests[0].URL.EscapedPath())
+	assert.Equal(t, "secret", requests[0].Header.Get("PRIVATE-TOKEN"))
+	assert.Equal(t, "mbt", payloads[0]["name"])
+	assert.Equal(t, "failed", payloads[0]["state"])
+}
+
+func TestBitbucketStatusPublisher(t *testing.T) {
+	var requests []*http.Request
+	var payloads []map[string]string
+	server := statusTestServer(t, &requests, &payloads)
+	defer server.Close()
+
+	options := &CommitStatusOptions{Provider: CommitStatusProviderBitbucket, API: server.URL, Repo: "team/repo", Token: "secret"}
+	p, err := NewStatusPublisher(options)
+	check(t, err)
+	check(t, PublishCommitStatuses(testNotificationSummary(), p, options))
+
+	assert.Len(t, requests, 4)
+	assert.Equal(t, "/repositories/team/repo/commit/abc/statuses/build", requests[0].URL.Path)
+	assert.Equal(t, "Bearer secret", requests[0].Header.Get("Authorization"))
+	assert.Equal(t, "SUCCESSFUL", payloads[0]["state"])
+	assert.Equal(t, "FAILED", payloads[2]["state"])
+	assert.Equal(t, "STOPPED", payloads[3]["state"])
+}
+
+func TestInvalidStatusProvider(t *testing.T) {
+	_, err := NewStatusPublisher(&CommitStatusOptions{Provider: "svn"})
+
+	assert.EqualError(t, err, "Invalid commit status provider 'svn' (expected github, gitlab or bitbucket)")
+}
@@ -0,0 +1,81 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import "sort"
+
+// ModuleComparison compares the version of a module in two branches.
+type ModuleComparison struct {
+	Name string `json:"name"`
+	// A and B are the versions of the module in each branch.
+	// Empty if the module does not exist in the branch.
+	A string `json:"a"`
+	B string `json:"b"`
+	// Differs is true if the versions are different or the module
+	// exists only in one branch.
+	Differs bool `json:"differs"`
+}
+
+func (s *stdSystem) CompareBranches(a, b string) ([]*ModuleComparison, error) {
+	ma, err := s.ManifestByBranch(a)
+	if err != nil {
+		return nil, err
+	}
+
+	mb, err := s.ManifestByBranch(b)
+	if err != nil {
+		return nil, err
+	}
+
+	byName := make(map[string]*ModuleComparison)
+	comparison := func(name string) *ModuleComparison {
+		c, ok := byName[name]
+		if !ok {
+			c = &ModuleComparison{Name: name}
+			byName[name] = c
+		}
+		return c
+	}
+
+	for _, m := range ma.Modules {
+		comparison(m.Name()).A = m.Version()
+	}
+	for _, m := range mb.Modules {
+		comparison(m.Name()).B = m.Version()
+	}
+
+	result := make([]*ModuleComparison, 0, len(byName))
+	for _, c := range byName {
+		c.Differs = c.A != c.B
+		result = append(result, c)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+
+	return result, nil
+}
+
+// CompareBranches compares the version of each module in branches
+// a and b of the repository in dir.
+func CompareBranches(dir, a, b string) ([]*ModuleComparison, error) {
+	s, err := NewSystem(dir, LogLevelNormal)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.CompareBranches(a, b)
+}
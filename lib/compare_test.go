@@ -0,0 +1,51 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompareBranches(t *testing.T) {
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+
+	check(t, repo.InitModule("app-a"))
+	check(t, repo.InitModule("app-b"))
+	check(t, repo.Commit("first"))
+
+	check(t, repo.SwitchToBranch("release"))
+	check(t, repo.SwitchToBranch("master"))
+	check(t, repo.WriteContent("app-a/foo", "hello"))
+	check(t, repo.InitModule("app-c"))
+	check(t, repo.Commit("second"))
+
+	release, err := NewWorld(t, ".tmp/repo").System.ManifestByBranch("release")
+	check(t, err)
+	master, err := NewWorld(t, ".tmp/repo").System.ManifestByBranch("master")
+	check(t, err)
+
+	c, err := CompareBranches(".tmp/repo", "master", "release")
+	check(t, err)
+
+	assert.Equal(t, []*ModuleComparison{
+		{Name: "app-a", A: master.Modules.indexByName()["app-a"].Version(), B: release.Modules.indexByName()["app-a"].Version(), Differs: true},
+		{Name: "app-b", A: master.Modules.indexByName()["app-b"].Version(), B: release.Modules.indexByName()["app-b"].Version(), Differs: false},
+		{Name: "app-c", A: master.Modules.indexByName()["app-c"].Version(), Differs: true},
+	}, c)
+}
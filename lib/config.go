@@ -0,0 +1,73 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	yaml "github.com/go-yaml/yaml"
+	"github.com/mbtproject/mbt/e"
+)
+
+// ConfigFile is the path of the configuration file relative to the
+// root of the repository.
+const ConfigFile = ".mbt/config.yml"
+
+// Config holds the default values of command line options keyed by
+// option name (e.g. parallelism). Lists are joined with commas.
+type Config map[string]string
+
+// LoadConfig reads the configuration file of the repository in dir.
+// An empty Config is returned when the file does not exist.
+func LoadConfig(dir string) (Config, error) {
+	path := filepath.Join(dir, ConfigFile)
+	content, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return Config{}, nil
+	}
+	if err != nil {
+		return nil, e.Wrapf(ErrClassUser, err, msgFailedReadFile, path)
+	}
+
+	raw := make(map[string]interface{})
+	if err := yaml.Unmarshal(content, &raw); err != nil {
+		return nil, e.Wrapf(ErrClassUser, err, msgInvalidConfigFile, path)
+	}
+
+	config := make(Config, len(raw))
+	for k, v := range raw {
+		switch value := v.(type) {
+		case nil:
+			config[k] = ""
+		case []interface{}:
+			items := make([]string, 0, len(value))
+			for _, i := range value {
+				items = append(items, fmt.Sprint(i))
+			}
+			config[k] = strings.Join(items, ",")
+		case map[interface{}]interface{}:
+			return nil, e.NewErrorf(ErrClassUser, msgInvalidConfigValue, k, path)
+		default:
+			config[k] = fmt.Sprint(value)
+		}
+	}
+
+	return config, nil
+}
@@ -0,0 +1,61 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadConfig(t *testing.T) {
+	clean()
+	writeAuditFile(t, ".tmp/repo/"+ConfigFile, "parallelism: 4\nkeep-going: true\ncache-dir: /tmp/cache\nexclude-dir: [vendor, third_party]\nnotify-slack:\n")
+
+	config, err := LoadConfig(".tmp/repo")
+	check(t, err)
+
+	assert.Equal(t, Config{
+		"parallelism":  "4",
+		"keep-going":   "true",
+		"cache-dir":    "/tmp/cache",
+		"exclude-dir":  "vendor,third_party",
+		"notify-slack": "",
+	}, config)
+}
+
+func TestLoadMissingConfig(t *testing.T) {
+	clean()
+
+	config, err := LoadConfig(".tmp/repo")
+	check(t, err)
+	assert.Empty(t, config)
+}
+
+func TestLoadInvalidConfig(t *testing.T) {
+	clean()
+	path := filepath.Join(".tmp/repo", ConfigFile)
+
+	writeAuditFile(t, path, "parallelism: [")
+	_, err := LoadConfig(".tmp/repo")
+	assert.EqualError(t, err, fmt.Sprintf(msgInvalidConfigFile, path))
+
+	writeAuditFile(t, path, "executor:\n  name: docker\n")
+	_, err = LoadConfig(".tmp/repo")
+	assert.EqualError(t, err, fmt.Sprintf(msgInvalidConfigValue, "executor", path))
+}
@@ -0,0 +1,187 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"sort"
+)
+
+// CouplingOptions describes how coupling is analysed.
+type CouplingOptions struct {
+	// Top is the number of module pairs and files reported.
+	// Defaults to 10.
+	Top int
+}
+
+// CouplingReport describes the modules changing together and the
+// files triggering the widest rebuilds in a range of commits.
+type CouplingReport struct {
+	From    string `json:"from"`
+	To      string `json:"to"`
+	Commits int    `json:"commits"`
+	// Pairs are the modules most frequently changed in the same commit.
+	Pairs []*ModulePair `json:"pairs"`
+	// Files are the changed files impacting the most modules.
+	Files []*FileImpact `json:"files"`
+}
+
+// ModulePair describes how often two modules change together.
+type ModulePair struct {
+	A string `json:"a"`
+	B string `json:"b"`
+	// Together is the number of commits changing both modules.
+	Together int `json:"together"`
+	// Coupling is the ratio of the commits changing both modules to
+	// the commits changing either of them.
+	Coupling float64 `json:"coupling"`
+}
+
+// FileImpact describes the modules rebuilt when a file changes.
+type FileImpact struct {
+	Path string `json:"path"`
+	// Changes is the number of commits changing the file.
+	Changes int `json:"changes"`
+	// Modules is the number of modules impacted by a change to the
+	// file including the dependents of the module containing it.
+	Modules int `json:"modules"`
+}
+
+func (s *stdSystem) Coupling(from, to string, options *CouplingOptions) (*CouplingReport, error) {
+	if options == nil {
+		options = &CouplingOptions{}
+	}
+	top := options.Top
+	if top <= 0 {
+		top = 10
+	}
+
+	toCommit, commits, err := s.history(from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &CouplingReport{From: from, To: toCommit.ID(), Commits: len(commits)}
+	changes := make(map[string]int)
+	together := make(map[[2]string]int)
+	files := make(map[string]*FileImpact)
+
+	for _, c := range commits {
+		info, err := s.Repo.CommitInfo(c)
+		if err != nil {
+			return nil, err
+		}
+
+		// Merge commits repeat the changes of the merged commits.
+		if len(info.Parents) != 1 {
+			continue
+		}
+
+		deltas, err := s.Repo.Changes(c)
+		if err != nil {
+			return nil, err
+		}
+
+		mods, err := s.Discover.ModulesInCommit(c)
+		if err != nil {
+			return nil, err
+		}
+
+		changed, err := s.Reducer.Reduce(mods, deltas)
+		if err != nil {
+			return nil, err
+		}
+
+		names := make([]string, 0, len(changed))
+		for _, m := range changed {
+			names = append(names, m.Name())
+			changes[m.Name()]++
+		}
+		sort.Strings(names)
+		for i := 0; i < len(names); i++ {
+			for j := i + 1; j < len(names); j++ {
+				together[[2]string{names[i], names[j]}]++
+			}
+		}
+
+		for _, d := range deltas {
+			impacted, err := s.Reducer.Reduce(mods, []*DiffDelta{d})
+			if err != nil {
+				return nil, err
+			}
+			impacted, err = impacted.expandRequiredByDependencies()
+			if err != nil {
+				return nil, err
+			}
+
+			path := d.NewFile
+			if path == "" {
+				path = d.OldFile
+			}
+			f, ok := files[path]
+			if !ok {
+				f = &FileImpact{Path: path}
+				files[path] = f
+			}
+			f.Changes++
+			if len(impacted) > f.Modules {
+				f.Modules = len(impacted)
+			}
+		}
+	}
+
+	report.Pairs = make([]*ModulePair, 0, len(together))
+	for k, n := range together {
+		report.Pairs = append(report.Pairs, &ModulePair{
+			A:        k[0],
+			B:        k[1],
+			Together: n,
+			Coupling: float64(n) / float64(changes[k[0]]+changes[k[1]]-n),
+		})
+	}
+	sort.Slice(report.Pairs, func(i, j int) bool {
+		a, b := report.Pairs[i], report.Pairs[j]
+		if a.Together != b.Together {
+			return a.Together > b.Together
+		}
+		if a.Coupling != b.Coupling {
+			return a.Coupling > b.Coupling
+		}
+		return a.A+"/"+a.B < b.A+"/"+b.B
+	})
+	if len(report.Pairs) > top {
+		report.Pairs = report.Pairs[:top]
+	}
+
+	report.Files = make([]*FileImpact, 0, len(files))
+	for _, f := range files {
+		report.Files = append(report.Files, f)
+	}
+	sort.Slice(report.Files, func(i, j int) bool {
+		a, b := report.Files[i], report.Files[j]
+		if a.Modules != b.Modules {
+			return a.Modules > b.Modules
+		}
+		if a.Changes != b.Changes {
+			return a.Changes > b.Changes
+		}
+		return a.Path < b.Path
+	})
+	if len(report.Files) > top {
+		report.Files = report.Files[:top]
+	}
+
+	return report, nil
+}
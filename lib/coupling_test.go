@@ -0,0 +1,54 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCoupling(t *testing.T) {
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+
+	check(t, repo.InitModule("lib-c"))
+	check(t, repo.InitModule("app-b"))
+	check(t, repo.InitModuleWithOptions("app-a", &Spec{Name: "app-a", Dependencies: []string{"lib-c"}}))
+	check(t, repo.Commit("first"))
+	first := repo.LastCommit.String()
+
+	check(t, repo.WriteContent("app-a/foo", "a"))
+	check(t, repo.WriteContent("app-b/foo", "b"))
+	check(t, repo.Commit("second"))
+	check(t, repo.WriteContent("app-a/foo", "aa"))
+	check(t, repo.WriteContent("app-b/foo", "bb"))
+	check(t, repo.Commit("third"))
+	check(t, repo.WriteContent("app-b/foo", "bbb"))
+	check(t, repo.WriteContent("lib-c/foo", "c"))
+	check(t, repo.Commit("fourth"))
+
+	report, err := NewWorld(t, ".tmp/repo").System.Coupling(first, "", nil)
+	check(t, err)
+
+	assert.Equal(t, 3, report.Commits)
+	assert.Equal(t, []*ModulePair{
+		{A: "app-a", B: "app-b", Together: 2, Coupling: float64(2) / 3},
+		{A: "app-b", B: "lib-c", Together: 1, Coupling: float64(1) / 3},
+	}, report.Pairs)
+	assert.Equal(t, &FileImpact{Path: "lib-c/foo", Changes: 1, Modules: 2}, report.Files[0])
+	assert.Equal(t, &FileImpact{Path: "app-b/foo", Changes: 3, Modules: 1}, report.Files[1])
+}
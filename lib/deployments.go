@@ -0,0 +1,235 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/mbtproject/mbt/e"
+)
+
+// Deployment records the version of a module deployed to an environment.
+type Deployment struct {
+	Module      string `json:"module"`
+	Version     string `json:"version"`
+	Environment string `json:"environment"`
+	// Commit is the sha of the manifest the module was deployed from.
+	Commit string    `json:"commit"`
+	Time   time.Time `json:"time"`
+}
+
+// DriftStatus describes how a deployed module differs from a manifest.
+type DriftStatus string
+
+const (
+	// DriftInSync indicates that the deployed version is the version in the manifest.
+	DriftInSync DriftStatus = "in-sync"
+	// DriftOutdated indicates that a different version is deployed.
+	DriftOutdated DriftStatus = "outdated"
+	// DriftNotDeployed indicates that the module is not deployed to the environment.
+	DriftNotDeployed DriftStatus = "not-deployed"
+	// DriftUnknown indicates that the deployed module is not in the manifest.
+	DriftUnknown DriftStatus = "unknown"
+)
+
+// Drift compares the deployed version of a module with the version
+// in a manifest.
+type Drift struct {
+	Module   string
+	Deployed string
+	Expected string
+	Status   DriftStatus
+}
+
+// RecordDeployments records that the modules in manifest are deployed
+// to env.
+func RecordDeployments(store DeploymentStore, env string, manifest *Manifest) ([]*Deployment, error) {
+	now := time.Now().UTC()
+	deployments := make([]*Deployment, 0, len(manifest.Modules))
+	for _, m := range manifest.Modules {
+		deployments = append(deployments, &Deployment{
+			Module:      m.Name(),
+			Version:     m.Version(),
+			Environment: env,
+			Commit:      manifest.Sha,
+			Time:        now,
+		})
+	}
+
+	if err := store.Record(deployments); err != nil {
+		return nil, err
+	}
+
+	return deployments, nil
+}
+
+// DetectDrift compares the modules deployed to env with the modules
+// in manifest. Results are sorted by module name.
+func DetectDrift(store DeploymentStore, env string, manifest *Manifest) ([]*Drift, error) {
+	deployments, err := store.Deployments(env)
+	if err != nil {
+		return nil, err
+	}
+
+	deployed := make(map[string]*Deployment, len(deployments))
+	for _, d := range deployments {
+		deployed[d.Module] = d
+	}
+
+	drifts := make([]*Drift, 0)
+	for _, m := range manifest.Modules {
+		d := &Drift{Module: m.Name(), Expected: m.Version(), Status: DriftNotDeployed}
+		if r, ok := deployed[m.Name()]; ok {
+			d.Deployed = r.Version
+			d.Status = DriftOutdated
+			if r.Version == m.Version() {
+				d.Status = DriftInSync
+			}
+			delete(deployed, m.Name())
+		}
+		drifts = append(drifts, d)
+	}
+
+	for _, r := range deployed {
+		drifts = append(drifts, &Drift{Module: r.Module, Deployed: r.Version, Status: DriftUnknown})
+	}
+
+	sort.Slice(drifts, func(i, j int) bool {
+		return drifts[i].Module < drifts[j].Module
+	})
+
+	return drifts, nil
+}
+
+// PromoteDeployments records that the versions of modules deployed to
+// env from are deployed to env to. All modules deployed to from are
+// promoted if names is empty.
+func PromoteDeployments(store DeploymentStore, from, to string, names []string) ([]*Deployment, error) {
+	deployments, err := store.Deployments(from)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(names) > 0 {
+		deployed := make(map[string]*Deployment, len(deployments))
+		for _, d := range deployments {
+			deployed[d.Module] = d
+		}
+
+		deployments = make([]*Deployment, 0, len(names))
+		for _, n := range names {
+			d, ok := deployed[n]
+			if !ok {
+				return nil, e.NewErrorf(ErrClassUser, msgNotDeployed, n, from)
+			}
+			deployments = append(deployments, d)
+		}
+	}
+
+	now := time.Now().UTC()
+	promoted := make([]*Deployment, 0, len(deployments))
+	for _, d := range deployments {
+		promoted = append(promoted, &Deployment{
+			Module:      d.Module,
+			Version:     d.Version,
+			Environment: to,
+			Commit:      d.Commit,
+			Time:        now,
+		})
+	}
+
+	if err := store.Record(promoted); err != nil {
+		return nil, err
+	}
+
+	return promoted, nil
+}
+
+type fileDeploymentStore struct {
+	path  string
+	mutex sync.Mutex
+}
+
+type deploymentsFile struct {
+	// Environments maps the name of each environment to the latest
+	// deployment of each module.
+	Environments map[string]map[string]*Deployment `json:"environments"`
+}
+
+// NewFileDeploymentStore creates a DeploymentStore persisting
+// deployments in a json file. Concurrent updates are serialised
+// with a lock file created next to it (<path>.lock).
+func NewFileDeploymentStore(path string) DeploymentStore {
+	return &fileDeploymentStore{path: path}
+}
+
+func (s *fileDeploymentStore) load() (*deploymentsFile, error) {
+	f := &deploymentsFile{}
+	if err := readJSONFile(s.path, f); err != nil {
+		return nil, err
+	}
+
+	f.init()
+	return f, nil
+}
+
+func (f *deploymentsFile) init() {
+	if f.Environments == nil {
+		f.Environments = make(map[string]map[string]*Deployment)
+	}
+}
+
+func (s *fileDeploymentStore) Deployments(env string) ([]*Deployment, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	f, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+
+	deployments := make([]*Deployment, 0, len(f.Environments[env]))
+	for _, d := range f.Environments[env] {
+		deployments = append(deployments, d)
+	}
+
+	sort.Slice(deployments, func(i, j int) bool {
+		return deployments[i].Module < deployments[j].Module
+	})
+
+	return deployments, nil
+}
+
+func (s *fileDeploymentStore) Record(deployments []*Deployment) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	f := &deploymentsFile{}
+	return updateJSONFile(s.path, f, func() error {
+		f.init()
+		for _, d := range deployments {
+			env, ok := f.Environments[d.Environment]
+			if !ok {
+				env = make(map[string]*Deployment)
+				f.Environments[d.Environment] = env
+			}
+			env[d.Module] = d
+		}
+		return nil
+	})
+}
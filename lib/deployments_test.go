@@ -0,0 +1,99 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileDeploymentStore(t *testing.T) {
+	clean()
+	store := NewFileDeploymentStore(".tmp/deployments.json")
+
+	d, err := store.Deployments("prod")
+	check(t, err)
+	assert.Empty(t, d)
+
+	check(t, store.Record([]*Deployment{
+		{Module: "b", Version: "b1", Environment: "prod"},
+		{Module: "a", Version: "a1", Environment: "prod"},
+		{Module: "a", Version: "a2", Environment: "stage"},
+	}))
+	check(t, store.Record([]*Deployment{{Module: "b", Version: "b2", Environment: "prod"}}))
+
+	d, err = NewFileDeploymentStore(".tmp/deployments.json").Deployments("prod")
+	check(t, err)
+	assert.Len(t, d, 2)
+	assert.Equal(t, "a1", d[0].Version)
+	assert.Equal(t, "b2", d[1].Version)
+}
+
+func TestDetectDrift(t *testing.T) {
+	clean()
+	store := NewFileDeploymentStore(".tmp/deployments.json")
+
+	_, err := RecordDeployments(store, "prod", &Manifest{Sha: "abc", Modules: Modules{
+		newTestModule("a", "a", "a1"),
+		newTestModule("b", "b", "b1"),
+		newTestModule("d", "d", "d1"),
+	}})
+	check(t, err)
+
+	drifts, err := DetectDrift(store, "prod", &Manifest{Sha: "def", Modules: Modules{
+		newTestModule("a", "a", "a1"),
+		newTestModule("b", "b", "b2"),
+		newTestModule("c", "c", "c1"),
+	}})
+	check(t, err)
+
+	assert.Equal(t, []*Drift{
+		{Module: "a", Deployed: "a1", Expected: "a1", Status: DriftInSync},
+		{Module: "b", Deployed: "b1", Expected: "b2", Status: DriftOutdated},
+		{Module: "c", Expected: "c1", Status: DriftNotDeployed},
+		{Module: "d", Deployed: "d1", Status: DriftUnknown},
+	}, drifts)
+}
+
+func TestPromoteDeployments(t *testing.T) {
+	clean()
+	store := NewFileDeploymentStore(".tmp/deployments.json")
+
+	check(t, store.Record([]*Deployment{
+		{Module: "a", Version: "a2", Environment: "stage", Commit: "def"},
+		{Module: "b", Version: "b2", Environment: "stage", Commit: "def"},
+		{Module: "a", Version: "a1", Environment: "prod", Commit: "abc"},
+		{Module: "b", Version: "b1", Environment: "prod", Commit: "abc"},
+	}))
+
+	promoted, err := PromoteDeployments(store, "stage", "prod", []string{"a"})
+	check(t, err)
+	assert.Len(t, promoted, 1)
+
+	d, err := store.Deployments("prod")
+	check(t, err)
+	assert.Equal(t, "a2", d[0].Version)
+	assert.Equal(t, "def", d[0].Commit)
+	assert.Equal(t, "b1", d[1].Version)
+
+	_, err = PromoteDeployments(store, "stage", "prod", []string{"c"})
+	assert.EqualError(t, err, "Module 'c' is not deployed to 'stage'")
+
+	promoted, err = PromoteDeployments(store, "stage", "prod", nil)
+	check(t, err)
+	assert.Len(t, promoted, 2)
+}
@@ -0,0 +1,121 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// DiagnosticWarning is the level of diagnostics describing problems
+// which do not prevent mbt from creating the manifest but are likely
+// to produce unexpected results.
+const DiagnosticWarning = "warning"
+
+// Diagnostic is a non-fatal problem found while creating a manifest.
+type Diagnostic struct {
+	Level   string `json:"Level" yaml:"Level"`
+	Module  string `json:"Module" yaml:"Module"`
+	Message string `json:"Message" yaml:"Message"`
+}
+
+// Diagnostics returns the problems found in the module while creating
+// the manifest it is in.
+func (a *Module) Diagnostics() []*Diagnostic {
+	diagnostics := make([]*Diagnostic, 0, len(a.metadata.diagnostics))
+	for _, d := range a.metadata.diagnostics {
+		c := *d
+		diagnostics = append(diagnostics, &c)
+	}
+	return diagnostics
+}
+
+func (m *moduleMetadata) warn(format string, args ...interface{}) {
+	m.diagnostics = append(m.diagnostics, &Diagnostic{
+		Level:   DiagnosticWarning,
+		Module:  m.spec.Name,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// diagnose finds the problems in the discovered modules.
+func (set moduleMetadataSet) diagnose() {
+	dirs := make(map[string]*moduleMetadata, len(set))
+	for _, m := range set {
+		dirs[m.dir] = m
+	}
+
+	for _, m := range set {
+		if m.spec.Name == "" {
+			m.warn(msgModuleWithoutName, m.dir)
+		}
+
+		deps := make(map[string]bool, len(m.spec.Dependencies))
+		for _, d := range m.spec.Dependencies {
+			if deps[d] {
+				m.warn(msgDuplicateDependency, d)
+			}
+			deps[d] = true
+		}
+
+		if m.dir == "" {
+			continue
+		}
+
+		// Find the closest module containing this module. Changes to
+		// this module match the directory of that module as well.
+		for d := m.dir; ; {
+			i := strings.LastIndex(d, "/")
+			if i < 0 {
+				d = ""
+			} else {
+				d = d[:i]
+			}
+
+			if p, ok := dirs[d]; ok {
+				m.warn(msgNestedModule, m.dir, p.spec.Name, p.spec.Name)
+				break
+			}
+
+			if d == "" {
+				break
+			}
+		}
+	}
+}
+
+// collectDiagnostics returns the diagnostics of the modules sorted by
+// module name.
+func collectDiagnostics(mods Modules) []*Diagnostic {
+	seen := make(map[*Diagnostic]bool)
+	diagnostics := make([]*Diagnostic, 0)
+	for _, mod := range mods {
+		// Variants share the diagnostics of their module.
+		for _, d := range mod.metadata.diagnostics {
+			if !seen[d] {
+				seen[d] = true
+				c := *d
+				diagnostics = append(diagnostics, &c)
+			}
+		}
+	}
+
+	sort.SliceStable(diagnostics, func(i, j int) bool {
+		return diagnostics[i].Module < diagnostics[j].Module
+	})
+	return diagnostics
+}
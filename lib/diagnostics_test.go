@@ -0,0 +1,69 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiagnosticsOfNestedModules(t *testing.T) {
+	a := newModuleMetadata("apps/a", "a", &Spec{Name: "app-a"}, nil)
+	b := newModuleMetadata("apps/a/worker", "b", &Spec{Name: "app-a-worker"}, nil)
+	c := newModuleMetadata("apps/b", "c", &Spec{Name: "app-b"}, nil)
+
+	mods, err := toModules(moduleMetadataSet{a, b, c})
+	check(t, err)
+	m := mods.indexByName()
+
+	assert.Equal(t, []*Diagnostic{
+		{Level: DiagnosticWarning, Module: "app-a-worker", Message: "Module in 'apps/a/worker' is nested in module 'app-a', changes to it also change 'app-a'"},
+	}, m["app-a-worker"].Diagnostics())
+	assert.Empty(t, m["app-a"].Diagnostics())
+	assert.Empty(t, m["app-b"].Diagnostics())
+}
+
+func TestDiagnosticsOfSpecs(t *testing.T) {
+	a := newModuleMetadata("app-a", "a", &Spec{Name: "app-a", Dependencies: []string{"app-b", "app-b"}}, nil)
+	b := newModuleMetadata("app-b", "b", &Spec{Name: "app-b"}, nil)
+	c := newModuleMetadata("app-c", "c", &Spec{}, nil)
+
+	mods, err := toModules(moduleMetadataSet{a, b, c})
+	check(t, err)
+
+	assert.Equal(t, []*Diagnostic{
+		{Level: DiagnosticWarning, Module: "app-a", Message: "Dependency 'app-b' is listed more than once"},
+	}, a.diagnostics)
+	assert.Equal(t, []*Diagnostic{
+		{Level: DiagnosticWarning, Module: "", Message: "Spec in 'app-c' does not have a name"},
+	}, c.diagnostics)
+	assert.Empty(t, b.diagnostics)
+	assert.Len(t, collectDiagnostics(mods), 2)
+}
+
+func TestDiagnosticsInDocument(t *testing.T) {
+	a := newModuleMetadata("app-a", "a", &Spec{Name: "app-a"}, nil)
+	a.warn(msgDuplicateDependency, "app-b")
+
+	mods, err := toModules(moduleMetadataSet{a})
+	check(t, err)
+	m := &Manifest{Modules: mods, Diagnostics: collectDiagnostics(mods)}
+	d := m.Document()
+
+	assert.Equal(t, []string{"Dependency 'app-b' is listed more than once"}, d.Modules["app-a"].Diagnostics)
+	assert.Equal(t, m.Diagnostics, d.Diagnostics)
+}
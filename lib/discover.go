@@ -20,6 +20,7 @@ import (
 	"encoding/hex"
 	"io"
 	"io/ioutil"
+	"os"
 	"path/filepath"
 	"strings"
 
@@ -35,29 +36,107 @@ type moduleMetadata struct {
 	hash                string
 	spec                *Spec
 	dependentFileHashes map[string]string
+	owners              []string
+	lastCommit          *lazyCommitInfo
+	lfsObjects          map[string]string
+	dockerfile          bool
+	terraform           bool
+	classRules          changeClassRules
+	diagnostics         []*Diagnostic
 }
 
 // moduleMetadataSet is an array of ModuleMetadata extracted from the repository.
 type moduleMetadataSet []*moduleMetadata
 
 type stdDiscover struct {
-	Repo Repo
-	Log  Log
+	Repo      Repo
+	Log       Log
+	specFile  string
+	terraform bool
+	profiler  *Profiler
+	specs     *specCache
 }
 
-const configFileName = ".mbt.yml"
+// DiscoverOptions describes the optional settings of the standard
+// discover implementation.
+type DiscoverOptions struct {
+	// SpecFile is the name of module spec files. Defaults to
+	// DefaultSpecFile.
+	SpecFile string
+	// Terraform enables the discovery of Terraform root modules
+	// (i.e. directories with a backend configuration or a
+	// .terraform.lock.hcl file) as modules.
+	Terraform bool
+	// Profiler records the time spent parsing specs when specified.
+	Profiler *Profiler
+}
+
+// DefaultSpecFile is the name of module spec files.
+const DefaultSpecFile = ".mbt.yml"
+
+// dockerfileName is the name of the file used to synthesize a default
+// build command for modules without one.
+const dockerfileName = "Dockerfile"
 
 // NewDiscover creates an instance of standard discover implementation.
 func NewDiscover(repo Repo, l Log) Discover {
-	return &stdDiscover{Repo: repo, Log: l}
+	return NewDiscoverWithSpecFile(repo, l, DefaultSpecFile)
+}
+
+// NewDiscoverWithSpecFile creates an instance of standard discover
+// implementation finding modules by the spec files with specified name.
+func NewDiscoverWithSpecFile(repo Repo, l Log, specFile string) Discover {
+	return NewDiscoverWithOptions(repo, l, &DiscoverOptions{SpecFile: specFile})
+}
+
+// NewDiscoverWithOptions creates an instance of standard discover
+// implementation configured with the specified options.
+func NewDiscoverWithOptions(repo Repo, l Log, options *DiscoverOptions) Discover {
+	specFile := options.SpecFile
+	if specFile == "" {
+		specFile = DefaultSpecFile
+	}
+	return &stdDiscover{Repo: repo, Log: l, specFile: specFile, terraform: options.Terraform, profiler: options.Profiler, specs: newSpecCache()}
 }
 
 func (d *stdDiscover) ModulesInCommit(commit Commit) (Modules, error) {
 	repo := d.Repo
 	metadataSet := moduleMetadataSet{}
+	owners := make(map[string][]byte)
+	dockerfiles := make(map[string]bool)
+	tf := terraformDirs{}
+	var classes []byte
 
 	err := repo.WalkBlobs(commit, func(b Blob) error {
-		if b.Name() == configFileName {
+		if b.Name() == dockerfileName {
+			dockerfiles[strings.TrimRight(b.Path(), "/")] = true
+		}
+
+		if d.terraform && isTerraformFile(b.Name()) {
+			contents, err := repo.BlobContents(b)
+			if err != nil {
+				return err
+			}
+			tf.add(strings.TrimRight(b.Path(), "/"), b.Name(), contents)
+		}
+
+		if b.Name() == "CODEOWNERS" {
+			contents, err := repo.BlobContents(b)
+			if err != nil {
+				return err
+			}
+			owners[b.String()] = contents
+		}
+
+		if b.String() == ChangeClassesFile {
+			contents, err := repo.BlobContents(b)
+			if err != nil {
+				return err
+			}
+			classes = contents
+		}
+
+		if b.Name() == d.specFile {
 			var (
 				hash string
 				err  error
@@ -74,16 +153,11 @@ func (d *stdDiscover) ModulesInCommit(commit Commit) (Modules, error) {
 				hash = commit.ID()
 			}
 
-			contents, err := repo.BlobContents(b)
+			spec, err := d.specInBlob(b)
 			if err != nil {
 				return err
 			}
 
-			spec, err := newSpec(contents)
-			if err != nil {
-				return e.Wrapf(ErrClassUser, err, "error while parsing the spec at %v", b)
-			}
-
 			// Discover the hashes for file dependencies of this module
 			dependentFileHashes := make(map[string]string)
 			for _, f := range spec.FileDependencies {
@@ -104,9 +178,67 @@ func (d *stdDiscover) ModulesInCommit(commit Commit) (Modules, error) {
 		return nil, err
 	}
 
+	if d.terraform {
+		metadataSet, err = tf.metadata(metadataSet, func(p string) (string, error) {
+			if p == "" {
+				return commit.ID(), nil
+			}
+			return repo.EntryID(commit, p)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, m := range metadataSet {
+		m.dockerfile = dockerfiles[m.dir]
+	}
+
+	if err = lfsObjects(repo, commit, metadataSet); err != nil {
+		return nil, err
+	}
+
+	for _, l := range codeOwnersLocations {
+		if c, ok := owners[l]; ok {
+			metadataSet.assignOwners(parseCodeOwners(c))
+			break
+		}
+	}
+
+	if classes != nil {
+		rules, err := parseChangeClasses(classes)
+		if err != nil {
+			return nil, err
+		}
+		metadataSet.assignChangeClasses(rules)
+	}
+
 	return toModules(metadataSet)
 }
 
+// specInBlob parses the spec in blob b. Specs are cached by the id of
+// the blob so that a spec is parsed once for all commits it is in.
+func (d *stdDiscover) specInBlob(b Blob) (*Spec, error) {
+	if spec, ok := d.specs.get(b.ID()); ok {
+		return spec, nil
+	}
+
+	contents, err := d.Repo.BlobContents(b)
+	if err != nil {
+		return nil, err
+	}
+
+	stop := d.profiler.Start(PhaseSpecParse)
+	spec, err := newSpec(contents)
+	stop()
+	if err != nil {
+		return nil, e.Wrapf(ErrClassUser, err, "error while parsing the spec at %v", b)
+	}
+
+	d.specs.put(b.ID(), spec)
+	return spec, nil
+}
+
 func (d *stdDiscover) ModulesInWorkspace() (Modules, error) {
 	metadataSet := moduleMetadataSet{}
 	absRepoPath, err := filepath.Abs(d.Repo.Path())
@@ -114,14 +246,14 @@ func (d *stdDiscover) ModulesInWorkspace() (Modules, error) {
 		return nil, e.Wrap(ErrClassInternal, err)
 	}
 
-	configFiles, err := d.Repo.FindAllFilesInWorkspace([]string{configFileName, "/**/" + configFileName})
+	configFiles, err := d.Repo.FindAllFilesInWorkspace([]string{d.specFile, "/**/" + d.specFile})
 
 	if err != nil {
 		return nil, err
 	}
 
 	for _, entry := range configFiles {
-		if filepath.Base(entry) != configFileName {
+		if filepath.Base(entry) != d.specFile {
 			// Fast path directories that matched path spec
 			// e.g. .mbt.yml/abc/foo
 			continue
@@ -134,7 +266,9 @@ func (d *stdDiscover) ModulesInWorkspace() (Modules, error) {
 			return nil, e.Wrapf(ErrClassInternal, err, "error whilst reading file contents at path %s", path)
 		}
 
+		stop := d.profiler.Start(PhaseSpecParse)
 		spec, err := newSpec(contents)
+		stop()
 		if err != nil {
 			return nil, e.Wrapf(ErrClassUser, err, "error whilst parsing spec at %s", path)
 		}
@@ -148,13 +282,40 @@ func (d *stdDiscover) ModulesInWorkspace() (Modules, error) {
 		}
 
 		hash := "local"
-		metadataSet = append(metadataSet, newModuleMetadata(dir, hash, spec, nil))
+		m := newModuleMetadata(dir, hash, spec, nil)
+		if fi, err := os.Stat(filepath.Join(filepath.Dir(path), dockerfileName)); err == nil && !fi.IsDir() {
+			m.dockerfile = true
+		}
+		metadataSet = append(metadataSet, m)
 	}
 
 	if err != nil {
 		return nil, e.Wrap(ErrClassInternal, err)
 	}
 
+	if d.terraform {
+		metadataSet, err = d.terraformMetadataInWorkspace(absRepoPath, metadataSet)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, l := range codeOwnersLocations {
+		c, err := ioutil.ReadFile(filepath.Join(absRepoPath, l))
+		if err == nil {
+			metadataSet.assignOwners(parseCodeOwners(c))
+			break
+		}
+	}
+
+	if c, err := ioutil.ReadFile(filepath.Join(absRepoPath, ChangeClassesFile)); err == nil {
+		rules, err := parseChangeClasses(c)
+		if err != nil {
+			return nil, err
+		}
+		metadataSet.assignChangeClasses(rules)
+	}
+
 	return toModules(metadataSet)
 }
 
@@ -191,14 +352,94 @@ func newSpec(content []byte) (*Spec, error) {
 		return nil, err
 	}
 
+	if err := validateSpecVersion(a); err != nil {
+		return nil, err
+	}
+
 	a.Properties, err = transformProps(a.Properties)
 	if err != nil {
 		return nil, err
 	}
 
+	if a.Resources != nil && a.Resources.Weight < 0 {
+		return nil, e.NewErrorf(ErrClassUser, msgInvalidResourceWeight, a.Resources.Weight, a.Name)
+	}
+
+	for _, o := range a.Outputs {
+		c := filepath.Clean(filepath.FromSlash(o))
+		if filepath.IsAbs(c) || c == ".." || strings.HasPrefix(c, ".."+string(filepath.Separator)) {
+			return nil, e.NewErrorf(ErrClassUser, msgInvalidOutput, o, a.Name)
+		}
+	}
+
+	for k, v := range a.Matrix {
+		if len(v) == 0 {
+			return nil, e.NewErrorf(ErrClassUser, msgEmptyMatrixAxis, k, a.Name)
+		}
+	}
+
+	for _, cmds := range []map[string]*Cmd{a.Build, a.Test} {
+		for _, c := range cmds {
+			if err := validateCmd(c.Cmd, c.Timeout, c.When); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := validateTasks(a); err != nil {
+		return nil, err
+	}
+
+	if err := validateExternalDependencies(a); err != nil {
+		return nil, err
+	}
+
+	if err := validateLifecycle(a); err != nil {
+		return nil, err
+	}
+
+	if err := validateCost(a); err != nil {
+		return nil, err
+	}
+
+	if err := validateSemver(a); err != nil {
+		return nil, err
+	}
+
+	if a.Hooks != nil {
+		for _, c := range []*Cmd{a.Hooks.Pre, a.Hooks.Post, a.Hooks.OnFailure} {
+			if c == nil {
+				continue
+			}
+			if err := validateCmd(c.Cmd, c.Timeout, c.When); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for _, c := range a.Commands {
+		if err := validateCmd(c.Cmd, c.Timeout, c.When); err != nil {
+			return nil, err
+		}
+	}
+
 	return a, nil
 }
 
+func validateCmd(cmd, timeout, when string) error {
+	if _, err := parseTimeout(cmd, timeout); err != nil {
+		return err
+	}
+
+	if when != "" {
+		if _, err := parseWhen(cmd, when); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 // toModules transforms an moduleMetadataSet to Modules structure
 // while establishing the dependency links.
 func toModules(a moduleMetadataSet) (Modules, error) {
@@ -215,6 +456,7 @@ func toModules(a moduleMetadataSet) (Modules, error) {
 		nodes = append(nodes, meta)
 	}
 	provider := newModuleMetadataProvider(m)
+	a.diagnose()
 
 	// Step 2
 	// Topological sort
@@ -268,13 +510,15 @@ func calculateVersion(topSorted Modules) Modules {
 		if a.Hash() == "local" {
 			a.version = "local"
 		} else {
-			if len(a.Requires()) == 0 && len(a.FileDependencies()) == 0 {
+			spec := a.metadata.spec
+			if len(a.requires) == 0 && len(spec.FileDependencies) == 0 && len(a.metadata.lfsObjects) == 0 && len(spec.ExternalDependencies) == 0 {
 				// Fast path for modules without any dependencies
 				a.version = a.Hash()
 			} else {
 				// This module has dependencies.
 				// Version is created by combining the hashes of the module
-				// content, its file dependencies and the hashes of the dependencies.
+				// content, its file dependencies, Git LFS objects, pinned external
+				// dependencies and the hashes of the dependencies.
 				h := sha1.New()
 
 				io.WriteString(h, a.Hash())
@@ -284,14 +528,17 @@ func calculateVersion(topSorted Modules) Modules {
 				// here because we are processing the list of modules in topological
 				// order. Therefore, version of a dependency would already contain
 				// the version of its dependencies.
-				for _, r := range a.Requires() {
+				for _, r := range a.requires {
 					io.WriteString(h, r.Version())
 				}
 
-				for _, f := range a.FileDependencies() {
+				for _, f := range spec.FileDependencies {
 					io.WriteString(h, a.metadata.dependentFileHashes[f])
 				}
 
+				writeLFSObjects(h, a.metadata.lfsObjects)
+				writeExternalDependencies(h, spec.ExternalDependencies)
+
 				a.version = hex.EncodeToString(h.Sum(nil))
 			}
 		}
@@ -199,3 +199,70 @@ func TestVersionChangeOnFileDependencyChange(t *testing.T) {
 
 	assert.NotEqual(t, m2[0].Version(), m1[0].Version())
 }
+
+func TestHookWithInvalidTimeout(t *testing.T) {
+	_, err := newSpec([]byte("name: app-a\nhooks:\n  pre:\n    cmd: echo\n    timeout: soon\n"))
+
+	assert.EqualError(t, err, fmt.Sprintf(msgInvalidTimeout, "soon", "echo"))
+}
+
+func TestCmdWithInvalidWhen(t *testing.T) {
+	_, err := newSpec([]byte("name: app-a\nbuild:\n  default:\n    cmd: echo\n    when: \"app.name ==\"\n"))
+
+	assert.EqualError(t, err, fmt.Sprintf(msgInvalidWhen, "app.name ==", "echo"))
+}
+
+func TestInvalidResourceWeight(t *testing.T) {
+	_, err := newSpec([]byte("name: app-a\nresources:\n  weight: -1\n"))
+
+	assert.EqualError(t, err, fmt.Sprintf(msgInvalidResourceWeight, -1, "app-a"))
+}
+
+func TestModuleOwners(t *testing.T) {
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+
+	check(t, repo.InitModule("app-a"))
+	check(t, repo.InitModule("app-b"))
+	check(t, repo.WriteContent("CODEOWNERS", "* @org/platform\n"))
+	check(t, repo.WriteContent(".github/CODEOWNERS", "app-a/ @org/payments\n"))
+	check(t, repo.Commit("first"))
+
+	world := NewWorld(t, ".tmp/repo")
+	lc, err := world.Repo.GetCommit(repo.LastCommit.String())
+	check(t, err)
+	mods, err := world.Discover.ModulesInCommit(lc)
+	check(t, err)
+	m := mods.indexByName()
+
+	assert.Equal(t, []string{"@org/payments"}, m["app-a"].Owners())
+	assert.Equal(t, []string{}, m["app-b"].Owners())
+
+	mods, err = world.Discover.ModulesInWorkspace()
+	check(t, err)
+	m = mods.indexByName()
+
+	assert.Equal(t, []string{"@org/payments"}, m["app-a"].Owners())
+}
+
+func TestDiscoverWithSpecFile(t *testing.T) {
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+
+	check(t, repo.InitModule("app-a"))
+	check(t, repo.WriteContent("app-b/mbt.yaml", "name: app-b\n"))
+	check(t, repo.Commit("first"))
+
+	world := NewWorld(t, ".tmp/repo")
+	discover := NewDiscoverWithSpecFile(world.Repo, world.Log, "mbt.yaml")
+	lc, err := world.Repo.GetCommit(repo.LastCommit.String())
+	check(t, err)
+
+	mods, err := discover.ModulesInCommit(lc)
+	check(t, err)
+	assert.Equal(t, []string{"app-b"}, mods.Names())
+
+	mods, err = discover.ModulesInWorkspace()
+	check(t, err)
+	assert.Equal(t, []string{"app-b"}, mods.Names())
+}
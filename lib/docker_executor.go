@@ -0,0 +1,85 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"os/exec"
+	"path"
+)
+
+const defaultDockerWorkDir = "/workspace"
+
+type dockerExecutor struct {
+	Log     Log
+	Options *DockerOptions
+}
+
+// NewDockerExecutor creates an Executor that runs the commands in
+// docker containers. Repository is mounted into the container
+// so that the command has access to the same workspace.
+func NewDockerExecutor(log Log, options *DockerOptions) Executor {
+	return &dockerExecutor{Log: log, Options: options}
+}
+
+func (x *dockerExecutor) Prepare(ctx *ExecContext) error {
+	return nil
+}
+
+func (x *dockerExecutor) Run(ctx *ExecContext) error {
+	docker := x.Options.Docker
+	if docker == "" {
+		docker = "docker"
+	}
+
+	args := x.runArgs(ctx)
+	x.Log.Debug("Executing %s %v", docker, args)
+
+	cmd := exec.Command(docker, args...)
+	cmd.Stdin = ctx.Options.Stdin
+	cmd.Stdout = ctx.Options.Stdout
+	cmd.Stderr = ctx.Options.Stderr
+	return runProcess(cmd, ctx.Options.Timeout)
+}
+
+func (x *dockerExecutor) Collect(ctx *ExecContext, runErr error) error {
+	return nil
+}
+
+func (x *dockerExecutor) runArgs(ctx *ExecContext) []string {
+	workDir := x.Options.WorkDir
+	if workDir == "" {
+		workDir = defaultDockerWorkDir
+	}
+
+	args := []string{
+		"run", "--rm", "-i",
+		"-v", ctx.Manifest.Dir + ":" + workDir,
+		"-w", path.Join(workDir, ctx.Dir()),
+	}
+
+	// Images are not pulled in offline mode.
+	if IsOffline() {
+		args = append(args, "--pull", "never")
+	}
+
+	for _, v := range ctx.Environment(workDir) {
+		args = append(args, "-e", v)
+	}
+
+	args = append(args, x.Options.Args...)
+	args = append(args, x.Options.Image, ctx.Command)
+	return append(args, ctx.Args...)
+}
@@ -0,0 +1,52 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDockerRunArgs(t *testing.T) {
+	x := &dockerExecutor{Options: &DockerOptions{Image: "golang:1.15", Args: []string{"--network", "host"}}}
+	mod := newTestModule("app-a", "app-a", "v1")
+
+	args := x.runArgs(&ExecContext{Manifest: &Manifest{Dir: "/repo", Sha: "abc"}, Module: mod, Command: "./build.sh", Args: []string{"a"}})
+
+	assert.Equal(t, []string{
+		"run", "--rm", "-i",
+		"-v", "/repo:/workspace",
+		"-w", "/workspace/app-a",
+		"-e", "MBT_BUILD_COMMIT=abc",
+		"-e", "MBT_MODULE_VERSION=v1",
+		"-e", "MBT_MODULE_NAME=app-a",
+		"-e", "MBT_MODULE_PATH=app-a",
+		"-e", "MBT_MODULE_GROUP=app-a",
+		"-e", "MBT_REPO_PATH=/workspace",
+		"--network", "host",
+		"golang:1.15", "./build.sh", "a",
+	}, args)
+}
+
+func TestDockerRunArgsWithWorkDir(t *testing.T) {
+	x := &dockerExecutor{Options: &DockerOptions{Image: "docker:20"}}
+	mod := newTestModule("app-a", "app-a", "v1")
+
+	args := x.runArgs(&ExecContext{Manifest: &Manifest{Dir: "/repo", Sha: "abc"}, Module: mod, Options: &CmdOptions{WorkDir: "."}, Command: "docker"})
+
+	assert.Equal(t, []string{"-w", "/workspace"}, args[5:7])
+}
@@ -0,0 +1,196 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	git "github.com/libgit2/git2go/v28"
+)
+
+// Doctor check statuses.
+const (
+	// DoctorStatusOK is the status of a satisfied prerequisite.
+	DoctorStatusOK = "ok"
+	// DoctorStatusWarning is the status of a prerequisite limiting
+	// some of the commands.
+	DoctorStatusWarning = "warning"
+	// DoctorStatusError is the status of a prerequisite preventing
+	// mbt from operating.
+	DoctorStatusError = "error"
+)
+
+// libgit2Version is the version of libgit2 mbt is built with.
+// git2go v28 refuses to build with any other version.
+const libgit2Version = "0.28"
+
+// doctorCacheKey is the key read to check the connectivity of caches.
+const doctorCacheKey = "mbt-doctor"
+
+// DoctorCheck is the result of validating a prerequisite of mbt.
+type DoctorCheck struct {
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Message string `json:"message"`
+}
+
+// DoctorOptions describes the optional prerequisites validated by Doctor.
+type DoctorOptions struct {
+	// Cache is checked to be reachable when specified.
+	Cache Cache
+	// Credentials are checked to be available in the environment.
+	Credentials []*Credential
+}
+
+// Credential is a secret read from an environment variable.
+// Missing credentials are reported as warnings since they are
+// required only by some of the options.
+type Credential struct {
+	// Env is the name of the environment variable.
+	Env string
+	// UsedBy describes the options using the credential.
+	UsedBy string
+}
+
+func (s *stdSystem) Doctor(options *DoctorOptions) ([]*DoctorCheck, error) {
+	checks := []*DoctorCheck{libgit2Check(git.Features()), gitCheck()}
+
+	state, err := s.Repo.State()
+	if err != nil {
+		return nil, err
+	}
+	checks = append(checks, repoStateChecks(state)...)
+	checks = append(checks, s.specCheck())
+
+	if options.Cache != nil {
+		checks = append(checks, cacheCheck(options.Cache))
+	}
+
+	return append(checks, credentialChecks(options.Credentials, os.LookupEnv)...), nil
+}
+
+func libgit2Check(features git.Feature) *DoctorCheck {
+	names := make([]string, 0)
+	for _, f := range []struct {
+		feature git.Feature
+		name    string
+	}{
+		{git.FeatureThreads, "threads"},
+		{git.FeatureHTTPS, "https"},
+		{git.FeatureSSH, "ssh"},
+	} {
+		if features&f.feature != 0 {
+			names = append(names, f.name)
+		}
+	}
+
+	c := &DoctorCheck{Name: "libgit2", Status: DoctorStatusOK, Message: fmt.Sprintf("version %s with features: %s", libgit2Version, strings.Join(names, ", "))}
+	if features&git.FeatureThreads == 0 {
+		c.Status = DoctorStatusWarning
+		c.Message += " (built without thread support required to access the repository concurrently)"
+	}
+	return c
+}
+
+func gitCheck() *DoctorCheck {
+	c := &DoctorCheck{Name: "git", Status: DoctorStatusOK}
+	out, err := exec.Command("git", "--version").Output()
+	if err != nil {
+		c.Status = DoctorStatusWarning
+		c.Message = "git is not available, required by pull requests, partial clones, commit trailers and external dependencies"
+		return c
+	}
+
+	c.Message = strings.TrimSpace(string(out))
+	return c
+}
+
+func repoStateChecks(state *RepoState) []*DoctorCheck {
+	checks := make([]*DoctorCheck, 0)
+	if state.Shallow {
+		checks = append(checks, &DoctorCheck{Name: "repository", Status: DoctorStatusWarning, Message: "shallow clone, commits beyond the fetched history cannot be compared (fetch them with git fetch --unshallow)"})
+	}
+	if state.Detached {
+		checks = append(checks, &DoctorCheck{Name: "repository", Status: DoctorStatusWarning, Message: "HEAD is detached, commands using the current branch (e.g. build head) fail"})
+	}
+	if state.Sparse {
+		checks = append(checks, &DoctorCheck{Name: "repository", Status: DoctorStatusOK, Message: "sparse checkout, paths excluded from the workspace are not reported as local changes"})
+	}
+	if state.Partial {
+		checks = append(checks, &DoctorCheck{Name: "repository", Status: DoctorStatusOK, Message: "partial clone, missing objects are fetched with git on demand"})
+	}
+
+	if len(checks) == 0 {
+		checks = append(checks, &DoctorCheck{Name: "repository", Status: DoctorStatusOK, Message: "complete clone with a branch checked out"})
+	}
+	return checks
+}
+
+// specCheck discovers the modules in HEAD to validate their specs.
+func (s *stdSystem) specCheck() *DoctorCheck {
+	c := &DoctorCheck{Name: "specs", Status: DoctorStatusOK}
+	empty, err := s.Repo.IsEmpty()
+	if err == nil && empty {
+		c.Status = DoctorStatusWarning
+		c.Message = "repository does not have any commits"
+		return c
+	}
+
+	var m *Manifest
+	head, err := s.Repo.ResolveRef("HEAD")
+	if err == nil {
+		m, err = s.ManifestByCommit(head.ID())
+	}
+	if err != nil {
+		c.Status = DoctorStatusError
+		c.Message = err.Error()
+		return c
+	}
+
+	c.Message = fmt.Sprintf("%v modules discovered in %s", len(m.Modules), head.ID())
+	return c
+}
+
+func cacheCheck(cache Cache) *DoctorCheck {
+	c := &DoctorCheck{Name: "cache", Status: DoctorStatusOK, Message: "cache is reachable"}
+	r, err := cache.Get(doctorCacheKey)
+	if err != nil {
+		c.Status = DoctorStatusError
+		c.Message = err.Error()
+		return c
+	}
+
+	if r != nil {
+		r.Close()
+	}
+	return c
+}
+
+func credentialChecks(credentials []*Credential, lookup func(string) (string, bool)) []*DoctorCheck {
+	checks := make([]*DoctorCheck, 0, len(credentials))
+	for _, cred := range credentials {
+		c := &DoctorCheck{Name: cred.Env, Status: DoctorStatusOK, Message: fmt.Sprintf("available for %s", cred.UsedBy)}
+		if v, ok := lookup(cred.Env); !ok || v == "" {
+			c.Status = DoctorStatusWarning
+			c.Message = fmt.Sprintf("not set, required by %s", cred.UsedBy)
+		}
+		checks = append(checks, c)
+	}
+	return checks
+}
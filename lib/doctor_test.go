@@ -0,0 +1,119 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	git "github.com/libgit2/git2go/v28"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLibgit2Check(t *testing.T) {
+	assert.Equal(t, &DoctorCheck{Name: "libgit2", Status: DoctorStatusOK, Message: "version 0.28 with features: threads, https, ssh"},
+		libgit2Check(git.FeatureThreads|git.FeatureHTTPS|git.FeatureSSH))
+
+	c := libgit2Check(git.FeatureHTTPS)
+	assert.Equal(t, DoctorStatusWarning, c.Status)
+	assert.Equal(t, "version 0.28 with features: https (built without thread support required to access the repository concurrently)", c.Message)
+}
+
+func TestRepoStateChecks(t *testing.T) {
+	assert.Equal(t, []*DoctorCheck{{Name: "repository", Status: DoctorStatusOK, Message: "complete clone with a branch checked out"}}, repoStateChecks(&RepoState{}))
+
+	checks := repoStateChecks(&RepoState{Shallow: true, Detached: true, Sparse: true, Partial: true})
+
+	assert.Len(t, checks, 4)
+	assert.Equal(t, []string{DoctorStatusWarning, DoctorStatusWarning, DoctorStatusOK, DoctorStatusOK},
+		[]string{checks[0].Status, checks[1].Status, checks[2].Status, checks[3].Status})
+}
+
+func TestCacheCheck(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/broken/mbt-doctor" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	assert.Equal(t, &DoctorCheck{Name: "cache", Status: DoctorStatusOK, Message: "cache is reachable"}, cacheCheck(NewHTTPCache(server.URL)))
+
+	c := cacheCheck(NewHTTPCache(server.URL + "/broken"))
+	assert.Equal(t, DoctorStatusError, c.Status)
+	assert.Equal(t, "Unexpected response 403 Forbidden from cache for key 'mbt-doctor'", c.Message)
+}
+
+func TestCredentialChecks(t *testing.T) {
+	env := map[string]string{"MBT_PR_TOKEN": "secret", "MBT_STATUS_TOKEN": ""}
+	lookup := func(k string) (string, bool) {
+		v, ok := env[k]
+		return v, ok
+	}
+
+	checks := credentialChecks([]*Credential{
+		{Env: "MBT_PR_TOKEN", UsedBy: "--pr"},
+		{Env: "MBT_STATUS_TOKEN", UsedBy: "--status-provider"},
+		{Env: "MBT_OTHER_TOKEN", UsedBy: "--other"},
+	}, lookup)
+
+	assert.Equal(t, []*DoctorCheck{
+		{Name: "MBT_PR_TOKEN", Status: DoctorStatusOK, Message: "available for --pr"},
+		{Name: "MBT_STATUS_TOKEN", Status: DoctorStatusWarning, Message: "not set, required by --status-provider"},
+		{Name: "MBT_OTHER_TOKEN", Status: DoctorStatusWarning, Message: "not set, required by --other"},
+	}, checks)
+}
+
+func TestDoctor(t *testing.T) {
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+
+	check(t, repo.InitModule("app-a"))
+	check(t, repo.Commit("first"))
+
+	checks, err := NewWorld(t, ".tmp/repo").System.Doctor(&DoctorOptions{})
+	check(t, err)
+
+	statuses := make(map[string]*DoctorCheck)
+	for _, c := range checks {
+		statuses[c.Name] = c
+	}
+
+	assert.Equal(t, DoctorStatusOK, statuses["repository"].Status)
+	assert.Equal(t, DoctorStatusOK, statuses["specs"].Status)
+	assert.Equal(t, "1 modules discovered in "+repo.LastCommit.String(), statuses["specs"].Message)
+}
+
+func TestDoctorWithInvalidSpec(t *testing.T) {
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+
+	check(t, repo.InitModule("app-a"))
+	check(t, repo.WriteContent("app-a/.mbt.yml", "blah:blah\nblah::"))
+	check(t, repo.Commit("first"))
+
+	checks, err := NewWorld(t, ".tmp/repo").System.Doctor(&DoctorOptions{})
+	check(t, err)
+
+	for _, c := range checks {
+		if c.Name == "specs" {
+			assert.Equal(t, DoctorStatusError, c.Status)
+		}
+	}
+}
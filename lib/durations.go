@@ -0,0 +1,142 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"sync"
+	"time"
+)
+
+// maxDurationSamples limits the number of samples contributing to
+// the average duration of a module so that recent builds have a
+// noticeable impact on the estimate.
+const maxDurationSamples = 10
+
+// defaultDurationEstimate is used for modules without a history when
+// there's no other module to derive an estimate from.
+const defaultDurationEstimate = time.Second
+
+type durationRecord struct {
+	// Average duration in seconds
+	Average float64 `json:"average"`
+	Samples int     `json:"samples"`
+}
+
+// buildDurations is a persistent store of historical build
+// durations of modules.
+type buildDurations struct {
+	Modules map[string]*durationRecord `json:"modules"`
+	path    string
+	mutex   sync.Mutex
+}
+
+// loadBuildDurations reads the build durations stored in path.
+// Returns an empty store if the file does not exist.
+func loadBuildDurations(path string) (*buildDurations, error) {
+	d := &buildDurations{Modules: make(map[string]*durationRecord), path: path}
+	if err := readJSONFile(path, d); err != nil {
+		return &buildDurations{Modules: make(map[string]*durationRecord), path: path}, err
+	}
+
+	if d.Modules == nil {
+		d.Modules = make(map[string]*durationRecord)
+	}
+
+	return d, nil
+}
+
+// record adds a new sample to the history of a module.
+func (d *buildDurations) record(name string, duration time.Duration) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	r, ok := d.Modules[name]
+	if !ok {
+		r = &durationRecord{}
+		d.Modules[name] = r
+	}
+
+	n := float64(r.Samples)
+	r.Average = (r.Average*n + duration.Seconds()) / (n + 1)
+	if r.Samples < maxDurationSamples {
+		r.Samples++
+	}
+}
+
+// estimate returns the expected duration of a module build.
+// Records are looked up with the key returned from key function.
+// Modules without a history are expected to take the average
+// duration of the known modules.
+func (d *buildDurations) estimate(mod *Module, key func(*Module) string) time.Duration {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	total := time.Duration(0)
+	for _, v := range mod.Variants() {
+		if r, ok := d.Modules[key(v)]; ok {
+			total += time.Duration(r.Average * float64(time.Second))
+		} else {
+			total += d.fallback()
+		}
+	}
+
+	return total
+}
+
+func (d *buildDurations) fallback() time.Duration {
+	if len(d.Modules) == 0 {
+		return defaultDurationEstimate
+	}
+
+	sum := 0.0
+	for _, r := range d.Modules {
+		sum += r.Average
+	}
+	return time.Duration(sum / float64(len(d.Modules)) * float64(time.Second))
+}
+
+// save writes the durations to the file they were loaded from.
+func (d *buildDurations) save() error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	return writeJSONFile(d.path, d)
+}
+
+// criticalPaths computes the expected duration of the longest chain of
+// builds starting from each module. That is, the estimated duration of
+// the module plus the longest critical path of the modules depending
+// on it. mods must be in topological order.
+func criticalPaths(mods Modules, estimate func(*Module) time.Duration) map[string]time.Duration {
+	scheduled := make(map[string]bool, len(mods))
+	for _, m := range mods {
+		scheduled[m.Name()] = true
+	}
+
+	paths := make(map[string]time.Duration, len(mods))
+	for i := len(mods) - 1; i >= 0; i-- {
+		m := mods[i]
+		longest := time.Duration(0)
+		for _, r := range m.RequiredBy() {
+			if scheduled[r.Name()] && paths[r.Name()] > longest {
+				longest = paths[r.Name()]
+			}
+		}
+		paths[m.Name()] = estimate(m) + longest
+	}
+
+	return paths
+}
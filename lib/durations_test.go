@@ -0,0 +1,76 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildDurationsRoundTrip(t *testing.T) {
+	clean()
+	path := filepath.Join(".tmp", "mbt", "durations.json")
+	d, err := loadBuildDurations(path)
+	check(t, err)
+
+	d.record("app-a", 2*time.Second)
+	d.record("app-a", 4*time.Second)
+	check(t, d.save())
+
+	d, err = loadBuildDurations(path)
+	check(t, err)
+	assert.Equal(t, &durationRecord{Average: 3, Samples: 2}, d.Modules["app-a"])
+}
+
+func TestBuildDurationsSampleLimit(t *testing.T) {
+	d := &buildDurations{Modules: make(map[string]*durationRecord)}
+	for i := 0; i < maxDurationSamples+5; i++ {
+		d.record("app-a", time.Second)
+	}
+
+	assert.Equal(t, maxDurationSamples, d.Modules["app-a"].Samples)
+	d.record("app-a", 12*time.Second)
+	assert.Equal(t, 2.0, d.Modules["app-a"].Average)
+}
+
+func TestDurationEstimate(t *testing.T) {
+	d := &buildDurations{Modules: make(map[string]*durationRecord)}
+	a := newTestModule("app-a", "app-a", "a")
+	b := newTestModule("app-b", "app-b", "b")
+
+	assert.Equal(t, defaultDurationEstimate, d.estimate(a, moduleDisplayName))
+
+	d.record("app-a", 4*time.Second)
+	assert.Equal(t, 4*time.Second, d.estimate(a, moduleDisplayName))
+	assert.Equal(t, 4*time.Second, d.estimate(b, moduleDisplayName))
+}
+
+func TestCriticalPaths(t *testing.T) {
+	a := newTestModule("app-a", "app-a", "a")
+	b := newTestModule("app-b", "app-b", "b", a)
+	c := newTestModule("app-c", "app-c", "c", b)
+	d := newTestModule("app-d", "app-d", "d")
+
+	estimates := map[string]time.Duration{"app-a": 1, "app-b": 2, "app-c": 3, "app-d": 5}
+	paths := criticalPaths(Modules{a, b, c, d}, func(m *Module) time.Duration {
+		return estimates[m.Name()]
+	})
+
+	assert.Equal(t, map[string]time.Duration{"app-a": 6, "app-b": 5, "app-c": 3, "app-d": 5}, paths)
+}
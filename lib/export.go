@@ -0,0 +1,201 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/mbtproject/mbt/e"
+)
+
+// exportRef temporarily references the rewritten history in the
+// source repository so that it can be fetched into the destination.
+const exportRef = "refs/mbt/export"
+
+// ExportResult describes the repository created by exporting the
+// history of a module.
+type ExportResult struct {
+	Module string `json:"module"`
+	Path   string `json:"path"`
+	// Commits is the number of commits in the exported history.
+	Commits int `json:"commits"`
+	// Head is the last commit of the exported history.
+	Head string `json:"head"`
+}
+
+// Export extracts the history of the named module, as of ref, into a
+// new repository at path. Module directory becomes the root of the
+// new repository and only the commits changing it are retained.
+// History is linearised and the commits made before the module was
+// moved to its current directory are not included.
+func (s *stdSystem) Export(name, ref, path string) (*ExportResult, error) {
+	c, err := s.Repo.ResolveRef(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	mods, err := s.Discover.ModulesInCommit(c)
+	if err != nil {
+		return nil, err
+	}
+
+	m, ok := mods.indexByName()[name]
+	if !ok {
+		return nil, e.NewErrorf(ErrClassUser, msgModuleNotFound, name, ref)
+	}
+
+	if m.Path() == "" {
+		return nil, e.NewErrorf(ErrClassUser, msgCannotExportRootModule, name)
+	}
+
+	if err := ensureEmptyDir(path); err != nil {
+		return nil, err
+	}
+
+	src := s.Repo.Path()
+	commits, err := gitOutput(src, "rev-list", "--reverse", "--topo-order", c.ID(), "--", m.Path())
+	if err != nil {
+		return nil, e.Wrapf(ErrClassInternal, err, msgFailedExport, name)
+	}
+
+	head, prevTree := "", ""
+	count := 0
+	for _, sha := range strings.Fields(commits) {
+		tree, err := gitOutput(src, "rev-parse", "--verify", "-q", sha+":"+m.Path())
+		if err != nil {
+			// Module directory is deleted in this commit.
+			tree, err = gitInput(src, "", "mktree")
+			if err != nil {
+				return nil, e.Wrapf(ErrClassInternal, err, msgFailedExport, name)
+			}
+		}
+
+		if tree == prevTree {
+			continue
+		}
+
+		head, err = rewriteCommit(src, sha, tree, head)
+		if err != nil {
+			return nil, e.Wrapf(ErrClassInternal, err, msgFailedExport, name)
+		}
+		prevTree = tree
+		count++
+	}
+
+	if head == "" {
+		return nil, e.NewErrorf(ErrClassUser, msgFailedExport, name)
+	}
+
+	if err := fetchExport(src, path, head); err != nil {
+		return nil, e.Wrapf(ErrClassInternal, err, msgFailedExport, name)
+	}
+
+	return &ExportResult{Module: name, Path: path, Commits: count, Head: head}, nil
+}
+
+// rewriteCommit creates a commit with the given tree and parent
+// preserving the author, committer and message of commit sha.
+func rewriteCommit(dir, sha, tree, parent string) (string, error) {
+	info, err := gitOutput(dir, "log", "-1", "--format=%an%x00%ae%x00%ad%x00%cn%x00%ce%x00%cd%x00%B", "--date=raw", sha)
+	if err != nil {
+		return "", err
+	}
+
+	fields := strings.SplitN(info, "\x00", 7)
+	if len(fields) != 7 {
+		return "", e.NewErrorf(ErrClassInternal, "unexpected commit format %v", sha)
+	}
+
+	args := []string{"commit-tree", tree}
+	if parent != "" {
+		args = append(args, "-p", parent)
+	}
+	args = append(args, "-F", "-")
+
+	env := []string{
+		"GIT_AUTHOR_NAME=" + fields[0],
+		"GIT_AUTHOR_EMAIL=" + fields[1],
+		"GIT_AUTHOR_DATE=" + fields[2],
+		"GIT_COMMITTER_NAME=" + fields[3],
+		"GIT_COMMITTER_EMAIL=" + fields[4],
+		"GIT_COMMITTER_DATE=" + fields[5],
+	}
+
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Stdin = strings.NewReader(fields[6] + "\n")
+	out, err := cmd.Output()
+	return strings.TrimSpace(string(out)), err
+}
+
+// fetchExport initialises a repository at path and checks out the
+// exported history as its master branch.
+func fetchExport(src, path, head string) error {
+	// Source is fetched from the destination directory.
+	src, err := filepath.Abs(src)
+	if err != nil {
+		return err
+	}
+
+	if _, err := gitOutput(src, "update-ref", exportRef, head); err != nil {
+		return err
+	}
+	defer gitOutput(src, "update-ref", "-d", exportRef)
+
+	steps := [][]string{
+		{"init", "-q", path},
+		{"-C", path, "fetch", "-q", "--update-head-ok", src, exportRef + ":refs/heads/master"},
+		{"-C", path, "symbolic-ref", "HEAD", "refs/heads/master"},
+		{"-C", path, "reset", "-q", "--hard"},
+	}
+
+	for _, args := range steps {
+		if out, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+			return e.NewErrorf(ErrClassInternal, "git %v: %s", strings.Join(args, " "), bytes.TrimSpace(out))
+		}
+	}
+
+	return nil
+}
+
+func ensureEmptyDir(path string) error {
+	entries, err := ioutil.ReadDir(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+
+	if err != nil || len(entries) > 0 {
+		return e.NewErrorf(ErrClassUser, msgExportDestinationNotEmpty, path)
+	}
+
+	return nil
+}
+
+func gitOutput(dir string, args ...string) (string, error) {
+	return gitInput(dir, "", args...)
+}
+
+func gitInput(dir, input string, args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	cmd.Stdin = strings.NewReader(input)
+	out, err := cmd.Output()
+	return strings.TrimSpace(string(out)), err
+}
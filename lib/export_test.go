@@ -0,0 +1,104 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"fmt"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExport(t *testing.T) {
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+
+	check(t, repo.InitModule("app-a"))
+	check(t, repo.InitModule("app-b"))
+	check(t, repo.Commit("first"))
+
+	check(t, repo.WriteContent("app-b/foo", "b"))
+	check(t, repo.Commit("second"))
+
+	check(t, repo.WriteContent("app-a/foo", "a"))
+	check(t, repo.Commit("third"))
+
+	r, err := NewWorld(t, ".tmp/repo").System.Export("app-a", "HEAD", ".tmp/export")
+	check(t, err)
+
+	assert.Equal(t, "app-a", r.Module)
+	assert.Equal(t, 2, r.Commits)
+	assert.Equal(t, "third\nfirst", runGit(t, "-C", ".tmp/export", "log", "--format=%s"))
+
+	content, err := ioutil.ReadFile(".tmp/export/foo")
+	check(t, err)
+	assert.Equal(t, "a", string(content))
+	assert.FileExists(t, ".tmp/export/.mbt.yml")
+	assert.Equal(t, "", runGit(t, "-C", ".tmp/repo", "for-each-ref", exportRef))
+}
+
+func TestExportUnknownModule(t *testing.T) {
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+
+	check(t, repo.InitModule("app-a"))
+	check(t, repo.Commit("first"))
+
+	_, err := NewWorld(t, ".tmp/repo").System.Export("app-b", "HEAD", ".tmp/export")
+	assert.EqualError(t, err, fmt.Sprintf(msgModuleNotFound, "app-b", "HEAD"))
+}
+
+func TestExportToNonEmptyDir(t *testing.T) {
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+
+	check(t, repo.InitModule("app-a"))
+	check(t, repo.Commit("first"))
+	writeAuditFile(t, ".tmp/export/foo", "foo")
+
+	_, err := NewWorld(t, ".tmp/repo").System.Export("app-a", "HEAD", ".tmp/export")
+	assert.EqualError(t, err, fmt.Sprintf(msgExportDestinationNotEmpty, ".tmp/export"))
+}
+
+func TestRewriteCommit(t *testing.T) {
+	clean()
+	src := ".tmp/export-src"
+	writeAuditFile(t, src+"/app-a/foo", "a")
+	runGit(t, "init", "-q", src)
+	runGit(t, "-C", src, "add", ".")
+	runGit(t, "-c", "user.name=alice", "-c", "user.email=alice@example.com", "-C", src, "commit", "-q", "-m", "add app-a", "-m", "details")
+	sha := runGit(t, "-C", src, "rev-parse", "HEAD")
+	tree := runGit(t, "-C", src, "rev-parse", "HEAD:app-a")
+
+	head, err := rewriteCommit(src, sha, tree, "")
+	check(t, err)
+
+	assert.Equal(t, tree, runGit(t, "-C", src, "rev-parse", head+"^{tree}"))
+	assert.Equal(t, runGit(t, "-C", src, "log", "-1", "--format=%an %ae %ad %B", sha), runGit(t, "-C", src, "log", "-1", "--format=%an %ae %ad %B", head))
+
+	check(t, fetchExport(src, ".tmp/export", head))
+	assert.Equal(t, head, runGit(t, "-C", ".tmp/export", "rev-parse", "master"))
+	assert.FileExists(t, ".tmp/export/foo")
+}
+
+func TestEnsureEmptyDir(t *testing.T) {
+	clean()
+	check(t, ensureEmptyDir(".tmp/export"))
+
+	writeAuditFile(t, ".tmp/export/foo", "foo")
+	assert.EqualError(t, ensureEmptyDir(".tmp/export"), fmt.Sprintf(msgExportDestinationNotEmpty, ".tmp/export"))
+}
@@ -0,0 +1,116 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"os"
+	"strings"
+
+	"github.com/mbtproject/mbt/e"
+	"github.com/mbtproject/mbt/expr"
+)
+
+// ChangedFiles returns the files changed in this module according to
+// the specified manifest. That is, the changed files within the
+// module directory and the ones matching its file dependencies.
+func (a *Module) ChangedFiles(m *Manifest) []string {
+	files := make([]string, 0)
+	prefix := strings.ToLower(a.Path() + "/")
+
+	for _, f := range m.ChangedFiles {
+		lf := strings.ToLower(f)
+		match := a.Path() == "" || strings.HasPrefix(lf, prefix)
+		for _, d := range a.FileDependencies() {
+			match = match || strings.HasPrefix(lf, strings.ToLower(d))
+		}
+
+		if match {
+			files = append(files, f)
+		}
+	}
+
+	return files
+}
+
+// expressionVars returns the variables available to expressions
+// evaluated against a module.
+func expressionVars(m *Manifest, mod *Module) map[string]interface{} {
+	dependencies := make([]interface{}, 0, len(mod.Requires()))
+	for _, r := range mod.Requires() {
+		dependencies = append(dependencies, r.Name())
+	}
+
+	env := make(map[string]interface{})
+	for _, kv := range os.Environ() {
+		if i := strings.Index(kv, "="); i > 0 {
+			env[kv[:i]] = kv[i+1:]
+		}
+	}
+
+	changedFiles := m.ChangedFiles
+	if changedFiles == nil {
+		changedFiles = []string{}
+	}
+
+	return map[string]interface{}{
+		"app": map[string]interface{}{
+			"name":              mod.Name(),
+			"path":              mod.Path(),
+			"version":           mod.Version(),
+			"properties":        mod.Properties(),
+			"dependencies":      dependencies,
+			"owners":            mod.Owners(),
+			"changedFiles":      mod.ChangedFiles(m),
+			"requiresMigration": mod.RequiresMigration(),
+			"changeClasses":     mod.ChangeClasses(),
+			"lifecycle":         mod.Lifecycle(),
+		},
+		"branch":       m.Branch,
+		"sha":          m.Sha,
+		"changedFiles": changedFiles,
+		"env":          env,
+	}
+}
+
+// parseWhen parses the when expression of a command.
+func parseWhen(cmd, when string) (*expr.Expr, error) {
+	x, err := expr.Parse(when)
+	if err != nil {
+		return nil, e.Wrapf(ErrClassUser, err, msgInvalidWhen, when, cmd)
+	}
+	return x, nil
+}
+
+// evalWhen evaluates the when expression of a command against
+// the specified module. Commands without an expression are
+// always executed.
+func evalWhen(cmd, when string, m *Manifest, mod *Module) (bool, error) {
+	if when == "" {
+		return true, nil
+	}
+
+	x, err := parseWhen(cmd, when)
+	if err != nil {
+		return false, err
+	}
+
+	r, err := x.EvalBool(expressionVars(m, mod))
+	if err != nil {
+		return false, e.Wrapf(ErrClassUser, err, msgFailedWhen, when, mod.Name())
+	}
+
+	return r, nil
+}
@@ -0,0 +1,68 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestModuleChangedFiles(t *testing.T) {
+	m := &Manifest{ChangedFiles: []string{"app-a/main.go", "app-ab/main.go", "shared/a.sql", "README.md"}}
+	a := newModule(newModuleMetadata("app-a", "a", &Spec{Name: "app-a", FileDependencies: []string{"shared/"}}, nil), nil)
+	root := newModule(newModuleMetadata("", "a", &Spec{Name: "root"}, nil), nil)
+
+	assert.Equal(t, []string{"app-a/main.go", "shared/a.sql"}, a.ChangedFiles(m))
+	assert.Equal(t, m.ChangedFiles, root.ChangedFiles(m))
+	assert.Equal(t, []string{}, a.ChangedFiles(&Manifest{}))
+}
+
+func TestEvalWhen(t *testing.T) {
+	os.Setenv("MBT_TEST_WHEN", "yes")
+	defer os.Unsetenv("MBT_TEST_WHEN")
+
+	m := &Manifest{Sha: "abc", Branch: "feature", ChangedFiles: []string{"app-a/db/a.sql"}}
+	mod := newModule(newModuleMetadata("app-a", "a", &Spec{Name: "app-a", Properties: map[string]interface{}{"team": "payments"}}, nil), nil)
+
+	for _, c := range []struct {
+		When     string
+		Expected bool
+	}{
+		{"", true},
+		{"app.name == 'app-a' && app.properties.team == 'payments'", true},
+		{"app.changedFiles.exists(f, f.startsWith('app-a/db/'))", true},
+		{"changedFiles.all(f, f.endsWith('.go'))", false},
+		{"branch == 'master'", false},
+		{"env.MBT_TEST_WHEN == 'yes' && sha == 'abc'", true},
+	} {
+		r, err := evalWhen("echo", c.When, m, mod)
+		check(t, err)
+		assert.Equal(t, c.Expected, r, c.When)
+	}
+}
+
+func TestEvalWhenErrors(t *testing.T) {
+	mod := newTestModule("app-a", "app-a", "a")
+
+	_, err := evalWhen("echo", "app.name ==", &Manifest{}, mod)
+	assert.EqualError(t, err, fmt.Sprintf(msgInvalidWhen, "app.name ==", "echo"))
+
+	_, err = evalWhen("echo", "app.name", &Manifest{}, mod)
+	assert.EqualError(t, err, fmt.Sprintf(msgFailedWhen, "app.name", "app-a"))
+}
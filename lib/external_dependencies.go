@@ -0,0 +1,179 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os/exec"
+	"regexp"
+	"strings"
+
+	"github.com/mbtproject/mbt/e"
+)
+
+// ExternalDependency is a dependency of a module on a path in another
+// repository pinned at a specific ref.
+type ExternalDependency struct {
+	// Repo is the URL of the repository.
+	Repo string `yaml:"repo" json:"repo"`
+	// Path in the repository the module depends on. Empty means the
+	// entire repository.
+	Path string `yaml:"path" json:"path,omitempty"`
+	// Ref the dependency is pinned at. A commit sha or a tag is
+	// recommended because the version of the module is not changed
+	// when a branch moves.
+	Ref string `yaml:"ref" json:"ref"`
+	// Track is the ref compared with Ref to find out whether the
+	// dependency is stale. Defaults to HEAD.
+	Track string `yaml:"track" json:"track,omitempty"`
+}
+
+func (d *ExternalDependency) track() string {
+	if d.Track == "" {
+		return "HEAD"
+	}
+	return d.Track
+}
+
+func validateExternalDependencies(spec *Spec) error {
+	for _, d := range spec.ExternalDependencies {
+		if d == nil || d.Repo == "" || d.Ref == "" {
+			return e.NewErrorf(ErrClassUser, msgInvalidExternalDependency, spec.Name)
+		}
+	}
+	return nil
+}
+
+// writeExternalDependencies writes the pinned external dependencies
+// to w in a stable order.
+func writeExternalDependencies(w io.Writer, deps []*ExternalDependency) {
+	for _, d := range deps {
+		io.WriteString(w, d.Repo)
+		io.WriteString(w, d.Path)
+		io.WriteString(w, d.Ref)
+	}
+}
+
+// RefResolver resolves a ref in a remote repository to a commit sha.
+type RefResolver func(repo, ref string) (string, error)
+
+var fullSha = regexp.MustCompile(`^[0-9a-f]{40}$`)
+
+// LsRemote is a RefResolver using git ls-remote.
+func LsRemote(repo, ref string) (string, error) {
+	if fullSha.MatchString(ref) {
+		return ref, nil
+	}
+
+	if err := RequireNetwork(fmt.Sprintf("Resolving %v in %v", ref, repo)); err != nil {
+		return "", err
+	}
+
+	out, err := exec.Command("git", "ls-remote", repo, ref, ref+"^{}").Output()
+	if err != nil {
+		return "", e.Wrapf(ErrClassUser, err, msgFailedRemoteRefLookup, ref, repo)
+	}
+
+	sha := ""
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			continue
+		}
+
+		// Annotated tags are listed twice. Peeled entry has the sha
+		// of the commit.
+		if strings.HasSuffix(fields[1], "^{}") {
+			return fields[0], nil
+		}
+
+		if sha == "" {
+			sha = fields[0]
+		}
+	}
+
+	if sha == "" {
+		return "", e.NewErrorf(ErrClassUser, msgFailedRemoteRefLookup, ref, repo)
+	}
+
+	return sha, nil
+}
+
+// ExternalDependencyStatus describes whether an external dependency of a
+// module is up to date with the ref it tracks.
+type ExternalDependencyStatus struct {
+	Module string `json:"module"`
+	*ExternalDependency
+	// Pinned is the commit sha of Ref.
+	Pinned string `json:"pinned"`
+	// Latest is the commit sha of the tracked ref.
+	Latest string `json:"latest"`
+	// Stale is true when the tracked ref has advanced since the
+	// dependency was pinned.
+	Stale bool `json:"stale"`
+}
+
+// CheckExternalDependencies resolves the external dependencies of mods
+// with resolve and reports the ones behind the ref they track.
+// Staleness is determined by the commits of refs. Therefore, a
+// dependency is reported as stale even if the tracked ref advanced
+// without changing its path.
+func CheckExternalDependencies(mods Modules, resolve RefResolver) ([]*ExternalDependencyStatus, error) {
+	resolved := make(map[string]string)
+	lookup := func(repo, ref string) (string, error) {
+		key := repo + " " + ref
+		if sha, ok := resolved[key]; ok {
+			return sha, nil
+		}
+
+		sha, err := resolve(repo, ref)
+		if err != nil {
+			return "", err
+		}
+
+		resolved[key] = sha
+		return sha, nil
+	}
+
+	statuses := make([]*ExternalDependencyStatus, 0)
+	for _, m := range mods {
+		for _, d := range m.ExternalDependencies() {
+			pinned, err := lookup(d.Repo, d.Ref)
+			if err != nil {
+				return nil, err
+			}
+
+			latest, err := lookup(d.Repo, d.track())
+			if err != nil {
+				return nil, err
+			}
+
+			statuses = append(statuses, &ExternalDependencyStatus{
+				Module:             m.Name(),
+				ExternalDependency: d,
+				Pinned:             pinned,
+				Latest:             latest,
+				Stale:              pinned != latest,
+			})
+		}
+	}
+
+	return statuses, nil
+}
@@ -0,0 +1,105 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExternalDependenciesInSpec(t *testing.T) {
+	spec, err := newSpec([]byte("name: app-a\nexternalDependencies:\n  - repo: https://example.com/lib.git\n    path: proto\n    ref: v1.0.0\n"))
+	check(t, err)
+
+	assert.Equal(t, []*ExternalDependency{{Repo: "https://example.com/lib.git", Path: "proto", Ref: "v1.0.0"}}, spec.ExternalDependencies)
+	assert.Equal(t, "HEAD", spec.ExternalDependencies[0].track())
+}
+
+func TestInvalidExternalDependency(t *testing.T) {
+	_, err := newSpec([]byte("name: app-a\nexternalDependencies:\n  - repo: https://example.com/lib.git\n"))
+	assert.EqualError(t, err, fmt.Sprintf(msgInvalidExternalDependency, "app-a"))
+
+	_, err = newSpec([]byte("name: app-a\nexternalDependencies:\n  - ref: v1.0.0\n"))
+	assert.EqualError(t, err, fmt.Sprintf(msgInvalidExternalDependency, "app-a"))
+}
+
+func TestCheckExternalDependencies(t *testing.T) {
+	shas := map[string]string{"v1": "sha-1", "HEAD": "sha-2", "release": "sha-1"}
+	calls := 0
+	resolve := func(repo, ref string) (string, error) {
+		calls++
+		return shas[ref], nil
+	}
+
+	stale := &ExternalDependency{Repo: "lib", Ref: "v1"}
+	current := &ExternalDependency{Repo: "lib", Path: "proto", Ref: "v1", Track: "release"}
+	a := newTestModule("app-a", "app-a", "a")
+	a.metadata.spec.ExternalDependencies = []*ExternalDependency{stale, current}
+	mods := Modules{a, newTestModule("app-b", "app-b", "b")}
+
+	statuses, err := CheckExternalDependencies(mods, resolve)
+	check(t, err)
+
+	assert.Equal(t, []*ExternalDependencyStatus{
+		{Module: "app-a", ExternalDependency: stale, Pinned: "sha-1", Latest: "sha-2", Stale: true},
+		{Module: "app-a", ExternalDependency: current, Pinned: "sha-1", Latest: "sha-1", Stale: false},
+	}, statuses)
+	assert.Equal(t, 3, calls)
+}
+
+func TestCheckExternalDependenciesResolveError(t *testing.T) {
+	resolve := func(repo, ref string) (string, error) {
+		return "", errors.New("doh")
+	}
+
+	a := newTestModule("app-a", "app-a", "a")
+	a.metadata.spec.ExternalDependencies = []*ExternalDependency{{Repo: "lib", Ref: "v1"}}
+	_, err := CheckExternalDependencies(Modules{a}, resolve)
+	assert.EqualError(t, err, "doh")
+}
+
+func TestLsRemote(t *testing.T) {
+	clean()
+	repo := ".tmp/external"
+	writeAuditFile(t, repo+"/proto/a.proto", "a")
+	runGit(t, "init", "-q", repo)
+	runGit(t, "-C", repo, "add", ".")
+	runGit(t, "-C", repo, "commit", "-q", "-m", "first")
+	first := runGit(t, "-C", repo, "rev-parse", "HEAD")
+	runGit(t, "-C", repo, "tag", "-a", "v1", "-m", "v1")
+
+	writeAuditFile(t, repo+"/proto/a.proto", "b")
+	runGit(t, "-C", repo, "commit", "-q", "-am", "second")
+	second := runGit(t, "-C", repo, "rev-parse", "HEAD")
+
+	sha, err := LsRemote(repo, "v1")
+	check(t, err)
+	assert.Equal(t, first, sha)
+
+	sha, err = LsRemote(repo, "HEAD")
+	check(t, err)
+	assert.Equal(t, second, sha)
+
+	sha, err = LsRemote(repo, first)
+	check(t, err)
+	assert.Equal(t, first, sha)
+
+	_, err = LsRemote(repo, "v2")
+	assert.EqualError(t, err, fmt.Sprintf(msgFailedRemoteRefLookup, "v2", repo))
+}
@@ -0,0 +1,71 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	yaml "github.com/go-yaml/yaml"
+	"github.com/mbtproject/mbt/e"
+)
+
+// HelmValuesOptions describes how Helm values of modules are generated.
+type HelmValuesOptions struct {
+	// Properties of modules included in the values along with
+	// the version.
+	Properties []string
+}
+
+func (m *Module) helmValues(options *HelmValuesOptions) yaml.MapSlice {
+	values := yaml.MapSlice{{Key: "version", Value: m.Version()}}
+	for _, p := range options.Properties {
+		if v, ok := m.Properties()[p]; ok {
+			values = append(values, yaml.MapItem{Key: p, Value: v})
+		}
+	}
+
+	return yaml.MapSlice{{Key: m.Name(), Value: values}}
+}
+
+// SerializeAsHelmValues generates a values.yaml mapping the name of
+// each module to its version and selected properties.
+func (mods Modules) SerializeAsHelmValues(options *HelmValuesOptions) (string, error) {
+	values := yaml.MapSlice{}
+	for _, m := range mods {
+		values = append(values, m.helmValues(options)...)
+	}
+
+	b, err := yaml.Marshal(values)
+	if err != nil {
+		return "", e.Wrap(ErrClassInternal, err)
+	}
+
+	return string(b), nil
+}
+
+// SerializeAsHelmValuesByModule generates a values.yaml fragment for
+// each module. Fragments are keyed by module name and produce the
+// output of SerializeAsHelmValues when merged.
+func (mods Modules) SerializeAsHelmValuesByModule(options *HelmValuesOptions) (map[string]string, error) {
+	fragments := make(map[string]string, len(mods))
+	for _, m := range mods {
+		b, err := yaml.Marshal(m.helmValues(options))
+		if err != nil {
+			return nil, e.Wrap(ErrClassInternal, err)
+		}
+		fragments[m.Name()] = string(b)
+	}
+
+	return fragments, nil
+}
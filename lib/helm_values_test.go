@@ -0,0 +1,53 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSerializeAsHelmValues(t *testing.T) {
+	a := newTestModule("app-a", "app-a", "a1")
+	a.metadata.spec.Properties = map[string]interface{}{"team": "payments", "replicas": 2, "owner": "x"}
+	mods := Modules{a, newTestModule("app-b", "app-b", "b1")}
+
+	s, err := mods.SerializeAsHelmValues(&HelmValuesOptions{Properties: []string{"team", "replicas"}})
+	check(t, err)
+
+	assert.Equal(t, `app-a:
+  version: a1
+  team: payments
+  replicas: 2
+app-b:
+  version: b1
+`, s)
+}
+
+func TestSerializeAsHelmValuesByModule(t *testing.T) {
+	a := newTestModule("app-a", "app-a", "a1")
+	a.metadata.spec.Properties = map[string]interface{}{"team": "payments"}
+	mods := Modules{a, newTestModule("app-b", "app-b", "b1")}
+
+	fragments, err := mods.SerializeAsHelmValuesByModule(&HelmValuesOptions{Properties: []string{"team"}})
+	check(t, err)
+
+	assert.Equal(t, map[string]string{
+		"app-a": "app-a:\n  version: a1\n  team: payments\n",
+		"app-b": "app-b:\n  version: b1\n",
+	}, fragments)
+}
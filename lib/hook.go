@@ -0,0 +1,188 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"text/template"
+
+	"github.com/mbtproject/mbt/e"
+)
+
+// hookMarker identifies the hooks generated by mbt.
+const hookMarker = "# Generated by mbt hook install."
+
+// HookOptions describes the pre-push hook generated by mbt.
+type HookOptions struct {
+	// Command used to invoke mbt. Defaults to mbt.
+	Command string
+	// Base is the branch new branches are compared with. Defaults to
+	// master.
+	Base string
+	// Test runs the tests of impacted modules and rejects the push if
+	// any of them fails.
+	Test bool
+}
+
+var prePushTemplate = template.Must(template.New("pre-push").Parse(`#!/bin/sh
+{{.Marker}}
+# Lists the modules impacted by pushed commits{{if .Test}} and runs their tests{{end}}.
+# Run 'mbt hook uninstall' to remove it.
+
+zero=0000000000000000000000000000000000000000
+
+while read local_ref local_sha remote_ref remote_sha; do
+	if [ "$local_sha" = "$zero" ]; then
+		continue
+	fi
+
+	if [ "$remote_sha" = "$zero" ] || ! git cat-file -e "$remote_sha" 2>/dev/null; then
+		from=$(git merge-base "$local_sha" {{.Base}} 2>/dev/null) || continue
+	else
+		from=$remote_sha
+	fi
+
+	echo "Modules impacted by $local_ref:"
+	{{.Command}} describe diff --from "$from" --to "$local_sha" || exit 1
+{{- if .Test}}
+	{{.Command}} test diff --from "$from" --to "$local_sha" || exit 1
+{{- end}}
+done
+
+exit 0
+`))
+
+// PrePushHook returns the content of a pre-push hook analysing the
+// impact of the pushed commits.
+func PrePushHook(options *HookOptions) (string, error) {
+	if options == nil {
+		options = &HookOptions{}
+	}
+
+	data := struct {
+		Marker  string
+		Command string
+		Base    string
+		Test    bool
+	}{hookMarker, options.Command, options.Base, options.Test}
+
+	if data.Command == "" {
+		data.Command = "mbt"
+	}
+	if data.Base == "" {
+		data.Base = "master"
+	}
+
+	buf := new(bytes.Buffer)
+	if err := prePushTemplate.Execute(buf, data); err != nil {
+		return "", e.Wrap(ErrClassInternal, err)
+	}
+
+	return buf.String(), nil
+}
+
+// prePushHookPath returns the path of pre-push hook of the repository
+// in dir. It respects core.hooksPath setting.
+func prePushHookPath(dir string) (string, error) {
+	path, err := gitOutput(dir, "rev-parse", "--git-path", "hooks/pre-push")
+	if err != nil {
+		return "", e.Wrapf(ErrClassUser, err, msgFailedHookPath, dir)
+	}
+
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+
+	return path, nil
+}
+
+func isGeneratedHook(path string) (bool, error) {
+	content, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return true, nil
+	}
+	if err != nil {
+		return false, e.Wrapf(ErrClassUser, err, msgFailedReadFile, path)
+	}
+
+	return bytes.Contains(content, []byte(hookMarker)), nil
+}
+
+// InstallHook writes a pre-push hook in the repository in dir and
+// returns its path. A hook that is not generated by mbt is only
+// replaced when force is true.
+func InstallHook(dir string, options *HookOptions, force bool) (string, error) {
+	path, err := prePushHookPath(dir)
+	if err != nil {
+		return "", err
+	}
+
+	generated, err := isGeneratedHook(path)
+	if err != nil {
+		return "", err
+	}
+
+	if !generated && !force {
+		return "", e.NewErrorf(ErrClassUser, msgHookExists, path)
+	}
+
+	content, err := PrePushHook(options)
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", e.Wrapf(ErrClassUser, err, msgFailedWriteFile, path)
+	}
+
+	if err := ioutil.WriteFile(path, []byte(content), 0755); err != nil {
+		return "", e.Wrapf(ErrClassUser, err, msgFailedWriteFile, path)
+	}
+
+	// WriteFile does not change the mode of an existing file.
+	if err := os.Chmod(path, 0755); err != nil {
+		return "", e.Wrapf(ErrClassUser, err, msgFailedWriteFile, path)
+	}
+
+	return path, nil
+}
+
+// UninstallHook removes the pre-push hook generated by mbt from the
+// repository in dir and returns its path.
+func UninstallHook(dir string) (string, error) {
+	path, err := prePushHookPath(dir)
+	if err != nil {
+		return "", err
+	}
+
+	generated, err := isGeneratedHook(path)
+	if err != nil {
+		return "", err
+	}
+
+	if !generated {
+		return "", e.NewErrorf(ErrClassUser, msgHookNotGenerated, path)
+	}
+
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return "", e.Wrap(ErrClassUser, err)
+	}
+
+	return path, nil
+}
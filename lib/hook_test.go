@@ -0,0 +1,146 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrePushHook(t *testing.T) {
+	h, err := PrePushHook(nil)
+	check(t, err)
+
+	assert.Contains(t, h, hookMarker)
+	assert.Contains(t, h, `mbt describe diff --from "$from" --to "$local_sha" || exit 1`)
+	assert.Contains(t, h, `git merge-base "$local_sha" master`)
+	assert.NotContains(t, h, "test diff")
+
+	h, err = PrePushHook(&HookOptions{Command: "/bin/mbt", Base: "main", Test: true})
+	check(t, err)
+
+	assert.Contains(t, h, `/bin/mbt test diff --from "$from" --to "$local_sha" || exit 1`)
+	assert.Contains(t, h, `git merge-base "$local_sha" main`)
+}
+
+func TestInstallHook(t *testing.T) {
+	clean()
+	repo := ".tmp/hook"
+	check(t, os.MkdirAll(repo, 0755))
+	runGit(t, "init", "-q", repo)
+
+	path, err := InstallHook(repo, nil, false)
+	check(t, err)
+
+	abs, err := filepath.Abs(filepath.Join(repo, ".git", "hooks", "pre-push"))
+	check(t, err)
+	actual, err := filepath.Abs(path)
+	check(t, err)
+	assert.Equal(t, abs, actual)
+
+	info, err := os.Stat(path)
+	check(t, err)
+	assert.Equal(t, os.FileMode(0755), info.Mode().Perm())
+
+	// Generated hook is replaced without force.
+	_, err = InstallHook(repo, &HookOptions{Test: true}, false)
+	check(t, err)
+	content, err := ioutil.ReadFile(path)
+	check(t, err)
+	assert.Contains(t, string(content), "test diff")
+
+	_, err = UninstallHook(repo)
+	check(t, err)
+	assert.NoFileExists(t, path)
+}
+
+func TestInstallHookDoesNotReplaceUserHook(t *testing.T) {
+	clean()
+	repo := ".tmp/hook"
+	check(t, os.MkdirAll(repo, 0755))
+	runGit(t, "init", "-q", repo)
+	path := filepath.Join(repo, ".git", "hooks", "pre-push")
+	writeAuditFile(t, path, "#!/bin/sh\nexit 0\n")
+
+	_, err := InstallHook(repo, nil, false)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "is not generated by mbt, use --force")
+
+	_, err = UninstallHook(repo)
+	assert.Error(t, err)
+	assert.FileExists(t, path)
+
+	_, err = InstallHook(repo, nil, true)
+	check(t, err)
+	content, err := ioutil.ReadFile(path)
+	check(t, err)
+	assert.Contains(t, string(content), hookMarker)
+}
+
+func TestInstallHookInHooksPath(t *testing.T) {
+	clean()
+	repo := ".tmp/hook"
+	check(t, os.MkdirAll(repo, 0755))
+	runGit(t, "init", "-q", repo)
+	runGit(t, "-C", repo, "config", "core.hooksPath", "githooks")
+
+	_, err := InstallHook(repo, nil, false)
+	check(t, err)
+	assert.FileExists(t, filepath.Join(repo, "githooks", "pre-push"))
+}
+
+func TestPrePushHookRanges(t *testing.T) {
+	clean()
+	repo := ".tmp/hook"
+	writeAuditFile(t, repo+"/a", "a")
+	runGit(t, "init", "-q", repo)
+	runGit(t, "-C", repo, "add", ".")
+	runGit(t, "-C", repo, "commit", "-q", "-m", "first")
+	runGit(t, "-C", repo, "branch", "-M", "master")
+	first := runGit(t, "-C", repo, "rev-parse", "HEAD")
+	writeAuditFile(t, repo+"/a", "b")
+	runGit(t, "-C", repo, "commit", "-q", "-am", "second")
+	second := runGit(t, "-C", repo, "rev-parse", "HEAD")
+
+	h, err := PrePushHook(&HookOptions{Command: "echo", Test: true})
+	check(t, err)
+	hook := filepath.Join(repo, "pre-push")
+	check(t, ioutil.WriteFile(hook, []byte(h), 0755))
+
+	zero := strings.Repeat("0", 40)
+	input := fmt.Sprintf("refs/heads/a %v refs/heads/a %v\nrefs/heads/b %v refs/heads/b %v\nrefs/heads/c %v refs/heads/c %v\n",
+		second, first, second, zero, zero, first)
+	cmd := exec.Command("sh", "pre-push")
+	cmd.Dir = repo
+	cmd.Stdin = strings.NewReader(input)
+	out, err := cmd.CombinedOutput()
+	check(t, err)
+
+	assert.Equal(t, fmt.Sprintf(`Modules impacted by refs/heads/a:
+describe diff --from %[1]v --to %[2]v
+test diff --from %[1]v --to %[2]v
+Modules impacted by refs/heads/b:
+describe diff --from %[2]v --to %[2]v
+test diff --from %[2]v --to %[2]v
+`, first, second), string(out))
+}
@@ -0,0 +1,105 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/mbtproject/mbt/e"
+)
+
+// DefaultImageTagPattern is the pattern of image references used
+// when a pattern is not specified.
+const DefaultImageTagPattern = "{registry}/{name}:{version}"
+
+// maxImageTagLength is the maximum length of image tags.
+const maxImageTagLength = 128
+
+var (
+	imagePlaceholder = regexp.MustCompile(`\{([^{}]*)\}`)
+	invalidImageName = regexp.MustCompile(`[^a-z0-9._/-]+`)
+	invalidImageTag  = regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
+)
+
+// ImageTag returns the image reference of the module computed from
+// pattern. Following placeholders are replaced in pattern:
+//
+//	{registry}: registry without the trailing slash
+//	{name}: module name in lower case
+//	{version}: module version
+//	{group}: module group
+//	{property:<path>}: value of the property at the dot separated path
+//
+// Characters that are not allowed in image names and tags are replaced
+// with dashes. Slashes left at the start of the reference when the
+// registry is empty are removed.
+func (a *Module) ImageTag(registry, pattern string) (string, error) {
+	if pattern == "" {
+		pattern = DefaultImageTagPattern
+	}
+
+	var err error
+	ref := imagePlaceholder.ReplaceAllStringFunc(pattern, func(p string) string {
+		if err != nil {
+			return ""
+		}
+
+		key := p[1 : len(p)-1]
+		switch {
+		case key == "registry":
+			return strings.TrimRight(registry, "/")
+		case key == "name":
+			return imageName(a.Name())
+		case key == "version":
+			return imageVersion(a.Version())
+		case key == "group":
+			return imageName(a.Group())
+		case strings.HasPrefix(key, "property:"):
+			path := strings.TrimPrefix(key, "property:")
+			v := resolveProperty(a.Properties(), strings.Split(path, "."), nil)
+			if v == nil {
+				err = e.NewErrorf(ErrClassUser, msgImageTagPropertyNotFound, path, a.Name())
+				return ""
+			}
+			return fmt.Sprintf("%v", v)
+		}
+
+		err = e.NewErrorf(ErrClassUser, msgInvalidImageTagPattern, pattern, p)
+		return ""
+	})
+
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimLeft(ref, "/"), nil
+}
+
+// imageName converts s to a valid image name component.
+func imageName(s string) string {
+	return strings.Trim(invalidImageName.ReplaceAllString(strings.ToLower(s), "-"), "-")
+}
+
+// imageVersion converts s to a valid image tag.
+func imageVersion(s string) string {
+	t := strings.TrimLeft(invalidImageTag.ReplaceAllString(s, "-"), ".-")
+	if len(t) > maxImageTagLength {
+		t = t[:maxImageTagLength]
+	}
+	return t
+}
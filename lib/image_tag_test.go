@@ -0,0 +1,60 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImageTag(t *testing.T) {
+	mod := newTestModule("App_A", "app-a", "feature/x+1")
+	mod.metadata.spec.Group = "Payments"
+	mod.metadata.spec.Properties = map[string]interface{}{"image": map[string]interface{}{"repo": "team"}}
+
+	tag, err := mod.ImageTag("registry.io/", "")
+	check(t, err)
+	assert.Equal(t, "registry.io/app_a:feature-x-1", tag)
+
+	tag, err = mod.ImageTag("", "")
+	check(t, err)
+	assert.Equal(t, "app_a:feature-x-1", tag)
+
+	tag, err = mod.ImageTag("registry.io", "{registry}/{property:image.repo}/{group}-{name}:v{version}")
+	check(t, err)
+	assert.Equal(t, "registry.io/team/payments-app_a:vfeature-x-1", tag)
+}
+
+func TestImageTagErrors(t *testing.T) {
+	mod := newTestModule("app-a", "app-a", "a1")
+
+	_, err := mod.ImageTag("", "{name}:{sha}")
+	assert.EqualError(t, err, "Invalid image tag pattern '{name}:{sha}' (unknown placeholder {sha})")
+
+	_, err = mod.ImageTag("", "{property:image.repo}/{name}")
+	assert.EqualError(t, err, "Property 'image.repo' of module 'app-a' is not found")
+}
+
+func TestImageTagTemplateFunction(t *testing.T) {
+	m := &Manifest{Modules: Modules{newTestModule("app-a", "app-a", "a1")}}
+	output := new(bytes.Buffer)
+
+	err := processTemplate([]byte(`{{imageTag (module "app-a") "registry.io" ""}}`), m, output)
+	check(t, err)
+	assert.Equal(t, "registry.io/app-a:a1", output.String())
+}
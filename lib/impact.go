@@ -0,0 +1,165 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mbtproject/mbt/e"
+)
+
+// ImpactGraph is the subgraph of a diff based manifest showing how
+// the changed files impact a module through its dependencies.
+type ImpactGraph struct {
+	// Module the graph is created for.
+	Module string `json:"module"`
+	// Modules in the graph in topological order. That is, a module
+	// is listed after the modules it depends on.
+	Modules []string `json:"modules"`
+	// Files are the changed files of the modules in the graph keyed
+	// by module name.
+	Files map[string][]string `json:"files"`
+	// Forced are the modules forced to be included.
+	Forced []string `json:"forced,omitempty"`
+	// Edges are the dependencies in the graph.
+	Edges []*ImpactEdge `json:"edges"`
+}
+
+// ImpactEdge is a dependency of a module in an impact graph.
+type ImpactEdge struct {
+	// Dependency is the name of the module the dependent depends on.
+	Dependency string `json:"dependency"`
+	Dependent  string `json:"dependent"`
+}
+
+// ImpactGraph creates the impact graph of the named module. Graph
+// follows the reasons of selecting each module (see
+// Module.SelectionReasons) therefore the manifest must be diff based.
+func (m *Manifest) ImpactGraph(name string) (*ImpactGraph, error) {
+	index := m.Modules.indexByName()
+	target, ok := index[name]
+	if !ok {
+		return nil, e.NewErrorf(ErrClassUser, msgModuleNotImpacted, name)
+	}
+
+	g := &ImpactGraph{Module: name, Files: make(map[string][]string), Edges: make([]*ImpactEdge, 0)}
+	visited := make(map[string]bool)
+	var visit func(mod *Module)
+	visit = func(mod *Module) {
+		if visited[mod.Name()] {
+			return
+		}
+		visited[mod.Name()] = true
+
+		for _, r := range mod.reasons {
+			switch r.Kind {
+			case SelectionChanged, SelectionFileDependency:
+				if _, ok := g.Files[mod.Name()]; !ok {
+					g.Files[mod.Name()] = mod.ChangedFiles(m)
+				}
+			case SelectionDependency:
+				if d, ok := index[r.Detail]; ok {
+					g.Edges = append(g.Edges, &ImpactEdge{Dependency: d.Name(), Dependent: mod.Name()})
+					visit(d)
+				}
+			case SelectionForced:
+				g.Forced = append(g.Forced, mod.Name())
+			}
+		}
+	}
+	visit(target)
+
+	for _, mod := range m.Modules {
+		if visited[mod.Name()] {
+			g.Modules = append(g.Modules, mod.Name())
+		}
+	}
+	sort.Strings(g.Forced)
+	sort.Slice(g.Edges, func(i, j int) bool {
+		if g.Edges[i].Dependency == g.Edges[j].Dependency {
+			return g.Edges[i].Dependent < g.Edges[j].Dependent
+		}
+		return g.Edges[i].Dependency < g.Edges[j].Dependency
+	})
+
+	return g, nil
+}
+
+// SerializeAsDot converts the impact graph into a dot graph. Changed
+// files point to their modules and modules point to their dependents.
+// Module the graph is created for is highlighted in red.
+func (g *ImpactGraph) SerializeAsDot() string {
+	lines := []string{}
+	for _, mod := range g.Modules {
+		color := "powderblue"
+		if mod == g.Module {
+			color = "red"
+		}
+		lines = append(lines, fmt.Sprintf("\"%s\" [shape=box fillcolor=%s style=filled fontcolor=black];", mod, color))
+	}
+
+	for _, mod := range g.Modules {
+		for _, f := range g.Files[mod] {
+			lines = append(lines, fmt.Sprintf("\"%s\" [shape=note];", f))
+			lines = append(lines, fmt.Sprintf("\"%s\" -> \"%s\"", f, mod))
+		}
+	}
+
+	if len(g.Forced) > 0 {
+		lines = append(lines, "\"forced\" [shape=ellipse];")
+	}
+	for _, mod := range g.Forced {
+		lines = append(lines, fmt.Sprintf("\"forced\" -> \"%s\"", mod))
+	}
+
+	for _, edge := range g.Edges {
+		lines = append(lines, fmt.Sprintf("\"%s\" -> \"%s\"", edge.Dependency, edge.Dependent))
+	}
+
+	return fmt.Sprintf(`digraph mbt {
+  %s
+}`, strings.Join(lines, "\n  "))
+}
+
+// SerializeAsMermaid converts the impact graph into a mermaid flowchart
+// that can be rendered in markdown (e.g. GitHub and GitLab).
+func (g *ImpactGraph) SerializeAsMermaid() string {
+	ids := mermaidIDs{}
+	lines := []string{"graph TD"}
+	for _, mod := range g.Modules {
+		lines = ids.declare(lines, "m", mod)
+	}
+
+	for _, mod := range g.Modules {
+		for _, f := range g.Files[mod] {
+			lines = append(lines, fmt.Sprintf("  %s[/\"%s\"/] --> %s", ids.id("f", f), mermaidLabel(f), ids.id("m", mod)))
+		}
+	}
+
+	for _, mod := range g.Forced {
+		lines = append(lines, fmt.Sprintf("  forced((forced)) --> %s", ids.id("m", mod)))
+	}
+
+	for _, edge := range g.Edges {
+		lines = append(lines, fmt.Sprintf("  %s --> %s", ids.id("m", edge.Dependency), ids.id("m", edge.Dependent)))
+	}
+
+	lines = append(lines, "  classDef target fill:#f66,color:#000")
+	lines = append(lines, fmt.Sprintf("  class %s target", ids.id("m", g.Module)))
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"testing"
+
+	"github.com/mbtproject/mbt/e"
+	"github.com/stretchr/testify/assert"
+)
+
+func testImpactManifest() *Manifest {
+	a := newModule(newModuleMetadata("lib-a", "a1", &Spec{Name: "lib-a"}, nil), nil)
+	b := newModule(newModuleMetadata("lib-b", "b1", &Spec{Name: "lib-b"}, nil), Modules{a})
+	c := newModule(newModuleMetadata("app-c", "c1", &Spec{Name: "app-c"}, nil), Modules{b})
+	d := newModule(newModuleMetadata("app-d", "d1", &Spec{Name: "app-d"}, nil), nil)
+	mods := Modules{a, b, c, d}
+
+	m := &Manifest{Modules: mods, ChangedFiles: []string{"lib-a/main.go", "app-c/main.go"}}
+	builder := &stdManifestBuilder{options: &ManifestBuilderOptions{ForceInclude: []string{"app-d"}}}
+	builder.explainSelection(mods, Modules{a, c}, m.ChangedFiles)
+	return m
+}
+
+func TestImpactGraph(t *testing.T) {
+	g, err := testImpactManifest().ImpactGraph("app-c")
+	check(t, err)
+
+	assert.Equal(t, &ImpactGraph{
+		Module:  "app-c",
+		Modules: []string{"lib-a", "lib-b", "app-c"},
+		Files: map[string][]string{
+			"lib-a": {"lib-a/main.go"},
+			"app-c": {"app-c/main.go"},
+		},
+		Edges: []*ImpactEdge{
+			{Dependency: "lib-a", Dependent: "lib-b"},
+			{Dependency: "lib-b", Dependent: "app-c"},
+		},
+	}, g)
+}
+
+func TestImpactGraphOfForcedModule(t *testing.T) {
+	g, err := testImpactManifest().ImpactGraph("app-d")
+	check(t, err)
+
+	assert.Equal(t, []string{"app-d"}, g.Modules)
+	assert.Equal(t, []string{"app-d"}, g.Forced)
+	assert.Empty(t, g.Files)
+	assert.Empty(t, g.Edges)
+}
+
+func TestImpactGraphOfModuleNotImpacted(t *testing.T) {
+	_, err := testImpactManifest().ImpactGraph("app-x")
+
+	assert.EqualError(t, err, "Module 'app-x' is not impacted by the changes")
+	assert.Equal(t, ErrClassUser, (err.(*e.E)).Class())
+}
+
+func TestImpactGraphAsDot(t *testing.T) {
+	g, err := testImpactManifest().ImpactGraph("app-c")
+	check(t, err)
+
+	assert.Equal(t, `digraph mbt {
+  "lib-a" [shape=box fillcolor=powderblue style=filled fontcolor=black];
+  "lib-b" [shape=box fillcolor=powderblue style=filled fontcolor=black];
+  "app-c" [shape=box fillcolor=red style=filled fontcolor=black];
+  "lib-a/main.go" [shape=note];
+  "lib-a/main.go" -> "lib-a"
+  "app-c/main.go" [shape=note];
+  "app-c/main.go" -> "app-c"
+  "lib-a" -> "lib-b"
+  "lib-b" -> "app-c"
+}`, g.SerializeAsDot())
+}
+
+func TestImpactGraphAsMermaid(t *testing.T) {
+	g, err := testImpactManifest().ImpactGraph("app-c")
+	check(t, err)
+
+	assert.Equal(t, `graph TD
+  m0["lib-a"]
+  m1["lib-b"]
+  m2["app-c"]
+  f3[/"lib-a/main.go"/] --> m0
+  f4[/"app-c/main.go"/] --> m2
+  m0 --> m1
+  m1 --> m2
+  classDef target fill:#f66,color:#000
+  class m2 target`, g.SerializeAsMermaid())
+
+	g, err = testImpactManifest().ImpactGraph("app-d")
+	check(t, err)
+
+	assert.Equal(t, `graph TD
+  m0["app-d"]
+  forced((forced)) --> m0
+  classDef target fill:#f66,color:#000
+  class m0 target`, g.SerializeAsMermaid())
+}
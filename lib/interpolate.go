@@ -0,0 +1,75 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"os"
+	"regexp"
+	"strings"
+)
+
+// envReference matches ${NAME} and ${NAME:-default}.
+var envReference = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+
+// envAllowed returns true if name matches one of the patterns.
+// Patterns are names or prefixes followed by * (e.g. JAVA_*).
+func envAllowed(name string, patterns []string) bool {
+	for _, p := range patterns {
+		if p == name || (strings.HasSuffix(p, "*") && strings.HasPrefix(name, strings.TrimSuffix(p, "*"))) {
+			return true
+		}
+	}
+	return false
+}
+
+// interpolateEnv expands the references to allowed environment variables
+// in s. Default value of a reference is used when the variable is unset
+// or empty. References to other variables are left intact so that they
+// can be expanded by the command itself (e.g. sh -c).
+func interpolateEnv(s string, allowed []string) string {
+	if len(allowed) == 0 {
+		return s
+	}
+
+	return envReference.ReplaceAllStringFunc(s, func(ref string) string {
+		m := envReference.FindStringSubmatch(ref)
+		name, hasDefault, def := m[1], m[2] != "", m[3]
+		if !envAllowed(name, allowed) {
+			return ref
+		}
+
+		v := os.Getenv(name)
+		if v == "" && hasDefault {
+			return def
+		}
+		return v
+	})
+}
+
+// interpolateCmd expands the references to allowed environment variables
+// in a command and its arguments.
+func interpolateCmd(command string, args []string, allowed []string) (string, []string) {
+	if len(allowed) == 0 {
+		return command, args
+	}
+
+	expanded := make([]string, len(args))
+	for i, a := range args {
+		expanded[i] = interpolateEnv(a, allowed)
+	}
+
+	return interpolateEnv(command, allowed), expanded
+}
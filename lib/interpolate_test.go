@@ -0,0 +1,55 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInterpolateEnv(t *testing.T) {
+	os.Setenv("MBT_TEST_HOME", "/opt/tool")
+	os.Setenv("MBT_TEST_EMPTY", "")
+	os.Setenv("MBT_SECRET", "secret")
+	defer os.Unsetenv("MBT_TEST_HOME")
+	defer os.Unsetenv("MBT_TEST_EMPTY")
+	defer os.Unsetenv("MBT_SECRET")
+
+	allowed := []string{"MBT_TEST_*", "MBT_UNSET"}
+
+	assert.Equal(t, "/opt/tool/bin", interpolateEnv("${MBT_TEST_HOME}/bin", allowed))
+	assert.Equal(t, "fallback", interpolateEnv("${MBT_TEST_EMPTY:-fallback}", allowed))
+	assert.Equal(t, "1.15", interpolateEnv("${MBT_UNSET:-1.15}", allowed))
+	assert.Equal(t, "", interpolateEnv("${MBT_UNSET}", allowed))
+	assert.Equal(t, "/opt/tool:${MBT_SECRET}", interpolateEnv("${MBT_TEST_HOME:-x}:${MBT_SECRET}", allowed))
+	assert.Equal(t, "$MBT_TEST_HOME", interpolateEnv("$MBT_TEST_HOME", allowed))
+	assert.Equal(t, "${MBT_TEST_HOME}", interpolateEnv("${MBT_TEST_HOME}", nil))
+}
+
+func TestInterpolateCmd(t *testing.T) {
+	os.Setenv("MBT_TEST_HOME", "/opt/tool")
+	defer os.Unsetenv("MBT_TEST_HOME")
+
+	cmd, args := interpolateCmd("${MBT_TEST_HOME}/bin/tool", []string{"-c", "${MBT_TEST_HOME}"}, []string{"MBT_TEST_HOME"})
+	assert.Equal(t, "/opt/tool/bin/tool", cmd)
+	assert.Equal(t, []string{"-c", "/opt/tool"}, args)
+
+	original := []string{"${MBT_TEST_HOME}"}
+	_, args = interpolateCmd("tool", original, nil)
+	assert.Equal(t, []string{"${MBT_TEST_HOME}"}, args)
+}
@@ -0,0 +1,107 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/mbtproject/mbt/e"
+)
+
+// readJSONFile decodes the json file in path into v.
+// v is left as it is if the file does not exist.
+func readJSONFile(path string, v interface{}) error {
+	c, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err == nil {
+		err = json.Unmarshal(c, v)
+	}
+	if err != nil {
+		return e.Wrapf(ErrClassUser, err, msgFailedReadFile, path)
+	}
+
+	return nil
+}
+
+// writeJSONFile encodes v into the json file in path.
+// Content is written to a temporary file first and renamed so that
+// the file is never left partially written (e.g. when mbt is
+// terminated while writing it).
+func writeJSONFile(path string, v interface{}) error {
+	c, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return e.Wrap(ErrClassInternal, err)
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return e.Wrapf(ErrClassUser, err, msgFailedWriteFile, path)
+	}
+
+	f, err := ioutil.TempFile(filepath.Dir(path), ".tmp-")
+	if err != nil {
+		return e.Wrapf(ErrClassUser, err, msgFailedWriteFile, path)
+	}
+
+	_, err = f.Write(c)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(f.Name(), 0644)
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return e.Wrapf(ErrClassUser, err, msgFailedWriteFile, path)
+	}
+
+	return nil
+}
+
+// updateJSONFile reads the json file in path into v, invokes update
+// and writes v back to the file. The file is locked during the update
+// so that concurrent updates from other processes (e.g. CI jobs
+// sharing the file) are not lost.
+func updateJSONFile(path string, v interface{}, update func() error) error {
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return e.Wrapf(ErrClassUser, err, msgFailedWriteFile, path)
+	}
+
+	unlock, err := lockFile(path + ".lock")
+	if err != nil {
+		return e.Wrapf(ErrClassUser, err, msgFailedLockFile, path)
+	}
+	defer unlock()
+
+	if err := readJSONFile(path, v); err != nil {
+		return err
+	}
+
+	if err := update(); err != nil {
+		return err
+	}
+
+	return writeJSONFile(path, v)
+}
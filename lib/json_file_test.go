@@ -0,0 +1,79 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"io/ioutil"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testCounterFile struct {
+	Count int `json:"count"`
+}
+
+func TestReadMissingJSONFile(t *testing.T) {
+	clean()
+	f := &testCounterFile{Count: 1}
+
+	check(t, readJSONFile(".tmp/missing.json", f))
+	assert.Equal(t, 1, f.Count)
+}
+
+func TestWriteJSONFile(t *testing.T) {
+	clean()
+	check(t, writeJSONFile(".tmp/store/counter.json", &testCounterFile{Count: 2}))
+
+	f := &testCounterFile{}
+	check(t, readJSONFile(".tmp/store/counter.json", f))
+	assert.Equal(t, 2, f.Count)
+
+	entries, err := ioutil.ReadDir(".tmp/store")
+	check(t, err)
+	assert.Len(t, entries, 1)
+}
+
+func TestReadInvalidJSONFile(t *testing.T) {
+	clean()
+	writeAuditFile(t, ".tmp/counter.json", "{")
+
+	err := readJSONFile(".tmp/counter.json", &testCounterFile{})
+	assert.EqualError(t, err, "Failed to read file '.tmp/counter.json'")
+}
+
+func TestConcurrentJSONFileUpdates(t *testing.T) {
+	clean()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			f := &testCounterFile{}
+			check(t, updateJSONFile(".tmp/counter.json", f, func() error {
+				f.Count++
+				return nil
+			}))
+		}()
+	}
+	wg.Wait()
+
+	f := &testCounterFile{}
+	check(t, readJSONFile(".tmp/counter.json", f))
+	assert.Equal(t, 20, f.Count)
+}
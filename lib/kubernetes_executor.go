@@ -0,0 +1,286 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os/exec"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/mbtproject/mbt/e"
+)
+
+const (
+	defaultKubernetesWorkDir = "/workspace"
+	defaultKubernetesTimeout = time.Hour
+	kubernetesPollInterval   = 2 * time.Second
+	kubernetesGitImage       = "alpine/git"
+)
+
+var invalidJobNameChars = regexp.MustCompile("[^a-z0-9-]+")
+
+type kubernetesExecutor struct {
+	Log     Log
+	Options *KubernetesOptions
+}
+
+// NewKubernetesExecutor creates an Executor that runs the commands
+// as Kubernetes Jobs.
+// Jobs are created and monitored with kubectl. Therefore, it should be
+// available and configured to access the target cluster.
+func NewKubernetesExecutor(log Log, options *KubernetesOptions) Executor {
+	return &kubernetesExecutor{Log: log, Options: options}
+}
+
+func (x *kubernetesExecutor) Prepare(ctx *ExecContext) error {
+	if err := RequireNetwork("kubernetes executor"); err != nil {
+		return err
+	}
+
+	name := kubernetesJobName(ctx.Module, time.Now())
+	spec, err := json.Marshal(x.jobSpec(name, ctx))
+	if err != nil {
+		return e.Wrap(ErrClassInternal, err)
+	}
+
+	err = x.kubectl(bytes.NewReader(spec), nil, ctx.Options.Stderr, "create", "-f", "-")
+	if err != nil {
+		return e.Wrapf(ErrClassUser, err, msgFailedKubernetesJob, name)
+	}
+
+	x.Log.Debug("Created job %s for module %s", name, ctx.Module.Name())
+	ctx.State = name
+	return nil
+}
+
+func (x *kubernetesExecutor) Run(ctx *ExecContext) error {
+	name := ctx.State.(string)
+
+	// Stream the logs of the job pod. kubectl waits for the pod to
+	// start before it begins streaming.
+	err := x.kubectl(nil, ctx.Options.Stdout, ctx.Options.Stderr, "logs", "-f", fmt.Sprintf("job/%s", name), fmt.Sprintf("--pod-running-timeout=%s", x.timeout(ctx)))
+	if err != nil {
+		x.Log.Debug("Failed to stream the logs of job %s - %v", name, err)
+	}
+
+	return x.waitForCompletion(name, x.timeout(ctx))
+}
+
+func (x *kubernetesExecutor) Collect(ctx *ExecContext, runErr error) error {
+	if !x.Options.KeepJobs {
+		x.deleteJob(ctx.State.(string))
+	}
+	return nil
+}
+
+func (x *kubernetesExecutor) waitForCompletion(name string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		out := new(bytes.Buffer)
+		err := x.kubectl(nil, out, nil, "get", fmt.Sprintf("job/%s", name), "-o", "jsonpath={.status.succeeded},{.status.failed}")
+		if err != nil {
+			return e.Wrapf(ErrClassUser, err, msgFailedKubernetesJob, name)
+		}
+
+		status := strings.Split(strings.TrimSpace(out.String()), ",")
+		if status[0] != "" && status[0] != "0" {
+			return nil
+		}
+
+		if len(status) > 1 && status[1] != "" && status[1] != "0" {
+			return e.NewErrorf(ErrClassUser, msgFailedKubernetesJob, name)
+		}
+
+		if time.Now().After(deadline) {
+			return e.NewErrorf(ErrClassUser, msgKubernetesJobTimeout, name)
+		}
+
+		time.Sleep(kubernetesPollInterval)
+	}
+}
+
+func (x *kubernetesExecutor) deleteJob(name string) {
+	err := x.kubectl(nil, nil, nil, "delete", fmt.Sprintf("job/%s", name), "--wait=false")
+	if err != nil {
+		x.Log.Warnf("Failed to delete job %s - %v", name, err)
+	}
+}
+
+func (x *kubernetesExecutor) kubectl(stdin io.Reader, stdout, stderr io.Writer, args ...string) error {
+	kubectl := x.Options.Kubectl
+	if kubectl == "" {
+		kubectl = "kubectl"
+	}
+
+	if x.Options.Context != "" {
+		args = append([]string{"--context", x.Options.Context}, args...)
+	}
+
+	if x.Options.Namespace != "" {
+		args = append([]string{"--namespace", x.Options.Namespace}, args...)
+	}
+
+	cmd := exec.Command(kubectl, args...)
+	cmd.Stdin = stdin
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	return cmd.Run()
+}
+
+// timeout returns the maximum amount of time to wait for a job.
+// Command timeout takes precedence over the timeout specified in options.
+func (x *kubernetesExecutor) timeout(ctx *ExecContext) time.Duration {
+	if ctx.Options != nil && ctx.Options.Timeout > 0 {
+		return ctx.Options.Timeout
+	}
+	if x.Options.Timeout <= 0 {
+		return defaultKubernetesTimeout
+	}
+	return x.Options.Timeout
+}
+
+func (x *kubernetesExecutor) workDir() string {
+	if x.Options.WorkDir == "" {
+		return defaultKubernetesWorkDir
+	}
+	return x.Options.WorkDir
+}
+
+func (x *kubernetesExecutor) jobSpec(name string, ctx *ExecContext) map[string]interface{} {
+	manifest, module := ctx.Manifest, ctx.Module
+	env := make([]map[string]interface{}, 0)
+	for _, v := range ctx.Environment(x.workDir()) {
+		kv := strings.SplitN(v, "=", 2)
+		env = append(env, map[string]interface{}{"name": kv[0], "value": kv[1]})
+	}
+
+	container := map[string]interface{}{
+		"name":       "build",
+		"image":      x.Options.Image,
+		"command":    append([]string{ctx.Command}, ctx.Args...),
+		"workingDir": path.Join(x.workDir(), ctx.Dir()),
+		"env":        env,
+	}
+
+	// Resource hints in module spec take precedence over the
+	// executor defaults.
+	resources := make(map[string]interface{})
+	hints := module.Resources()
+	if hints.CPU != "" {
+		resources["cpu"] = hints.CPU
+	} else if x.Options.CPU != "" {
+		resources["cpu"] = x.Options.CPU
+	}
+	if hints.Memory != "" {
+		resources["memory"] = hints.Memory
+	} else if x.Options.Memory != "" {
+		resources["memory"] = x.Options.Memory
+	}
+	if len(resources) > 0 {
+		container["resources"] = map[string]interface{}{
+			"requests": resources,
+			"limits":   resources,
+		}
+	}
+
+	podSpec := map[string]interface{}{
+		"restartPolicy": "Never",
+	}
+
+	if x.Options.ServiceAccount != "" {
+		podSpec["serviceAccountName"] = x.Options.ServiceAccount
+	}
+
+	if x.Options.RepoURL != "" {
+		mount := []map[string]interface{}{{"name": "workspace", "mountPath": x.workDir()}}
+		container["volumeMounts"] = mount
+		podSpec["volumes"] = []map[string]interface{}{{"name": "workspace", "emptyDir": map[string]interface{}{}}}
+		// Init containers run in order. git is executed without a shell
+		// so that the repository URL is not interpreted.
+		podSpec["initContainers"] = []map[string]interface{}{
+			{
+				"name":         "clone",
+				"image":        kubernetesGitImage,
+				"command":      []string{"git", "clone", "--", x.Options.RepoURL, "."},
+				"workingDir":   x.workDir(),
+				"volumeMounts": mount,
+			},
+			{
+				"name":         "checkout",
+				"image":        kubernetesGitImage,
+				"command":      []string{"git", "checkout", "--quiet", manifest.Sha},
+				"workingDir":   x.workDir(),
+				"volumeMounts": mount,
+			},
+		}
+	}
+
+	podSpec["containers"] = []map[string]interface{}{container}
+
+	labels := map[string]interface{}{
+		"app.kubernetes.io/managed-by": "mbt",
+		"mbt/module":                   kubernetesLabelValue(module.Name()),
+	}
+
+	return map[string]interface{}{
+		"apiVersion": "batch/v1",
+		"kind":       "Job",
+		"metadata": map[string]interface{}{
+			"name":   name,
+			"labels": labels,
+		},
+		"spec": map[string]interface{}{
+			"backoffLimit":          0,
+			"activeDeadlineSeconds": int64(x.timeout(ctx).Seconds()),
+			"template": map[string]interface{}{
+				"metadata": map[string]interface{}{"labels": labels},
+				"spec":     podSpec,
+			},
+		},
+	}
+}
+
+// kubernetesJobName creates a unique job name for a module that
+// is a valid DNS-1123 label.
+func kubernetesJobName(module *Module, now time.Time) string {
+	version := module.Version()
+	if len(version) > 8 {
+		version = version[:8]
+	}
+
+	suffix := fmt.Sprintf("-%s-%x", version, now.UnixNano())
+	prefix := "mbt-" + kubernetesLabelValue(module.Name())
+	if len(prefix)+len(suffix) > 63 {
+		prefix = strings.TrimRight(prefix[:63-len(suffix)], "-")
+	}
+
+	return prefix + suffix
+}
+
+func kubernetesLabelValue(v string) string {
+	v = invalidJobNameChars.ReplaceAllString(strings.ToLower(v), "-")
+	if len(v) > 63 {
+		v = v[:63]
+	}
+	return strings.Trim(v, "-")
+}
@@ -0,0 +1,85 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKubernetesJobName(t *testing.T) {
+	mod := newTestModule("App_A", "app-a", "4b9a0d4f2fd0bd3c7d0b3a1e1f4c1a2b3c4d5e6f")
+	name := kubernetesJobName(mod, time.Unix(0, 255))
+
+	assert.Equal(t, "mbt-app-a-4b9a0d4f-ff", name)
+}
+
+func TestKubernetesJobNameForLongModuleName(t *testing.T) {
+	mod := newTestModule(strings.Repeat("a", 100), "app-a", "local")
+	name := kubernetesJobName(mod, time.Unix(0, 255))
+
+	assert.Len(t, name, 63)
+	assert.True(t, strings.HasSuffix(name, "-local-ff"))
+}
+
+func TestKubernetesJobSpec(t *testing.T) {
+	mod := newTestModule("app-a", "app-a", "abc")
+	x := &kubernetesExecutor{Options: &KubernetesOptions{Image: "golang", CPU: "1", RepoURL: "https://example.com/repo.git"}}
+	spec := x.jobSpec("job-a", &ExecContext{Manifest: &Manifest{Dir: "/repo", Sha: "sha"}, Module: mod, Command: "./build.sh", Args: []string{"a"}})
+
+	podSpec := spec["spec"].(map[string]interface{})["template"].(map[string]interface{})["spec"].(map[string]interface{})
+	container := podSpec["containers"].([]map[string]interface{})[0]
+
+	assert.Equal(t, "job-a", spec["metadata"].(map[string]interface{})["name"])
+	assert.Equal(t, "golang", container["image"])
+	assert.Equal(t, []string{"./build.sh", "a"}, container["command"])
+	assert.Equal(t, "/workspace/app-a", container["workingDir"])
+	assert.Contains(t, container["env"], map[string]interface{}{"name": "MBT_REPO_PATH", "value": "/workspace"})
+	assert.Contains(t, container["env"], map[string]interface{}{"name": "MBT_BUILD_COMMIT", "value": "sha"})
+	assert.Equal(t, map[string]interface{}{"cpu": "1"}, container["resources"].(map[string]interface{})["limits"])
+
+	init := podSpec["initContainers"].([]map[string]interface{})
+	assert.Len(t, init, 2)
+	assert.Equal(t, []string{"git", "clone", "--", "https://example.com/repo.git", "."}, init[0]["command"])
+	assert.Equal(t, []string{"git", "checkout", "--quiet", "sha"}, init[1]["command"])
+}
+
+func TestKubernetesJobSpecDoesNotInterpretRepoURL(t *testing.T) {
+	mod := newTestModule("app-a", "app-a", "abc")
+	url := "https://example.com/repo.git; rm -rf / #"
+	x := &kubernetesExecutor{Options: &KubernetesOptions{Image: "golang", RepoURL: url}}
+	spec := x.jobSpec("job-a", &ExecContext{Manifest: &Manifest{Dir: "/repo", Sha: "sha"}, Module: mod, Command: "./build.sh"})
+
+	podSpec := spec["spec"].(map[string]interface{})["template"].(map[string]interface{})["spec"].(map[string]interface{})
+	init := podSpec["initContainers"].([]map[string]interface{})
+
+	assert.Equal(t, []string{"git", "clone", "--", url, "."}, init[0]["command"])
+}
+
+func TestKubernetesJobSpecWithModuleResources(t *testing.T) {
+	mod := newTestModule("app-a", "app-a", "abc")
+	mod.metadata.spec.Resources = &Resources{CPU: "4", Memory: "8Gi"}
+	x := &kubernetesExecutor{Options: &KubernetesOptions{Image: "golang", CPU: "1"}}
+	spec := x.jobSpec("job-a", &ExecContext{Manifest: &Manifest{Dir: "/repo", Sha: "sha"}, Module: mod, Command: "./build.sh"})
+
+	podSpec := spec["spec"].(map[string]interface{})["template"].(map[string]interface{})["spec"].(map[string]interface{})
+	container := podSpec["containers"].([]map[string]interface{})[0]
+
+	assert.Equal(t, map[string]interface{}{"cpu": "4", "memory": "8Gi"}, container["resources"].(map[string]interface{})["requests"])
+}
@@ -0,0 +1,270 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	yaml "github.com/go-yaml/yaml"
+	"github.com/mbtproject/mbt/e"
+)
+
+// KubernetesManifestOptions describes how Kubernetes manifests of
+// modules are generated.
+type KubernetesManifestOptions struct {
+	// Registry is prefixed to the module name to form the image name
+	// of modules that do not specify kubernetes.image property.
+	Registry string
+	// Namespace of the generated resources.
+	// Resources are not bound to a namespace if not specified.
+	Namespace string
+}
+
+// kubernetesApp is the deployment configuration of a module
+// specified in its kubernetes property.
+type kubernetesApp struct {
+	mod         *Module
+	name        string
+	image       string
+	replicas    int
+	port        int
+	servicePort int
+	serviceType string
+	env         map[string]interface{}
+}
+
+// kubernetesApps returns the deployment configuration of modules
+// with kubernetes property.
+func (mods Modules) kubernetesApps(options *KubernetesManifestOptions) ([]*kubernetesApp, error) {
+	apps := make([]*kubernetesApp, 0)
+	for _, m := range mods {
+		p, ok := m.Properties()["kubernetes"]
+		if !ok {
+			continue
+		}
+
+		props, ok := p.(map[string]interface{})
+		if !ok {
+			return nil, e.NewErrorf(ErrClassUser, msgInvalidProperty, "kubernetes", m.Name(), "expected a dictionary")
+		}
+
+		app := &kubernetesApp{
+			mod:      m,
+			name:     kubernetesLabelValue(m.Name()),
+			image:    m.Name(),
+			replicas: 1,
+			env:      map[string]interface{}{},
+		}
+
+		if options.Registry != "" {
+			app.image = strings.TrimSuffix(options.Registry, "/") + "/" + m.Name()
+		}
+
+		var err error
+		for k, v := range props {
+			switch k {
+			case "image":
+				app.image, err = stringProperty(v)
+			case "replicas":
+				app.replicas, err = intProperty(v)
+			case "port":
+				app.port, err = intProperty(v)
+			case "servicePort":
+				app.servicePort, err = intProperty(v)
+			case "serviceType":
+				app.serviceType, err = stringProperty(v)
+			case "env":
+				var ok bool
+				if app.env, ok = v.(map[string]interface{}); !ok {
+					err = fmt.Errorf("expected a dictionary")
+				}
+			}
+
+			if err != nil {
+				return nil, e.NewErrorf(ErrClassUser, msgInvalidProperty, "kubernetes."+k, m.Name(), err)
+			}
+		}
+
+		if app.servicePort == 0 {
+			app.servicePort = app.port
+		}
+
+		apps = append(apps, app)
+	}
+
+	return apps, nil
+}
+
+func stringProperty(v interface{}) (string, error) {
+	s, ok := v.(string)
+	if !ok {
+		return "", fmt.Errorf("expected a string")
+	}
+	return s, nil
+}
+
+func intProperty(v interface{}) (int, error) {
+	i, ok := v.(int)
+	if !ok {
+		return 0, fmt.Errorf("expected an integer")
+	}
+	return i, nil
+}
+
+// SerializeAsKubernetes generates Kubernetes Deployment and Service
+// manifests for the modules with kubernetes property.
+// Image tag of each Deployment is set to the module version.
+// Service is generated only if kubernetes.port property is specified.
+func (mods Modules) SerializeAsKubernetes(options *KubernetesManifestOptions) (string, error) {
+	apps, err := mods.kubernetesApps(options)
+	if err != nil {
+		return "", err
+	}
+
+	docs := make([]string, 0)
+	for _, app := range apps {
+		resources := []yaml.MapSlice{app.deployment(options)}
+		if app.port > 0 {
+			resources = append(resources, app.service(options))
+		}
+
+		for _, r := range resources {
+			b, err := yaml.Marshal(r)
+			if err != nil {
+				return "", e.Wrap(ErrClassInternal, err)
+			}
+			docs = append(docs, string(b))
+		}
+	}
+
+	return strings.Join(docs, "---\n"), nil
+}
+
+// SerializeAsKustomization generates a kustomization overriding the
+// image tags of the modules with kubernetes property with their
+// versions.
+func (mods Modules) SerializeAsKustomization(options *KubernetesManifestOptions) (string, error) {
+	apps, err := mods.kubernetesApps(options)
+	if err != nil {
+		return "", err
+	}
+
+	images := make([]yaml.MapSlice, 0, len(apps))
+	for _, app := range apps {
+		images = append(images, yaml.MapSlice{
+			{Key: "name", Value: app.image},
+			{Key: "newTag", Value: app.mod.Version()},
+		})
+	}
+
+	k := yaml.MapSlice{
+		{Key: "apiVersion", Value: "kustomize.config.k8s.io/v1beta1"},
+		{Key: "kind", Value: "Kustomization"},
+	}
+	if options.Namespace != "" {
+		k = append(k, yaml.MapItem{Key: "namespace", Value: options.Namespace})
+	}
+	k = append(k, yaml.MapItem{Key: "images", Value: images})
+
+	b, err := yaml.Marshal(k)
+	if err != nil {
+		return "", e.Wrap(ErrClassInternal, err)
+	}
+
+	return string(b), nil
+}
+
+func (app *kubernetesApp) metadata(options *KubernetesManifestOptions) yaml.MapSlice {
+	m := yaml.MapSlice{{Key: "name", Value: app.name}}
+	if options.Namespace != "" {
+		m = append(m, yaml.MapItem{Key: "namespace", Value: options.Namespace})
+	}
+
+	return append(m, yaml.MapItem{Key: "labels", Value: yaml.MapSlice{
+		{Key: "app", Value: app.name},
+		{Key: "app.kubernetes.io/version", Value: kubernetesLabelValue(app.mod.Version())},
+	}})
+}
+
+func (app *kubernetesApp) deployment(options *KubernetesManifestOptions) yaml.MapSlice {
+	container := yaml.MapSlice{
+		{Key: "name", Value: app.name},
+		{Key: "image", Value: fmt.Sprintf("%s:%s", app.image, app.mod.Version())},
+	}
+
+	if app.port > 0 {
+		container = append(container, yaml.MapItem{Key: "ports", Value: []yaml.MapSlice{
+			{{Key: "containerPort", Value: app.port}},
+		}})
+	}
+
+	if len(app.env) > 0 {
+		keys := make([]string, 0, len(app.env))
+		for k := range app.env {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		env := make([]yaml.MapSlice, 0, len(keys))
+		for _, k := range keys {
+			env = append(env, yaml.MapSlice{
+				{Key: "name", Value: k},
+				{Key: "value", Value: fmt.Sprintf("%v", app.env[k])},
+			})
+		}
+		container = append(container, yaml.MapItem{Key: "env", Value: env})
+	}
+
+	labels := yaml.MapSlice{{Key: "app", Value: app.name}}
+	return yaml.MapSlice{
+		{Key: "apiVersion", Value: "apps/v1"},
+		{Key: "kind", Value: "Deployment"},
+		{Key: "metadata", Value: app.metadata(options)},
+		{Key: "spec", Value: yaml.MapSlice{
+			{Key: "replicas", Value: app.replicas},
+			{Key: "selector", Value: yaml.MapSlice{{Key: "matchLabels", Value: labels}}},
+			{Key: "template", Value: yaml.MapSlice{
+				{Key: "metadata", Value: yaml.MapSlice{{Key: "labels", Value: labels}}},
+				{Key: "spec", Value: yaml.MapSlice{
+					{Key: "containers", Value: []yaml.MapSlice{container}},
+				}},
+			}},
+		}},
+	}
+}
+
+func (app *kubernetesApp) service(options *KubernetesManifestOptions) yaml.MapSlice {
+	spec := yaml.MapSlice{}
+	if app.serviceType != "" {
+		spec = append(spec, yaml.MapItem{Key: "type", Value: app.serviceType})
+	}
+	spec = append(spec,
+		yaml.MapItem{Key: "selector", Value: yaml.MapSlice{{Key: "app", Value: app.name}}},
+		yaml.MapItem{Key: "ports", Value: []yaml.MapSlice{{
+			{Key: "port", Value: app.servicePort},
+			{Key: "targetPort", Value: app.port},
+		}}},
+	)
+
+	return yaml.MapSlice{
+		{Key: "apiVersion", Value: "v1"},
+		{Key: "kind", Value: "Service"},
+		{Key: "metadata", Value: app.metadata(options)},
+		{Key: "spec", Value: spec},
+	}
+}
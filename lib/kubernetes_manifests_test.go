@@ -0,0 +1,139 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSerializeAsKubernetes(t *testing.T) {
+	a := newTestModule("app-a", "app-a", "a1")
+	a.metadata.spec.Properties = map[string]interface{}{
+		"kubernetes": map[string]interface{}{
+			"port":     8080,
+			"replicas": 2,
+			"env":      map[string]interface{}{"MODE": "prod", "DEBUG": false},
+		},
+	}
+	c := newTestModule("App_C", "App_C", "c1")
+	c.metadata.spec.Properties = map[string]interface{}{
+		"kubernetes": map[string]interface{}{"image": "example.com/c"},
+	}
+	mods := Modules{a, newTestModule("lib-b", "lib-b", "b1"), c}
+
+	s, err := mods.SerializeAsKubernetes(&KubernetesManifestOptions{Registry: "registry.local/", Namespace: "prod"})
+	check(t, err)
+
+	assert.Equal(t, `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app-a
+  namespace: prod
+  labels:
+    app: app-a
+    app.kubernetes.io/version: a1
+spec:
+  replicas: 2
+  selector:
+    matchLabels:
+      app: app-a
+  template:
+    metadata:
+      labels:
+        app: app-a
+    spec:
+      containers:
+      - name: app-a
+        image: registry.local/app-a:a1
+        ports:
+        - containerPort: 8080
+        env:
+        - name: DEBUG
+          value: "false"
+        - name: MODE
+          value: prod
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: app-a
+  namespace: prod
+  labels:
+    app: app-a
+    app.kubernetes.io/version: a1
+spec:
+  selector:
+    app: app-a
+  ports:
+  - port: 8080
+    targetPort: 8080
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app-c
+  namespace: prod
+  labels:
+    app: app-c
+    app.kubernetes.io/version: c1
+spec:
+  replicas: 1
+  selector:
+    matchLabels:
+      app: app-c
+  template:
+    metadata:
+      labels:
+        app: app-c
+    spec:
+      containers:
+      - name: app-c
+        image: example.com/c:c1
+`, s)
+}
+
+func TestSerializeAsKustomization(t *testing.T) {
+	a := newTestModule("app-a", "app-a", "a1")
+	a.metadata.spec.Properties = map[string]interface{}{"kubernetes": map[string]interface{}{}}
+	b := newTestModule("app-b", "app-b", "b1")
+	b.metadata.spec.Properties = map[string]interface{}{"kubernetes": map[string]interface{}{"image": "example.com/b"}}
+	mods := Modules{a, b}
+
+	s, err := mods.SerializeAsKustomization(&KubernetesManifestOptions{Registry: "registry.local"})
+	check(t, err)
+
+	assert.Equal(t, `apiVersion: kustomize.config.k8s.io/v1beta1
+kind: Kustomization
+images:
+- name: registry.local/app-a
+  newTag: a1
+- name: example.com/b
+  newTag: b1
+`, s)
+}
+
+func TestInvalidKubernetesProperty(t *testing.T) {
+	mod := newTestModule("app-a", "app-a", "a1")
+	mod.metadata.spec.Properties = map[string]interface{}{"kubernetes": "yes"}
+	_, err := Modules{mod}.SerializeAsKubernetes(&KubernetesManifestOptions{})
+	assert.EqualError(t, err, "Invalid property 'kubernetes' in module 'app-a': expected a dictionary")
+
+	mod.metadata.spec.Properties = map[string]interface{}{"kubernetes": map[string]interface{}{"port": "http"}}
+	_, err = Modules{mod}.SerializeAsKubernetes(&KubernetesManifestOptions{})
+	assert.EqualError(t, err, "Invalid property 'kubernetes.port' in module 'app-a': expected an integer")
+}
@@ -0,0 +1,73 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import "sync"
+
+// lazyCommitInfo loads a CommitInfo on first access.
+type lazyCommitInfo struct {
+	once sync.Once
+	load func() (*CommitInfo, error)
+	info *CommitInfo
+}
+
+func (l *lazyCommitInfo) get() *CommitInfo {
+	l.once.Do(func() {
+		// Errors are ignored since the last commit is informational.
+		l.info, _ = l.load()
+	})
+	return l.info
+}
+
+// lastCommit walks the first parent history of commit and returns
+// the oldest commit in which the tree at path is the same as the
+// tree in commit (i.e. the last commit changing path).
+func lastCommit(repo Repo, commit Commit, path string) (*CommitInfo, error) {
+	info, err := repo.CommitInfo(commit)
+	if err != nil {
+		return nil, err
+	}
+
+	// Modules in the root directory change in every commit.
+	if path == "" {
+		return info, nil
+	}
+
+	id, err := repo.EntryID(commit, path)
+	if err != nil {
+		return nil, err
+	}
+
+	for len(info.Parents) > 0 {
+		parent, err := repo.GetCommit(info.Parents[0])
+		if err != nil {
+			return nil, err
+		}
+
+		// Path does not exist in the parent when the module is added.
+		parentID, err := repo.EntryID(parent, path)
+		if err != nil || parentID != id {
+			return info, nil
+		}
+
+		info, err = repo.CommitInfo(parent)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return info, nil
+}
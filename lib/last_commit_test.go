@@ -0,0 +1,77 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestManifestCommit(t *testing.T) {
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+
+	check(t, repo.InitModule("app-a"))
+	check(t, repo.Commit("first\n\nbody"))
+
+	m, err := NewWorld(t, ".tmp/repo").System.ManifestByCurrentBranch()
+	check(t, err)
+
+	assert.Equal(t, repo.LastCommit.String(), m.Commit.Sha)
+	assert.Equal(t, "first", m.Commit.Subject)
+	assert.NotEmpty(t, m.Commit.Author)
+	assert.Empty(t, m.Commit.Parents)
+}
+
+func TestModuleLastCommit(t *testing.T) {
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+
+	check(t, repo.InitModule("app-a"))
+	check(t, repo.InitModule("app-b"))
+	check(t, repo.Commit("first"))
+	first := repo.LastCommit.String()
+
+	check(t, repo.WriteContent("app-a/foo", "hello"))
+	check(t, repo.Commit("second"))
+	second := repo.LastCommit.String()
+
+	check(t, repo.WriteContent("readme.md", "hello"))
+	check(t, repo.Commit("third"))
+
+	m, err := NewWorld(t, ".tmp/repo").System.ManifestByCurrentBranch()
+	check(t, err)
+	mods := m.Modules.indexByName()
+
+	assert.Equal(t, second, mods["app-a"].LastCommit().Sha)
+	assert.Equal(t, "second", mods["app-a"].LastCommit().Subject)
+	assert.Equal(t, first, mods["app-b"].LastCommit().Sha)
+}
+
+func TestLastCommitOfWorkspaceModule(t *testing.T) {
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+
+	check(t, repo.InitModule("app-a"))
+	check(t, repo.Commit("first"))
+
+	m, err := NewWorld(t, ".tmp/repo").System.ManifestByWorkspace()
+	check(t, err)
+
+	assert.Nil(t, m.Commit)
+	assert.Nil(t, m.Modules[0].LastCommit())
+}
@@ -0,0 +1,198 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	git "github.com/libgit2/git2go/v28"
+	"github.com/mbtproject/mbt/e"
+)
+
+// Git LFS replaces the contents of large files with small pointer files.
+// Pointer files look like:
+//
+//	version https://git-lfs.github.com/spec/v1
+//	oid sha256:4d7a214614ab2935c943f9e0ff69d22eadbb8f32b1258daaa5e2ca24d17e2393
+//	size 12345
+//
+// See https://github.com/git-lfs/git-lfs/blob/main/docs/spec.md
+
+const (
+	lfsPointerVersion = "version https://git-lfs.github.com/spec/v1"
+	lfsMaxPointerSize = 1024
+)
+
+// LFSPointer is the parsed content of a Git LFS pointer file.
+type LFSPointer struct {
+	OID  string
+	Size int64
+}
+
+// parseLFSPointer parses the contents of a Git LFS pointer file.
+// Returns false if the contents is not a valid pointer.
+func parseLFSPointer(contents []byte) (*LFSPointer, bool) {
+	if len(contents) > lfsMaxPointerSize || !bytes.HasPrefix(contents, []byte(lfsPointerVersion+"\n")) {
+		return nil, false
+	}
+
+	p := &LFSPointer{Size: -1}
+	scanner := bufio.NewScanner(bytes.NewReader(contents))
+	for scanner.Scan() {
+		kv := strings.SplitN(scanner.Text(), " ", 2)
+		if len(kv) != 2 {
+			return nil, false
+		}
+
+		switch kv[0] {
+		case "oid":
+			if !strings.HasPrefix(kv[1], "sha256:") {
+				return nil, false
+			}
+			p.OID = strings.TrimPrefix(kv[1], "sha256:")
+		case "size":
+			size, err := strconv.ParseInt(kv[1], 10, 64)
+			if err != nil {
+				return nil, false
+			}
+			p.Size = size
+		}
+	}
+
+	if p.OID == "" || p.Size < 0 {
+		return nil, false
+	}
+
+	return p, true
+}
+
+// lfsObjects finds the Git LFS pointers in the directories of the modules
+// opted in with 'lfs' option and records their object ids (keyed by the
+// path of the pointer file) in module metadata.
+func lfsObjects(repo Repo, commit Commit, set moduleMetadataSet) error {
+	var lfsModules moduleMetadataSet
+	for _, m := range set {
+		if m.spec.LFS {
+			m.lfsObjects = make(map[string]string)
+			lfsModules = append(lfsModules, m)
+		}
+	}
+
+	if len(lfsModules) == 0 {
+		return nil
+	}
+
+	return repo.WalkBlobs(commit, func(b Blob) error {
+		var owners moduleMetadataSet
+		for _, m := range lfsModules {
+			if m.dir == "" || strings.HasPrefix(b.Path(), m.dir+"/") {
+				owners = append(owners, m)
+			}
+		}
+
+		if len(owners) == 0 {
+			return nil
+		}
+
+		contents, err := repo.BlobContents(b)
+		if err != nil {
+			return err
+		}
+
+		if p, ok := parseLFSPointer(contents); ok {
+			for _, m := range owners {
+				m.lfsObjects[b.String()] = p.OID
+			}
+		}
+
+		return nil
+	})
+}
+
+// writeLFSObjects writes the object ids of Git LFS pointers to w in a
+// stable order.
+func writeLFSObjects(w io.Writer, objects map[string]string) {
+	paths := make([]string, 0, len(objects))
+	for p := range objects {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	for _, p := range paths {
+		io.WriteString(w, p)
+		io.WriteString(w, objects[p])
+	}
+}
+
+// isSmudgedLFSFile checks whether the file at path in the working
+// directory is the content referred by the Git LFS pointer stored in tree.
+// Such files differ from the blobs in git only because git lfs replaced
+// the pointer with the actual content during checkout.
+func (r *libgitRepo) isSmudgedLFSFile(tree *git.Tree, path string) (bool, error) {
+	if tree == nil {
+		return false, nil
+	}
+
+	entry, err := tree.EntryByPath(path)
+	if err != nil || entry.Type != git.ObjectBlob {
+		return false, nil
+	}
+
+	var blob *git.Blob
+	err = r.withObjects(func() (err error) {
+		blob, err = r.Repo.LookupBlob(entry.Id)
+		return
+	})
+	if err != nil {
+		return false, err
+	}
+
+	if blob.Size() > lfsMaxPointerSize {
+		return false, nil
+	}
+
+	pointer, ok := parseLFSPointer(blob.Contents())
+	if !ok {
+		return false, nil
+	}
+
+	f, err := os.Open(filepath.Join(r.Repo.Workdir(), filepath.FromSlash(path)))
+	if err != nil {
+		return false, nil
+	}
+	defer f.Close()
+
+	stat, err := f.Stat()
+	if err != nil || stat.Size() != pointer.Size {
+		return false, nil
+	}
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return false, e.Wrap(ErrClassInternal, err)
+	}
+
+	return hex.EncodeToString(h.Sum(nil)) == pointer.OID, nil
+}
@@ -0,0 +1,96 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func lfsPointer(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return fmt.Sprintf("%s\noid sha256:%s\nsize %v\n", lfsPointerVersion, hex.EncodeToString(sum[:]), len(content))
+}
+
+func TestParseLFSPointer(t *testing.T) {
+	p, ok := parseLFSPointer([]byte(lfsPointer("model")))
+
+	assert.True(t, ok)
+	assert.Equal(t, int64(5), p.Size)
+	assert.Len(t, p.OID, 64)
+}
+
+func TestParseInvalidLFSPointer(t *testing.T) {
+	for _, c := range []string{
+		"",
+		"hello world",
+		lfsPointerVersion + "\n",
+		lfsPointerVersion + "\noid md5:abc\nsize 1\n",
+		lfsPointerVersion + "\noid sha256:abc\nsize x\n",
+		lfsPointerVersion + "\noid sha256:abc\n",
+	} {
+		_, ok := parseLFSPointer([]byte(c))
+		assert.False(t, ok, c)
+	}
+}
+
+func TestLFSObjectsInVersion(t *testing.T) {
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+
+	check(t, repo.InitModuleWithOptions("app-a", &Spec{Name: "app-a", LFS: true}))
+	check(t, repo.InitModule("app-b"))
+	check(t, repo.WriteContent("app-a/model.bin", lfsPointer("model")))
+	check(t, repo.WriteContent("app-b/model.bin", lfsPointer("model")))
+	check(t, repo.Commit("first"))
+
+	m, err := NewWorld(t, ".tmp/repo").System.ManifestByCommit(repo.LastCommit.String())
+	check(t, err)
+
+	a := m.Modules.indexByName()["app-a"]
+	b := m.Modules.indexByName()["app-b"]
+	sum := sha256.Sum256([]byte("model"))
+
+	assert.Equal(t, map[string]string{"app-a/model.bin": hex.EncodeToString(sum[:])}, a.LFSObjects())
+	assert.NotEqual(t, a.Hash(), a.Version())
+	assert.Empty(t, b.LFSObjects())
+	assert.Equal(t, b.Hash(), b.Version())
+}
+
+func TestManifestByLocalDirForSmudgedLFSFiles(t *testing.T) {
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+
+	check(t, repo.InitModule("app-a"))
+	check(t, repo.WriteContent("app-a/model.bin", lfsPointer("model")))
+	check(t, repo.Commit("first"))
+
+	check(t, repo.WriteContent("app-a/model.bin", "model"))
+
+	m, err := NewWorld(t, ".tmp/repo").System.ManifestByWorkspaceChanges()
+	check(t, err)
+	assert.Len(t, m.Modules, 0)
+
+	check(t, repo.WriteContent("app-a/model.bin", "other"))
+
+	m, err = NewWorld(t, ".tmp/repo").System.ManifestByWorkspaceChanges()
+	check(t, err)
+	assert.Len(t, m.Modules, 1)
+}
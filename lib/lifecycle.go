@@ -0,0 +1,67 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import "github.com/mbtproject/mbt/e"
+
+// Lifecycle states of modules.
+const (
+	// LifecycleActive is the state of the modules under development.
+	// This is the default.
+	LifecycleActive = "active"
+	// LifecycleDeprecated is the state of the modules being sunset.
+	// A warning is reported when they are selected by changes.
+	LifecycleDeprecated = "deprecated"
+	// LifecycleFrozen is the state of the modules that must not
+	// change. Diff based manifests are not created when their files
+	// change.
+	LifecycleFrozen = "frozen"
+)
+
+// Lifecycle returns the lifecycle state of the module.
+func (a *Module) Lifecycle() string {
+	if l := a.metadata.spec.Lifecycle; l != "" {
+		return l
+	}
+	return LifecycleActive
+}
+
+func validateLifecycle(spec *Spec) error {
+	switch spec.Lifecycle {
+	case "", LifecycleActive, LifecycleDeprecated, LifecycleFrozen:
+		return nil
+	}
+	return e.NewErrorf(ErrClassUser, msgInvalidLifecycle, spec.Lifecycle, spec.Name)
+}
+
+// checkLifecycle reports the deprecated modules selected by a diff
+// and fails if the files of a frozen module are changed. It must be
+// called after explainSelection.
+func checkLifecycle(mods Modules) error {
+	for _, m := range mods {
+		switch m.Lifecycle() {
+		case LifecycleDeprecated:
+			m.metadata.warn(msgDeprecatedModuleSelected)
+		case LifecycleFrozen:
+			for _, r := range m.reasons {
+				if r.Kind == SelectionChanged || r.Kind == SelectionFileDependency {
+					return e.NewErrorf(ErrClassUser, msgFrozenModuleChanged, m.Name())
+				}
+			}
+		}
+	}
+	return nil
+}
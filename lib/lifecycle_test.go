@@ -0,0 +1,90 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/mbtproject/mbt/e"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLifecycle(t *testing.T) {
+	assert.Equal(t, LifecycleActive, newTestModule("app-a", "app-a", "a1").Lifecycle())
+
+	spec, err := newSpec([]byte("name: app-a\nlifecycle: frozen"))
+	check(t, err)
+	assert.Equal(t, LifecycleFrozen, spec.Lifecycle)
+}
+
+func TestInvalidLifecycle(t *testing.T) {
+	_, err := newSpec([]byte("name: app-a\nlifecycle: retired"))
+
+	assert.EqualError(t, err, fmt.Sprintf(msgInvalidLifecycle, "retired", "app-a"))
+	assert.Equal(t, ErrClassUser, (err.(*e.E)).Class())
+}
+
+func TestCheckLifecycleOfDeprecatedModule(t *testing.T) {
+	a := newModule(newModuleMetadata("app-a", "a1", &Spec{Name: "app-a", Lifecycle: LifecycleDeprecated}, nil), nil)
+	b := newTestModule("app-b", "app-b", "b1")
+
+	check(t, checkLifecycle(Modules{a, b}))
+
+	assert.Len(t, a.Diagnostics(), 1)
+	assert.Equal(t, msgDeprecatedModuleSelected, a.Diagnostics()[0].Message)
+	assert.Empty(t, b.Diagnostics())
+}
+
+func TestCheckLifecycleOfFrozenModule(t *testing.T) {
+	a := newModule(newModuleMetadata("lib-a", "a1", &Spec{Name: "lib-a"}, nil), nil)
+	b := newModule(newModuleMetadata("app-b", "b1", &Spec{Name: "app-b", Lifecycle: LifecycleFrozen}, nil), Modules{a})
+
+	builder := &stdManifestBuilder{}
+	builder.explainSelection(Modules{a, b}, Modules{a}, []string{"lib-a/main.go"})
+	check(t, checkLifecycle(Modules{a, b}))
+
+	builder.explainSelection(Modules{a, b}, Modules{a, b}, []string{"lib-a/main.go", "app-b/main.go"})
+	err := checkLifecycle(Modules{a, b})
+
+	assert.EqualError(t, err, fmt.Sprintf(msgFrozenModuleChanged, "app-b"))
+	assert.Equal(t, ErrClassUser, (err.(*e.E)).Class())
+}
+
+func TestFrozenModuleInDiff(t *testing.T) {
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+
+	check(t, repo.InitModuleWithOptions("lib-a", &Spec{Name: "lib-a"}))
+	check(t, repo.InitModuleWithOptions("app-b", &Spec{Name: "app-b", Dependencies: []string{"lib-a"}, Lifecycle: LifecycleFrozen}))
+	check(t, repo.Commit("first"))
+	c1 := repo.LastCommit
+
+	check(t, repo.WriteContent("lib-a/foo", "bar"))
+	check(t, repo.Commit("second"))
+	c2 := repo.LastCommit
+
+	world := NewWorld(t, ".tmp/repo")
+	m, err := world.System.ManifestByDiff(c1.String(), c2.String())
+	check(t, err)
+	assert.Equal(t, []string{"lib-a", "app-b"}, m.Modules.Names())
+
+	check(t, repo.WriteContent("app-b/foo", "bar"))
+	check(t, repo.Commit("third"))
+
+	_, err = world.System.ManifestByDiff(c2.String(), repo.LastCommit.String())
+	assert.EqualError(t, err, fmt.Sprintf(msgFrozenModuleChanged, "app-b"))
+}
@@ -18,6 +18,8 @@ package lib
 import (
 	"strings"
 
+	"github.com/mbtproject/mbt/e"
+	"github.com/mbtproject/mbt/expr"
 	"github.com/mbtproject/mbt/utils"
 )
 
@@ -71,6 +73,14 @@ func (s *stdSystem) ManifestByWorkspaceChanges() (*Manifest, error) {
 	return s.MB.ByWorkspaceChanges()
 }
 
+func (s *stdSystem) AdvanceManifest(prev *Manifest, to string) (*Manifest, error) {
+	c, err := s.Repo.GetCommit(to)
+	if err != nil {
+		return nil, err
+	}
+	return s.MB.Advance(prev, c)
+}
+
 // FilterByName reduces the modules in a Manifest to the
 // ones that are matching the terms specified in filter.
 // Multiple terms can be specified as a comma separated
@@ -93,7 +103,78 @@ func (m *Manifest) FilterByName(filterOptions *FilterOptions) *Manifest {
 		}
 	}
 
-	return &Manifest{Dir: m.Dir, Modules: filteredModules, Sha: m.Sha}
+	return m.withModules(filteredModules)
+}
+
+// FilterByGroup reduces the modules in a Manifest to the ones
+// in the specified group. Comparison is case insensitive.
+func (m *Manifest) FilterByGroup(group string) *Manifest {
+	filteredModules := make(Modules, 0)
+	for _, mod := range m.Modules {
+		if strings.EqualFold(mod.Group(), group) {
+			filteredModules = append(filteredModules, mod)
+		}
+	}
+
+	return m.withModules(filteredModules)
+}
+
+// withModules creates a copy of the Manifest with the specified modules.
+func (m *Manifest) withModules(mods Modules) *Manifest {
+	return &Manifest{
+		Dir:          m.Dir,
+		Modules:      mods,
+		Sha:          m.Sha,
+		Branch:       m.Branch,
+		ChangedFiles: append([]string(nil), m.ChangedFiles...),
+		Base:         m.Base,
+		Commit:       m.Commit,
+		Diagnostics:  append([]*Diagnostic(nil), m.Diagnostics...),
+		discovered:   m.discovered,
+	}
+}
+
+// FilterByOwner reduces the modules in a Manifest to the ones
+// owned by the specified owner. Comparison is case insensitive.
+func (m *Manifest) FilterByOwner(owner string) *Manifest {
+	filteredModules := make(Modules, 0)
+	for _, mod := range m.Modules {
+		for _, o := range mod.Owners() {
+			if strings.EqualFold(o, owner) {
+				filteredModules = append(filteredModules, mod)
+				break
+			}
+		}
+	}
+
+	return m.withModules(filteredModules)
+}
+
+// FilterExpr reduces the modules in a Manifest to the ones
+// satisfying the specified expression. Expression is written
+// in a subset of Common Expression Language (see expr package)
+// and has access to the same variables available to when
+// expressions of commands. For example,
+// app.properties.team == 'payments' && app.changedFiles.exists(f, f.endsWith('.sql'))
+func (m *Manifest) FilterExpr(filter string) (*Manifest, error) {
+	x, err := expr.Parse(filter)
+	if err != nil {
+		return nil, e.Wrapf(ErrClassUser, err, msgInvalidFilterExpr, filter)
+	}
+
+	filteredModules := make(Modules, 0)
+	for _, mod := range m.Modules {
+		match, err := x.EvalBool(expressionVars(m, mod))
+		if err != nil {
+			return nil, e.Wrapf(ErrClassUser, err, msgFailedFilterExpr, filter, mod.Name())
+		}
+
+		if match {
+			filteredModules = append(filteredModules, mod)
+		}
+	}
+
+	return m.withModules(filteredModules), nil
 }
 
 // ApplyFilters will filter the modules in the manifest to the ones that
@@ -108,16 +189,34 @@ func (m *Manifest) ApplyFilters(filterOptions *FilterOptions) (*Manifest, error)
 		m = m.FilterByName(filterOptions)
 	}
 
-	if filterOptions.Dependents {
+	if filterOptions.Owner != "" {
+		m = m.FilterByOwner(filterOptions.Owner)
+	}
+
+	if filterOptions.Group != "" {
+		m = m.FilterByGroup(filterOptions.Group)
+	}
+
+	if filterOptions.Expr != "" {
 		var err error
 
-		m.Modules, err = m.Modules.expandRequiredByDependencies()
+		m, err = m.FilterExpr(filterOptions.Expr)
 
 		if err != nil {
 			return nil, err
 		}
 	}
 
+	if filterOptions.Dependents {
+		expanded, err := m.Modules.expandRequiredByDependencies()
+
+		if err != nil {
+			return nil, err
+		}
+
+		m = m.withModules(expanded)
+	}
+
 	return m, nil
 }
 
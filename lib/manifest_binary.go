@@ -0,0 +1,362 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"sort"
+
+	"github.com/mbtproject/mbt/e"
+)
+
+/*
+Binary format of ManifestDocument uses protocol buffers wire format
+so that it can be decoded by any protobuf implementation with the
+following schema:
+
+	syntax = "proto3";
+
+	message Manifest {
+		uint32 schema_version = 1;
+		repeated Module modules = 2;
+		string sha = 3;
+		string branch = 4;
+		string base = 5;
+		repeated string changed_files = 6;
+		repeated Diagnostic diagnostics = 7;
+	}
+
+	message Module {
+		string name = 1;
+		string path = 2;
+		string version = 3;
+		bytes properties = 4; // json encoded
+		repeated string owners = 5;
+		string group = 6;
+		bool requires_migration = 7;
+		repeated string change_classes = 8;
+		repeated string diagnostics = 9;
+		repeated string selected_because = 10;
+		string lifecycle = 11;
+		bytes trailers = 12; // json encoded
+	}
+
+	message Diagnostic {
+		string level = 1;
+		string module = 2;
+		string message = 3;
+	}
+
+Modules are written in the order of their names. Unknown fields are
+skipped when decoding.
+*/
+
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+const (
+	fieldManifestSchemaVersion = 1
+	fieldManifestModules       = 2
+	fieldManifestSha           = 3
+	fieldManifestBranch        = 4
+	fieldManifestBase          = 5
+	fieldManifestChangedFiles  = 6
+	fieldManifestDiagnostics   = 7
+
+	fieldModuleName        = 1
+	fieldModulePath        = 2
+	fieldModuleVersion     = 3
+	fieldModuleProperties  = 4
+	fieldModuleOwners      = 5
+	fieldModuleGroup       = 6
+	fieldModuleMigration   = 7
+	fieldModuleClasses     = 8
+	fieldModuleDiagnostics = 9
+	fieldModuleReasons     = 10
+	fieldModuleLifecycle   = 11
+	fieldModuleTrailers    = 12
+
+	fieldDiagnosticLevel   = 1
+	fieldDiagnosticModule  = 2
+	fieldDiagnosticMessage = 3
+)
+
+type wireWriter struct {
+	buf []byte
+}
+
+func (w *wireWriter) uvarint(v uint64) {
+	var b [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(b[:], v)
+	w.buf = append(w.buf, b[:n]...)
+}
+
+func (w *wireWriter) varint(field int, v uint64) {
+	w.uvarint(uint64(field<<3 | wireVarint))
+	w.uvarint(v)
+}
+
+func (w *wireWriter) bytes(field int, b []byte) {
+	w.uvarint(uint64(field<<3 | wireBytes))
+	w.uvarint(uint64(len(b)))
+	w.buf = append(w.buf, b...)
+}
+
+func (w *wireWriter) string(field int, s string) {
+	if s != "" {
+		w.bytes(field, []byte(s))
+	}
+}
+
+type wireReader struct {
+	buf []byte
+}
+
+// next reads the next field. For varint fields, value is returned in v
+// and b is nil.
+func (r *wireReader) next() (field int, v uint64, b []byte, err error) {
+	tag, err := r.uvarint()
+	if err != nil {
+		return 0, 0, nil, err
+	}
+
+	field = int(tag >> 3)
+	switch tag & 7 {
+	case wireVarint:
+		v, err = r.uvarint()
+	case wireFixed64:
+		_, err = r.take(8)
+	case wireFixed32:
+		_, err = r.take(4)
+	case wireBytes:
+		var n uint64
+		if n, err = r.uvarint(); err == nil {
+			b, err = r.take(n)
+		}
+	default:
+		err = e.NewErrorf(ErrClassUser, msgInvalidBinaryManifest)
+	}
+
+	return field, v, b, err
+}
+
+func (r *wireReader) uvarint() (uint64, error) {
+	v, n := binary.Uvarint(r.buf)
+	if n <= 0 {
+		return 0, e.NewErrorf(ErrClassUser, msgInvalidBinaryManifest)
+	}
+	r.buf = r.buf[n:]
+	return v, nil
+}
+
+func (r *wireReader) take(n uint64) ([]byte, error) {
+	if n > uint64(len(r.buf)) {
+		return nil, e.NewErrorf(ErrClassUser, msgInvalidBinaryManifest)
+	}
+	b := r.buf[:n]
+	r.buf = r.buf[n:]
+	return b, nil
+}
+
+// MarshalBinary encodes the document in the binary manifest format.
+func (d *ManifestDocument) MarshalBinary() ([]byte, error) {
+	names := make([]string, 0, len(d.Modules))
+	for n := range d.Modules {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+
+	w := &wireWriter{}
+	w.varint(fieldManifestSchemaVersion, uint64(d.SchemaVersion))
+	for _, n := range names {
+		b, err := d.Modules[n].marshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		w.bytes(fieldManifestModules, b)
+	}
+	w.string(fieldManifestSha, d.Sha)
+	w.string(fieldManifestBranch, d.Branch)
+	w.string(fieldManifestBase, d.Base)
+	for _, f := range d.ChangedFiles {
+		w.bytes(fieldManifestChangedFiles, []byte(f))
+	}
+	for _, diag := range d.Diagnostics {
+		dw := &wireWriter{}
+		dw.string(fieldDiagnosticLevel, diag.Level)
+		dw.string(fieldDiagnosticModule, diag.Module)
+		dw.string(fieldDiagnosticMessage, diag.Message)
+		w.bytes(fieldManifestDiagnostics, dw.buf)
+	}
+
+	return w.buf, nil
+}
+
+func (m *ModuleDocument) marshalBinary() ([]byte, error) {
+	w := &wireWriter{}
+	w.string(fieldModuleName, m.Name)
+	w.string(fieldModulePath, m.Path)
+	w.string(fieldModuleVersion, m.Version)
+	if len(m.Properties) > 0 {
+		props, err := json.Marshal(m.Properties)
+		if err != nil {
+			return nil, e.Wrap(ErrClassInternal, err)
+		}
+		w.bytes(fieldModuleProperties, props)
+	}
+	for _, o := range m.Owners {
+		w.bytes(fieldModuleOwners, []byte(o))
+	}
+	w.string(fieldModuleGroup, m.Group)
+	if m.RequiresMigration {
+		w.varint(fieldModuleMigration, 1)
+	}
+	for _, c := range m.ChangeClasses {
+		w.bytes(fieldModuleClasses, []byte(c))
+	}
+	for _, d := range m.Diagnostics {
+		w.bytes(fieldModuleDiagnostics, []byte(d))
+	}
+	for _, r := range m.SelectedBecause {
+		w.bytes(fieldModuleReasons, []byte(r))
+	}
+	w.string(fieldModuleLifecycle, m.Lifecycle)
+	if len(m.Trailers) > 0 {
+		trailers, err := json.Marshal(m.Trailers)
+		if err != nil {
+			return nil, e.Wrap(ErrClassInternal, err)
+		}
+		w.bytes(fieldModuleTrailers, trailers)
+	}
+	return w.buf, nil
+}
+
+// UnmarshalBinary decodes a document in the binary manifest format.
+func (d *ManifestDocument) UnmarshalBinary(data []byte) error {
+	*d = ManifestDocument{Modules: make(map[string]*ModuleDocument)}
+
+	r := &wireReader{buf: data}
+	for len(r.buf) > 0 {
+		field, v, b, err := r.next()
+		if err != nil {
+			return err
+		}
+
+		switch field {
+		case fieldManifestSchemaVersion:
+			d.SchemaVersion = int(v)
+		case fieldManifestModules:
+			m := &ModuleDocument{}
+			if err := m.unmarshalBinary(b); err != nil {
+				return err
+			}
+			d.Modules[m.Name] = m
+		case fieldManifestSha:
+			d.Sha = string(b)
+		case fieldManifestBranch:
+			d.Branch = string(b)
+		case fieldManifestBase:
+			d.Base = string(b)
+		case fieldManifestChangedFiles:
+			d.ChangedFiles = append(d.ChangedFiles, string(b))
+		case fieldManifestDiagnostics:
+			diag, err := unmarshalDiagnostic(b)
+			if err != nil {
+				return err
+			}
+			d.Diagnostics = append(d.Diagnostics, diag)
+		}
+	}
+
+	if d.SchemaVersion > ManifestSchemaVersion {
+		return e.NewErrorf(ErrClassUser, msgUnsupportedManifestSchema, d.SchemaVersion)
+	}
+
+	return nil
+}
+
+func (m *ModuleDocument) unmarshalBinary(data []byte) error {
+	m.Properties = make(map[string]interface{})
+	m.Owners = []string{}
+
+	r := &wireReader{buf: data}
+	for len(r.buf) > 0 {
+		field, v, b, err := r.next()
+		if err != nil {
+			return err
+		}
+
+		switch field {
+		case fieldModuleName:
+			m.Name = string(b)
+		case fieldModulePath:
+			m.Path = string(b)
+		case fieldModuleVersion:
+			m.Version = string(b)
+		case fieldModuleProperties:
+			if err := json.Unmarshal(b, &m.Properties); err != nil {
+				return e.Wrapf(ErrClassUser, err, msgInvalidBinaryManifest)
+			}
+		case fieldModuleOwners:
+			m.Owners = append(m.Owners, string(b))
+		case fieldModuleGroup:
+			m.Group = string(b)
+		case fieldModuleMigration:
+			m.RequiresMigration = v != 0
+		case fieldModuleClasses:
+			m.ChangeClasses = append(m.ChangeClasses, string(b))
+		case fieldModuleDiagnostics:
+			m.Diagnostics = append(m.Diagnostics, string(b))
+		case fieldModuleReasons:
+			m.SelectedBecause = append(m.SelectedBecause, string(b))
+		case fieldModuleLifecycle:
+			m.Lifecycle = string(b)
+		case fieldModuleTrailers:
+			if err := json.Unmarshal(b, &m.Trailers); err != nil {
+				return e.Wrapf(ErrClassUser, err, msgInvalidBinaryManifest)
+			}
+		}
+	}
+
+	return nil
+}
+
+func unmarshalDiagnostic(data []byte) (*Diagnostic, error) {
+	d := &Diagnostic{}
+	r := &wireReader{buf: data}
+	for len(r.buf) > 0 {
+		field, _, b, err := r.next()
+		if err != nil {
+			return nil, err
+		}
+
+		switch field {
+		case fieldDiagnosticLevel:
+			d.Level = string(b)
+		case fieldDiagnosticModule:
+			d.Module = string(b)
+		case fieldDiagnosticMessage:
+			d.Message = string(b)
+		}
+	}
+
+	return d, nil
+}
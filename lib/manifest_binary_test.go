@@ -0,0 +1,90 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestManifestBinaryRoundTrip(t *testing.T) {
+	d := testDocumentModules().Document()
+	d.Modules["lib-b"].RequiresMigration = true
+	d.Modules["lib-b"].ChangeClasses = []string{"code", "migrations"}
+	d.Sha = "abc"
+	d.Base = "def"
+	d.ChangedFiles = []string{"lib-b/a", "lib-b/b"}
+	d.Modules["lib-b"].Diagnostics = []string{"Dependency 'app-a' is listed more than once"}
+	d.Modules["lib-b"].SelectedBecause = []string{"own files changed", "dependency app-a changed"}
+	d.Modules["lib-b"].Lifecycle = LifecycleDeprecated
+	d.Modules["lib-b"].Trailers = map[string][]string{"Ticket": {"PAY-1", "PAY-2"}}
+	d.Diagnostics = []*Diagnostic{{Level: DiagnosticWarning, Module: "lib-b", Message: "Dependency 'app-a' is listed more than once"}}
+
+	b, err := d.MarshalBinary()
+	check(t, err)
+
+	decoded := &ManifestDocument{}
+	check(t, decoded.UnmarshalBinary(b))
+
+	assert.Equal(t, ManifestSchemaVersion, decoded.SchemaVersion)
+	assert.Equal(t, d.Modules["lib-b"], decoded.Modules["lib-b"])
+	assert.Equal(t, "app-a", decoded.Modules["app-a"].Name)
+	assert.Equal(t, []string{"@alice"}, decoded.Modules["app-a"].Owners)
+	assert.Equal(t, map[string]interface{}{"port": float64(8080)}, decoded.Modules["app-a"].Properties)
+	assert.True(t, decoded.Modules["lib-b"].RequiresMigration)
+	assert.Equal(t, "abc", decoded.Sha)
+	assert.Equal(t, "def", decoded.Base)
+	assert.Equal(t, "", decoded.Branch)
+	assert.Equal(t, []string{"lib-b/a", "lib-b/b"}, decoded.ChangedFiles)
+	assert.False(t, decoded.Modules["app-a"].RequiresMigration)
+	assert.Equal(t, d.Diagnostics, decoded.Diagnostics)
+}
+
+func TestManifestBinaryIsDeterministic(t *testing.T) {
+	a, err := testDocumentModules().Document().MarshalBinary()
+	check(t, err)
+	b, err := testDocumentModules().Document().MarshalBinary()
+	check(t, err)
+
+	assert.Equal(t, a, b)
+}
+
+func TestManifestBinarySkipsUnknownFields(t *testing.T) {
+	w := &wireWriter{}
+	w.varint(fieldManifestSchemaVersion, 1)
+	w.varint(15, 42)
+	w.bytes(16, []byte("future"))
+	m := &wireWriter{}
+	m.string(fieldModuleName, "app-a")
+	m.varint(20, 1)
+	w.bytes(fieldManifestModules, m.buf)
+
+	d := &ManifestDocument{}
+	check(t, d.UnmarshalBinary(w.buf))
+	assert.Equal(t, "app-a", d.Modules["app-a"].Name)
+}
+
+func TestInvalidBinaryManifest(t *testing.T) {
+	d := &ManifestDocument{}
+	assert.EqualError(t, d.UnmarshalBinary([]byte{0x12, 0x05, 0x0a}), msgInvalidBinaryManifest)
+	assert.EqualError(t, d.UnmarshalBinary([]byte{0x08, 0x80}), msgInvalidBinaryManifest)
+
+	w := &wireWriter{}
+	w.varint(fieldManifestSchemaVersion, ManifestSchemaVersion+1)
+	assert.EqualError(t, d.UnmarshalBinary(w.buf), fmt.Sprintf(msgUnsupportedManifestSchema, ManifestSchemaVersion+1))
+}
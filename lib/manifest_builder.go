@@ -21,7 +21,13 @@ import (
 
 // NewManifestBuilder creates a new ManifestBuilder
 func NewManifestBuilder(repo Repo, reducer Reducer, discover Discover, log Log) ManifestBuilder {
-	return &stdManifestBuilder{Repo: repo, Discover: discover, Log: log, Reducer: reducer}
+	return NewManifestBuilderWithOptions(repo, reducer, discover, log, &ManifestBuilderOptions{})
+}
+
+// NewManifestBuilderWithOptions creates a new ManifestBuilder
+// configured with the specified options.
+func NewManifestBuilderWithOptions(repo Repo, reducer Reducer, discover Discover, log Log, options *ManifestBuilderOptions) ManifestBuilder {
+	return &stdManifestBuilder{Repo: repo, Discover: discover, Log: log, Reducer: reducer, options: options}
 }
 
 type stdManifestBuilder struct {
@@ -29,13 +35,14 @@ type stdManifestBuilder struct {
 	Repo     Repo
 	Discover Discover
 	Reducer  Reducer
+	options  *ManifestBuilderOptions
 }
 
 type manifestBuilder func() (*Manifest, error)
 
 func (b *stdManifestBuilder) ByDiff(from, to Commit) (*Manifest, error) {
 	return b.runManifestBuilder(func() (*Manifest, error) {
-		mods, err := b.Discover.ModulesInCommit(to)
+		all, err := b.Discover.ModulesInCommit(to)
 		if err != nil {
 			return nil, err
 		}
@@ -45,17 +52,44 @@ func (b *stdManifestBuilder) ByDiff(from, to Commit) (*Manifest, error) {
 			return nil, err
 		}
 
-		mods, err = b.Reducer.Reduce(mods, deltas)
+		changed, err := b.Reducer.Reduce(all, deltas)
+		if err != nil {
+			return nil, err
+		}
+
+		mods, err := b.expandDependents(all, changed)
+		if err != nil {
+			return nil, err
+		}
+		mods = b.force(all, mods)
+		b.explainSelection(mods, changed, deltaFiles(deltas))
+		if err = checkLifecycle(mods); err != nil {
+			return nil, err
+		}
+		if err = b.checkBudget(mods); err != nil {
+			return nil, err
+		}
+
+		m, err := b.buildManifest(mods, to.ID())
 		if err != nil {
 			return nil, err
 		}
 
-		mods, err = mods.expandRequiredByDependencies()
+		if err = b.describeCommit(m, to); err != nil {
+			return nil, err
+		}
+
+		base, err := b.Repo.MergeBase(from, to)
 		if err != nil {
 			return nil, err
 		}
 
-		return b.buildManifest(mods, to.ID())
+		m.setChangedFiles(changedFiles(deltas))
+		m.Base = base.ID()
+		if err = b.describeTrailers(m); err != nil {
+			return nil, err
+		}
+		return m, nil
 	})
 }
 
@@ -71,7 +105,13 @@ func (b *stdManifestBuilder) ByPr(src, dst string) (*Manifest, error) {
 			return nil, err
 		}
 
-		return b.ByDiff(from, to)
+		m, err := b.ByDiff(from, to)
+		if err != nil {
+			return nil, err
+		}
+
+		m.Branch = src
+		return m, nil
 	})
 }
 
@@ -82,13 +122,23 @@ func (b *stdManifestBuilder) ByCommit(sha Commit) (*Manifest, error) {
 			return nil, err
 		}
 
-		return b.buildManifest(mods, sha.ID())
+		m, err := b.buildManifest(mods, sha.ID())
+		if err != nil {
+			return nil, err
+		}
+
+		if err = b.describeCommit(m, sha); err != nil {
+			return nil, err
+		}
+
+		m.discovered = mods
+		return m, nil
 	})
 }
 
 func (b *stdManifestBuilder) ByCommitContent(sha Commit) (*Manifest, error) {
 	return b.runManifestBuilder(func() (*Manifest, error) {
-		mods, err := b.Discover.ModulesInCommit(sha)
+		all, err := b.Discover.ModulesInCommit(sha)
 		if err != nil {
 			return nil, err
 		}
@@ -98,19 +148,46 @@ func (b *stdManifestBuilder) ByCommitContent(sha Commit) (*Manifest, error) {
 			return nil, err
 		}
 
+		mods := all
 		if len(diff) > 0 {
-			mods, err = b.Reducer.Reduce(mods, diff)
+			changed, err := b.Reducer.Reduce(all, diff)
 			if err != nil {
 				return nil, err
 			}
 
-			mods, err = mods.expandRequiredByDependencies()
+			mods, err = b.expandDependents(all, changed)
 			if err != nil {
 				return nil, err
 			}
+			mods = b.force(all, mods)
+			b.explainSelection(mods, changed, deltaFiles(diff))
+			if err = checkLifecycle(mods); err != nil {
+				return nil, err
+			}
+			if err = b.checkBudget(mods); err != nil {
+				return nil, err
+			}
+		} else {
+			mods = b.force(all, mods)
 		}
 
-		return b.buildManifest(mods, sha.ID())
+		m, err := b.buildManifest(mods, sha.ID())
+		if err != nil {
+			return nil, err
+		}
+
+		if err = b.describeCommit(m, sha); err != nil {
+			return nil, err
+		}
+
+		m.setChangedFiles(changedFiles(diff))
+		if len(m.Commit.Parents) > 0 {
+			m.Base = m.Commit.Parents[0]
+		}
+		if err = b.describeTrailers(m); err != nil {
+			return nil, err
+		}
+		return m, nil
 	})
 }
 
@@ -121,7 +198,13 @@ func (b *stdManifestBuilder) ByBranch(name string) (*Manifest, error) {
 			return nil, err
 		}
 
-		return b.ByCommit(c)
+		m, err := b.ByCommit(c)
+		if err != nil {
+			return nil, err
+		}
+
+		m.Branch = name
+		return m, nil
 	})
 }
 
@@ -145,7 +228,7 @@ func (b *stdManifestBuilder) ByWorkspace() (*Manifest, error) {
 }
 
 func (b *stdManifestBuilder) ByWorkspaceChanges() (*Manifest, error) {
-	mods, err := b.Discover.ModulesInWorkspace()
+	all, err := b.Discover.ModulesInWorkspace()
 	if err != nil {
 		return nil, err
 	}
@@ -155,17 +238,32 @@ func (b *stdManifestBuilder) ByWorkspaceChanges() (*Manifest, error) {
 		return nil, err
 	}
 
-	mods, err = b.Reducer.Reduce(mods, deltas)
+	changed, err := b.Reducer.Reduce(all, deltas)
 	if err != nil {
 		return nil, err
 	}
 
-	mods, err = mods.expandRequiredByDependencies()
+	mods, err := b.expandDependents(all, changed)
 	if err != nil {
 		return nil, err
 	}
+	mods = b.force(all, mods)
+	b.explainSelection(mods, changed, deltaFiles(deltas))
+	if err = checkLifecycle(mods); err != nil {
+		return nil, err
+	}
+	if err = b.checkBudget(mods); err != nil {
+		return nil, err
+	}
 
-	return b.buildManifest(mods, "local")
+	m, err := b.buildManifest(mods, "local")
+	if err != nil {
+		return nil, err
+	}
+
+	m.setChangedFiles(changedFiles(deltas))
+	m.Base = "HEAD"
+	return m, nil
 }
 
 func (b *stdManifestBuilder) runManifestBuilder(builder manifestBuilder) (*Manifest, error) {
@@ -190,5 +288,37 @@ func (b *stdManifestBuilder) buildManifest(modules Modules, sha string) (*Manife
 			return nil, err
 		}
 	}
-	return &Manifest{Dir: repoPath, Modules: modules, Sha: sha}, nil
+	m := &Manifest{Dir: repoPath, Modules: modules, Sha: sha, Diagnostics: collectDiagnostics(modules)}
+	for _, d := range m.Diagnostics {
+		b.Log.Warnf(msgDiagnostic, d.Module, d.Message)
+	}
+	return m, nil
+}
+
+// describeCommit sets the metadata of commit in manifest and its
+// modules. Last commits of modules are resolved on demand since
+// it requires walking the history.
+func (b *stdManifestBuilder) describeCommit(m *Manifest, commit Commit) error {
+	info, err := b.Repo.CommitInfo(commit)
+	if err != nil {
+		return err
+	}
+	m.Commit = info
+
+	for _, mod := range m.Modules {
+		path := mod.Path()
+		mod.metadata.lastCommit = &lazyCommitInfo{load: func() (*CommitInfo, error) {
+			return lastCommit(b.Repo, commit, path)
+		}}
+	}
+
+	return nil
+}
+
+func changedFiles(deltas []*DiffDelta) []string {
+	files := make([]string, 0, len(deltas))
+	for _, d := range deltas {
+		files = append(files, d.NewFile)
+	}
+	return files
 }
@@ -0,0 +1,136 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	yaml "github.com/go-yaml/yaml"
+	"github.com/mbtproject/mbt/e"
+)
+
+// ManifestSchemaVersion is the version of the schema of
+// ManifestDocument. It is incremented when a change to the schema
+// is not backward compatible.
+const ManifestSchemaVersion = 1
+
+// ModuleDocument is the serialisable description of a module.
+type ModuleDocument struct {
+	Name       string                 `json:"Name" yaml:"Name"`
+	Path       string                 `json:"Path" yaml:"Path"`
+	Version    string                 `json:"Version" yaml:"Version"`
+	Properties map[string]interface{} `json:"Properties" yaml:"Properties"`
+	Owners     []string               `json:"Owners" yaml:"Owners"`
+	Group      string                 `json:"Group" yaml:"Group"`
+	// RequiresMigration is true when the changes of the module in a
+	// diff based manifest include migration files.
+	RequiresMigration bool `json:"RequiresMigration,omitempty" yaml:"RequiresMigration,omitempty"`
+	// ChangeClasses are the classes of the changes of the module in
+	// a diff based manifest.
+	ChangeClasses []string `json:"ChangeClasses,omitempty" yaml:"ChangeClasses,omitempty"`
+	// Diagnostics are the messages of the problems found in the module.
+	Diagnostics []string `json:"Diagnostics,omitempty" yaml:"Diagnostics,omitempty"`
+	// SelectedBecause are the reasons of selecting the module in a
+	// diff based manifest.
+	SelectedBecause []string `json:"SelectedBecause,omitempty" yaml:"SelectedBecause,omitempty"`
+	// Lifecycle is the state of the module specified in its spec.
+	Lifecycle string `json:"Lifecycle,omitempty" yaml:"Lifecycle,omitempty"`
+	// Trailers are the values of the configured commit trailers in
+	// the commits changing the module in a diff based manifest.
+	Trailers map[string][]string `json:"Trailers,omitempty" yaml:"Trailers,omitempty"`
+}
+
+// ManifestDocument is the serialisable description of a set of
+// modules keyed by module name.
+type ManifestDocument struct {
+	SchemaVersion int                        `json:"SchemaVersion" yaml:"SchemaVersion"`
+	Modules       map[string]*ModuleDocument `json:"Modules" yaml:"Modules"`
+	// Sha, Branch, Base and ChangedFiles describe the manifest the
+	// modules are in. They are empty in documents of a set of modules.
+	Sha          string   `json:"Sha,omitempty" yaml:"Sha,omitempty"`
+	Branch       string   `json:"Branch,omitempty" yaml:"Branch,omitempty"`
+	Base         string   `json:"Base,omitempty" yaml:"Base,omitempty"`
+	ChangedFiles []string `json:"ChangedFiles,omitempty" yaml:"ChangedFiles,omitempty"`
+	// Diagnostics are the non-fatal problems found while creating
+	// the manifest.
+	Diagnostics []*Diagnostic `json:"Diagnostics,omitempty" yaml:"Diagnostics,omitempty"`
+}
+
+// Document returns the serialisable description of the module.
+func (a *Module) Document() *ModuleDocument {
+	var diagnostics []string
+	for _, d := range a.Diagnostics() {
+		diagnostics = append(diagnostics, d.Message)
+	}
+
+	var reasons []string
+	for _, r := range a.reasons {
+		reasons = append(reasons, r.String())
+	}
+
+	var trailers map[string][]string
+	if len(a.trailers) > 0 {
+		trailers = a.Trailers()
+	}
+
+	return &ModuleDocument{
+		Name:              a.Name(),
+		Path:              a.Path(),
+		Version:           a.Version(),
+		Properties:        a.Properties(),
+		Owners:            a.Owners(),
+		Group:             a.Group(),
+		RequiresMigration: a.RequiresMigration(),
+		ChangeClasses:     append([]string(nil), a.changeClasses...),
+		Diagnostics:       diagnostics,
+		SelectedBecause:   reasons,
+		Lifecycle:         a.metadata.spec.Lifecycle,
+		Trailers:          trailers,
+	}
+}
+
+// Document returns the serialisable description of the modules.
+func (mods Modules) Document() *ManifestDocument {
+	d := &ManifestDocument{
+		SchemaVersion: ManifestSchemaVersion,
+		Modules:       make(map[string]*ModuleDocument, len(mods)),
+	}
+
+	for _, m := range mods {
+		d.Modules[m.Name()] = m.Document()
+	}
+
+	return d
+}
+
+// Document returns the serialisable description of the manifest.
+func (m *Manifest) Document() *ManifestDocument {
+	d := m.Modules.Document()
+	d.Sha = m.Sha
+	d.Branch = m.Branch
+	d.Base = m.Base
+	d.ChangedFiles = append([]string(nil), m.ChangedFiles...)
+	d.Diagnostics = append([]*Diagnostic(nil), m.Diagnostics...)
+	return d
+}
+
+// SerializeAsYAML serializes the modules as a yaml ManifestDocument.
+func (mods Modules) SerializeAsYAML() (string, error) {
+	b, err := yaml.Marshal(mods.Document())
+	if err != nil {
+		return "", e.Wrap(ErrClassInternal, err)
+	}
+
+	return string(b), nil
+}
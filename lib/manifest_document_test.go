@@ -0,0 +1,78 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"encoding/json"
+	"testing"
+
+	yaml "github.com/go-yaml/yaml"
+	"github.com/stretchr/testify/assert"
+)
+
+func testDocumentModules() Modules {
+	a := ownedTestModule("app-a", "@alice")
+	a.metadata.spec.Properties = map[string]interface{}{"port": 8080}
+	b := newTestModule("lib-b", "libs/lib-b", "b1")
+	b.metadata.spec.Properties = map[string]interface{}{}
+	return Modules{a, b}
+}
+
+func TestSerializeAsYAML(t *testing.T) {
+	s, err := testDocumentModules().SerializeAsYAML()
+	check(t, err)
+
+	assert.Equal(t, `SchemaVersion: 1
+Modules:
+  app-a:
+    Name: app-a
+    Path: app-a
+    Version: app-a1
+    Properties:
+      port: 8080
+    Owners:
+    - '@alice'
+    Group: app-a
+  lib-b:
+    Name: lib-b
+    Path: libs/lib-b
+    Version: b1
+    Properties: {}
+    Owners: []
+    Group: libs
+`, s)
+}
+
+func TestYAMLDocumentMatchesJSONSchema(t *testing.T) {
+	d := testDocumentModules().Document()
+
+	j, err := json.Marshal(d)
+	check(t, err)
+	y, err := yaml.Marshal(d)
+	check(t, err)
+
+	fromJSON := make(map[string]interface{})
+	check(t, json.Unmarshal(j, &fromJSON))
+
+	fromYAML := &ManifestDocument{}
+	check(t, yaml.Unmarshal(y, fromYAML))
+	j2, err := json.Marshal(fromYAML)
+	check(t, err)
+
+	roundTrip := make(map[string]interface{})
+	check(t, json.Unmarshal(j2, &roundTrip))
+	assert.Equal(t, fromJSON, roundTrip)
+}
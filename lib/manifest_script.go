@@ -0,0 +1,167 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/mbtproject/mbt/e"
+)
+
+// ManifestScriptOutput is the output of a manifest script.
+type ManifestScriptOutput struct {
+	// Modules to keep in the manifest keyed by name. Modules which
+	// are not in the output are removed from the manifest.
+	Modules map[string]*ModuleScriptOutput `json:"Modules"`
+}
+
+// ModuleScriptOutput describes the changes to a module made by
+// a manifest script.
+type ModuleScriptOutput struct {
+	// Properties are merged into the properties of the module.
+	Properties map[string]interface{} `json:"Properties"`
+}
+
+// scriptManifestBuilder is a ManifestBuilder which post-processes
+// the manifests with a script.
+type scriptManifestBuilder struct {
+	ManifestBuilder
+	script string
+	log    Log
+}
+
+// NewScriptManifestBuilder creates a ManifestBuilder post-processing
+// the manifests created by mb with the specified script.
+// Script is an executable written in any language, its path is
+// relative to the repository root unless it is absolute.
+// It receives the manifest on stdin as a json ManifestDocument and
+// writes a json ManifestScriptOutput to stdout. Scripts can therefore
+// customise the selection of modules and compute dynamic properties.
+func NewScriptManifestBuilder(mb ManifestBuilder, log Log, script string) ManifestBuilder {
+	return &scriptManifestBuilder{ManifestBuilder: mb, script: script, log: log}
+}
+
+func (b *scriptManifestBuilder) apply(m *Manifest, err error) (*Manifest, error) {
+	if err != nil {
+		return nil, err
+	}
+
+	return applyManifestScript(b.script, m)
+}
+
+func applyManifestScript(script string, m *Manifest) (*Manifest, error) {
+	if len(m.Modules) == 0 {
+		return m, nil
+	}
+
+	input, err := json.Marshal(m.Document())
+	if err != nil {
+		return nil, e.Wrap(ErrClassInternal, err)
+	}
+
+	path := script
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(m.Dir, path)
+	}
+
+	stdout := new(bytes.Buffer)
+	cmd := exec.Command(path)
+	cmd.Dir = m.Dir
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return nil, e.Wrapf(ErrClassUser, err, msgManifestScriptFailed, script)
+	}
+
+	output := &ManifestScriptOutput{}
+	if err := json.Unmarshal(stdout.Bytes(), output); err != nil {
+		return nil, e.Wrapf(ErrClassUser, err, msgInvalidManifestScriptOutput, script)
+	}
+
+	mods := make(Modules, 0, len(output.Modules))
+	for _, mod := range m.Modules {
+		o, ok := output.Modules[mod.Name()]
+		if !ok {
+			continue
+		}
+
+		if o != nil && len(o.Properties) > 0 {
+			mod.mergeProperties(o.Properties)
+		}
+		mods = append(mods, mod)
+	}
+
+	return m.withModules(mods), nil
+}
+
+// mergeProperties sets the specified properties of the module without
+// modifying the properties of the spec it is discovered from.
+func (a *Module) mergeProperties(props map[string]interface{}) {
+	merged := make(map[string]interface{}, len(a.metadata.spec.Properties)+len(props))
+	for k, v := range a.metadata.spec.Properties {
+		merged[k] = v
+	}
+	for k, v := range props {
+		merged[k] = v
+	}
+
+	spec := *a.metadata.spec
+	spec.Properties = merged
+	metadata := *a.metadata
+	metadata.spec = &spec
+	a.metadata = &metadata
+}
+
+func (b *scriptManifestBuilder) ByDiff(from, to Commit) (*Manifest, error) {
+	return b.apply(b.ManifestBuilder.ByDiff(from, to))
+}
+
+func (b *scriptManifestBuilder) ByPr(src, dst string) (*Manifest, error) {
+	return b.apply(b.ManifestBuilder.ByPr(src, dst))
+}
+
+func (b *scriptManifestBuilder) ByCommit(sha Commit) (*Manifest, error) {
+	return b.apply(b.ManifestBuilder.ByCommit(sha))
+}
+
+func (b *scriptManifestBuilder) ByCommitContent(sha Commit) (*Manifest, error) {
+	return b.apply(b.ManifestBuilder.ByCommitContent(sha))
+}
+
+func (b *scriptManifestBuilder) Advance(prev *Manifest, to Commit) (*Manifest, error) {
+	return b.apply(b.ManifestBuilder.Advance(prev, to))
+}
+
+func (b *scriptManifestBuilder) ByBranch(name string) (*Manifest, error) {
+	return b.apply(b.ManifestBuilder.ByBranch(name))
+}
+
+func (b *scriptManifestBuilder) ByCurrentBranch() (*Manifest, error) {
+	return b.apply(b.ManifestBuilder.ByCurrentBranch())
+}
+
+func (b *scriptManifestBuilder) ByWorkspace() (*Manifest, error) {
+	return b.apply(b.ManifestBuilder.ByWorkspace())
+}
+
+func (b *scriptManifestBuilder) ByWorkspaceChanges() (*Manifest, error) {
+	return b.apply(b.ManifestBuilder.ByWorkspaceChanges())
+}
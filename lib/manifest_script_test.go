@@ -0,0 +1,72 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeManifestScript(t *testing.T, path, content string) {
+	writeAuditFile(t, path, "#!/bin/sh\n"+content)
+	check(t, os.Chmod(path, 0755))
+}
+
+func TestManifestScript(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.SkipNow()
+	}
+	clean()
+	dir, err := filepath.Abs(".tmp/repo")
+	check(t, err)
+	writeManifestScript(t, ".tmp/repo/.mbt/select", `grep -q '"app-b"' && echo '{"Modules": {"app-b": {"Properties": {"replicas": 3}}, "app-c": null}}'`)
+
+	a := newTestModule("app-a", "app-a", "a")
+	b := newTestModule("app-b", "app-b", "b")
+	b.metadata.spec.Properties = map[string]interface{}{"team": "payments"}
+	c := newTestModule("app-c", "app-c", "c")
+	spec := b.metadata.spec
+
+	m, err := applyManifestScript(".mbt/select", &Manifest{Dir: dir, Sha: "abc", Modules: Modules{a, b, c}})
+	check(t, err)
+
+	assert.Equal(t, Modules{b, c}, m.Modules)
+	assert.Equal(t, "abc", m.Sha)
+	assert.Equal(t, map[string]interface{}{"team": "payments", "replicas": float64(3)}, b.Properties())
+	assert.Equal(t, map[string]interface{}{"team": "payments"}, spec.Properties)
+}
+
+func TestManifestScriptErrors(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.SkipNow()
+	}
+	clean()
+	dir, err := filepath.Abs(".tmp/repo")
+	check(t, err)
+	writeManifestScript(t, ".tmp/repo/fail", "exit 1\n")
+	writeManifestScript(t, ".tmp/repo/invalid", "echo invalid\n")
+	m := &Manifest{Dir: dir, Modules: Modules{newTestModule("app-a", "app-a", "a")}}
+
+	_, err = applyManifestScript("fail", m)
+	assert.EqualError(t, err, "Manifest script 'fail' failed")
+
+	_, err = applyManifestScript("invalid", m)
+	assert.EqualError(t, err, "Invalid output of manifest script 'invalid'")
+}
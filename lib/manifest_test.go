@@ -402,6 +402,42 @@ func TestManifestByLocalDirForAddition(t *testing.T) {
 	assert.Equal(t, "local", m.Modules[0].Version())
 }
 
+func TestManifestByLocalDirForStagedAddition(t *testing.T) {
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+
+	check(t, repo.InitModule("app-a"))
+	check(t, repo.Commit("first"))
+
+	check(t, repo.InitModule("app-b"))
+	check(t, repo.Stage("app-b/.mbt.yml"))
+
+	m, err := NewWorld(t, ".tmp/repo").System.ManifestByWorkspaceChanges()
+	check(t, err)
+
+	assert.Len(t, m.Modules, 1)
+	assert.Equal(t, "app-b", m.Modules[0].Name())
+}
+
+func TestManifestByLocalDirForIgnoredOutputs(t *testing.T) {
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+
+	check(t, repo.InitModule("app-a"))
+	check(t, repo.InitModule("app-b"))
+	check(t, repo.WriteContent(".gitignore", "node_modules/\n"))
+	check(t, repo.WriteContent(".mbtignore", "dist/\n"))
+	check(t, repo.Commit("first"))
+
+	check(t, repo.WriteContent("app-a/node_modules/foo/index.js", "foo"))
+	check(t, repo.WriteContent("app-b/dist/app.js", "app"))
+
+	m, err := NewWorld(t, ".tmp/repo").System.ManifestByWorkspaceChanges()
+	check(t, err)
+
+	assert.Len(t, m.Modules, 0)
+}
+
 func TestManifestByLocalDirForConversion(t *testing.T) {
 	clean()
 	repo := NewTestRepo(t, ".tmp/repo")
@@ -1224,3 +1260,35 @@ func TestApplyFilter(t *testing.T) {
 	assert.Equal(t, "app-b", m1.Modules[0].Name())
 	assert.Equal(t, "app-a", m1.Modules[1].Name())
 }
+
+func TestFilterExpr(t *testing.T) {
+	appA := &Module{metadata: &moduleMetadata{dir: "app-a", spec: &Spec{Name: "app-a", Properties: map[string]interface{}{"team": "payments"}}}}
+	appB := &Module{metadata: &moduleMetadata{dir: "app-b", spec: &Spec{Name: "app-b", Properties: map[string]interface{}{"team": "payments"}}}}
+	appC := &Module{metadata: &moduleMetadata{dir: "app-c", spec: &Spec{Name: "app-c", Properties: map[string]interface{}{"team": "search"}}}}
+
+	m := &Manifest{Modules: []*Module{appA, appB, appC}, ChangedFiles: []string{"app-a/db/a.sql", "app-b/main.go"}}
+
+	m1, err := m.FilterExpr("app.properties.team == 'payments'")
+	check(t, err)
+	assert.Len(t, m1.Modules, 2)
+	assert.Equal(t, "app-a", m1.Modules[0].Name())
+	assert.Equal(t, "app-b", m1.Modules[1].Name())
+
+	m1, err = m.ApplyFilters(&FilterOptions{Expr: "app.properties.team == 'payments' && app.changedFiles.exists(f, f.endsWith('.sql'))"})
+	check(t, err)
+	assert.Len(t, m1.Modules, 1)
+	assert.Equal(t, "app-a", m1.Modules[0].Name())
+	assert.Equal(t, m.ChangedFiles, m1.ChangedFiles)
+}
+
+func TestInvalidFilterExpr(t *testing.T) {
+	appA := &Module{metadata: &moduleMetadata{dir: "app-a", spec: &Spec{Name: "app-a"}}}
+	m := &Manifest{Modules: []*Module{appA}}
+
+	_, err := m.FilterExpr("app.name ==")
+	assert.EqualError(t, err, fmt.Sprintf(msgInvalidFilterExpr, "app.name =="))
+	assert.Equal(t, ErrClassUser, (err.(*e.E)).Class())
+
+	_, err = m.FilterExpr("app.properties.team == 'payments'")
+	assert.EqualError(t, err, fmt.Sprintf(msgFailedFilterExpr, "app.properties.team == 'payments'", "app-a"))
+}
@@ -22,4 +22,7 @@ const (
 	ErrClassUser
 	// ErrClassInternal is an internal error potentially due to a bug
 	ErrClassInternal
+	// ErrClassBuild is a failure of a command executed for a module
+	// (e.g. build or test command)
+	ErrClassBuild
 )
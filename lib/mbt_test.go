@@ -41,9 +41,9 @@ func (r *TestRepository) InitModule(p string) error {
 	return r.InitModuleWithOptions(p, &Spec{
 		Name: path.Base(p),
 		Build: map[string]*Cmd{
-			"darwin":  {"./build.sh", []string{}},
-			"linux":   {"./build.sh", []string{}},
-			"windows": {"powershell", []string{"-ExecutionPolicy", "Bypass", "-File", ".\\build.ps1"}},
+			"darwin":  {Cmd: "./build.sh", Args: []string{}},
+			"linux":   {Cmd: "./build.sh", Args: []string{}},
+			"windows": {Cmd: "powershell", Args: []string{"-ExecutionPolicy", "Bypass", "-File", ".\\build.ps1"}},
 		},
 		Properties: map[string]interface{}{"foo": "bar", "jar": "car"},
 	})
@@ -283,6 +283,20 @@ func (r *TestRepository) SimpleMerge(src, dst string) (*git.Oid, error) {
 	return head, err
 }
 
+func (r *TestRepository) Stage(p string) error {
+	idx, err := r.Repo.Index()
+	if err != nil {
+		return err
+	}
+
+	err = idx.AddByPath(p)
+	if err != nil {
+		return err
+	}
+
+	return idx.Write()
+}
+
 func (r *TestRepository) Remove(p string) error {
 	return os.RemoveAll(path.Join(r.Dir, p))
 }
@@ -327,6 +341,15 @@ func NewTestRepo(t *testing.T, dir string) *TestRepository {
 	return repo
 }
 
+// newTestModule creates a module for the tests that do not require a
+// repository. Spec of the module can be customised via its metadata
+// (e.g. mod.metadata.spec.Properties).
+func newTestModule(name, dir, version string, requires ...*Module) *Module {
+	m := newModule(newModuleMetadata(dir, version, &Spec{Name: name}, nil), requires)
+	m.version = version
+	return m
+}
+
 func clean() {
 	os.RemoveAll(".tmp")
 }
@@ -515,6 +538,36 @@ func (r *TestRepo) MergeBase(a, b Commit) (Commit, error) {
 	return sCommit(ret[0]), sErr(ret[1])
 }
 
+func (r *TestRepo) Note(commit Commit, ref string) (string, error) {
+	ret := r.Interceptor.Call("Note", commit, ref)
+	return ret[0].(string), sErr(ret[1])
+}
+
+func (r *TestRepo) SetNote(commit Commit, ref, note string) error {
+	ret := r.Interceptor.Call("SetNote", commit, ref, note)
+	return sErr(ret[0])
+}
+
+func (r *TestRepo) History(from, to Commit) ([]Commit, error) {
+	ret := r.Interceptor.Call("History", from, to)
+	return ret[0].([]Commit), sErr(ret[1])
+}
+
+func (r *TestRepo) CommitInfo(commit Commit) (*CommitInfo, error) {
+	ret := r.Interceptor.Call("CommitInfo", commit)
+	return ret[0].(*CommitInfo), sErr(ret[1])
+}
+
+func (r *TestRepo) ResolveRef(ref string) (Commit, error) {
+	ret := r.Interceptor.Call("ResolveRef", ref)
+	return sCommit(ret[0]), sErr(ret[1])
+}
+
+func (r *TestRepo) State() (*RepoState, error) {
+	ret := r.Interceptor.Call("State")
+	return ret[0].(*RepoState), sErr(ret[1])
+}
+
 type TestManifestBuilder struct {
 	Interceptor *intercept.Interceptor
 }
@@ -534,6 +587,11 @@ func (b *TestManifestBuilder) ByCommit(sha Commit) (*Manifest, error) {
 	return sManifest(ret[0]), sErr(ret[1])
 }
 
+func (b *TestManifestBuilder) Advance(prev *Manifest, to Commit) (*Manifest, error) {
+	ret := b.Interceptor.Call("Advance", prev, to)
+	return sManifest(ret[0]), sErr(ret[1])
+}
+
 func (b *TestManifestBuilder) ByCommitContent(sha Commit) (*Manifest, error) {
 	ret := b.Interceptor.Call("ByCommitContent", sha)
 	return sManifest(ret[0]), sErr(ret[1])
@@ -624,6 +682,51 @@ func (s *TestSystem) BuildWorkspaceChanges(options *CmdOptions) (*BuildSummary,
 	return sBuildSummary(ret[0]), sErr(ret[1])
 }
 
+func (s *TestSystem) TestBranch(name string, filterOptions *FilterOptions, options *CmdOptions) (*BuildSummary, error) {
+	ret := s.Interceptor.Call("TestBranch", name, filterOptions, options)
+	return sBuildSummary(ret[0]), sErr(ret[1])
+}
+
+func (s *TestSystem) TestPr(src, dst string, options *CmdOptions) (*BuildSummary, error) {
+	ret := s.Interceptor.Call("TestPr", src, dst, options)
+	return sBuildSummary(ret[0]), sErr(ret[1])
+}
+
+func (s *TestSystem) TestDiff(from, to string, options *CmdOptions) (*BuildSummary, error) {
+	ret := s.Interceptor.Call("TestDiff", from, to, options)
+	return sBuildSummary(ret[0]), sErr(ret[1])
+}
+
+func (s *TestSystem) TestCurrentBranch(filterOptions *FilterOptions, options *CmdOptions) (*BuildSummary, error) {
+	ret := s.Interceptor.Call("TestCurrentBranch", filterOptions, options)
+	return sBuildSummary(ret[0]), sErr(ret[1])
+}
+
+func (s *TestSystem) TestCommit(commit string, filterOptions *FilterOptions, options *CmdOptions) (*BuildSummary, error) {
+	ret := s.Interceptor.Call("TestCommit", commit, filterOptions, options)
+	return sBuildSummary(ret[0]), sErr(ret[1])
+}
+
+func (s *TestSystem) TestCommitContent(commit string, options *CmdOptions) (*BuildSummary, error) {
+	ret := s.Interceptor.Call("TestCommitContent", commit, options)
+	return sBuildSummary(ret[0]), sErr(ret[1])
+}
+
+func (s *TestSystem) TestWorkspace(filterOptions *FilterOptions, options *CmdOptions) (*BuildSummary, error) {
+	ret := s.Interceptor.Call("TestWorkspace", filterOptions, options)
+	return sBuildSummary(ret[0]), sErr(ret[1])
+}
+
+func (s *TestSystem) TestWorkspaceChanges(options *CmdOptions) (*BuildSummary, error) {
+	ret := s.Interceptor.Call("TestWorkspaceChanges", options)
+	return sBuildSummary(ret[0]), sErr(ret[1])
+}
+
+func (s *TestSystem) RunTask(task string, manifest *Manifest, options *CmdOptions) (*BuildSummary, error) {
+	ret := s.Interceptor.Call("RunTask", task, manifest, options)
+	return sBuildSummary(ret[0]), sErr(ret[1])
+}
+
 func (s *TestSystem) RunInBranch(command, name string, filterOptions *FilterOptions, options *CmdOptions) (*RunResult, error) {
 	ret := s.Interceptor.Call("RunInBranch", command, name, filterOptions, options)
 	return sRunResult(ret[0]), sErr(ret[1])
@@ -690,6 +793,19 @@ func (s *TestSystem) ManifestByCommit(sha string) (*Manifest, error) {
 	return sManifest(ret[0]), sErr(ret[1])
 }
 
+func (s *TestSystem) AdvanceManifest(prev *Manifest, to string) (*Manifest, error) {
+	ret := s.Interceptor.Call("AdvanceManifest", prev, to)
+	return sManifest(ret[0]), sErr(ret[1])
+}
+
+func (s *TestSystem) ManifestsByBranches(names []string) ([]*Manifest, error) {
+	ret := s.Interceptor.Call("ManifestsByBranches", names)
+	if ret[0] == nil {
+		return nil, sErr(ret[1])
+	}
+	return ret[0].([]*Manifest), sErr(ret[1])
+}
+
 func (s *TestSystem) ManifestByCommitContent(sha string) (*Manifest, error) {
 	ret := s.Interceptor.Call("ManifestByCommitContent", sha)
 	return sManifest(ret[0]), sErr(ret[1])
@@ -715,6 +831,46 @@ func (s *TestSystem) ManifestByWorkspaceChanges() (*Manifest, error) {
 	return sManifest(ret[0]), sErr(ret[1])
 }
 
+func (s *TestSystem) Stats(from, to string, options *StatsOptions) (*Stats, error) {
+	ret := s.Interceptor.Call("Stats", from, to, options)
+	return ret[0].(*Stats), sErr(ret[1])
+}
+
+func (s *TestSystem) Coupling(from, to string, options *CouplingOptions) (*CouplingReport, error) {
+	ret := s.Interceptor.Call("Coupling", from, to, options)
+	return ret[0].(*CouplingReport), sErr(ret[1])
+}
+
+func (s *TestSystem) CompareBranches(a, b string) ([]*ModuleComparison, error) {
+	ret := s.Interceptor.Call("CompareBranches", a, b)
+	return ret[0].([]*ModuleComparison), sErr(ret[1])
+}
+
+func (s *TestSystem) ModuleVersion(name, ref string) (string, error) {
+	ret := s.Interceptor.Call("ModuleVersion", name, ref)
+	return ret[0].(string), sErr(ret[1])
+}
+
+func (s *TestSystem) Export(name, ref, path string) (*ExportResult, error) {
+	ret := s.Interceptor.Call("Export", name, ref, path)
+	return ret[0].(*ExportResult), sErr(ret[1])
+}
+
+func (s *TestSystem) Release(branch string, options *ReleaseOptions) (*ReleaseManifest, error) {
+	ret := s.Interceptor.Call("Release", branch, options)
+	return ret[0].(*ReleaseManifest), sErr(ret[1])
+}
+
+func (s *TestSystem) ValidateRelease(r *ReleaseManifest, sha string) ([]*ReleaseValidation, error) {
+	ret := s.Interceptor.Call("ValidateRelease", r, sha)
+	return ret[0].([]*ReleaseValidation), sErr(ret[1])
+}
+
+func (s *TestSystem) Doctor(options *DoctorOptions) ([]*DoctorCheck, error) {
+	ret := s.Interceptor.Call("Doctor", options)
+	return ret[0].([]*DoctorCheck), sErr(ret[1])
+}
+
 type TestDiscover struct {
 	Interceptor *intercept.Interceptor
 }
@@ -729,6 +885,11 @@ func (d *TestDiscover) ModulesInWorkspace() (Modules, error) {
 	return sModules(ret[0]), sErr(ret[1])
 }
 
+func (d *TestDiscover) AdvanceModules(prev Modules, from, to Commit) (Modules, error) {
+	ret := d.Interceptor.Call("AdvanceModules", prev, from, to)
+	return sModules(ret[0]), sErr(ret[1])
+}
+
 type TestReducer struct {
 	Interceptor *intercept.Interceptor
 }
@@ -0,0 +1,97 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SerializeAsMermaid converts specified modules into a mermaid
+// flowchart that can be rendered in markdown (e.g. GitHub and GitLab)
+// without installing graphviz.
+func (mods Modules) SerializeAsMermaid() string {
+	ids := mermaidIDs{}
+	lines := []string{"graph TD"}
+
+	for _, m := range mods {
+		lines = ids.declare(lines, "m", m.Name())
+		for _, r := range m.Requires() {
+			lines = ids.declare(lines, "m", r.Name())
+			lines = append(lines, fmt.Sprintf("  %s --> %s", ids.id("m", m.Name()), ids.id("m", r.Name())))
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// GroupedSerializeAsMermaid converts specified modules into a mermaid
+// flowchart in the same way as SerializeAsMermaid.
+//
+// This variant highlights impacted modules in red, while plotting
+// their dependencies in the default style
+func (mods Modules) GroupedSerializeAsMermaid() string {
+	ids := mermaidIDs{}
+	lines := []string{"graph TD"}
+	impacted := make([]string, 0, len(mods))
+
+	for _, m := range mods {
+		lines = ids.declare(lines, "m", m.Name())
+		impacted = append(impacted, ids.id("m", m.Name()))
+	}
+
+	for _, m := range mods {
+		for _, r := range m.Requires() {
+			lines = ids.declare(lines, "m", r.Name())
+			lines = append(lines, fmt.Sprintf("  %s --> %s", ids.id("m", m.Name()), ids.id("m", r.Name())))
+		}
+	}
+
+	if len(impacted) > 0 {
+		lines = append(lines, "  classDef impacted fill:#f66,color:#000")
+		lines = append(lines, fmt.Sprintf("  class %s impacted", strings.Join(impacted, ",")))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// mermaidIDs assigns the identifiers of mermaid nodes since labels
+// such as file paths may contain characters not allowed in them.
+type mermaidIDs map[string]string
+
+// id returns the identifier of the node with the label. Prefix
+// distinguishes the nodes of different kinds with the same label.
+func (ids mermaidIDs) id(prefix, label string) string {
+	key := prefix + label
+	if _, ok := ids[key]; !ok {
+		ids[key] = fmt.Sprintf("%s%d", prefix, len(ids))
+	}
+	return ids[key]
+}
+
+// declare appends the declaration of a rectangular node to lines if
+// it is not declared already.
+func (ids mermaidIDs) declare(lines []string, prefix, label string) []string {
+	if _, ok := ids[prefix+label]; ok {
+		return lines
+	}
+	return append(lines, fmt.Sprintf("  %s[\"%s\"]", ids.id(prefix, label), mermaidLabel(label)))
+}
+
+// mermaidLabel escapes the characters that cannot appear in a quoted
+// mermaid label.
+func mermaidLabel(s string) string {
+	return strings.Replace(s, "\"", "#quot;", -1)
+}
@@ -0,0 +1,69 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testGraphModules() (a, b, c, d *Module) {
+	a = newModule(newModuleMetadata("lib-a", "a1", &Spec{Name: "lib-a"}, nil), nil)
+	b = newModule(newModuleMetadata("lib-b", "b1", &Spec{Name: "lib-b"}, nil), Modules{a})
+	c = newModule(newModuleMetadata("app-a", "c1", &Spec{Name: "app-a"}, nil), Modules{b})
+	d = newModule(newModuleMetadata("app-b", "d1", &Spec{Name: "app-b"}, nil), nil)
+	return
+}
+
+func TestSerializeAsMermaid(t *testing.T) {
+	a, b, c, d := testGraphModules()
+
+	assert.Equal(t, `graph TD
+  m0["lib-a"]
+  m1["lib-b"]
+  m1 --> m0
+  m2["app-a"]
+  m2 --> m1
+  m3["app-b"]`, Modules{a, b, c, d}.SerializeAsMermaid())
+}
+
+func TestGroupedSerializeAsMermaid(t *testing.T) {
+	_, b, c, _ := testGraphModules()
+
+	assert.Equal(t, `graph TD
+  m0["lib-b"]
+  m1["app-a"]
+  m2["lib-a"]
+  m0 --> m2
+  m1 --> m0
+  classDef impacted fill:#f66,color:#000
+  class m0,m1 impacted`, Modules{b, c}.GroupedSerializeAsMermaid())
+}
+
+func TestSerializeAsMermaidOfNoModules(t *testing.T) {
+	assert.Equal(t, "graph TD", Modules{}.SerializeAsMermaid())
+	assert.Equal(t, "graph TD", Modules{}.GroupedSerializeAsMermaid())
+}
+
+func TestMermaidLabel(t *testing.T) {
+	ids := mermaidIDs{}
+	lines := ids.declare(nil, "m", `app "a"`)
+	lines = ids.declare(lines, "m", `app "a"`)
+
+	assert.Equal(t, []string{`  m0["app #quot;a#quot;"]`}, lines)
+	assert.Equal(t, "f1", ids.id("f", `app "a"`))
+}
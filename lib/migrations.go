@@ -0,0 +1,77 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"strings"
+)
+
+// DefaultMigrationPatterns are the patterns of migration files used
+// for modules that do not specify migrations in the spec.
+// Patterns follow the rules of CODEOWNERS patterns and are relative
+// to the module directory.
+var DefaultMigrationPatterns = []string{"migrations/", "db/migrate/"}
+
+// RequiresMigration returns true if the module is in a diff based
+// manifest and its changed files include migration files.
+func (a *Module) RequiresMigration() bool {
+	for _, c := range a.changeClasses {
+		if c == ChangeClassMigrations {
+			return true
+		}
+	}
+	return false
+}
+
+// MigrationPatterns returns the patterns of migration files of the module.
+func (a *Module) MigrationPatterns() []string {
+	if a.metadata.spec.Migrations == nil {
+		return copyStrings(DefaultMigrationPatterns)
+	}
+	return copyStrings(a.metadata.spec.Migrations)
+}
+
+// isMigration returns true if any of the files is a migration
+// file of the module.
+func isMigration(mod *Module, files []string) bool {
+	for _, p := range mod.MigrationPatterns() {
+		r, err := codeOwnersPattern(p)
+		if err != nil {
+			continue
+		}
+
+		for _, f := range files {
+			if r.MatchString(moduleRelativePath(mod, f)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// moduleRelativePath returns the path of file f relative to the
+// directory of the module. Paths outside the module directory
+// (e.g. file dependencies) are returned as they are.
+func moduleRelativePath(mod *Module, f string) string {
+	if mod.Path() == "" {
+		return f
+	}
+	prefix := mod.Path() + "/"
+	if len(f) > len(prefix) && strings.EqualFold(f[:len(prefix)], prefix) {
+		return f[len(prefix):]
+	}
+	return f
+}
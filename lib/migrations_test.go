@@ -0,0 +1,43 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequiresMigration(t *testing.T) {
+	a := newTestModule("app-a", "app-a", "a")
+	b := newTestModule("app-b", "app-b", "b")
+	b.metadata.spec.Migrations = []string{"schema/*.sql"}
+	c := newTestModule("app-c", "app-c", "c")
+	m := &Manifest{Modules: Modules{a, b, c}}
+
+	m.setChangedFiles([]string{"app-a/db/migrate/001.rb", "app-b/migrations/001.sql", "app-c/main.go"})
+
+	assert.True(t, a.RequiresMigration())
+	assert.False(t, b.RequiresMigration())
+	assert.False(t, c.RequiresMigration())
+	assert.True(t, a.Document().RequiresMigration)
+
+	m.setChangedFiles([]string{"APP-B/schema/002.sql", "app-c/internal/migrations/001.sql"})
+
+	assert.False(t, a.RequiresMigration())
+	assert.True(t, b.RequiresMigration())
+	assert.True(t, c.RequiresMigration())
+}
@@ -16,6 +16,9 @@ limitations under the License.
 package lib
 
 import (
+	"sort"
+	"strings"
+
 	"github.com/mbtproject/mbt/e"
 	"github.com/mbtproject/mbt/graph"
 )
@@ -30,29 +33,104 @@ func (a *Module) Path() string {
 	return a.metadata.dir
 }
 
-// Build returns the build configuration for the module.
+// Build returns a copy of the build configuration for the module.
 func (a *Module) Build() map[string]*Cmd {
-	return a.metadata.spec.Build
+	return copyCmds(a.metadata.spec.Build)
+}
+
+// Test returns a copy of the test commands of the module keyed by the platform.
+func (a *Module) Test() map[string]*Cmd {
+	return copyCmds(a.metadata.spec.Test)
+}
+
+// Tasks returns a copy of the named tasks of the module.
+func (a *Module) Tasks() map[string]*Task {
+	tasks := a.metadata.spec.Tasks
+	if tasks == nil {
+		return nil
+	}
+
+	c := make(map[string]*Task, len(tasks))
+	for k, t := range tasks {
+		if t == nil {
+			c[k] = nil
+			continue
+		}
+		task := *t
+		task.Args = copyStrings(t.Args)
+		task.OS = copyStrings(t.OS)
+		task.DependsOn = copyStrings(t.DependsOn)
+		task.Outputs = copyStrings(t.Outputs)
+		c[k] = &task
+	}
+	return c
+}
+
+// Hooks returns a copy of the build hooks of this module.
+// Returns an empty Hooks if none is specified.
+func (a *Module) Hooks() *Hooks {
+	h := a.metadata.spec.Hooks
+	if h == nil {
+		return &Hooks{}
+	}
+	return &Hooks{Pre: copyCmd(h.Pre), Post: copyCmd(h.Post), OnFailure: copyCmd(h.OnFailure)}
 }
 
-// Commands returns a list of user defined commands in the spec.
+// Commands returns a copy of the user defined commands in the spec.
 func (a *Module) Commands() map[string]*UserCmd {
-	return a.metadata.spec.Commands
+	cmds := a.metadata.spec.Commands
+	if cmds == nil {
+		return nil
+	}
+
+	c := make(map[string]*UserCmd, len(cmds))
+	for k, u := range cmds {
+		if u == nil {
+			c[k] = nil
+			continue
+		}
+		cmd := *u
+		cmd.Args = copyStrings(u.Args)
+		cmd.OS = copyStrings(u.OS)
+		c[k] = &cmd
+	}
+	return c
 }
 
-// Properties returns the custom properties in the configuration.
+// Properties returns a copy of the custom properties in the configuration.
 func (a *Module) Properties() map[string]interface{} {
-	return a.metadata.spec.Properties
+	return copyProps(a.metadata.spec.Properties)
+}
+
+// Group returns the group (e.g. business domain) of the module.
+// Defaults to the top level directory of the module unless it is
+// specified in the spec. Root module does not belong to a group
+// by default.
+func (a *Module) Group() string {
+	if g := a.metadata.spec.Group; g != "" {
+		return g
+	}
+
+	return strings.SplitN(a.Path(), "/", 2)[0]
+}
+
+// Owners returns the owners of the module according to the
+// CODEOWNERS file in the repository.
+func (a *Module) Owners() []string {
+	if a.metadata.owners == nil {
+		return []string{}
+	}
+	return append([]string{}, a.metadata.owners...)
 }
 
 // Requires returns an array of modules required by this module.
 func (a *Module) Requires() Modules {
-	return a.requires
+	return append(Modules{}, a.requires...)
 }
 
 // RequiredBy returns an array of modules requires this module.
 func (a *Module) RequiredBy() Modules {
-	return a.requiredBy
+	return append(Modules{}, a.requiredBy...)
 }
 
 // Version returns the content based version SHA for the module.
@@ -60,6 +138,115 @@ func (a *Module) Version() string {
 	return a.version
 }
 
+// Resources returns the resource hints of this module.
+// Returns a Resources with weight 1 if none is specified.
+func (a *Module) Resources() *Resources {
+	r := a.metadata.spec.Resources
+	if r == nil {
+		return &Resources{Weight: 1}
+	}
+
+	c := *r
+	if c.Weight < 1 {
+		c.Weight = 1
+	}
+	return &c
+}
+
+// Outputs returns the paths of the files produced by the build of
+// this module. Paths are relative to the module directory and
+// may contain glob patterns.
+func (a *Module) Outputs() []string {
+	return append([]string(nil), a.metadata.spec.Outputs...)
+}
+
+// SBOM returns the command generating the software bill of materials
+// of this module. Nil if the module uses the default scanner.
+func (a *Module) SBOM() *Cmd {
+	return copyCmd(a.metadata.spec.SBOM)
+}
+
+// Variant returns the build matrix variant this module represents.
+// Nil unless the module is returned by Variants.
+func (a *Module) Variant() *Variant {
+	if a.variant == nil {
+		return nil
+	}
+
+	values := make(map[string]string, len(a.variant.Values))
+	for k, v := range a.variant.Values {
+		values[k] = v
+	}
+	return &Variant{Name: a.variant.Name, Values: values}
+}
+
+// Variants expands the build matrix of this module.
+// A copy of the module is returned for each variant with the
+// variant name appended to its version.
+// If the module does not have a build matrix, returned list
+// contains just the module itself.
+func (a *Module) Variants() Modules {
+	matrix := a.metadata.spec.Matrix
+	if len(matrix) == 0 {
+		return Modules{a}
+	}
+
+	axes := make([]string, 0, len(matrix))
+	for k := range matrix {
+		axes = append(axes, k)
+	}
+	sort.Strings(axes)
+
+	variants := []*Variant{{Values: map[string]string{}}}
+	for _, axis := range axes {
+		expanded := make([]*Variant, 0, len(variants)*len(matrix[axis]))
+		for _, v := range variants {
+			for _, value := range matrix[axis] {
+				values := make(map[string]string, len(v.Values)+1)
+				for k, x := range v.Values {
+					values[k] = x
+				}
+				values[axis] = value
+
+				name := value
+				if v.Name != "" {
+					name = v.Name + "-" + value
+				}
+				expanded = append(expanded, &Variant{Name: name, Values: values})
+			}
+		}
+		variants = expanded
+	}
+
+	mods := make(Modules, 0, len(variants))
+	for _, v := range variants {
+		// Copying the module makes sure that variants carry every
+		// property of it (e.g. change classes used in when conditions).
+		c := *a
+		c.version = a.version + "-" + v.Name
+		c.variant = v
+		mods = append(mods, &c)
+	}
+
+	return mods
+}
+
+// LastCommit returns the last commit changing the module directory.
+// Returns nil if the module is not discovered from a commit.
+func (a *Module) LastCommit() *CommitInfo {
+	if a.metadata.lastCommit == nil {
+		return nil
+	}
+
+	info := a.metadata.lastCommit.get()
+	if info == nil {
+		return nil
+	}
+	c := *info
+	c.Parents = copyStrings(info.Parents)
+	return &c
+}
+
 // Hash for the content of this module.
 func (a *Module) Hash() string {
 	return a.metadata.hash
@@ -67,9 +254,57 @@ func (a *Module) Hash() string {
 
 // FileDependencies returns the list of file dependencies this module has.
 func (a *Module) FileDependencies() []string {
-	return a.metadata.spec.FileDependencies
+	return append([]string(nil), a.metadata.spec.FileDependencies...)
+}
+
+// ExternalDependencies returns the dependencies of the module on
+// paths in other repositories.
+func (a *Module) ExternalDependencies() []*ExternalDependency {
+	deps := a.metadata.spec.ExternalDependencies
+	if deps == nil {
+		return nil
+	}
+
+	c := make([]*ExternalDependency, 0, len(deps))
+	for _, d := range deps {
+		if d == nil {
+			c = append(c, nil)
+			continue
+		}
+		dep := *d
+		c = append(c, &dep)
+	}
+	return c
+}
+
+// LFSObjects returns the object ids of Git LFS pointers in the module
+// keyed by the path of the pointer file.
+// Empty unless the module is opted in with 'lfs' option.
+func (a *Module) LFSObjects() map[string]string {
+	if a.metadata.lfsObjects == nil {
+		return map[string]string{}
+	}
+
+	objects := make(map[string]string, len(a.metadata.lfsObjects))
+	for k, v := range a.metadata.lfsObjects {
+		objects[k] = v
+	}
+	return objects
+}
+
+// IsTerraform returns true if the module directory is a Terraform
+// root module. See DiscoverOptions.Terraform.
+func (a *Module) IsTerraform() bool {
+	return a.metadata.terraform
+}
+
+// HasDockerfile returns true if the module directory contains a Dockerfile.
+func (a *Module) HasDockerfile() bool {
+	return a.metadata.dockerfile
 }
 
+// Node providers read the dependencies of modules directly instead of
+// using the accessors, which copy them on each call.
 type requiredByNodeProvider struct{}
 
 func (p *requiredByNodeProvider) ID(vertex interface{}) interface{} {
@@ -77,11 +312,11 @@ func (p *requiredByNodeProvider) ID(vertex interface{}) interface{} {
 }
 
 func (p *requiredByNodeProvider) ChildCount(vertex interface{}) int {
-	return len(vertex.(*Module).RequiredBy())
+	return len(vertex.(*Module).requiredBy)
 }
 
 func (p *requiredByNodeProvider) Child(vertex interface{}, index int) (interface{}, error) {
-	return vertex.(*Module).RequiredBy()[index], nil
+	return vertex.(*Module).requiredBy[index], nil
 }
 
 type requiresNodeProvider struct{}
@@ -91,11 +326,11 @@ func (p *requiresNodeProvider) ID(vertex interface{}) interface{} {
 }
 
 func (p *requiresNodeProvider) ChildCount(vertex interface{}) int {
-	return len(vertex.(*Module).Requires())
+	return len(vertex.(*Module).requires)
 }
 
 func (p *requiresNodeProvider) Child(vertex interface{}, index int) (interface{}, error) {
-	return vertex.(*Module).Requires()[index], nil
+	return vertex.(*Module).requires[index], nil
 }
 
 func newModule(metadata *moduleMetadata, requires Modules) *Module {
@@ -116,6 +351,43 @@ func newModule(metadata *moduleMetadata, requires Modules) *Module {
 	return mod
 }
 
+// Names returns the names of the modules.
+func (l Modules) Names() []string {
+	names := make([]string, 0, len(l))
+	for _, m := range l {
+		names = append(names, m.Name())
+	}
+	return names
+}
+
+// ModuleGroup is a set of modules in the same group.
+type ModuleGroup struct {
+	Name    string
+	Modules Modules
+}
+
+// Groups partitions the modules by their group.
+// Groups are sorted by name and preserve the order of modules.
+func (l Modules) Groups() []*ModuleGroup {
+	byName := make(map[string]*ModuleGroup)
+	groups := make([]*ModuleGroup, 0)
+	for _, m := range l {
+		g, ok := byName[m.Group()]
+		if !ok {
+			g = &ModuleGroup{Name: m.Group(), Modules: Modules{}}
+			byName[g.Name] = g
+			groups = append(groups, g)
+		}
+		g.Modules = append(g.Modules, m)
+	}
+
+	sort.Slice(groups, func(i, j int) bool {
+		return groups[i].Name < groups[j].Name
+	})
+
+	return groups
+}
+
 func (l Modules) indexByName() map[string]*Module {
 	q := make(map[string]*Module)
 	for _, a := range l {
@@ -192,3 +464,31 @@ func (l Modules) expandRequiresDependencies() (Modules, error) {
 
 	return r, nil
 }
+
+func copyStrings(s []string) []string {
+	if s == nil {
+		return nil
+	}
+	return append(make([]string, 0, len(s)), s...)
+}
+
+func copyCmd(c *Cmd) *Cmd {
+	if c == nil {
+		return nil
+	}
+	cmd := *c
+	cmd.Args = copyStrings(c.Args)
+	return &cmd
+}
+
+func copyCmds(cmds map[string]*Cmd) map[string]*Cmd {
+	if cmds == nil {
+		return nil
+	}
+
+	c := make(map[string]*Cmd, len(cmds))
+	for k, cmd := range cmds {
+		c[k] = copyCmd(cmd)
+	}
+	return c
+}
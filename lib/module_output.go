@@ -0,0 +1,181 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"bytes"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/mbtproject/mbt/e"
+)
+
+// OutputMode specifies how the output of commands executed for
+// modules is written to Stdout and Stderr.
+type OutputMode = int
+
+const (
+	// OutputModeRaw writes the output of commands as it is.
+	OutputModeRaw = iota
+	// OutputModePrefixed prefixes each line of output with the
+	// name of the module.
+	OutputModePrefixed
+	// OutputModeGrouped buffers the output of a module and writes
+	// it at once when the command is complete.
+	OutputModeGrouped
+)
+
+// Colors used to highlight module name prefixes.
+var prefixColors = []int{31, 32, 33, 34, 35, 36}
+
+// outputLock serialises the writes of prefixed lines and grouped
+// output so that they are never interleaved.
+var outputLock sync.Mutex
+
+// moduleOutput returns a copy of options with Stdout and Stderr
+// configured as per the OutputMode. Returned function must be
+// invoked once the command is complete to flush any pending output.
+func moduleOutput(options *CmdOptions, mod *Module) (*CmdOptions, func()) {
+	switch options.OutputMode {
+	case OutputModePrefixed:
+		prefix := modulePrefix(mod.Name(), options.Color)
+		stdout := &prefixWriter{Target: options.Stdout, Prefix: prefix}
+		stderr := &prefixWriter{Target: options.Stderr, Prefix: prefix}
+		o := *options
+		o.Stdout = stdout
+		o.Stderr = stderr
+		return &o, func() {
+			stdout.Flush()
+			stderr.Flush()
+		}
+	case OutputModeGrouped:
+		stdout := new(bytes.Buffer)
+		stderr := new(bytes.Buffer)
+		o := *options
+		o.Stdout = stdout
+		o.Stderr = stderr
+		return &o, func() {
+			outputLock.Lock()
+			defer outputLock.Unlock()
+			if options.Stdout != nil {
+				options.Stdout.Write(stdout.Bytes())
+			}
+			if options.Stderr != nil {
+				options.Stderr.Write(stderr.Bytes())
+			}
+		}
+	default:
+		return options, func() {}
+	}
+}
+
+func modulePrefix(name string, color bool) string {
+	if !color {
+		return fmt.Sprintf("[%s] ", name)
+	}
+
+	h := fnv.New32a()
+	h.Write([]byte(name))
+	c := prefixColors[h.Sum32()%uint32(len(prefixColors))]
+	return fmt.Sprintf("\x1b[%dm[%s]\x1b[0m ", c, name)
+}
+
+// prefixWriter writes each line written to it into Target with
+// the specified prefix. Incomplete lines are held until they
+// are terminated or the writer is flushed.
+type prefixWriter struct {
+	Target  io.Writer
+	Prefix  string
+	pending []byte
+	mutex   sync.Mutex
+}
+
+func (w *prefixWriter) Write(p []byte) (int, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.pending = append(w.pending, p...)
+	for {
+		i := bytes.IndexByte(w.pending, '\n')
+		if i < 0 {
+			break
+		}
+
+		err := w.writeLine(w.pending[:i+1])
+		w.pending = w.pending[i+1:]
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return len(p), nil
+}
+
+// Flush writes any incomplete line held by the writer.
+func (w *prefixWriter) Flush() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if len(w.pending) == 0 {
+		return nil
+	}
+
+	line := append(w.pending, '\n')
+	w.pending = nil
+	return w.writeLine(line)
+}
+
+func (w *prefixWriter) writeLine(line []byte) error {
+	if w.Target == nil {
+		return nil
+	}
+
+	outputLock.Lock()
+	defer outputLock.Unlock()
+
+	_, err := w.Target.Write(append([]byte(w.Prefix), line...))
+	return err
+}
+
+// createModuleLog creates the log file of a task of a module in
+// <dir>/<module name>/<module version>.<task>.log.
+func createModuleLog(dir string, mod *Module, task string) (*os.File, error) {
+	path := filepath.Join(dir, mod.Name(), fmt.Sprintf("%s.%s.log", mod.Version(), task))
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return nil, e.Wrapf(ErrClassUser, err, msgFailedCreateLogFile, path)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, e.Wrapf(ErrClassUser, err, msgFailedCreateLogFile, path)
+	}
+
+	return f, nil
+}
+
+// teeWriter returns a writer that writes to both target and log.
+// target may be nil.
+func teeWriter(target io.Writer, log io.Writer) io.Writer {
+	if target == nil {
+		return log
+	}
+	return io.MultiWriter(target, log)
+}
@@ -0,0 +1,118 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRawModuleOutput(t *testing.T) {
+	buff := new(bytes.Buffer)
+	options := &CmdOptions{Stdout: buff, Stderr: buff}
+
+	o, flush := moduleOutput(options, newTestModule("app-a", "app-a", "a"))
+	o.Stdout.Write([]byte("a\nb"))
+	flush()
+
+	assert.Equal(t, options, o)
+	assert.Equal(t, "a\nb", buff.String())
+}
+
+func TestPrefixedModuleOutput(t *testing.T) {
+	buff := new(bytes.Buffer)
+	options := &CmdOptions{Stdout: buff, Stderr: buff, OutputMode: OutputModePrefixed}
+
+	o, flush := moduleOutput(options, newTestModule("app-a", "app-a", "a"))
+	o.Stdout.Write([]byte("a\nb"))
+	o.Stderr.Write([]byte("c\n"))
+	o.Stdout.Write([]byte("c\nd"))
+	assert.Equal(t, "[app-a] a\n[app-a] c\n[app-a] bc\n", buff.String())
+
+	flush()
+	assert.Equal(t, "[app-a] a\n[app-a] c\n[app-a] bc\n[app-a] d\n", buff.String())
+}
+
+func TestColoredModulePrefix(t *testing.T) {
+	p := modulePrefix("app-a", true)
+
+	assert.Contains(t, p, "[app-a]")
+	assert.True(t, p[0] == '\x1b')
+	assert.Equal(t, p, modulePrefix("app-a", true))
+}
+
+func TestGroupedModuleOutput(t *testing.T) {
+	stdout := new(bytes.Buffer)
+	stderr := new(bytes.Buffer)
+	options := &CmdOptions{Stdout: stdout, Stderr: stderr, OutputMode: OutputModeGrouped}
+
+	o, flush := moduleOutput(options, newTestModule("app-a", "app-a", "a"))
+	o.Stdout.Write([]byte("a\n"))
+	o.Stderr.Write([]byte("b\n"))
+	assert.Equal(t, "", stdout.String())
+	assert.Equal(t, "", stderr.String())
+
+	flush()
+	assert.Equal(t, "a\n", stdout.String())
+	assert.Equal(t, "b\n", stderr.String())
+}
+
+func TestCreateModuleLog(t *testing.T) {
+	clean()
+	f, err := createModuleLog(".tmp/logs", newTestModule("app-a", "app-a", "abc"), "build")
+	check(t, err)
+	defer f.Close()
+
+	_, err = teeWriter(nil, f).Write([]byte("a\n"))
+	check(t, err)
+
+	assert.Equal(t, filepath.Join(".tmp/logs", "app-a", "abc.build.log"), f.Name())
+	c, err := ioutil.ReadFile(f.Name())
+	check(t, err)
+	assert.Equal(t, "a\n", string(c))
+}
+
+func TestCreateModuleLogPerTask(t *testing.T) {
+	clean()
+	mod := newTestModule("app-a", "app-a", "abc")
+	for _, task := range []string{"generate", "build"} {
+		f, err := createModuleLog(".tmp/logs", mod, task)
+		check(t, err)
+		_, err = f.Write([]byte(task))
+		check(t, err)
+		check(t, f.Close())
+	}
+
+	for _, task := range []string{"generate", "build"} {
+		c, err := ioutil.ReadFile(filepath.Join(".tmp/logs", "app-a", "abc."+task+".log"))
+		check(t, err)
+		assert.Equal(t, task, string(c))
+	}
+}
+
+func TestTeeWriter(t *testing.T) {
+	target := new(bytes.Buffer)
+	log := new(bytes.Buffer)
+
+	teeWriter(target, log).Write([]byte("a"))
+
+	assert.Equal(t, "a", target.String())
+	assert.Equal(t, "a", log.String())
+}
@@ -0,0 +1,122 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// GetString returns the property at the dotted path (e.g.
+// kubernetes.namespace) as a string. Numbers and booleans are
+// formatted as strings. def is returned when the property is not
+// found or it is not a scalar value.
+func (a *Module) GetString(path string, def string) string {
+	if s, ok := propertyString(a.property(path)); ok {
+		return s
+	}
+	return def
+}
+
+// GetInt returns the property at the dotted path as an int.
+// Whole numbers and strings containing an integer are converted.
+// def is returned when the property is not found or it cannot be
+// converted.
+func (a *Module) GetInt(path string, def int) int {
+	switch v := a.property(path).(type) {
+	case int:
+		return v
+	case int64:
+		if int64(int(v)) == v {
+			return int(v)
+		}
+	case uint64:
+		if v <= math.MaxInt64 && int64(int(v)) == int64(v) {
+			return int(v)
+		}
+	case float64:
+		// Numbers decoded from json are float64.
+		if v == math.Trunc(v) && math.Abs(v) < 1<<53 {
+			return int(v)
+		}
+	case string:
+		if i, err := strconv.Atoi(strings.TrimSpace(v)); err == nil {
+			return i
+		}
+	}
+	return def
+}
+
+// GetBool returns the property at the dotted path as a bool.
+// Strings accepted by strconv.ParseBool (e.g. "true" or "0") are
+// converted. def is returned when the property is not found or it
+// cannot be converted.
+func (a *Module) GetBool(path string, def bool) bool {
+	switch v := a.property(path).(type) {
+	case bool:
+		return v
+	case string:
+		if b, err := strconv.ParseBool(strings.TrimSpace(v)); err == nil {
+			return b
+		}
+	}
+	return def
+}
+
+// GetStringSlice returns the property at the dotted path as a slice
+// of strings. A scalar value is returned as a slice with one element.
+// def is returned when the property is not found or it contains values
+// other than scalars.
+func (a *Module) GetStringSlice(path string, def []string) []string {
+	v := a.property(path)
+	if l, ok := v.([]interface{}); ok {
+		r := make([]string, 0, len(l))
+		for _, i := range l {
+			s, ok := propertyString(i)
+			if !ok {
+				return def
+			}
+			r = append(r, s)
+		}
+		return r
+	}
+
+	if s, ok := propertyString(v); ok {
+		return []string{s}
+	}
+	return def
+}
+
+// property returns the value at the dotted path in the properties
+// of the module or nil if it is not found.
+func (a *Module) property(path string) interface{} {
+	if path == "" {
+		return nil
+	}
+	return resolveProperty(a.metadata.spec.Properties, strings.Split(path, "."), nil)
+}
+
+func propertyString(v interface{}) (string, bool) {
+	switch s := v.(type) {
+	case string:
+		return s, true
+	case bool, int, int64, uint64, float64:
+		return fmt.Sprint(s), true
+	}
+	return "", false
+}
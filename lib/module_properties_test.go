@@ -0,0 +1,84 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func propertiesTestModule() *Module {
+	mod := newTestModule("app-a", "app-a", "abc")
+	mod.metadata.spec.Properties = map[string]interface{}{
+		"name": "a",
+		"kubernetes": map[string]interface{}{
+			"replicas":  3,
+			"timeout":   float64(30),
+			"port":      "8080",
+			"enabled":   true,
+			"debug":     "false",
+			"ports":     []interface{}{80, "443"},
+			"labels":    map[string]interface{}{"tier": "web"},
+			"nested":    []interface{}{map[string]interface{}{}},
+			"fraction":  1.5,
+			"namespace": "prod",
+		},
+	}
+	return mod
+}
+
+func TestGetString(t *testing.T) {
+	mod := propertiesTestModule()
+
+	assert.Equal(t, "a", mod.GetString("name", "x"))
+	assert.Equal(t, "prod", mod.GetString("kubernetes.namespace", "x"))
+	assert.Equal(t, "3", mod.GetString("kubernetes.replicas", "x"))
+	assert.Equal(t, "true", mod.GetString("kubernetes.enabled", "x"))
+	assert.Equal(t, "x", mod.GetString("kubernetes.labels", "x"))
+	assert.Equal(t, "x", mod.GetString("kubernetes.missing", "x"))
+	assert.Equal(t, "x", mod.GetString("name.missing", "x"))
+	assert.Equal(t, "x", mod.GetString("", "x"))
+}
+
+func TestGetInt(t *testing.T) {
+	mod := propertiesTestModule()
+
+	assert.Equal(t, 3, mod.GetInt("kubernetes.replicas", 1))
+	assert.Equal(t, 30, mod.GetInt("kubernetes.timeout", 1))
+	assert.Equal(t, 8080, mod.GetInt("kubernetes.port", 1))
+	assert.Equal(t, 1, mod.GetInt("kubernetes.fraction", 1))
+	assert.Equal(t, 1, mod.GetInt("kubernetes.namespace", 1))
+	assert.Equal(t, 1, mod.GetInt("kubernetes.missing", 1))
+}
+
+func TestGetBool(t *testing.T) {
+	mod := propertiesTestModule()
+
+	assert.True(t, mod.GetBool("kubernetes.enabled", false))
+	assert.False(t, mod.GetBool("kubernetes.debug", true))
+	assert.True(t, mod.GetBool("kubernetes.namespace", true))
+	assert.True(t, mod.GetBool("kubernetes.missing", true))
+}
+
+func TestGetStringSlice(t *testing.T) {
+	mod := propertiesTestModule()
+
+	assert.Equal(t, []string{"80", "443"}, mod.GetStringSlice("kubernetes.ports", nil))
+	assert.Equal(t, []string{"prod"}, mod.GetStringSlice("kubernetes.namespace", nil))
+	assert.Equal(t, []string{"x"}, mod.GetStringSlice("kubernetes.nested", []string{"x"}))
+	assert.Nil(t, mod.GetStringSlice("kubernetes.missing", nil))
+}
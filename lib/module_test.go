@@ -0,0 +1,204 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVariants(t *testing.T) {
+	mod := newModule(newModuleMetadata("app-a", "a", &Spec{
+		Name: "app-a",
+		Matrix: map[string][]string{
+			"goos":   {"linux", "darwin"},
+			"goarch": {"amd64", "arm64"},
+		},
+	}, nil), nil)
+	mod.version = "abc"
+
+	variants := mod.Variants()
+
+	assert.Len(t, variants, 4)
+	names := make([]string, 0)
+	for _, v := range variants {
+		names = append(names, v.Version())
+		assert.Equal(t, "app-a", v.Name())
+		assert.Equal(t, map[string]string{"goarch": v.Variant().Values["goarch"], "goos": v.Variant().Values["goos"]}, v.Variant().Values)
+	}
+	assert.Equal(t, []string{"abc-amd64-linux", "abc-amd64-darwin", "abc-arm64-linux", "abc-arm64-darwin"}, names)
+	assert.Nil(t, mod.Variant())
+}
+
+func TestVariantsWithoutMatrix(t *testing.T) {
+	mod := newTestModule("app-a", "app-a", "abc")
+
+	assert.Equal(t, Modules{mod}, mod.Variants())
+}
+
+func TestVariantEnvironment(t *testing.T) {
+	mod := newModule(newModuleMetadata("app-a", "a", &Spec{
+		Name:   "app-a",
+		Matrix: map[string][]string{"goos": {"linux"}},
+	}, nil), nil)
+	mod.version = "abc"
+
+	env := setupModBuildEnvironment(&Manifest{Sha: "sha", Dir: "/repo"}, mod.Variants()[0])
+
+	assert.Contains(t, env, "MBT_MODULE_VERSION=abc-linux")
+	assert.Contains(t, env, "MBT_VARIANT=linux")
+	assert.Contains(t, env, "MBT_VARIANT_GOOS=linux")
+}
+
+func TestEmptyMatrixAxis(t *testing.T) {
+	_, err := newSpec([]byte("name: app-a\nmatrix:\n  goos: []\n"))
+
+	assert.EqualError(t, err, fmt.Sprintf(msgEmptyMatrixAxis, "goos", "app-a"))
+}
+
+func TestModuleGroup(t *testing.T) {
+	a := newTestModule("app-a", "payments/app-a", "a")
+	b := newModule(newModuleMetadata("orders/app-b", "b", &Spec{Name: "app-b", Group: "checkout"}, nil), nil)
+	c := newTestModule("root", "", "c")
+
+	assert.Equal(t, "payments", a.Group())
+	assert.Equal(t, "checkout", b.Group())
+	assert.Equal(t, "", c.Group())
+}
+
+func TestModuleGroups(t *testing.T) {
+	mods := Modules{
+		newTestModule("app-c", "payments/app-c", "c"),
+		newTestModule("app-a", "orders/app-a", "a"),
+		newTestModule("app-b", "payments/app-b", "b"),
+	}
+
+	groups := mods.Groups()
+
+	assert.Len(t, groups, 2)
+	assert.Equal(t, "orders", groups[0].Name)
+	assert.Equal(t, []string{"app-a"}, groups[0].Modules.Names())
+	assert.Equal(t, "payments", groups[1].Name)
+	assert.Equal(t, []string{"app-c", "app-b"}, groups[1].Modules.Names())
+}
+
+func TestFilterByGroup(t *testing.T) {
+	commit := &CommitInfo{Sha: "abc"}
+	m := &Manifest{Sha: "abc", Commit: commit, Modules: Modules{
+		newTestModule("app-a", "payments/app-a", "a"),
+		newTestModule("app-b", "orders/app-b", "b"),
+	}}
+
+	m, err := m.ApplyFilters(&FilterOptions{Group: "Payments"})
+	check(t, err)
+
+	assert.Equal(t, []string{"app-a"}, m.Modules.Names())
+	assert.Equal(t, commit, m.Commit)
+}
+
+func TestModuleAccessorsReturnCopies(t *testing.T) {
+	mod := newTestModule("app-a", "app-a", "abc")
+	mod.metadata.spec.Properties = map[string]interface{}{
+		"kubernetes": map[string]interface{}{"replicas": 2},
+		"tags":       []interface{}{"a"},
+	}
+	mod.metadata.owners = []string{"@alice"}
+
+	p := mod.Properties()
+	p["kubernetes"].(map[string]interface{})["replicas"] = 3
+	p["tags"].([]interface{})[0] = "b"
+	p["port"] = 8080
+	mod.Owners()[0] = "@bob"
+
+	assert.Equal(t, map[string]interface{}{
+		"kubernetes": map[string]interface{}{"replicas": 2},
+		"tags":       []interface{}{"a"},
+	}, mod.Properties())
+	assert.Equal(t, []string{"@alice"}, mod.Owners())
+}
+
+func TestManifestConcurrentReads(t *testing.T) {
+	m := &Manifest{Modules: testDocumentModules(), ChangedFiles: []string{"app-a/main.go"}}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for _, mod := range m.Modules {
+				mod.Properties()[fmt.Sprintf("p%v", i)] = i
+			}
+			d := m.Document()
+			d.Modules["app-a"].Properties["port"] = i
+			d.ChangedFiles[0] = "changed"
+			m.FilterByOwner("@alice")
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Equal(t, map[string]interface{}{"port": 8080}, m.Modules.indexByName()["app-a"].Properties())
+	assert.Equal(t, []string{"app-a/main.go"}, m.ChangedFiles)
+}
+
+func TestApplyDependentsFilterReturnsNewManifest(t *testing.T) {
+	a := newTestModule("app-a", "app-a", "a1")
+	newModule(newModuleMetadata("app-b", "b1", &Spec{Name: "app-b"}, nil), Modules{a})
+	m := &Manifest{Modules: Modules{a}}
+
+	filtered, err := m.ApplyFilters(&FilterOptions{Dependents: true})
+	check(t, err)
+
+	assert.ElementsMatch(t, []string{"app-a", "app-b"}, filtered.Modules.Names())
+	assert.Equal(t, []string{"app-a"}, m.Modules.Names())
+}
+
+func TestModuleSpecAccessorsReturnCopies(t *testing.T) {
+	mod := newTestModule("app-a", "app-a", "abc")
+	spec := mod.metadata.spec
+	spec.Build = map[string]*Cmd{"default": {Cmd: "./build.sh", Args: []string{"a"}}}
+	spec.Test = map[string]*Cmd{"default": {Cmd: "./test.sh"}}
+	spec.Tasks = map[string]*Task{"lint": {Cmd: "./lint.sh", DependsOn: []string{"build"}}}
+	spec.Hooks = &Hooks{Pre: &Cmd{Cmd: "./pre.sh"}}
+	spec.Commands = map[string]*UserCmd{"deploy": {Cmd: "./deploy.sh", OS: []string{"linux"}}}
+	spec.ExternalDependencies = []*ExternalDependency{{Repo: "https://example.com/repo.git", Ref: "v1"}}
+	spec.Resources = &Resources{Weight: 2}
+	spec.SBOM = &Cmd{Cmd: "./sbom.sh"}
+
+	mod.Build()["default"].Args[0] = "b"
+	mod.Build()["linux"] = &Cmd{Cmd: "./other.sh"}
+	mod.Test()["default"].Cmd = "./other.sh"
+	mod.Tasks()["lint"].DependsOn[0] = "test"
+	mod.Hooks().Pre.Cmd = "./other.sh"
+	mod.Commands()["deploy"].OS[0] = "windows"
+	mod.ExternalDependencies()[0].Ref = "v2"
+	mod.Resources().Weight = 3
+	mod.SBOM().Cmd = "./other.sh"
+	mod.MigrationPatterns()[0] = "other/"
+
+	assert.Equal(t, map[string]*Cmd{"default": {Cmd: "./build.sh", Args: []string{"a"}}}, mod.Build())
+	assert.Equal(t, "./test.sh", mod.Test()["default"].Cmd)
+	assert.Equal(t, []string{"build"}, mod.Tasks()["lint"].DependsOn)
+	assert.Equal(t, "./pre.sh", mod.Hooks().Pre.Cmd)
+	assert.Equal(t, []string{"linux"}, mod.Commands()["deploy"].OS)
+	assert.Equal(t, "v1", mod.ExternalDependencies()[0].Ref)
+	assert.Equal(t, 2, mod.Resources().Weight)
+	assert.Equal(t, "./sbom.sh", mod.SBOM().Cmd)
+	assert.Equal(t, DefaultMigrationPatterns[0], mod.MigrationPatterns()[0])
+	assert.Equal(t, []string{"migrations/", "db/migrate/"}, DefaultMigrationPatterns)
+}
@@ -0,0 +1,50 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import "github.com/mbtproject/mbt/e"
+
+func (s *stdSystem) ModuleVersion(name, ref string) (string, error) {
+	c, err := s.Repo.ResolveRef(ref)
+	if err != nil {
+		return "", err
+	}
+
+	// Discovering the modules is sufficient to compute the versions,
+	// hence the manifest is not built.
+	mods, err := s.Discover.ModulesInCommit(c)
+	if err != nil {
+		return "", err
+	}
+
+	m, ok := mods.indexByName()[name]
+	if !ok {
+		return "", e.NewErrorf(ErrClassUser, msgModuleNotFound, name, ref)
+	}
+
+	return m.Version(), nil
+}
+
+// ModuleVersion returns the version of the named module at a branch,
+// tag or commit of the repository in dir.
+func ModuleVersion(dir, name, ref string) (string, error) {
+	s, err := NewSystem(dir, LogLevelNormal)
+	if err != nil {
+		return "", err
+	}
+
+	return s.ModuleVersion(name, ref)
+}
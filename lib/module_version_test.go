@@ -0,0 +1,79 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestModuleVersion(t *testing.T) {
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+
+	check(t, repo.InitModule("app-a"))
+	check(t, repo.Commit("first"))
+	first := repo.LastCommit.String()
+	m1, err := NewWorld(t, ".tmp/repo").System.ManifestByCommit(first)
+	check(t, err)
+
+	check(t, repo.WriteContent("app-a/foo", "hello"))
+	check(t, repo.Commit("second"))
+	m2, err := NewWorld(t, ".tmp/repo").System.ManifestByCurrentBranch()
+	check(t, err)
+
+	v, err := ModuleVersion(".tmp/repo", "app-a", first)
+	check(t, err)
+	assert.Equal(t, m1.Modules[0].Version(), v)
+
+	v, err = ModuleVersion(".tmp/repo", "app-a", first[:7])
+	check(t, err)
+	assert.Equal(t, m1.Modules[0].Version(), v)
+
+	v, err = ModuleVersion(".tmp/repo", "app-a", "master")
+	check(t, err)
+	assert.Equal(t, m2.Modules[0].Version(), v)
+
+	v, err = ModuleVersion(".tmp/repo", "app-a", "master~1")
+	check(t, err)
+	assert.Equal(t, m1.Modules[0].Version(), v)
+}
+
+func TestVersionOfMissingModule(t *testing.T) {
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+
+	check(t, repo.InitModule("app-a"))
+	check(t, repo.Commit("first"))
+
+	_, err := ModuleVersion(".tmp/repo", "app-b", "master")
+
+	assert.EqualError(t, err, fmt.Sprintf(msgModuleNotFound, "app-b", "master"))
+}
+
+func TestVersionAtInvalidRef(t *testing.T) {
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+
+	check(t, repo.InitModule("app-a"))
+	check(t, repo.Commit("first"))
+
+	_, err := ModuleVersion(".tmp/repo", "app-a", "foo")
+
+	assert.EqualError(t, err, fmt.Sprintf(msgFailedRefLookup, "foo"))
+}
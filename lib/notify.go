@@ -0,0 +1,261 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/smtp"
+	"sort"
+	"strings"
+
+	"github.com/mbtproject/mbt/e"
+)
+
+// Notification summarises the outcome of a build for an owner.
+type Notification struct {
+	// Owner of the modules in the notification as specified in
+	// CODEOWNERS. Empty for the modules without an owner.
+	Owner   string                 `json:"owner"`
+	Branch  string                 `json:"branch"`
+	Sha     string                 `json:"sha"`
+	Built   []string               `json:"built"`
+	Skipped []string               `json:"skipped"`
+	Failed  []*NotificationFailure `json:"failed"`
+}
+
+// NotificationFailure describes a module failed to build.
+type NotificationFailure struct {
+	Module string `json:"module"`
+	Error  string `json:"error"`
+}
+
+// Subject returns a one line summary of the notification.
+func (n *Notification) Subject() string {
+	owner := n.Owner
+	if owner == "" {
+		owner = "unowned modules"
+	}
+
+	status := "succeeded"
+	if len(n.Failed) > 0 {
+		status = "failed"
+	}
+
+	target := n.Branch
+	if target == "" {
+		target = n.Sha
+	}
+	if target != "" {
+		target = " of " + target
+	}
+
+	return fmt.Sprintf("Build%s %s for %s", target, status, owner)
+}
+
+// Text returns the notification as plain text.
+func (n *Notification) Text() string {
+	lines := []string{n.Subject()}
+	for _, m := range n.Built {
+		lines = append(lines, "BUILT "+m)
+	}
+	for _, f := range n.Failed {
+		lines = append(lines, fmt.Sprintf("FAILED %s: %s", f.Module, f.Error))
+	}
+	for _, m := range n.Skipped {
+		lines = append(lines, "SKIPPED "+m)
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// BuildNotifications groups the modules in summary by their owners.
+// A module with multiple owners is included in the notification of
+// each owner. Notifications are sorted by owner.
+func BuildNotifications(summary *BuildSummary) []*Notification {
+	byOwner := make(map[string]*Notification)
+	notification := func(owner string) *Notification {
+		n, ok := byOwner[owner]
+		if !ok {
+			n = &Notification{Owner: owner, Built: []string{}, Skipped: []string{}, Failed: []*NotificationFailure{}}
+			if summary.Manifest != nil {
+				n.Branch = summary.Manifest.Branch
+				n.Sha = summary.Manifest.Sha
+			}
+			byOwner[owner] = n
+		}
+		return n
+	}
+
+	owners := func(m *Module) []string {
+		if len(m.Owners()) == 0 {
+			return []string{""}
+		}
+		return m.Owners()
+	}
+
+	for _, r := range summary.Completed {
+		for _, o := range owners(r.Module) {
+			n := notification(o)
+			n.Built = append(n.Built, moduleDisplayName(r.Module))
+		}
+	}
+
+	for _, f := range summary.Failures {
+		for _, o := range owners(f.Module) {
+			n := notification(o)
+			n.Failed = append(n.Failed, &NotificationFailure{Module: moduleDisplayName(f.Module), Error: fmt.Sprint(f.Err)})
+		}
+	}
+
+	for _, m := range summary.Skipped {
+		for _, o := range owners(m) {
+			n := notification(o)
+			n.Skipped = append(n.Skipped, m.Name())
+		}
+	}
+
+	notifications := make([]*Notification, 0, len(byOwner))
+	for _, n := range byOwner {
+		notifications = append(notifications, n)
+	}
+
+	sort.Slice(notifications, func(i, j int) bool {
+		return notifications[i].Owner < notifications[j].Owner
+	})
+
+	return notifications
+}
+
+// SendNotifications sends the notifications of summary with each
+// notifier. All notifications are attempted and the first error is
+// returned.
+func SendNotifications(summary *BuildSummary, notifiers ...Notifier) error {
+	var first error
+	for _, n := range BuildNotifications(summary) {
+		for _, notifier := range notifiers {
+			if err := notifier.Notify(n); err != nil && first == nil {
+				first = err
+			}
+		}
+	}
+
+	return first
+}
+
+type webhookNotifier struct {
+	url     string
+	client  *http.Client
+	payload func(*Notification) interface{}
+}
+
+// NewWebhookNotifier creates a Notifier posting each notification
+// as json to the specified url.
+func NewWebhookNotifier(url string) Notifier {
+	return &webhookNotifier{
+		url:     url,
+		client:  http.DefaultClient,
+		payload: func(n *Notification) interface{} { return n },
+	}
+}
+
+// NewSlackNotifier creates a Notifier posting each notification
+// to a Slack incoming webhook.
+func NewSlackNotifier(url string) Notifier {
+	return &webhookNotifier{
+		url:    url,
+		client: http.DefaultClient,
+		payload: func(n *Notification) interface{} {
+			return map[string]string{"text": n.Text()}
+		},
+	}
+}
+
+func (w *webhookNotifier) Notify(n *Notification) error {
+	if err := RequireNetwork("Notification to " + w.url); err != nil {
+		return err
+	}
+
+	b, err := json.Marshal(w.payload(n))
+	if err != nil {
+		return e.Wrap(ErrClassInternal, err)
+	}
+
+	res, err := w.client.Post(w.url, "application/json", bytes.NewReader(b))
+	if err != nil {
+		return e.Wrapf(ErrClassUser, err, msgFailedNotification, w.url)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return e.NewErrorf(ErrClassUser, msgUnexpectedNotificationResponse, res.Status, w.url)
+	}
+
+	return nil
+}
+
+// EmailOptions describes how notifications are sent by email.
+type EmailOptions struct {
+	// Server is the address of the SMTP server (e.g. smtp.example.com:25).
+	Server string
+	From   string
+	// To receives all notifications. In addition, owners specified
+	// as email addresses in CODEOWNERS receive their notifications.
+	To []string
+}
+
+type emailNotifier struct {
+	options *EmailOptions
+	send    func(addr string, a smtp.Auth, from string, to []string, msg []byte) error
+}
+
+// NewEmailNotifier creates a Notifier sending notifications by email.
+func NewEmailNotifier(options *EmailOptions) Notifier {
+	return &emailNotifier{options: options, send: smtp.SendMail}
+}
+
+func (m *emailNotifier) recipients(n *Notification) []string {
+	to := append([]string{}, m.options.To...)
+	if !strings.HasPrefix(n.Owner, "@") && strings.Contains(n.Owner, "@") {
+		to = append(to, n.Owner)
+	}
+	return to
+}
+
+func (m *emailNotifier) Notify(n *Notification) error {
+	to := m.recipients(n)
+	if len(to) == 0 {
+		return nil
+	}
+
+	if err := RequireNetwork("Email notification via " + m.options.Server); err != nil {
+		return err
+	}
+
+	msg := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\n\r\n%s\r\n",
+		m.options.From,
+		strings.Join(to, ", "),
+		n.Subject(),
+		strings.Replace(n.Text(), "\n", "\r\n", -1))
+
+	if err := m.send(m.options.Server, nil, m.options.From, to, []byte(msg)); err != nil {
+		return e.Wrapf(ErrClassUser, err, msgFailedNotification, m.options.Server)
+	}
+
+	return nil
+}
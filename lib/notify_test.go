@@ -0,0 +1,106 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/smtp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func ownedTestModule(name string, owners ...string) *Module {
+	m := newTestModule(name, name, name+"1")
+	m.metadata.owners = owners
+	return m
+}
+
+func testNotificationSummary() *BuildSummary {
+	return &BuildSummary{
+		Manifest: &Manifest{Branch: "master", Sha: "abc"},
+		Completed: []*BuildResult{
+			{Module: ownedTestModule("app-a", "@org/payments")},
+			{Module: ownedTestModule("app-b", "@org/payments", "ops@example.com")},
+		},
+		Failures: []*CmdFailure{{Module: ownedTestModule("app-c", "@org/orders"), Err: errors.New("exit status 1")}},
+		Skipped:  []*Module{ownedTestModule("app-d")},
+	}
+}
+
+func TestBuildNotifications(t *testing.T) {
+	n := BuildNotifications(testNotificationSummary())
+
+	assert.Len(t, n, 4)
+	assert.Equal(t, "", n[0].Owner)
+	assert.Equal(t, []string{"app-d"}, n[0].Skipped)
+	assert.Equal(t, "@org/orders", n[1].Owner)
+	assert.Equal(t, []*NotificationFailure{{Module: "app-c", Error: "exit status 1"}}, n[1].Failed)
+	assert.Equal(t, "@org/payments", n[2].Owner)
+	assert.Equal(t, []string{"app-a", "app-b"}, n[2].Built)
+	assert.Equal(t, "ops@example.com", n[3].Owner)
+	assert.Equal(t, []string{"app-b"}, n[3].Built)
+
+	assert.Equal(t, "Build of master failed for @org/orders\nFAILED app-c: exit status 1", n[1].Text())
+	assert.Equal(t, "Build of master succeeded for unowned modules\nSKIPPED app-d", n[0].Text())
+}
+
+func TestSlackNotifier(t *testing.T) {
+	payloads := make([]map[string]string, 0)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		p := make(map[string]string)
+		json.Unmarshal(b, &p)
+		payloads = append(payloads, p)
+	}))
+	defer server.Close()
+
+	check(t, SendNotifications(testNotificationSummary(), NewSlackNotifier(server.URL)))
+
+	assert.Len(t, payloads, 4)
+	assert.Equal(t, "Build of master succeeded for @org/payments\nBUILT app-a\nBUILT app-b", payloads[2]["text"])
+}
+
+func TestWebhookNotifierFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	err := NewWebhookNotifier(server.URL).Notify(&Notification{})
+	assert.EqualError(t, err, "Unexpected response 500 Internal Server Error from '"+server.URL+"'")
+}
+
+func TestEmailNotifier(t *testing.T) {
+	sent := make(map[string][]string)
+	n := &emailNotifier{
+		options: &EmailOptions{Server: "smtp.local:25", From: "mbt@example.com", To: []string{"builds@example.com"}},
+		send: func(addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+			sent[string(msg)] = to
+			return nil
+		},
+	}
+
+	check(t, n.Notify(&Notification{Owner: "ops@example.com", Branch: "master", Built: []string{"app-a"}}))
+
+	assert.Equal(t, map[string][]string{
+		"From: mbt@example.com\r\nTo: builds@example.com, ops@example.com\r\nSubject: Build of master succeeded for ops@example.com\r\n\r\nBuild of master succeeded for ops@example.com\r\nBUILT app-a\r\n": {"builds@example.com", "ops@example.com"},
+	}, sent)
+}
@@ -0,0 +1,75 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	git "github.com/libgit2/git2go/v28"
+	"github.com/mbtproject/mbt/e"
+)
+
+// ODBOptions tunes the object database for large repositories.
+// Zero values keep the defaults of libgit2.
+//
+// Note that libgit2 used by mbt does not read commit-graph files,
+// merge bases are found by walking the commits.
+type ODBOptions struct {
+	// CacheSize is the maximum size of decompressed objects (e.g.
+	// trees) kept in memory in megabytes. Defaults to 256.
+	CacheSize int
+	// MappedLimit is the maximum size of pack files mapped into
+	// memory in megabytes.
+	MappedLimit int
+	// PreloadPacks loads the indexes of all pack files when the
+	// repository is opened instead of on demand. It moves the cost
+	// of opening pack indexes out of the first lookups, which is
+	// useful when profiling (see Profiler) and when most packs are
+	// used anyway (e.g. merge bases far in the history).
+	PreloadPacks bool
+}
+
+const megabyte = 1024 * 1024
+
+// tune applies the options to the object database of the repository.
+// Cache and mapping limits are process wide settings of libgit2.
+func (r *libgitRepo) tune(options *ODBOptions) error {
+	if options.CacheSize < 0 || options.MappedLimit < 0 {
+		return e.NewErrorf(ErrClassUser, msgInvalidODBOptions)
+	}
+
+	if options.CacheSize > 0 {
+		if err := git.SetCacheMaxSize(options.CacheSize * megabyte); err != nil {
+			return e.Wrap(ErrClassInternal, err)
+		}
+	}
+
+	if options.MappedLimit > 0 {
+		if err := git.SetMwindowMappedLimit(options.MappedLimit * megabyte); err != nil {
+			return e.Wrap(ErrClassInternal, err)
+		}
+	}
+
+	if options.PreloadPacks {
+		odb, err := r.Repo.Odb()
+		if err != nil {
+			return e.Wrap(ErrClassInternal, err)
+		}
+		// Looking up an object which does not exist makes the pack
+		// backend search (and therefore open the index of) every pack.
+		odb.Exists(&git.Oid{})
+	}
+
+	return nil
+}
@@ -0,0 +1,52 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"sync/atomic"
+
+	"github.com/mbtproject/mbt/e"
+)
+
+// offline is set when operations must not access the network.
+// Accessed atomically since it is read from concurrent builds.
+var offline int32
+
+// SetOffline enables or disables offline mode. In offline mode,
+// operations requiring network access (e.g. remote caches, notifications,
+// pull request lookups and resolving remote refs) fail immediately
+// instead of attempting to reach the network.
+func SetOffline(enabled bool) {
+	var v int32
+	if enabled {
+		v = 1
+	}
+	atomic.StoreInt32(&offline, v)
+}
+
+// IsOffline returns true when offline mode is enabled.
+func IsOffline() bool {
+	return atomic.LoadInt32(&offline) == 1
+}
+
+// RequireNetwork returns an error describing operation when offline mode
+// is enabled.
+func RequireNetwork(operation string) error {
+	if IsOffline() {
+		return e.NewErrorf(ErrClassUser, msgOffline, operation)
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mbtproject/mbt/e"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequireNetwork(t *testing.T) {
+	assert.NoError(t, RequireNetwork("--remote-cache"))
+
+	SetOffline(true)
+	defer SetOffline(false)
+
+	err := RequireNetwork("--remote-cache")
+
+	assert.EqualError(t, err, "--remote-cache requires network access which is not allowed in offline mode")
+	assert.Equal(t, ErrClassUser, (err.(*e.E)).Class())
+}
+
+func TestHTTPCacheInOfflineMode(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+	}))
+	defer server.Close()
+
+	SetOffline(true)
+	defer SetOffline(false)
+
+	c := NewHTTPCache(server.URL)
+	_, err := c.Get("a")
+	assert.Error(t, err)
+	assert.Error(t, c.Put("a", nil))
+	assert.Equal(t, 0, requests)
+}
+
+func TestWebhookNotifierInOfflineMode(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+	}))
+	defer server.Close()
+
+	SetOffline(true)
+	defer SetOffline(false)
+
+	err := NewWebhookNotifier(server.URL).Notify(&Notification{Owner: "@a"})
+
+	assert.EqualError(t, err, "Notification to "+server.URL+" requires network access which is not allowed in offline mode")
+	assert.Equal(t, 0, requests)
+}
+
+func TestLsRemoteInOfflineMode(t *testing.T) {
+	SetOffline(true)
+	defer SetOffline(false)
+
+	sha := "0123456789abcdef0123456789abcdef01234567"
+	resolved, err := LsRemote("https://example.com/repo.git", sha)
+	assert.NoError(t, err)
+	assert.Equal(t, sha, resolved)
+
+	_, err = LsRemote("https://example.com/repo.git", "master")
+	assert.EqualError(t, err, "Resolving master in https://example.com/repo.git requires network access which is not allowed in offline mode")
+}
+
+func TestDockerRunArgsInOfflineMode(t *testing.T) {
+	SetOffline(true)
+	defer SetOffline(false)
+
+	x := &dockerExecutor{Options: &DockerOptions{Image: "golang:1.15"}}
+	mod := newTestModule("app-a", "app-a", "v1")
+
+	args := x.runArgs(&ExecContext{Manifest: &Manifest{Dir: "/repo", Sha: "abc"}, Module: mod, Command: "./build.sh"})
+
+	assert.Equal(t, []string{"--pull", "never"}, args[7:9])
+}
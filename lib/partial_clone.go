@@ -0,0 +1,148 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"bytes"
+	"os/exec"
+	"regexp"
+	"strings"
+
+	git "github.com/libgit2/git2go/v28"
+	"github.com/mbtproject/mbt/e"
+)
+
+// Partial clones (e.g. git clone --filter=blob:none) omit objects which
+// are fetched lazily from the promisor remote by git. libgit2 does not
+// know how to do that. Therefore, when an object cannot be found in a
+// partial clone, we ask git to fetch it and retry the operation.
+// When the object is still not available, the operation fails with an
+// error naming the missing object.
+
+var missingObjectPattern = regexp.MustCompile(`\b[0-9a-f]{40}\b`)
+
+// maxObjectFetches limits the number of objects fetched on demand for a
+// single operation. Treeless clones may require one fetch per subtree.
+const maxObjectFetches = 256
+
+// rootCause returns the innermost error wrapped by err.
+func rootCause(err error) error {
+	for {
+		wrapped, ok := err.(*e.E)
+		if !ok || wrapped.InnerError() == nil {
+			return err
+		}
+		err = wrapped.InnerError()
+	}
+}
+
+func isMissingObject(err error) bool {
+	err = rootCause(err)
+	return git.IsErrorClass(err, git.ErrorClassOdb) && git.IsErrorCode(err, git.ErrorCodeNotFound)
+}
+
+func missingObjectID(err error) string {
+	return missingObjectPattern.FindString(rootCause(err).Error())
+}
+
+// isPartialClone checks the configuration of repo to see whether it is
+// a partial clone. Result is cached in libgitRepo when it is opened
+// since objects are looked up frequently.
+func isPartialClone(repo *git.Repository) bool {
+	config, err := repo.Config()
+	if err != nil {
+		return false
+	}
+	defer config.Free()
+
+	remote, err := config.LookupString("extensions.partialclone")
+	return err == nil && remote != ""
+}
+
+func (r *libgitRepo) fetchObject(id string) error {
+	r.Log.Debug("Fetching missing object %v", id)
+	// cat-file triggers git's lazy fetch from the promisor remote.
+	cmd := exec.Command("git", "-C", r.Repo.Workdir(), "cat-file", "-e", id)
+	return cmd.Run()
+}
+
+// withObjects runs op and fetches the objects it reports as missing until
+// it succeeds. op must be safe to run more than once.
+func (r *libgitRepo) withObjects(op func() error) error {
+	fetched := make(map[string]bool)
+	for {
+		err := op()
+		if err == nil || !isMissingObject(err) {
+			return err
+		}
+
+		id := missingObjectID(err)
+		if !r.partial || id == "" || fetched[id] || len(fetched) >= maxObjectFetches {
+			return e.Wrapf(ErrClassUser, err, msgMissingObject, id)
+		}
+
+		if nerr := RequireNetwork("Fetching missing object " + id); nerr != nil {
+			return nerr
+		}
+
+		if ferr := r.fetchObject(id); ferr != nil {
+			return e.Wrapf(ErrClassUser, err, msgMissingObject, id)
+		}
+		fetched[id] = true
+	}
+}
+
+// wrapObjectErr wraps err with an interpolated message unless err is
+// already describing a missing object, which is more useful to the user.
+func wrapObjectErr(err error, format string, args ...interface{}) error {
+	if wrapped, ok := err.(*e.E); ok && isMissingObject(err) {
+		return wrapped
+	}
+
+	return e.Wrapf(ErrClassInternal, err, format, args...)
+}
+
+// skipWorktree returns the set of paths excluded from the working
+// directory by sparse checkout. These paths are absent in the working
+// directory by design and must not be reported as deleted.
+// Returns nil when sparse checkout is not enabled.
+func (r *libgitRepo) skipWorktree() (map[string]bool, error) {
+	config, err := r.Repo.Config()
+	if err != nil {
+		return nil, e.Wrap(ErrClassInternal, err)
+	}
+	defer config.Free()
+
+	sparse, err := config.LookupBool("core.sparseCheckout")
+	if err != nil || !sparse {
+		return nil, nil
+	}
+
+	out, err := exec.Command("git", "-C", r.Repo.Workdir(), "ls-files", "-t", "-z").Output()
+	if err != nil {
+		return nil, e.Wrapf(ErrClassInternal, err, msgFailedSparseCheckout)
+	}
+
+	paths := make(map[string]bool)
+	for _, entry := range bytes.Split(out, []byte{0}) {
+		s := string(entry)
+		if strings.HasPrefix(s, "S ") {
+			paths[strings.TrimPrefix(s, "S ")] = true
+		}
+	}
+
+	return paths, nil
+}
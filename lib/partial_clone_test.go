@@ -0,0 +1,92 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	git "github.com/libgit2/git2go/v28"
+	"github.com/mbtproject/mbt/e"
+	"github.com/stretchr/testify/assert"
+)
+
+const missingID = "0123456789abcdef0123456789abcdef01234567"
+
+func missingObjectErr() error {
+	return &git.GitError{
+		Message: fmt.Sprintf("object not found - no match for id (%v)", missingID),
+		Class:   git.ErrorClassOdb,
+		Code:    git.ErrorCodeNotFound,
+	}
+}
+
+func TestMissingObjectDetection(t *testing.T) {
+	err := e.Wrap(ErrClassInternal, missingObjectErr())
+
+	assert.True(t, isMissingObject(err))
+	assert.Equal(t, missingID, missingObjectID(err))
+	assert.False(t, isMissingObject(errors.New("doh")))
+	assert.False(t, isMissingObject(&git.GitError{Class: git.ErrorClassTree, Code: git.ErrorCodeNotFound}))
+}
+
+func TestWrapObjectErrPreservesMissingObject(t *testing.T) {
+	missing := e.Wrapf(ErrClassUser, missingObjectErr(), msgMissingObject, missingID)
+
+	assert.EqualError(t, wrapObjectErr(missing, msgFailedTreeLoad, "abc"), fmt.Sprintf(msgMissingObject, missingID))
+	assert.EqualError(t, wrapObjectErr(errors.New("doh"), msgFailedTreeLoad, "abc"), fmt.Sprintf(msgFailedTreeLoad, "abc"))
+}
+
+func TestManifestOfBloblessClone(t *testing.T) {
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+
+	check(t, repo.InitModule("app-a"))
+	check(t, repo.InitModule("app-b"))
+	check(t, repo.Commit("first"))
+
+	src, err := filepath.Abs(".tmp/repo")
+	check(t, err)
+	runGit(t, "-C", src, "config", "uploadpack.allowFilter", "true")
+	runGit(t, "clone", "--quiet", "--no-checkout", "--filter=blob:none", "file://"+src, ".tmp/partial")
+
+	m, err := NewWorld(t, ".tmp/partial").System.ManifestByCommit(repo.LastCommit.String())
+	check(t, err)
+
+	assert.Len(t, m.Modules, 2)
+	assert.Equal(t, "app-a", m.Modules[0].Name())
+	assert.Equal(t, "app-b", m.Modules[1].Name())
+}
+
+func TestManifestByLocalDirForSparseCheckout(t *testing.T) {
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+
+	check(t, repo.InitModule("app-a"))
+	check(t, repo.InitModule("app-b"))
+	check(t, repo.Commit("first"))
+
+	runGit(t, "-C", ".tmp/repo", "config", "core.sparseCheckout", "true")
+	check(t, repo.WriteContent(".git/info/sparse-checkout", "/app-a/\n"))
+	runGit(t, "-C", ".tmp/repo", "read-tree", "-mu", "HEAD")
+
+	m, err := NewWorld(t, ".tmp/repo").System.ManifestByWorkspaceChanges()
+	check(t, err)
+
+	assert.Len(t, m.Modules, 0)
+}
@@ -0,0 +1,114 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/mbtproject/mbt/e"
+)
+
+// PluginPrefix is the prefix of the names of plugin executables.
+// Plugin foo is an executable named mbt-foo found in PATH.
+const PluginPrefix = "mbt-"
+
+// PluginOptions describes how a plugin is executed.
+type PluginOptions struct {
+	// Args are passed to the plugin as they are.
+	Args []string
+	// Stdout and Stderr of the plugin. Default to the ones of
+	// the current process.
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// Plugins returns the sorted names of the plugins found in PATH.
+func Plugins() []string {
+	seen := make(map[string]bool)
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		entries, err := ioutil.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+
+		for _, entry := range entries {
+			n := entry.Name()
+			if entry.IsDir() || !strings.HasPrefix(n, PluginPrefix) {
+				continue
+			}
+
+			n = strings.TrimSuffix(strings.TrimPrefix(n, PluginPrefix), filepath.Ext(n))
+			if n != "" {
+				seen[n] = true
+			}
+		}
+	}
+
+	names := make([]string, 0, len(seen))
+	for n := range seen {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// RunPlugin executes the plugin with specified name. Manifest m is
+// written to the stdin of the plugin as a json ManifestDocument and
+// the plugin is started in the repository directory with
+// MBT_REPO_PATH, MBT_BUILD_COMMIT and MBT_BRANCH environment variables.
+func RunPlugin(name string, m *Manifest, options *PluginOptions) error {
+	path, err := exec.LookPath(PluginPrefix + name)
+	if err != nil {
+		return e.Wrapf(ErrClassUser, err, msgPluginNotFound, name, PluginPrefix+name)
+	}
+
+	input, err := json.Marshal(m.Document())
+	if err != nil {
+		return e.Wrap(ErrClassInternal, err)
+	}
+
+	cmd := exec.Command(path, options.Args...)
+	cmd.Dir = m.Dir
+	cmd.Env = append(os.Environ(),
+		fmt.Sprintf("MBT_REPO_PATH=%s", m.Dir),
+		fmt.Sprintf("MBT_BUILD_COMMIT=%s", m.Sha),
+		fmt.Sprintf("MBT_BRANCH=%s", m.Branch),
+	)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = options.Stdout
+	cmd.Stderr = options.Stderr
+	if cmd.Stdout == nil {
+		cmd.Stdout = os.Stdout
+	}
+	if cmd.Stderr == nil {
+		cmd.Stderr = os.Stderr
+	}
+
+	if err := cmd.Run(); err != nil {
+		return e.Wrapf(ErrClassUser, err, msgPluginFailed, name)
+	}
+
+	return nil
+}
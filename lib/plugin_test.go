@@ -0,0 +1,81 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func withPluginPath(t *testing.T) func() {
+	dir, err := filepath.Abs(".tmp/plugins")
+	check(t, err)
+	writeAuditFile(t, filepath.Join(dir, "mbt-echo"), "#!/bin/sh\necho \"$1 $MBT_BUILD_COMMIT\"\ncat\n")
+	check(t, os.Chmod(filepath.Join(dir, "mbt-echo"), 0755))
+	writeAuditFile(t, filepath.Join(dir, "mbt-fail"), "#!/bin/sh\nexit 3\n")
+	check(t, os.Chmod(filepath.Join(dir, "mbt-fail"), 0755))
+
+	path := os.Getenv("PATH")
+	check(t, os.Setenv("PATH", dir+string(os.PathListSeparator)+path))
+	return func() {
+		os.Setenv("PATH", path)
+	}
+}
+
+func TestRunPlugin(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.SkipNow()
+	}
+	clean()
+	defer withPluginPath(t)()
+
+	m := &Manifest{Dir: ".", Sha: "abc", Modules: Modules{newTestModule("app-a", "app-a", "a1")}, ChangedFiles: []string{"app-a/main.go"}}
+	out := new(bytes.Buffer)
+	check(t, RunPlugin("echo", m, &PluginOptions{Args: []string{"hello"}, Stdout: out}))
+
+	line, err := out.ReadString('\n')
+	check(t, err)
+	assert.Equal(t, "hello abc\n", line)
+
+	d := &ManifestDocument{}
+	check(t, json.Unmarshal(out.Bytes(), d))
+	assert.Equal(t, "abc", d.Sha)
+	assert.Equal(t, []string{"app-a/main.go"}, d.ChangedFiles)
+	assert.Equal(t, "a1", d.Modules["app-a"].Version)
+
+	assert.Contains(t, Plugins(), "echo")
+}
+
+func TestRunPluginErrors(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.SkipNow()
+	}
+	clean()
+	defer withPluginPath(t)()
+
+	m := &Manifest{Dir: ".", Modules: Modules{}}
+	err := RunPlugin("fail", m, &PluginOptions{Stdout: new(bytes.Buffer)})
+	assert.EqualError(t, err, "Plugin 'fail' failed")
+
+	err = RunPlugin("missing", m, &PluginOptions{})
+	assert.EqualError(t, err, "Plugin 'missing' is not found (expected an executable named mbt-missing in PATH)")
+}
@@ -0,0 +1,315 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	yaml "github.com/go-yaml/yaml"
+	"github.com/mbtproject/mbt/e"
+	"github.com/mbtproject/mbt/expr"
+)
+
+const (
+	// PolicyLevelError is the level of violations failing the
+	// construction of manifests.
+	PolicyLevelError = "error"
+	// PolicyLevelWarning is the level of violations which are
+	// reported without failing.
+	PolicyLevelWarning = "warning"
+)
+
+// Violation describes a module violating a policy.
+type Violation struct {
+	Policy  string `json:"policy"`
+	Module  string `json:"module"`
+	Message string `json:"message"`
+	// Level is either PolicyLevelError or PolicyLevelWarning.
+	Level string `json:"level"`
+}
+
+// exprRule is a policy rule written as an expression in the
+// policies file.
+type exprRule struct {
+	Name        string `yaml:"name"`
+	Description string `yaml:"description"`
+	// Rule is an expression each module must satisfy.
+	Rule  string `yaml:"rule"`
+	Level string `yaml:"level"`
+	rule  *expr.Expr
+}
+
+type exprPolicy struct {
+	Policies []*exprRule `yaml:"policies"`
+}
+
+// NewExprPolicy creates a Policy from the rules in a yaml document.
+// Each rule is an expression with access to the same variables as
+// when expressions of commands. For example,
+//
+// policies:
+//   - name: team
+//     description: Modules must specify the owning team
+//     rule: has(app.properties.team)
+//     level: warning
+func NewExprPolicy(content []byte) (Policy, error) {
+	p := &exprPolicy{}
+	if err := yaml.Unmarshal(content, p); err != nil {
+		return nil, e.Wrap(ErrClassUser, err)
+	}
+
+	for _, r := range p.Policies {
+		if r.Level == "" {
+			r.Level = PolicyLevelError
+		}
+
+		if r.Level != PolicyLevelError && r.Level != PolicyLevelWarning {
+			return nil, e.NewErrorf(ErrClassUser, msgInvalidPolicyLevel, r.Level, r.Name)
+		}
+
+		x, err := expr.Parse(r.Rule)
+		if err != nil {
+			return nil, e.Wrapf(ErrClassUser, err, msgInvalidPolicyRule, r.Name)
+		}
+		r.rule = x
+	}
+
+	return p, nil
+}
+
+func (p *exprPolicy) Evaluate(m *Manifest) ([]*Violation, error) {
+	violations := make([]*Violation, 0)
+	for _, mod := range m.Modules {
+		vars := expressionVars(m, mod)
+		for _, r := range p.Policies {
+			ok, err := r.rule.EvalBool(vars)
+			if err != nil {
+				return nil, e.Wrapf(ErrClassUser, err, msgFailedPolicyRule, r.Name, mod.Name())
+			}
+
+			if !ok {
+				msg := r.Description
+				if msg == "" {
+					msg = r.Rule
+				}
+				violations = append(violations, &Violation{Policy: r.Name, Module: mod.Name(), Message: msg, Level: r.Level})
+			}
+		}
+	}
+
+	return violations, nil
+}
+
+type regoPolicy struct {
+	opa  string
+	path string
+}
+
+// NewRegoPolicy creates a Policy evaluating the Rego policy at path
+// with Open Policy Agent (https://www.openpolicyagent.org) binary opa.
+// Policy must be in package mbt. Messages in deny and warn sets are
+// reported as violations of level error and warning respectively.
+// Input of the policy has the same variables as when expressions
+// of commands.
+func NewRegoPolicy(opa, path string) Policy {
+	if opa == "" {
+		opa = "opa"
+	}
+	return &regoPolicy{opa: opa, path: path}
+}
+
+type regoResult struct {
+	Result []struct {
+		Expressions []struct {
+			Value struct {
+				Deny []string `json:"deny"`
+				Warn []string `json:"warn"`
+			} `json:"value"`
+		} `json:"expressions"`
+	} `json:"result"`
+}
+
+func (p *regoPolicy) Evaluate(m *Manifest) ([]*Violation, error) {
+	name := strings.TrimSuffix(filepath.Base(p.path), filepath.Ext(p.path))
+	violations := make([]*Violation, 0)
+	for _, mod := range m.Modules {
+		input, err := json.Marshal(expressionVars(m, mod))
+		if err != nil {
+			return nil, e.Wrap(ErrClassInternal, err)
+		}
+
+		var stdout, stderr bytes.Buffer
+		cmd := exec.Command(p.opa, "eval", "--format", "json", "--stdin-input", "--data", p.path, "data.mbt")
+		cmd.Stdin = bytes.NewReader(input)
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+		if err := cmd.Run(); err != nil {
+			return nil, e.Wrapf(ErrClassUser, err, msgFailedPolicyEval, p.path, strings.TrimSpace(stderr.String()))
+		}
+
+		r := &regoResult{}
+		if err := json.Unmarshal(stdout.Bytes(), r); err != nil {
+			return nil, e.Wrapf(ErrClassUser, err, msgFailedPolicyEval, p.path, "unexpected output")
+		}
+
+		for _, res := range r.Result {
+			for _, x := range res.Expressions {
+				for _, msg := range x.Value.Deny {
+					violations = append(violations, &Violation{Policy: name, Module: mod.Name(), Message: msg, Level: PolicyLevelError})
+				}
+				for _, msg := range x.Value.Warn {
+					violations = append(violations, &Violation{Policy: name, Module: mod.Name(), Message: msg, Level: PolicyLevelWarning})
+				}
+			}
+		}
+	}
+
+	return violations, nil
+}
+
+// LoadPolicy loads the policy at path. Files with .rego extension
+// are evaluated with opa binary (see NewRegoPolicy). Other files
+// are read as expression policies (see NewExprPolicy).
+func LoadPolicy(path, opa string) (Policy, error) {
+	if filepath.Ext(path) == ".rego" {
+		return NewRegoPolicy(opa, path), nil
+	}
+
+	c, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, e.Wrapf(ErrClassUser, err, msgFailedReadFile, path)
+	}
+
+	p, err := NewExprPolicy(c)
+	if err != nil {
+		return nil, e.Wrapf(ErrClassUser, err, msgInvalidPolicy, path)
+	}
+
+	return p, nil
+}
+
+// EvaluatePolicies evaluates the modules in manifest against each policy.
+// Violations are sorted by module and policy.
+func EvaluatePolicies(m *Manifest, policies ...Policy) ([]*Violation, error) {
+	violations := make([]*Violation, 0)
+	for _, p := range policies {
+		v, err := p.Evaluate(m)
+		if err != nil {
+			return nil, err
+		}
+		violations = append(violations, v...)
+	}
+
+	sort.SliceStable(violations, func(i, j int) bool {
+		if violations[i].Module != violations[j].Module {
+			return violations[i].Module < violations[j].Module
+		}
+		return violations[i].Policy < violations[j].Policy
+	})
+
+	return violations, nil
+}
+
+// policyManifestBuilder is a ManifestBuilder which fails when the
+// modules in a manifest violate policies.
+type policyManifestBuilder struct {
+	ManifestBuilder
+	policies []Policy
+	log      Log
+}
+
+// NewPolicyManifestBuilder creates a ManifestBuilder enforcing policies
+// on the manifests created by mb. Violations of level warning are
+// logged and violations of level error fail the construction of
+// the manifest.
+func NewPolicyManifestBuilder(mb ManifestBuilder, log Log, policies ...Policy) ManifestBuilder {
+	return &policyManifestBuilder{ManifestBuilder: mb, policies: policies, log: log}
+}
+
+func (b *policyManifestBuilder) enforce(m *Manifest, err error) (*Manifest, error) {
+	if err != nil {
+		return nil, err
+	}
+
+	violations, err := EvaluatePolicies(m, b.policies...)
+	if err != nil {
+		return nil, err
+	}
+
+	failed := make([]string, 0)
+	for _, v := range violations {
+		if v.Level == PolicyLevelWarning {
+			b.log.Warnf(msgPolicyViolation, v.Module, v.Policy, v.Message)
+			m.Diagnostics = append(m.Diagnostics[:len(m.Diagnostics):len(m.Diagnostics)], &Diagnostic{
+				Level:   DiagnosticWarning,
+				Module:  v.Module,
+				Message: fmt.Sprintf(msgPolicyWarning, v.Policy, v.Message),
+			})
+		} else {
+			b.log.Errorf(msgPolicyViolation, v.Module, v.Policy, v.Message)
+			failed = append(failed, v.Module+"/"+v.Policy)
+		}
+	}
+
+	if len(failed) > 0 {
+		return nil, e.NewErrorf(ErrClassUser, msgPolicyViolations, strings.Join(failed, ", "))
+	}
+
+	return m, nil
+}
+
+func (b *policyManifestBuilder) ByDiff(from, to Commit) (*Manifest, error) {
+	return b.enforce(b.ManifestBuilder.ByDiff(from, to))
+}
+
+func (b *policyManifestBuilder) ByPr(src, dst string) (*Manifest, error) {
+	return b.enforce(b.ManifestBuilder.ByPr(src, dst))
+}
+
+func (b *policyManifestBuilder) ByCommit(sha Commit) (*Manifest, error) {
+	return b.enforce(b.ManifestBuilder.ByCommit(sha))
+}
+
+func (b *policyManifestBuilder) ByCommitContent(sha Commit) (*Manifest, error) {
+	return b.enforce(b.ManifestBuilder.ByCommitContent(sha))
+}
+
+func (b *policyManifestBuilder) Advance(prev *Manifest, to Commit) (*Manifest, error) {
+	return b.enforce(b.ManifestBuilder.Advance(prev, to))
+}
+
+func (b *policyManifestBuilder) ByBranch(name string) (*Manifest, error) {
+	return b.enforce(b.ManifestBuilder.ByBranch(name))
+}
+
+func (b *policyManifestBuilder) ByCurrentBranch() (*Manifest, error) {
+	return b.enforce(b.ManifestBuilder.ByCurrentBranch())
+}
+
+func (b *policyManifestBuilder) ByWorkspace() (*Manifest, error) {
+	return b.enforce(b.ManifestBuilder.ByWorkspace())
+}
+
+func (b *policyManifestBuilder) ByWorkspaceChanges() (*Manifest, error) {
+	return b.enforce(b.ManifestBuilder.ByWorkspaceChanges())
+}
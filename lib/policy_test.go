@@ -0,0 +1,110 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testPolicies = `
+policies:
+  - name: team
+    description: Modules must specify the owning team
+    rule: has(app.properties.team)
+  - name: naming
+    rule: app.name.matches('^app-')
+    level: warning
+`
+
+func policyTestManifest() *Manifest {
+	a := newTestModule("app-a", "app-a", "a1")
+	a.metadata.spec.Properties = map[string]interface{}{"team": "payments"}
+	b := newTestModule("lib-b", "lib-b", "b1")
+	b.metadata.spec.Properties = map[string]interface{}{}
+	return &Manifest{Sha: "abc", Modules: Modules{a, b}}
+}
+
+type staticManifestBuilder struct {
+	ManifestBuilder
+	manifest *Manifest
+}
+
+func (b *staticManifestBuilder) ByWorkspace() (*Manifest, error) {
+	return b.manifest, nil
+}
+
+func TestExprPolicy(t *testing.T) {
+	p, err := NewExprPolicy([]byte(testPolicies))
+	check(t, err)
+
+	v, err := EvaluatePolicies(policyTestManifest(), p)
+	check(t, err)
+
+	assert.Equal(t, []*Violation{
+		{Policy: "naming", Module: "lib-b", Message: "app.name.matches('^app-')", Level: PolicyLevelWarning},
+		{Policy: "team", Module: "lib-b", Message: "Modules must specify the owning team", Level: PolicyLevelError},
+	}, v)
+}
+
+func TestInvalidExprPolicy(t *testing.T) {
+	_, err := NewExprPolicy([]byte("policies:\n  - name: a\n    rule: 'true'\n    level: fatal\n"))
+	assert.EqualError(t, err, "Invalid level 'fatal' in policy 'a' (expected error or warning)")
+
+	_, err = NewExprPolicy([]byte("policies:\n  - name: a\n    rule: 'a =='\n"))
+	assert.EqualError(t, err, "Invalid rule in policy 'a'")
+}
+
+func TestPolicyManifestBuilder(t *testing.T) {
+	p, err := NewExprPolicy([]byte(testPolicies))
+	check(t, err)
+
+	mb := NewPolicyManifestBuilder(&staticManifestBuilder{manifest: policyTestManifest()}, NewStdLog(LogLevelNormal), p)
+	_, err = mb.ByWorkspace()
+	assert.EqualError(t, err, "Policy violations in lib-b/team")
+
+	p, err = NewExprPolicy([]byte("policies:\n  - name: naming\n    rule: app.name.matches('^app-')\n    level: warning\n"))
+	check(t, err)
+
+	mb = NewPolicyManifestBuilder(&staticManifestBuilder{manifest: policyTestManifest()}, NewStdLog(LogLevelNormal), p)
+	m, err := mb.ByWorkspace()
+	check(t, err)
+	assert.Len(t, m.Modules, 2)
+}
+
+func TestRegoPolicy(t *testing.T) {
+	clean()
+	check(t, os.MkdirAll(".tmp", 0755))
+	// Fake opa binary denying the modules without a team property.
+	check(t, ioutil.WriteFile(".tmp/opa", []byte(`#!/bin/sh
+if grep -q '"team"'; then
+  echo '{"result":[{"expressions":[{"value":{"warn":["no tests"]}}]}]}'
+else
+  echo '{"result":[{"expressions":[{"value":{"deny":["team is required"]}}]}]}'
+fi
+`), 0755))
+
+	v, err := EvaluatePolicies(policyTestManifest(), NewRegoPolicy(".tmp/opa", "policies/modules.rego"))
+	check(t, err)
+
+	assert.Equal(t, []*Violation{
+		{Policy: "modules", Module: "app-a", Message: "no tests", Level: PolicyLevelWarning},
+		{Policy: "modules", Module: "lib-b", Message: "team is required", Level: PolicyLevelError},
+	}, v)
+}
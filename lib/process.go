@@ -0,0 +1,169 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"context"
+	"os/exec"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/mbtproject/mbt/e"
+)
+
+// runProcess runs the command and waits for it to complete.
+// If timeout is greater than zero and the command does not complete
+// within that time, entire process group of the command is killed.
+// This makes sure that we do not leave behind any child processes
+// spawned by the command (e.g. build scripts).
+// Commands without a timeout stay in the process group of mbt so that
+// they receive signals from the terminal (e.g. Ctrl-C) and can read
+// from it.
+func runProcess(cmd *exec.Cmd, timeout time.Duration) error {
+	if timeout <= 0 {
+		return cmd.Run()
+	}
+
+	setProcessGroup(cmd)
+
+	err := cmd.Start()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		killProcessGroup(cmd)
+		<-done
+		return e.NewErrorf(ErrClassUser, msgCommandTimeout, timeout)
+	}
+}
+
+// parseTimeout converts the timeout specified in a spec to a
+// time.Duration. Empty string represents no timeout.
+func parseTimeout(cmd, timeout string) (time.Duration, error) {
+	if timeout == "" {
+		return 0, nil
+	}
+
+	d, err := time.ParseDuration(timeout)
+	if err != nil || d < 0 {
+		return 0, e.NewErrorf(ErrClassUser, msgInvalidTimeout, timeout, cmd)
+	}
+
+	return d, nil
+}
+
+// execWithRetries executes a command via ProcessManager retrying up to
+// the specified number of times on failure. Command is executed in
+// workDir when specified.
+func (s *stdSystem) execWithRetries(manifest *Manifest, module *Module, options *CmdOptions, timeout string, retries int, workDir string, command string, args ...string) error {
+	d, err := parseTimeout(command, timeout)
+	if err != nil {
+		return err
+	}
+
+	if d > 0 {
+		o := *options
+		o.Timeout = d
+		options = &o
+	}
+
+	if workDir != "" {
+		dir, err := resolveWorkDir(module, workDir)
+		if err != nil {
+			return err
+		}
+
+		o := *options
+		o.WorkDir = dir
+		options = &o
+		command = commandInWorkDir(module, dir, command)
+	}
+
+	command, args = interpolateCmd(command, args, options.AllowEnv)
+
+	attempts := retries + 1
+	for i := 1; i <= attempts; i++ {
+		if i > 1 {
+			s.Log.Warnf(msgRetryingCommand, command, module.Name(), i, attempts)
+		}
+
+		err = s.ProcessManager.Exec(manifest, module, options, command, args...)
+		if err == nil {
+			return nil
+		}
+	}
+
+	return err
+}
+
+// resolveWorkDir returns the path, relative to the repository root, of
+// the work directory of a command. Work directory is relative to the
+// module directory unless it starts with /, in which case it is relative
+// to the repository root. Repository root is represented as ".".
+func resolveWorkDir(module *Module, workDir string) (string, error) {
+	var dir string
+	if strings.HasPrefix(workDir, "/") {
+		dir = path.Clean(strings.TrimPrefix(workDir, "/"))
+	} else {
+		dir = path.Clean(path.Join(module.Path(), workDir))
+	}
+
+	if dir == ".." || strings.HasPrefix(dir, "../") {
+		return "", e.NewErrorf(ErrClassUser, msgInvalidWorkDir, workDir, module.Name())
+	}
+
+	if dir == "" {
+		dir = "."
+	}
+
+	return dir, nil
+}
+
+// commandInWorkDir rewrites a relative command path (e.g. ./build.sh),
+// which is always relative to the module directory, so that it can be
+// resolved from the work directory. Commands found in PATH are returned
+// as they are.
+func commandInWorkDir(module *Module, dir, command string) string {
+	if !strings.Contains(command, "/") || path.IsAbs(command) {
+		return command
+	}
+
+	target := path.Join(module.Path(), command)
+	depth := 0
+	if dir != "." {
+		depth = len(strings.Split(dir, "/"))
+	}
+
+	rel := strings.Repeat("../", depth) + target
+	if !strings.HasPrefix(rel, "../") {
+		rel = "./" + rel
+	}
+
+	return rel
+}
@@ -24,29 +24,58 @@ import (
 )
 
 type stdProcessManager struct {
-	Log Log
+	Log      Log
+	Executor Executor
 }
 
 func (p *stdProcessManager) Exec(manifest *Manifest, module *Module, options *CmdOptions, command string, args ...string) error {
-	cmd := exec.Command(command)
-	cmd.Env = append(os.Environ(), p.setupModBuildEnvironment(manifest, module)...)
-	cmd.Dir = path.Join(manifest.Dir, module.Path())
-	cmd.Stdin = options.Stdin
-	cmd.Stdout = options.Stdout
-	cmd.Stderr = options.Stderr
-	cmd.Args = append(cmd.Args, args...)
-	return cmd.Run()
+	ctx := &ExecContext{
+		Manifest: manifest,
+		Module:   module,
+		Options:  options,
+		Command:  command,
+		Args:     args,
+	}
+
+	err := p.Executor.Prepare(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = p.Executor.Run(ctx)
+	collectErr := p.Executor.Collect(ctx, err)
+	if err != nil {
+		return err
+	}
+
+	return collectErr
 }
 
-func (p *stdProcessManager) setupModBuildEnvironment(manifest *Manifest, mod *Module) []string {
+func setupModBuildEnvironment(manifest *Manifest, mod *Module) []string {
 	r := []string{
 		fmt.Sprintf("MBT_BUILD_COMMIT=%s", manifest.Sha),
 		fmt.Sprintf("MBT_MODULE_VERSION=%s", mod.Version()),
 		fmt.Sprintf("MBT_MODULE_NAME=%s", mod.Name()),
 		fmt.Sprintf("MBT_MODULE_PATH=%s", mod.Path()),
+		fmt.Sprintf("MBT_MODULE_GROUP=%s", mod.Group()),
 		fmt.Sprintf("MBT_REPO_PATH=%s", manifest.Dir),
 	}
 
+	if n := mod.BuildNumber(); n > 0 {
+		r = append(r, fmt.Sprintf("MBT_BUILD_NUMBER=%v", n))
+	}
+
+	if v := mod.SnapshotVersion(); v != "" {
+		r = append(r, fmt.Sprintf("MBT_SNAPSHOT_VERSION=%s", v))
+	}
+
+	if v := mod.Variant(); v != nil {
+		r = append(r, fmt.Sprintf("MBT_VARIANT=%s", v.Name))
+		for k, x := range v.Values {
+			r = append(r, fmt.Sprintf("MBT_VARIANT_%s=%s", strings.ToUpper(k), x))
+		}
+	}
+
 	for k, v := range mod.Properties() {
 		if value, ok := v.(string); ok {
 			r = append(r, fmt.Sprintf("MBT_MODULE_PROPERTY_%s=%s", strings.ToUpper(k), value))
@@ -56,7 +85,47 @@ func (p *stdProcessManager) setupModBuildEnvironment(manifest *Manifest, mod *Mo
 	return r
 }
 
-// NewProcessManager creates an instance of ProcessManager.
+// Environment returns the mbt environment variables for the command
+// in ctx. repoDir is the path to repository in the environment
+// the command is executed.
+func (ctx *ExecContext) Environment(repoDir string) []string {
+	return setupModBuildEnvironment(&Manifest{Dir: repoDir, Sha: ctx.Manifest.Sha, Modules: ctx.Manifest.Modules}, ctx.Module)
+}
+
+// NewProcessManager creates an instance of ProcessManager that
+// executes commands in local host.
 func NewProcessManager(log Log) ProcessManager {
-	return &stdProcessManager{Log: log}
+	return NewProcessManagerWithExecutor(log, NewHostExecutor())
+}
+
+// NewProcessManagerWithExecutor creates an instance of ProcessManager
+// that executes commands with the specified Executor.
+func NewProcessManagerWithExecutor(log Log, executor Executor) ProcessManager {
+	return &stdProcessManager{Log: log, Executor: executor}
+}
+
+type hostExecutor struct{}
+
+// NewHostExecutor creates an Executor that runs commands in local host.
+func NewHostExecutor() Executor {
+	return &hostExecutor{}
+}
+
+func (x *hostExecutor) Prepare(ctx *ExecContext) error {
+	return nil
+}
+
+func (x *hostExecutor) Run(ctx *ExecContext) error {
+	cmd := exec.Command(ctx.Command)
+	cmd.Env = append(os.Environ(), ctx.Environment(ctx.Manifest.Dir)...)
+	cmd.Dir = path.Join(ctx.Manifest.Dir, ctx.Dir())
+	cmd.Stdin = ctx.Options.Stdin
+	cmd.Stdout = ctx.Options.Stdout
+	cmd.Stderr = ctx.Options.Stderr
+	cmd.Args = append(cmd.Args, ctx.Args...)
+	return runProcess(cmd, ctx.Options.Timeout)
+}
+
+func (x *hostExecutor) Collect(ctx *ExecContext, runErr error) error {
+	return nil
 }
@@ -0,0 +1,78 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type recordingExecutor struct {
+	stages     []string
+	prepareErr error
+	runErr     error
+	collected  error
+}
+
+func (x *recordingExecutor) Prepare(ctx *ExecContext) error {
+	x.stages = append(x.stages, "prepare")
+	ctx.State = "prepared"
+	return x.prepareErr
+}
+
+func (x *recordingExecutor) Run(ctx *ExecContext) error {
+	x.stages = append(x.stages, "run:"+ctx.State.(string))
+	return x.runErr
+}
+
+func (x *recordingExecutor) Collect(ctx *ExecContext, runErr error) error {
+	x.stages = append(x.stages, "collect")
+	x.collected = runErr
+	return nil
+}
+
+func TestProcessManagerExecutorStages(t *testing.T) {
+	x := &recordingExecutor{}
+	pm := NewProcessManagerWithExecutor(NewStdLog(LogLevelNormal), x)
+
+	err := pm.Exec(&Manifest{}, newTestModule("app-a", "app-a", "v"), stdTestCmdOptions(nil), "cmd")
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"prepare", "run:prepared", "collect"}, x.stages)
+}
+
+func TestProcessManagerCollectsFailedRuns(t *testing.T) {
+	x := &recordingExecutor{runErr: errors.New("doh")}
+	pm := NewProcessManagerWithExecutor(NewStdLog(LogLevelNormal), x)
+
+	err := pm.Exec(&Manifest{}, newTestModule("app-a", "app-a", "v"), stdTestCmdOptions(nil), "cmd")
+
+	assert.EqualError(t, err, "doh")
+	assert.EqualError(t, x.collected, "doh")
+	assert.Equal(t, []string{"prepare", "run:prepared", "collect"}, x.stages)
+}
+
+func TestProcessManagerSkipsRunWhenPrepareFails(t *testing.T) {
+	x := &recordingExecutor{prepareErr: errors.New("doh")}
+	pm := NewProcessManagerWithExecutor(NewStdLog(LogLevelNormal), x)
+
+	err := pm.Exec(&Manifest{}, newTestModule("app-a", "app-a", "v"), stdTestCmdOptions(nil), "cmd")
+
+	assert.EqualError(t, err, "doh")
+	assert.Equal(t, []string{"prepare"}, x.stages)
+}
@@ -0,0 +1,111 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"fmt"
+	"os/exec"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTimeout(t *testing.T) {
+	d, err := parseTimeout("make", "")
+	check(t, err)
+	assert.Equal(t, time.Duration(0), d)
+
+	d, err = parseTimeout("make", "1m30s")
+	check(t, err)
+	assert.Equal(t, 90*time.Second, d)
+
+	_, err = parseTimeout("make", "soon")
+	assert.EqualError(t, err, fmt.Sprintf(msgInvalidTimeout, "soon", "make"))
+
+	_, err = parseTimeout("make", "-1s")
+	assert.EqualError(t, err, fmt.Sprintf(msgInvalidTimeout, "-1s", "make"))
+}
+
+func TestRunProcessTimeoutKillsProcessGroup(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.SkipNow()
+	}
+
+	start := time.Now()
+	err := runProcess(exec.Command("sh", "-c", "sleep 10 & sleep 10"), 100*time.Millisecond)
+
+	assert.EqualError(t, err, fmt.Sprintf(msgCommandTimeout, 100*time.Millisecond))
+	assert.True(t, time.Since(start) < 5*time.Second)
+}
+
+func TestRunProcessWithoutTimeout(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.SkipNow()
+	}
+
+	assert.NoError(t, runProcess(exec.Command("true"), 0))
+	assert.Error(t, runProcess(exec.Command("false"), time.Minute))
+}
+
+func TestRunProcessWithoutTimeoutKeepsProcessGroup(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.SkipNow()
+	}
+
+	cmd := exec.Command("true")
+	check(t, runProcess(cmd, 0))
+	assert.Nil(t, cmd.SysProcAttr)
+
+	cmd = exec.Command("true")
+	check(t, runProcess(cmd, time.Minute))
+	assert.NotNil(t, cmd.SysProcAttr)
+}
+
+func TestResolveWorkDir(t *testing.T) {
+	mod := newTestModule("app-a", "apps/app-a", "v1")
+
+	for workDir, expected := range map[string]string{
+		"/":        ".",
+		"/tools":   "tools",
+		"src":      "apps/app-a/src",
+		"..":       "apps",
+		"../../":   ".",
+		"./src/..": "apps/app-a",
+	} {
+		dir, err := resolveWorkDir(mod, workDir)
+		check(t, err)
+		assert.Equal(t, expected, dir, workDir)
+	}
+
+	_, err := resolveWorkDir(mod, "../../..")
+	assert.EqualError(t, err, fmt.Sprintf(msgInvalidWorkDir, "../../..", "app-a"))
+
+	_, err = resolveWorkDir(mod, "/../x")
+	assert.EqualError(t, err, fmt.Sprintf(msgInvalidWorkDir, "/../x", "app-a"))
+}
+
+func TestCommandInWorkDir(t *testing.T) {
+	mod := newTestModule("app-a", "apps/app-a", "v1")
+
+	assert.Equal(t, "docker", commandInWorkDir(mod, ".", "docker"))
+	assert.Equal(t, "/usr/bin/make", commandInWorkDir(mod, ".", "/usr/bin/make"))
+	assert.Equal(t, "./apps/app-a/build.sh", commandInWorkDir(mod, ".", "./build.sh"))
+	assert.Equal(t, "./tools/lint.sh", commandInWorkDir(mod, ".", "../../tools/lint.sh"))
+	assert.Equal(t, "../apps/app-a/build.sh", commandInWorkDir(mod, "tools", "./build.sh"))
+	assert.Equal(t, "../../../apps/app-a/scripts/build.sh", commandInWorkDir(mod, "apps/app-a/src", "./scripts/build.sh"))
+}
@@ -0,0 +1,188 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"sync"
+	"time"
+)
+
+// Phases of creating a manifest recorded by Profiler.
+const (
+	// PhaseDiscover is the discovery of modules in a commit or the
+	// workspace. It includes PhaseTreeWalk and PhaseSpecParse.
+	PhaseDiscover = "discover"
+	// PhaseTreeWalk is walking the git tree or the workspace looking
+	// for spec files.
+	PhaseTreeWalk = "tree walk"
+	// PhaseSpecParse is parsing the spec files.
+	PhaseSpecParse = "spec parse"
+	// PhaseMergeBase is finding the merge base of commits.
+	PhaseMergeBase = "merge base"
+	// PhaseDiff is computing the changes between commits or in the
+	// workspace.
+	PhaseDiff = "diff"
+	// PhaseReduce is the reduction of modules to the changed ones.
+	PhaseReduce = "reduce"
+)
+
+// PhaseStats is the time spent in a phase.
+type PhaseStats struct {
+	Phase string
+	Calls int
+	Total time.Duration
+	Min   time.Duration
+	Max   time.Duration
+}
+
+// Mean returns the average duration of a call.
+func (s *PhaseStats) Mean() time.Duration {
+	if s.Calls == 0 {
+		return 0
+	}
+	return s.Total / time.Duration(s.Calls)
+}
+
+// Profiler records the time spent in the phases of creating
+// manifests. A nil Profiler records nothing.
+type Profiler struct {
+	mu     sync.Mutex
+	phases map[string]*PhaseStats
+	order  []string
+}
+
+// NewProfiler creates a new Profiler.
+func NewProfiler() *Profiler {
+	return &Profiler{phases: make(map[string]*PhaseStats)}
+}
+
+// Start starts timing a call in the specified phase. Returned
+// function stops it.
+func (p *Profiler) Start(phase string) func() {
+	if p == nil {
+		return func() {}
+	}
+
+	start := time.Now()
+	return func() {
+		p.record(phase, time.Since(start))
+	}
+}
+
+func (p *Profiler) record(phase string, d time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	s, ok := p.phases[phase]
+	if !ok {
+		s = &PhaseStats{Phase: phase, Min: d}
+		p.phases[phase] = s
+		p.order = append(p.order, phase)
+	}
+
+	s.Calls++
+	s.Total += d
+	if d < s.Min {
+		s.Min = d
+	}
+	if d > s.Max {
+		s.Max = d
+	}
+}
+
+// Stats returns the statistics of the recorded phases in the order
+// they were first recorded.
+func (p *Profiler) Stats() []*PhaseStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	stats := make([]*PhaseStats, 0, len(p.order))
+	for _, phase := range p.order {
+		s := *p.phases[phase]
+		stats = append(stats, &s)
+	}
+	return stats
+}
+
+type profiledRepo struct {
+	Repo
+	profiler *Profiler
+}
+
+func (r *profiledRepo) WalkBlobs(a Commit, callback BlobWalkCallback) error {
+	defer r.profiler.Start(PhaseTreeWalk)()
+	return r.Repo.WalkBlobs(a, callback)
+}
+
+func (r *profiledRepo) FindAllFilesInWorkspace(pathSpec []string) ([]string, error) {
+	defer r.profiler.Start(PhaseTreeWalk)()
+	return r.Repo.FindAllFilesInWorkspace(pathSpec)
+}
+
+func (r *profiledRepo) Diff(a, b Commit) ([]*DiffDelta, error) {
+	defer r.profiler.Start(PhaseDiff)()
+	return r.Repo.Diff(a, b)
+}
+
+func (r *profiledRepo) DiffMergeBase(from, to Commit) ([]*DiffDelta, error) {
+	defer r.profiler.Start(PhaseDiff)()
+	return r.Repo.DiffMergeBase(from, to)
+}
+
+func (r *profiledRepo) DiffWorkspace() ([]*DiffDelta, error) {
+	defer r.profiler.Start(PhaseDiff)()
+	return r.Repo.DiffWorkspace()
+}
+
+func (r *profiledRepo) Changes(c Commit) ([]*DiffDelta, error) {
+	defer r.profiler.Start(PhaseDiff)()
+	return r.Repo.Changes(c)
+}
+
+func (r *profiledRepo) MergeBase(a, b Commit) (Commit, error) {
+	defer r.profiler.Start(PhaseMergeBase)()
+	return r.Repo.MergeBase(a, b)
+}
+
+type profiledDiscover struct {
+	Discover
+	profiler *Profiler
+}
+
+func (d *profiledDiscover) ModulesInCommit(commit Commit) (Modules, error) {
+	defer d.profiler.Start(PhaseDiscover)()
+	return d.Discover.ModulesInCommit(commit)
+}
+
+func (d *profiledDiscover) ModulesInWorkspace() (Modules, error) {
+	defer d.profiler.Start(PhaseDiscover)()
+	return d.Discover.ModulesInWorkspace()
+}
+
+func (d *profiledDiscover) AdvanceModules(prev Modules, from, to Commit) (Modules, error) {
+	defer d.profiler.Start(PhaseDiscover)()
+	return d.Discover.AdvanceModules(prev, from, to)
+}
+
+type profiledReducer struct {
+	Reducer
+	profiler *Profiler
+}
+
+func (r *profiledReducer) Reduce(modules Modules, deltas []*DiffDelta) (Modules, error) {
+	defer r.profiler.Start(PhaseReduce)()
+	return r.Reducer.Reduce(modules, deltas)
+}
@@ -0,0 +1,57 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProfilerStats(t *testing.T) {
+	p := NewProfiler()
+	p.record(PhaseDiff, 3*time.Millisecond)
+	p.record(PhaseReduce, 2*time.Millisecond)
+	p.record(PhaseDiff, 1*time.Millisecond)
+
+	stats := p.Stats()
+
+	assert.Equal(t, []*PhaseStats{
+		{Phase: PhaseDiff, Calls: 2, Total: 4 * time.Millisecond, Min: time.Millisecond, Max: 3 * time.Millisecond},
+		{Phase: PhaseReduce, Calls: 1, Total: 2 * time.Millisecond, Min: 2 * time.Millisecond, Max: 2 * time.Millisecond},
+	}, stats)
+	assert.Equal(t, 2*time.Millisecond, stats[0].Mean())
+}
+
+func TestNilProfiler(t *testing.T) {
+	var p *Profiler
+	p.Start(PhaseDiff)()
+}
+
+func TestProfiledReducer(t *testing.T) {
+	p := NewProfiler()
+	r := &profiledReducer{Reducer: NewReducer(NewStdLog(LogLevelNormal)), profiler: p}
+	mods := Modules{newTestModule("app-a", "app-a", "a"), newTestModule("app-b", "app-b", "b")}
+
+	reduced, err := r.Reduce(mods, []*DiffDelta{{NewFile: "app-a/main.go"}})
+	check(t, err)
+
+	assert.Equal(t, Modules{mods[0]}, reduced)
+	assert.Len(t, p.Stats(), 1)
+	assert.Equal(t, PhaseReduce, p.Stats()[0].Phase)
+	assert.Equal(t, 1, p.Stats()[0].Calls)
+}
@@ -0,0 +1,249 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	"github.com/mbtproject/mbt/e"
+)
+
+const (
+	inTotoStatementType = "https://in-toto.io/Statement/v1"
+	slsaProvenanceType  = "https://slsa.dev/provenance/v1"
+	mbtBuildType        = "https://github.com/mbtproject/mbt/build@v1"
+)
+
+// ProvenanceOptions describes how the SLSA provenance of built
+// modules is generated.
+type ProvenanceOptions struct {
+	// Dir is where provenance statements are written.
+	// Statement of each module is stored in <Dir>/<module>-<version>.intoto.json.
+	Dir string
+	// BuilderID identifies the platform running the build
+	// (e.g. URL of the CI pipeline).
+	BuilderID string
+	// RepoURI is the URI of the repository built. Defaults to the
+	// path to the repository.
+	RepoURI string
+	// Sign signs each statement with cosign keyless signing. Signature
+	// bundle is written next to the statement with .bundle extension.
+	Sign bool
+	// Cosign is the path to cosign binary.
+	Cosign string
+	// StartedOn is the time the build started.
+	StartedOn time.Time
+}
+
+type inTotoStatement struct {
+	Type          string           `json:"_type"`
+	Subject       []*inTotoSubject `json:"subject"`
+	PredicateType string           `json:"predicateType"`
+	Predicate     *slsaProvenance  `json:"predicate"`
+}
+
+type inTotoSubject struct {
+	Name   string            `json:"name"`
+	Digest map[string]string `json:"digest"`
+}
+
+type slsaProvenance struct {
+	BuildDefinition *slsaBuildDefinition `json:"buildDefinition"`
+	RunDetails      *slsaRunDetails      `json:"runDetails"`
+}
+
+type slsaBuildDefinition struct {
+	BuildType            string                 `json:"buildType"`
+	ExternalParameters   map[string]interface{} `json:"externalParameters"`
+	ResolvedDependencies []*inTotoSubject       `json:"resolvedDependencies"`
+}
+
+type slsaRunDetails struct {
+	Builder  map[string]string `json:"builder"`
+	Metadata map[string]string `json:"metadata"`
+}
+
+// fileDigest returns the hex encoded SHA-256 digest of a file.
+func fileDigest(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", e.Wrapf(ErrClassUser, err, msgFailedReadFile, path)
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", e.Wrapf(ErrClassUser, err, msgFailedReadFile, path)
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// GenerateProvenance writes an in-toto statement with SLSA provenance
+// for each module completed in summary. Subjects of a statement are
+// the artifacts of the module (see NewArtifactManifest) and its
+// dependencies are the repository commit and the versions of the
+// modules it depends on. Modules without artifacts are skipped.
+// Returns the paths to the statements.
+func GenerateProvenance(summary *BuildSummary, options *ProvenanceOptions) ([]string, error) {
+	artifacts, err := NewArtifactManifest(summary)
+	if err != nil {
+		return nil, err
+	}
+
+	repoURI := options.RepoURI
+	if repoURI == "" {
+		repoURI = "git+file://" + filepath.ToSlash(summary.Manifest.Dir)
+	}
+
+	if err := os.MkdirAll(options.Dir, 0755); err != nil {
+		return nil, e.Wrapf(ErrClassUser, err, msgFailedWriteFile, options.Dir)
+	}
+
+	paths := make([]string, 0)
+	for i, r := range summary.Completed {
+		a := artifacts.Modules[i]
+		if len(a.Artifacts) == 0 {
+			continue
+		}
+
+		subjects := make([]*inTotoSubject, 0, len(a.Artifacts))
+		for _, f := range a.Artifacts {
+			subjects = append(subjects, &inTotoSubject{Name: f.Path, Digest: map[string]string{"sha256": f.Digest}})
+		}
+
+		dependencies := []*inTotoSubject{{Name: repoURI, Digest: map[string]string{"gitCommit": summary.Manifest.Sha}}}
+		for _, d := range r.Module.Requires() {
+			dependencies = append(dependencies, &inTotoSubject{Name: "mbt:module/" + d.Name(), Digest: map[string]string{"mbtVersion": d.Version()}})
+		}
+
+		statement := &inTotoStatement{
+			Type:          inTotoStatementType,
+			Subject:       subjects,
+			PredicateType: slsaProvenanceType,
+			Predicate: &slsaProvenance{
+				BuildDefinition: &slsaBuildDefinition{
+					BuildType: mbtBuildType,
+					ExternalParameters: map[string]interface{}{
+						"module":  a.Name,
+						"path":    r.Module.Path(),
+						"version": a.Version,
+						"branch":  summary.Manifest.Branch,
+					},
+					ResolvedDependencies: dependencies,
+				},
+				RunDetails: &slsaRunDetails{
+					Builder: map[string]string{"id": options.BuilderID},
+					Metadata: map[string]string{
+						"invocationId": fmt.Sprintf("%s-%s", a.Name, a.Version),
+						"startedOn":    options.StartedOn.UTC().Format(time.RFC3339),
+						"finishedOn":   options.StartedOn.Add(r.Duration).UTC().Format(time.RFC3339),
+					},
+				},
+			},
+		}
+
+		c, err := json.MarshalIndent(statement, "", "  ")
+		if err != nil {
+			return nil, e.Wrap(ErrClassInternal, err)
+		}
+
+		path := filepath.Join(options.Dir, fmt.Sprintf("%s-%s.intoto.json", a.Name, a.Version))
+		if err := ioutil.WriteFile(path, c, 0644); err != nil {
+			return nil, e.Wrapf(ErrClassUser, err, msgFailedWriteFile, path)
+		}
+
+		if options.Sign {
+			if err := SignFile(options.Cosign, path); err != nil {
+				return nil, err
+			}
+		}
+
+		paths = append(paths, path)
+	}
+
+	return paths, nil
+}
+
+// SignatureOptions describes how the signature of a file is verified.
+type SignatureOptions struct {
+	// Cosign is the path to cosign binary.
+	Cosign string
+	// CertificateIdentity is the identity expected in the signing
+	// certificate (e.g. email address or workflow URL).
+	CertificateIdentity string
+	// CertificateOIDCIssuer is the OIDC issuer expected in the
+	// signing certificate.
+	CertificateOIDCIssuer string
+}
+
+// SignFile signs a file with cosign keyless signing. Signature bundle
+// is written next to the file with .bundle extension.
+func SignFile(cosign, path string) error {
+	if cosign == "" {
+		cosign = "cosign"
+	}
+
+	if err := RequireNetwork("Keyless signing of " + path); err != nil {
+		return err
+	}
+
+	out, err := exec.Command(cosign, "sign-blob", "--yes", "--bundle", path+".bundle", path).CombinedOutput()
+	if err != nil {
+		return e.Wrapf(ErrClassUser, err, msgFailedSign, path, string(out))
+	}
+
+	return nil
+}
+
+// VerifySignature verifies the signature bundle of a file created
+// by SignFile.
+func VerifySignature(path string, options *SignatureOptions) error {
+	cosign := options.Cosign
+	if cosign == "" {
+		cosign = "cosign"
+	}
+
+	args := []string{"verify-blob", "--bundle", path + ".bundle"}
+	if options.CertificateIdentity != "" {
+		args = append(args, "--certificate-identity", options.CertificateIdentity)
+	}
+	if options.CertificateOIDCIssuer != "" {
+		args = append(args, "--certificate-oidc-issuer", options.CertificateOIDCIssuer)
+	}
+	// Transparency log entry embedded in the bundle is verified
+	// without contacting the log.
+	if IsOffline() {
+		args = append(args, "--offline")
+	}
+	args = append(args, path)
+
+	out, err := exec.Command(cosign, args...).CombinedOutput()
+	if err != nil {
+		return e.Wrapf(ErrClassUser, err, msgInvalidSignature, path, string(out))
+	}
+
+	return nil
+}
@@ -0,0 +1,132 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func provenanceTestSummary(t *testing.T) *BuildSummary {
+	writeAuditFile(t, ".tmp/repo/app-a/dist/a.tar.gz", "a")
+
+	dir, err := filepath.Abs(".tmp/repo")
+	check(t, err)
+
+	b := newTestModule("lib-b", "lib-b", "b1")
+	a := newModule(newModuleMetadata("app-a", "a", &Spec{Name: "app-a", Outputs: []string{"dist"}}, nil), Modules{b})
+	a.version = "a1"
+
+	return &BuildSummary{
+		Manifest:  &Manifest{Dir: dir, Sha: "abc", Branch: "master"},
+		Completed: []*BuildResult{{Module: a, Duration: time.Minute}, {Module: b}},
+	}
+}
+
+func TestGenerateProvenance(t *testing.T) {
+	clean()
+	started := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	paths, err := GenerateProvenance(provenanceTestSummary(t), &ProvenanceOptions{
+		Dir:       ".tmp/provenance",
+		BuilderID: "https://ci.example.com",
+		RepoURI:   "git+https://example.com/repo",
+		StartedOn: started,
+	})
+	check(t, err)
+	assert.Equal(t, []string{filepath.Join(".tmp/provenance", "app-a-a1.intoto.json")}, paths)
+
+	c, err := ioutil.ReadFile(paths[0])
+	check(t, err)
+	s := &inTotoStatement{}
+	check(t, json.Unmarshal(c, s))
+
+	assert.Equal(t, inTotoStatementType, s.Type)
+	assert.Equal(t, slsaProvenanceType, s.PredicateType)
+	assert.Equal(t, []*inTotoSubject{
+		// sha256 of "a"
+		{Name: "app-a/dist/a.tar.gz", Digest: map[string]string{"sha256": "ca978112ca1bbdcafac231b39a23dc4da786eff8147c4e72b9807785afee48bb"}},
+	}, s.Subject)
+	assert.Equal(t, []*inTotoSubject{
+		{Name: "git+https://example.com/repo", Digest: map[string]string{"gitCommit": "abc"}},
+		{Name: "mbt:module/lib-b", Digest: map[string]string{"mbtVersion": "b1"}},
+	}, s.Predicate.BuildDefinition.ResolvedDependencies)
+	assert.Equal(t, "a1", s.Predicate.BuildDefinition.ExternalParameters["version"])
+	assert.Equal(t, "https://ci.example.com", s.Predicate.RunDetails.Builder["id"])
+	assert.Equal(t, "2020-01-02T03:04:05Z", s.Predicate.RunDetails.Metadata["startedOn"])
+	assert.Equal(t, "2020-01-02T03:05:05Z", s.Predicate.RunDetails.Metadata["finishedOn"])
+}
+
+func TestSignProvenance(t *testing.T) {
+	clean()
+	check(t, os.MkdirAll(".tmp", 0755))
+	// Fake cosign binary writing the bundle specified in its arguments.
+	check(t, ioutil.WriteFile(".tmp/cosign", []byte(`#!/bin/sh
+echo signed > "$4"
+`), 0755))
+
+	paths, err := GenerateProvenance(provenanceTestSummary(t), &ProvenanceOptions{
+		Dir:    ".tmp/provenance",
+		Sign:   true,
+		Cosign: ".tmp/cosign",
+	})
+	check(t, err)
+
+	c, err := ioutil.ReadFile(paths[0] + ".bundle")
+	check(t, err)
+	assert.Equal(t, "signed\n", string(c))
+}
+
+func TestSignProvenanceFailure(t *testing.T) {
+	clean()
+	check(t, os.MkdirAll(".tmp", 0755))
+	check(t, ioutil.WriteFile(".tmp/cosign", []byte("#!/bin/sh\necho no identity token\nexit 1\n"), 0755))
+
+	_, err := GenerateProvenance(provenanceTestSummary(t), &ProvenanceOptions{
+		Dir:    ".tmp/provenance",
+		Sign:   true,
+		Cosign: ".tmp/cosign",
+	})
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "no identity token")
+}
+
+func TestVerifySignature(t *testing.T) {
+	clean()
+	check(t, os.MkdirAll(".tmp", 0755))
+	// Fake cosign binary accepting the bundles containing "signed".
+	check(t, ioutil.WriteFile(".tmp/cosign", []byte(`#!/bin/sh
+grep -q signed "$3" || { echo invalid bundle; exit 1; }
+test "$5" = "ci@example.com" || { echo unexpected identity; exit 1; }
+`), 0755))
+	check(t, ioutil.WriteFile(".tmp/manifest.json", []byte("{}"), 0644))
+	options := &SignatureOptions{Cosign: ".tmp/cosign", CertificateIdentity: "ci@example.com"}
+
+	check(t, ioutil.WriteFile(".tmp/manifest.json.bundle", []byte("signed"), 0644))
+	assert.NoError(t, VerifySignature(".tmp/manifest.json", options))
+
+	check(t, ioutil.WriteFile(".tmp/manifest.json.bundle", []byte("forged"), 0644))
+	err := VerifySignature(".tmp/manifest.json", options)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid bundle")
+}
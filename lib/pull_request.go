@@ -0,0 +1,324 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os/exec"
+	"strings"
+
+	"github.com/mbtproject/mbt/e"
+)
+
+// Pull request providers.
+const (
+	// PullRequestProviderBitbucketServer resolves pull requests of
+	// Bitbucket Server (and Data Center) repositories.
+	PullRequestProviderBitbucketServer = "bitbucket"
+	// PullRequestProviderBitbucketCloud resolves pull requests of
+	// bitbucket.org repositories.
+	PullRequestProviderBitbucketCloud = "bitbucket-cloud"
+	// PullRequestProviderGerrit resolves Gerrit changes. Pull request
+	// id is the change number optionally followed by the patch set
+	// number (e.g. 1234/5). The current patch set is used if the patch
+	// set is not specified.
+	PullRequestProviderGerrit = "gerrit"
+)
+
+// PullRequestOptions describes how pull requests are resolved.
+type PullRequestOptions struct {
+	// Provider is the hosting service of the repository
+	// (bitbucket, bitbucket-cloud or gerrit).
+	Provider string
+	// API is the base URL of the provider API (e.g.
+	// https://bitbucket.example.com for Bitbucket Server).
+	// Defaults to the public API for Bitbucket Cloud.
+	API string
+	// Repo identifies the repository in the provider API
+	// (project/slug for Bitbucket Server and workspace/slug for
+	// Bitbucket Cloud). Not used for Gerrit.
+	Repo string
+	// User is used along with Token for basic authentication
+	// (e.g. app passwords in Bitbucket Cloud). Token is used as a
+	// bearer token if User is not specified.
+	User  string
+	Token string
+	// Remote is the git remote pull request refs are fetched from.
+	// Defaults to origin.
+	Remote string
+	// Dst overrides the destination branch of pull requests.
+	Dst string
+}
+
+// PullRequest is a pull request resolved into refs available in
+// the local repository.
+type PullRequest struct {
+	ID string
+	// Ref is the remote ref of the pull request head.
+	Ref string
+	// Src is the local ref of the pull request head.
+	Src string
+	// Dst is the local ref of the destination branch.
+	Dst string
+	// SrcBranch and DstBranch are the names of the source and
+	// destination branches as reported by the provider.
+	SrcBranch string
+	DstBranch string
+}
+
+// ResolvePullRequest looks up a pull request via the provider API
+// and fetches its head and destination branch into the repository
+// in dir so that the returned refs can be used with ManifestByPr.
+func ResolvePullRequest(dir, id string, options *PullRequestOptions) (*PullRequest, error) {
+	pr, err := lookupPullRequest(id, options)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := fetchPullRequest(dir, pr, options); err != nil {
+		return nil, err
+	}
+
+	return pr, nil
+}
+
+// authorization returns the value of Authorization header used
+// to authenticate with the provider. Empty if no token is specified.
+func (o *PullRequestOptions) authorization() string {
+	if o.Token == "" {
+		return ""
+	}
+	if o.User != "" {
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(o.User+":"+o.Token))
+	}
+	return "Bearer " + o.Token
+}
+
+func lookupPullRequest(id string, options *PullRequestOptions) (*PullRequest, error) {
+	pr := &PullRequest{
+		ID:        id,
+		Ref:       fmt.Sprintf("refs/pull-requests/%s/from", id),
+		Src:       fmt.Sprintf("refs/mbt/pull-requests/%s/from", id),
+		DstBranch: options.Dst,
+	}
+
+	api := strings.TrimSuffix(options.API, "/")
+	switch options.Provider {
+	case PullRequestProviderBitbucketServer:
+		// Bitbucket Server exposes the pull request head as
+		// refs/pull-requests/<id>/from, hence the API is only needed
+		// to find the destination branch.
+		if pr.DstBranch != "" {
+			return withDst(pr, options), nil
+		}
+		if api == "" {
+			return nil, e.NewErrorf(ErrClassUser, msgPullRequestDstRequired, id)
+		}
+		project, slug, err := splitRepo(options.Repo)
+		if err != nil {
+			return nil, err
+		}
+
+		body := struct {
+			FromRef struct{ DisplayID string } `json:"fromRef"`
+			ToRef   struct{ DisplayID string } `json:"toRef"`
+		}{}
+		url := fmt.Sprintf("%s/rest/api/1.0/projects/%s/repos/%s/pull-requests/%s", api, project, slug, id)
+		if err := getJSON(url, options.authorization(), &body); err != nil {
+			return nil, err
+		}
+		pr.SrcBranch = body.FromRef.DisplayID
+		pr.DstBranch = body.ToRef.DisplayID
+	case PullRequestProviderBitbucketCloud:
+		if api == "" {
+			api = "https://api.bitbucket.org/2.0"
+		}
+		body := struct {
+			Source struct {
+				Branch struct{ Name string }
+			}
+			Destination struct {
+				Branch struct{ Name string }
+			}
+		}{}
+		url := fmt.Sprintf("%s/repositories/%s/pullrequests/%s", api, options.Repo, id)
+		if err := getJSON(url, options.authorization(), &body); err != nil {
+			return nil, err
+		}
+		pr.SrcBranch = body.Source.Branch.Name
+		// Bitbucket Cloud does not publish pull request refs,
+		// therefore the head of the source branch is fetched instead.
+		pr.Ref = "refs/heads/" + pr.SrcBranch
+		if pr.DstBranch == "" {
+			pr.DstBranch = body.Destination.Branch.Name
+		}
+	case PullRequestProviderGerrit:
+		if err := lookupGerritChange(pr, api, options); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, e.NewErrorf(ErrClassUser, msgInvalidPullRequestProvider, options.Provider)
+	}
+
+	return withDst(pr, options), nil
+}
+
+func withDst(pr *PullRequest, options *PullRequestOptions) *PullRequest {
+	pr.Dst = fmt.Sprintf("refs/remotes/%s/%s", remoteName(options), pr.DstBranch)
+	return pr
+}
+
+func remoteName(options *PullRequestOptions) string {
+	if options.Remote == "" {
+		return "origin"
+	}
+	return options.Remote
+}
+
+func splitRepo(repo string) (string, string, error) {
+	parts := strings.Split(repo, "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", e.NewErrorf(ErrClassUser, msgInvalidPullRequestRepo, repo)
+	}
+	return parts[0], parts[1], nil
+}
+
+func getJSON(url, authorization string, v interface{}) error {
+	if err := RequireNetwork("Pull request lookup " + url); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return e.Wrapf(ErrClassUser, err, msgFailedPullRequestLookup, url)
+	}
+	req.Header.Set("Accept", "application/json")
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return e.Wrapf(ErrClassUser, err, msgFailedPullRequestLookup, url)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return e.NewErrorf(ErrClassUser, msgUnexpectedNotificationResponse, res.Status, url)
+	}
+
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return e.Wrapf(ErrClassUser, err, msgFailedPullRequestLookup, url)
+	}
+
+	// Gerrit prefixes json responses with a line preventing XSSI.
+	body = bytes.TrimPrefix(body, []byte(")]}'"))
+	if err := json.Unmarshal(body, v); err != nil {
+		return e.Wrapf(ErrClassUser, err, msgFailedPullRequestLookup, url)
+	}
+	return nil
+}
+
+// fetchPullRequest fetches the refs of a pull request from the remote.
+func fetchPullRequest(dir string, pr *PullRequest, options *PullRequestOptions) error {
+	if err := RequireNetwork("Fetching pull request " + pr.ID); err != nil {
+		return err
+	}
+
+	args := []string{"-C", dir}
+	if auth := options.authorization(); auth != "" {
+		args = append(args, "-c", "http.extraHeader=Authorization: "+auth)
+	}
+	args = append(args, "fetch", "--quiet", remoteName(options),
+		fmt.Sprintf("+%s:%s", pr.Ref, pr.Src),
+		fmt.Sprintf("+refs/heads/%s:%s", pr.DstBranch, pr.Dst))
+
+	out, err := exec.Command("git", args...).CombinedOutput()
+	if err != nil {
+		return e.Wrapf(ErrClassUser, err, msgFailedPullRequestFetch, pr.ID, strings.TrimSpace(string(out)))
+	}
+
+	return nil
+}
+
+// gerritChangeRef returns the ref of a Gerrit patch set
+// (i.e. refs/changes/<last two digits of change>/<change>/<patch set>).
+func gerritChangeRef(change, patchSet string) string {
+	shard := change
+	if len(shard) < 2 {
+		shard = "0" + shard
+	}
+	return fmt.Sprintf("refs/changes/%s/%s/%s", shard[len(shard)-2:], change, patchSet)
+}
+
+func lookupGerritChange(pr *PullRequest, api string, options *PullRequestOptions) error {
+	parts := strings.Split(pr.ID, "/")
+	if len(parts) > 2 || parts[0] == "" {
+		return e.NewErrorf(ErrClassUser, msgInvalidGerritChange, pr.ID)
+	}
+	change := parts[0]
+	pr.Src = fmt.Sprintf("refs/mbt/changes/%s", pr.ID)
+
+	if len(parts) == 2 && pr.DstBranch != "" {
+		pr.Ref = gerritChangeRef(change, parts[1])
+		return nil
+	}
+
+	if api == "" {
+		return e.NewErrorf(ErrClassUser, msgPullRequestDstRequired, pr.ID)
+	}
+
+	// Authenticated requests are prefixed with /a/ in Gerrit REST API.
+	prefix := ""
+	if options.Token != "" {
+		prefix = "/a"
+	}
+
+	url := fmt.Sprintf("%s%s/changes/%s?o=CURRENT_REVISION", api, prefix, change)
+	body := struct {
+		Branch          string `json:"branch"`
+		CurrentRevision string `json:"current_revision"`
+		Revisions       map[string]struct {
+			Number int    `json:"_number"`
+			Ref    string `json:"ref"`
+		} `json:"revisions"`
+	}{}
+	if err := getJSON(url, options.authorization(), &body); err != nil {
+		return err
+	}
+
+	if pr.DstBranch == "" {
+		pr.DstBranch = body.Branch
+	}
+
+	if len(parts) == 2 {
+		pr.Ref = gerritChangeRef(change, parts[1])
+	} else {
+		current, ok := body.Revisions[body.CurrentRevision]
+		if !ok {
+			return e.NewErrorf(ErrClassUser, msgInvalidGerritChange, pr.ID)
+		}
+		pr.Ref = current.Ref
+	}
+
+	return nil
+}
@@ -0,0 +1,210 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLookupBitbucketServerPullRequest(t *testing.T) {
+	var authorization string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/rest/api/1.0/projects/PRJ/repos/repo/pull-requests/7", r.URL.Path)
+		authorization = r.Header.Get("Authorization")
+		w.Write([]byte(`{"fromRef":{"displayId":"feature"},"toRef":{"displayId":"develop"}}`))
+	}))
+	defer server.Close()
+
+	pr, err := lookupPullRequest("7", &PullRequestOptions{
+		Provider: PullRequestProviderBitbucketServer,
+		API:      server.URL,
+		Repo:     "PRJ/repo",
+		Token:    "secret",
+	})
+	check(t, err)
+
+	assert.Equal(t, "Bearer secret", authorization)
+	assert.Equal(t, &PullRequest{
+		ID:        "7",
+		Ref:       "refs/pull-requests/7/from",
+		Src:       "refs/mbt/pull-requests/7/from",
+		Dst:       "refs/remotes/origin/develop",
+		SrcBranch: "feature",
+		DstBranch: "develop",
+	}, pr)
+}
+
+func TestLookupBitbucketCloudPullRequest(t *testing.T) {
+	var authorization string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/repositories/team/repo/pullrequests/7", r.URL.Path)
+		authorization = r.Header.Get("Authorization")
+		w.Write([]byte(`{"source":{"branch":{"name":"feature"}},"destination":{"branch":{"name":"main"}}}`))
+	}))
+	defer server.Close()
+
+	pr, err := lookupPullRequest("7", &PullRequestOptions{
+		Provider: PullRequestProviderBitbucketCloud,
+		API:      server.URL,
+		Repo:     "team/repo",
+		User:     "ci",
+		Token:    "app-password",
+		Remote:   "upstream",
+	})
+	check(t, err)
+
+	// base64 of ci:app-password
+	assert.Equal(t, "Basic Y2k6YXBwLXBhc3N3b3Jk", authorization)
+	assert.Equal(t, "feature", pr.SrcBranch)
+	assert.Equal(t, "refs/heads/feature", pr.Ref)
+	assert.Equal(t, "refs/remotes/upstream/main", pr.Dst)
+}
+
+func TestBitbucketServerPullRequestWithoutDst(t *testing.T) {
+	_, err := lookupPullRequest("7", &PullRequestOptions{Provider: PullRequestProviderBitbucketServer})
+
+	assert.EqualError(t, err, "Destination branch of pull request 7 is required when the provider API is not specified")
+}
+
+func TestInvalidPullRequestProvider(t *testing.T) {
+	_, err := lookupPullRequest("7", &PullRequestOptions{Provider: "svn"})
+
+	assert.EqualError(t, err, "Invalid pull request provider 'svn' (expected bitbucket, bitbucket-cloud or gerrit)")
+}
+
+func runGit(t *testing.T, args ...string) string {
+	args = append([]string{"-c", "user.name=mbt", "-c", "user.email=mbt@example.com"}, args...)
+	out, err := exec.Command("git", args...).CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v: %v\n%s", args, err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func TestResolveBitbucketServerPullRequest(t *testing.T) {
+	clean()
+	check(t, os.MkdirAll(".tmp/remote", 0755))
+	runGit(t, "-C", ".tmp/remote", "init", "--quiet")
+	runGit(t, "-C", ".tmp/remote", "checkout", "--quiet", "-b", "master")
+	runGit(t, "-C", ".tmp/remote", "commit", "--quiet", "--allow-empty", "-m", "first")
+	runGit(t, "-C", ".tmp/remote", "checkout", "--quiet", "-b", "feature")
+	runGit(t, "-C", ".tmp/remote", "commit", "--quiet", "--allow-empty", "-m", "second")
+	head := runGit(t, "-C", ".tmp/remote", "rev-parse", "HEAD")
+	runGit(t, "-C", ".tmp/remote", "update-ref", "refs/pull-requests/7/from", head)
+	runGit(t, "clone", "--quiet", "--single-branch", "--branch", "master", ".tmp/remote", ".tmp/repo")
+
+	pr, err := ResolvePullRequest(".tmp/repo", "7", &PullRequestOptions{
+		Provider: PullRequestProviderBitbucketServer,
+		Dst:      "master",
+	})
+	check(t, err)
+
+	assert.Equal(t, head, runGit(t, "-C", ".tmp/repo", "rev-parse", pr.Src))
+	assert.Equal(t, runGit(t, "-C", ".tmp/remote", "rev-parse", "master"), runGit(t, "-C", ".tmp/repo", "rev-parse", pr.Dst))
+}
+
+func TestResolveMissingPullRequest(t *testing.T) {
+	clean()
+	check(t, os.MkdirAll(".tmp/remote", 0755))
+	runGit(t, "-C", ".tmp/remote", "init", "--quiet")
+	runGit(t, "-C", ".tmp/remote", "checkout", "--quiet", "-b", "master")
+	runGit(t, "-C", ".tmp/remote", "commit", "--quiet", "--allow-empty", "-m", "first")
+	runGit(t, "clone", "--quiet", ".tmp/remote", ".tmp/repo")
+
+	_, err := ResolvePullRequest(".tmp/repo", "8", &PullRequestOptions{
+		Provider: PullRequestProviderBitbucketServer,
+		Dst:      "master",
+	})
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "Failed to fetch pull request 8")
+}
+
+func TestGerritChangeRef(t *testing.T) {
+	assert.Equal(t, "refs/changes/34/1234/5", gerritChangeRef("1234", "5"))
+	assert.Equal(t, "refs/changes/05/5/1", gerritChangeRef("5", "1"))
+}
+
+func TestLookupGerritChange(t *testing.T) {
+	var authorization string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/a/changes/1234", r.URL.Path)
+		authorization = r.Header.Get("Authorization")
+		w.Write([]byte(`)]}'
+{"branch":"stable","current_revision":"abc","revisions":{"abc":{"_number":3,"ref":"refs/changes/34/1234/3"}}}`))
+	}))
+	defer server.Close()
+
+	pr, err := lookupPullRequest("1234", &PullRequestOptions{
+		Provider: PullRequestProviderGerrit,
+		API:      server.URL,
+		User:     "ci",
+		Token:    "http-password",
+	})
+	check(t, err)
+
+	assert.Equal(t, "Basic Y2k6aHR0cC1wYXNzd29yZA==", authorization)
+	assert.Equal(t, &PullRequest{
+		ID:        "1234",
+		Ref:       "refs/changes/34/1234/3",
+		Src:       "refs/mbt/changes/1234",
+		Dst:       "refs/remotes/origin/stable",
+		DstBranch: "stable",
+	}, pr)
+}
+
+func TestLookupGerritPatchSetWithoutAPI(t *testing.T) {
+	pr, err := lookupPullRequest("1234/2", &PullRequestOptions{Provider: PullRequestProviderGerrit, Dst: "master"})
+	check(t, err)
+
+	assert.Equal(t, "refs/changes/34/1234/2", pr.Ref)
+	assert.Equal(t, "refs/mbt/changes/1234/2", pr.Src)
+	assert.Equal(t, "refs/remotes/origin/master", pr.Dst)
+}
+
+func TestInvalidGerritChange(t *testing.T) {
+	_, err := lookupPullRequest("1234/2/1", &PullRequestOptions{Provider: PullRequestProviderGerrit, Dst: "master"})
+
+	assert.EqualError(t, err, "Invalid Gerrit change '1234/2/1' (expected <change> or <change>/<patch set>)")
+}
+
+func TestResolveGerritChange(t *testing.T) {
+	clean()
+	check(t, os.MkdirAll(".tmp/remote", 0755))
+	runGit(t, "-C", ".tmp/remote", "init", "--quiet")
+	runGit(t, "-C", ".tmp/remote", "checkout", "--quiet", "-b", "master")
+	runGit(t, "-C", ".tmp/remote", "commit", "--quiet", "--allow-empty", "-m", "first")
+	runGit(t, "-C", ".tmp/remote", "commit", "--quiet", "--allow-empty", "-m", "change")
+	change := runGit(t, "-C", ".tmp/remote", "rev-parse", "HEAD")
+	runGit(t, "-C", ".tmp/remote", "update-ref", "refs/changes/34/1234/2", change)
+	runGit(t, "-C", ".tmp/remote", "reset", "--quiet", "--hard", "HEAD~1")
+	runGit(t, "clone", "--quiet", ".tmp/remote", ".tmp/repo")
+
+	pr, err := ResolvePullRequest(".tmp/repo", "1234/2", &PullRequestOptions{
+		Provider: PullRequestProviderGerrit,
+		Dst:      "master",
+	})
+	check(t, err)
+
+	assert.Equal(t, change, runGit(t, "-C", ".tmp/repo", "rev-parse", pr.Src))
+}
@@ -42,6 +42,14 @@ func (r *stdReducer) Reduce(modules Modules, deltas []*DiffDelta) (Modules, erro
 		nfp := strings.ToLower(d.NewFile)
 		r.Log.Debug("Index change %s", nfp)
 		t.Add(nfp, nfp)
+
+		// Renamed files change the module they are moved from
+		// as well.
+		if d.OldFile != "" && d.OldFile != d.NewFile {
+			ofp := strings.ToLower(d.OldFile)
+			r.Log.Debug("Index change %s", ofp)
+			t.Add(ofp, ofp)
+		}
 	}
 
 	for _, m := range modules {
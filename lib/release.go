@@ -0,0 +1,216 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mbtproject/mbt/e"
+)
+
+// releasePublishTask is the task run to publish the artifacts of the
+// released modules. Modules without it are released without
+// publishing any artifacts.
+const releasePublishTask = "publish"
+
+// ReleaseOptions are the options of a release.
+type ReleaseOptions struct {
+	// Name of the release. Each released module is tagged as
+	// <module>/<name>.
+	Name string
+	// Since is the commit of the previous release. When specified,
+	// only the modules impacted by the changes since then are released
+	// and their changelogs start from it.
+	Since string
+	// Path of the release manifest.
+	Path string
+	// Cmd are the options used to run build and publish tasks.
+	Cmd *CmdOptions
+}
+
+// Release builds the modules in a branch, tags them, generates their
+// changelogs, runs their publish task and writes the release manifest.
+// Tags created are deleted when a subsequent step fails. Artifacts
+// already published are not retracted.
+func (s *stdSystem) Release(branch string, options *ReleaseOptions) (*ReleaseManifest, error) {
+	if options.Name == "" {
+		return nil, e.NewError(ErrClassUser, msgReleaseNameRequired)
+	}
+
+	var m *Manifest
+	var err error
+	if options.Since != "" {
+		m, err = s.ManifestByDiff(options.Since, branch)
+	} else {
+		m, err = s.ManifestByBranch(branch)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	build, err := s.checkoutAndRunTarget(m, buildTarget, options.Cmd)
+	if err != nil {
+		return nil, err
+	}
+	if len(build.Failures) > 0 {
+		return nil, e.NewErrorf(ErrClassUser, msgReleaseStepFailed, options.Name, "build", build.Failures[0].Module.Name())
+	}
+
+	artifacts, err := NewArtifactManifest(build)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &ReleaseManifest{
+		Schema:  ReleaseManifestSchema,
+		Name:    options.Name,
+		Sha:     m.Sha,
+		Branch:  branch,
+		Modules: make([]*ReleasedModule, 0, len(build.Completed)),
+	}
+
+	// Build summary has an entry for each variant of a module with a
+	// build matrix. Variants share the name of the module, therefore
+	// each module is tagged and published just once.
+	modules := m.Modules.indexByName()
+	released := make(map[string]bool)
+	built := make(Modules, 0, len(build.Completed))
+	for i, b := range build.Completed {
+		mod, ok := modules[b.Module.Name()]
+		if !ok || released[mod.Name()] {
+			continue
+		}
+		released[mod.Name()] = true
+
+		changes, err := changelog(m.Dir, options.Since, m.Sha, mod.Path())
+		if err != nil {
+			return nil, err
+		}
+
+		built = append(built, mod)
+		r.Modules = append(r.Modules, &ReleasedModule{
+			Name:      mod.Name(),
+			Version:   mod.Version(),
+			Tag:       releaseTag(mod, options.Name),
+			Changes:   changes,
+			Artifacts: artifacts.Modules[i].Artifacts,
+		})
+	}
+
+	if options.Cmd.DryRun {
+		// Nothing is tagged, published or written in a dry run.
+		return r, nil
+	}
+
+	tx := &releaseTransaction{dir: m.Dir}
+	err = tx.run(func() error {
+		for _, mod := range r.Modules {
+			if err := tx.tag(mod.Tag, m.Sha); err != nil {
+				return err
+			}
+		}
+
+		publish, err := s.checkoutAndRunTarget(m.withModules(built), taskTarget(releasePublishTask), options.Cmd)
+		if err != nil {
+			return err
+		}
+		if len(publish.Failures) > 0 {
+			return e.NewErrorf(ErrClassUser, msgReleaseStepFailed, options.Name, releasePublishTask, publish.Failures[0].Module.Name())
+		}
+
+		published := make(map[string]bool)
+		for _, p := range publish.Completed {
+			published[p.Module.Name()] = true
+		}
+		for _, mod := range r.Modules {
+			mod.Published = published[mod.Name]
+		}
+
+		if options.Path == "" {
+			return nil
+		}
+		return r.Write(options.Path)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// releaseTag returns the tag of a module in the named release.
+func releaseTag(mod *Module, name string) string {
+	return fmt.Sprintf("%s/%s", mod.Name(), name)
+}
+
+// changelog returns the subjects of the commits changing path in the
+// range since..sha, most recent first. All commits reachable from sha
+// are included when since is empty.
+func changelog(dir, since, sha, path string) ([]string, error) {
+	rev := sha
+	if since != "" {
+		rev = since + ".." + sha
+	}
+	if path == "" {
+		path = "."
+	}
+
+	out, err := gitOutput(dir, "log", "--format=%s", rev, "--", path)
+	if err != nil {
+		return nil, e.Wrapf(ErrClassInternal, err, msgFailedChangelog, path)
+	}
+
+	changes := make([]string, 0)
+	for _, l := range strings.Split(out, "\n") {
+		if l != "" {
+			changes = append(changes, l)
+		}
+	}
+	return changes, nil
+}
+
+// releaseTransaction records the tags created during a release so
+// that they can be deleted when a later step fails.
+type releaseTransaction struct {
+	dir  string
+	tags []string
+}
+
+func (t *releaseTransaction) tag(name, sha string) error {
+	if _, err := gitOutput(t.dir, "tag", name, sha); err != nil {
+		return e.Wrapf(ErrClassUser, err, msgFailedReleaseTag, name)
+	}
+
+	t.tags = append(t.tags, name)
+	return nil
+}
+
+// run invokes fn and deletes the tags created by it if it fails.
+func (t *releaseTransaction) run(fn func() error) error {
+	err := fn()
+	if err != nil {
+		t.rollback()
+	}
+	return err
+}
+
+func (t *releaseTransaction) rollback() {
+	for i := len(t.tags) - 1; i >= 0; i-- {
+		gitOutput(t.dir, "tag", "-d", t.tags[i])
+	}
+	t.tags = nil
+}
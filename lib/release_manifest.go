@@ -0,0 +1,253 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/mbtproject/mbt/e"
+)
+
+// ReleaseManifestSchema is the version of the release manifest format
+// written by this version of mbt.
+const ReleaseManifestSchema = 1
+
+// ReleaseManifest describes the modules released from a commit.
+// It is written by a release and can be checked into a repository
+// (e.g. to drive GitOps and promotion flows).
+type ReleaseManifest struct {
+	// Schema is the version of the format of the release manifest.
+	Schema  int               `json:"schema"`
+	Name    string            `json:"name"`
+	Sha     string            `json:"sha"`
+	Branch  string            `json:"branch"`
+	Modules []*ReleasedModule `json:"modules"`
+}
+
+// ReleasedModule describes a module included in a release.
+type ReleasedModule struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	// Tag created for the module.
+	Tag string `json:"tag"`
+	// Changes are the subjects of the commits changing the module
+	// since the previous release.
+	Changes []string `json:"changes"`
+	// Artifacts are the files matching the outputs of the module.
+	Artifacts []*Artifact `json:"artifacts"`
+	// Published indicates whether the publish task is run for the
+	// module.
+	Published bool `json:"published"`
+}
+
+// Release change statuses.
+const (
+	// ReleaseChangeAdded is the status of a module only in the newer release.
+	ReleaseChangeAdded = "added"
+	// ReleaseChangeRemoved is the status of a module only in the older release.
+	ReleaseChangeRemoved = "removed"
+	// ReleaseChangeUpdated is the status of a module released with a
+	// different version.
+	ReleaseChangeUpdated = "updated"
+	// ReleaseChangeRebuilt is the status of a module released with the
+	// same version but different artifacts.
+	ReleaseChangeRebuilt = "rebuilt"
+)
+
+// ReleaseChange is a difference of a module between two releases.
+type ReleaseChange struct {
+	Module string `json:"module"`
+	// From and To are the versions of the module in each release.
+	// Empty if the module is not in the release.
+	From   string `json:"from"`
+	To     string `json:"to"`
+	Status string `json:"status"`
+}
+
+// Release validation statuses.
+const (
+	// ReleaseValidationOK is the status of a module with the released
+	// version in the commit.
+	ReleaseValidationOK = "ok"
+	// ReleaseValidationMismatch is the status of a module with a
+	// different version in the commit.
+	ReleaseValidationMismatch = "mismatch"
+	// ReleaseValidationMissing is the status of a module that does not
+	// exist in the commit.
+	ReleaseValidationMissing = "missing"
+)
+
+// ReleaseValidation is the result of validating a released module
+// against a commit.
+type ReleaseValidation struct {
+	Module   string `json:"module"`
+	Expected string `json:"expected"`
+	Actual   string `json:"actual"`
+	Status   string `json:"status"`
+}
+
+// ReadReleaseManifest reads the release manifest stored in path.
+func ReadReleaseManifest(path string) (*ReleaseManifest, error) {
+	c, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, e.Wrapf(ErrClassUser, err, msgFailedReadFile, path)
+	}
+
+	r := &ReleaseManifest{}
+	if err := json.Unmarshal(c, r); err != nil {
+		return nil, e.Wrapf(ErrClassUser, err, msgInvalidReleaseManifest, path)
+	}
+
+	if r.Schema > ReleaseManifestSchema {
+		return nil, e.NewErrorf(ErrClassUser, msgUnsupportedReleaseSchema, r.Schema, path)
+	}
+
+	if r.Sha == "" {
+		return nil, e.NewErrorf(ErrClassUser, msgInvalidReleaseManifest, path)
+	}
+
+	for _, m := range r.Modules {
+		if m == nil || m.Name == "" {
+			return nil, e.NewErrorf(ErrClassUser, msgInvalidReleaseManifest, path)
+		}
+	}
+
+	return r, nil
+}
+
+// Write stores the release manifest in path.
+func (r *ReleaseManifest) Write(path string) error {
+	c, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return e.Wrap(ErrClassInternal, err)
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), 0755)
+	if err == nil {
+		err = ioutil.WriteFile(path, c, 0644)
+	}
+	if err != nil {
+		return e.Wrapf(ErrClassUser, err, msgFailedWriteFile, path)
+	}
+
+	return nil
+}
+
+// ArtifactManifest returns the artifacts of the released modules
+// so that they can be verified with VerifyArtifacts.
+func (r *ReleaseManifest) ArtifactManifest() *ArtifactManifest {
+	a := &ArtifactManifest{
+		Sha:     r.Sha,
+		Branch:  r.Branch,
+		Modules: make([]*ModuleArtifacts, 0, len(r.Modules)),
+	}
+
+	for _, m := range r.Modules {
+		a.Modules = append(a.Modules, &ModuleArtifacts{Name: m.Name, Version: m.Version, Artifacts: m.Artifacts})
+	}
+
+	return a
+}
+
+// DiffReleases lists the modules changed from release a to release b
+// sorted by name. Modules released with the same version and
+// artifacts are not included.
+func DiffReleases(a, b *ReleaseManifest) []*ReleaseChange {
+	older := make(map[string]*ReleasedModule)
+	for _, m := range a.Modules {
+		older[m.Name] = m
+	}
+
+	changes := make([]*ReleaseChange, 0)
+	for _, m := range b.Modules {
+		prev, ok := older[m.Name]
+		delete(older, m.Name)
+
+		switch {
+		case !ok:
+			changes = append(changes, &ReleaseChange{Module: m.Name, To: m.Version, Status: ReleaseChangeAdded})
+		case prev.Version != m.Version:
+			changes = append(changes, &ReleaseChange{Module: m.Name, From: prev.Version, To: m.Version, Status: ReleaseChangeUpdated})
+		case !sameArtifacts(prev.Artifacts, m.Artifacts):
+			changes = append(changes, &ReleaseChange{Module: m.Name, From: prev.Version, To: m.Version, Status: ReleaseChangeRebuilt})
+		}
+	}
+
+	for _, m := range older {
+		changes = append(changes, &ReleaseChange{Module: m.Name, From: m.Version, Status: ReleaseChangeRemoved})
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Module < changes[j].Module
+	})
+	return changes
+}
+
+// sameArtifacts returns true if a and b have the same paths and
+// digests regardless of their order.
+func sameArtifacts(a, b []*Artifact) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	digests := make(map[string]string, len(a))
+	for _, f := range a {
+		digests[f.Path] = f.Digest
+	}
+
+	for _, f := range b {
+		if d, ok := digests[f.Path]; !ok || d != f.Digest {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (s *stdSystem) ValidateRelease(r *ReleaseManifest, sha string) ([]*ReleaseValidation, error) {
+	if sha == "" {
+		sha = r.Sha
+	}
+
+	m, err := s.ManifestByCommit(sha)
+	if err != nil {
+		return nil, err
+	}
+
+	mods := m.Modules.indexByName()
+	results := make([]*ReleaseValidation, 0, len(r.Modules))
+	for _, rm := range r.Modules {
+		v := &ReleaseValidation{Module: rm.Name, Expected: rm.Version}
+		mod, ok := mods[rm.Name]
+		switch {
+		case !ok:
+			v.Status = ReleaseValidationMissing
+		case mod.Version() != rm.Version:
+			v.Actual = mod.Version()
+			v.Status = ReleaseValidationMismatch
+		default:
+			v.Actual = mod.Version()
+			v.Status = ReleaseValidationOK
+		}
+		results = append(results, v)
+	}
+
+	return results, nil
+}
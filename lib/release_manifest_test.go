@@ -0,0 +1,146 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"io/ioutil"
+	"os"
+	"runtime"
+	"testing"
+
+	"github.com/mbtproject/mbt/e"
+	"github.com/stretchr/testify/assert"
+)
+
+func writeReleaseFile(t *testing.T, content string) string {
+	check(t, os.MkdirAll(".tmp", 0755))
+	check(t, ioutil.WriteFile(".tmp/release.json", []byte(content), 0644))
+	return ".tmp/release.json"
+}
+
+func TestWriteAndReadReleaseManifest(t *testing.T) {
+	clean()
+	r := &ReleaseManifest{
+		Schema: ReleaseManifestSchema,
+		Name:   "v1",
+		Sha:    "abc",
+		Branch: "master",
+		Modules: []*ReleasedModule{
+			{Name: "app-a", Version: "a1", Tag: "app-a/v1", Changes: []string{"first"}, Artifacts: []*Artifact{{Path: "app-a/dist/a", Digest: "d"}}, Published: true},
+		},
+	}
+	check(t, r.Write(".tmp/out/release.json"))
+
+	read, err := ReadReleaseManifest(".tmp/out/release.json")
+	check(t, err)
+	assert.Equal(t, r, read)
+}
+
+func TestReadInvalidReleaseManifest(t *testing.T) {
+	clean()
+
+	for _, c := range []string{
+		`not json`,
+		`{"name": "v1"}`,
+		`{"sha": "abc", "modules": [{"version": "a1"}]}`,
+		`{"sha": "abc", "modules": [null]}`,
+	} {
+		_, err := ReadReleaseManifest(writeReleaseFile(t, c))
+		assert.EqualError(t, err, "Invalid release manifest '.tmp/release.json'", c)
+		assert.Equal(t, ErrClassUser, (err.(*e.E)).Class())
+	}
+}
+
+func TestReadUnsupportedReleaseManifest(t *testing.T) {
+	clean()
+
+	_, err := ReadReleaseManifest(writeReleaseFile(t, `{"schema": 2, "sha": "abc"}`))
+
+	assert.EqualError(t, err, "Release manifest schema version 2 of '.tmp/release.json' is not supported")
+	assert.Equal(t, ErrClassUser, (err.(*e.E)).Class())
+}
+
+func TestDiffReleases(t *testing.T) {
+	a := &ReleaseManifest{Modules: []*ReleasedModule{
+		{Name: "app-a", Version: "a1"},
+		{Name: "app-b", Version: "b1", Artifacts: []*Artifact{{Path: "b", Digest: "1"}}},
+		{Name: "app-c", Version: "c1"},
+		{Name: "app-d", Version: "d1", Artifacts: []*Artifact{{Path: "d1", Digest: "1"}, {Path: "d2", Digest: "2"}}},
+	}}
+	b := &ReleaseManifest{Modules: []*ReleasedModule{
+		{Name: "app-e", Version: "e1"},
+		{Name: "app-d", Version: "d1", Artifacts: []*Artifact{{Path: "d2", Digest: "2"}, {Path: "d1", Digest: "1"}}},
+		{Name: "app-b", Version: "b1", Artifacts: []*Artifact{{Path: "b", Digest: "2"}}},
+		{Name: "app-a", Version: "a2"},
+	}}
+
+	assert.Equal(t, []*ReleaseChange{
+		{Module: "app-a", From: "a1", To: "a2", Status: ReleaseChangeUpdated},
+		{Module: "app-b", From: "b1", To: "b1", Status: ReleaseChangeRebuilt},
+		{Module: "app-c", From: "c1", Status: ReleaseChangeRemoved},
+		{Module: "app-e", To: "e1", Status: ReleaseChangeAdded},
+	}, DiffReleases(a, b))
+
+	assert.Empty(t, DiffReleases(a, a))
+}
+
+func TestReleaseArtifactManifest(t *testing.T) {
+	artifacts := []*Artifact{{Path: "app-a/dist/a", Digest: "d"}}
+	r := &ReleaseManifest{Sha: "abc", Branch: "master", Modules: []*ReleasedModule{
+		{Name: "app-a", Version: "a1", Tag: "app-a/v1", Artifacts: artifacts},
+	}}
+
+	assert.Equal(t, &ArtifactManifest{
+		Sha:     "abc",
+		Branch:  "master",
+		Modules: []*ModuleArtifacts{{Name: "app-a", Version: "a1", Artifacts: artifacts}},
+	}, r.ArtifactManifest())
+}
+
+func TestValidateRelease(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.SkipNow()
+	}
+
+	clean()
+	repo := initReleaseRepo(t, "echo")
+	c1 := repo.LastCommit.String()
+
+	world := NewWorld(t, ".tmp/repo")
+	m, err := world.System.ManifestByCommit(c1)
+	check(t, err)
+	a := m.Modules.indexByName()["app-a"]
+
+	r := &ReleaseManifest{Sha: c1, Modules: []*ReleasedModule{
+		{Name: "app-a", Version: a.Version()},
+		{Name: "app-c", Version: "c1"},
+	}}
+
+	results, err := world.System.ValidateRelease(r, "")
+	check(t, err)
+	assert.Equal(t, []*ReleaseValidation{
+		{Module: "app-a", Expected: a.Version(), Actual: a.Version(), Status: ReleaseValidationOK},
+		{Module: "app-c", Expected: "c1", Status: ReleaseValidationMissing},
+	}, results)
+
+	check(t, repo.WriteContent("app-a/main.go", "package main"))
+	check(t, repo.Commit("second"))
+
+	results, err = NewWorld(t, ".tmp/repo").System.ValidateRelease(r, repo.LastCommit.String())
+	check(t, err)
+	assert.Equal(t, ReleaseValidationMismatch, results[0].Status)
+	assert.NotEqual(t, a.Version(), results[0].Actual)
+}
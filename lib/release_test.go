@@ -0,0 +1,195 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"runtime"
+	"testing"
+
+	"github.com/mbtproject/mbt/e"
+	"github.com/stretchr/testify/assert"
+)
+
+func initReleaseRepo(t *testing.T, publish string) *TestRepository {
+	repo := NewTestRepo(t, ".tmp/repo")
+	check(t, repo.InitModuleWithOptions("app-a", &Spec{
+		Name:  "app-a",
+		Build: map[string]*Cmd{"default": {Cmd: "echo", Args: []string{"build app-a"}}},
+		Tasks: map[string]*Task{
+			"publish": {Cmd: publish, Args: []string{"publish app-a"}},
+		},
+	}))
+	check(t, repo.InitModuleWithOptions("app-b", &Spec{
+		Name:  "app-b",
+		Build: map[string]*Cmd{"default": {Cmd: "echo", Args: []string{"build app-b"}}},
+	}))
+	check(t, repo.Commit("first"))
+	return repo
+}
+
+func TestRelease(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.SkipNow()
+	}
+
+	clean()
+	repo := initReleaseRepo(t, "echo")
+
+	buff := new(bytes.Buffer)
+	r, err := NewWorld(t, ".tmp/repo").System.Release("master", &ReleaseOptions{
+		Name: "v1",
+		Path: ".tmp/release.json",
+		Cmd:  stdTestCmdOptions(buff),
+	})
+	check(t, err)
+
+	assert.Equal(t, "build app-a\nbuild app-b\npublish app-a\n", buff.String())
+	assert.Equal(t, repo.LastCommit.String(), r.Sha)
+	assert.Equal(t, "master", r.Branch)
+	assert.Len(t, r.Modules, 2)
+	assert.Equal(t, "app-a/v1", r.Modules[0].Tag)
+	assert.Equal(t, []string{"first"}, r.Modules[0].Changes)
+	assert.True(t, r.Modules[0].Published)
+	assert.Equal(t, "app-b/v1", r.Modules[1].Tag)
+	assert.False(t, r.Modules[1].Published)
+
+	tags, err := gitOutput(".tmp/repo", "tag", "-l")
+	check(t, err)
+	assert.Equal(t, "app-a/v1\napp-b/v1", tags)
+
+	c, err := ioutil.ReadFile(".tmp/release.json")
+	check(t, err)
+	written := &ReleaseManifest{}
+	check(t, json.Unmarshal(c, written))
+	assert.Equal(t, r, written)
+}
+
+func TestReleaseSince(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.SkipNow()
+	}
+
+	clean()
+	repo := initReleaseRepo(t, "echo")
+	c1 := repo.LastCommit
+
+	check(t, repo.WriteContent("app-b/main.go", "package main"))
+	check(t, repo.Commit("second"))
+
+	r, err := NewWorld(t, ".tmp/repo").System.Release("master", &ReleaseOptions{
+		Name:  "v2",
+		Since: c1.String(),
+		Cmd:   stdTestCmdOptions(new(bytes.Buffer)),
+	})
+	check(t, err)
+
+	assert.Len(t, r.Modules, 1)
+	assert.Equal(t, "app-b", r.Modules[0].Name)
+	assert.Equal(t, []string{"second"}, r.Modules[0].Changes)
+}
+
+func TestReleaseRollback(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.SkipNow()
+	}
+
+	clean()
+	initReleaseRepo(t, "false")
+
+	_, err := NewWorld(t, ".tmp/repo").System.Release("master", &ReleaseOptions{
+		Name: "v1",
+		Path: ".tmp/release.json",
+		Cmd:  stdTestCmdOptions(new(bytes.Buffer)),
+	})
+	assert.Error(t, err)
+
+	tags, err := gitOutput(".tmp/repo", "tag", "-l")
+	check(t, err)
+	assert.Empty(t, tags)
+
+	_, err = os.Stat(".tmp/release.json")
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestReleaseDryRun(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.SkipNow()
+	}
+
+	clean()
+	initReleaseRepo(t, "echo")
+
+	options := stdTestCmdOptions(new(bytes.Buffer))
+	options.DryRun = true
+	r, err := NewWorld(t, ".tmp/repo").System.Release("master", &ReleaseOptions{
+		Name: "v1",
+		Path: ".tmp/release.json",
+		Cmd:  options,
+	})
+	check(t, err)
+	assert.Len(t, r.Modules, 2)
+
+	tags, err := gitOutput(".tmp/repo", "tag", "-l")
+	check(t, err)
+	assert.Empty(t, tags)
+}
+
+func TestReleaseWithoutName(t *testing.T) {
+	_, err := (&stdSystem{}).Release("master", &ReleaseOptions{})
+
+	assert.EqualError(t, err, msgReleaseNameRequired)
+	assert.Equal(t, ErrClassUser, (err.(*e.E)).Class())
+}
+
+func TestReleaseMatrix(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.SkipNow()
+	}
+
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+	check(t, repo.InitModuleWithOptions("app-a", &Spec{
+		Name:  "app-a",
+		Build: map[string]*Cmd{"default": {Cmd: "sh", Args: []string{"-c", "echo build $MBT_VARIANT"}}},
+		Tasks: map[string]*Task{
+			"publish": {Cmd: "sh", Args: []string{"-c", "echo publish $MBT_MODULE_VERSION"}},
+		},
+		Matrix: map[string][]string{"goos": {"linux", "darwin"}},
+	}))
+	check(t, repo.Commit("first"))
+
+	buff := new(bytes.Buffer)
+	r, err := NewWorld(t, ".tmp/repo").System.Release("master", &ReleaseOptions{
+		Name: "v1",
+		Cmd:  stdTestCmdOptions(buff),
+	})
+	check(t, err)
+
+	assert.Len(t, r.Modules, 1)
+	v := r.Modules[0].Version
+	assert.Equal(t, "app-a/v1", r.Modules[0].Tag)
+	assert.True(t, r.Modules[0].Published)
+	assert.Equal(t, fmt.Sprintf("build linux\nbuild darwin\npublish %s-linux\npublish %s-darwin\n", v, v), buff.String())
+
+	tags, err := gitOutput(".tmp/repo", "tag", "-l")
+	check(t, err)
+	assert.Equal(t, "app-a/v1", tags)
+}
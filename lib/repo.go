@@ -16,7 +16,12 @@ limitations under the License.
 package lib
 
 import (
+	"bytes"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
 
 	git "github.com/libgit2/git2go/v28"
 	"github.com/mbtproject/mbt/e"
@@ -47,6 +52,7 @@ func (b *libgitBlob) String() string {
 type libgitCommit struct {
 	commit *git.Commit
 	tree   *git.Tree
+	repo   *libgitRepo
 }
 
 func (c *libgitCommit) ID() string {
@@ -71,16 +77,24 @@ func (r *libgitReference) SymbolicName() string {
 }
 
 type libgitRepo struct {
-	path string
-	Repo *git.Repository
-	Log  Log
+	path        string
+	Repo        *git.Repository
+	Log         Log
+	diffOptions *DiffOptions
+	dirs        *dirFilter
+	// partial is true when the repository is a partial clone.
+	partial bool
 }
 
 func (c *libgitCommit) Tree() (*git.Tree, error) {
 	if c.tree == nil {
-		tree, err := c.commit.Tree()
+		var tree *git.Tree
+		err := c.repo.withObjects(func() (err error) {
+			tree, err = c.commit.Tree()
+			return
+		})
 		if err != nil {
-			return nil, e.Wrapf(ErrClassInternal, err, msgFailedTreeLoad, c.commit.Id())
+			return nil, wrapObjectErr(err, msgFailedTreeLoad, c.commit.Id())
 		}
 		c.tree = tree
 	}
@@ -90,18 +104,71 @@ func (c *libgitCommit) Tree() (*git.Tree, error) {
 
 // NewLibgitRepo creates a libgit2 repo instance
 func NewLibgitRepo(path string, log Log) (Repo, error) {
+	return NewLibgitRepoWithOptions(path, log, nil)
+}
+
+// NewLibgitRepoWithOptions creates a libgit2 repo instance detecting
+// changes with the specified diff options.
+func NewLibgitRepoWithOptions(path string, log Log, options *DiffOptions) (Repo, error) {
+	if options == nil {
+		options = &DiffOptions{}
+	}
+
+	if options.SimilarityThreshold < 0 || options.SimilarityThreshold > 100 {
+		return nil, e.NewErrorf(ErrClassUser, msgInvalidSimilarityThreshold, options.SimilarityThreshold)
+	}
+
 	repo, err := git.OpenRepository(path)
 	if err != nil {
 		return nil, e.Wrapf(ErrClassUser, err, msgFailedOpenRepo, path)
 	}
 
+	if err := loadIgnoreRules(repo); err != nil {
+		return nil, err
+	}
+
 	return &libgitRepo{
-		path: path,
-		Repo: repo,
-		Log:  log,
+		path:        path,
+		Repo:        repo,
+		Log:         log,
+		diffOptions: options,
+		dirs:        newDirFilter(options),
+		partial:     isPartialClone(repo),
 	}, nil
 }
 
+// IgnoreFileName is the name of the file containing additional
+// rules (in .gitignore format) used to exclude untracked files from
+// workspace changes. Rules in this file are applied on top of
+// .gitignore, so that build outputs can be ignored by mbt without
+// hiding them from git.
+const IgnoreFileName = ".mbtignore"
+
+func loadIgnoreRules(repo *git.Repository) error {
+	if repo.IsBare() {
+		return nil
+	}
+
+	p := filepath.Join(repo.Workdir(), IgnoreFileName)
+	rules, err := ioutil.ReadFile(p)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return e.Wrapf(ErrClassUser, err, msgFailedLoadIgnoreRules, p)
+	}
+
+	// Rules added here live only in memory for the lifetime of this
+	// repository instance. They are interpreted relative to the root
+	// of the working directory.
+	err = repo.AddIgnoreRule(string(rules))
+	if err != nil {
+		return e.Wrapf(ErrClassUser, err, msgFailedLoadIgnoreRules, p)
+	}
+
+	return nil
+}
+
 func (r *libgitRepo) GetCommit(commitSha string) (Commit, error) {
 	commitOid, err := git.NewOid(commitSha)
 	if err != nil {
@@ -113,7 +180,7 @@ func (r *libgitRepo) GetCommit(commitSha string) (Commit, error) {
 		return nil, e.Wrapf(ErrClassUser, err, msgCommitShaNotFound, commitSha)
 	}
 
-	return &libgitCommit{commit: commit}, nil
+	return &libgitCommit{commit: commit, repo: r}, nil
 }
 
 func (r *libgitRepo) Path() string {
@@ -121,12 +188,16 @@ func (r *libgitRepo) Path() string {
 }
 
 func (r *libgitRepo) Diff(a, b Commit) ([]*DiffDelta, error) {
-	diff, err := diff(r.Repo, a, b)
+	var d *git.Diff
+	err := r.withObjects(func() (err error) {
+		d, err = diff(r.Repo, a, b)
+		return
+	})
 	if err != nil {
 		return nil, e.Wrap(ErrClassInternal, err)
 	}
 
-	return deltas(diff)
+	return r.deltas(d, false)
 }
 
 func (r *libgitRepo) DiffMergeBase(from, to Commit) ([]*DiffDelta, error) {
@@ -135,35 +206,113 @@ func (r *libgitRepo) DiffMergeBase(from, to Commit) ([]*DiffDelta, error) {
 		return nil, err
 	}
 
-	diff, err := diff(r.Repo, bc, to)
+	var d *git.Diff
+	err = r.withObjects(func() (err error) {
+		d, err = diff(r.Repo, bc, to)
+		return
+	})
 	if err != nil {
 		return nil, e.Wrap(ErrClassInternal, err)
 	}
 
-	return deltas(diff)
+	return r.deltas(d, false)
 }
 
 func (r *libgitRepo) DiffWorkspace() ([]*DiffDelta, error) {
-	index, err := r.Repo.Index()
+	tree, err := r.headTree()
 	if err != nil {
-		return nil, e.Wrap(ErrClassInternal, err)
+		return nil, err
 	}
 
+	// Diffing the tree of HEAD against the working directory (via the index)
+	// captures staged, intent-to-add (git add -N) and unstaged changes in
+	// one pass.
 	// Diff flags below are essential to get a list of
 	// untracked files (including the ones inside new directories)
 	// in the diff.
 	// Without git.DiffRecurseUntracked option, if a new file is added inside
 	// a new directory, we only get the path to the directory.
 	// This option is same as running git status -uall
-	diff, err := r.Repo.DiffIndexToWorkdir(index, &git.DiffOptions{
-		Flags: git.DiffIncludeUntracked | git.DiffRecurseUntracked,
+	// Ignored files are excluded unless git.DiffIncludeIgnored is set,
+	// therefore .gitignore rules are honoured.
+	var flags git.DiffOptionsFlag
+	if !r.diffOptions.ExcludeUntracked {
+		flags = git.DiffIncludeUntracked | git.DiffRecurseUntracked
+	}
+
+	var diff *git.Diff
+	err = r.withObjects(func() (err error) {
+		diff, err = r.Repo.DiffTreeToWorkdirWithIndex(tree, &git.DiffOptions{
+			Flags: flags,
+		})
+		return
 	})
 
 	if err != nil {
 		return nil, e.Wrap(ErrClassInternal, err)
 	}
 
-	return deltas(diff)
+	d, err := r.deltas(diff, true)
+	if err != nil {
+		return nil, err
+	}
+
+	skipped, err := r.skipWorktree()
+	if err != nil {
+		return nil, err
+	}
+
+	filtered := make([]*DiffDelta, 0, len(d))
+	for _, delta := range d {
+		if skipped[delta.OldFile] {
+			continue
+		}
+
+		// Files tracked by Git LFS are replaced with their content
+		// in the working directory. They are not changed unless the
+		// content differs from the one referred by the pointer.
+		smudged, err := r.isSmudgedLFSFile(tree, delta.OldFile)
+		if err != nil {
+			return nil, err
+		}
+
+		if !smudged {
+			filtered = append(filtered, delta)
+		}
+	}
+
+	return filtered, nil
+}
+
+// headTree returns the tree of the commit pointed by HEAD.
+// Returns nil tree when HEAD is unborn (i.e. repository without any commits)
+// so that every file in the workspace is treated as an addition.
+func (r *libgitRepo) headTree() (*git.Tree, error) {
+	unborn, err := r.Repo.IsHeadUnborn()
+	if err != nil {
+		return nil, e.Wrap(ErrClassInternal, err)
+	}
+
+	if unborn {
+		return nil, nil
+	}
+
+	head, err := r.Repo.Head()
+	if err != nil {
+		return nil, e.Wrap(ErrClassInternal, err)
+	}
+
+	commit, err := r.Repo.LookupCommit(head.Target())
+	if err != nil {
+		return nil, e.Wrap(ErrClassInternal, err)
+	}
+
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, e.Wrap(ErrClassInternal, err)
+	}
+
+	return tree, nil
 }
 
 func (r *libgitRepo) Changes(c Commit) ([]*DiffDelta, error) {
@@ -188,12 +337,16 @@ func (r *libgitRepo) Changes(c Commit) ([]*DiffDelta, error) {
 		return nil, e.Wrap(ErrClassInternal, err)
 	}
 
-	d, err := repo.DiffTreeToTree(t1, t2, &git.DiffOptions{})
+	var d *git.Diff
+	err = r.withObjects(func() (err error) {
+		d, err = repo.DiffTreeToTree(t1, t2, &git.DiffOptions{})
+		return
+	})
 	if err != nil {
 		return nil, e.Wrap(ErrClassInternal, err)
 	}
 
-	return deltas(d)
+	return r.deltas(d, false)
 }
 
 func (r *libgitRepo) WalkBlobs(commit Commit, callback BlobWalkCallback) error {
@@ -202,40 +355,73 @@ func (r *libgitRepo) WalkBlobs(commit Commit, callback BlobWalkCallback) error {
 		return err
 	}
 
-	var (
-		walkErr error
-	)
-
-	err = tree.Walk(func(path string, entry *git.TreeEntry) int {
-		if entry.Type == git.ObjectBlob {
-			b := &libgitBlob{
+	// Collect the blobs before invoking the callback so that the walk
+	// can be restarted if a subtree has to be fetched on demand.
+	var blobs []*libgitBlob
+	err = r.withObjects(func() error {
+		blobs = blobs[:0]
+		return r.walkTree(tree, "", func(path string, entry *git.TreeEntry) {
+			blobs = append(blobs, &libgitBlob{
 				entry:  entry,
 				path:   path,
 				commit: commit.(*libgitCommit),
-			}
-			walkErr = callback(b)
-			if walkErr != nil {
-				return -1
-			}
-		}
-		return 0
+			})
+		})
 	})
 
-	if walkErr != nil {
-		return walkErr
+	if err != nil {
+		return wrapObjectErr(err, msgFailedTreeWalk, tree.Id())
 	}
 
-	if err != nil {
-		return e.Wrapf(ErrClassInternal, err, msgFailedTreeWalk, tree.Id())
+	for _, b := range blobs {
+		if err := callback(b); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// walkTree invokes callback for each blob in tree skipping the
+// excluded directories.
+// libgit2 tree walk is not used here because it visits every subtree.
+func (r *libgitRepo) walkTree(tree *git.Tree, dir string, callback func(string, *git.TreeEntry)) error {
+	count := tree.EntryCount()
+	for i := uint64(0); i < count; i++ {
+		entry := tree.EntryByIndex(i)
+		switch entry.Type {
+		case git.ObjectBlob:
+			callback(dir, entry)
+		case git.ObjectTree:
+			if r.dirs.excludedDir(dir + entry.Name) {
+				r.Log.Debug("Skipping excluded directory %v%v", dir, entry.Name)
+				continue
+			}
+
+			subtree, err := r.Repo.LookupTree(entry.Id)
+			if err != nil {
+				return err
+			}
+
+			err = r.walkTree(subtree, dir+entry.Name+"/", callback)
+			subtree.Free()
+			if err != nil {
+				return err
+			}
+		}
 	}
 
 	return nil
 }
 
 func (r *libgitRepo) BlobContents(blob Blob) ([]byte, error) {
-	bl, err := r.Repo.LookupBlob(blob.(*libgitBlob).entry.Id)
+	var bl *git.Blob
+	err := r.withObjects(func() (err error) {
+		bl, err = r.Repo.LookupBlob(blob.(*libgitBlob).entry.Id)
+		return
+	})
 	if err != nil {
-		return nil, e.Wrapf(ErrClassInternal, err, "error while fetching the blob object for %s%s", blob.Path(), blob.Name())
+		return nil, wrapObjectErr(err, "error while fetching the blob object for %s%s", blob.Path(), blob.Name())
 	}
 
 	return bl.Contents(), nil
@@ -247,9 +433,13 @@ func (r *libgitRepo) EntryID(commit Commit, path string) (string, error) {
 		return "", err
 	}
 
-	entry, err := tree.EntryByPath(path)
+	var entry *git.TreeEntry
+	err = r.withObjects(func() (err error) {
+		entry, err = tree.EntryByPath(path)
+		return
+	})
 	if err != nil {
-		return "", e.Wrapf(ErrClassInternal, err, "error while fetching the tree entry for %s", path)
+		return "", wrapObjectErr(err, "error while fetching the tree entry for %s", path)
 	}
 
 	return entry.Id.String(), nil
@@ -329,6 +519,9 @@ func (r *libgitRepo) FindAllFilesInWorkspace(pathSpec []string) ([]string, error
 				if path == "" {
 					path = entry.HeadToIndex.NewFile.Path
 				}
+				if r.dirs.excludedFile(path) {
+					continue
+				}
 				r.Log.Debug("Matching file detected: %v", path)
 				configPaths = append(configPaths, path)
 			}
@@ -377,12 +570,16 @@ func (r *libgitRepo) BlobContentsFromTree(commit Commit, path string) ([]byte, e
 		return nil, e.Wrap(ErrClassInternal, err)
 	}
 
-	item, err := t.EntryByPath(path)
-	if err != nil {
-		return nil, e.Wrap(ErrClassInternal, err)
-	}
+	var blob *git.Blob
+	err = r.withObjects(func() error {
+		item, err := t.EntryByPath(path)
+		if err != nil {
+			return err
+		}
 
-	blob, err := r.Repo.LookupBlob(item.Id)
+		blob, err = r.Repo.LookupBlob(item.Id)
+		return err
+	})
 	if err != nil {
 		return nil, e.Wrap(ErrClassInternal, err)
 	}
@@ -479,6 +676,127 @@ func (r *libgitRepo) MergeBase(a, b Commit) (Commit, error) {
 	return r.GetCommit(bid.String())
 }
 
+func (r *libgitRepo) Note(commit Commit, ref string) (string, error) {
+	note, err := r.Repo.Notes.Read(ref, commit.(*libgitCommit).commit.Id())
+	if err != nil {
+		if git.IsErrorCode(err, git.ErrorCodeNotFound) {
+			return "", nil
+		}
+		return "", e.Wrapf(ErrClassInternal, err, msgFailedReadNote, commit)
+	}
+	defer note.Free()
+
+	return note.Message(), nil
+}
+
+func (r *libgitRepo) SetNote(commit Commit, ref, note string) error {
+	sig, err := r.Repo.DefaultSignature()
+	if err != nil {
+		// Fallback to a generic signature when user.name and
+		// user.email are not configured (e.g. CI environments).
+		sig = &git.Signature{Name: "mbt", Email: "mbt@localhost", When: time.Now()}
+	}
+
+	_, err = r.Repo.Notes.Create(ref, sig, sig, commit.(*libgitCommit).commit.Id(), note, true)
+	if err != nil {
+		return e.Wrapf(ErrClassInternal, err, msgFailedWriteNote, commit)
+	}
+
+	return nil
+}
+
+func (r *libgitRepo) History(from, to Commit) ([]Commit, error) {
+	walk, err := r.Repo.Walk()
+	if err != nil {
+		return nil, e.Wrap(ErrClassInternal, err)
+	}
+	defer walk.Free()
+
+	walk.Sorting(git.SortTopological | git.SortTime)
+	if err := walk.Push(to.(*libgitCommit).commit.Id()); err != nil {
+		return nil, e.Wrap(ErrClassInternal, err)
+	}
+	if from != nil {
+		if err := walk.Hide(from.(*libgitCommit).commit.Id()); err != nil {
+			return nil, e.Wrap(ErrClassInternal, err)
+		}
+	}
+
+	commits := make([]Commit, 0)
+	err = walk.Iterate(func(c *git.Commit) bool {
+		commits = append(commits, &libgitCommit{commit: c, repo: r})
+		return true
+	})
+	if err != nil {
+		return nil, e.Wrap(ErrClassInternal, err)
+	}
+
+	return commits, nil
+}
+
+func (r *libgitRepo) CommitInfo(commit Commit) (*CommitInfo, error) {
+	c := commit.(*libgitCommit).commit
+	parents := make([]string, 0, c.ParentCount())
+	for i := uint(0); i < c.ParentCount(); i++ {
+		parents = append(parents, c.ParentId(i).String())
+	}
+
+	return &CommitInfo{
+		Sha:         c.Id().String(),
+		Author:      c.Author().Name,
+		AuthorEmail: c.Author().Email,
+		Time:        c.Committer().When,
+		Subject:     c.Summary(),
+		Parents:     parents,
+	}, nil
+}
+
+func (r *libgitRepo) ResolveRef(ref string) (Commit, error) {
+	obj, err := r.Repo.RevparseSingle(ref)
+	if err != nil {
+		return nil, e.Wrapf(ErrClassUser, err, msgFailedRefLookup, ref)
+	}
+
+	// Annotated tags point to tag objects, peel them to commits.
+	peeled, err := obj.Peel(git.ObjectCommit)
+	if err != nil {
+		return nil, e.Wrapf(ErrClassUser, err, msgFailedRefLookup, ref)
+	}
+
+	commit, err := peeled.AsCommit()
+	if err != nil {
+		return nil, e.Wrap(ErrClassInternal, err)
+	}
+
+	return &libgitCommit{commit: commit, repo: r}, nil
+}
+
+func (r *libgitRepo) State() (*RepoState, error) {
+	shallow, err := r.Repo.IsShallow()
+	if err != nil {
+		return nil, e.Wrap(ErrClassInternal, err)
+	}
+
+	detached, err := r.Repo.IsHeadDetached()
+	if err != nil {
+		return nil, e.Wrap(ErrClassInternal, err)
+	}
+
+	config, err := r.Repo.Config()
+	if err != nil {
+		return nil, e.Wrap(ErrClassInternal, err)
+	}
+	defer config.Free()
+
+	sparse, err := config.LookupBool("core.sparseCheckout")
+	return &RepoState{
+		Shallow:  shallow,
+		Sparse:   err == nil && sparse,
+		Partial:  r.partial,
+		Detached: detached,
+	}, nil
+}
+
 func diff(repo *git.Repository, ca, cb Commit) (*git.Diff, error) {
 	t1, err := ca.(*libgitCommit).Tree()
 	if err != nil {
@@ -498,7 +816,22 @@ func diff(repo *git.Repository, ca, cb Commit) (*git.Diff, error) {
 	return diff, nil
 }
 
-func deltas(diff *git.Diff) ([]*DiffDelta, error) {
+// deltas extracts the changes in diff according to the diff options
+// of the repository. workdir indicates that the new side of the diff is
+// the working directory.
+func (r *libgitRepo) deltas(diff *git.Diff, workdir bool) ([]*DiffDelta, error) {
+	if r.diffOptions.SimilarityThreshold > 0 {
+		err := r.withObjects(func() error {
+			return diff.FindSimilar(&git.DiffFindOptions{
+				Flags:           git.DiffFindRenames,
+				RenameThreshold: uint16(r.diffOptions.SimilarityThreshold),
+			})
+		})
+		if err != nil {
+			return nil, e.Wrap(ErrClassInternal, err)
+		}
+	}
+
 	count, err := diff.NumDeltas()
 	if err != nil {
 		return nil, e.Wrap(ErrClassInternal, err)
@@ -506,6 +839,23 @@ func deltas(diff *git.Diff) ([]*DiffDelta, error) {
 
 	deltas := make([]*DiffDelta, 0, count)
 	err = diff.ForEach(func(delta git.DiffDelta, num float64) (git.DiffForEachHunkCallback, error) {
+		if r.dirs.excludedFile(delta.NewFile.Path) && r.dirs.excludedFile(delta.OldFile.Path) {
+			r.Log.Debug("Skipping change in excluded directory %v", delta.NewFile.Path)
+			return nil, nil
+		}
+
+		if r.diffOptions.SkipBinary {
+			binary, err := r.isBinaryDelta(delta, workdir)
+			if err != nil {
+				return nil, err
+			}
+
+			if binary {
+				r.Log.Debug("Skipping binary change %v", delta.NewFile.Path)
+				return nil, nil
+			}
+		}
+
 		deltas = append(deltas, &DiffDelta{
 			OldFile: delta.OldFile.Path,
 			NewFile: delta.NewFile.Path,
@@ -515,3 +865,62 @@ func deltas(diff *git.Diff) ([]*DiffDelta, error) {
 
 	return deltas, err
 }
+
+// binaryProbeSize is the number of bytes inspected to decide whether a
+// file is binary. Same heuristic used by git.
+const binaryProbeSize = 8000
+
+func isBinary(content []byte) bool {
+	if len(content) > binaryProbeSize {
+		content = content[:binaryProbeSize]
+	}
+	return bytes.IndexByte(content, 0) != -1
+}
+
+func (r *libgitRepo) isBinaryDelta(delta git.DiffDelta, workdir bool) (bool, error) {
+	if delta.Flags&git.DiffFlagBinary != 0 {
+		return true, nil
+	}
+
+	if delta.Flags&git.DiffFlagNotBinary != 0 {
+		return false, nil
+	}
+
+	content, err := r.diffFileContent(delta.NewFile, workdir)
+	if err == nil && content == nil {
+		content, err = r.diffFileContent(delta.OldFile, false)
+	}
+
+	if err != nil {
+		return false, err
+	}
+
+	return isBinary(content), nil
+}
+
+// diffFileContent reads the content of a file in a diff.
+// Returns nil if the file does not exist on that side of the diff.
+func (r *libgitRepo) diffFileContent(f git.DiffFile, workdir bool) ([]byte, error) {
+	if f.Flags&git.DiffFlagExists == 0 {
+		return nil, nil
+	}
+
+	if workdir {
+		content, err := ioutil.ReadFile(filepath.Join(r.Repo.Workdir(), filepath.FromSlash(f.Path)))
+		if err != nil {
+			return nil, e.Wrap(ErrClassInternal, err)
+		}
+		return content, nil
+	}
+
+	var blob *git.Blob
+	err := r.withObjects(func() (err error) {
+		blob, err = r.Repo.LookupBlob(f.Oid)
+		return
+	})
+	if err != nil {
+		return nil, wrapObjectErr(err, "error while fetching the blob object for %s", f.Path)
+	}
+
+	return blob.Contents(), nil
+}
@@ -16,6 +16,7 @@ limitations under the License.
 package lib
 
 import (
+	"bytes"
 	"fmt"
 	"testing"
 
@@ -69,6 +70,163 @@ func TestDiffByIndex(t *testing.T) {
 	assert.Len(t, diff, 1)
 }
 
+func TestDiffWorkspaceForStagedChanges(t *testing.T) {
+	clean()
+
+	repo := NewTestRepo(t, ".tmp/repo")
+
+	check(t, repo.InitModule("app-a"))
+	check(t, repo.Commit("first"))
+
+	check(t, repo.WriteContent("app-a/test.txt", "test contents"))
+	check(t, repo.Stage("app-a/test.txt"))
+
+	diff, err := NewWorld(t, ".tmp/repo").Repo.DiffWorkspace()
+	check(t, err)
+
+	assert.Len(t, diff, 1)
+	assert.Equal(t, "app-a/test.txt", diff[0].NewFile)
+}
+
+func TestDiffWorkspaceForIgnoredFiles(t *testing.T) {
+	clean()
+
+	repo := NewTestRepo(t, ".tmp/repo")
+
+	check(t, repo.InitModule("app-a"))
+	check(t, repo.WriteContent(".gitignore", "bin/\n"))
+	check(t, repo.Commit("first"))
+
+	check(t, repo.WriteContent("app-a/bin/out", "output"))
+
+	diff, err := NewWorld(t, ".tmp/repo").Repo.DiffWorkspace()
+	check(t, err)
+
+	assert.Empty(t, diff)
+}
+
+func TestDiffWorkspaceForMbtIgnoredFiles(t *testing.T) {
+	clean()
+
+	repo := NewTestRepo(t, ".tmp/repo")
+
+	check(t, repo.InitModule("app-a"))
+	check(t, repo.WriteContent(".mbtignore", "*.out\n"))
+	check(t, repo.Commit("first"))
+
+	check(t, repo.WriteContent("app-a/build.out", "output"))
+	check(t, repo.WriteContent("app-a/main.go", "package main"))
+
+	diff, err := NewWorld(t, ".tmp/repo").Repo.DiffWorkspace()
+	check(t, err)
+
+	assert.Len(t, diff, 1)
+	assert.Equal(t, "app-a/main.go", diff[0].NewFile)
+}
+
+func TestDiffWorkspaceForAnEmptyRepo(t *testing.T) {
+	clean()
+
+	repo := NewTestRepo(t, ".tmp/repo")
+
+	check(t, repo.InitModule("app-a"))
+	check(t, repo.Stage("app-a/.mbt.yml"))
+	check(t, repo.WriteContent("app-a/test.txt", "test contents"))
+
+	diff, err := NewWorld(t, ".tmp/repo").Repo.DiffWorkspace()
+	check(t, err)
+
+	assert.Len(t, diff, 2)
+}
+
+func TestDiffWorkspaceExcludingUntracked(t *testing.T) {
+	clean()
+
+	repo := NewTestRepo(t, ".tmp/repo")
+
+	check(t, repo.InitModule("app-a"))
+	check(t, repo.WriteContent("app-a/test.txt", "test contents"))
+	check(t, repo.Commit("first"))
+
+	check(t, repo.WriteContent("app-a/test.txt", "amend contents"))
+	check(t, repo.WriteContent("app-a/new.txt", "new contents"))
+
+	r, err := NewLibgitRepoWithOptions(".tmp/repo", NewStdLog(LogLevelNormal), &DiffOptions{ExcludeUntracked: true})
+	check(t, err)
+	diff, err := r.DiffWorkspace()
+	check(t, err)
+
+	assert.Len(t, diff, 1)
+	assert.Equal(t, "app-a/test.txt", diff[0].NewFile)
+}
+
+func TestDiffSkippingBinaryFiles(t *testing.T) {
+	clean()
+
+	repo := NewTestRepo(t, ".tmp/repo")
+
+	check(t, repo.InitModule("app-a"))
+	check(t, repo.Commit("first"))
+	first := repo.LastCommit.String()
+
+	check(t, repo.WriteContent("app-a/image.png", "\x89PNG\x00\x00"))
+	check(t, repo.WriteContent("app-a/main.go", "package main"))
+	check(t, repo.Commit("second"))
+
+	r, err := NewLibgitRepoWithOptions(".tmp/repo", NewStdLog(LogLevelNormal), &DiffOptions{SkipBinary: true})
+	check(t, err)
+	a, err := r.GetCommit(first)
+	check(t, err)
+	b, err := r.GetCommit(repo.LastCommit.String())
+	check(t, err)
+
+	diff, err := r.Diff(a, b)
+	check(t, err)
+
+	assert.Len(t, diff, 1)
+	assert.Equal(t, "app-a/main.go", diff[0].NewFile)
+}
+
+func TestDiffWithRenameDetection(t *testing.T) {
+	clean()
+
+	repo := NewTestRepo(t, ".tmp/repo")
+
+	check(t, repo.InitModule("app-a"))
+	check(t, repo.InitModule("app-b"))
+	check(t, repo.WriteContent("app-a/lib.go", "package lib\n\nfunc Foo() {}\n"))
+	check(t, repo.Commit("first"))
+	first := repo.LastCommit.String()
+
+	check(t, repo.Rename("app-a/lib.go", "app-b/lib.go"))
+	check(t, repo.Commit("second"))
+
+	r, err := NewLibgitRepoWithOptions(".tmp/repo", NewStdLog(LogLevelNormal), &DiffOptions{SimilarityThreshold: 50})
+	check(t, err)
+	a, err := r.GetCommit(first)
+	check(t, err)
+	b, err := r.GetCommit(repo.LastCommit.String())
+	check(t, err)
+
+	diff, err := r.Diff(a, b)
+	check(t, err)
+
+	assert.Equal(t, []*DiffDelta{{OldFile: "app-a/lib.go", NewFile: "app-b/lib.go"}}, diff)
+}
+
+func TestInvalidSimilarityThreshold(t *testing.T) {
+	_, err := NewLibgitRepoWithOptions(".tmp/repo", NewStdLog(LogLevelNormal), &DiffOptions{SimilarityThreshold: 101})
+
+	assert.EqualError(t, err, fmt.Sprintf(msgInvalidSimilarityThreshold, 101))
+	assert.Equal(t, ErrClassUser, (err.(*e.E)).Class())
+}
+
+func TestIsBinary(t *testing.T) {
+	assert.True(t, isBinary([]byte("a\x00b")))
+	assert.False(t, isBinary([]byte("hello")))
+	assert.False(t, isBinary(append(bytes.Repeat([]byte("a"), binaryProbeSize), 0)))
+}
+
 func TestDirtyWorkspaceForUntracked(t *testing.T) {
 	clean()
 	repo := NewTestRepo(t, ".tmp/repo")
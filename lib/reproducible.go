@@ -0,0 +1,165 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/mbtproject/mbt/e"
+)
+
+// ReproducibleOptions describes how builds are verified to be
+// reproducible. Each module is built twice and the digests of its
+// outputs are compared.
+type ReproducibleOptions struct {
+	// Executor runs the second build of each module (e.g. to compare
+	// the builds on the host and in a container). Executor of the
+	// system is used when it is nil.
+	Executor Executor
+}
+
+// Reproducibility is the result of building a module twice and
+// comparing the digests of its outputs.
+type Reproducibility struct {
+	// Outputs is the number of output files of the first build.
+	// Modules without outputs cannot be verified.
+	Outputs int
+	// Differences lists the outputs which differ between the builds.
+	Differences []*OutputDifference
+}
+
+// OutputDifference is an output file of a module which differs
+// between two builds.
+type OutputDifference struct {
+	// Path of the file relative to the repository.
+	Path string
+	// First is the hex encoded SHA-256 digest of the file in the first
+	// build. Empty when the file was not produced by the build.
+	First string
+	// Second is the digest of the file in the second build.
+	Second string
+}
+
+// Reproducible returns true if the outputs of both builds are identical.
+func (r *Reproducibility) Reproducible() bool {
+	return len(r.Differences) == 0
+}
+
+// CheckReproducible returns an error naming the modules in summary
+// whose builds were found to be nondeterministic.
+func CheckReproducible(summary *BuildSummary) error {
+	names := make([]string, 0)
+	for _, r := range summary.Completed {
+		if r.Reproducibility != nil && !r.Reproducibility.Reproducible() {
+			names = append(names, moduleDisplayName(r.Module))
+		}
+	}
+
+	if len(names) > 0 {
+		return e.NewErrorf(ErrClassUser, msgNondeterministicBuilds, strings.Join(names, ", "))
+	}
+
+	return nil
+}
+
+// verifyReproducible builds a module again and compares the digests of
+// its outputs with the ones of the previous build.
+func (s *stdSystem) verifyReproducible(step *taskStep, m *Manifest, mod *Module, options *CmdOptions) (*Reproducibility, error) {
+	first, err := outputDigests(m, mod)
+	if err != nil {
+		return nil, err
+	}
+
+	// Outputs are removed so that the second build cannot reuse them.
+	for p := range first {
+		path := filepath.Join(m.Dir, filepath.FromSlash(p))
+		if err := os.Remove(path); err != nil {
+			return nil, e.Wrapf(ErrClassUser, err, msgFailedWriteFile, path)
+		}
+	}
+
+	x := s
+	if options.Reproducible.Executor != nil {
+		c := *s
+		c.ProcessManager = NewProcessManagerWithExecutor(s.Log, options.Reproducible.Executor)
+		x = &c
+	}
+
+	// Output of the second build is not logged to keep the log of
+	// the first one.
+	o := *options
+	o.LogDir = ""
+	s.Log.Infof(msgRebuildingModule, moduleDisplayName(mod))
+	if _, err := x.execTarget(step.target, step.cmd, m, mod, &o); err != nil {
+		return nil, err
+	}
+
+	second, err := outputDigests(m, mod)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Reproducibility{Outputs: len(first), Differences: compareDigests(first, second)}, nil
+}
+
+// outputDigests returns the digests of the output files of a module
+// by their paths relative to the repository.
+func outputDigests(m *Manifest, mod *Module) (map[string]string, error) {
+	files, err := outputFiles(filepath.Join(m.Dir, filepath.FromSlash(mod.Path())), mod.Outputs())
+	if err != nil {
+		return nil, err
+	}
+
+	digests := make(map[string]string, len(files))
+	for _, f := range files {
+		rel, err := filepath.Rel(m.Dir, f)
+		if err != nil {
+			return nil, e.Wrap(ErrClassInternal, err)
+		}
+
+		if digests[filepath.ToSlash(rel)], err = fileDigest(f); err != nil {
+			return nil, err
+		}
+	}
+
+	return digests, nil
+}
+
+func compareDigests(first, second map[string]string) []*OutputDifference {
+	paths := make(map[string]bool)
+	for p := range first {
+		paths[p] = true
+	}
+	for p := range second {
+		paths[p] = true
+	}
+
+	differences := make([]*OutputDifference, 0)
+	for p := range paths {
+		if first[p] != second[p] {
+			differences = append(differences, &OutputDifference{Path: p, First: first[p], Second: second[p]})
+		}
+	}
+
+	sort.Slice(differences, func(i, j int) bool {
+		return differences[i].Path < differences[j].Path
+	})
+
+	return differences
+}
@@ -0,0 +1,107 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// outputProcessManager writes the next of its contents into the output
+// of a module each time a command is executed.
+type outputProcessManager struct {
+	contents []string
+	runs     int
+}
+
+func (p *outputProcessManager) Exec(manifest *Manifest, module *Module, options *CmdOptions, command string, args ...string) error {
+	content := p.contents[p.runs%len(p.contents)]
+	p.runs++
+	return ioutil.WriteFile(filepath.Join(manifest.Dir, module.Path(), "dist", "app.bin"), []byte(content), 0644)
+}
+
+// verifyTestModule verifies the build of a module with an output
+// containing first after its first build.
+func verifyTestModule(t *testing.T, first string, pm ProcessManager) (*Reproducibility, error) {
+	clean()
+	writeAuditFile(t, ".tmp/repo/app-a/dist/app.bin", first)
+
+	dir, err := filepath.Abs(".tmp/repo")
+	check(t, err)
+
+	a := newModule(newModuleMetadata("app-a", "a", &Spec{Name: "app-a", Outputs: []string{"dist"}}, nil), nil)
+	s := &stdSystem{Log: NewStdLog(LogLevelNormal), ProcessManager: pm}
+	options := CmdOptionsWithStdIO(func(*Module, CmdStage, error) {})
+	options.Reproducible = &ReproducibleOptions{}
+
+	return s.verifyReproducible(&taskStep{target: buildTarget, cmd: &Cmd{Cmd: "./build.sh"}, main: true}, &Manifest{Dir: dir}, a, options)
+}
+
+func TestReproducibleBuild(t *testing.T) {
+	pm := &outputProcessManager{contents: []string{"a"}}
+
+	r, err := verifyTestModule(t, "a", pm)
+	check(t, err)
+
+	assert.Equal(t, 1, pm.runs)
+	assert.Equal(t, 1, r.Outputs)
+	assert.True(t, r.Reproducible())
+}
+
+func TestNondeterministicBuild(t *testing.T) {
+	pm := &outputProcessManager{contents: []string{"b"}}
+
+	r, err := verifyTestModule(t, "a", pm)
+	check(t, err)
+
+	assert.False(t, r.Reproducible())
+	assert.Equal(t, []*OutputDifference{{
+		Path:   "app-a/dist/app.bin",
+		First:  "ca978112ca1bbdcafac231b39a23dc4da786eff8147c4e72b9807785afee48bb",
+		Second: "3e23e8160039594a33894f6564e1b1348bbd7a0088d42c4acb73eeaed59c009d",
+	}}, r.Differences)
+}
+
+func TestCompareDigests(t *testing.T) {
+	differences := compareDigests(
+		map[string]string{"a/c": "1", "a/b": "2", "a/d": "3"},
+		map[string]string{"a/c": "1", "a/b": "4", "a/e": "5"},
+	)
+
+	assert.Equal(t, []*OutputDifference{
+		{Path: "a/b", First: "2", Second: "4"},
+		{Path: "a/d", First: "3"},
+		{Path: "a/e", Second: "5"},
+	}, differences)
+}
+
+func TestCheckReproducible(t *testing.T) {
+	a := newTestModule("app-a", "app-a", "a1")
+	b := newTestModule("app-b", "app-b", "b1")
+	c := newTestModule("app-c", "app-c", "c1")
+
+	summary := &BuildSummary{Completed: []*BuildResult{
+		{Module: a, Reproducibility: &Reproducibility{Outputs: 1, Differences: []*OutputDifference{{Path: "app-a/a"}}}},
+		{Module: b, Reproducibility: &Reproducibility{Outputs: 1}},
+		{Module: c},
+	}}
+
+	assert.EqualError(t, CheckReproducible(summary), "Builds of app-a are not reproducible")
+	assert.NoError(t, CheckReproducible(&BuildSummary{Completed: summary.Completed[1:]}))
+}
@@ -21,9 +21,20 @@ const (
 	msgFailedOpenRepo                      = "Failed to open a git repository in dir - '%v'"
 	msgFailedTemplatePath                  = "Failed to read the template in file '%v'"
 	msgFailedReadFile                      = "Failed to read file '%v'"
+	msgFailedWriteFile                     = "Failed to write file '%v'"
+	msgFailedCreateLogFile                 = "Failed to create log file '%v'"
 	msgFailedLocalPath                     = "Failed to read the path '%v'"
 	msgFailedTemplateParse                 = "Failed to parse the template"
 	msgFailedBuild                         = "Failed to build module '%v'"
+	msgFailedHook                          = "Failed to run %v hook of module '%v'"
+	msgFailedBuilds                        = "Failed to build %v module(s): %v"
+	msgFailedTest                          = "Tests failed in module '%v'"
+	msgFailedTests                         = "Tests failed in %v module(s): %v"
+	msgFailedTask                          = "Task '%v' failed in module '%v'"
+	msgFailedTasks                         = "Task '%v' failed in %v module(s): %v"
+	msgUnknownTaskDependency               = "Task '%v' depends on unknown task '%v' in module '%v'"
+	msgCircularTaskDependency              = "Circular task dependency %v in module '%v'"
+	msgRunningUpstreamTask                 = "Running %v of upstream module %v"
 	msgTemplateNotFound                    = "Specified template %v is not found in git tree %v"
 	msgFailedSpecParse                     = "Failed to parse the spec file"
 	msgFailedBranchLookup                  = "Failed to find the branch '%v'"
@@ -35,4 +46,112 @@ const (
 	msgSuccessfulCheckout                  = "Successfully checked out commit %v"
 	msgDirtyWorkingDir                     = "Dirty working dir"
 	msgDetachedHead                        = "Head is currently detached"
+	msgFailedKubernetesJob                 = "Kubernetes job '%v' failed"
+	msgKubernetesJobTimeout                = "Timed out waiting for Kubernetes job '%v'"
+	msgCommandTimeout                      = "Command timed out after %v"
+	msgInvalidWhen                         = "Invalid when expression '%v' in command '%v'"
+	msgFailedWhen                          = "Failed to evaluate when expression '%v' for module '%v'"
+	msgInvalidFilterExpr                   = "Invalid filter expression '%v'"
+	msgFailedFilterExpr                    = "Failed to evaluate filter expression '%v' for module '%v'"
+	msgEmptyMatrixAxis                     = "Matrix axis '%v' of module '%v' does not have any values"
+	msgInvalidResourceWeight               = "Invalid resource weight %v of module '%v'"
+	msgInvalidTimeout                      = "Invalid timeout '%v' in command '%v'"
+	msgRetryingCommand                     = "Retrying %v in module %v (attempt %v of %v)"
+	msgSSHHostNotFound                     = "Failed to find a host labelled '%v' for module '%v'"
+	msgInvalidProperty                     = "Invalid property '%v' in module '%v': %v"
+	msgInvalidOutput                       = "Output '%v' of module '%v' must be a relative path within the module"
+	msgFailedCacheRestore                  = "Failed to restore cached outputs of module '%v'"
+	msgFailedCacheSave                     = "Failed to cache outputs of module '%v'"
+	msgUnexpectedCacheResponse             = "Unexpected response %v from cache for key '%v'"
+	msgNotDeployed                         = "Module '%v' is not deployed to '%v'"
+	msgFailedNotification                  = "Failed to send notification to '%v'"
+	msgUnexpectedNotificationResponse      = "Unexpected response %v from '%v'"
+	msgInvalidPolicy                       = "Invalid policy '%v'"
+	msgInvalidPolicyLevel                  = "Invalid level '%v' in policy '%v' (expected error or warning)"
+	msgInvalidPolicyRule                   = "Invalid rule in policy '%v'"
+	msgFailedPolicyRule                    = "Failed to evaluate policy '%v' for module '%v'"
+	msgFailedPolicyEval                    = "Failed to evaluate policy '%v': %v"
+	msgPolicyViolation                     = "Module '%v' violates policy '%v': %v"
+	msgPolicyViolations                    = "Policy violations in %v"
+	msgFailedAudit                         = "Failed to audit module '%v'"
+	msgInvalidSBOMFormat                   = "Invalid SBOM format '%v' (expected cyclonedx or spdx)"
+	msgFailedSBOM                          = "Failed to generate SBOM of module '%v'"
+	msgFailedSign                          = "Failed to sign '%v': %v"
+	msgInvalidSignature                    = "Invalid signature of '%v': %v"
+	msgFailedReadNote                      = "Failed to read the note of commit %v"
+	msgFailedWriteNote                     = "Failed to write the note of commit %v"
+	msgInvalidStatusProvider               = "Invalid commit status provider '%v' (expected github, gitlab or bitbucket)"
+	msgFailedCommitStatus                  = "Failed to publish commit status to '%v'"
+	msgInvalidPullRequestProvider          = "Invalid pull request provider '%v' (expected bitbucket, bitbucket-cloud or gerrit)"
+	msgInvalidPullRequestRepo              = "Invalid repository '%v' (expected <project>/<slug>)"
+	msgPullRequestDstRequired              = "Destination branch of pull request %v is required when the provider API is not specified"
+	msgFailedPullRequestLookup             = "Failed to look up pull request at '%v'"
+	msgFailedPullRequestFetch              = "Failed to fetch pull request %v: %v"
+	msgInvalidGerritChange                 = "Invalid Gerrit change '%v' (expected <change> or <change>/<patch set>)"
+	msgFailedRefLookup                     = "Failed to find the commit of '%v'"
+	msgModuleNotFound                      = "Module '%v' is not found in '%v'"
+	msgFailedLoadIgnoreRules               = "Failed to load ignore rules from '%v'"
+	msgMissingObject                       = "Object %v is missing from the repository (fetch it or use a clone without --filter)"
+	msgFailedSparseCheckout                = "Failed to read the sparse checkout entries of the workspace"
+	msgInvalidSimilarityThreshold          = "Invalid similarity threshold %v (expected a value between 0 and 100)"
+	msgInvalidWorkspaceFile                = "Invalid workspace file '%v'"
+	msgInvalidWorkspaceRepository          = "Repositories in workspace file '%v' require a name and a path"
+	msgDuplicateWorkspaceRepository        = "Duplicate repository '%v' in workspace file '%v'"
+	msgFailedWorkspaceManifest             = "Failed to create the manifest of repository '%v'"
+	msgInvalidExternalDependency           = "External dependencies of module '%v' require a repo and a ref"
+	msgFailedRemoteRefLookup               = "Failed to find '%v' in repository '%v'"
+	msgCannotExportRootModule              = "Module '%v' is in the root of the repository and cannot be exported"
+	msgExportDestinationNotEmpty           = "Export destination '%v' is not empty"
+	msgFailedExport                        = "Failed to export the history of module '%v'"
+	msgInvalidBinaryManifest               = "Invalid binary manifest"
+	msgUnsupportedManifestSchema           = "Manifest schema version %v is not supported"
+	msgFailedHookPath                      = "Failed to find the hooks directory of repository '%v'"
+	msgHookExists                          = "Hook '%v' is not generated by mbt, use --force to replace it"
+	msgHookNotGenerated                    = "Hook '%v' is not generated by mbt"
+	msgInvalidConfigFile                   = "Invalid configuration file '%v'"
+	msgInvalidConfigValue                  = "Invalid value of '%v' in configuration file '%v'"
+	msgInvalidWorkDir                      = "Work directory '%v' of module '%v' is outside the repository"
+	msgPluginNotFound                      = "Plugin '%v' is not found (expected an executable named %v in PATH)"
+	msgPluginFailed                        = "Plugin '%v' failed"
+	msgManifestScriptFailed                = "Manifest script '%v' failed"
+	msgInvalidManifestScriptOutput         = "Invalid output of manifest script '%v'"
+	msgUnsupportedSpecVersion              = "Spec version %v of module '%v' is not supported (this version of mbt supports versions up to %v, upgrade mbt to use newer specs)"
+	msgFailedDiffStats                     = "Failed to read the changes since '%v'"
+	msgInvalidChangeClasses                = "Invalid change classes file '%v' (expected a list of class and patterns)"
+	msgInvalidImageTagPattern              = "Invalid image tag pattern '%v' (unknown placeholder %v)"
+	msgImageTagPropertyNotFound            = "Property '%v' of module '%v' is not found"
+	msgModuleWithoutName                   = "Spec in '%v' does not have a name"
+	msgNestedModule                        = "Module in '%v' is nested in module '%v', changes to it also change '%v'"
+	msgDiagnostic                          = "%v: %v"
+	msgPolicyWarning                       = "Violates policy '%v': %v"
+	msgCannotAdvanceManifest               = "Manifest of '%v' cannot be advanced (only manifests of commits and branches can be advanced)"
+	msgInvalidODBOptions                   = "Object database cache size and mapped limit cannot be negative"
+	msgDuplicatePropertyKey                = "Property key '%v' is specified more than once"
+	msgDuplicateDependency                 = "Dependency '%v' is listed more than once"
+	msgReleaseNameRequired                 = "Release name is required"
+	msgReleaseStepFailed                   = "Release '%v' failed to %v module '%v'"
+	msgFailedChangelog                     = "Failed to generate the changelog of '%v'"
+	msgFailedReleaseTag                    = "Failed to create tag '%v' (it may already exist)"
+	msgInvalidReleaseManifest              = "Invalid release manifest '%v'"
+	msgUnsupportedReleaseSchema            = "Release manifest schema version %v of '%v' is not supported"
+	msgForcedModuleNotFound                = "Module '%v' forced to be included is not found"
+	msgInvalidDependentsPolicy             = "Invalid dependents policy '%v' (expected none, direct, transitive or a depth)"
+	msgModuleNotImpacted                   = "Module '%v' is not impacted by the changes"
+	msgInvalidLifecycle                    = "Invalid lifecycle '%v' of module '%v' (expected active, deprecated or frozen)"
+	msgDeprecatedModuleSelected            = "Deprecated module is selected by the changes"
+	msgFrozenModuleChanged                 = "Module '%v' is frozen and its files must not change"
+	msgInvalidCost                         = "Invalid cost %v of module '%v' (expected a positive number)"
+	msgInvalidBudgetPolicy                 = "Invalid budget policy '%v' (expected warn or fail)"
+	msgBuildBudgetExceeded                 = "Cost of the modules selected by the changes is %v which exceeds the budget of %v, consider splitting the changes"
+	msgFailedTrailers                      = "Failed to read the commit trailers of module '%v'"
+	msgInvalidSemver                       = "Invalid semver '%v' of module '%v' (expected major.minor.patch)"
+	msgInvalidSnapshotPattern              = "Invalid snapshot version pattern '%v' (unknown placeholder %v)"
+	msgInvalidVersionFormat                = "Invalid version format '%v' (expected full, base36 or a length between %v and %v)"
+	msgCacheGCNotSupported                 = "Cache does not support listing its entries for garbage collection"
+	msgOffline                             = "%v requires network access which is not allowed in offline mode"
+	msgRebuildingModule                    = "Building %v again to verify it is reproducible"
+	msgNondeterministicBuilds              = "Builds of %v are not reproducible"
+	msgSSHWorkspaceBuild                   = "Commands in the workspace cannot be executed over ssh since remote hosts cannot check out uncommitted changes"
+	msgSSHWorktreeFailed                   = "Failed to check out %v on host %v: %v"
+	msgFailedLockFile                      = "Failed to lock file '%v'"
 )
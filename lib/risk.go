@@ -0,0 +1,213 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mbtproject/mbt/e"
+)
+
+// Risk levels of changes.
+const (
+	RiskLow    = "low"
+	RiskMedium = "medium"
+	RiskHigh   = "high"
+)
+
+// Thresholds at which each factor contributes its full weight to the
+// risk score.
+const (
+	riskFilesThreshold      = 20
+	riskLinesThreshold      = 500
+	riskDependentsThreshold = 10
+	riskDaysThreshold       = 180
+)
+
+// RiskFactors are the inputs of the risk score of a module change.
+type RiskFactors struct {
+	// Files is the number of files changed in the module.
+	Files int
+	// Lines is the number of lines added or deleted in the module.
+	Lines int
+	// Dependents is the number of modules depending on the module
+	// directly or transitively.
+	Dependents int
+	// DaysSinceLastChange is the number of days since the module was
+	// changed before the changes being assessed. -1 when unknown.
+	DaysSinceLastChange int
+}
+
+// RiskScore is the risk assessment of the changes of a module.
+type RiskScore struct {
+	Module string
+	// Score is a value between 0 and 100. Each factor contributes up
+	// to 25 points growing linearly until its threshold.
+	Score   int
+	Level   string
+	Factors *RiskFactors
+}
+
+// AssessRisk computes the risk scores of the modules in manifest m
+// based on the changes it is created for. Changes are read from the
+// repository at m.Dir with git. Scores are sorted in descending order.
+func AssessRisk(m *Manifest) ([]*RiskScore, error) {
+	return assessRisk(m, time.Now())
+}
+
+func assessRisk(m *Manifest, now time.Time) ([]*RiskScore, error) {
+	lines, err := changedLines(m)
+	if err != nil {
+		return nil, err
+	}
+
+	scores := make([]*RiskScore, 0, len(m.Modules))
+	for _, mod := range m.Modules {
+		f := &RiskFactors{Dependents: len(dependents(mod)), DaysSinceLastChange: -1}
+		for _, file := range mod.ChangedFiles(m) {
+			f.Files++
+			f.Lines += lines[file]
+		}
+
+		if m.Base != "" {
+			f.DaysSinceLastChange, err = daysSinceLastChange(m, mod, now)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		scores = append(scores, newRiskScore(mod.Name(), f))
+	}
+
+	sort.SliceStable(scores, func(i, j int) bool {
+		if scores[i].Score != scores[j].Score {
+			return scores[i].Score > scores[j].Score
+		}
+		return scores[i].Module < scores[j].Module
+	})
+
+	return scores, nil
+}
+
+func newRiskScore(name string, f *RiskFactors) *RiskScore {
+	score := riskPoints(f.Files, riskFilesThreshold) +
+		riskPoints(f.Lines, riskLinesThreshold) +
+		riskPoints(f.Dependents, riskDependentsThreshold) +
+		riskPoints(f.DaysSinceLastChange, riskDaysThreshold)
+
+	level := RiskLow
+	switch {
+	case score >= 67:
+		level = RiskHigh
+	case score >= 34:
+		level = RiskMedium
+	}
+
+	return &RiskScore{Module: name, Score: score, Level: level, Factors: f}
+}
+
+func riskPoints(v, threshold int) int {
+	if v <= 0 {
+		return 0
+	}
+	if v >= threshold {
+		return 25
+	}
+	return v * 25 / threshold
+}
+
+// dependents returns the modules depending on mod directly
+// or transitively.
+func dependents(mod *Module) map[string]bool {
+	seen := make(map[string]bool)
+	queue := append(Modules{}, mod.RequiredBy()...)
+	for len(queue) > 0 {
+		d := queue[0]
+		queue = queue[1:]
+		if seen[d.Name()] {
+			continue
+		}
+		seen[d.Name()] = true
+		queue = append(queue, d.RequiredBy()...)
+	}
+	return seen
+}
+
+// changedLines returns the number of lines added or deleted in each
+// file changed since the base of manifest m. Binary files do not have
+// any changed lines.
+func changedLines(m *Manifest) (map[string]int, error) {
+	lines := make(map[string]int)
+	if m.Base == "" || len(m.ChangedFiles) == 0 {
+		return lines, nil
+	}
+
+	args := []string{"diff", "--numstat", "--no-renames", m.Base}
+	if m.Sha != "local" {
+		args = append(args, m.Sha)
+	}
+
+	out, err := gitOutput(m.Dir, args...)
+	if err != nil {
+		return nil, e.Wrapf(ErrClassInternal, err, msgFailedDiffStats, m.Base)
+	}
+
+	parseNumstat(out, lines)
+	return lines, nil
+}
+
+// parseNumstat adds the changed lines in the output of git diff --numstat
+// to lines.
+func parseNumstat(out string, lines map[string]int) {
+	for _, l := range strings.Split(out, "\n") {
+		parts := strings.SplitN(l, "\t", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		added, _ := strconv.Atoi(parts[0])
+		deleted, _ := strconv.Atoi(parts[1])
+		lines[parts[2]] += added + deleted
+	}
+}
+
+// daysSinceLastChange returns the number of days since the last commit
+// changing the module before the base of manifest m.
+func daysSinceLastChange(m *Manifest, mod *Module, now time.Time) (int, error) {
+	path := mod.Path()
+	if path == "" {
+		path = "."
+	}
+
+	out, err := gitOutput(m.Dir, "log", "-1", "--format=%ct", m.Base, "--", path)
+	if err != nil {
+		return 0, e.Wrapf(ErrClassInternal, err, msgFailedDiffStats, m.Base)
+	}
+
+	if out == "" {
+		// Module did not exist at the base.
+		return -1, nil
+	}
+
+	ts, err := strconv.ParseInt(out, 10, 64)
+	if err != nil {
+		return 0, e.Wrapf(ErrClassInternal, err, msgFailedDiffStats, m.Base)
+	}
+
+	return int(now.Sub(time.Unix(ts, 0)).Hours() / 24), nil
+}
@@ -0,0 +1,80 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRiskScore(t *testing.T) {
+	s := newRiskScore("app-a", &RiskFactors{Files: 2, Lines: 50, Dependents: 0, DaysSinceLastChange: -1})
+	assert.Equal(t, 4, s.Score)
+	assert.Equal(t, RiskLow, s.Level)
+
+	s = newRiskScore("app-a", &RiskFactors{Files: 10, Lines: 250, Dependents: 5, DaysSinceLastChange: 90})
+	assert.Equal(t, 48, s.Score)
+	assert.Equal(t, RiskMedium, s.Level)
+
+	s = newRiskScore("app-a", &RiskFactors{Files: 40, Lines: 1000, Dependents: 10, DaysSinceLastChange: 30})
+	assert.Equal(t, 79, s.Score)
+	assert.Equal(t, RiskHigh, s.Level)
+}
+
+func TestParseNumstat(t *testing.T) {
+	lines := make(map[string]int)
+	parseNumstat("10\t2\tapp-a/main.go\n-\t-\tapp-a/logo.png\n1\t0\tapp-b/file with spaces.go", lines)
+
+	assert.Equal(t, map[string]int{"app-a/main.go": 12, "app-a/logo.png": 0, "app-b/file with spaces.go": 1}, lines)
+}
+
+func TestAssessRisk(t *testing.T) {
+	clean()
+	check(t, os.MkdirAll(".tmp/risk", 0755))
+	runGit(t, "-C", ".tmp/risk", "init", "--quiet")
+	writeAuditFile(t, ".tmp/risk/app-a/main.go", "a\n")
+	writeAuditFile(t, ".tmp/risk/lib-b/lib.go", "b\n")
+	runGit(t, "-C", ".tmp/risk", "add", "-A")
+	runGit(t, "-C", ".tmp/risk", "commit", "--quiet", "-m", "first")
+	base := runGit(t, "-C", ".tmp/risk", "rev-parse", "HEAD")
+	ts, err := strconv.ParseInt(runGit(t, "-C", ".tmp/risk", "log", "-1", "--format=%ct"), 10, 64)
+	check(t, err)
+
+	writeAuditFile(t, ".tmp/risk/lib-b/lib.go", "b\nc\nd\n")
+	runGit(t, "-C", ".tmp/risk", "add", "-A")
+	runGit(t, "-C", ".tmp/risk", "commit", "--quiet", "-m", "second")
+	sha := runGit(t, "-C", ".tmp/risk", "rev-parse", "HEAD")
+
+	a := newTestModule("app-a", "app-a", "a")
+	b := newTestModule("lib-b", "lib-b", "b")
+	a.requires = Modules{b}
+	b.requiredBy = Modules{a}
+	m := &Manifest{Dir: ".tmp/risk", Sha: sha, Base: base, Modules: Modules{a, b}}
+	m.setChangedFiles([]string{"lib-b/lib.go"})
+
+	scores, err := assessRisk(m, time.Unix(ts, 0).Add(30*24*time.Hour))
+	check(t, err)
+
+	assert.Len(t, scores, 2)
+	assert.Equal(t, "lib-b", scores[0].Module)
+	assert.Equal(t, &RiskFactors{Files: 1, Lines: 2, Dependents: 1, DaysSinceLastChange: 30}, scores[0].Factors)
+	assert.Equal(t, "app-a", scores[1].Module)
+	assert.Equal(t, &RiskFactors{Files: 0, Lines: 0, Dependents: 0, DaysSinceLastChange: 30}, scores[1].Factors)
+}
@@ -139,8 +139,18 @@ func (s *stdSystem) runManifest(command string, m *Manifest, options *CmdOptions
 			continue
 		}
 
-		options.Callback(a, CmdStageBeforeBuild, nil)
-		err = s.execCommand(cmd, m, a, options)
+		run, whenErr := evalWhen(cmd.Cmd, cmd.When, m, a)
+		if whenErr == nil && !run {
+			skipped = append(skipped, a)
+			options.Callback(a, CmdStageSkipBuild, nil)
+			continue
+		}
+
+		err = whenErr
+		if err == nil {
+			options.Callback(a, CmdStageBeforeBuild, nil)
+			err = s.execCommand(cmd, m, a, options)
+		}
 		if err != nil {
 			failed = append(failed, &CmdFailure{Err: err, Module: a})
 			options.Callback(a, CmdStageFailedBuild, err)
@@ -154,7 +164,10 @@ func (s *stdSystem) runManifest(command string, m *Manifest, options *CmdOptions
 }
 
 func (s *stdSystem) execCommand(command *UserCmd, manifest *Manifest, module *Module, options *CmdOptions) error {
-	err := s.ProcessManager.Exec(manifest, module, options, command.Cmd, command.Args...)
+	options, flush := moduleOutput(options, module)
+	defer flush()
+
+	err := s.execWithRetries(manifest, module, options, command.Timeout, command.Retries, "", command.Cmd, command.Args...)
 	if err != nil {
 		return e.Wrap(ErrClassUser, err)
 	}
@@ -0,0 +1,91 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/mbtproject/mbt/e"
+)
+
+const (
+	// SBOMFormatCycloneDX is CycloneDX json format.
+	SBOMFormatCycloneDX = "cyclonedx"
+	// SBOMFormatSPDX is SPDX json format.
+	SBOMFormatSPDX = "spdx"
+)
+
+// SBOMOptions describes how the software bill of materials (SBOM)
+// of modules are generated.
+type SBOMOptions struct {
+	// Dir is the directory where SBOMs are written.
+	// SBOM of each module is stored in <Dir>/<module>-<version>.<Format>.json.
+	Dir string
+	// Format of the SBOMs. Either SBOMFormatCycloneDX or SBOMFormatSPDX.
+	Format string
+	// Scanner generates the SBOM of the modules without an sbom
+	// command in their spec. It is executed in the module directory
+	// as <Scanner> dir:. -o <Format>-json (e.g. syft) and the SBOM is
+	// read from its stdout.
+	Scanner string
+}
+
+// generateSBOM writes the SBOM of a module built with the command in
+// its spec or the default scanner. Returns the path to the SBOM.
+func (s *stdSystem) generateSBOM(m *Manifest, mod *Module, options *CmdOptions) (string, error) {
+	sbom := options.SBOM
+	format := sbom.Format
+	if format == "" {
+		format = SBOMFormatCycloneDX
+	}
+
+	if format != SBOMFormatCycloneDX && format != SBOMFormatSPDX {
+		return "", e.NewErrorf(ErrClassUser, msgInvalidSBOMFormat, format)
+	}
+
+	cmd := mod.SBOM()
+	if cmd == nil {
+		scanner := sbom.Scanner
+		if scanner == "" {
+			scanner = "syft"
+		}
+		cmd = &Cmd{Cmd: scanner, Args: []string{"dir:.", "-o", format + "-json"}}
+	}
+
+	if err := os.MkdirAll(sbom.Dir, 0755); err != nil {
+		return "", e.Wrapf(ErrClassUser, err, msgFailedSBOM, moduleDisplayName(mod))
+	}
+
+	path := filepath.Join(sbom.Dir, fmt.Sprintf("%s-%s.%s.json", mod.Name(), mod.Version(), format))
+	f, err := os.Create(path)
+	if err != nil {
+		return "", e.Wrapf(ErrClassUser, err, msgFailedSBOM, moduleDisplayName(mod))
+	}
+	defer f.Close()
+
+	o := *options
+	o.Stdin = nil
+	o.Stdout = f
+	// Retries are not applicable since the output is not repeatable.
+	err = s.execWithRetries(m, mod, &o, cmd.Timeout, 0, cmd.WorkDir, cmd.Cmd, cmd.Args...)
+	if err != nil {
+		return "", e.Wrapf(ErrClassUser, err, msgFailedSBOM, moduleDisplayName(mod))
+	}
+
+	return path, nil
+}
@@ -0,0 +1,108 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"sort"
+	"time"
+)
+
+type scheduledResult struct {
+	mod    *Module
+	weight int
+	err    error
+}
+
+// schedule invokes fn for each module once all of its dependencies
+// in mods are complete. Modules are started in the order they appear
+// in mods as long as the total weight of the running modules is
+// within the capacity. A module heavier than the capacity is started
+// only when nothing else is running.
+// If priorities are specified, ready modules with higher priority
+// are started first.
+// When fn returns an error, no further modules are started and the
+// first error is returned once the running modules are complete.
+func schedule(mods Modules, capacity int, priorities map[string]time.Duration, fn func(mod *Module) error) error {
+	if capacity < 1 {
+		capacity = 1
+	}
+
+	if priorities != nil {
+		ordered := make(Modules, len(mods))
+		copy(ordered, mods)
+		sort.SliceStable(ordered, func(i, j int) bool {
+			return priorities[ordered[i].Name()] > priorities[ordered[j].Name()]
+		})
+		mods = ordered
+	}
+
+	scheduled := make(map[string]bool, len(mods))
+	for _, m := range mods {
+		scheduled[m.Name()] = true
+	}
+
+	started := make(map[string]bool, len(mods))
+	done := make(map[string]bool, len(mods))
+	results := make(chan *scheduledResult)
+	running, active := 0, 0
+	var firstErr error
+
+	ready := func(mod *Module) bool {
+		for _, r := range mod.Requires() {
+			if scheduled[r.Name()] && !done[r.Name()] {
+				return false
+			}
+		}
+		return true
+	}
+
+	for {
+		if firstErr == nil {
+			for _, mod := range mods {
+				if started[mod.Name()] || !ready(mod) {
+					continue
+				}
+
+				w := mod.Resources().Weight
+				if w > capacity {
+					w = capacity
+				}
+				if running+w > capacity {
+					continue
+				}
+
+				started[mod.Name()] = true
+				running += w
+				active++
+				go func(mod *Module, w int) {
+					results <- &scheduledResult{mod: mod, weight: w, err: fn(mod)}
+				}(mod, w)
+			}
+		}
+
+		if active == 0 {
+			return firstErr
+		}
+
+		r := <-results
+		active--
+		running -= r.weight
+		done[r.mod.Name()] = true
+		if r.err != nil && firstErr == nil {
+			firstErr = r.err
+		}
+	}
+}
@@ -0,0 +1,134 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSequentialSchedule(t *testing.T) {
+	a := newTestModule("app-a", "app-a", "a")
+	b := newTestModule("app-b", "app-b", "b", a)
+	c := newTestModule("app-c", "app-c", "c")
+	c.metadata.spec.Resources = &Resources{Weight: 5}
+
+	order := make([]string, 0)
+	err := schedule(Modules{a, b, c}, 1, nil, func(mod *Module) error {
+		order = append(order, mod.Name())
+		return nil
+	})
+
+	check(t, err)
+	assert.Equal(t, []string{"app-a", "app-b", "app-c"}, order)
+}
+
+func TestParallelScheduleRespectsWeights(t *testing.T) {
+	weights := []int{2, 1, 2, 1, 10}
+	mods := make(Modules, 0, len(weights))
+	for i, w := range weights {
+		name := fmt.Sprintf("app-%d", i)
+		mod := newTestModule(name, name, name)
+		mod.metadata.spec.Resources = &Resources{Weight: w}
+		mods = append(mods, mod)
+	}
+
+	var mutex sync.Mutex
+	running, peak := 0, 0
+	err := schedule(mods, 3, nil, func(mod *Module) error {
+		w := mod.Resources().Weight
+		if w > 3 {
+			w = 3
+		}
+
+		mutex.Lock()
+		running += w
+		if running > peak {
+			peak = running
+		}
+		mutex.Unlock()
+
+		time.Sleep(10 * time.Millisecond)
+
+		mutex.Lock()
+		running -= w
+		mutex.Unlock()
+		return nil
+	})
+
+	check(t, err)
+	assert.Equal(t, 3, peak)
+}
+
+func TestParallelScheduleRespectsDependencies(t *testing.T) {
+	a := newTestModule("app-a", "app-a", "a")
+	b := newTestModule("app-b", "app-b", "b", a)
+	c := newTestModule("app-c", "app-c", "c", b)
+
+	var mutex sync.Mutex
+	order := make([]string, 0)
+	err := schedule(Modules{a, b, c}, 10, nil, func(mod *Module) error {
+		time.Sleep(time.Millisecond)
+		mutex.Lock()
+		order = append(order, mod.Name())
+		mutex.Unlock()
+		return nil
+	})
+
+	check(t, err)
+	assert.Equal(t, []string{"app-a", "app-b", "app-c"}, order)
+}
+
+func TestScheduleStopsOnError(t *testing.T) {
+	a := newTestModule("app-a", "app-a", "a")
+	b := newTestModule("app-b", "app-b", "b")
+
+	called := make([]string, 0)
+	err := schedule(Modules{a, b}, 1, nil, func(mod *Module) error {
+		called = append(called, mod.Name())
+		return errors.New("doh")
+	})
+
+	assert.EqualError(t, err, "doh")
+	assert.Equal(t, []string{"app-a"}, called)
+}
+
+func TestDefaultResources(t *testing.T) {
+	assert.Equal(t, &Resources{Weight: 1}, newTestModule("app-a", "app-a", "a").Resources())
+	mod := newTestModule("app-a", "app-a", "a")
+	mod.metadata.spec.Resources = &Resources{}
+	assert.Equal(t, 1, mod.Resources().Weight)
+}
+
+func TestScheduleByPriority(t *testing.T) {
+	a := newTestModule("app-a", "app-a", "a")
+	b := newTestModule("app-b", "app-b", "b")
+	c := newTestModule("app-c", "app-c", "c", a)
+
+	order := make([]string, 0)
+	err := schedule(Modules{a, b, c}, 1, map[string]time.Duration{"app-a": 1, "app-b": 5, "app-c": 1}, func(mod *Module) error {
+		order = append(order, mod.Name())
+		return nil
+	})
+
+	check(t, err)
+	assert.Equal(t, []string{"app-b", "app-a", "app-c"}, order)
+}
@@ -0,0 +1,301 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/mbtproject/mbt/e"
+)
+
+// Dependents inclusion policies.
+const (
+	// DependentsTransitive includes all modules depending on the
+	// changed modules directly or indirectly. This is the default.
+	DependentsTransitive = "transitive"
+	// DependentsDirect includes only the modules depending on the
+	// changed modules directly.
+	DependentsDirect = "direct"
+	// DependentsNone includes only the changed modules.
+	DependentsNone = "none"
+)
+
+// ManifestBuilderOptions describes the optional settings of the
+// standard manifest builder implementation.
+type ManifestBuilderOptions struct {
+	// ForceInclude are the names of the modules always included in
+	// diff based manifests regardless of the changes (e.g. smoke
+	// test suites).
+	ForceInclude []string
+	// ForceExclude are the names of the modules never included in
+	// diff based manifests (e.g. deprecated modules). Exclusion takes
+	// precedence over inclusion.
+	ForceExclude []string
+	// Dependents is the policy of including the dependents of changed
+	// modules in diff based manifests. It is one of DependentsNone,
+	// DependentsDirect, DependentsTransitive or the maximum depth of
+	// the dependents included (e.g. 2 includes the dependents of
+	// direct dependents). Defaults to DependentsTransitive.
+	Dependents string
+	// Budget is the maximum cost of the modules selected in diff
+	// based manifests (see Module.Cost). Zero disables the budget.
+	Budget int
+	// BudgetPolicy is either BudgetWarn or BudgetFail.
+	BudgetPolicy string
+	// Trailers are the keys of the commit trailers (e.g. Ticket)
+	// collected from the commits changing each module in diff based
+	// manifests. See Module.Trailers.
+	Trailers []string
+}
+
+// dependentsDepth returns the maximum depth of the dependents
+// included by policy or -1 if all dependents are included.
+func dependentsDepth(policy string) (int, error) {
+	switch policy {
+	case "", DependentsTransitive:
+		return -1, nil
+	case DependentsNone:
+		return 0, nil
+	case DependentsDirect:
+		return 1, nil
+	}
+
+	depth, err := strconv.Atoi(policy)
+	if err != nil || depth < 0 {
+		return 0, e.NewErrorf(ErrClassUser, msgInvalidDependentsPolicy, policy)
+	}
+	return depth, nil
+}
+
+// expandDependents adds the dependents of the modules selected from
+// all by a diff according to the dependents policy.
+func (b *stdManifestBuilder) expandDependents(all, selected Modules) (Modules, error) {
+	depth := -1
+	if b.options != nil {
+		var err error
+		depth, err = dependentsDepth(b.options.Dependents)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if depth < 0 {
+		return selected.expandRequiredByDependencies()
+	}
+
+	names := make(map[string]bool, len(selected))
+	for _, m := range selected {
+		names[m.Name()] = true
+	}
+
+	level := selected
+	for i := 0; i < depth && len(level) > 0; i++ {
+		next := make(Modules, 0)
+		for _, m := range level {
+			for _, d := range m.requiredBy {
+				if !names[d.Name()] {
+					names[d.Name()] = true
+					next = append(next, d)
+				}
+			}
+		}
+		level = next
+	}
+
+	r := make(Modules, 0, len(names))
+	for _, m := range all {
+		if names[m.Name()] {
+			r = append(r, m)
+		}
+	}
+	return r, nil
+}
+
+// force applies the forced inclusions and exclusions to the modules
+// selected from all by a diff. Modules are returned in the order of
+// all which is topologically sorted.
+func (b *stdManifestBuilder) force(all, selected Modules) Modules {
+	if b.options == nil || (len(b.options.ForceInclude) == 0 && len(b.options.ForceExclude) == 0) {
+		return selected
+	}
+
+	index := all.indexByName()
+	names := make(map[string]bool, len(selected))
+	for _, m := range selected {
+		names[m.Name()] = true
+	}
+
+	for _, n := range b.options.ForceInclude {
+		if _, ok := index[n]; !ok {
+			b.Log.Warnf(msgForcedModuleNotFound, n)
+			continue
+		}
+		names[n] = true
+	}
+
+	for _, n := range b.options.ForceExclude {
+		delete(names, n)
+	}
+
+	r := make(Modules, 0, len(names))
+	for _, m := range all {
+		if names[m.Name()] {
+			r = append(r, m)
+		}
+	}
+	return r
+}
+
+// Reasons of selecting a module in a diff based manifest.
+const (
+	// SelectionChanged is the reason of a module with changed files
+	// in its directory.
+	SelectionChanged = "changed"
+	// SelectionFileDependency is the reason of a module with a changed
+	// file dependency.
+	SelectionFileDependency = "file-dependency"
+	// SelectionDependency is the reason of a module depending on a
+	// selected module.
+	SelectionDependency = "dependency"
+	// SelectionForced is the reason of a module forced to be included.
+	SelectionForced = "forced"
+)
+
+// SelectionReason describes why a module is selected in a diff based
+// manifest.
+type SelectionReason struct {
+	Kind string `json:"kind" yaml:"kind"`
+	// Detail is the matching file dependency or the name of the
+	// selected dependency.
+	Detail string `json:"detail,omitempty" yaml:"detail,omitempty"`
+}
+
+func (r *SelectionReason) String() string {
+	switch r.Kind {
+	case SelectionChanged:
+		return "own files changed"
+	case SelectionFileDependency:
+		return fmt.Sprintf("file dependency %s changed", r.Detail)
+	case SelectionDependency:
+		return fmt.Sprintf("dependency %s changed", r.Detail)
+	case SelectionForced:
+		return "forced"
+	}
+	return r.Kind
+}
+
+// SelectionReasons returns the reasons of selecting the module in a
+// diff based manifest. It is empty in other manifests.
+func (a *Module) SelectionReasons() []*SelectionReason {
+	reasons := make([]*SelectionReason, 0, len(a.reasons))
+	for _, r := range a.reasons {
+		c := *r
+		reasons = append(reasons, &c)
+	}
+	return reasons
+}
+
+// explainSelection sets the reasons of selecting each module in mods.
+// changed are the modules selected by the changed files.
+func (b *stdManifestBuilder) explainSelection(mods, changed Modules, files []string) {
+	direct := make(map[string]bool, len(changed))
+	for _, m := range changed {
+		direct[m.Name()] = true
+	}
+
+	selected := make(map[string]bool, len(mods))
+	for _, m := range mods {
+		selected[m.Name()] = true
+	}
+
+	forced := make(map[string]bool)
+	expands := true
+	if b.options != nil {
+		for _, n := range b.options.ForceInclude {
+			forced[n] = true
+		}
+		depth, _ := dependentsDepth(b.options.Dependents)
+		expands = depth != 0
+	}
+
+	for _, m := range mods {
+		reasons := make([]*SelectionReason, 0)
+		if direct[m.Name()] {
+			reasons = append(reasons, changeReasons(m, files)...)
+		}
+		if expands {
+			for _, d := range m.requires {
+				if selected[d.Name()] {
+					reasons = append(reasons, &SelectionReason{Kind: SelectionDependency, Detail: d.Name()})
+				}
+			}
+		}
+		if forced[m.Name()] {
+			reasons = append(reasons, &SelectionReason{Kind: SelectionForced})
+		}
+		m.reasons = reasons
+	}
+}
+
+// changeReasons returns the reasons of selecting a module by the
+// changed files.
+func changeReasons(m *Module, files []string) []*SelectionReason {
+	prefix := strings.ToLower(m.Path() + "/")
+	own := false
+	for _, f := range files {
+		if m.Path() == "" || strings.HasPrefix(strings.ToLower(f), prefix) {
+			own = true
+			break
+		}
+	}
+
+	reasons := make([]*SelectionReason, 0)
+	if own {
+		reasons = append(reasons, &SelectionReason{Kind: SelectionChanged})
+	}
+
+	for _, d := range m.FileDependencies() {
+		ld := strings.ToLower(d)
+		for _, f := range files {
+			if strings.HasPrefix(strings.ToLower(f), ld) {
+				reasons = append(reasons, &SelectionReason{Kind: SelectionFileDependency, Detail: d})
+				break
+			}
+		}
+	}
+
+	if len(reasons) == 0 {
+		// Module is selected by a reducer matching changes
+		// differently.
+		reasons = append(reasons, &SelectionReason{Kind: SelectionChanged})
+	}
+	return reasons
+}
+
+// deltaFiles returns the files changed by deltas including the
+// original paths of renamed files.
+func deltaFiles(deltas []*DiffDelta) []string {
+	files := make([]string, 0, len(deltas))
+	for _, d := range deltas {
+		files = append(files, d.NewFile)
+		if d.OldFile != "" && d.OldFile != d.NewFile {
+			files = append(files, d.OldFile)
+		}
+	}
+	return files
+}
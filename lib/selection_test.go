@@ -0,0 +1,184 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/mbtproject/mbt/e"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestForceSelection(t *testing.T) {
+	a := newTestModule("app-a", "app-a", "a1")
+	b := newTestModule("app-b", "app-b", "b1")
+	c := newTestModule("app-c", "app-c", "c1")
+	d := newTestModule("app-d", "app-d", "d1")
+	all := Modules{a, b, c, d}
+
+	builder := &stdManifestBuilder{Log: NewStdLog(LogLevelNormal), options: &ManifestBuilderOptions{
+		ForceInclude: []string{"app-d", "app-a", "app-x"},
+		ForceExclude: []string{"app-c", "app-a"},
+	}}
+
+	assert.Equal(t, []string{"app-b", "app-d"}, builder.force(all, Modules{c, b}).Names())
+	assert.Equal(t, []string{"app-d"}, builder.force(all, Modules{}).Names())
+}
+
+func TestForceSelectionWithoutOptions(t *testing.T) {
+	a := newTestModule("app-a", "app-a", "a1")
+	b := newTestModule("app-b", "app-b", "b1")
+	selected := Modules{b, a}
+
+	assert.Equal(t, selected, (&stdManifestBuilder{}).force(Modules{a, b}, selected))
+	assert.Equal(t, selected, (&stdManifestBuilder{options: &ManifestBuilderOptions{}}).force(Modules{a, b}, selected))
+}
+
+func TestForceSelectionInDiff(t *testing.T) {
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+
+	check(t, repo.InitModule("app-a"))
+	check(t, repo.InitModule("app-b"))
+	check(t, repo.InitModule("app-c"))
+	check(t, repo.Commit("first"))
+	c1 := repo.LastCommit
+
+	check(t, repo.WriteContent("app-a/foo", "bar"))
+	check(t, repo.WriteContent("app-b/foo", "bar"))
+	check(t, repo.Commit("second"))
+	c2 := repo.LastCommit
+
+	log := NewStdLog(LogLevelNormal)
+	r, err := NewLibgitRepo(".tmp/repo", log)
+	check(t, err)
+
+	from, err := r.GetCommit(c1.String())
+	check(t, err)
+	to, err := r.GetCommit(c2.String())
+	check(t, err)
+
+	mb := NewManifestBuilderWithOptions(r, NewReducer(log), NewDiscover(r, log), log, &ManifestBuilderOptions{
+		ForceInclude: []string{"app-c"},
+		ForceExclude: []string{"app-b"},
+	})
+	m, err := mb.ByDiff(from, to)
+	check(t, err)
+
+	assert.Equal(t, []string{"app-a", "app-c"}, m.Modules.Names())
+}
+
+func TestDependentsPolicy(t *testing.T) {
+	// lib-a <- app-b <- app-c <- app-d, lib-e is unrelated.
+	a := newModule(newModuleMetadata("lib-a", "a1", &Spec{Name: "lib-a"}, nil), nil)
+	b := newModule(newModuleMetadata("app-b", "b1", &Spec{Name: "app-b"}, nil), Modules{a})
+	c := newModule(newModuleMetadata("app-c", "c1", &Spec{Name: "app-c"}, nil), Modules{b})
+	d := newModule(newModuleMetadata("app-d", "d1", &Spec{Name: "app-d"}, nil), Modules{c})
+	x := newModule(newModuleMetadata("lib-e", "e1", &Spec{Name: "lib-e"}, nil), nil)
+	all := Modules{a, x, b, c, d}
+
+	expand := func(policy string) []string {
+		builder := &stdManifestBuilder{options: &ManifestBuilderOptions{Dependents: policy}}
+		mods, err := builder.expandDependents(all, Modules{a})
+		check(t, err)
+		return mods.Names()
+	}
+
+	assert.Equal(t, []string{"lib-a"}, expand(DependentsNone))
+	assert.Equal(t, []string{"lib-a"}, expand("0"))
+	assert.Equal(t, []string{"lib-a", "app-b"}, expand(DependentsDirect))
+	assert.Equal(t, []string{"lib-a", "app-b", "app-c"}, expand("2"))
+	assert.Equal(t, []string{"lib-a", "app-b", "app-c", "app-d"}, expand("10"))
+	assert.Equal(t, []string{"lib-a", "app-b", "app-c", "app-d"}, expand(DependentsTransitive))
+	assert.Equal(t, []string{"lib-a", "app-b", "app-c", "app-d"}, expand(""))
+}
+
+func TestInvalidDependentsPolicy(t *testing.T) {
+	for _, p := range []string{"all", "-1", "1.5"} {
+		builder := &stdManifestBuilder{options: &ManifestBuilderOptions{Dependents: p}}
+		_, err := builder.expandDependents(Modules{}, Modules{})
+
+		assert.EqualError(t, err, fmt.Sprintf(msgInvalidDependentsPolicy, p))
+		assert.Equal(t, ErrClassUser, (err.(*e.E)).Class())
+	}
+
+	_, err := NewSystemWithOptions(".tmp/repo", &SystemOptions{Dependents: "all"})
+	assert.EqualError(t, err, fmt.Sprintf(msgInvalidDependentsPolicy, "all"))
+}
+
+func reasonStrings(mod *Module) []string {
+	reasons := make([]string, 0)
+	for _, r := range mod.SelectionReasons() {
+		reasons = append(reasons, r.String())
+	}
+	return reasons
+}
+
+func TestExplainSelection(t *testing.T) {
+	a := newModule(newModuleMetadata("lib-a", "a1", &Spec{Name: "lib-a", FileDependencies: []string{"shared/config.yml"}}, nil), nil)
+	b := newModule(newModuleMetadata("app-b", "b1", &Spec{Name: "app-b"}, nil), Modules{a})
+	c := newModule(newModuleMetadata("app-c", "c1", &Spec{Name: "app-c"}, nil), nil)
+	d := newModule(newModuleMetadata("app-d", "d1", &Spec{Name: "app-d"}, nil), nil)
+
+	builder := &stdManifestBuilder{options: &ManifestBuilderOptions{ForceInclude: []string{"app-d"}}}
+	builder.explainSelection(Modules{a, b, c, d}, Modules{a, c}, []string{"lib-a/main.go", "shared/config.yml"})
+
+	assert.Equal(t, []string{"own files changed", "file dependency shared/config.yml changed"}, reasonStrings(a))
+	assert.Equal(t, []string{"dependency lib-a changed"}, reasonStrings(b))
+	assert.Equal(t, []string{"own files changed"}, reasonStrings(c))
+	assert.Equal(t, []string{"forced"}, reasonStrings(d))
+	assert.Equal(t, []string{"dependency lib-a changed"}, b.Document().SelectedBecause)
+	assert.Empty(t, newTestModule("app-e", "app-e", "e1").SelectionReasons())
+}
+
+func TestExplainSelectionWithoutDependents(t *testing.T) {
+	a := newModule(newModuleMetadata("lib-a", "a1", &Spec{Name: "lib-a"}, nil), nil)
+	b := newModule(newModuleMetadata("app-b", "b1", &Spec{Name: "app-b"}, nil), Modules{a})
+
+	builder := &stdManifestBuilder{options: &ManifestBuilderOptions{Dependents: DependentsNone}}
+	builder.explainSelection(Modules{a, b}, Modules{a, b}, []string{"lib-a/main.go", "app-b/main.go"})
+
+	assert.Equal(t, []string{"own files changed"}, reasonStrings(b))
+}
+
+func TestExplainSelectionInDiff(t *testing.T) {
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+
+	check(t, repo.InitModuleWithOptions("lib-a", &Spec{Name: "lib-a"}))
+	check(t, repo.InitModuleWithOptions("app-b", &Spec{Name: "app-b", Dependencies: []string{"lib-a"}}))
+	check(t, repo.InitModuleWithOptions("app-c", &Spec{Name: "app-c", FileDependencies: []string{"shared"}}))
+	check(t, repo.Commit("first"))
+	c1 := repo.LastCommit
+
+	check(t, repo.WriteContent("lib-a/foo", "bar"))
+	check(t, repo.WriteContent("shared/foo", "bar"))
+	check(t, repo.Commit("second"))
+
+	m, err := NewWorld(t, ".tmp/repo").System.ManifestByDiff(c1.String(), repo.LastCommit.String())
+	check(t, err)
+
+	reasons := make(map[string][]string)
+	for _, mod := range m.Modules {
+		reasons[mod.Name()] = reasonStrings(mod)
+	}
+	assert.Equal(t, map[string][]string{
+		"lib-a": {"own files changed"},
+		"app-b": {"dependency lib-a changed"},
+		"app-c": {"file dependency shared changed"},
+	}, reasons)
+}
@@ -0,0 +1,123 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/mbtproject/mbt/e"
+)
+
+// DefaultSnapshotPattern is the pattern of snapshot versions used
+// when a pattern is not specified (e.g. 1.2.3-20240601.1+4b9a0d4).
+const DefaultSnapshotPattern = "{semver}-{date}.{buildNumber}+{sha}"
+
+// defaultSemver is the semantic version of modules without semver
+// in their spec.
+const defaultSemver = "0.0.0"
+
+// shortShaLength is the length of abbreviated commit shas.
+const shortShaLength = 7
+
+var semverPattern = regexp.MustCompile(`^[0-9]+\.[0-9]+\.[0-9]+$`)
+
+// Semver returns the semantic version specified in the module spec.
+// Defaults to 0.0.0.
+func (a *Module) Semver() string {
+	if v := a.metadata.spec.Semver; v != "" {
+		return v
+	}
+	return defaultSemver
+}
+
+// SnapshotVersion returns the snapshot version issued to the module
+// when it is built with CmdOptions.SnapshotPattern. Empty otherwise.
+func (a *Module) SnapshotVersion() string {
+	return a.snapshotVersion
+}
+
+// withSnapshotVersion returns a copy of the module with snapshot
+// version v (see withBuildNumber).
+func (a *Module) withSnapshotVersion(v string) *Module {
+	c := *a
+	c.snapshotVersion = v
+	return &c
+}
+
+func validateSemver(spec *Spec) error {
+	if spec.Semver != "" && !semverPattern.MatchString(spec.Semver) {
+		return e.NewErrorf(ErrClassUser, msgInvalidSemver, spec.Semver, spec.Name)
+	}
+	return nil
+}
+
+// snapshotVersion computes the snapshot version of a module in the
+// manifest created for commit sha at time t. Following placeholders
+// are replaced in pattern:
+//
+//	{semver}: semantic version in the module spec
+//	{date}: date of t in UTC formatted as yyyyMMdd
+//	{time}: time of t in UTC formatted as HHmmss
+//	{buildNumber}: build number of the module (0 if not issued)
+//	{sha}: abbreviated commit sha
+//	{version}: module version
+//	{name}: module name
+func snapshotVersion(pattern string, mod *Module, sha string, t time.Time) (string, error) {
+	if pattern == "" {
+		pattern = DefaultSnapshotPattern
+	}
+
+	var err error
+	v := imagePlaceholder.ReplaceAllStringFunc(pattern, func(p string) string {
+		switch p[1 : len(p)-1] {
+		case "semver":
+			return mod.Semver()
+		case "date":
+			return t.UTC().Format("20060102")
+		case "time":
+			return t.UTC().Format("150405")
+		case "buildNumber":
+			return fmt.Sprint(mod.BuildNumber())
+		case "sha":
+			if len(sha) > shortShaLength {
+				return sha[:shortShaLength]
+			}
+			return sha
+		case "version":
+			return mod.Version()
+		case "name":
+			return mod.Name()
+		}
+
+		if err == nil {
+			err = e.NewErrorf(ErrClassUser, msgInvalidSnapshotPattern, pattern, p)
+		}
+		return ""
+	})
+
+	if err != nil {
+		return "", err
+	}
+	return v, nil
+}
+
+// SnapshotVersion returns the snapshot version of mod in manifest m
+// computed from pattern at time t. See DefaultSnapshotPattern.
+func (m *Manifest) SnapshotVersion(mod *Module, pattern string, t time.Time) (string, error) {
+	return snapshotVersion(pattern, mod, m.Sha, t)
+}
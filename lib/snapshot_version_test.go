@@ -0,0 +1,118 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mbtproject/mbt/e"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSnapshotVersion(t *testing.T) {
+	mod := newTestModule("app-a", "app-a", "a1")
+	mod.metadata.spec.Semver = "1.2.3"
+	mod.buildNumber = 4
+	m := &Manifest{Sha: "4b9a0d4f2fd0bd3c7d0b3a1e1f4c1a2b3c4d5e6f", Modules: Modules{mod}}
+	now := time.Date(2024, 6, 1, 23, 30, 15, 0, time.FixedZone("X", -3600))
+
+	v, err := m.SnapshotVersion(mod, "", now)
+	check(t, err)
+	assert.Equal(t, "1.2.3-20240602.4+4b9a0d4", v)
+
+	v, err = m.SnapshotVersion(mod, "{name}-{semver}-{date}{time}-{version}", now)
+	check(t, err)
+	assert.Equal(t, "app-a-1.2.3-20240602003015-a1", v)
+
+	v, err = (&Manifest{Sha: "local"}).SnapshotVersion(newTestModule("app-b", "app-b", "b1"), "", now)
+	check(t, err)
+	assert.Equal(t, "0.0.0-20240602.0+local", v)
+}
+
+func TestSnapshotVersionWithInvalidPattern(t *testing.T) {
+	_, err := (&Manifest{}).SnapshotVersion(newTestModule("app-a", "app-a", "a1"), "{semver}-{branch}", time.Now())
+
+	assert.EqualError(t, err, fmt.Sprintf(msgInvalidSnapshotPattern, "{semver}-{branch}", "{branch}"))
+	assert.Equal(t, ErrClassUser, (err.(*e.E)).Class())
+}
+
+func TestInvalidSemver(t *testing.T) {
+	for _, v := range []string{"1.2", "v1.2.3", "1.2.3-rc1"} {
+		_, err := newSpec([]byte("name: app-a\nsemver: " + v))
+
+		assert.EqualError(t, err, fmt.Sprintf(msgInvalidSemver, v, "app-a"))
+	}
+
+	spec, err := newSpec([]byte("name: app-a\nsemver: 10.0.1"))
+	check(t, err)
+	assert.Equal(t, "10.0.1", spec.Semver)
+}
+
+func TestSnapshotVersionEnvironment(t *testing.T) {
+	mod := newTestModule("app-a", "app-a", "a1")
+	assert.NotContains(t, strings.Join(setupModBuildEnvironment(&Manifest{}, mod), "\n"), "MBT_SNAPSHOT_VERSION")
+
+	mod.snapshotVersion = "1.2.3-20240601.1+4b9a0d4"
+	assert.Contains(t, setupModBuildEnvironment(&Manifest{}, mod), "MBT_SNAPSHOT_VERSION=1.2.3-20240601.1+4b9a0d4")
+}
+
+func TestSnapshotVersionTemplateFunction(t *testing.T) {
+	m := &Manifest{Sha: "4b9a0d4f2fd0bd3c7d0b3a1e1f4c1a2b3c4d5e6f", Modules: Modules{newTestModule("app-a", "app-a", "a1")}}
+	output := new(bytes.Buffer)
+
+	err := processTemplate([]byte(`{{snapshotVersion (module "app-a") "{semver}.{buildNumber}+{sha}"}}`), m, output)
+	check(t, err)
+	assert.Equal(t, "0.0.0.0+4b9a0d4", output.String())
+}
+
+func TestSnapshotVersionsInParallelBuild(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mbt-snapshot-versions")
+	check(t, err)
+	defer os.RemoveAll(dir)
+
+	mods := Modules{}
+	for _, name := range []string{"app-a", "app-b", "app-c", "app-d"} {
+		spec := &Spec{Name: name, Semver: "1.0.0", Build: map[string]*Cmd{"default": {Cmd: "./build.sh"}}}
+		mods = append(mods, newModule(newModuleMetadata(name, name, spec, nil), nil))
+	}
+	m := &Manifest{Dir: dir, Sha: "4b9a0d4f2fd0bd3c7d0b3a1e1f4c1a2b3c4d5e6f", Modules: mods}
+
+	s := &stdSystem{Log: NewStdLog(LogLevelNormal), ProcessManager: &buildNumberProcessManager{numbers: make(map[string]int)}}
+	options := stdTestCmdOptions(new(bytes.Buffer))
+	options.Parallelism = 4
+	options.BuildNumbers = NewFileBuildNumberStore(filepath.Join(dir, "build-numbers.json"))
+	options.SnapshotPattern = "{semver}-{buildNumber}+{sha}"
+
+	summary, err := s.runTarget(m, buildTarget, options)
+	check(t, err)
+
+	assert.Len(t, summary.Completed, 4)
+	for _, r := range summary.Completed {
+		assert.Equal(t, "1.0.0-1+4b9a0d4", r.Module.SnapshotVersion())
+	}
+
+	// Snapshot versions are issued on copies of the modules in the manifest.
+	for _, mod := range m.Modules {
+		assert.Equal(t, "", mod.SnapshotVersion())
+	}
+}
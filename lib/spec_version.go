@@ -0,0 +1,143 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	yaml "github.com/go-yaml/yaml"
+	"github.com/mbtproject/mbt/e"
+)
+
+// CurrentSpecVersion is the latest version of the spec format.
+// Specs without a version are in version 0 which is compatible with
+// version 1.
+const CurrentSpecVersion = 1
+
+// specMigrations rewrite the content of a spec in the version they
+// are keyed by to the next version. Migrations edit the content
+// as text to preserve comments and formatting.
+var specMigrations = map[int]func([]byte) []byte{
+	0: func(content []byte) []byte {
+		return append([]byte("version: 1\n"), content...)
+	},
+}
+
+// SpecMigration describes a spec rewritten to the current version.
+type SpecMigration struct {
+	// Path of the spec relative to the repository root.
+	Path string
+	From int
+	To   int
+}
+
+func validateSpecVersion(s *Spec) error {
+	if s.Version < 0 || s.Version > CurrentSpecVersion {
+		return e.NewErrorf(ErrClassUser, msgUnsupportedSpecVersion, s.Version, s.Name, CurrentSpecVersion)
+	}
+	return nil
+}
+
+// MigrateSpec rewrites the content of a spec to the current version.
+// Returns the version of the original content.
+func MigrateSpec(content []byte) ([]byte, int, error) {
+	var v struct {
+		Version int `yaml:"version"`
+	}
+	if err := yaml.Unmarshal(content, &v); err != nil {
+		return nil, 0, e.Wrap(ErrClassUser, err)
+	}
+
+	from := v.Version
+	if err := validateSpecVersion(&Spec{Version: from}); err != nil {
+		return nil, 0, err
+	}
+
+	for ; v.Version < CurrentSpecVersion; v.Version++ {
+		content = specMigrations[v.Version](content)
+	}
+
+	return content, from, nil
+}
+
+// MigrateSpecs rewrites the specs in the workspace of the repository
+// at dir to the current version. Directories in DefaultExcludedDirs
+// are skipped. Specs are not modified when dryRun is true.
+func MigrateSpecs(dir, specFile string, dryRun bool) ([]*SpecMigration, error) {
+	if specFile == "" {
+		specFile = DefaultSpecFile
+	}
+
+	excluded := map[string]bool{".git": true}
+	for _, d := range DefaultExcludedDirs {
+		excluded[d] = true
+	}
+
+	migrations := make([]*SpecMigration, 0)
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() {
+			if excluded[info.Name()] {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if info.Name() != specFile {
+			return nil
+		}
+
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return e.Wrapf(ErrClassUser, err, msgFailedReadFile, path)
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return e.Wrap(ErrClassInternal, err)
+		}
+		rel = filepath.ToSlash(rel)
+
+		migrated, from, err := MigrateSpec(content)
+		if err != nil {
+			return e.Wrapf(ErrClassUser, err, "error whilst migrating spec at %s", rel)
+		}
+
+		if from == CurrentSpecVersion {
+			return nil
+		}
+
+		if !dryRun {
+			if err := ioutil.WriteFile(path, migrated, info.Mode()); err != nil {
+				return e.Wrapf(ErrClassUser, err, msgFailedWriteFile, path)
+			}
+		}
+
+		migrations = append(migrations, &SpecMigration{Path: rel, From: from, To: CurrentSpecVersion})
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return migrations, nil
+}
@@ -0,0 +1,79 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSpecVersion(t *testing.T) {
+	s, err := newSpec([]byte("version: 1\nname: app-a\n"))
+	check(t, err)
+	assert.Equal(t, 1, s.Version)
+
+	s, err = newSpec([]byte("name: app-a\n"))
+	check(t, err)
+	assert.Equal(t, 0, s.Version)
+
+	_, err = newSpec([]byte("version: 2\nname: app-a\n"))
+	assert.EqualError(t, err, "Spec version 2 of module 'app-a' is not supported (this version of mbt supports versions up to 1, upgrade mbt to use newer specs)")
+}
+
+func TestMigrateSpec(t *testing.T) {
+	content, from, err := MigrateSpec([]byte("# app\nname: app-a\n"))
+	check(t, err)
+	assert.Equal(t, 0, from)
+	assert.Equal(t, "version: 1\n# app\nname: app-a\n", string(content))
+
+	s, err := newSpec(content)
+	check(t, err)
+	assert.Equal(t, CurrentSpecVersion, s.Version)
+
+	content, from, err = MigrateSpec(content)
+	check(t, err)
+	assert.Equal(t, 1, from)
+	assert.Equal(t, "version: 1\n# app\nname: app-a\n", string(content))
+}
+
+func TestMigrateSpecs(t *testing.T) {
+	clean()
+	writeAuditFile(t, ".tmp/repo/app-a/.mbt.yml", "name: app-a\n")
+	writeAuditFile(t, ".tmp/repo/app-b/.mbt.yml", "version: 1\nname: app-b\n")
+	writeAuditFile(t, ".tmp/repo/app-c/vendor/lib/.mbt.yml", "name: lib\n")
+
+	migrations, err := MigrateSpecs(".tmp/repo", "", true)
+	check(t, err)
+	assert.Equal(t, []*SpecMigration{{Path: "app-a/.mbt.yml", From: 0, To: 1}}, migrations)
+
+	content, err := ioutil.ReadFile(".tmp/repo/app-a/.mbt.yml")
+	check(t, err)
+	assert.Equal(t, "name: app-a\n", string(content))
+
+	migrations, err = MigrateSpecs(".tmp/repo", "", false)
+	check(t, err)
+	assert.Len(t, migrations, 1)
+
+	content, err = ioutil.ReadFile(".tmp/repo/app-a/.mbt.yml")
+	check(t, err)
+	assert.Equal(t, "version: 1\nname: app-a\n", string(content))
+
+	migrations, err = MigrateSpecs(".tmp/repo", "", false)
+	check(t, err)
+	assert.Empty(t, migrations)
+}
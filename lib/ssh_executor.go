@@ -0,0 +1,228 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mbtproject/mbt/e"
+	"github.com/mbtproject/mbt/utils"
+)
+
+// sshHostLabelProperty is the module property used to select
+// the remote host for a module.
+const sshHostLabelProperty = "executorLabel"
+
+// sshWorktreesDir is the directory, relative to the repository in
+// remote hosts, containing the worktrees of the builds.
+const sshWorktreesDir = ".git/mbt-worktrees"
+
+// sshStaleWorktreeMinutes is the age of the worktrees removed when
+// a new worktree is created in a remote host.
+const sshStaleWorktreeMinutes = 24 * 60
+
+type sshExecutor struct {
+	Log     Log
+	Options *SSHOptions
+	// id distinguishes the worktrees of this executor from the ones
+	// of other builds sharing the remote hosts.
+	id        string
+	mutex     sync.Mutex
+	worktrees map[string]*sshWorktree
+}
+
+// sshWorktree is a worktree of the commit being built in a remote host.
+type sshWorktree struct {
+	once sync.Once
+	dir  string
+	err  error
+}
+
+// sshState is the state of a command between the stages of execution.
+type sshState struct {
+	host string
+	dir  string
+}
+
+// NewSSHExecutor creates an Executor that runs the commands in
+// remote hosts over ssh.
+// Commit being built is checked out once in each remote host into
+// a worktree of the repository used only by this executor. Therefore,
+// concurrent builds sharing the remote hosts do not interfere with
+// each other.
+func NewSSHExecutor(log Log, options *SSHOptions) Executor {
+	return &sshExecutor{
+		Log:       log,
+		Options:   options,
+		id:        strconv.FormatInt(time.Now().UnixNano(), 36),
+		worktrees: make(map[string]*sshWorktree),
+	}
+}
+
+func (x *sshExecutor) Prepare(ctx *ExecContext) error {
+	if ctx.Manifest.Sha == "" || ctx.Manifest.Sha == "local" {
+		return e.NewError(ErrClassUser, msgSSHWorkspaceBuild)
+	}
+
+	if err := RequireNetwork("ssh executor"); err != nil {
+		return err
+	}
+
+	host, err := x.selectHost(ctx.Module)
+	if err != nil {
+		return err
+	}
+
+	dir, err := x.worktree(host, ctx.Manifest.Sha)
+	if err != nil {
+		return err
+	}
+
+	ctx.State = &sshState{host: host, dir: dir}
+	return nil
+}
+
+func (x *sshExecutor) Run(ctx *ExecContext) error {
+	state := ctx.State.(*sshState)
+	x.Log.Debug("Executing %s in module %s on host %s", ctx.Command, ctx.Module.Name(), state.host)
+
+	cmd := x.command(state.host, x.remoteScript(ctx, state.dir))
+	cmd.Stdin = ctx.Options.Stdin
+	cmd.Stdout = ctx.Options.Stdout
+	cmd.Stderr = ctx.Options.Stderr
+	return runProcess(cmd, ctx.Options.Timeout)
+}
+
+func (x *sshExecutor) Collect(ctx *ExecContext, runErr error) error {
+	return nil
+}
+
+// command returns the command executing script in host over ssh.
+func (x *sshExecutor) command(host, script string) *exec.Cmd {
+	sshArgs := make([]string, 0)
+	if x.Options.Port != 0 {
+		sshArgs = append(sshArgs, "-p", fmt.Sprintf("%d", x.Options.Port))
+	}
+	if x.Options.Identity != "" {
+		sshArgs = append(sshArgs, "-i", x.Options.Identity)
+	}
+	sshArgs = append(sshArgs, host, script)
+
+	ssh := x.Options.SSH
+	if ssh == "" {
+		ssh = "ssh"
+	}
+
+	return exec.Command(ssh, sshArgs...)
+}
+
+func (x *sshExecutor) selectHost(module *Module) (string, error) {
+	label := module.Resources().Label
+	if label == "" {
+		label = module.GetString(sshHostLabelProperty, "")
+	}
+
+	if label != "" {
+		host, ok := x.Options.Hosts[label]
+		if !ok {
+			return "", e.NewErrorf(ErrClassUser, msgSSHHostNotFound, label, module.Name())
+		}
+		return host, nil
+	}
+
+	if x.Options.DefaultHost == "" {
+		return "", e.NewErrorf(ErrClassUser, msgSSHHostNotFound, "default", module.Name())
+	}
+
+	return x.Options.DefaultHost, nil
+}
+
+// worktree returns the path to the worktree of commit sha in host.
+// Worktree is created when it is requested for the first time.
+// Concurrent requests for the same worktree wait until it is created.
+func (x *sshExecutor) worktree(host, sha string) (string, error) {
+	x.mutex.Lock()
+	key := host + " " + sha
+	w, ok := x.worktrees[key]
+	if !ok {
+		w = &sshWorktree{dir: path.Join(x.Options.RepoDir, sshWorktreesDir, sha+"-"+x.id)}
+		x.worktrees[key] = w
+	}
+	x.mutex.Unlock()
+
+	w.once.Do(func() {
+		x.Log.Debug("Checking out %s into %s on host %s", sha, w.dir, host)
+		out := new(bytes.Buffer)
+		cmd := x.command(host, x.worktreeScript(sha, w.dir))
+		cmd.Stdout = out
+		cmd.Stderr = out
+		if err := cmd.Run(); err != nil {
+			w.err = e.Wrapf(ErrClassUser, err, msgSSHWorktreeFailed, sha, host, strings.TrimSpace(out.String()))
+		}
+	})
+
+	return w.dir, w.err
+}
+
+// worktreeScript builds the shell script creating the worktree of
+// commit sha in dir. Script fetches the commit from the remote and
+// removes the worktrees left behind by the builds of previous days.
+func (x *sshExecutor) worktreeScript(sha, dir string) string {
+	remote := x.Options.Remote
+	if remote == "" {
+		remote = "origin"
+	}
+
+	root := path.Join(x.Options.RepoDir, sshWorktreesDir)
+	return strings.Join([]string{
+		fmt.Sprintf("cd %s", utils.ShellQuote(x.Options.RepoDir)),
+		fmt.Sprintf("git fetch --quiet %s", utils.ShellQuote(remote)),
+		fmt.Sprintf("mkdir -p %s", utils.ShellQuote(root)),
+		fmt.Sprintf("find %s -mindepth 1 -maxdepth 1 -type d -mmin +%d -exec rm -rf {} +", utils.ShellQuote(root), sshStaleWorktreeMinutes),
+		"git worktree prune",
+		fmt.Sprintf("git worktree add --quiet --detach %s %s", utils.ShellQuote(dir), utils.ShellQuote(sha)),
+	}, " && ")
+}
+
+// remoteScript builds the shell script executed in the remote host.
+// Script runs the command in module directory of the worktree in
+// repoDir with mbt environment.
+func (x *sshExecutor) remoteScript(ctx *ExecContext, repoDir string) string {
+	steps := []string{fmt.Sprintf("cd %s", utils.ShellQuote(repoDir))}
+	if dir := ctx.Dir(); dir != "" {
+		steps = append(steps, fmt.Sprintf("cd %s", utils.ShellQuote(dir)))
+	}
+
+	cmd := []string{"env"}
+	for _, v := range ctx.Environment(repoDir) {
+		cmd = append(cmd, utils.ShellQuote(v))
+	}
+
+	cmd = append(cmd, utils.ShellQuote(ctx.Command))
+	for _, a := range ctx.Args {
+		cmd = append(cmd, utils.ShellQuote(a))
+	}
+
+	steps = append(steps, strings.Join(cmd, " "))
+	return strings.Join(steps, " && ")
+}
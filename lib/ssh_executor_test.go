@@ -0,0 +1,145 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/mbtproject/mbt/e"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSSHHostSelection(t *testing.T) {
+	x := &sshExecutor{Options: &SSHOptions{
+		Hosts:       map[string]string{"arm64": "ci@arm"},
+		DefaultHost: "ci@default",
+	}}
+
+	a := newTestModule("app-a", "app-a", "a")
+	a.metadata.spec.Properties = map[string]interface{}{"executorLabel": "arm64"}
+	b := newTestModule("app-b", "app-b", "b")
+	c := newTestModule("app-c", "app-c", "c")
+	c.metadata.spec.Properties = map[string]interface{}{"executorLabel": "darwin"}
+
+	host, err := x.selectHost(a)
+	check(t, err)
+	assert.Equal(t, "ci@arm", host)
+
+	host, err = x.selectHost(b)
+	check(t, err)
+	assert.Equal(t, "ci@default", host)
+
+	_, err = x.selectHost(c)
+	assert.EqualError(t, err, "Failed to find a host labelled 'darwin' for module 'app-c'")
+}
+
+func TestSSHRemoteScript(t *testing.T) {
+	x := &sshExecutor{Options: &SSHOptions{RepoDir: "/src/repo"}}
+	mod := newTestModule("app-a", "app-a", "v1")
+
+	script := x.remoteScript(&ExecContext{Manifest: &Manifest{Dir: "/local/repo", Sha: "abc"}, Module: mod, Command: "./build.sh", Args: []string{"a b"}}, "/src/repo/.git/mbt-worktrees/abc-1")
+
+	assert.Equal(t, "cd /src/repo/.git/mbt-worktrees/abc-1 && cd app-a && "+
+		"env MBT_BUILD_COMMIT=abc MBT_MODULE_VERSION=v1 MBT_MODULE_NAME=app-a MBT_MODULE_PATH=app-a MBT_MODULE_GROUP=app-a MBT_REPO_PATH=/src/repo/.git/mbt-worktrees/abc-1 ./build.sh 'a b'", script)
+}
+
+func TestSSHWorktreeScript(t *testing.T) {
+	x := &sshExecutor{Options: &SSHOptions{RepoDir: "/src/repo"}}
+
+	script := x.worktreeScript("abc", "/src/repo/.git/mbt-worktrees/abc-1")
+
+	assert.Equal(t, "cd /src/repo && git fetch --quiet origin && mkdir -p /src/repo/.git/mbt-worktrees && "+
+		"find /src/repo/.git/mbt-worktrees -mindepth 1 -maxdepth 1 -type d -mmin +1440 -exec rm -rf {} + && "+
+		"git worktree prune && git worktree add --quiet --detach /src/repo/.git/mbt-worktrees/abc-1 abc", script)
+}
+
+func TestSSHWorktreeIsCreatedOncePerHost(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.SkipNow()
+	}
+
+	clean()
+	log, err := filepath.Abs(".tmp/ssh.log")
+	check(t, err)
+	writeManifestScript(t, ".tmp/ssh", fmt.Sprintf("echo \"$1\" >> %s", log))
+
+	x := NewSSHExecutor(NewStdLog(LogLevelNormal), &SSHOptions{
+		SSH:         ".tmp/ssh",
+		Hosts:       map[string]string{"arm64": "ci@arm"},
+		DefaultHost: "ci@default",
+		RepoDir:     "/src/repo",
+	}).(*sshExecutor)
+
+	a := newTestModule("app-a", "app-a", "a")
+	a.metadata.spec.Properties = map[string]interface{}{"executorLabel": "arm64"}
+	b := newTestModule("app-b", "app-b", "b")
+	c := newTestModule("app-c", "app-c", "c")
+	m := &Manifest{Sha: "abc", Modules: Modules{a, b, c}}
+
+	var wg sync.WaitGroup
+	dirs := make([]string, 6)
+	for i := range dirs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			ctx := &ExecContext{Manifest: m, Module: m.Modules[i%3]}
+			check(t, x.Prepare(ctx))
+			dirs[i] = ctx.State.(*sshState).dir
+		}(i)
+	}
+	wg.Wait()
+
+	for _, d := range dirs {
+		assert.Equal(t, "/src/repo/.git/mbt-worktrees/abc-"+x.id, d)
+	}
+
+	out, err := ioutil.ReadFile(log)
+	check(t, err)
+	hosts := strings.Split(strings.TrimSpace(string(out)), "\n")
+	sort.Strings(hosts)
+	assert.Equal(t, []string{"ci@arm", "ci@default"}, hosts)
+}
+
+func TestSSHRejectsWorkspaceBuilds(t *testing.T) {
+	x := NewSSHExecutor(NewStdLog(LogLevelNormal), &SSHOptions{DefaultHost: "ci@default", RepoDir: "/src/repo"})
+	mod := newTestModule("app-a", "app-a", "local")
+
+	err := x.Prepare(&ExecContext{Manifest: &Manifest{Sha: "local", Modules: Modules{mod}}, Module: mod})
+
+	assert.EqualError(t, err, msgSSHWorkspaceBuild)
+	assert.Equal(t, ErrClassUser, (err.(*e.E)).Class())
+}
+
+func TestSSHHostSelectionByResourceLabel(t *testing.T) {
+	x := &sshExecutor{Options: &SSHOptions{
+		Hosts: map[string]string{"arm64": "ci@arm", "gpu": "ci@gpu"},
+	}}
+
+	a := newTestModule("app-a", "app-a", "a")
+	a.metadata.spec.Resources = &Resources{Label: "gpu"}
+	a.metadata.spec.Properties = map[string]interface{}{"executorLabel": "arm64"}
+
+	host, err := x.selectHost(a)
+	check(t, err)
+	assert.Equal(t, "ci@gpu", host)
+}
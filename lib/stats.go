@@ -0,0 +1,237 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"fmt"
+	"sort"
+)
+
+// StatsOptions describes how statistics are computed.
+type StatsOptions struct {
+	// HotPaths is the number of most frequently changed paths
+	// reported. Defaults to 10.
+	HotPaths int
+}
+
+// Stats describes how modules change over a range of commits.
+type Stats struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	// Commits is the number of commits in the range.
+	Commits int `json:"commits"`
+	// Modules is the number of modules in 'to' commit.
+	Modules int `json:"modules"`
+	// Weeks lists the number of modules changed in each week.
+	Weeks []*WeeklyChanges `json:"weeks"`
+	// AverageCommitManifestSize is the average number of modules
+	// impacted by non-merge commits.
+	AverageCommitManifestSize float64 `json:"averageCommitManifestSize"`
+	// AverageMergeManifestSize is the average number of modules
+	// impacted by merge commits (e.g. merged pull requests) compared
+	// to their first parent.
+	AverageMergeManifestSize float64 `json:"averageMergeManifestSize"`
+	// HotPaths are the most frequently changed files.
+	HotPaths []*PathChanges `json:"hotPaths"`
+	// Dependencies lists fan-in and fan-out of modules in 'to'
+	// commit sorted by fan-in.
+	Dependencies []*ModuleDependencies `json:"dependencies"`
+}
+
+// WeeklyChanges is the number of commits and modules changed in an
+// ISO week (e.g. 2020-W05).
+type WeeklyChanges struct {
+	Week    string `json:"week"`
+	Commits int    `json:"commits"`
+	Modules int    `json:"modules"`
+}
+
+// PathChanges is the number of commits changing a path.
+type PathChanges struct {
+	Path    string `json:"path"`
+	Changes int    `json:"changes"`
+}
+
+// ModuleDependencies describes the coupling of a module.
+type ModuleDependencies struct {
+	Module string `json:"module"`
+	// FanIn is the number of modules depending on the module.
+	FanIn int `json:"fanIn"`
+	// FanOut is the number of modules the module depends on.
+	FanOut int `json:"fanOut"`
+}
+
+func (s *stdSystem) Stats(from, to string, options *StatsOptions) (*Stats, error) {
+	if options == nil {
+		options = &StatsOptions{}
+	}
+	hotPaths := options.HotPaths
+	if hotPaths <= 0 {
+		hotPaths = 10
+	}
+
+	toCommit, commits, err := s.history(from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &Stats{From: from, To: toCommit.ID(), Commits: len(commits)}
+	weeks := make(map[string]*WeeklyChanges)
+	weeklyModules := make(map[string]map[string]bool)
+	paths := make(map[string]int)
+	var commitSizes, merges, mergeSizes int
+
+	for _, c := range commits {
+		info, err := s.Repo.CommitInfo(c)
+		if err != nil {
+			return nil, err
+		}
+
+		var m *Manifest
+		if len(info.Parents) > 1 {
+			parent, err := s.Repo.GetCommit(info.Parents[0])
+			if err != nil {
+				return nil, err
+			}
+			m, err = s.MB.ByDiff(parent, c)
+			if err != nil {
+				return nil, err
+			}
+			merges++
+			mergeSizes += len(m.Modules)
+		} else {
+			m, err = s.MB.ByCommitContent(c)
+			if err != nil {
+				return nil, err
+			}
+			commitSizes += len(m.Modules)
+
+			// Merge commits are excluded from hot paths because
+			// their changes are already counted in merged commits.
+			changes, err := s.Repo.Changes(c)
+			if err != nil {
+				return nil, err
+			}
+			for _, d := range changes {
+				p := d.NewFile
+				if p == "" {
+					p = d.OldFile
+				}
+				paths[p]++
+			}
+		}
+
+		year, week := info.Time.ISOWeek()
+		key := fmt.Sprintf("%04d-W%02d", year, week)
+		w, ok := weeks[key]
+		if !ok {
+			w = &WeeklyChanges{Week: key}
+			weeks[key] = w
+			weeklyModules[key] = make(map[string]bool)
+		}
+		w.Commits++
+		for _, mod := range m.Modules {
+			weeklyModules[key][mod.Name()] = true
+		}
+		w.Modules = len(weeklyModules[key])
+	}
+
+	if n := len(commits) - merges; n > 0 {
+		stats.AverageCommitManifestSize = float64(commitSizes) / float64(n)
+	}
+	if merges > 0 {
+		stats.AverageMergeManifestSize = float64(mergeSizes) / float64(merges)
+	}
+
+	stats.Weeks = make([]*WeeklyChanges, 0, len(weeks))
+	for _, w := range weeks {
+		stats.Weeks = append(stats.Weeks, w)
+	}
+	sort.Slice(stats.Weeks, func(i, j int) bool {
+		return stats.Weeks[i].Week < stats.Weeks[j].Week
+	})
+
+	stats.HotPaths = make([]*PathChanges, 0, len(paths))
+	for p, n := range paths {
+		stats.HotPaths = append(stats.HotPaths, &PathChanges{Path: p, Changes: n})
+	}
+	sort.Slice(stats.HotPaths, func(i, j int) bool {
+		a, b := stats.HotPaths[i], stats.HotPaths[j]
+		return a.Changes > b.Changes || (a.Changes == b.Changes && a.Path < b.Path)
+	})
+	if len(stats.HotPaths) > hotPaths {
+		stats.HotPaths = stats.HotPaths[:hotPaths]
+	}
+
+	m, err := s.MB.ByCommit(toCommit)
+	if err != nil {
+		return nil, err
+	}
+	stats.Modules = len(m.Modules)
+	stats.Dependencies = moduleDependencies(m.Modules)
+
+	return stats, nil
+}
+
+// history returns the commits reachable from 'to' but not from 'from'.
+// 'to' defaults to the head of current branch and the entire
+// history is returned if 'from' is not specified.
+func (s *stdSystem) history(from, to string) (Commit, []Commit, error) {
+	var toCommit, fromCommit Commit
+	var err error
+	if to == "" {
+		toCommit, err = s.Repo.CurrentBranchCommit()
+	} else {
+		toCommit, err = s.Repo.GetCommit(to)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if from != "" {
+		fromCommit, err = s.Repo.GetCommit(from)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	commits, err := s.Repo.History(fromCommit, toCommit)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return toCommit, commits, nil
+}
+
+// moduleDependencies returns the fan-in and fan-out of modules
+// sorted by fan-in and then by fan-out.
+func moduleDependencies(mods Modules) []*ModuleDependencies {
+	deps := make([]*ModuleDependencies, 0, len(mods))
+	for _, m := range mods {
+		deps = append(deps, &ModuleDependencies{Module: m.Name(), FanIn: len(m.RequiredBy()), FanOut: len(m.Requires())})
+	}
+	sort.SliceStable(deps, func(i, j int) bool {
+		a, b := deps[i], deps[j]
+		if a.FanIn != b.FanIn {
+			return a.FanIn > b.FanIn
+		}
+		if a.FanOut != b.FanOut {
+			return a.FanOut > b.FanOut
+		}
+		return a.Module < b.Module
+	})
+	return deps
+}
@@ -0,0 +1,73 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestModuleDependencies(t *testing.T) {
+	c := newTestModule("lib-c", "lib-c", "c1")
+	b := newModule(newModuleMetadata("lib-b", "b", &Spec{Name: "lib-b"}, nil), Modules{c})
+	a := newModule(newModuleMetadata("app-a", "a", &Spec{Name: "app-a"}, nil), Modules{b, c})
+
+	assert.Equal(t, []*ModuleDependencies{
+		{Module: "lib-c", FanIn: 2, FanOut: 0},
+		{Module: "lib-b", FanIn: 1, FanOut: 1},
+		{Module: "app-a", FanIn: 0, FanOut: 2},
+	}, moduleDependencies(Modules{a, b, c}))
+}
+
+func TestStats(t *testing.T) {
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+
+	check(t, repo.InitModule("lib-b"))
+	check(t, repo.InitModuleWithOptions("app-a", &Spec{Name: "app-a", Dependencies: []string{"lib-b"}}))
+	check(t, repo.Commit("first"))
+	first := repo.LastCommit.String()
+
+	check(t, repo.SwitchToBranch("feature"))
+	check(t, repo.WriteContent("lib-b/foo", "hello"))
+	check(t, repo.Commit("second"))
+	check(t, repo.WriteContent("lib-b/foo", "hello world"))
+	check(t, repo.Commit("third"))
+
+	check(t, repo.SwitchToBranch("master"))
+	check(t, repo.WriteContent("app-a/bar", "hello"))
+	check(t, repo.Commit("fourth"))
+
+	merge, err := repo.SimpleMerge("feature", "master")
+	check(t, err)
+
+	stats, err := NewWorld(t, ".tmp/repo").System.Stats(first, merge.String(), &StatsOptions{HotPaths: 1})
+	check(t, err)
+
+	assert.Equal(t, 4, stats.Commits)
+	assert.Equal(t, 2, stats.Modules)
+	// Changes to lib-b impact app-a as well.
+	assert.Equal(t, float64(5)/3, stats.AverageCommitManifestSize)
+	assert.Equal(t, float64(2), stats.AverageMergeManifestSize)
+	assert.Equal(t, []*PathChanges{{Path: "lib-b/foo", Changes: 2}}, stats.HotPaths)
+	assert.Len(t, stats.Weeks, 1)
+	assert.Equal(t, 2, stats.Weeks[0].Modules)
+	assert.Equal(t, []*ModuleDependencies{
+		{Module: "lib-b", FanIn: 1, FanOut: 0},
+		{Module: "app-a", FanIn: 0, FanOut: 1},
+	}, stats.Dependencies)
+}
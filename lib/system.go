@@ -18,6 +18,7 @@ package lib
 import (
 	"io"
 	"os"
+	"time"
 )
 
 // This file defines the interfaces and types that make up MBT system.
@@ -78,8 +79,9 @@ type Repo interface {
 	// In other words, diff contains the deltas of changes occurred in 'to' commit tree
 	// since it diverged from 'from' commit tree.
 	DiffMergeBase(from, to Commit) ([]*DiffDelta, error)
-	// DiffWorkspace gets the changes in current workspace.
-	// This should include untracked changes.
+	// DiffWorkspace gets the changes in current workspace relative to HEAD.
+	// This should include staged, intent-to-add and untracked changes
+	// while skipping the files ignored by .gitignore.
 	DiffWorkspace() ([]*DiffDelta, error)
 	// Changes returns a an array of DiffDelta objects representing the changes
 	// in the specified commit.
@@ -117,6 +119,50 @@ type Repo interface {
 	CheckoutReference(Reference) error
 	// MergeBase returns the merge base of two commits.
 	MergeBase(a, b Commit) (Commit, error)
+	// Note returns the note attached to commit in notes reference ref.
+	// Returns an empty string if commit does not have a note.
+	Note(commit Commit, ref string) (string, error)
+	// SetNote attaches a note to commit in notes reference ref
+	// replacing the existing note.
+	SetNote(commit Commit, ref, note string) error
+	// History returns the commits reachable from to but not from
+	// from, newest first. All commits reachable from to are returned
+	// if from is nil.
+	History(from, to Commit) ([]Commit, error)
+	// CommitInfo returns the metadata of a commit.
+	CommitInfo(commit Commit) (*CommitInfo, error)
+	// ResolveRef returns the commit pointed by a branch, tag or sha.
+	ResolveRef(ref string) (Commit, error)
+	// State returns the properties of the clone affecting how
+	// commands operate (e.g. shallow or sparse clones).
+	State() (*RepoState, error)
+}
+
+// RepoState describes the properties of a clone.
+type RepoState struct {
+	// Shallow is true for clones with truncated history
+	// (e.g. git clone --depth 1).
+	Shallow bool
+	// Sparse is true when sparse checkout is enabled.
+	Sparse bool
+	// Partial is true for partial clones
+	// (e.g. git clone --filter=blob:none).
+	Partial bool
+	// Detached is true when HEAD does not point to a branch.
+	Detached bool
+}
+
+// CommitInfo describes a commit.
+type CommitInfo struct {
+	Sha         string
+	Author      string
+	AuthorEmail string
+	// Time is the committer timestamp.
+	Time time.Time
+	// Subject is the first line of the commit message.
+	Subject string
+	// Parents are the shas of the parent commits.
+	Parents []string
 }
 
 /** Module Discovery **/
@@ -125,31 +171,147 @@ type Repo interface {
 type Cmd struct {
 	Cmd  string
 	Args []string `yaml:",flow"`
+	// Timeout is the maximum duration of the command (e.g. 10m).
+	Timeout string `yaml:"timeout,omitempty"`
+	// Retries is the number of times the command is retried on failure.
+	Retries int `yaml:"retries,omitempty"`
+	// When is an expression that must evaluate to true for the
+	// command to be executed.
+	When string `yaml:"when,omitempty"`
+	// WorkDir is the directory the command is executed in, relative
+	// to the module directory or to the repository root when it starts
+	// with /. Defaults to the module directory.
+	WorkDir string `yaml:"workDir,omitempty"`
 }
 
 // UserCmd represents the structure of a user defined command in .mbt.yml
 type UserCmd struct {
+	Cmd     string
+	Args    []string `yaml:",flow"`
+	OS      []string `yaml:"os"`
+	Timeout string   `yaml:"timeout,omitempty"`
+	Retries int      `yaml:"retries,omitempty"`
+	When    string   `yaml:"when,omitempty"`
+}
+
+// Task represents the structure of a named task in .mbt.yml
+// (e.g. lint, deploy).
+type Task struct {
 	Cmd  string
 	Args []string `yaml:",flow"`
-	OS   []string `yaml:"os"`
+	// OS is the list of platforms the task is applicable to.
+	// Task is applicable to all platforms if not specified.
+	OS      []string `yaml:"os"`
+	Timeout string   `yaml:"timeout,omitempty"`
+	Retries int      `yaml:"retries,omitempty"`
+	When    string   `yaml:"when,omitempty"`
+	// DependsOn is the list of tasks of the module executed
+	// before this task.
+	DependsOn []string `yaml:"dependsOn"`
+	// Outputs are the paths of the files produced by the task
+	// relative to the module directory.
+	Outputs []string `yaml:"outputs"`
+	// Cache skips the task if its outputs for the module version
+	// are available in the cache.
+	Cache bool `yaml:"cache"`
+	// WorkDir is the directory the task is executed in. See Cmd.WorkDir.
+	WorkDir string `yaml:"workDir,omitempty"`
+}
+
+// Hooks represents the commands executed around the build command
+// of a module.
+type Hooks struct {
+	// Pre is executed before the build command.
+	// Module is not built if it fails.
+	Pre *Cmd `yaml:"pre"`
+	// Post is executed after a successful build.
+	Post *Cmd `yaml:"post"`
+	// OnFailure is executed when the build or one of the other
+	// hooks fails.
+	OnFailure *Cmd `yaml:"onFailure"`
+}
+
+// Resources describes the resources required to build a module.
+type Resources struct {
+	// Weight is the share of the build capacity consumed by the
+	// build of the module. Defaults to 1.
+	Weight int `yaml:"weight"`
+	// CPU requested by the build (e.g. 500m). Used by executors
+	// that schedule builds in remote environments.
+	CPU string `yaml:"cpu"`
+	// Memory requested by the build (e.g. 1Gi). Used by executors
+	// that schedule builds in remote environments.
+	Memory string `yaml:"memory"`
+	// Label routes the build to an agent with a matching label.
+	Label string `yaml:"label"`
 }
 
 // Spec represents the structure of .mbt.yml contents.
 type Spec struct {
+	// Version of the spec format. See CurrentSpecVersion.
+	Version          int                    `yaml:"version"`
 	Name             string                 `yaml:"name"`
 	Build            map[string]*Cmd        `yaml:"build"`
+	Test             map[string]*Cmd        `yaml:"test"`
+	Hooks            *Hooks                 `yaml:"hooks"`
+	Matrix           map[string][]string    `yaml:"matrix"`
+	Resources        *Resources             `yaml:"resources"`
+	Outputs          []string               `yaml:"outputs"`
+	Tasks            map[string]*Task       `yaml:"tasks"`
+	SBOM             *Cmd                   `yaml:"sbom"`
 	Commands         map[string]*UserCmd    `yaml:"commands"`
 	Properties       map[string]interface{} `yaml:"properties"`
 	Dependencies     []string               `yaml:"dependencies"`
 	FileDependencies []string               `yaml:"fileDependencies"`
+	LFS              bool                   `yaml:"lfs"`
+	Group            string                 `yaml:"group"`
+	// ExternalDependencies are the paths in other repositories
+	// this module's build depend on.
+	ExternalDependencies []*ExternalDependency `yaml:"externalDependencies"`
+	// Migrations are the patterns of migration files in the module
+	// directory. DefaultMigrationPatterns are used when it is nil.
+	Migrations []string `yaml:"migrations"`
+	// Lifecycle is the state of the module (active, deprecated or
+	// frozen). See Module.Lifecycle.
+	Lifecycle string `yaml:"lifecycle"`
+	// Approvals are the groups required to approve the changes
+	// impacting this module. See Manifest.RequiredApprovals.
+	Approvals []string `yaml:"approvals"`
+	// Cost is the weight of building this module used to enforce
+	// build budgets. Defaults to 1. See ManifestBuilderOptions.
+	Cost int `yaml:"cost"`
+	// Semver is the semantic version of the module used in snapshot
+	// versions (e.g. 1.2.3). See Module.Semver.
+	Semver string `yaml:"semver"`
 }
 
 // Module represents a single module in the repository.
+// Module is immutable and safe for concurrent use (see Manifest).
 type Module struct {
 	metadata   *moduleMetadata
 	version    string
 	requires   Modules
 	requiredBy Modules
+	variant    *Variant
+	// changeClasses are the classes of the changed files of the module.
+	changeClasses []string
+	// reasons of selecting the module in a diff based manifest.
+	reasons []*SelectionReason
+	// trailers are the values of commit trailers in the commits
+	// changing the module in a diff based manifest.
+	trailers map[string][]string
+	// buildNumber issued for the module in a build.
+	buildNumber int
+	// snapshotVersion issued for the module in a build.
+	snapshotVersion string
+}
+
+// Variant is a combination of values in the build matrix of a module.
+type Variant struct {
+	// Name of the variant formed by joining its values with '-'
+	Name string
+	// Values of the variant keyed by the matrix axis
+	Values map[string]string
 }
 
 // Modules is an array of Module.
@@ -163,6 +325,10 @@ type Discover interface {
 	// ModulesInWorkspace walks current workspace looking for
 	// directories with .mbt.yml file. Returns discovered Modules.
 	ModulesInWorkspace() (Modules, error)
+	// AdvanceModules returns the modules in commit to given the
+	// modules discovered in commit from, reading only what changed
+	// where possible.
+	AdvanceModules(prev Modules, from, to Commit) (Modules, error)
 }
 
 // Reducer reduces a given modules set to impacted set from a diff delta
@@ -171,10 +337,42 @@ type Reducer interface {
 }
 
 // Manifest represents a collection modules in the repository.
+//
+// A Manifest and its modules are not modified by mbt once returned by a
+// ManifestBuilder. Methods of Manifest return new manifests instead of
+// changing it and accessors of Module return deep copies of the slices,
+// maps and structs they expose (e.g. Build, Tasks, Hooks and Properties).
+//
+// Immutability of Module is enforced, but the fields of Manifest are
+// exported for templates and serialisation. Callers must treat them as
+// read-only: neither assign to them nor modify the slices they hold
+// (Modules, ChangedFiles and Diagnostics) or the CommitInfo pointed by
+// Commit. Copy a field before changing it. Manifests honouring this
+// contract can be shared between goroutines (e.g. parallel builds).
 type Manifest struct {
 	Dir     string
 	Sha     string
 	Modules Modules
+	// Branch is the name of the branch manifest is created for.
+	// Empty unless manifest is created for a branch or a pull request.
+	Branch string
+	// ChangedFiles is the list of files changed in the diff used to
+	// create the manifest. Empty unless manifest is created for a diff.
+	ChangedFiles []string
+	// Base is the commit ChangedFiles are relative to (e.g. the merge
+	// base of a diff). It is HEAD for workspace changes and empty unless
+	// manifest is created for a diff.
+	Base string
+	// Commit describes the commit manifest is created for.
+	// Nil if manifest is created for the workspace.
+	Commit *CommitInfo
+	// Diagnostics are the non-fatal problems found while creating the
+	// manifest (e.g. nested modules or policy warnings).
+	Diagnostics []*Diagnostic
+	// discovered are all modules in the commit manifest is created
+	// for, which is used to advance the manifest. Nil unless manifest
+	// is created for a commit.
+	discovered Modules
 }
 
 // ManifestBuilder builds Manifest for various conditions
@@ -196,6 +394,10 @@ type ManifestBuilder interface {
 	ByWorkspace() (*Manifest, error)
 	// ByWorkspaceChanges creates the manifest for the changes in workspace
 	ByWorkspaceChanges() (*Manifest, error)
+	// Advance creates the manifest for the specified commit from a
+	// manifest created for another commit (e.g. its parent) by ByCommit,
+	// ByBranch, ByCurrentBranch or Advance.
+	Advance(prev *Manifest, to Commit) (*Manifest, error)
 }
 
 /** Workspace Management **/
@@ -219,6 +421,187 @@ type ProcessManager interface {
 	Exec(manifest *Manifest, module *Module, options *CmdOptions, command string, args ...string) error
 }
 
+// ExecContext contains the information about a command being
+// executed by an Executor.
+type ExecContext struct {
+	// Manifest the module belongs to.
+	Manifest *Manifest
+	// Module the command is executed for.
+	Module *Module
+	// Options of the command, including the io streams.
+	Options *CmdOptions
+	// Command to execute.
+	Command string
+	// Args of the command.
+	Args []string
+	// State is the executor specific information shared between
+	// Prepare, Run and Collect stages.
+	State interface{}
+}
+
+// Dir returns the directory, relative to the repository root, the
+// command is executed in.
+func (ctx *ExecContext) Dir() string {
+	if ctx.Options != nil && ctx.Options.WorkDir != "" {
+		return ctx.Options.WorkDir
+	}
+	return ctx.Module.Path()
+}
+
+// Executor runs commands on behalf of ProcessManager.
+// ProcessManager drives an Executor through three stages for each
+// command. Implement this interface to run commands in an environment
+// other than the ones supported out of the box.
+type Executor interface {
+	// Prepare sets up the environment required to run the command.
+	Prepare(ctx *ExecContext) error
+	// Run executes the command and streams its output to the
+	// io streams in ctx.Options.
+	Run(ctx *ExecContext) error
+	// Collect is invoked after Run regardless of its outcome
+	// with the error returned from Run. It should gather the results
+	// and release any resources acquired in Prepare.
+	Collect(ctx *ExecContext, runErr error) error
+}
+
+// Cache stores the outputs of module builds.
+// Entries are keyed by the module name, version and the host platform.
+// See NewDirCache, NewHTTPCache and NewTieredCache for the built-in caches.
+type Cache interface {
+	// Get returns the content stored for key.
+	// Returns nil if the key is not found.
+	Get(key string) (io.ReadCloser, error)
+	// Put stores the content read from r for key.
+	Put(key string, r io.Reader) error
+}
+
+// CollectableCache is a Cache whose entries can be listed and
+// removed. See CollectCache.
+type CollectableCache interface {
+	Cache
+	// Entries returns the entries stored in the cache.
+	Entries() ([]*CacheEntry, error)
+	// Delete removes the entry stored for key.
+	Delete(key string) error
+}
+
+// DeploymentStore stores the versions of modules deployed to
+// environments. See NewFileDeploymentStore for the built-in store.
+type DeploymentStore interface {
+	// Deployments returns the latest deployment of each module
+	// in env sorted by module name.
+	Deployments(env string) ([]*Deployment, error)
+	// Record stores deployments replacing the previous deployment
+	// of the same module in the same environment.
+	Record(deployments []*Deployment) error
+}
+
+// BuildMetadataStore stores the results of the builds of commits.
+// See NewGitNotesStore for the built-in store.
+type BuildMetadataStore interface {
+	// BuildMetadata returns the metadata recorded for the commit sha.
+	// Returns nil if there's no metadata for the commit.
+	BuildMetadata(sha string) (*BuildMetadata, error)
+	// Record stores metadata merging it with the metadata
+	// previously recorded for the same commit.
+	Record(metadata *BuildMetadata) error
+}
+
+// BuildNumberStore issues monotonically increasing build numbers for
+// modules. See NewFileBuildNumberStore for the built-in store.
+type BuildNumberStore interface {
+	// Current returns the last build number issued for the module.
+	// Returns zero if no number is issued yet.
+	Current(module string) (int, error)
+	// Next issues the next build number for the module starting
+	// from 1.
+	Next(module string) (int, error)
+}
+
+// Notifier sends the notifications of build results.
+// See NewSlackNotifier, NewWebhookNotifier and NewEmailNotifier for
+// the built-in notifiers.
+type Notifier interface {
+	Notify(n *Notification) error
+}
+
+// StatusPublisher publishes the statuses of builds on commits.
+// See NewStatusPublisher for the built-in publishers.
+type StatusPublisher interface {
+	Publish(sha string, status *CommitStatus) error
+}
+
+// Policy evaluates the modules in manifests against rules.
+// See NewExprPolicy and NewRegoPolicy for the built-in policies.
+type Policy interface {
+	// Evaluate returns the violations of the modules in m.
+	Evaluate(m *Manifest) ([]*Violation, error)
+}
+
+// KubernetesOptions describes how commands are scheduled as Kubernetes Jobs.
+type KubernetesOptions struct {
+	// Kubectl is the path to kubectl binary. Defaults to kubectl in PATH.
+	Kubectl string
+	// Context is the kubeconfig context to use. Current context is used if
+	// not specified.
+	Context string
+	// Namespace where the jobs are created.
+	Namespace string
+	// Image used to run the command.
+	Image string
+	// CPU and Memory are the resource requests/limits of the job container
+	// (e.g. 500m, 1Gi).
+	CPU, Memory string
+	// ServiceAccount used by the job pod.
+	ServiceAccount string
+	// RepoURL is cloned into the job workspace at the commit being built.
+	// When it is not specified, image is expected to contain the
+	// repository at WorkDir.
+	RepoURL string
+	// WorkDir is the path to repository inside the job container.
+	// Defaults to /workspace.
+	WorkDir string
+	// Timeout is the maximum amount of time to wait for a job to complete.
+	Timeout time.Duration
+	// KeepJobs prevents the deletion of jobs after they are completed.
+	KeepJobs bool
+}
+
+// DockerOptions describes how commands are executed in docker containers.
+type DockerOptions struct {
+	// Docker is the path to docker binary. Defaults to docker in PATH.
+	Docker string
+	// Image used to run the command.
+	Image string
+	// WorkDir is the path where repository is mounted inside the container.
+	// Defaults to /workspace.
+	WorkDir string
+	// Args are the additional arguments to docker run command.
+	Args []string
+}
+
+// SSHOptions describes how commands are executed in remote hosts over ssh.
+type SSHOptions struct {
+	// SSH is the path to ssh binary. Defaults to ssh in PATH.
+	SSH string
+	// Hosts is a map of labels to hosts (e.g. user@host).
+	// Host of a module is selected by matching the value of
+	// executorLabel property in its spec.
+	Hosts map[string]string
+	// DefaultHost is used for modules without an executorLabel property.
+	DefaultHost string
+	// Port of ssh server in remote hosts.
+	Port int
+	// Identity is the path to private key used for authentication.
+	Identity string
+	// RepoDir is the path to a clone of the repository in remote hosts.
+	// Commit being built is checked out into a worktree of this clone.
+	RepoDir string
+	// Remote is the name of the git remote fetched in remote hosts
+	// to obtain the commit being built. Defaults to origin.
+	Remote string
+}
+
 /** Build **/
 
 // CmdStage is an enum to indicate various stages of a command.
@@ -231,17 +614,37 @@ type BuildSummary struct {
 	// Completed list of the modules built. This list does not
 	// include the modules that were skipped due to
 	// the unavailability of a build command for the
-	// host platform.
+	// host platform. In a dry run, this is the list of modules
+	// that would be built.
 	Completed []*BuildResult
 	// Skipped modules due to the unavailability of a build command for
-	// the host platform
+	// the host platform or a failure in one of their dependencies.
 	Skipped []*Module
+	// Failures occurred while building modules. This list is populated
+	// only when KeepGoing option is used. Otherwise, build is aborted
+	// on the first failure.
+	Failures []*CmdFailure
 }
 
 // BuildResult is summary for a single module build
 type BuildResult struct {
-	// Module of the build result
+	// Module of the build result. When the module has a build
+	// matrix, there's a result for each variant.
 	Module *Module
+	// LogFile is the path to the file containing the build output.
+	// Empty unless LogDir option is specified.
+	LogFile string
+	// Duration of the build
+	Duration time.Duration
+	// Cached is true if the outputs of the module were restored
+	// from the cache instead of building it.
+	Cached bool
+	// SBOM is the path to the software bill of materials generated
+	// for the module. Empty unless SBOM option is specified.
+	SBOM string
+	// Reproducibility is the result of building the module twice.
+	// Nil unless Reproducible option is specified.
+	Reproducibility *Reproducibility
 }
 
 const (
@@ -256,6 +659,9 @@ const (
 
 	// CmdStageFailedBuild is when module command is failed
 	CmdStageFailedBuild
+
+	// CmdStageCachedBuild is when module outputs are restored from the cache
+	CmdStageCachedBuild
 )
 
 // CmdStageCallback is the callback function used to notify various build stages
@@ -268,6 +674,15 @@ type FilterOptions struct {
 	Name       string
 	Fuzzy      bool
 	Dependents bool
+	// Expr is an expression modules must satisfy.
+	// See Manifest.FilterExpr.
+	Expr string
+	// Owner selects the modules owned by the specified owner
+	// (e.g. @org/team) according to CODEOWNERS.
+	Owner string
+	// Group selects the modules in the specified group.
+	// See Module.Group.
+	Group string
 }
 
 // CmdOptions defines various options required by methods executing
@@ -277,12 +692,71 @@ type CmdOptions struct {
 	Stdout, Stderr io.Writer
 	Callback       CmdStageCallback
 	FailFast       bool
+	// Parallelism is the capacity available to build modules
+	// concurrently. Each module consumes capacity equal to its
+	// resource weight. Modules are built sequentially when this is
+	// less than 2.
+	Parallelism int
+	// DurationsFile is the path to the file used to persist the
+	// build durations of modules. When specified, parallel builds
+	// start the modules on the longest estimated path of dependent
+	// builds (critical path) first.
+	DurationsFile string
+	// KeepGoing continues building the remaining modules when a build
+	// fails. Modules depending on a failed module are skipped.
+	KeepGoing bool
+	// OutputMode specifies how the output of each module is written
+	// to Stdout and Stderr.
+	OutputMode OutputMode
+	// Color highlights the module name prefixes when OutputMode is
+	// OutputModePrefixed.
+	Color bool
+	// LogDir is the directory where the output of each module build
+	// is written in addition to Stdout and Stderr. Logs are stored
+	// in <LogDir>/<module name>/<module version>.log.
+	LogDir string
+	// Cache stores the outputs of module builds. When specified,
+	// outputs of a module are restored from the cache instead of
+	// building it if they are available for the module version.
+	Cache Cache
+	// DryRun writes the commands that would be executed along with
+	// their environment to Stdout without executing them.
+	DryRun bool
+	// Timeout is the maximum duration of a command.
+	// Zero means no timeout. Timeout specified in the spec of a
+	// command takes precedence.
+	Timeout time.Duration
+	// SBOM generates a software bill of materials for each module
+	// built when specified.
+	SBOM *SBOMOptions
+	// WorkDir is the directory, relative to the repository root, the
+	// command is executed in. Module directory is used when it is empty.
+	WorkDir string
+	// AllowEnv lists the environment variables expanded in commands
+	// and their arguments (e.g. ${JAVA_HOME} or ${GO_VERSION:-1.15}).
+	// Entries ending with * match the variables with that prefix.
+	AllowEnv []string
+	// BuildNumbers issues a build number for each module built
+	// when specified. See Module.BuildNumber.
+	BuildNumbers BuildNumberStore
+	// SnapshotPattern is the pattern of the snapshot version issued
+	// for each module built when specified (see
+	// Manifest.SnapshotVersion). Snapshot versions include the build
+	// numbers when BuildNumbers is specified.
+	SnapshotPattern string
+	// Reproducible builds each module twice and compares the digests
+	// of its outputs when specified. Outputs are not restored from
+	// the cache. See BuildResult.Reproducibility.
+	Reproducible *ReproducibleOptions
 }
 
 // CmdFailure contains the failures occurred while running a user defined command.
 type CmdFailure struct {
 	Module *Module
 	Err    error
+	// LogFile is the path to the file containing the command output.
+	// Empty unless LogDir option is specified.
+	LogFile string
 }
 
 // RunResult is the result of running a user defined command.
@@ -344,6 +818,37 @@ type System interface {
 	// BuildWorkspace builds changes in current workspace.
 	BuildWorkspaceChanges(options *CmdOptions) (*BuildSummary, error)
 
+	// TestBranch runs the tests of modules in the specified branch.
+	// This function accepts FilterOptions to specify which modules to be tested
+	// within that branch.
+	TestBranch(name string, filterOptions *FilterOptions, options *CmdOptions) (*BuildSummary, error)
+
+	// TestPr runs the tests of modules changed in 'src' branch since it
+	// diverged from 'dst' branch and the modules depending on them.
+	TestPr(src, dst string, options *CmdOptions) (*BuildSummary, error)
+
+	// TestDiff runs the tests of modules changed between two commits
+	// and the modules depending on them.
+	TestDiff(from, to string, options *CmdOptions) (*BuildSummary, error)
+
+	// TestCurrentBranch runs the tests of modules in the current branch.
+	// This function accepts FilterOptions to specify which modules to be tested.
+	TestCurrentBranch(filterOptions *FilterOptions, options *CmdOptions) (*BuildSummary, error)
+
+	// TestCommit runs the tests of modules in the specified commit.
+	// This function accepts FilterOptions to specify which modules to be tested.
+	TestCommit(commit string, filterOptions *FilterOptions, options *CmdOptions) (*BuildSummary, error)
+
+	// TestCommitContent runs the tests of modules changed in the specified commit.
+	TestCommitContent(commit string, options *CmdOptions) (*BuildSummary, error)
+
+	// TestWorkspace runs the tests of modules in the current workspace.
+	// This function accepts FilterOptions to specify which modules to be tested.
+	TestWorkspace(filterOptions *FilterOptions, options *CmdOptions) (*BuildSummary, error)
+
+	// TestWorkspaceChanges runs the tests of modules changed in current workspace.
+	TestWorkspaceChanges(options *CmdOptions) (*BuildSummary, error)
+
 	// IntersectionByCommit returns the manifest of intersection of modules modified
 	// between two commits.
 	// If we consider M as the merge base of first and second commits,
@@ -382,6 +887,58 @@ type System interface {
 	// ByWorkspaceChanges creates the manifest for the changes in workspace
 	ManifestByWorkspaceChanges() (*Manifest, error)
 
+	// AdvanceManifest creates the manifest for the specified commit
+	// from prev, the manifest of another commit, applying only the
+	// changes between them instead of walking the whole tree.
+	// The new manifest contains all modules in the commit regardless
+	// of the filters applied to prev.
+	AdvanceManifest(prev *Manifest, to string) (*Manifest, error)
+
+	// ManifestsByBranches creates the manifests for the specified
+	// branches in the same order, sharing the work between them.
+	ManifestsByBranches(names []string) ([]*Manifest, error)
+
+	// Stats computes the statistics of the modules changed in the
+	// commits reachable from 'to' but not from 'from'.
+	Stats(from, to string, options *StatsOptions) (*Stats, error)
+
+	// Coupling reports the modules frequently changed together and
+	// the files impacting the most modules in the commits reachable
+	// from 'to' but not from 'from'.
+	Coupling(from, to string, options *CouplingOptions) (*CouplingReport, error)
+
+	// CompareBranches compares the version of each module in
+	// branches a and b.
+	CompareBranches(a, b string) ([]*ModuleComparison, error)
+
+	// ModuleVersion returns the version of the named module at a
+	// branch, tag or commit.
+	ModuleVersion(name, ref string) (string, error)
+
+	// Export extracts the history of the named module, as of ref,
+	// into a new repository at path.
+	Export(name, ref, path string) (*ExportResult, error)
+
+	// Release builds the modules in a branch, tags them, generates
+	// their changelogs, publishes their artifacts and writes the
+	// release manifest. Tags are deleted if the release fails.
+	Release(branch string, options *ReleaseOptions) (*ReleaseManifest, error)
+
+	// ValidateRelease compares the version of each module in a release
+	// manifest with its version in the specified commit. Commit of the
+	// release is used if sha is empty.
+	ValidateRelease(r *ReleaseManifest, sha string) ([]*ReleaseValidation, error)
+
+	// Doctor validates the prerequisites of mbt in the environment
+	// and the repository. See DoctorCheck.
+	Doctor(options *DoctorOptions) ([]*DoctorCheck, error)
+
+	// RunTask runs the named task of the modules in a manifest.
+	// Build and test commands are available as build and test tasks.
+	// Unless the manifest is created for the workspace, its commit is
+	// checked out while running the task.
+	RunTask(task string, manifest *Manifest, options *CmdOptions) (*BuildSummary, error)
+
 	// RunInBranch runs a command in a branch.
 	// This function accepts FilterOptions to specify a subset of modules.
 	RunInBranch(command, name string, filterOptions *FilterOptions, options *CmdOptions) (*RunResult, error)
@@ -426,18 +983,136 @@ type stdSystem struct {
 	ProcessManager   ProcessManager
 }
 
+// SystemOptions describes the optional settings used to create a System.
+type SystemOptions struct {
+	// LogLevel of the system log.
+	LogLevel int
+	// Executor used to run commands. Commands are executed in local
+	// host when it is not specified.
+	// See NewHostExecutor, NewDockerExecutor, NewKubernetesExecutor and
+	// NewSSHExecutor for the built-in executors.
+	Executor Executor
+	// Policies enforced on the manifests. Manifests with modules
+	// violating policies at error level are not created.
+	Policies []Policy
+	// Diff options used to detect the changes. Default options are
+	// used when it is not specified.
+	Diff *DiffOptions
+	// SpecFile is the name of module spec files. Defaults to
+	// DefaultSpecFile.
+	SpecFile string
+	// Terraform enables the discovery of Terraform root modules.
+	// See DiscoverOptions.Terraform.
+	Terraform bool
+	// ManifestScript post-processes the manifests when specified.
+	// See NewScriptManifestBuilder.
+	ManifestScript string
+	// Profiler records the time spent in the phases of creating
+	// manifests when specified.
+	Profiler *Profiler
+	// ODB tunes the object database of the repository when specified.
+	ODB *ODBOptions
+	// ForceInclude and ForceExclude are the names of the modules
+	// always included in or excluded from diff based manifests.
+	// See ManifestBuilderOptions.
+	ForceInclude []string
+	ForceExclude []string
+	// Dependents is the policy of including the dependents of changed
+	// modules in diff based manifests. See ManifestBuilderOptions.
+	Dependents string
+	// Budget and BudgetPolicy limit the cost of the modules selected
+	// in diff based manifests. See ManifestBuilderOptions.
+	Budget       int
+	BudgetPolicy string
+	// Trailers are the keys of the commit trailers collected for the
+	// modules in diff based manifests. See ManifestBuilderOptions.
+	Trailers []string
+	// VersionFormat is the format module versions are rendered in:
+	// VersionFormatFull (default), VersionFormatBase36 or the number of
+	// leading characters kept (e.g. 7 or 12).
+	VersionFormat string
+}
+
+// DiffOptions describes how changes are detected in diff based manifests.
+type DiffOptions struct {
+	// SimilarityThreshold enables rename detection when greater than zero.
+	// Files are considered renamed when they are at least this percent
+	// similar (1-100).
+	SimilarityThreshold int
+	// ExcludeUntracked ignores untracked files in workspace changes.
+	ExcludeUntracked bool
+	// SkipBinary ignores changes to binary files.
+	SkipBinary bool
+	// ExcludeDirs are the names of directories skipped when discovering
+	// modules and matching changes. DefaultExcludedDirs is used when it
+	// is nil.
+	ExcludeDirs []string
+	// IncludeDirs are the names or paths of excluded directories to
+	// consider nevertheless (e.g. app-a/vendor).
+	IncludeDirs []string
+}
+
 // NewSystem creates a new instance of core mbt system
 func NewSystem(path string, logLevel int) (System, error) {
-	log := NewStdLog(logLevel)
-	repo, err := NewLibgitRepo(path, log)
+	return NewSystemWithOptions(path, &SystemOptions{LogLevel: logLevel})
+}
+
+// NewSystemWithOptions creates a new instance of core mbt system
+// configured with the specified options.
+func NewSystemWithOptions(path string, options *SystemOptions) (System, error) {
+	if _, err := dependentsDepth(options.Dependents); err != nil {
+		return nil, err
+	}
+	if err := validateBudgetPolicy(options.BudgetPolicy); err != nil {
+		return nil, err
+	}
+	render, err := versionRenderer(options.VersionFormat)
+	if err != nil {
+		return nil, err
+	}
+
+	log := NewStdLog(options.LogLevel)
+	repo, err := NewLibgitRepoWithOptions(path, log, options.Diff)
 	if err != nil {
 		return nil, err
 	}
-	discover := NewDiscover(repo, log)
+	if options.ODB != nil {
+		if err := repo.(*libgitRepo).tune(options.ODB); err != nil {
+			return nil, err
+		}
+	}
+	if options.Profiler != nil {
+		repo = &profiledRepo{Repo: repo, profiler: options.Profiler}
+	}
+	discover := NewDiscoverWithOptions(repo, log, &DiscoverOptions{SpecFile: options.SpecFile, Terraform: options.Terraform, Profiler: options.Profiler})
+	if options.VersionFormat != "" && options.VersionFormat != VersionFormatFull {
+		discover = &formattedDiscover{Discover: discover, render: render}
+	}
 	reducer := NewReducer(log)
-	mb := NewManifestBuilder(repo, reducer, discover, log)
+	if options.Profiler != nil {
+		discover = &profiledDiscover{Discover: discover, profiler: options.Profiler}
+		reducer = &profiledReducer{Reducer: reducer, profiler: options.Profiler}
+	}
+	mb := NewManifestBuilderWithOptions(repo, reducer, discover, log, &ManifestBuilderOptions{
+		ForceInclude: options.ForceInclude,
+		ForceExclude: options.ForceExclude,
+		Dependents:   options.Dependents,
+		Budget:       options.Budget,
+		BudgetPolicy: options.BudgetPolicy,
+		Trailers:     options.Trailers,
+	})
+	if options.ManifestScript != "" {
+		mb = NewScriptManifestBuilder(mb, log, options.ManifestScript)
+	}
+	if len(options.Policies) > 0 {
+		mb = NewPolicyManifestBuilder(mb, log, options.Policies...)
+	}
 	wm := NewWorkspaceManager(log, repo)
-	pm := NewProcessManager(log)
+	executor := options.Executor
+	if executor == nil {
+		executor = NewHostExecutor()
+	}
+	pm := NewProcessManagerWithExecutor(log, executor)
 	return initSystem(log, repo, mb, discover, reducer, wm, pm), nil
 }
 
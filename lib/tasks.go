@@ -0,0 +1,388 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"fmt"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/mbtproject/mbt/e"
+)
+
+// target is a task executed for modules in the order of their
+// dependencies. Build and test commands in the spec are available
+// as build and test tasks unless a module overrides them in tasks.
+type target struct {
+	name string
+	// msgFailed and msgFailures are used to report the failures
+	// of a single module and multiple modules respectively.
+	// Generic task messages are used if not specified.
+	msgFailed, msgFailures string
+}
+
+var buildTarget = &target{
+	name:        "build",
+	msgFailed:   msgFailedBuild,
+	msgFailures: msgFailedBuilds,
+}
+
+var testTarget = &target{
+	name:        "test",
+	msgFailed:   msgFailedTest,
+	msgFailures: msgFailedTests,
+}
+
+// taskTarget returns the target executing the named task.
+func taskTarget(name string) *target {
+	switch name {
+	case buildTarget.name:
+		return buildTarget
+	case testTarget.name:
+		return testTarget
+	}
+	return &target{name: name}
+}
+
+// taskStep is a command executed as part of a target.
+type taskStep struct {
+	target *target
+	cmd    *Cmd
+	// main is true for the step executing the target itself
+	// as opposed to the tasks it depends on.
+	main bool
+}
+
+// task returns the task of a module defined in tasks section of the spec.
+func (t *target) task(mod *Module) (*Task, bool) {
+	task, ok := mod.Tasks()[t.name]
+	return task, ok
+}
+
+// cmd returns the command of the target applicable to the
+// host platform.
+func (t *target) cmd(mod *Module) (*Cmd, bool) {
+	if task, ok := t.task(mod); ok {
+		if !task.applicable(runtime.GOOS) {
+			return nil, false
+		}
+		return task.cmd(), true
+	}
+
+	switch t.name {
+	case buildTarget.name:
+		if c, ok := platformCmd(mod.Build()); ok {
+			return c, true
+		}
+		if mod.HasDockerfile() {
+			return dockerBuildCmd(mod), true
+		}
+		return nil, false
+	case testTarget.name:
+		return platformCmd(mod.Test())
+	}
+
+	return nil, false
+}
+
+// dockerBuildCmd synthesizes the build command of a module without
+// a build command for the current platform but with a Dockerfile.
+// The image is tagged with the name and the version of the module.
+func dockerBuildCmd(mod *Module) *Cmd {
+	tag := fmt.Sprintf("%s:%s", imageName(mod.Name()), imageVersion(mod.Version()))
+	return &Cmd{Cmd: "docker", Args: []string{"build", "-t", tag, "."}}
+}
+
+// hooks returns the commands executed around the command of the target.
+// Hooks are only applicable to the build commands.
+func (t *target) hooks(mod *Module) *Hooks {
+	if _, ok := t.task(mod); !ok && t.name == buildTarget.name {
+		return mod.Hooks()
+	}
+	return &Hooks{}
+}
+
+// cache returns the outputs of the target and whether its results
+// should be cached. Builds are cached if outputs are specified.
+// Results of modules in the workspace are never cached because they
+// do not have a content based version.
+func (t *target) cache(mod *Module) ([]string, bool) {
+	if mod.Hash() == "local" {
+		return nil, false
+	}
+
+	if task, ok := t.task(mod); ok {
+		return task.Outputs, task.Cache
+	}
+
+	if t.name == buildTarget.name {
+		return mod.Outputs(), len(mod.Outputs()) > 0
+	}
+
+	return nil, false
+}
+
+// dependsOn returns the names of the tasks of the module executed
+// before the target.
+func (t *target) dependsOn(mod *Module) []string {
+	if task, ok := t.task(mod); ok {
+		return task.DependsOn
+	}
+	return nil
+}
+
+// plan returns the steps required to execute the target for a module.
+// Tasks the target depends on are ordered such that a task is always
+// preceded by its dependencies. Tasks not applicable to the host
+// platform and tasks of upstream modules are ignored.
+// Last step is the target itself.
+func (t *target) plan(mod *Module) []*taskStep {
+	steps := make([]*taskStep, 0)
+	visited := make(map[string]bool)
+
+	var visit func(name string)
+	visit = func(name string) {
+		if visited[name] {
+			return
+		}
+		visited[name] = true
+
+		dep := taskTarget(name)
+		for _, d := range dep.dependsOn(mod) {
+			if _, ok := upstreamTask(d); !ok {
+				visit(d)
+			}
+		}
+
+		if name == t.name {
+			return
+		}
+
+		if cmd, ok := dep.cmd(mod); ok {
+			steps = append(steps, &taskStep{target: dep, cmd: cmd})
+		}
+	}
+
+	visit(t.name)
+
+	cmd, _ := t.cmd(mod)
+	return append(steps, &taskStep{target: t, cmd: cmd, main: true})
+}
+
+// upstreamTask parses a task dependency referring to the tasks of the
+// modules a module depends on (e.g. ^build).
+// Returns the name of the task and true if dep is such a reference.
+func upstreamTask(dep string) (string, bool) {
+	if strings.HasPrefix(dep, "^") {
+		return dep[1:], true
+	}
+	return "", false
+}
+
+// taskNode is a task of a module.
+type taskNode struct {
+	mod    *Module
+	target *target
+}
+
+// prerequisites returns the tasks of upstream modules that must be
+// executed before executing the target for mods.
+// Tasks refer to the tasks of upstream modules in dependsOn with ^ prefix
+// (e.g. ^build). Returned list is ordered such that a task always
+// appears after the tasks it depends on. It does not contain the
+// target of the modules in mods since they are executed in
+// the order of module dependencies anyway.
+func (t *target) prerequisites(mods Modules) []*taskNode {
+	included := make(map[string]bool, len(mods))
+	for _, m := range mods {
+		included[m.Name()] = true
+	}
+
+	nodes := make([]*taskNode, 0)
+	visited := make(map[string]bool)
+
+	var visit func(mod *Module, task *target)
+	visit = func(mod *Module, task *target) {
+		key := mod.Name() + "^" + task.name
+		if visited[key] {
+			return
+		}
+		visited[key] = true
+
+		if _, ok := task.cmd(mod); !ok {
+			return
+		}
+
+		for _, step := range task.plan(mod) {
+			for _, d := range step.target.dependsOn(mod) {
+				u, ok := upstreamTask(d)
+				if !ok {
+					continue
+				}
+
+				for _, r := range mod.Requires() {
+					visit(r, taskTarget(u))
+				}
+			}
+		}
+
+		if task.name != t.name || !included[mod.Name()] {
+			nodes = append(nodes, &taskNode{mod: mod, target: task})
+		}
+	}
+
+	for _, m := range mods {
+		visit(m, t)
+	}
+
+	return nodes
+}
+
+// cacheKey returns the key used to store the outputs of a module.
+// Since commands are platform specific, outputs are cached
+// separately for each platform.
+func (t *target) cacheKey(mod *Module) string {
+	if t.name == buildTarget.name {
+		return fmt.Sprintf("%s-%s-%s", mod.Name(), mod.Version(), runtime.GOOS)
+	}
+	return fmt.Sprintf("%s-%s-%s-%s", mod.Name(), mod.Version(), t.name, runtime.GOOS)
+}
+
+// durationKey returns the key used to record the durations of
+// executing the target for a module.
+func (t *target) durationKey(mod *Module) string {
+	if t.name == buildTarget.name {
+		return moduleDisplayName(mod)
+	}
+	return t.name + ":" + moduleDisplayName(mod)
+}
+
+func (t *target) failed(err error, mod *Module) error {
+	if t.msgFailed != "" {
+		return e.Wrapf(ErrClassBuild, err, t.msgFailed, moduleDisplayName(mod))
+	}
+	return e.Wrapf(ErrClassBuild, err, msgFailedTask, t.name, moduleDisplayName(mod))
+}
+
+func (t *target) failures(names []string) error {
+	if t.msgFailures != "" {
+		return e.NewErrorf(ErrClassBuild, t.msgFailures, len(names), strings.Join(names, ", "))
+	}
+	return e.NewErrorf(ErrClassBuild, msgFailedTasks, t.name, len(names), strings.Join(names, ", "))
+}
+
+// applicable returns true if the task can be executed on the
+// specified platform.
+func (t *Task) applicable(goos string) bool {
+	if len(t.OS) == 0 {
+		return true
+	}
+
+	for _, o := range t.OS {
+		if o == goos {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *Task) cmd() *Cmd {
+	return &Cmd{Cmd: t.Cmd, Args: t.Args, Timeout: t.Timeout, Retries: t.Retries, When: t.When, WorkDir: t.WorkDir}
+}
+
+// validateTasks ensures the tasks of a spec refer to known tasks
+// and do not have circular dependencies.
+func validateTasks(spec *Spec) error {
+	known := func(name string) bool {
+		_, ok := spec.Tasks[name]
+		return ok || name == buildTarget.name || name == testTarget.name
+	}
+
+	for name, task := range spec.Tasks {
+		if err := validateCmd(task.Cmd, task.Timeout, task.When); err != nil {
+			return err
+		}
+
+		for _, d := range task.DependsOn {
+			if u, ok := upstreamTask(d); ok {
+				// Tasks of upstream modules are validated when
+				// they are executed since modules may not define
+				// every task.
+				if u == "" {
+					return e.NewErrorf(ErrClassUser, msgUnknownTaskDependency, name, d, spec.Name)
+				}
+				continue
+			}
+
+			if !known(d) {
+				return e.NewErrorf(ErrClassUser, msgUnknownTaskDependency, name, d, spec.Name)
+			}
+		}
+	}
+
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := make(map[string]int)
+	var visit func(name string, path []string) error
+	visit = func(name string, path []string) error {
+		switch state[name] {
+		case visiting:
+			return e.NewErrorf(ErrClassUser, msgCircularTaskDependency, strings.Join(append(path, name), " -> "), spec.Name)
+		case visited:
+			return nil
+		}
+
+		state[name] = visiting
+		if task, ok := spec.Tasks[name]; ok {
+			for _, d := range task.DependsOn {
+				if _, ok := upstreamTask(d); ok {
+					continue
+				}
+				if err := visit(d, append(path, name)); err != nil {
+					return err
+				}
+			}
+		}
+		state[name] = visited
+		return nil
+	}
+
+	names := make([]string, 0, len(spec.Tasks))
+	for name := range spec.Tasks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if err := visit(name, nil); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *stdSystem) RunTask(task string, manifest *Manifest, options *CmdOptions) (*BuildSummary, error) {
+	t := taskTarget(task)
+	if manifest.Sha == "local" || len(manifest.Modules) == 0 {
+		return s.runTarget(manifest, t, options)
+	}
+
+	return s.checkoutAndRunTarget(manifest, t, options)
+}
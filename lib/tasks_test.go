@@ -0,0 +1,246 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"bytes"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func stepNames(steps []*taskStep) []string {
+	names := make([]string, 0, len(steps))
+	for _, s := range steps {
+		names = append(names, s.target.name)
+	}
+	return names
+}
+
+func TestTaskPlan(t *testing.T) {
+	mod := newTestModule("app-a", "app-a", "a1b2c3")
+	mod.metadata.spec = &Spec{
+		Name:  "app-a",
+		Build: map[string]*Cmd{"default": {Cmd: "make"}},
+		Tasks: map[string]*Task{
+			"lint":    {Cmd: "lint"},
+			"package": {Cmd: "package", DependsOn: []string{"build", "lint"}},
+			"deploy":  {Cmd: "deploy", DependsOn: []string{"package", "lint"}},
+			"other":   {Cmd: "other", OS: []string{"plan9-only"}},
+			"release": {Cmd: "release", DependsOn: []string{"other", "test"}},
+		},
+	}
+
+	assert.Equal(t, []string{"build", "lint", "package", "deploy"}, stepNames(taskTarget("deploy").plan(mod)))
+	assert.Equal(t, []string{"release"}, stepNames(taskTarget("release").plan(mod)))
+	assert.Equal(t, []string{"build"}, stepNames(buildTarget.plan(mod)))
+}
+
+func TestTaskCmd(t *testing.T) {
+	mod := newTestModule("app-a", "app-a", "a1b2c3")
+	mod.metadata.spec = &Spec{
+		Name:  "app-a",
+		Build: map[string]*Cmd{"default": {Cmd: "make"}},
+		Tasks: map[string]*Task{
+			"lint":  {Cmd: "lint", Args: []string{"a"}, Retries: 2},
+			"other": {Cmd: "other", OS: []string{"plan9-only"}},
+		},
+	}
+
+	c, ok := taskTarget("lint").cmd(mod)
+	assert.True(t, ok)
+	assert.Equal(t, &Cmd{Cmd: "lint", Args: []string{"a"}, Retries: 2}, c)
+
+	_, ok = taskTarget("other").cmd(mod)
+	assert.False(t, ok)
+
+	_, ok = taskTarget("missing").cmd(mod)
+	assert.False(t, ok)
+
+	c, ok = buildTarget.cmd(mod)
+	assert.True(t, ok)
+	assert.Equal(t, "make", c.Cmd)
+}
+
+func TestDockerfileBuild(t *testing.T) {
+	mod := newTestModule("App-A", "App-A", "a1b2c3")
+	_, ok := buildTarget.cmd(mod)
+	assert.False(t, ok)
+
+	mod.metadata.dockerfile = true
+	c, ok := buildTarget.cmd(mod)
+	assert.True(t, ok)
+	assert.Equal(t, &Cmd{Cmd: "docker", Args: []string{"build", "-t", "app-a:a1b2c3", "."}}, c)
+
+	_, ok = testTarget.cmd(mod)
+	assert.False(t, ok)
+
+	mod.metadata.spec.Build = map[string]*Cmd{"default": {Cmd: "make"}}
+	c, ok = buildTarget.cmd(mod)
+	assert.True(t, ok)
+	assert.Equal(t, "make", c.Cmd)
+}
+
+func TestTaskOverridesBuild(t *testing.T) {
+	mod := newTestModule("app-a", "app-a", "a1b2c3")
+	mod.metadata.spec = &Spec{
+		Name:    "app-a",
+		Build:   map[string]*Cmd{"default": {Cmd: "make"}},
+		Hooks:   &Hooks{Pre: &Cmd{Cmd: "pre"}},
+		Outputs: []string{"bin"},
+		Tasks:   map[string]*Task{"build": {Cmd: "bazel"}},
+	}
+
+	c, ok := buildTarget.cmd(mod)
+	assert.True(t, ok)
+	assert.Equal(t, "bazel", c.Cmd)
+	assert.Equal(t, &Hooks{}, buildTarget.hooks(mod))
+
+	_, cache := buildTarget.cache(mod)
+	assert.False(t, cache)
+}
+
+func TestTaskCache(t *testing.T) {
+	mod := newTestModule("app-a", "app-a", "a1b2c3")
+	mod.metadata.spec = &Spec{
+		Name:    "app-a",
+		Outputs: []string{"bin"},
+		Tasks: map[string]*Task{
+			"lint":    {Cmd: "lint", Cache: true},
+			"package": {Cmd: "package", Outputs: []string{"dist"}},
+		},
+	}
+
+	outputs, ok := buildTarget.cache(mod)
+	assert.True(t, ok)
+	assert.Equal(t, []string{"bin"}, outputs)
+
+	outputs, ok = taskTarget("lint").cache(mod)
+	assert.True(t, ok)
+	assert.Empty(t, outputs)
+
+	_, ok = taskTarget("package").cache(mod)
+	assert.False(t, ok)
+
+	assert.Equal(t, "app-a-a1b2c3-"+runtime.GOOS, buildTarget.cacheKey(mod))
+	assert.Equal(t, "app-a-a1b2c3-lint-"+runtime.GOOS, taskTarget("lint").cacheKey(mod))
+
+	local := newModule(newModuleMetadata("app-a", "local", mod.metadata.spec, nil), nil)
+	_, ok = buildTarget.cache(local)
+	assert.False(t, ok)
+}
+
+func TestInvalidTaskDependencies(t *testing.T) {
+	_, err := newSpec([]byte(`
+name: app-a
+tasks:
+  lint:
+    cmd: lint
+    dependsOn: [format]
+`))
+	assert.EqualError(t, err, "Task 'lint' depends on unknown task 'format' in module 'app-a'")
+
+	_, err = newSpec([]byte(`
+name: app-a
+tasks:
+  a:
+    cmd: a
+    dependsOn: [b]
+  b:
+    cmd: b
+    dependsOn: [a]
+`))
+	assert.EqualError(t, err, "Circular task dependency a -> b -> a in module 'app-a'")
+
+	_, err = newSpec([]byte(`
+name: app-a
+tasks:
+  deploy:
+    cmd: deploy
+    dependsOn: [build, test]
+`))
+	assert.NoError(t, err)
+}
+
+func TestTaskDryRunPlan(t *testing.T) {
+	mod := newTestModule("app-a", "app-a", "a1b2c3")
+	mod.metadata.spec = &Spec{
+		Name: "app-a",
+		Tasks: map[string]*Task{
+			"lint":   {Cmd: "lint"},
+			"deploy": {Cmd: "deploy", Args: []string{"now"}, DependsOn: []string{"lint"}},
+		},
+	}
+
+	buff := new(bytes.Buffer)
+	writeBuildPlan(buff, 1, taskTarget("deploy").plan(mod), &Manifest{Dir: "/repo", Sha: "abc", Modules: Modules{mod}}, mod)
+
+	assert.Contains(t, buff.String(), "1. app-a (path: app-a version: a1b2c3)\n  lint: lint\n  cmd: deploy now\n")
+}
+
+func TestTaskPrerequisites(t *testing.T) {
+	a := newModule(newModuleMetadata("app-a", "a", &Spec{
+		Name:  "app-a",
+		Tasks: map[string]*Task{"build": {Cmd: "build-a"}, "codegen": {Cmd: "codegen-a"}},
+	}, nil), nil)
+	b := newModule(newModuleMetadata("app-b", "b", &Spec{
+		Name: "app-b",
+		Tasks: map[string]*Task{
+			"build": {Cmd: "build-b", DependsOn: []string{"^build", "^codegen"}},
+		},
+	}, nil), Modules{a})
+	c := newModule(newModuleMetadata("app-c", "c", &Spec{
+		Name:  "app-c",
+		Build: map[string]*Cmd{"default": {Cmd: "make"}},
+		Tasks: map[string]*Task{
+			"test": {Cmd: "test-c", DependsOn: []string{"^build", "lint"}},
+			"lint": {Cmd: "lint-c", DependsOn: []string{"^lint"}},
+		},
+	}, nil), Modules{b})
+
+	nodes := testTarget.prerequisites(Modules{c})
+	names := make([]string, 0, len(nodes))
+	for _, n := range nodes {
+		names = append(names, n.mod.Name()+":"+n.target.name)
+	}
+	assert.Equal(t, []string{"app-a:build", "app-a:codegen", "app-b:build"}, names)
+
+	nodes = buildTarget.prerequisites(Modules{a, b})
+	assert.Len(t, nodes, 1)
+	assert.Equal(t, "codegen", nodes[0].target.name)
+	assert.Len(t, buildTarget.prerequisites(Modules{b}), 2)
+}
+
+func TestUpstreamTaskDependency(t *testing.T) {
+	_, err := newSpec([]byte(`
+name: app-a
+tasks:
+  test:
+    cmd: test
+    dependsOn: [^build]
+`))
+	assert.NoError(t, err)
+
+	_, err = newSpec([]byte(`
+name: app-a
+tasks:
+  test:
+    cmd: test
+    dependsOn: [^]
+`))
+	assert.EqualError(t, err, "Task 'test' depends on unknown task '^' in module 'app-a'")
+}
@@ -0,0 +1,227 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"io/ioutil"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/mbtproject/mbt/e"
+)
+
+// TerraformGroup is the group of modules discovered from Terraform
+// root modules.
+const TerraformGroup = "terraform"
+
+// terraformLockFile is the dependency lock file created in the
+// directories of Terraform root modules.
+const terraformLockFile = ".terraform.lock.hcl"
+
+var (
+	terraformBackend = regexp.MustCompile(`(?m)^\s*backend\s+"[^"]*"`)
+	terraformSource  = regexp.MustCompile(`(?m)^\s*source\s*=\s*"(\.\.?/[^"]*)"`)
+)
+
+// terraformDir is the Terraform configuration found in a directory.
+type terraformDir struct {
+	// root is true when the directory is a root module i.e. it
+	// configures a backend or has a dependency lock file.
+	root bool
+	// sources are the repository paths of local modules used
+	// in the directory.
+	sources map[string]bool
+}
+
+// terraformDirs indexes Terraform configuration by directory.
+type terraformDirs map[string]*terraformDir
+
+// isTerraformFile returns true if the file with specified name
+// contributes to the detection of Terraform root modules.
+func isTerraformFile(name string) bool {
+	return strings.HasSuffix(name, ".tf") || name == terraformLockFile
+}
+
+// add records the Terraform file in dir with specified name and contents.
+func (s terraformDirs) add(dir, name string, contents []byte) {
+	t, ok := s[dir]
+	if !ok {
+		t = &terraformDir{sources: make(map[string]bool)}
+		s[dir] = t
+	}
+
+	if name == terraformLockFile || terraformBackend.Match(contents) {
+		t.root = true
+	}
+
+	for _, m := range terraformSource.FindAllSubmatch(contents, -1) {
+		p := path.Clean(path.Join(dir, string(m[1])))
+		if p == "." || p == ".." || strings.HasPrefix(p, "../") {
+			continue
+		}
+		t.sources[p] = true
+	}
+}
+
+// dependencies returns the local modules used by the module in dir
+// including the ones used transitively.
+func (s terraformDirs) dependencies(dir string) []string {
+	seen := map[string]bool{dir: true}
+	queue := []string{dir}
+	deps := make([]string, 0)
+
+	for len(queue) > 0 {
+		t, ok := s[queue[0]]
+		queue = queue[1:]
+		if !ok {
+			continue
+		}
+
+		for p := range t.sources {
+			if seen[p] {
+				continue
+			}
+			seen[p] = true
+			deps = append(deps, p)
+			queue = append(queue, p)
+		}
+	}
+
+	sort.Strings(deps)
+	return deps
+}
+
+// metadata appends the metadata of root modules to set using hash
+// to find the hashes of repository paths. Existing modules in the
+// directories of root modules are marked as Terraform modules instead.
+func (s terraformDirs) metadata(set moduleMetadataSet, hash func(p string) (string, error)) (moduleMetadataSet, error) {
+	existing := make(map[string]*moduleMetadata)
+	for _, m := range set {
+		existing[m.dir] = m
+	}
+
+	dirs := make([]string, 0, len(s))
+	for d, t := range s {
+		if t.root {
+			dirs = append(dirs, d)
+		}
+	}
+	sort.Strings(dirs)
+
+	for _, d := range dirs {
+		if m, ok := existing[d]; ok {
+			m.terraform = true
+			continue
+		}
+
+		spec := &Spec{
+			Name:             terraformModuleName(d),
+			Group:            TerraformGroup,
+			Properties:       make(map[string]interface{}),
+			Build:            make(map[string]*Cmd),
+			FileDependencies: s.dependencies(d),
+		}
+
+		h, err := hash(d)
+		if err != nil {
+			return nil, err
+		}
+
+		fileHashes := make(map[string]string)
+		for _, f := range spec.FileDependencies {
+			fh, err := hash(f)
+			if err != nil {
+				return nil, err
+			}
+			fileHashes[f] = fh
+		}
+
+		m := newModuleMetadata(d, h, spec, fileHashes)
+		m.terraform = true
+		set = append(set, m)
+	}
+
+	return set, nil
+}
+
+// terraformMetadataInWorkspace appends the metadata of root modules
+// in the workspace to set.
+func (d *stdDiscover) terraformMetadataInWorkspace(absRepoPath string, set moduleMetadataSet) (moduleMetadataSet, error) {
+	files, err := d.Repo.FindAllFilesInWorkspace([]string{"*.tf", "/**/*.tf", terraformLockFile, "/**/" + terraformLockFile})
+	if err != nil {
+		return nil, err
+	}
+
+	tf := terraformDirs{}
+	for _, entry := range files {
+		name := filepath.Base(entry)
+		if !isTerraformFile(name) {
+			continue
+		}
+
+		p := filepath.Join(absRepoPath, entry)
+		contents, err := ioutil.ReadFile(p)
+		if err != nil {
+			return nil, e.Wrapf(ErrClassInternal, err, "error whilst reading file contents at path %s", p)
+		}
+
+		dir := filepath.ToSlash(filepath.Dir(entry))
+		if dir == "." {
+			dir = ""
+		}
+		tf.add(dir, name, contents)
+	}
+
+	return tf.metadata(set, func(string) (string, error) {
+		return "local", nil
+	})
+}
+
+// terraformModuleName returns the name of the module discovered
+// from the Terraform root module in dir.
+func terraformModuleName(dir string) string {
+	if dir == "" {
+		return TerraformGroup
+	}
+	return strings.Replace(dir, "/", "-", -1)
+}
+
+// TerraformStack is a Terraform root module in a manifest.
+type TerraformStack struct {
+	Name    string
+	Dir     string
+	Version string
+}
+
+// TerraformStacks returns the Terraform root modules in mods.
+func TerraformStacks(mods Modules) []*TerraformStack {
+	stacks := make([]*TerraformStack, 0)
+	for _, m := range mods {
+		if !m.IsTerraform() {
+			continue
+		}
+
+		dir := m.Path()
+		if dir == "" {
+			dir = "."
+		}
+		stacks = append(stacks, &TerraformStack{Name: m.Name(), Dir: dir, Version: m.Version()})
+	}
+	return stacks
+}
@@ -0,0 +1,95 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testTerraformDirs() terraformDirs {
+	tf := terraformDirs{}
+	tf.add("infra/prod", "main.tf", []byte(`
+terraform {
+  backend "s3" {}
+}
+
+module "vpc" {
+  source = "../../modules/vpc"
+}
+
+module "remote" {
+  source = "hashicorp/consul/aws"
+}
+`))
+	tf.add("infra/dev", ".terraform.lock.hcl", []byte(""))
+	tf.add("infra/dev", "main.tf", []byte(`module "outside" { source = "../../../other" }`))
+	tf.add("modules/vpc", "main.tf", []byte(`
+module "subnets" {
+  source = "./subnets"
+}
+`))
+	tf.add("modules/vpc/subnets", "main.tf", []byte(`resource "aws_subnet" "a" {}`))
+	return tf
+}
+
+func TestTerraformRootModules(t *testing.T) {
+	tf := testTerraformDirs()
+
+	assert.True(t, tf["infra/prod"].root)
+	assert.True(t, tf["infra/dev"].root)
+	assert.False(t, tf["modules/vpc"].root)
+	assert.Equal(t, []string{"modules/vpc", "modules/vpc/subnets"}, tf.dependencies("infra/prod"))
+	assert.Equal(t, []string{}, tf.dependencies("infra/dev"))
+}
+
+func TestTerraformMetadata(t *testing.T) {
+	tf := testTerraformDirs()
+	existing := newModuleMetadata("infra/dev", "a", &Spec{Name: "dev"}, nil)
+
+	set, err := tf.metadata(moduleMetadataSet{existing}, func(p string) (string, error) {
+		return p + "-hash", nil
+	})
+	check(t, err)
+
+	assert.Len(t, set, 2)
+	assert.True(t, existing.terraform)
+
+	prod := set[1]
+	assert.Equal(t, "infra-prod", prod.spec.Name)
+	assert.Equal(t, TerraformGroup, prod.spec.Group)
+	assert.Equal(t, "infra/prod-hash", prod.hash)
+	assert.Equal(t, map[string]string{"modules/vpc": "modules/vpc-hash", "modules/vpc/subnets": "modules/vpc/subnets-hash"}, prod.dependentFileHashes)
+	assert.True(t, prod.terraform)
+}
+
+func TestTerraformStacks(t *testing.T) {
+	tf := testTerraformDirs()
+	set, err := tf.metadata(moduleMetadataSet{newModuleMetadata("app-a", "a", &Spec{Name: "app-a"}, nil)}, func(p string) (string, error) {
+		return "local", nil
+	})
+	check(t, err)
+
+	mods, err := toModules(set)
+	check(t, err)
+
+	stacks := TerraformStacks(mods)
+	assert.Len(t, stacks, 2)
+	assert.Equal(t, &TerraformStack{Name: "infra-dev", Dir: "infra/dev", Version: "local"}, stacks[0])
+	assert.Equal(t, &TerraformStack{Name: "infra-prod", Dir: "infra/prod", Version: "local"}, stacks[1])
+	assert.Equal(t, "terraform", terraformModuleName(""))
+}
@@ -0,0 +1,108 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+func (s *stdSystem) TestBranch(name string, filterOptions *FilterOptions, options *CmdOptions) (*BuildSummary, error) {
+	m, err := s.ManifestByBranch(name)
+	if err != nil {
+		return nil, err
+	}
+
+	m, err = m.ApplyFilters(filterOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.checkoutAndRunTarget(m, testTarget, options)
+}
+
+func (s *stdSystem) TestPr(src, dst string, options *CmdOptions) (*BuildSummary, error) {
+	m, err := s.ManifestByPr(src, dst)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.checkoutAndRunTarget(m, testTarget, options)
+}
+
+func (s *stdSystem) TestDiff(from, to string, options *CmdOptions) (*BuildSummary, error) {
+	m, err := s.ManifestByDiff(from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.checkoutAndRunTarget(m, testTarget, options)
+}
+
+func (s *stdSystem) TestCurrentBranch(filterOptions *FilterOptions, options *CmdOptions) (*BuildSummary, error) {
+	m, err := s.ManifestByCurrentBranch()
+	if err != nil {
+		return nil, err
+	}
+
+	m, err = m.ApplyFilters(filterOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.checkoutAndRunTarget(m, testTarget, options)
+}
+
+func (s *stdSystem) TestCommit(commit string, filterOptions *FilterOptions, options *CmdOptions) (*BuildSummary, error) {
+	m, err := s.ManifestByCommit(commit)
+	if err != nil {
+		return nil, err
+	}
+
+	m, err = m.ApplyFilters(filterOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.checkoutAndRunTarget(m, testTarget, options)
+}
+
+func (s *stdSystem) TestCommitContent(commit string, options *CmdOptions) (*BuildSummary, error) {
+	m, err := s.ManifestByCommitContent(commit)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.checkoutAndRunTarget(m, testTarget, options)
+}
+
+func (s *stdSystem) TestWorkspace(filterOptions *FilterOptions, options *CmdOptions) (*BuildSummary, error) {
+	m, err := s.ManifestByWorkspace()
+	if err != nil {
+		return nil, err
+	}
+
+	m, err = m.ApplyFilters(filterOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.runTarget(m, testTarget, options)
+}
+
+func (s *stdSystem) TestWorkspaceChanges(options *CmdOptions) (*BuildSummary, error) {
+	m, err := s.ManifestByWorkspaceChanges()
+	if err != nil {
+		return nil, err
+	}
+
+	return s.runTarget(m, testTarget, options)
+}
@@ -0,0 +1,94 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"strings"
+
+	"github.com/mbtproject/mbt/e"
+)
+
+// parseTrailers returns the values of the trailers with the specified
+// keys in commit messages. Trailers are the "Key: value" lines in the
+// last paragraph of a message. Keys are matched case insensitively and
+// values are listed once in the order they first appear.
+func parseTrailers(messages []string, keys []string) map[string][]string {
+	canonical := make(map[string]string, len(keys))
+	for _, k := range keys {
+		canonical[strings.ToLower(k)] = k
+	}
+
+	trailers := make(map[string][]string)
+	seen := make(map[string]bool)
+	for _, msg := range messages {
+		paragraphs := strings.Split(strings.TrimSpace(strings.Replace(msg, "\r\n", "\n", -1)), "\n\n")
+		// The first paragraph is the subject.
+		if len(paragraphs) < 2 {
+			continue
+		}
+
+		for _, l := range strings.Split(paragraphs[len(paragraphs)-1], "\n") {
+			parts := strings.SplitN(l, ":", 2)
+			if len(parts) != 2 {
+				continue
+			}
+			key, ok := canonical[strings.ToLower(strings.TrimSpace(parts[0]))]
+			value := strings.TrimSpace(parts[1])
+			if !ok || value == "" || seen[key+"\x00"+value] {
+				continue
+			}
+			seen[key+"\x00"+value] = true
+			trailers[key] = append(trailers[key], value)
+		}
+	}
+
+	return trailers
+}
+
+// describeTrailers sets the values of the configured trailers in the
+// commits changing each module of diff based manifest m since its base.
+func (b *stdManifestBuilder) describeTrailers(m *Manifest) error {
+	if b.options == nil || len(b.options.Trailers) == 0 || m.Base == "" || m.Sha == "local" {
+		return nil
+	}
+
+	for _, mod := range m.Modules {
+		path := mod.Path()
+		if path == "" {
+			path = "."
+		}
+
+		out, err := gitOutput(m.Dir, "log", "--reverse", "-z", "--format=%B", m.Base+".."+m.Sha, "--", path)
+		if err != nil {
+			return e.Wrapf(ErrClassInternal, err, msgFailedTrailers, mod.Name())
+		}
+
+		mod.trailers = parseTrailers(strings.Split(out, "\x00"), b.options.Trailers)
+	}
+
+	return nil
+}
+
+// Trailers returns the values of the configured commit trailers
+// (e.g. Ticket) in the commits changing the module in a diff based
+// manifest. It is empty in other manifests.
+func (a *Module) Trailers() map[string][]string {
+	trailers := make(map[string][]string, len(a.trailers))
+	for k, v := range a.trailers {
+		trailers[k] = append([]string{}, v...)
+	}
+	return trailers
+}
@@ -0,0 +1,69 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTrailers(t *testing.T) {
+	messages := []string{
+		"Fix rounding\n\nRounds to cents.\n\nTicket: PAY-1\ndeploy-env: staging\nSigned-off-by: someone",
+		"Ticket: PAY-9\n",
+		"Fix rounding again\n\nTicket: PAY-1\r\nTicket: PAY-2\r\n",
+		"Refactor\n\nBody mentioning Ticket: PAY-3 in a paragraph.\n\nReviewed-by: someone",
+		"",
+	}
+
+	assert.Equal(t, map[string][]string{
+		"Ticket":     {"PAY-1", "PAY-2"},
+		"Deploy-Env": {"staging"},
+	}, parseTrailers(messages, []string{"Ticket", "Deploy-Env"}))
+	assert.Empty(t, parseTrailers(messages, nil))
+}
+
+func TestTrailersOfModuleWithoutTrailers(t *testing.T) {
+	mod := newTestModule("app-a", "app-a", "a1")
+
+	assert.Equal(t, map[string][]string{}, mod.Trailers())
+	assert.Nil(t, mod.Document().Trailers)
+}
+
+func TestTrailersInDiff(t *testing.T) {
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+
+	check(t, repo.InitModule("app-a"))
+	check(t, repo.InitModule("app-b"))
+	check(t, repo.Commit("first"))
+	c1 := repo.LastCommit
+
+	check(t, repo.WriteContent("app-a/foo", "bar"))
+	check(t, repo.Commit("second\n\nTicket: PAY-1"))
+	check(t, repo.WriteContent("app-b/foo", "bar"))
+	check(t, repo.Commit("third\n\nTicket: OPS-2"))
+
+	system, err := NewSystemWithOptions(".tmp/repo", &SystemOptions{LogLevel: LogLevelNormal, Trailers: []string{"Ticket"}})
+	check(t, err)
+
+	m, err := system.ManifestByDiff(c1.String(), repo.LastCommit.String())
+	check(t, err)
+
+	assert.Equal(t, map[string][]string{"Ticket": {"PAY-1"}}, m.Modules.indexByName()["app-a"].Trailers())
+	assert.Equal(t, map[string][]string{"Ticket": {"OPS-2"}}, m.Modules.indexByName()["app-b"].Trailers())
+}
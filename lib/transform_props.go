@@ -15,7 +15,11 @@ limitations under the License.
 
 package lib
 
-import "github.com/mbtproject/mbt/e"
+import (
+	"fmt"
+
+	"github.com/mbtproject/mbt/e"
+)
 
 // transformProps is a helper function to convert all map[interface{}]interface{}
 // to map[string]interface{}.
@@ -25,7 +29,9 @@ import "github.com/mbtproject/mbt/e"
 // Due to this behavior, if we serialise the output of yaml.Unmarshal to
 // with json.Marshal, it blows-up for scenarios with nested maps.
 // This function is used to normalise the the whole tree before
-// we use it.
+// we use it. Keys which are not strings (e.g. numbers or booleans)
+// are converted to strings the same way yaml converts the keys of the
+// top level properties.
 func transformProps(p map[string]interface{}) (map[string]interface{}, error) {
 	for k, v := range p {
 		nv, err := transformIfRequired(v)
@@ -58,9 +64,9 @@ func transformIfRequired(v interface{}) (interface{}, error) {
 func transformMaps(m map[interface{}]interface{}) (map[string]interface{}, error) {
 	newMap := make(map[string]interface{})
 	for k, v := range m {
-		sk, ok := k.(string)
-		if !ok {
-			return nil, e.NewErrorf(ErrClassInternal, "Key is not a string %v", k)
+		sk := propertyKey(k)
+		if _, ok := newMap[sk]; ok {
+			return nil, e.NewErrorf(ErrClassUser, msgDuplicatePropertyKey, sk)
 		}
 
 		nv, err := transformIfRequired(v)
@@ -71,3 +77,42 @@ func transformMaps(m map[interface{}]interface{}) (map[string]interface{}, error
 	}
 	return newMap, nil
 }
+
+func propertyKey(k interface{}) string {
+	switch v := k.(type) {
+	case string:
+		return v
+	case nil:
+		return ""
+	}
+	return fmt.Sprint(k)
+}
+
+// copyProps returns a deep copy of the normalised properties so that
+// callers can modify it without affecting the module.
+func copyProps(p map[string]interface{}) map[string]interface{} {
+	if p == nil {
+		return nil
+	}
+
+	c := make(map[string]interface{}, len(p))
+	for k, v := range p {
+		c[k] = copyProp(v)
+	}
+	return c
+}
+
+func copyProp(v interface{}) interface{} {
+	switch c := v.(type) {
+	case map[string]interface{}:
+		return copyProps(c)
+	case []interface{}:
+		a := make([]interface{}, len(c))
+		for i, e := range c {
+			a[i] = copyProp(e)
+		}
+		return a
+	}
+
+	return v
+}
@@ -16,6 +16,7 @@ limitations under the License.
 package lib
 
 import (
+	"encoding/json"
 	"testing"
 
 	"github.com/mbtproject/mbt/e"
@@ -50,11 +51,40 @@ func TestNonStringKey(t *testing.T) {
 	i := make(map[string]interface{})
 	n := make(map[interface{}]interface{})
 	n[42] = "foo"
+	n[true] = "bar"
+	n[1.5] = []interface{}{map[interface{}]interface{}{8080: "http"}}
 	i["a"] = n
 
 	o, err := transformProps(i)
+	check(t, err)
+
+	assert.Equal(t, map[string]interface{}{
+		"42":   "foo",
+		"true": "bar",
+		"1.5":  []interface{}{map[string]interface{}{"8080": "http"}},
+	}, o["a"])
+}
+
+func TestDuplicateKeyAfterConversion(t *testing.T) {
+	i := make(map[string]interface{})
+	n := make(map[interface{}]interface{})
+	n[42] = "foo"
+	n["42"] = "bar"
+	i["a"] = n
 
-	assert.EqualError(t, err, "Key is not a string 42")
-	assert.Equal(t, ErrClassInternal, (err.(*e.E)).Class())
+	o, err := transformProps(i)
+
+	assert.EqualError(t, err, "Property key '42' is specified more than once")
+	assert.Equal(t, ErrClassUser, (err.(*e.E)).Class())
 	assert.Nil(t, o)
 }
+
+func TestNormalisedPropertiesInJSON(t *testing.T) {
+	spec, err := newSpec([]byte("name: app-a\nproperties:\n  ports:\n    80: http\n    443: https\n"))
+	check(t, err)
+
+	b, err := json.Marshal(spec.Properties)
+	check(t, err)
+
+	assert.Equal(t, `{"ports":{"443":"https","80":"http"}}`, string(b))
+}
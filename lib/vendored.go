@@ -0,0 +1,91 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"path"
+	"strings"
+)
+
+// DefaultExcludedDirs are the names of directories skipped by default
+// when discovering modules and matching changes. These directories
+// typically contain third party code which is expensive to walk and
+// should not be attributed to the modules containing them.
+// Note that module versions are computed from the entire module
+// directory, hence changes in excluded directories still change them.
+var DefaultExcludedDirs = []string{"vendor", "node_modules"}
+
+// dirFilter decides whether a directory is excluded according to the
+// ExcludeDirs and IncludeDirs in DiffOptions.
+type dirFilter struct {
+	exclude map[string]bool
+	include map[string]bool
+}
+
+func newDirFilter(options *DiffOptions) *dirFilter {
+	names := options.ExcludeDirs
+	if names == nil {
+		names = DefaultExcludedDirs
+	}
+
+	f := &dirFilter{
+		exclude: make(map[string]bool),
+		include: make(map[string]bool),
+	}
+
+	for _, n := range names {
+		if n != "" {
+			f.exclude[n] = true
+		}
+	}
+
+	for _, n := range options.IncludeDirs {
+		if n != "" {
+			f.include[strings.Trim(n, "/")] = true
+		}
+	}
+
+	return f
+}
+
+// excludedDir checks whether dir (a slash separated path relative to the
+// repository root) or any of its parents is excluded.
+// Excluded directories are included again when either their name or
+// path is in IncludeDirs.
+func (f *dirFilter) excludedDir(dir string) bool {
+	if len(f.exclude) == 0 {
+		return false
+	}
+
+	parts := strings.Split(strings.Trim(dir, "/"), "/")
+	for i, name := range parts {
+		if !f.exclude[name] || f.include[name] {
+			continue
+		}
+
+		if !f.include[strings.Join(parts[:i+1], "/")] {
+			return true
+		}
+	}
+
+	return false
+}
+
+// excludedFile checks whether the file at p is inside an excluded directory.
+func (f *dirFilter) excludedFile(p string) bool {
+	d := path.Dir(p)
+	return d != "." && f.excludedDir(d)
+}
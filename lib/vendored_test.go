@@ -0,0 +1,82 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefaultExcludedDirs(t *testing.T) {
+	f := newDirFilter(&DiffOptions{})
+
+	assert.True(t, f.excludedDir("vendor"))
+	assert.True(t, f.excludedDir("app-a/node_modules/foo"))
+	assert.True(t, f.excludedFile("app-a/vendor/lib.go"))
+	assert.False(t, f.excludedFile("app-a/vendor"))
+	assert.False(t, f.excludedDir("app-a/vendors"))
+	assert.False(t, f.excludedFile("main.go"))
+}
+
+func TestIncludedDirs(t *testing.T) {
+	f := newDirFilter(&DiffOptions{
+		ExcludeDirs: []string{"vendor", "third_party"},
+		IncludeDirs: []string{"app-a/vendor/", "third_party"},
+	})
+
+	assert.False(t, f.excludedDir("app-a/vendor/foo"))
+	assert.True(t, f.excludedDir("app-b/vendor/foo"))
+	assert.False(t, f.excludedDir("app-b/third_party"))
+	assert.True(t, f.excludedDir("app-a/vendor/third_party/vendor"))
+	assert.False(t, f.excludedDir("node_modules"))
+}
+
+func TestNoExcludedDirs(t *testing.T) {
+	f := newDirFilter(&DiffOptions{ExcludeDirs: []string{}})
+
+	assert.False(t, f.excludedDir("vendor"))
+}
+
+func TestManifestSkipsVendoredDirs(t *testing.T) {
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+
+	check(t, repo.InitModule("app-a"))
+	check(t, repo.InitModule("app-a/vendor/app-b"))
+	check(t, repo.InitModule("app-c"))
+	check(t, repo.Commit("first"))
+	first := repo.LastCommit.String()
+
+	check(t, repo.WriteContent("app-a/vendor/lib/lib.go", "package lib"))
+	check(t, repo.WriteContent("app-c/main.go", "package main"))
+	check(t, repo.Commit("second"))
+
+	m, err := NewWorld(t, ".tmp/repo").System.ManifestByDiff(first, repo.LastCommit.String())
+	check(t, err)
+
+	assert.Len(t, m.Modules, 1)
+	assert.Equal(t, "app-c", m.Modules[0].Name())
+
+	s, err := NewSystemWithOptions(".tmp/repo", &SystemOptions{Diff: &DiffOptions{IncludeDirs: []string{"app-a/vendor"}}})
+	check(t, err)
+	m, err = s.ManifestByDiff(first, repo.LastCommit.String())
+	check(t, err)
+
+	assert.Len(t, m.Modules, 2)
+	assert.Equal(t, "app-a", m.Modules[0].Name())
+	assert.Equal(t, "app-c", m.Modules[1].Name())
+}
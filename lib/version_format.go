@@ -0,0 +1,113 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"math/big"
+	"strconv"
+
+	"github.com/mbtproject/mbt/e"
+)
+
+// Version formats.
+const (
+	// VersionFormatFull renders versions as 40 character hex strings.
+	// This is the default.
+	VersionFormatFull = "full"
+	// VersionFormatBase36 renders versions in base 36 (31 characters
+	// at most) for registries imposing short tag lengths.
+	VersionFormatBase36 = "base36"
+)
+
+// Minimum and maximum lengths of abbreviated versions.
+const (
+	minVersionLength = 4
+	maxVersionLength = 40
+)
+
+// versionRenderer returns the function rendering versions in format.
+// format is one of VersionFormatFull, VersionFormatBase36 or the
+// number of leading characters kept (e.g. 7 or 12).
+func versionRenderer(format string) (func(string) string, error) {
+	switch format {
+	case "", VersionFormatFull:
+		return func(v string) string { return v }, nil
+	case VersionFormatBase36:
+		return func(v string) string {
+			n, ok := new(big.Int).SetString(v, 16)
+			if !ok {
+				return v
+			}
+			return n.Text(36)
+		}, nil
+	}
+
+	length, err := strconv.Atoi(format)
+	if err != nil || length < minVersionLength || length > maxVersionLength {
+		return nil, e.NewErrorf(ErrClassUser, msgInvalidVersionFormat, format, minVersionLength, maxVersionLength)
+	}
+	return func(v string) string {
+		if len(v) > length {
+			return v[:length]
+		}
+		return v
+	}, nil
+}
+
+// renderVersions renders the versions of mods with render. Versions of
+// modules in the workspace (i.e. local) are not changed.
+func renderVersions(mods Modules, render func(string) string) Modules {
+	for _, m := range mods {
+		if m.version != "local" {
+			m.version = render(m.version)
+		}
+	}
+	return mods
+}
+
+// formattedDiscover renders the versions of the modules discovered
+// by Discover in a format other than VersionFormatFull.
+// Versions are calculated in full and rendered afterwards, hence the
+// version of a module still depends on the full versions of its
+// dependencies.
+type formattedDiscover struct {
+	Discover
+	render func(string) string
+}
+
+func (d *formattedDiscover) ModulesInCommit(commit Commit) (Modules, error) {
+	mods, err := d.Discover.ModulesInCommit(commit)
+	if err != nil {
+		return nil, err
+	}
+	return renderVersions(mods, d.render), nil
+}
+
+func (d *formattedDiscover) ModulesInWorkspace() (Modules, error) {
+	mods, err := d.Discover.ModulesInWorkspace()
+	if err != nil {
+		return nil, err
+	}
+	return renderVersions(mods, d.render), nil
+}
+
+func (d *formattedDiscover) AdvanceModules(prev Modules, from, to Commit) (Modules, error) {
+	mods, err := d.Discover.AdvanceModules(prev, from, to)
+	if err != nil {
+		return nil, err
+	}
+	return renderVersions(mods, d.render), nil
+}
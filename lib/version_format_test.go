@@ -0,0 +1,88 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/mbtproject/mbt/e"
+	"github.com/stretchr/testify/assert"
+)
+
+const testVersion = "4b9a0d4f2fd0bd3c7d0b3a1e1f4c1a2b3c4d5e6f"
+
+func TestVersionRenderer(t *testing.T) {
+	cases := map[string]string{
+		"":                  testVersion,
+		VersionFormatFull:   testVersion,
+		"12":                "4b9a0d4f2fd0",
+		"7":                 "4b9a0d4",
+		"40":                testVersion,
+		VersionFormatBase36: "8tx56htuiq6imssbdi12y5al5d66lwv",
+	}
+
+	for format, expected := range cases {
+		render, err := versionRenderer(format)
+		check(t, err)
+		assert.Equal(t, expected, render(testVersion), format)
+	}
+}
+
+func TestInvalidVersionFormat(t *testing.T) {
+	for _, format := range []string{"short", "3", "41", "-7"} {
+		_, err := versionRenderer(format)
+
+		assert.EqualError(t, err, fmt.Sprintf(msgInvalidVersionFormat, format, minVersionLength, maxVersionLength))
+		assert.Equal(t, ErrClassUser, (err.(*e.E)).Class())
+	}
+
+	_, err := NewSystemWithOptions(".tmp/repo", &SystemOptions{VersionFormat: "short"})
+	assert.EqualError(t, err, fmt.Sprintf(msgInvalidVersionFormat, "short", minVersionLength, maxVersionLength))
+}
+
+func TestRenderVersions(t *testing.T) {
+	a := newTestModule("app-a", "app-a", testVersion)
+	b := newTestModule("app-b", "app-b", "local")
+
+	render, err := versionRenderer("7")
+	check(t, err)
+	renderVersions(Modules{a, b}, render)
+
+	assert.Equal(t, "4b9a0d4", a.Version())
+	assert.Equal(t, "local", b.Version())
+}
+
+func TestVersionFormatInManifest(t *testing.T) {
+	clean()
+	repo := NewTestRepo(t, ".tmp/repo")
+
+	check(t, repo.InitModule("app-a"))
+	check(t, repo.InitModuleWithOptions("app-b", &Spec{Name: "app-b", Dependencies: []string{"app-a"}}))
+	check(t, repo.Commit("first"))
+
+	full, err := NewWorld(t, ".tmp/repo").System.ManifestByCommit(repo.LastCommit.String())
+	check(t, err)
+
+	system, err := NewSystemWithOptions(".tmp/repo", &SystemOptions{LogLevel: LogLevelNormal, VersionFormat: "12"})
+	check(t, err)
+	short, err := system.ManifestByCommit(repo.LastCommit.String())
+	check(t, err)
+
+	for i, m := range short.Modules {
+		assert.Equal(t, full.Modules[i].Version()[:12], m.Version())
+	}
+}
@@ -0,0 +1,134 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"io/ioutil"
+	"path/filepath"
+
+	yaml "github.com/go-yaml/yaml"
+	"github.com/mbtproject/mbt/e"
+)
+
+// DefaultWorkspaceFile is the name of the file listing the repositories
+// of a multi-repository workspace.
+const DefaultWorkspaceFile = "mbt-workspace.yml"
+
+// WorkspaceFile lists the repositories aggregated in a WorkspaceManifest.
+type WorkspaceFile struct {
+	Repositories []*WorkspaceRepository `yaml:"repositories"`
+}
+
+// WorkspaceRepository is a repository in a WorkspaceFile.
+type WorkspaceRepository struct {
+	// Name of the repository used to namespace its module names.
+	Name string `yaml:"name"`
+	// Path to the repository. Relative paths are resolved from the
+	// directory containing the workspace file.
+	Path string `yaml:"path"`
+	// Branch used when manifests are created for branches.
+	// Defaults to master.
+	Branch string `yaml:"branch"`
+}
+
+// LoadWorkspaceFile reads the workspace file at path.
+func LoadWorkspaceFile(path string) (*WorkspaceFile, error) {
+	c, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, e.Wrapf(ErrClassUser, err, msgFailedReadFile, path)
+	}
+
+	w := &WorkspaceFile{}
+	if err = yaml.Unmarshal(c, w); err != nil {
+		return nil, e.Wrapf(ErrClassUser, err, msgInvalidWorkspaceFile, path)
+	}
+
+	names := make(map[string]bool)
+	for _, r := range w.Repositories {
+		if r.Name == "" || r.Path == "" {
+			return nil, e.NewErrorf(ErrClassUser, msgInvalidWorkspaceRepository, path)
+		}
+
+		if names[r.Name] {
+			return nil, e.NewErrorf(ErrClassUser, msgDuplicateWorkspaceRepository, r.Name, path)
+		}
+		names[r.Name] = true
+
+		if !filepath.IsAbs(r.Path) {
+			r.Path = filepath.Join(filepath.Dir(path), r.Path)
+		}
+
+		if r.Branch == "" {
+			r.Branch = "master"
+		}
+	}
+
+	return w, nil
+}
+
+// RepositoryManifest is the manifest of a repository in a workspace.
+type RepositoryManifest struct {
+	Repository *WorkspaceRepository
+	Manifest   *Manifest
+}
+
+// WorkspaceModule is a module in a WorkspaceManifest.
+type WorkspaceModule struct {
+	// Name of the module prefixed with the name of its repository
+	// (e.g. core/app-a).
+	Name       string
+	Repository *WorkspaceRepository
+	Module     *Module
+}
+
+// WorkspaceManifest aggregates the manifests of the repositories
+// in a WorkspaceFile.
+type WorkspaceManifest struct {
+	Repositories []*RepositoryManifest
+}
+
+// NewWorkspaceManifest creates the manifest of each repository in w
+// with build and aggregates them.
+func NewWorkspaceManifest(w *WorkspaceFile, build func(*WorkspaceRepository) (*Manifest, error)) (*WorkspaceManifest, error) {
+	wm := &WorkspaceManifest{Repositories: make([]*RepositoryManifest, 0, len(w.Repositories))}
+	for _, r := range w.Repositories {
+		m, err := build(r)
+		if err != nil {
+			return nil, e.Wrapf(ErrClassUser, err, msgFailedWorkspaceManifest, r.Name)
+		}
+
+		wm.Repositories = append(wm.Repositories, &RepositoryManifest{Repository: r, Manifest: m})
+	}
+
+	return wm, nil
+}
+
+// Modules returns the modules of all repositories with namespaced names.
+// Modules are ordered by repository as specified in the workspace file.
+func (w *WorkspaceManifest) Modules() []*WorkspaceModule {
+	mods := make([]*WorkspaceModule, 0)
+	for _, r := range w.Repositories {
+		for _, m := range r.Manifest.Modules {
+			mods = append(mods, &WorkspaceModule{
+				Name:       r.Repository.Name + "/" + m.Name(),
+				Repository: r.Repository,
+				Module:     m,
+			})
+		}
+	}
+
+	return mods
+}
@@ -0,0 +1,91 @@
+/*
+Copyright 2018 MBT Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+		http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadWorkspaceFile(t *testing.T) {
+	clean()
+	path := ".tmp/workspace/mbt-workspace.yml"
+	writeAuditFile(t, path, "repositories:\n  - name: core\n    path: ../core\n  - name: web\n    path: /src/web\n    branch: main\n")
+
+	w, err := LoadWorkspaceFile(path)
+	check(t, err)
+
+	assert.Len(t, w.Repositories, 2)
+	assert.Equal(t, &WorkspaceRepository{Name: "core", Path: filepath.Join(".tmp", "core"), Branch: "master"}, w.Repositories[0])
+	assert.Equal(t, &WorkspaceRepository{Name: "web", Path: "/src/web", Branch: "main"}, w.Repositories[1])
+}
+
+func TestLoadInvalidWorkspaceFile(t *testing.T) {
+	clean()
+	path := ".tmp/workspace/mbt-workspace.yml"
+
+	writeAuditFile(t, path, "repositories:\n  - name: core\n")
+	_, err := LoadWorkspaceFile(path)
+	assert.EqualError(t, err, fmt.Sprintf(msgInvalidWorkspaceRepository, path))
+
+	writeAuditFile(t, path, "repositories:\n  - name: core\n    path: a\n  - name: core\n    path: b\n")
+	_, err = LoadWorkspaceFile(path)
+	assert.EqualError(t, err, fmt.Sprintf(msgDuplicateWorkspaceRepository, "core", path))
+
+	writeAuditFile(t, path, "repositories: foo\n")
+	_, err = LoadWorkspaceFile(path)
+	assert.EqualError(t, err, fmt.Sprintf(msgInvalidWorkspaceFile, path))
+}
+
+func TestWorkspaceManifest(t *testing.T) {
+	w := &WorkspaceFile{Repositories: []*WorkspaceRepository{
+		{Name: "core", Path: "core"},
+		{Name: "web", Path: "web"},
+	}}
+
+	manifests := map[string]*Manifest{
+		"core": {Modules: Modules{newTestModule("app-a", "app-a", "a1"), newTestModule("lib", "lib", "l1")}},
+		"web":  {Modules: Modules{newTestModule("app-a", "app-a", "a2")}},
+	}
+
+	wm, err := NewWorkspaceManifest(w, func(r *WorkspaceRepository) (*Manifest, error) {
+		return manifests[r.Name], nil
+	})
+	check(t, err)
+
+	mods := wm.Modules()
+	assert.Len(t, mods, 3)
+	assert.Equal(t, "core/app-a", mods[0].Name)
+	assert.Equal(t, "a1", mods[0].Module.Version())
+	assert.Equal(t, "core/lib", mods[1].Name)
+	assert.Equal(t, "web/app-a", mods[2].Name)
+	assert.Equal(t, "web", mods[2].Repository.Name)
+	assert.Equal(t, "a2", mods[2].Module.Version())
+}
+
+func TestWorkspaceManifestForRepositoryFailure(t *testing.T) {
+	w := &WorkspaceFile{Repositories: []*WorkspaceRepository{{Name: "core", Path: "core"}}}
+
+	_, err := NewWorkspaceManifest(w, func(r *WorkspaceRepository) (*Manifest, error) {
+		return nil, errors.New("doh")
+	})
+
+	assert.EqualError(t, err, fmt.Sprintf(msgFailedWorkspaceManifest, "core"))
+}
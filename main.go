@@ -24,6 +24,6 @@ import (
 func main() {
 	if err := cmd.RootCmd.Execute(); err != nil {
 		println(err)
-		os.Exit(1)
+		os.Exit(cmd.ExitCode(err))
 	}
 }
@@ -41,3 +41,25 @@ func IsSubsequence(input, target string, ignoreCase bool) bool {
 
 	return idx == len(targetArray)
 }
+
+// ShellQuote quotes the input so that it is interpreted as
+// a single word by a POSIX shell.
+func ShellQuote(input string) string {
+	if input == "" {
+		return "''"
+	}
+
+	safe := true
+	for _, r := range input {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("-_./=:,@%+", r)) {
+			safe = false
+			break
+		}
+	}
+
+	if safe {
+		return input
+	}
+
+	return "'" + strings.Replace(input, "'", `'"'"'`, -1) + "'"
+}
@@ -46,3 +46,13 @@ func TestIsSubsequence(t *testing.T) {
 	assert.False(t, IsSubsequence("barry", "barray a", true))
 	assert.False(t, IsSubsequence("", "abc", true))
 }
+
+func TestShellQuote(t *testing.T) {
+	assert.Equal(t, "''", ShellQuote(""))
+	assert.Equal(t, "abc", ShellQuote("abc"))
+	assert.Equal(t, "./build.sh", ShellQuote("./build.sh"))
+	assert.Equal(t, "MBT_A=b", ShellQuote("MBT_A=b"))
+	assert.Equal(t, "'a b'", ShellQuote("a b"))
+	assert.Equal(t, `'a'"'"'b'`, ShellQuote("a'b"))
+	assert.Equal(t, "'$HOME'", ShellQuote("$HOME"))
+}